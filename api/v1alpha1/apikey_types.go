@@ -0,0 +1,187 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ApiKeySpec defines the desired state of ApiKey
+type ApiKeySpec struct {
+	// DomainRef is the Domain of the namespace the key sends for. The
+	// Domain must be registered with Kannon.
+	//+kubebuilder:validation:Required
+	DomainRef DomainReference `json:"domainRef"`
+
+	// Secret configures the Secret the key is stored in.
+	// +optional
+	Secret *ApiKeySecretSpec `json:"secret,omitempty"`
+
+	// RotateAfter is how old the key gets before it is replaced by a new
+	// one, e.g. 2160h. The old key is revoked rotationOverlap after the
+	// Secret holds the new one. The key is never rotated when not set.
+	// +optional
+	RotateAfter *metav1.Duration `json:"rotateAfter,omitempty"`
+
+	// RotationOverlap is how long a replaced key keeps working, so that
+	// the clients still using it pick up the new one. Defaults to 1h, 0s
+	// revokes it at once.
+	// +optional
+	RotationOverlap *metav1.Duration `json:"rotationOverlap,omitempty"`
+}
+
+type DomainReference struct {
+	// Name is the name of the Domain.
+	Name string `json:"name"`
+}
+
+type ApiKeySecretSpec struct {
+	// Name is the name of the Secret. Defaults to <apikey name>-apikey.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// KeyKey is the key of the API key in the Secret. Defaults to key.
+	// +optional
+	KeyKey string `json:"keyKey,omitempty"`
+
+	// DomainKey is the key of the domain name in the Secret. Defaults to
+	// domain.
+	// +optional
+	DomainKey string `json:"domainKey,omitempty"`
+}
+
+const (
+	DefaultApiKeySecretKeyKey    = "key"
+	DefaultApiKeySecretDomainKey = "domain"
+
+	DefaultApiKeyRotationOverlap = time.Hour
+)
+
+// RotationOverlapOrDefault returns how long a replaced key keeps working.
+func (s *ApiKeySpec) RotationOverlapOrDefault() time.Duration {
+	if s.RotationOverlap != nil && s.RotationOverlap.Duration >= 0 {
+		return s.RotationOverlap.Duration
+	}
+	return DefaultApiKeyRotationOverlap
+}
+
+// SecretNameOrDefault returns the name of the Secret holding the key.
+func (a *ApiKey) SecretNameOrDefault() string {
+	if s := a.Spec.Secret; s != nil && s.Name != "" {
+		return s.Name
+	}
+	return fmt.Sprintf("%s-apikey", a.Name)
+}
+
+// KeyKeyOrDefault returns the key of the API key in the Secret.
+func (s *ApiKeySecretSpec) KeyKeyOrDefault() string {
+	if s != nil && s.KeyKey != "" {
+		return s.KeyKey
+	}
+	return DefaultApiKeySecretKeyKey
+}
+
+// DomainKeyOrDefault returns the key of the domain name in the Secret.
+func (s *ApiKeySecretSpec) DomainKeyOrDefault() string {
+	if s != nil && s.DomainKey != "" {
+		return s.DomainKey
+	}
+	return DefaultApiKeySecretDomainKey
+}
+
+// ApiKeyStatus defines the observed state of ApiKey
+type ApiKeyStatus struct {
+	// ObservedGeneration is the generation of the spec last applied.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// KeyID is the Kannon identifier of the current key.
+	// +optional
+	KeyID string `json:"keyID,omitempty"`
+
+	// Domain is the domain name the current key was issued for.
+	// +optional
+	Domain string `json:"domain,omitempty"`
+
+	// SecretName is the Secret holding the current key.
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+
+	// IssuedAt is when the current key was created.
+	// +optional
+	IssuedAt *metav1.Time `json:"issuedAt,omitempty"`
+
+	// Retiring are the replaced keys, revoked once their overlap ends.
+	// +optional
+	Retiring []RetiringApiKey `json:"retiring,omitempty"`
+
+	// Conditions are the latest observations of the key state.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// RetiringApiKey is a replaced key still working until RevokeAt.
+type RetiringApiKey struct {
+	// KeyID is the Kannon identifier of the key.
+	KeyID string `json:"keyID"`
+
+	// Domain is the domain name the key was issued for.
+	Domain string `json:"domain"`
+
+	// RevokeAt is when the key is revoked.
+	RevokeAt metav1.Time `json:"revokeAt"`
+}
+
+const (
+	ReasonApiKeyIssued         = "Issued"
+	ReasonApiKeyIssueFailed    = "IssueFailed"
+	ReasonApiKeyDomainNotFound = "DomainNotFound"
+	ReasonApiKeySecretConflict = "SecretConflict"
+)
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+
+// ApiKey is a Kannon API key of a Domain, stored in a Secret
+// +kubebuilder:printcolumn:name="Domain",type=string,JSONPath=`.status.domain`
+// +kubebuilder:printcolumn:name="Secret",type=string,JSONPath=`.status.secretName`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Issued",type=date,JSONPath=`.status.issuedAt`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+type ApiKey struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ApiKeySpec   `json:"spec,omitempty"`
+	Status ApiKeyStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ApiKeyList contains a list of ApiKey
+type ApiKeyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ApiKey `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ApiKey{}, &ApiKeyList{})
+}
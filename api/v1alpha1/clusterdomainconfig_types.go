@@ -0,0 +1,168 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ClusterDomainConfigSpec defines the defaults of the Domains of the cluster
+type ClusterDomainConfigSpec struct {
+	// Ingress holds the defaults of the stats routes of the Domains.
+	// +optional
+	Ingress *IngressDefaults `json:"ingress,omitempty"`
+
+	// DKIM holds the defaults of the generated DKIM keys.
+	// +optional
+	DKIM *DKIMDefaults `json:"dkim,omitempty"`
+
+	// DNS holds the resolvers the records of the Domains are checked with.
+	// +optional
+	DNS *DNSDefaults `json:"dns,omitempty"`
+
+	// CheckInterval is how often the verified records of the Domains
+	// without spec.checkInterval are checked.
+	// +optional
+	CheckInterval *metav1.Duration `json:"checkInterval,omitempty"`
+
+	// UnverifiedCheckInterval caps the backoff of the checks of the
+	// records of the Domains without spec.unverifiedCheckInterval.
+	// +optional
+	UnverifiedCheckInterval *metav1.Duration `json:"unverifiedCheckInterval,omitempty"`
+
+	// Notifications are sinks notified of the changes of the Domains, in
+	// addition to the ones configured with the flags of the operator.
+	// +optional
+	Notifications *NotificationsSpec `json:"notifications,omitempty"`
+}
+
+type IngressDefaults struct {
+	// ClassName is the IngressClass of the stats Ingresses of the Domains
+	// without spec.ingress.className.
+	// +optional
+	ClassName string `json:"className,omitempty"`
+
+	// Service is the backend of the stats routes of the Domains without
+	// spec.ingress.service. It must exist in the namespace of each of them.
+	// +optional
+	Service *DomainIngressServiceSpec `json:"service,omitempty"`
+}
+
+type DKIMDefaults struct {
+	// RSAKeyBits is the size of the RSA keys generated from now on.
+	// Defaults to 2048.
+	// +kubebuilder:validation:Minimum=1024
+	// +kubebuilder:validation:Maximum=4096
+	// +optional
+	RSAKeyBits int `json:"rsaKeyBits,omitempty"`
+
+	// MinRSAKeyBits is the smallest generated RSA key the DKIM key check
+	// accepts, overriding the flag of the operator.
+	// +kubebuilder:validation:Minimum=1024
+	// +optional
+	MinRSAKeyBits int `json:"minRSAKeyBits,omitempty"`
+}
+
+type DNSDefaults struct {
+	// Resolvers replace the resolvers of the flags of the operator, in the
+	// format of its DNS mode: host:port addresses for udp and dot, URLs
+	// for doh.
+	// +kubebuilder:validation:MaxItems=16
+	// +optional
+	Resolvers []string `json:"resolvers,omitempty"`
+}
+
+type NotificationsSpec struct {
+	// Slack are incoming webhooks of Slack.
+	// +optional
+	Slack []NotificationSink `json:"slack,omitempty"`
+
+	// Webhooks are endpoints receiving the events as JSON, or the body
+	// rendered by their template.
+	// +optional
+	Webhooks []NotificationSink `json:"webhooks,omitempty"`
+}
+
+type NotificationSink struct {
+	// URL is where the notifications are posted. The incoming webhook URLs
+	// of Slack are secrets: anyone allowed to read the ClusterDomainConfig
+	// can read them.
+	//+kubebuilder:validation:Required
+	URL string `json:"url"`
+
+	// Template is a text/template executed with the event.
+	// +optional
+	Template string `json:"template,omitempty"`
+
+	// Headers are added to the requests.
+	// +optional
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// Events are the types of the events notified, all of them when empty.
+	// +optional
+	Events []string `json:"events,omitempty"`
+}
+
+// ClusterDomainConfigStatus defines the observed state of ClusterDomainConfig
+type ClusterDomainConfigStatus struct {
+	// ObservedGeneration is the generation of the spec last applied.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions are the latest observations of the config state.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+const (
+	// ConditionConfigApplied is True when the resolvers and the
+	// notification sinks of the ClusterDomainConfig are in use.
+	ConditionConfigApplied = "Applied"
+)
+
+const (
+	ReasonConfigApplied = "Applied"
+	ReasonConfigInvalid = "InvalidConfig"
+)
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster,shortName=cdc
+
+// ClusterDomainConfig holds the operator-wide defaults of the Domains
+// +kubebuilder:printcolumn:name="Applied",type=string,JSONPath=`.status.conditions[?(@.type=="Applied")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+type ClusterDomainConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterDomainConfigSpec   `json:"spec,omitempty"`
+	Status ClusterDomainConfigStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ClusterDomainConfigList contains a list of ClusterDomainConfig
+type ClusterDomainConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterDomainConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterDomainConfig{}, &ClusterDomainConfigList{})
+}
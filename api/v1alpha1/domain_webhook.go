@@ -0,0 +1,420 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// log is for logging in this package.
+var domainlog = logf.Log.WithName("domain-resource")
+
+func (r *Domain) SetupWebhookWithManager(mgr ctrl.Manager, defaulter *DomainDefaulter) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		WithDefaulter(defaulter).
+		WithValidator(&DomainValidator{Client: mgr.GetAPIReader()}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-core-k8s-kannon-email-v1alpha1-domain,mutating=true,failurePolicy=fail,sideEffects=None,groups=core.k8s.kannon.email,resources=domains,verbs=create;update,versions=v1alpha1,name=mdomain.kb.io,admissionReviewVersions=v1
+
+// DefaultStatsPrefix is the stats prefix set when neither a prefix nor a
+// stats host is specified.
+const DefaultStatsPrefix = "stats"
+
+// DomainDefaulter fills the optional fields of the Domain spec, so that the
+// stored object shows the values actually in use.
+// +kubebuilder:object:generate=false
+type DomainDefaulter struct {
+	// DefaultIngressClass is set as the ingress class when none is specified.
+	DefaultIngressClass string
+}
+
+var _ admission.CustomDefaulter = &DomainDefaulter{}
+
+// Default implements admission.CustomDefaulter.
+func (d *DomainDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	domain, ok := obj.(*Domain)
+	if !ok {
+		return fmt.Errorf("expected a Domain but got a %T", obj)
+	}
+	domainlog.Info("default", "name", domain.Name)
+
+	spec := &domain.Spec
+	if spec.DKIM.Selector == "" {
+		spec.DKIM.Selector = DefaultDKIMSelector
+	}
+	if spec.StatsPrefix == "" && spec.StatsHost == "" && (spec.Stats == nil || len(spec.Stats.Hosts) == 0 && spec.Stats.SubdomainPrefix == "") {
+		spec.StatsPrefix = DefaultStatsPrefix
+	}
+	if spec.StatsPath == "" {
+		spec.StatsPath = DefaultStatsPath
+	}
+	if spec.Ingress.ClassName == "" {
+		spec.Ingress.ClassName = d.DefaultIngressClass
+	}
+
+	return nil
+}
+
+//+kubebuilder:webhook:path=/validate-core-k8s-kannon-email-v1alpha1-domain,mutating=false,failurePolicy=fail,sideEffects=None,groups=core.k8s.kannon.email,resources=domains,verbs=create;update,versions=v1alpha1,name=vdomain.kb.io,admissionReviewVersions=v1
+
+// DomainValidator rejects malformed Domains and Domains whose domain name is
+// already handled by another Domain in the cluster.
+// +kubebuilder:object:generate=false
+type DomainValidator struct {
+	// Client reads the Domains of the whole cluster. It must bypass the
+	// cache of the manager, which may be restricted to some namespaces and
+	// to the Domains matching a selector: the Domains missing from it would
+	// escape the uniqueness check.
+	Client client.Reader
+}
+
+var _ admission.CustomValidator = &DomainValidator{}
+
+// ValidateCreate implements admission.CustomValidator.
+func (v *DomainValidator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	domain, ok := obj.(*Domain)
+	if !ok {
+		return fmt.Errorf("expected a Domain but got a %T", obj)
+	}
+	domainlog.Info("validate create", "name", domain.Name)
+
+	return v.validate(ctx, domain)
+}
+
+// ValidateUpdate implements admission.CustomValidator.
+func (v *DomainValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) error {
+	domain, ok := newObj.(*Domain)
+	if !ok {
+		return fmt.Errorf("expected a Domain but got a %T", newObj)
+	}
+	domainlog.Info("validate update", "name", domain.Name)
+
+	return v.validate(ctx, domain)
+}
+
+// ValidateDelete implements admission.CustomValidator.
+func (v *DomainValidator) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
+
+func (v *DomainValidator) validate(ctx context.Context, domain *Domain) error {
+	errs := validateDomainSpec(domain.Spec, field.NewPath("spec"))
+
+	if len(errs) == 0 {
+		dup, name, err := v.findDuplicate(ctx, domain)
+		if err != nil {
+			return apierrors.NewInternalError(err)
+		}
+		if dup != nil {
+			errs = append(errs, field.Duplicate(domainNamePath(domain.Spec, name),
+				fmt.Sprintf("%s is already handled by Domain %s/%s", name, dup.Namespace, dup.Name)))
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return apierrors.NewInvalid(GroupVersion.WithKind("Domain").GroupKind(), domain.Name, errs)
+}
+
+// findDuplicate returns another Domain of the cluster handling one of the
+// domain names of domain, its domainName or one of its additionalDomains,
+// and that name.
+func (v *DomainValidator) findDuplicate(ctx context.Context, domain *Domain) (*Domain, string, error) {
+	domains := &DomainList{}
+	if err := v.Client.List(ctx, domains); err != nil {
+		return nil, "", err
+	}
+
+	for i := range domains.Items {
+		other := &domains.Items[i]
+		if other.Namespace == domain.Namespace && other.Name == domain.Name {
+			continue
+		}
+		for _, name := range domain.Spec.DomainNames() {
+			for _, otherName := range other.Spec.DomainNames() {
+				if sameDomainName(otherName, name) {
+					return other, name, nil
+				}
+			}
+		}
+	}
+
+	return nil, "", nil
+}
+
+// domainNamePath returns the path of the field of spec holding name.
+func domainNamePath(spec DomainSpec, name string) *field.Path {
+	for i, additional := range spec.AdditionalDomains {
+		if additional == name {
+			return field.NewPath("spec", "additionalDomains").Index(i)
+		}
+	}
+	return field.NewPath("spec", "domainName")
+}
+
+func sameDomainName(a, b string) bool {
+	return strings.EqualFold(strings.TrimSuffix(a, "."), strings.TrimSuffix(b, "."))
+}
+
+func validateDomainSpec(spec DomainSpec, path *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+
+	errs = append(errs, validateFQDN(spec.DomainName, path.Child("domainName"), true)...)
+	errs = append(errs, validateFQDN(spec.BaseDomain, path.Child("baseDomain"), true)...)
+	errs = append(errs, validateFQDN(spec.StatsHost, path.Child("statsHost"), false)...)
+	errs = append(errs, validateFQDN(spec.BounceHost, path.Child("bounceHost"), false)...)
+
+	switch stats := spec.Stats; {
+	case stats != nil && len(stats.Hosts) > 0:
+		hostsPath := path.Child("stats", "hosts")
+		if spec.StatsHost != "" {
+			errs = append(errs, field.Invalid(hostsPath, stats.Hosts, "cannot be combined with spec.statsHost"))
+		}
+		seenHosts := map[string]bool{}
+		for i, host := range stats.Hosts {
+			errs = append(errs, validateFQDN(host, hostsPath.Index(i), true)...)
+			if key := strings.ToLower(strings.TrimSuffix(host, ".")); seenHosts[key] {
+				errs = append(errs, field.Duplicate(hostsPath.Index(i), host))
+			} else {
+				seenHosts[key] = true
+			}
+		}
+	case spec.StatsHost != "":
+	case stats != nil && stats.SubdomainPrefix != "":
+		errs = append(errs, validateLabel(stats.SubdomainPrefix, path.Child("stats", "subdomainPrefix"))...)
+	default:
+		errs = append(errs, validateLabel(spec.StatsPrefix, path.Child("statsPrefix"))...)
+	}
+
+	if spec.StatsPath != "" && !strings.HasPrefix(spec.StatsPath, "/") {
+		errs = append(errs, field.Invalid(path.Child("statsPath"), spec.StatsPath, "must start with /"))
+	}
+
+	dkimPath := path.Child("dkim")
+	if spec.DKIM.Selector != "" {
+		errs = append(errs, validateLabel(spec.DKIM.Selector, dkimPath.Child("selector"))...)
+	}
+	if _, err := base64.StdEncoding.DecodeString(spec.DKIM.PublicKey); err != nil {
+		errs = append(errs, field.Invalid(dkimPath.Child("publicKey"), spec.DKIM.PublicKey, "must be base64 encoded"))
+	}
+
+	if period := spec.DKIM.RotationPeriod; period != nil {
+		switch {
+		case spec.DKIM.PublicKey != "":
+			errs = append(errs, field.Forbidden(dkimPath.Child("rotationPeriod"), "only generated keys can be rotated, remove publicKey"))
+		case period.Duration <= 0:
+			errs = append(errs, field.Invalid(dkimPath.Child("rotationPeriod"), period.Duration.String(), "must be positive"))
+		}
+	}
+
+	errs = append(errs, validateInterval(spec.CheckInterval, path.Child("checkInterval"), time.Minute, 24*time.Hour)...)
+	errs = append(errs, validateInterval(spec.UnverifiedCheckInterval, path.Child("unverifiedCheckInterval"), 10*time.Second, 24*time.Hour)...)
+
+	if spec.TLS != nil && spec.TLS.SecretName != "" {
+		for _, msg := range validation.IsDNS1123Subdomain(spec.TLS.SecretName) {
+			errs = append(errs, field.Invalid(path.Child("tls", "secretName"), spec.TLS.SecretName, msg))
+		}
+	}
+
+	if spec.RoutingOrDefault() == RoutingGatewayAPI && (spec.Gateway == nil || spec.Gateway.Name == "") {
+		errs = append(errs, field.Required(path.Child("gateway", "name"), "required with gatewayAPI routing"))
+	}
+
+	if spec.DNS != nil && spec.DNS.Provider != nil {
+		providerPath := path.Child("dns", "provider")
+		if spec.DNS.Provider.CredentialsSecretName == "" && spec.DNS.Provider.SecretRef == nil {
+			errs = append(errs, field.Required(providerPath.Child("credentialsSecretName"), "either credentialsSecretName or secretRef is required"))
+		}
+		errs = append(errs, validateCredentialsRef(spec.DNS.Provider.SecretRef, providerPath.Child("secretRef"))...)
+		if spec.DNS.Provider.Name == "route53" && spec.DNS.Provider.Zone == "" {
+			errs = append(errs, field.Required(providerPath.Child("zone"), "the hosted zone ID is required for route53"))
+		}
+	}
+
+	errs = append(errs, validateCredentialsRef(spec.KannonCredentialsRef, path.Child("kannonCredentialsRef"))...)
+
+	if spec.MTASTSEnabled() {
+		mtaSTSPath := path.Child("mtaSTS")
+		if len(spec.MTASTS.MX) == 0 {
+			errs = append(errs, field.Required(mtaSTSPath.Child("mx"), "at least one MX host is required"))
+		}
+		for i, mx := range spec.MTASTS.MX {
+			errs = append(errs, validateFQDN(strings.TrimPrefix(mx, "*."), mtaSTSPath.Child("mx").Index(i), true)...)
+		}
+		if maxAge := spec.MTASTS.MaxAge; maxAge != nil && (maxAge.Duration <= 0 || maxAge.Duration > 365*24*time.Hour) {
+			errs = append(errs, field.Invalid(mtaSTSPath.Child("maxAge"), maxAge.Duration.String(), "must be positive and at most a year"))
+		}
+	}
+
+	if spec.TLSRPT != nil {
+		uriPath := path.Child("tlsRPT", "reportURIs")
+		if len(spec.TLSRPT.ReportURIs) == 0 {
+			errs = append(errs, field.Required(uriPath, "at least one report URI is required"))
+		}
+		for i, uri := range spec.TLSRPT.ReportURIs {
+			if !strings.HasPrefix(uri, "mailto:") && !strings.HasPrefix(uri, "https://") {
+				errs = append(errs, field.Invalid(uriPath.Index(i), uri, "must be a mailto: or https: URI"))
+			}
+		}
+	}
+
+	if spec.BIMI != nil {
+		bimiPath := path.Child("bimi")
+		if spec.BIMI.Selector != "" {
+			for _, msg := range validation.IsDNS1123Label(spec.BIMI.Selector) {
+				errs = append(errs, field.Invalid(bimiPath.Child("selector"), spec.BIMI.Selector, msg))
+			}
+		}
+		if !strings.HasPrefix(spec.BIMI.LogoURL, "https://") {
+			errs = append(errs, field.Invalid(bimiPath.Child("logoURL"), spec.BIMI.LogoURL, "must be an https URL"))
+		}
+		if vmc := spec.BIMI.VMCURL; vmc != "" && !strings.HasPrefix(vmc, "https://") {
+			errs = append(errs, field.Invalid(bimiPath.Child("vmcURL"), vmc, "must be an https URL"))
+		}
+	}
+
+	seenIPs := map[string]bool{}
+	for i, ip := range spec.SendingIPs {
+		ipPath := path.Child("sendingIPs").Index(i)
+		parsed := net.ParseIP(ip)
+		switch {
+		case parsed == nil:
+			errs = append(errs, field.Invalid(ipPath, ip, "must be an IPv4 or IPv6 address"))
+		case seenIPs[parsed.String()]:
+			errs = append(errs, field.Duplicate(ipPath, ip))
+		}
+		if parsed != nil {
+			seenIPs[parsed.String()] = true
+		}
+	}
+
+	for i, additional := range spec.AdditionalDomains {
+		additionalPath := path.Child("additionalDomains").Index(i)
+		errs = append(errs, validateFQDN(additional, additionalPath, true)...)
+		if sameDomainName(additional, spec.DomainName) {
+			errs = append(errs, field.Invalid(additionalPath, additional, "must not be the domainName"))
+		}
+		for _, previous := range spec.AdditionalDomains[:i] {
+			if sameDomainName(additional, previous) {
+				errs = append(errs, field.Duplicate(additionalPath, additional))
+				break
+			}
+		}
+	}
+
+	if spec.Ingress.IsEnabled() {
+		// without a service, the one of the ClusterDomainConfig is used
+		if spec.Ingress.Service != (DomainIngressServiceSpec{}) {
+			servicePath := path.Child("ingress", "service")
+			for _, msg := range validation.IsDNS1035Label(spec.Ingress.Service.Name) {
+				errs = append(errs, field.Invalid(servicePath.Child("name"), spec.Ingress.Service.Name, msg))
+			}
+			for _, msg := range validation.IsValidPortNum(int(spec.Ingress.Service.Port)) {
+				errs = append(errs, field.Invalid(servicePath.Child("port"), spec.Ingress.Service.Port, msg))
+			}
+		}
+		for i, host := range spec.Ingress.ExtraHosts {
+			errs = append(errs, validateFQDN(host, path.Child("ingress", "extraHosts").Index(i), true)...)
+		}
+		for i, p := range spec.Ingress.ExtraPaths {
+			if !strings.HasPrefix(p, "/") {
+				errs = append(errs, field.Invalid(path.Child("ingress", "extraPaths").Index(i), p, "must start with /"))
+			}
+		}
+		if stats := spec.Stats; stats != nil {
+			seen := map[string]bool{}
+			for i, p := range stats.Paths {
+				switch {
+				case !strings.HasPrefix(p, "/"):
+					errs = append(errs, field.Invalid(path.Child("stats", "paths").Index(i), p, "must start with /"))
+				case seen[p]:
+					errs = append(errs, field.Duplicate(path.Child("stats", "paths").Index(i), p))
+				}
+				seen[p] = true
+			}
+			errs = append(errs, validateFQDN(stats.RewriteHost, path.Child("stats", "rewriteHost"), false)...)
+		}
+	}
+
+	return errs
+}
+
+func validateInterval(interval *metav1.Duration, path *field.Path, min, max time.Duration) field.ErrorList {
+	if interval == nil || (interval.Duration >= min && interval.Duration <= max) {
+		return nil
+	}
+
+	return field.ErrorList{field.Invalid(path, interval.Duration.String(), fmt.Sprintf("must be between %s and %s", min, max))}
+}
+
+// validateCredentialsRef checks the name of the referenced Secret and the
+// keys its credentials are mapped to.
+func validateCredentialsRef(ref *CredentialsSecretReference, path *field.Path) field.ErrorList {
+	if ref == nil {
+		return nil
+	}
+
+	errs := field.ErrorList{}
+	for _, msg := range validation.IsDNS1123Subdomain(ref.Name) {
+		errs = append(errs, field.Invalid(path.Child("name"), ref.Name, msg))
+	}
+	for credential, key := range ref.Keys {
+		for _, msg := range validation.IsConfigMapKey(key) {
+			errs = append(errs, field.Invalid(path.Child("keys").Key(credential), key, msg))
+		}
+	}
+	return errs
+}
+
+func validateFQDN(value string, path *field.Path, required bool) field.ErrorList {
+	if value == "" {
+		if required {
+			return field.ErrorList{field.Required(path, "")}
+		}
+		return nil
+	}
+
+	return validation.IsFullyQualifiedDomainName(path, strings.TrimSuffix(value, "."))
+}
+
+func validateLabel(value string, path *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	for _, msg := range validation.IsDNS1123Label(value) {
+		errs = append(errs, field.Invalid(path, value, msg))
+	}
+	return errs
+}
@@ -0,0 +1,228 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestDefaultDomain(t *testing.T) {
+	domain := createDomain("example", "default", "example.com")
+	domain.Spec.DKIM.Selector = ""
+	domain.Spec.StatsPrefix = ""
+
+	d := &DomainDefaulter{DefaultIngressClass: "nginx"}
+	require.NoError(t, d.Default(context.Background(), domain))
+
+	assert.Equal(t, DefaultDKIMSelector, domain.Spec.DKIM.Selector)
+	assert.Equal(t, DefaultStatsPrefix, domain.Spec.StatsPrefix)
+	assert.Equal(t, DefaultStatsPath, domain.Spec.StatsPath)
+	assert.Equal(t, "nginx", domain.Spec.Ingress.ClassName)
+
+	// explicit values are kept
+	domain.Spec.Ingress.ClassName = "traefik"
+	domain.Spec.StatsPath = "/"
+	require.NoError(t, d.Default(context.Background(), domain))
+
+	assert.Equal(t, "traefik", domain.Spec.Ingress.ClassName)
+	assert.Equal(t, "/", domain.Spec.StatsPath)
+}
+
+func TestValidateDomain(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(*Domain)
+		invalid string
+	}{
+		{"valid", func(d *Domain) {}, ""},
+		{"empty base domain", func(d *Domain) { d.Spec.BaseDomain = "" }, "spec.baseDomain"},
+		{"malformed domain name", func(d *Domain) { d.Spec.DomainName = "example_com" }, "spec.domainName"},
+		{"single label domain name", func(d *Domain) { d.Spec.DomainName = "localhost" }, "spec.domainName"},
+		{"invalid selector", func(d *Domain) { d.Spec.DKIM.Selector = "not a selector" }, "spec.dkim.selector"},
+		{"invalid public key", func(d *Domain) { d.Spec.DKIM.PublicKey = "not base64!" }, "spec.dkim.publicKey"},
+		{"rotation of a provided key", func(d *Domain) {
+			d.Spec.DKIM.RotationPeriod = &metav1.Duration{Duration: time.Hour}
+		}, "spec.dkim.rotationPeriod"},
+		{"too frequent checks", func(d *Domain) {
+			d.Spec.CheckInterval = &metav1.Duration{Duration: time.Second}
+		}, "spec.checkInterval"},
+		{"too rare unverified checks", func(d *Domain) {
+			d.Spec.UnverifiedCheckInterval = &metav1.Duration{Duration: 48 * time.Hour}
+		}, "spec.unverifiedCheckInterval"},
+		{"relative stats path", func(d *Domain) { d.Spec.StatsPath = "stats" }, "spec.statsPath"},
+		{"missing ingress port", func(d *Domain) { d.Spec.Ingress.Service.Port = 0 }, "spec.ingress.service.port"},
+		{"missing ingress service name", func(d *Domain) { d.Spec.Ingress.Service.Name = "" }, "spec.ingress.service.name"},
+		{"ingress service of the cluster config", func(d *Domain) { d.Spec.Ingress.Service = DomainIngressServiceSpec{} }, ""},
+		{"invalid extra host", func(d *Domain) {
+			d.Spec.Ingress.ExtraHosts = []string{"stats.example.org", "not a host"}
+		}, "spec.ingress.extraHosts[1]"},
+		{"stats hosts", func(d *Domain) {
+			d.Spec.Stats = &DomainStatsSpec{Hosts: []string{"click.example.com", "open.example.com"}}
+		}, ""},
+		{"duplicate stats host", func(d *Domain) {
+			d.Spec.Stats = &DomainStatsSpec{Hosts: []string{"click.example.com", "Click.example.com."}}
+		}, "spec.stats.hosts[1]"},
+		{"stats hosts with a stats host", func(d *Domain) {
+			d.Spec.StatsHost = "track.example.com"
+			d.Spec.Stats = &DomainStatsSpec{Hosts: []string{"click.example.com"}}
+		}, "spec.stats.hosts"},
+		{"invalid stats subdomain prefix", func(d *Domain) {
+			d.Spec.Stats = &DomainStatsSpec{SubdomainPrefix: "not a label"}
+		}, "spec.stats.subdomainPrefix"},
+		{"gateway routing without gateway", func(d *Domain) { d.Spec.Routing = RoutingGatewayAPI }, "spec.gateway.name"},
+		{"route53 without zone", func(d *Domain) {
+			d.Spec.DNS = &DomainDNSSpec{AutoProvision: true, Provider: &DNSProviderSpec{Name: "route53", CredentialsSecretName: "aws"}}
+		}, "spec.dns.provider.zone"},
+		{"dns provider without credentials", func(d *Domain) {
+			d.Spec.DNS = &DomainDNSSpec{AutoProvision: true, Provider: &DNSProviderSpec{Name: "cloudflare"}}
+		}, "spec.dns.provider.credentialsSecretName"},
+		{"dns provider with a secret reference", func(d *Domain) {
+			d.Spec.DNS = &DomainDNSSpec{AutoProvision: true, Provider: &DNSProviderSpec{
+				Name:      "cloudflare",
+				SecretRef: &CredentialsSecretReference{Name: "cloudflare", Keys: map[string]string{"api-token": "CF_API_TOKEN"}},
+			}}
+		}, ""},
+		{"invalid mapped credential key", func(d *Domain) {
+			d.Spec.KannonCredentialsRef = &CredentialsSecretReference{Name: "kannon", Keys: map[string]string{"key": "not a key"}}
+		}, "spec.kannonCredentialsRef.keys[key]"},
+		{"relative stats route path", func(d *Domain) {
+			d.Spec.Stats = &DomainStatsSpec{Paths: []string{"/o/", "c/"}}
+		}, "spec.stats.paths[1]"},
+		{"duplicate stats route path", func(d *Domain) {
+			d.Spec.Stats = &DomainStatsSpec{Paths: []string{"/o/", "/o/"}}
+		}, "spec.stats.paths[1]"},
+		{"invalid rewrite host", func(d *Domain) {
+			d.Spec.Stats = &DomainStatsSpec{Paths: []string{"/o/"}, RewriteHost: "stats_backend"}
+		}, "spec.stats.rewriteHost"},
+		{"relative extra path", func(d *Domain) { d.Spec.Ingress.ExtraPaths = []string{"open"} }, "spec.ingress.extraPaths[0]"},
+		{"mta-sts without mx", func(d *Domain) { d.Spec.MTASTS = &MTASTSSpec{Enabled: true} }, "spec.mtaSTS.mx"},
+		{"mta-sts with wildcard mx", func(d *Domain) {
+			d.Spec.MTASTS = &MTASTSSpec{Enabled: true, MX: []string{"*.mail.example.com"}}
+		}, ""},
+		{"tls-rpt with http uri", func(d *Domain) {
+			d.Spec.TLSRPT = &TLSRPTSpec{ReportURIs: []string{"mailto:tls@example.com", "http://example.com/tls"}}
+		}, "spec.tlsRPT.reportURIs[1]"},
+		{"bimi with http vmc", func(d *Domain) {
+			d.Spec.BIMI = &BIMISpec{LogoURL: "https://example.com/logo.svg", VMCURL: "http://example.com/vmc.pem"}
+		}, "spec.bimi.vmcURL"},
+		{"invalid sending ip", func(d *Domain) { d.Spec.SendingIPs = []string{"192.0.2.1", "mx.example.com"} }, "spec.sendingIPs[1]"},
+		{"duplicate sending ip", func(d *Domain) { d.Spec.SendingIPs = []string{"2001:db8::1", "2001:DB8::1"} }, "spec.sendingIPs[1]"},
+		{"additional domains", func(d *Domain) { d.Spec.AdditionalDomains = []string{"news.example.com", "example.org"} }, ""},
+		{"invalid additional domain", func(d *Domain) { d.Spec.AdditionalDomains = []string{"*.example.com"} }, "spec.additionalDomains[0]"},
+		{"additional domain is the domain name", func(d *Domain) { d.Spec.AdditionalDomains = []string{"Example.com."} }, "spec.additionalDomains[0]"},
+		{"duplicate additional domain", func(d *Domain) {
+			d.Spec.AdditionalDomains = []string{"news.example.com", "NEWS.example.com"}
+		}, "spec.additionalDomains[1]"},
+		{"disabled ingress", func(d *Domain) {
+			disabled := false
+			d.Spec.Ingress = DomainIngressSpec{Enabled: &disabled}
+		}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			domain := createDomain("example", "default", "example.com")
+			tt.mutate(domain)
+
+			err := createValidator(t).ValidateCreate(context.Background(), domain)
+			if tt.invalid == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			require.True(t, apierrors.IsInvalid(err), "should be an invalid error: %v", err)
+			assert.Contains(t, err.Error(), tt.invalid)
+		})
+	}
+}
+
+func TestValidateDomainNameIsUnique(t *testing.T) {
+	existing := createDomain("example", "team-a", "example.com")
+	v := createValidator(t, existing)
+
+	err := v.ValidateCreate(context.Background(), createDomain("example", "team-b", "example.com."))
+	require.True(t, apierrors.IsInvalid(err), "should reject a duplicate domain name: %v", err)
+	assert.Contains(t, err.Error(), "team-a/example")
+
+	assert.NoError(t, v.ValidateCreate(context.Background(), createDomain("other", "team-b", "other.com")))
+	assert.NoError(t, v.ValidateUpdate(context.Background(), existing, existing), "should not conflict with itself")
+}
+
+func TestValidateAdditionalDomainsAreUnique(t *testing.T) {
+	existing := createDomain("example", "team-a", "example.com")
+	existing.Spec.AdditionalDomains = []string{"news.example.com"}
+	v := createValidator(t, existing)
+
+	err := v.ValidateCreate(context.Background(), createDomain("news", "team-b", "news.example.com"))
+	require.True(t, apierrors.IsInvalid(err), "should reject a domain name handled as an additional domain: %v", err)
+	assert.Contains(t, err.Error(), "spec.domainName")
+	assert.Contains(t, err.Error(), "team-a/example")
+
+	other := createDomain("other", "team-b", "other.com")
+	other.Spec.AdditionalDomains = []string{"alerts.other.com", "example.com"}
+	err = v.ValidateCreate(context.Background(), other)
+	require.True(t, apierrors.IsInvalid(err), "should reject an additional domain handled by another Domain: %v", err)
+	assert.Contains(t, err.Error(), "spec.additionalDomains[1]")
+
+	existing.Spec.AdditionalDomains = append(existing.Spec.AdditionalDomains, "alerts.example.com")
+	assert.NoError(t, v.ValidateUpdate(context.Background(), existing, existing), "should not conflict with itself")
+}
+
+func createValidator(t *testing.T, objs ...client.Object) *DomainValidator {
+	t.Helper()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, AddToScheme(scheme))
+
+	return &DomainValidator{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build(),
+	}
+}
+
+func createDomain(name, namespace, domainName string) *Domain {
+	return &Domain{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: DomainSpec{
+			DomainName:  domainName,
+			BaseDomain:  "mx.kannon.email",
+			StatsPrefix: "stats",
+			DKIM: DKIM{
+				Selector:  "kannon",
+				PublicKey: "cHVibGljS2V5",
+			},
+			Ingress: DomainIngressSpec{
+				Service: DomainIngressServiceSpec{
+					Name: "kannon-stats",
+					Port: 80,
+				},
+			},
+		},
+	}
+}
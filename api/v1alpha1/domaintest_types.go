@@ -0,0 +1,213 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DomainTestSpec defines the desired state of DomainTest
+type DomainTestSpec struct {
+	// DomainRef is the Domain of the namespace the test message is sent
+	// from. The Domain must be registered with Kannon.
+	//+kubebuilder:validation:Required
+	DomainRef DomainReference `json:"domainRef"`
+
+	// Sender is the local part of the address the message is sent from.
+	// Defaults to k8nnon-test.
+	// +kubebuilder:validation:MaxLength=64
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9._+-]+$`
+	// +optional
+	Sender string `json:"sender,omitempty"`
+
+	// To is the seed address the message is sent to. The mail of its
+	// domain must be delivered to the SMTP sink of the operator, where the
+	// message is checked. Defaults to an address of the sink domain.
+	// +optional
+	To string `json:"to,omitempty"`
+
+	// Timeout is how long the message is awaited after the test starts.
+	// Defaults to 10 minutes.
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+const (
+	// DefaultDomainTestSender is the local part the test messages are sent
+	// from when spec.sender is not set.
+	DefaultDomainTestSender = "k8nnon-test"
+
+	// DefaultDomainTestTimeout is how long a test message is awaited when
+	// spec.timeout is not set.
+	DefaultDomainTestTimeout = 10 * time.Minute
+)
+
+// SenderOrDefault returns the local part the message is sent from.
+func (s DomainTestSpec) SenderOrDefault() string {
+	if s.Sender != "" {
+		return s.Sender
+	}
+	return DefaultDomainTestSender
+}
+
+// TimeoutOrDefault returns how long the message is awaited.
+func (s DomainTestSpec) TimeoutOrDefault() time.Duration {
+	if s.Timeout != nil && s.Timeout.Duration > 0 {
+		return s.Timeout.Duration
+	}
+	return DefaultDomainTestTimeout
+}
+
+// DomainTestStatus defines the observed state of DomainTest
+type DomainTestStatus struct {
+	// ObservedGeneration is the generation of the spec tested.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Token identifies the test message, it is sent in its subject.
+	// +optional
+	Token string `json:"token,omitempty"`
+
+	// Recipient is the address the message was sent to.
+	// +optional
+	Recipient string `json:"recipient,omitempty"`
+
+	// MessageID is the Kannon identifier of the message.
+	// +optional
+	MessageID string `json:"messageID,omitempty"`
+
+	// StartTime is when the test of the generation started.
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// SendingTime is when the message was handed to Kannon. It is recorded
+	// before the message is sent, so that a message whose sending was not
+	// recorded is awaited rather than sent again.
+	// +optional
+	SendingTime *metav1.Time `json:"sendingTime,omitempty"`
+
+	// SentTime is when Kannon accepted the message.
+	// +optional
+	SentTime *metav1.Time `json:"sentTime,omitempty"`
+
+	// ReceivedTime is when the SMTP sink received the message.
+	// +optional
+	ReceivedTime *metav1.Time `json:"receivedTime,omitempty"`
+
+	// DKIM is the verification of the DKIM signatures of the received
+	// message.
+	// +optional
+	DKIM *MessageAuthResult `json:"dkim,omitempty"`
+
+	// SPF is the authorization of the sending address by the SPF record
+	// of the MAIL FROM domain.
+	// +optional
+	SPF *MessageAuthResult `json:"spf,omitempty"`
+
+	// DMARC is the alignment of the authenticated domains with the domain
+	// of the From header.
+	// +optional
+	DMARC *MessageAuthResult `json:"dmarc,omitempty"`
+
+	// Conditions are the latest observations of the test state.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+type MessageAuthResult struct {
+	// Result is pass, fail or none.
+	// +kubebuilder:validation:Enum=pass;fail;none
+	Result string `json:"result"`
+
+	// Domain is the authenticated domain: the signing domain for DKIM, the
+	// MAIL FROM domain for SPF and the From domain for DMARC.
+	// +optional
+	Domain string `json:"domain,omitempty"`
+
+	// Selector is the selector of the verified DKIM signature.
+	// +optional
+	Selector string `json:"selector,omitempty"`
+
+	// IP is the address the message was received from, checked by SPF.
+	// +optional
+	IP string `json:"ip,omitempty"`
+
+	// Message explains the result.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// Results of the message authentication checks.
+const (
+	AuthResultPass = "pass"
+	AuthResultFail = "fail"
+	AuthResultNone = "none"
+)
+
+const (
+	// ConditionTestPassed is True when the test message was received with
+	// a valid DKIM signature, an authorized sending address and an aligned
+	// From domain. It is Unknown while the message is awaited.
+	ConditionTestPassed = "Passed"
+)
+
+const (
+	ReasonTestPending             = "Pending"
+	ReasonTestSending             = "Sending"
+	ReasonTestSent                = "Sent"
+	ReasonTestPassed              = "Passed"
+	ReasonTestFailed              = "AuthenticationFailed"
+	ReasonTestTimedOut            = "TimedOut"
+	ReasonTestSendFailed          = "SendFailed"
+	ReasonTestDomainNotFound      = "DomainNotFound"
+	ReasonTestDomainNotRegistered = "DomainNotRegistered"
+)
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:shortName=dt
+
+// DomainTest sends a test message from a Domain and checks its
+// authentication on receipt. A change of the spec runs the test again
+// +kubebuilder:printcolumn:name="Domain",type=string,JSONPath=`.spec.domainRef.name`
+// +kubebuilder:printcolumn:name="Passed",type=string,JSONPath=`.status.conditions[?(@.type=="Passed")].status`
+// +kubebuilder:printcolumn:name="DKIM",type=string,JSONPath=`.status.dkim.result`
+// +kubebuilder:printcolumn:name="SPF",type=string,JSONPath=`.status.spf.result`
+// +kubebuilder:printcolumn:name="DMARC",type=string,JSONPath=`.status.dmarc.result`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+type DomainTest struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DomainTestSpec   `json:"spec,omitempty"`
+	Status DomainTestStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// DomainTestList contains a list of DomainTest
+type DomainTestList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DomainTest `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DomainTest{}, &DomainTestList{})
+}
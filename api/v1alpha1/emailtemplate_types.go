@@ -0,0 +1,118 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EmailTemplateSpec defines the desired state of EmailTemplate
+type EmailTemplateSpec struct {
+	// DomainRef is the Domain of the namespace the template belongs to.
+	// The Domain must be registered with Kannon.
+	//+kubebuilder:validation:Required
+	DomainRef DomainReference `json:"domainRef"`
+
+	// Title is the title of the template in Kannon. Defaults to the name
+	// of the EmailTemplate.
+	// +optional
+	Title string `json:"title,omitempty"`
+
+	// HTML is the body of the template. Exactly one of html and htmlFrom
+	// must be set.
+	// +optional
+	HTML string `json:"html,omitempty"`
+
+	// HTMLFrom is the key of a ConfigMap of the namespace holding the body
+	// of the template.
+	// +optional
+	HTMLFrom *corev1.ConfigMapKeySelector `json:"htmlFrom,omitempty"`
+}
+
+// TitleOrDefault returns the title of the template in Kannon.
+func (t *EmailTemplate) TitleOrDefault() string {
+	if t.Spec.Title != "" {
+		return t.Spec.Title
+	}
+	return t.Name
+}
+
+// EmailTemplateStatus defines the observed state of EmailTemplate
+type EmailTemplateStatus struct {
+	// ObservedGeneration is the generation of the spec last synced.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// TemplateID is the Kannon identifier of the template.
+	// +optional
+	TemplateID string `json:"templateID,omitempty"`
+
+	// Domain is the domain name the template is synced to.
+	// +optional
+	Domain string `json:"domain,omitempty"`
+
+	// ContentHash is the SHA-256 of the title and the body last synced.
+	// The template is only uploaded again when it changes.
+	// +optional
+	ContentHash string `json:"contentHash,omitempty"`
+
+	// LastSyncTime is when the template was last uploaded.
+	// +optional
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+
+	// Conditions are the latest observations of the template state.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+const (
+	ReasonTemplateSynced         = "Synced"
+	ReasonTemplateSyncFailed     = "SyncFailed"
+	ReasonTemplateInvalid        = "InvalidTemplate"
+	ReasonTemplateDomainNotFound = "DomainNotFound"
+)
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+
+// EmailTemplate is an email template of a Domain synced to Kannon
+// +kubebuilder:printcolumn:name="Domain",type=string,JSONPath=`.status.domain`
+// +kubebuilder:printcolumn:name="Template ID",type=string,JSONPath=`.status.templateID`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Last Sync",type=date,JSONPath=`.status.lastSyncTime`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+type EmailTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   EmailTemplateSpec   `json:"spec,omitempty"`
+	Status EmailTemplateStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// EmailTemplateList contains a list of EmailTemplate
+type EmailTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []EmailTemplate `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&EmailTemplate{}, &EmailTemplateList{})
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// IPWarmupSpec defines the desired state of IPWarmup
+type IPWarmupSpec struct {
+	// IPs are the sending addresses being warmed up. Each of them gets the
+	// daily limit of the current phase.
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:MaxItems=256
+	IPs []string `json:"ips"`
+
+	// StartTime is when the first phase starts. The days of the schedule
+	// are counted from it.
+	//+kubebuilder:validation:Required
+	StartTime metav1.Time `json:"startTime"`
+
+	// Schedule are the phases of the warm-up, in order. Once the last one
+	// ends, the daily limit is removed.
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:MaxItems=64
+	Schedule []IPWarmupPhase `json:"schedule"`
+}
+
+type IPWarmupPhase struct {
+	// Days is how long the phase lasts, e.g. 7 for a week.
+	// +kubebuilder:validation:Minimum=1
+	Days int32 `json:"days"`
+
+	// DailyLimit is how many messages each address can send per day during
+	// the phase.
+	// +kubebuilder:validation:Minimum=1
+	DailyLimit int64 `json:"dailyLimit"`
+}
+
+// ScheduleDays returns how many days the whole schedule lasts.
+func (s IPWarmupSpec) ScheduleDays() int32 {
+	var days int32
+	for _, phase := range s.Schedule {
+		days += phase.Days
+	}
+	return days
+}
+
+// IPWarmupState is where an IPWarmup is in its schedule.
+type IPWarmupState string
+
+const (
+	IPWarmupPending   IPWarmupState = "Pending"
+	IPWarmupWarmingUp IPWarmupState = "WarmingUp"
+	IPWarmupCompleted IPWarmupState = "Completed"
+)
+
+// IPWarmupStatus defines the observed state of IPWarmup
+type IPWarmupStatus struct {
+	// ObservedGeneration is the generation of the spec last applied.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// State is Pending before spec.startTime, WarmingUp during the
+	// schedule and Completed after it.
+	// +optional
+	State IPWarmupState `json:"state,omitempty"`
+
+	// Phase is the current phase, starting from 1. It is 0 outside of the
+	// schedule.
+	// +optional
+	Phase int32 `json:"phase"`
+
+	// RemainingDays is how many days of the schedule are left, including
+	// today.
+	// +optional
+	RemainingDays int32 `json:"remainingDays"`
+
+	// DailyLimit is today's limit of each address, 0 when there is none.
+	// +optional
+	DailyLimit int64 `json:"dailyLimit"`
+
+	// LastSyncTime is when the limit was last pushed to Kannon.
+	// +optional
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+
+	// Conditions are the latest observations of the warm-up state.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+const (
+	// ConditionLimitSynced is True when today's limit is set on every
+	// address by the Kannon API. It is only reported when the Kannon
+	// integration is enabled.
+	ConditionLimitSynced = "LimitSynced"
+)
+
+const (
+	ReasonLimitSynced     = "Synced"
+	ReasonLimitSyncFailed = "SyncFailed"
+	ReasonWarmupPending   = "Pending"
+)
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:shortName=ipw
+
+// IPWarmup ramps up the daily volume of sending addresses on a schedule
+// +kubebuilder:printcolumn:name="State",type=string,JSONPath=`.status.state`
+// +kubebuilder:printcolumn:name="Phase",type=integer,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Daily Limit",type=integer,JSONPath=`.status.dailyLimit`
+// +kubebuilder:printcolumn:name="Remaining Days",type=integer,JSONPath=`.status.remainingDays`
+// +kubebuilder:printcolumn:name="Synced",type=string,JSONPath=`.status.conditions[?(@.type=="LimitSynced")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+type IPWarmup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   IPWarmupSpec   `json:"spec,omitempty"`
+	Status IPWarmupStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// IPWarmupList contains a list of IPWarmup
+type IPWarmupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []IPWarmup `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&IPWarmup{}, &IPWarmupList{})
+}
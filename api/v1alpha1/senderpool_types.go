@@ -0,0 +1,147 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SenderPoolSpec defines the desired state of SenderPool
+type SenderPoolSpec struct {
+	// IPs are the sending addresses of the pool. An address belongs to a
+	// single pool of the namespace.
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:MaxItems=256
+	IPs []string `json:"ips"`
+
+	// ReverseDomain is the domain the PTR records of the addresses must
+	// name hosts under, e.g. mx.kannon.email. The hosts must resolve back
+	// to the addresses.
+	//+kubebuilder:validation:Required
+	ReverseDomain string `json:"reverseDomain"`
+
+	// SPFDomain is the domain whose SPF record must authorize every
+	// address, usually the one the Domains include, e.g. spf.kannon.email.
+	//+kubebuilder:validation:Required
+	SPFDomain string `json:"spfDomain"`
+
+	// CheckInterval is how often the addresses are checked. Defaults to an
+	// hour.
+	// +optional
+	CheckInterval *metav1.Duration `json:"checkInterval,omitempty"`
+}
+
+// DefaultSenderPoolCheckInterval is how often the addresses of a pool are
+// checked when spec.checkInterval is not set.
+const DefaultSenderPoolCheckInterval = time.Hour
+
+// CheckIntervalOrDefault returns how often the addresses are checked.
+func (s SenderPoolSpec) CheckIntervalOrDefault() time.Duration {
+	if s.CheckInterval != nil && s.CheckInterval.Duration > 0 {
+		return s.CheckInterval.Duration
+	}
+	return DefaultSenderPoolCheckInterval
+}
+
+// SenderPoolStatus defines the observed state of SenderPool
+type SenderPoolStatus struct {
+	// ObservedGeneration is the generation of the spec last checked.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// LastCheckTime is when the addresses were last checked.
+	// +optional
+	LastCheckTime *metav1.Time `json:"lastCheckTime,omitempty"`
+
+	// IPs are the checks of the valid addresses of spec.ips, in the same
+	// order.
+	// +optional
+	IPs []SenderPoolIPStatus `json:"ips,omitempty"`
+
+	// Domains is how many Domains of the namespace reference the pool.
+	// +optional
+	Domains int `json:"domains"`
+
+	// Conditions are the latest observations of the pool state.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+type SenderPoolIPStatus struct {
+	// IP is the checked address.
+	IP string `json:"ip"`
+
+	// PTR is the check of the reverse DNS of the address.
+	PTR DNSStatusStats `json:"ptr"`
+
+	// Host is the name of the PTR record found resolving back to the
+	// address.
+	// +optional
+	Host string `json:"host,omitempty"`
+
+	// SPF is the check of the authorization of the address by the SPF
+	// record of spec.spfDomain.
+	SPF DNSStatusStats `json:"spf"`
+}
+
+const (
+	// ConditionSenderPoolReady is True on a SenderPool when the reverse
+	// DNS and the SPF authorization of every address are verified. On a
+	// Domain it mirrors the readiness of the referenced pool.
+	ConditionSenderPoolReady = "SenderPoolReady"
+)
+
+const (
+	ReasonSenderPoolVerified    = "Verified"
+	ReasonSenderPoolNotVerified = "NotVerified"
+	ReasonInvalidMembers        = "InvalidMembers"
+	ReasonSenderPoolNotFound    = "SenderPoolNotFound"
+)
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:shortName=sp
+
+// SenderPool is a pool of sending addresses shared by Domains
+// +kubebuilder:printcolumn:name="Reverse Domain",type=string,JSONPath=`.spec.reverseDomain`
+// +kubebuilder:printcolumn:name="SPF Domain",type=string,JSONPath=`.spec.spfDomain`
+// +kubebuilder:printcolumn:name="Domains",type=integer,JSONPath=`.status.domains`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="SenderPoolReady")].status`
+// +kubebuilder:printcolumn:name="Last Check",type=date,JSONPath=`.status.lastCheckTime`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+type SenderPool struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SenderPoolSpec   `json:"spec,omitempty"`
+	Status SenderPoolStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// SenderPoolList contains a list of SenderPool
+type SenderPoolList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SenderPool `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SenderPool{}, &SenderPoolList{})
+}
@@ -22,78 +22,243 @@ limitations under the License.
 package v1alpha1
 
 import (
-	runtime "k8s.io/apimachinery/pkg/runtime"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *DKim) DeepCopyInto(out *DKim) {
+func (in *AdditionalDomainStatus) DeepCopyInto(out *AdditionalDomainStatus) {
 	*out = *in
+	in.DKIM.DeepCopyInto(&out.DKIM)
+	in.SPF.DeepCopyInto(&out.SPF)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DKim.
-func (in *DKim) DeepCopy() *DKim {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdditionalDomainStatus.
+func (in *AdditionalDomainStatus) DeepCopy() *AdditionalDomainStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(DKim)
+	out := new(AdditionalDomainStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *DNSStatus) DeepCopyInto(out *DNSStatus) {
+func (in *ApiKey) DeepCopyInto(out *ApiKey) {
 	*out = *in
-	out.Stats = in.Stats
-	out.DKIM = in.DKIM
-	out.SFP = in.SFP
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DNSStatus.
-func (in *DNSStatus) DeepCopy() *DNSStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApiKey.
+func (in *ApiKey) DeepCopy() *ApiKey {
 	if in == nil {
 		return nil
 	}
-	out := new(DNSStatus)
+	out := new(ApiKey)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ApiKey) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *DNSStatusStats) DeepCopyInto(out *DNSStatusStats) {
+func (in *ApiKeyList) DeepCopyInto(out *ApiKeyList) {
 	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ApiKey, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DNSStatusStats.
-func (in *DNSStatusStats) DeepCopy() *DNSStatusStats {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApiKeyList.
+func (in *ApiKeyList) DeepCopy() *ApiKeyList {
 	if in == nil {
 		return nil
 	}
-	out := new(DNSStatusStats)
+	out := new(ApiKeyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ApiKeyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApiKeySecretSpec) DeepCopyInto(out *ApiKeySecretSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApiKeySecretSpec.
+func (in *ApiKeySecretSpec) DeepCopy() *ApiKeySecretSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ApiKeySecretSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Domain) DeepCopyInto(out *Domain) {
+func (in *ApiKeySpec) DeepCopyInto(out *ApiKeySpec) {
+	*out = *in
+	out.DomainRef = in.DomainRef
+	if in.Secret != nil {
+		in, out := &in.Secret, &out.Secret
+		*out = new(ApiKeySecretSpec)
+		**out = **in
+	}
+	if in.RotateAfter != nil {
+		in, out := &in.RotateAfter, &out.RotateAfter
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.RotationOverlap != nil {
+		in, out := &in.RotationOverlap, &out.RotationOverlap
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApiKeySpec.
+func (in *ApiKeySpec) DeepCopy() *ApiKeySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ApiKeySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApiKeyStatus) DeepCopyInto(out *ApiKeyStatus) {
+	*out = *in
+	if in.IssuedAt != nil {
+		in, out := &in.IssuedAt, &out.IssuedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Retiring != nil {
+		in, out := &in.Retiring, &out.Retiring
+		*out = make([]RetiringApiKey, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApiKeyStatus.
+func (in *ApiKeyStatus) DeepCopy() *ApiKeyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ApiKeyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BIMISpec) DeepCopyInto(out *BIMISpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BIMISpec.
+func (in *BIMISpec) DeepCopy() *BIMISpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BIMISpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BIMIStatus) DeepCopyInto(out *BIMIStatus) {
+	*out = *in
+	in.DNSStatusStats.DeepCopyInto(&out.DNSStatusStats)
+	in.Indicator.DeepCopyInto(&out.Indicator)
+	if in.VMC != nil {
+		in, out := &in.VMC, &out.VMC
+		*out = new(DNSStatusStats)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BIMIStatus.
+func (in *BIMIStatus) DeepCopy() *BIMIStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BIMIStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertificateStatus) DeepCopyInto(out *CertificateStatus) {
+	*out = *in
+	if in.NotAfter != nil {
+		in, out := &in.NotAfter, &out.NotAfter
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertificateStatus.
+func (in *CertificateStatus) DeepCopy() *CertificateStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CertificateStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterDomainConfig) DeepCopyInto(out *ClusterDomainConfig) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Domain.
-func (in *Domain) DeepCopy() *Domain {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterDomainConfig.
+func (in *ClusterDomainConfig) DeepCopy() *ClusterDomainConfig {
 	if in == nil {
 		return nil
 	}
-	out := new(Domain)
+	out := new(ClusterDomainConfig)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *Domain) DeepCopyObject() runtime.Object {
+func (in *ClusterDomainConfig) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -101,26 +266,109 @@ func (in *Domain) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *DomainIngressServiceSpec) DeepCopyInto(out *DomainIngressServiceSpec) {
+func (in *ClusterDomainConfigList) DeepCopyInto(out *ClusterDomainConfigList) {
 	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ClusterDomainConfig, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DomainIngressServiceSpec.
-func (in *DomainIngressServiceSpec) DeepCopy() *DomainIngressServiceSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterDomainConfigList.
+func (in *ClusterDomainConfigList) DeepCopy() *ClusterDomainConfigList {
 	if in == nil {
 		return nil
 	}
-	out := new(DomainIngressServiceSpec)
+	out := new(ClusterDomainConfigList)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterDomainConfigList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *DomainIngressSpec) DeepCopyInto(out *DomainIngressSpec) {
+func (in *ClusterDomainConfigSpec) DeepCopyInto(out *ClusterDomainConfigSpec) {
 	*out = *in
-	out.Service = in.Service
-	if in.Annotations != nil {
-		in, out := &in.Annotations, &out.Annotations
+	if in.Ingress != nil {
+		in, out := &in.Ingress, &out.Ingress
+		*out = new(IngressDefaults)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DKIM != nil {
+		in, out := &in.DKIM, &out.DKIM
+		*out = new(DKIMDefaults)
+		**out = **in
+	}
+	if in.DNS != nil {
+		in, out := &in.DNS, &out.DNS
+		*out = new(DNSDefaults)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CheckInterval != nil {
+		in, out := &in.CheckInterval, &out.CheckInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.UnverifiedCheckInterval != nil {
+		in, out := &in.UnverifiedCheckInterval, &out.UnverifiedCheckInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Notifications != nil {
+		in, out := &in.Notifications, &out.Notifications
+		*out = new(NotificationsSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterDomainConfigSpec.
+func (in *ClusterDomainConfigSpec) DeepCopy() *ClusterDomainConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterDomainConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterDomainConfigStatus) DeepCopyInto(out *ClusterDomainConfigStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterDomainConfigStatus.
+func (in *ClusterDomainConfigStatus) DeepCopy() *ClusterDomainConfigStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterDomainConfigStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CredentialsSecretReference) DeepCopyInto(out *CredentialsSecretReference) {
+	*out = *in
+	if in.Keys != nil {
+		in, out := &in.Keys, &out.Keys
 		*out = make(map[string]string, len(*in))
 		for key, val := range *in {
 			(*out)[key] = val
@@ -128,77 +376,1844 @@ func (in *DomainIngressSpec) DeepCopyInto(out *DomainIngressSpec) {
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DomainIngressSpec.
-func (in *DomainIngressSpec) DeepCopy() *DomainIngressSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CredentialsSecretReference.
+func (in *CredentialsSecretReference) DeepCopy() *CredentialsSecretReference {
 	if in == nil {
 		return nil
 	}
-	out := new(DomainIngressSpec)
+	out := new(CredentialsSecretReference)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *DomainList) DeepCopyInto(out *DomainList) {
+func (in *DKIM) DeepCopyInto(out *DKIM) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ListMeta.DeepCopyInto(&out.ListMeta)
-	if in.Items != nil {
-		in, out := &in.Items, &out.Items
-		*out = make([]Domain, len(*in))
+	if in.RotationPeriod != nil {
+		in, out := &in.RotationPeriod, &out.RotationPeriod
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DKIM.
+func (in *DKIM) DeepCopy() *DKIM {
+	if in == nil {
+		return nil
+	}
+	out := new(DKIM)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DKIMDefaults) DeepCopyInto(out *DKIMDefaults) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DKIMDefaults.
+func (in *DKIMDefaults) DeepCopy() *DKIMDefaults {
+	if in == nil {
+		return nil
+	}
+	out := new(DKIMDefaults)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DKIMKey) DeepCopyInto(out *DKIMKey) {
+	*out = *in
+	if in.CreatedAt != nil {
+		in, out := &in.CreatedAt, &out.CreatedAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DKIMKey.
+func (in *DKIMKey) DeepCopy() *DKIMKey {
+	if in == nil {
+		return nil
+	}
+	out := new(DKIMKey)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DKIMStatus) DeepCopyInto(out *DKIMStatus) {
+	*out = *in
+	in.DKIMKey.DeepCopyInto(&out.DKIMKey)
+	if in.Pending != nil {
+		in, out := &in.Pending, &out.Pending
+		*out = new(DKIMKey)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RetiringKeys != nil {
+		in, out := &in.RetiringKeys, &out.RetiringKeys
+		*out = make([]RetiringDKIMKey, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DomainList.
-func (in *DomainList) DeepCopy() *DomainList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DKIMStatus.
+func (in *DKIMStatus) DeepCopy() *DKIMStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(DomainList)
+	out := new(DKIMStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *DomainList) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DMARCHTTPSpec) DeepCopyInto(out *DMARCHTTPSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DMARCHTTPSpec.
+func (in *DMARCHTTPSpec) DeepCopy() *DMARCHTTPSpec {
+	if in == nil {
+		return nil
 	}
-	return nil
+	out := new(DMARCHTTPSpec)
+	in.DeepCopyInto(out)
+	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *DomainSpec) DeepCopyInto(out *DomainSpec) {
+func (in *DMARCIMAPSpec) DeepCopyInto(out *DMARCIMAPSpec) {
 	*out = *in
-	out.DKim = in.DKim
-	in.Ingress.DeepCopyInto(&out.Ingress)
+	if in.PollInterval != nil {
+		in, out := &in.PollInterval, &out.PollInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DomainSpec.
-func (in *DomainSpec) DeepCopy() *DomainSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DMARCIMAPSpec.
+func (in *DMARCIMAPSpec) DeepCopy() *DMARCIMAPSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(DomainSpec)
+	out := new(DMARCIMAPSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *DomainStatus) DeepCopyInto(out *DomainStatus) {
+func (in *DMARCReportsSpec) DeepCopyInto(out *DMARCReportsSpec) {
 	*out = *in
-	out.DNS = in.DNS
+	if in.IMAP != nil {
+		in, out := &in.IMAP, &out.IMAP
+		*out = new(DMARCIMAPSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HTTP != nil {
+		in, out := &in.HTTP, &out.HTTP
+		*out = new(DMARCHTTPSpec)
+		**out = **in
+	}
+	if in.Window != nil {
+		in, out := &in.Window, &out.Window
+		*out = new(v1.Duration)
+		**out = **in
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DomainStatus.
-func (in *DomainStatus) DeepCopy() *DomainStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DMARCReportsSpec.
+func (in *DMARCReportsSpec) DeepCopy() *DMARCReportsSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(DomainStatus)
+	out := new(DMARCReportsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DMARCReportsStatus) DeepCopyInto(out *DMARCReportsStatus) {
+	*out = *in
+	out.Window = in.Window
+	if in.Reporters != nil {
+		in, out := &in.Reporters, &out.Reporters
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.TopFailingSources != nil {
+		in, out := &in.TopFailingSources, &out.TopFailingSources
+		*out = make([]DMARCSource, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastReportEnd != nil {
+		in, out := &in.LastReportEnd, &out.LastReportEnd
+		*out = (*in).DeepCopy()
+	}
+	if in.PolledAt != nil {
+		in, out := &in.PolledAt, &out.PolledAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DMARCReportsStatus.
+func (in *DMARCReportsStatus) DeepCopy() *DMARCReportsStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DMARCReportsStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DMARCSource) DeepCopyInto(out *DMARCSource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DMARCSource.
+func (in *DMARCSource) DeepCopy() *DMARCSource {
+	if in == nil {
+		return nil
+	}
+	out := new(DMARCSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DMARCSpec) DeepCopyInto(out *DMARCSpec) {
+	*out = *in
+	if in.ReportURIs != nil {
+		in, out := &in.ReportURIs, &out.ReportURIs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DMARCSpec.
+func (in *DMARCSpec) DeepCopy() *DMARCSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DMARCSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DMARCStatus) DeepCopyInto(out *DMARCStatus) {
+	*out = *in
+	in.DNSStatusStats.DeepCopyInto(&out.DNSStatusStats)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DMARCStatus.
+func (in *DMARCStatus) DeepCopy() *DMARCStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DMARCStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DNSDefaults) DeepCopyInto(out *DNSDefaults) {
+	*out = *in
+	if in.Resolvers != nil {
+		in, out := &in.Resolvers, &out.Resolvers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DNSDefaults.
+func (in *DNSDefaults) DeepCopy() *DNSDefaults {
+	if in == nil {
+		return nil
+	}
+	out := new(DNSDefaults)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DNSProviderSpec) DeepCopyInto(out *DNSProviderSpec) {
+	*out = *in
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(CredentialsSecretReference)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DNSProviderSpec.
+func (in *DNSProviderSpec) DeepCopy() *DNSProviderSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DNSProviderSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DNSRecord) DeepCopyInto(out *DNSRecord) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DNSRecord.
+func (in *DNSRecord) DeepCopy() *DNSRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(DNSRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DNSStatus) DeepCopyInto(out *DNSStatus) {
+	*out = *in
+	if in.LastCheckTime != nil {
+		in, out := &in.LastCheckTime, &out.LastCheckTime
+		*out = (*in).DeepCopy()
+	}
+	in.Stats.DeepCopyInto(&out.Stats)
+	in.DKIM.DeepCopyInto(&out.DKIM)
+	in.SPF.DeepCopyInto(&out.SPF)
+	in.MX.DeepCopyInto(&out.MX)
+	in.DMARC.DeepCopyInto(&out.DMARC)
+	if in.MTASTS != nil {
+		in, out := &in.MTASTS, &out.MTASTS
+		*out = new(MTASTSStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TLSRPT != nil {
+		in, out := &in.TLSRPT, &out.TLSRPT
+		*out = new(DNSStatusStats)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BIMI != nil {
+		in, out := &in.BIMI, &out.BIMI
+		*out = new(BIMIStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PTR != nil {
+		in, out := &in.PTR, &out.PTR
+		*out = make([]PTRStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.AdditionalDomains != nil {
+		in, out := &in.AdditionalDomains, &out.AdditionalDomains
+		*out = make([]AdditionalDomainStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DNSStatus.
+func (in *DNSStatus) DeepCopy() *DNSStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DNSStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DNSStatusStats) DeepCopyInto(out *DNSStatusStats) {
+	*out = *in
+	if in.Expected != nil {
+		in, out := &in.Expected, &out.Expected
+		*out = new(DNSRecord)
+		**out = **in
+	}
+	if in.Observed != nil {
+		in, out := &in.Observed, &out.Observed
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Resolvers != nil {
+		in, out := &in.Resolvers, &out.Resolvers
+		*out = make([]ResolverStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.CheckedAt != nil {
+		in, out := &in.CheckedAt, &out.CheckedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.LastVerified != nil {
+		in, out := &in.LastVerified, &out.LastVerified
+		*out = (*in).DeepCopy()
+	}
+	if in.LastTransitionTime != nil {
+		in, out := &in.LastTransitionTime, &out.LastTransitionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DNSStatusStats.
+func (in *DNSStatusStats) DeepCopy() *DNSStatusStats {
+	if in == nil {
+		return nil
+	}
+	out := new(DNSStatusStats)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeliveryBucket) DeepCopyInto(out *DeliveryBucket) {
+	*out = *in
+	in.Start.DeepCopyInto(&out.Start)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeliveryBucket.
+func (in *DeliveryBucket) DeepCopy() *DeliveryBucket {
+	if in == nil {
+		return nil
+	}
+	out := new(DeliveryBucket)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeliverySpec) DeepCopyInto(out *DeliverySpec) {
+	*out = *in
+	if in.MinSent != nil {
+		in, out := &in.MinSent, &out.MinSent
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeliverySpec.
+func (in *DeliverySpec) DeepCopy() *DeliverySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DeliverySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeliveryStatus) DeepCopyInto(out *DeliveryStatus) {
+	*out = *in
+	out.Window = in.Window
+	if in.UpdatedAt != nil {
+		in, out := &in.UpdatedAt, &out.UpdatedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Buckets != nil {
+		in, out := &in.Buckets, &out.Buckets
+		*out = make([]DeliveryBucket, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeliveryStatus.
+func (in *DeliveryStatus) DeepCopy() *DeliveryStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DeliveryStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Domain) DeepCopyInto(out *Domain) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Domain.
+func (in *Domain) DeepCopy() *Domain {
+	if in == nil {
+		return nil
+	}
+	out := new(Domain)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Domain) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DomainDNSSpec) DeepCopyInto(out *DomainDNSSpec) {
+	*out = *in
+	if in.Provider != nil {
+		in, out := &in.Provider, &out.Provider
+		*out = new(DNSProviderSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DomainDNSSpec.
+func (in *DomainDNSSpec) DeepCopy() *DomainDNSSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DomainDNSSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DomainExportSpec) DeepCopyInto(out *DomainExportSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DomainExportSpec.
+func (in *DomainExportSpec) DeepCopy() *DomainExportSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DomainExportSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DomainGatewaySpec) DeepCopyInto(out *DomainGatewaySpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DomainGatewaySpec.
+func (in *DomainGatewaySpec) DeepCopy() *DomainGatewaySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DomainGatewaySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DomainIngressServiceSpec) DeepCopyInto(out *DomainIngressServiceSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DomainIngressServiceSpec.
+func (in *DomainIngressServiceSpec) DeepCopy() *DomainIngressServiceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DomainIngressServiceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DomainIngressSpec) DeepCopyInto(out *DomainIngressSpec) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+	out.Service = in.Service
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ExtraHosts != nil {
+		in, out := &in.ExtraHosts, &out.ExtraHosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExtraPaths != nil {
+		in, out := &in.ExtraPaths, &out.ExtraPaths
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DomainIngressSpec.
+func (in *DomainIngressSpec) DeepCopy() *DomainIngressSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DomainIngressSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DomainList) DeepCopyInto(out *DomainList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Domain, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DomainList.
+func (in *DomainList) DeepCopy() *DomainList {
+	if in == nil {
+		return nil
+	}
+	out := new(DomainList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DomainList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DomainMonitoringSpec) DeepCopyInto(out *DomainMonitoringSpec) {
+	*out = *in
+	if in.For != nil {
+		in, out := &in.For, &out.For
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DomainMonitoringSpec.
+func (in *DomainMonitoringSpec) DeepCopy() *DomainMonitoringSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DomainMonitoringSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DomainReference) DeepCopyInto(out *DomainReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DomainReference.
+func (in *DomainReference) DeepCopy() *DomainReference {
+	if in == nil {
+		return nil
+	}
+	out := new(DomainReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DomainSpec) DeepCopyInto(out *DomainSpec) {
+	*out = *in
+	in.DKIM.DeepCopyInto(&out.DKIM)
+	in.Ingress.DeepCopyInto(&out.Ingress)
+	if in.IngressLabels != nil {
+		in, out := &in.IngressLabels, &out.IngressLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(DomainTLSSpec)
+		**out = **in
+	}
+	if in.Stats != nil {
+		in, out := &in.Stats, &out.Stats
+		*out = new(DomainStatsSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Gateway != nil {
+		in, out := &in.Gateway, &out.Gateway
+		*out = new(DomainGatewaySpec)
+		**out = **in
+	}
+	if in.DNS != nil {
+		in, out := &in.DNS, &out.DNS
+		*out = new(DomainDNSSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Monitoring != nil {
+		in, out := &in.Monitoring, &out.Monitoring
+		*out = new(DomainMonitoringSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CheckInterval != nil {
+		in, out := &in.CheckInterval, &out.CheckInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.UnverifiedCheckInterval != nil {
+		in, out := &in.UnverifiedCheckInterval, &out.UnverifiedCheckInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.ResourceLabels != nil {
+		in, out := &in.ResourceLabels, &out.ResourceLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ResourceAnnotations != nil {
+		in, out := &in.ResourceAnnotations, &out.ResourceAnnotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.MTASTS != nil {
+		in, out := &in.MTASTS, &out.MTASTS
+		*out = new(MTASTSSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TLSRPT != nil {
+		in, out := &in.TLSRPT, &out.TLSRPT
+		*out = new(TLSRPTSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DMARC != nil {
+		in, out := &in.DMARC, &out.DMARC
+		*out = new(DMARCSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BIMI != nil {
+		in, out := &in.BIMI, &out.BIMI
+		*out = new(BIMISpec)
+		**out = **in
+	}
+	if in.SendingIPs != nil {
+		in, out := &in.SendingIPs, &out.SendingIPs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AdditionalDomains != nil {
+		in, out := &in.AdditionalDomains, &out.AdditionalDomains
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SenderPoolRef != nil {
+		in, out := &in.SenderPoolRef, &out.SenderPoolRef
+		*out = new(SenderPoolReference)
+		**out = **in
+	}
+	if in.KannonCredentialsRef != nil {
+		in, out := &in.KannonCredentialsRef, &out.KannonCredentialsRef
+		*out = new(CredentialsSecretReference)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Delivery != nil {
+		in, out := &in.Delivery, &out.Delivery
+		*out = new(DeliverySpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Reputation != nil {
+		in, out := &in.Reputation, &out.Reputation
+		*out = new(ReputationSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DMARCReports != nil {
+		in, out := &in.DMARCReports, &out.DMARCReports
+		*out = new(DMARCReportsSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Export != nil {
+		in, out := &in.Export, &out.Export
+		*out = new(DomainExportSpec)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DomainSpec.
+func (in *DomainSpec) DeepCopy() *DomainSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DomainSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DomainStatsSpec) DeepCopyInto(out *DomainStatsSpec) {
+	*out = *in
+	if in.Hosts != nil {
+		in, out := &in.Hosts, &out.Hosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Paths != nil {
+		in, out := &in.Paths, &out.Paths
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NetworkPolicy != nil {
+		in, out := &in.NetworkPolicy, &out.NetworkPolicy
+		*out = new(StatsNetworkPolicySpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DomainStatsSpec.
+func (in *DomainStatsSpec) DeepCopy() *DomainStatsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DomainStatsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DomainStatus) DeepCopyInto(out *DomainStatus) {
+	*out = *in
+	in.DNS.DeepCopyInto(&out.DNS)
+	if in.LastCheckTime != nil {
+		in, out := &in.LastCheckTime, &out.LastCheckTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Certificate != nil {
+		in, out := &in.Certificate, &out.Certificate
+		*out = new(CertificateStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DKIM != nil {
+		in, out := &in.DKIM, &out.DKIM
+		*out = new(DKIMStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.OriginalSPF != nil {
+		in, out := &in.OriginalSPF, &out.OriginalSPF
+		*out = make([]OriginalSPFRecord, len(*in))
+		copy(*out, *in)
+	}
+	if in.Delivery != nil {
+		in, out := &in.Delivery, &out.Delivery
+		*out = new(DeliveryStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Reputation != nil {
+		in, out := &in.Reputation, &out.Reputation
+		*out = new(ReputationStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DMARCReports != nil {
+		in, out := &in.DMARCReports, &out.DMARCReports
+		*out = new(DMARCReportsStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Ownership != nil {
+		in, out := &in.Ownership, &out.Ownership
+		*out = new(OwnershipStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DomainStatus.
+func (in *DomainStatus) DeepCopy() *DomainStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DomainStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DomainTLSSpec) DeepCopyInto(out *DomainTLSSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DomainTLSSpec.
+func (in *DomainTLSSpec) DeepCopy() *DomainTLSSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DomainTLSSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DomainTest) DeepCopyInto(out *DomainTest) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DomainTest.
+func (in *DomainTest) DeepCopy() *DomainTest {
+	if in == nil {
+		return nil
+	}
+	out := new(DomainTest)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DomainTest) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DomainTestList) DeepCopyInto(out *DomainTestList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]DomainTest, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DomainTestList.
+func (in *DomainTestList) DeepCopy() *DomainTestList {
+	if in == nil {
+		return nil
+	}
+	out := new(DomainTestList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DomainTestList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DomainTestSpec) DeepCopyInto(out *DomainTestSpec) {
+	*out = *in
+	out.DomainRef = in.DomainRef
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DomainTestSpec.
+func (in *DomainTestSpec) DeepCopy() *DomainTestSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DomainTestSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DomainTestStatus) DeepCopyInto(out *DomainTestStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.SendingTime != nil {
+		in, out := &in.SendingTime, &out.SendingTime
+		*out = (*in).DeepCopy()
+	}
+	if in.SentTime != nil {
+		in, out := &in.SentTime, &out.SentTime
+		*out = (*in).DeepCopy()
+	}
+	if in.ReceivedTime != nil {
+		in, out := &in.ReceivedTime, &out.ReceivedTime
+		*out = (*in).DeepCopy()
+	}
+	if in.DKIM != nil {
+		in, out := &in.DKIM, &out.DKIM
+		*out = new(MessageAuthResult)
+		**out = **in
+	}
+	if in.SPF != nil {
+		in, out := &in.SPF, &out.SPF
+		*out = new(MessageAuthResult)
+		**out = **in
+	}
+	if in.DMARC != nil {
+		in, out := &in.DMARC, &out.DMARC
+		*out = new(MessageAuthResult)
+		**out = **in
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DomainTestStatus.
+func (in *DomainTestStatus) DeepCopy() *DomainTestStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DomainTestStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EmailTemplate) DeepCopyInto(out *EmailTemplate) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EmailTemplate.
+func (in *EmailTemplate) DeepCopy() *EmailTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(EmailTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *EmailTemplate) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EmailTemplateList) DeepCopyInto(out *EmailTemplateList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]EmailTemplate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EmailTemplateList.
+func (in *EmailTemplateList) DeepCopy() *EmailTemplateList {
+	if in == nil {
+		return nil
+	}
+	out := new(EmailTemplateList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *EmailTemplateList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EmailTemplateSpec) DeepCopyInto(out *EmailTemplateSpec) {
+	*out = *in
+	out.DomainRef = in.DomainRef
+	if in.HTMLFrom != nil {
+		in, out := &in.HTMLFrom, &out.HTMLFrom
+		*out = new(corev1.ConfigMapKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EmailTemplateSpec.
+func (in *EmailTemplateSpec) DeepCopy() *EmailTemplateSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(EmailTemplateSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EmailTemplateStatus) DeepCopyInto(out *EmailTemplateStatus) {
+	*out = *in
+	if in.LastSyncTime != nil {
+		in, out := &in.LastSyncTime, &out.LastSyncTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EmailTemplateStatus.
+func (in *EmailTemplateStatus) DeepCopy() *EmailTemplateStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(EmailTemplateStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPWarmup) DeepCopyInto(out *IPWarmup) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPWarmup.
+func (in *IPWarmup) DeepCopy() *IPWarmup {
+	if in == nil {
+		return nil
+	}
+	out := new(IPWarmup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *IPWarmup) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPWarmupList) DeepCopyInto(out *IPWarmupList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]IPWarmup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPWarmupList.
+func (in *IPWarmupList) DeepCopy() *IPWarmupList {
+	if in == nil {
+		return nil
+	}
+	out := new(IPWarmupList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *IPWarmupList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPWarmupPhase) DeepCopyInto(out *IPWarmupPhase) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPWarmupPhase.
+func (in *IPWarmupPhase) DeepCopy() *IPWarmupPhase {
+	if in == nil {
+		return nil
+	}
+	out := new(IPWarmupPhase)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPWarmupSpec) DeepCopyInto(out *IPWarmupSpec) {
+	*out = *in
+	if in.IPs != nil {
+		in, out := &in.IPs, &out.IPs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	if in.Schedule != nil {
+		in, out := &in.Schedule, &out.Schedule
+		*out = make([]IPWarmupPhase, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPWarmupSpec.
+func (in *IPWarmupSpec) DeepCopy() *IPWarmupSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(IPWarmupSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPWarmupStatus) DeepCopyInto(out *IPWarmupStatus) {
+	*out = *in
+	if in.LastSyncTime != nil {
+		in, out := &in.LastSyncTime, &out.LastSyncTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPWarmupStatus.
+func (in *IPWarmupStatus) DeepCopy() *IPWarmupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(IPWarmupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IngressDefaults) DeepCopyInto(out *IngressDefaults) {
+	*out = *in
+	if in.Service != nil {
+		in, out := &in.Service, &out.Service
+		*out = new(DomainIngressServiceSpec)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngressDefaults.
+func (in *IngressDefaults) DeepCopy() *IngressDefaults {
+	if in == nil {
+		return nil
+	}
+	out := new(IngressDefaults)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MTASTSSpec) DeepCopyInto(out *MTASTSSpec) {
+	*out = *in
+	if in.MX != nil {
+		in, out := &in.MX, &out.MX
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MaxAge != nil {
+		in, out := &in.MaxAge, &out.MaxAge
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MTASTSSpec.
+func (in *MTASTSSpec) DeepCopy() *MTASTSSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MTASTSSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MTASTSStatus) DeepCopyInto(out *MTASTSStatus) {
+	*out = *in
+	in.DNSStatusStats.DeepCopyInto(&out.DNSStatusStats)
+	in.Policy.DeepCopyInto(&out.Policy)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MTASTSStatus.
+func (in *MTASTSStatus) DeepCopy() *MTASTSStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MTASTSStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MXStatus) DeepCopyInto(out *MXStatus) {
+	*out = *in
+	in.DNSStatusStats.DeepCopyInto(&out.DNSStatusStats)
+	if in.Hosts != nil {
+		in, out := &in.Hosts, &out.Hosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MXStatus.
+func (in *MXStatus) DeepCopy() *MXStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MXStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MessageAuthResult) DeepCopyInto(out *MessageAuthResult) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MessageAuthResult.
+func (in *MessageAuthResult) DeepCopy() *MessageAuthResult {
+	if in == nil {
+		return nil
+	}
+	out := new(MessageAuthResult)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NotificationSink) DeepCopyInto(out *NotificationSink) {
+	*out = *in
+	if in.Headers != nil {
+		in, out := &in.Headers, &out.Headers
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Events != nil {
+		in, out := &in.Events, &out.Events
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NotificationSink.
+func (in *NotificationSink) DeepCopy() *NotificationSink {
+	if in == nil {
+		return nil
+	}
+	out := new(NotificationSink)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NotificationsSpec) DeepCopyInto(out *NotificationsSpec) {
+	*out = *in
+	if in.Slack != nil {
+		in, out := &in.Slack, &out.Slack
+		*out = make([]NotificationSink, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Webhooks != nil {
+		in, out := &in.Webhooks, &out.Webhooks
+		*out = make([]NotificationSink, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NotificationsSpec.
+func (in *NotificationsSpec) DeepCopy() *NotificationsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NotificationsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OriginalSPFRecord) DeepCopyInto(out *OriginalSPFRecord) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OriginalSPFRecord.
+func (in *OriginalSPFRecord) DeepCopy() *OriginalSPFRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(OriginalSPFRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OwnershipStatus) DeepCopyInto(out *OwnershipStatus) {
+	*out = *in
+	in.DNSStatusStats.DeepCopyInto(&out.DNSStatusStats)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OwnershipStatus.
+func (in *OwnershipStatus) DeepCopy() *OwnershipStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(OwnershipStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PTRStatus) DeepCopyInto(out *PTRStatus) {
+	*out = *in
+	in.DNSStatusStats.DeepCopyInto(&out.DNSStatusStats)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PTRStatus.
+func (in *PTRStatus) DeepCopy() *PTRStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PTRStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostmasterIPReputation) DeepCopyInto(out *PostmasterIPReputation) {
+	*out = *in
+	if in.SampleIPs != nil {
+		in, out := &in.SampleIPs, &out.SampleIPs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostmasterIPReputation.
+func (in *PostmasterIPReputation) DeepCopy() *PostmasterIPReputation {
+	if in == nil {
+		return nil
+	}
+	out := new(PostmasterIPReputation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostmasterReputation) DeepCopyInto(out *PostmasterReputation) {
+	*out = *in
+	if in.IPReputations != nil {
+		in, out := &in.IPReputations, &out.IPReputations
+		*out = make([]PostmasterIPReputation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostmasterReputation.
+func (in *PostmasterReputation) DeepCopy() *PostmasterReputation {
+	if in == nil {
+		return nil
+	}
+	out := new(PostmasterReputation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReputationSpec) DeepCopyInto(out *ReputationSpec) {
+	*out = *in
+	if in.PollInterval != nil {
+		in, out := &in.PollInterval, &out.PollInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReputationSpec.
+func (in *ReputationSpec) DeepCopy() *ReputationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ReputationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReputationStatus) DeepCopyInto(out *ReputationStatus) {
+	*out = *in
+	if in.PolledAt != nil {
+		in, out := &in.PolledAt, &out.PolledAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Postmaster != nil {
+		in, out := &in.Postmaster, &out.Postmaster
+		*out = new(PostmasterReputation)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SNDS != nil {
+		in, out := &in.SNDS, &out.SNDS
+		*out = new(SNDSReputation)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReputationStatus.
+func (in *ReputationStatus) DeepCopy() *ReputationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ReputationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResolverStatus) DeepCopyInto(out *ResolverStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResolverStatus.
+func (in *ResolverStatus) DeepCopy() *ResolverStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ResolverStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RetiringApiKey) DeepCopyInto(out *RetiringApiKey) {
+	*out = *in
+	in.RevokeAt.DeepCopyInto(&out.RevokeAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RetiringApiKey.
+func (in *RetiringApiKey) DeepCopy() *RetiringApiKey {
+	if in == nil {
+		return nil
+	}
+	out := new(RetiringApiKey)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RetiringDKIMKey) DeepCopyInto(out *RetiringDKIMKey) {
+	*out = *in
+	in.RetireAt.DeepCopyInto(&out.RetireAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RetiringDKIMKey.
+func (in *RetiringDKIMKey) DeepCopy() *RetiringDKIMKey {
+	if in == nil {
+		return nil
+	}
+	out := new(RetiringDKIMKey)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SNDSIPReputation) DeepCopyInto(out *SNDSIPReputation) {
+	*out = *in
+	if in.ActivityEnd != nil {
+		in, out := &in.ActivityEnd, &out.ActivityEnd
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SNDSIPReputation.
+func (in *SNDSIPReputation) DeepCopy() *SNDSIPReputation {
+	if in == nil {
+		return nil
+	}
+	out := new(SNDSIPReputation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SNDSReputation) DeepCopyInto(out *SNDSReputation) {
+	*out = *in
+	if in.IPs != nil {
+		in, out := &in.IPs, &out.IPs
+		*out = make([]SNDSIPReputation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SNDSReputation.
+func (in *SNDSReputation) DeepCopy() *SNDSReputation {
+	if in == nil {
+		return nil
+	}
+	out := new(SNDSReputation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SPFStatus) DeepCopyInto(out *SPFStatus) {
+	*out = *in
+	in.DNSStatusStats.DeepCopyInto(&out.DNSStatusStats)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SPFStatus.
+func (in *SPFStatus) DeepCopy() *SPFStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SPFStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SenderPool) DeepCopyInto(out *SenderPool) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SenderPool.
+func (in *SenderPool) DeepCopy() *SenderPool {
+	if in == nil {
+		return nil
+	}
+	out := new(SenderPool)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SenderPool) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SenderPoolIPStatus) DeepCopyInto(out *SenderPoolIPStatus) {
+	*out = *in
+	in.PTR.DeepCopyInto(&out.PTR)
+	in.SPF.DeepCopyInto(&out.SPF)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SenderPoolIPStatus.
+func (in *SenderPoolIPStatus) DeepCopy() *SenderPoolIPStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SenderPoolIPStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SenderPoolList) DeepCopyInto(out *SenderPoolList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]SenderPool, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SenderPoolList.
+func (in *SenderPoolList) DeepCopy() *SenderPoolList {
+	if in == nil {
+		return nil
+	}
+	out := new(SenderPoolList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SenderPoolList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SenderPoolReference) DeepCopyInto(out *SenderPoolReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SenderPoolReference.
+func (in *SenderPoolReference) DeepCopy() *SenderPoolReference {
+	if in == nil {
+		return nil
+	}
+	out := new(SenderPoolReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SenderPoolSpec) DeepCopyInto(out *SenderPoolSpec) {
+	*out = *in
+	if in.IPs != nil {
+		in, out := &in.IPs, &out.IPs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.CheckInterval != nil {
+		in, out := &in.CheckInterval, &out.CheckInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SenderPoolSpec.
+func (in *SenderPoolSpec) DeepCopy() *SenderPoolSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SenderPoolSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SenderPoolStatus) DeepCopyInto(out *SenderPoolStatus) {
+	*out = *in
+	if in.LastCheckTime != nil {
+		in, out := &in.LastCheckTime, &out.LastCheckTime
+		*out = (*in).DeepCopy()
+	}
+	if in.IPs != nil {
+		in, out := &in.IPs, &out.IPs
+		*out = make([]SenderPoolIPStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SenderPoolStatus.
+func (in *SenderPoolStatus) DeepCopy() *SenderPoolStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SenderPoolStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StatsNetworkPolicySpec) DeepCopyInto(out *StatsNetworkPolicySpec) {
+	*out = *in
+	if in.IngressPodSelector != nil {
+		in, out := &in.IngressPodSelector, &out.IngressPodSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StatsNetworkPolicySpec.
+func (in *StatsNetworkPolicySpec) DeepCopy() *StatsNetworkPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(StatsNetworkPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TLSRPTSpec) DeepCopyInto(out *TLSRPTSpec) {
+	*out = *in
+	if in.ReportURIs != nil {
+		in, out := &in.ReportURIs, &out.ReportURIs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSRPTSpec.
+func (in *TLSRPTSpec) DeepCopy() *TLSRPTSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TLSRPTSpec)
 	in.DeepCopyInto(out)
 	return out
 }
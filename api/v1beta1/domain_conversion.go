@@ -0,0 +1,160 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"github.com/kannon-email/k8nnon/api/v1alpha1"
+)
+
+// ConvertTo converts the Domain to the v1alpha1 hub.
+func (src *Domain) ConvertTo(dstRaw conversion.Hub) error {
+	dst, ok := dstRaw.(*v1alpha1.Domain)
+	if !ok {
+		return fmt.Errorf("unexpected hub type %T", dstRaw)
+	}
+
+	dst.ObjectMeta = src.ObjectMeta
+	if err := convertJSON(&src.Spec, &dst.Spec); err != nil {
+		return err
+	}
+	dst.Status = v1alpha1.DomainStatus{}
+	if err := convertJSON(&src.Status, &dst.Status); err != nil {
+		return err
+	}
+
+	hubRecords := hubDNSRecords(&dst.Status)
+	for i, r := range dnsRecords(&src.Status) {
+		hub := hubRecords[i]
+		hub.OK = r.Verified
+		hub.CntOK = r.Counts.Verified
+		hub.CntErr = r.Counts.Failed
+		hub.CntKO = r.Counts.Mismatch
+		hub.CheckedAt = r.LastCheckTime
+		if r.Error != "" {
+			hub.Message = r.Error
+		}
+	}
+
+	return nil
+}
+
+// ConvertFrom converts the v1alpha1 hub to the Domain.
+func (dst *Domain) ConvertFrom(srcRaw conversion.Hub) error {
+	src, ok := srcRaw.(*v1alpha1.Domain)
+	if !ok {
+		return fmt.Errorf("unexpected hub type %T", srcRaw)
+	}
+
+	dst.ObjectMeta = src.ObjectMeta
+	if err := convertJSON(&src.Spec, &dst.Spec); err != nil {
+		return err
+	}
+	dst.Status = DomainStatus{}
+	if err := convertJSON(&src.Status, &dst.Status); err != nil {
+		return err
+	}
+
+	records := dnsRecords(&dst.Status)
+	for i, r := range hubDNSRecords(&src.Status) {
+		record := records[i]
+		record.Verified = r.OK
+		record.Counts = CheckCounts{Verified: r.CntOK, Failed: r.CntErr, Mismatch: r.CntKO}
+		record.LastCheckTime = r.CheckedAt
+		if r.State == v1alpha1.CheckStateUnknown {
+			record.Message, record.Error = "", r.Message
+		}
+		if len(r.Observed) == 1 {
+			record.ObservedValue = r.Observed[0]
+		}
+	}
+	dst.Status.DNS.DKIM.Selector = src.DKIMSelector()
+
+	return nil
+}
+
+// convertJSON copies the fields with the same schema in both versions: the
+// spec, and the status but the details of the DNS checks.
+func convertJSON(src, dst interface{}) error {
+	data, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
+
+// dnsRecords returns the DNS checks of the status, in the order of
+// hubDNSRecords.
+func dnsRecords(s *DomainStatus) []*DNSRecordStatus {
+	dns := &s.DNS
+	records := []*DNSRecordStatus{&dns.Stats, &dns.DKIM.DNSRecordStatus, &dns.SPF.DNSRecordStatus, &dns.MX.DNSRecordStatus, &dns.DMARC.DNSRecordStatus}
+	if dns.MTASTS != nil {
+		records = append(records, &dns.MTASTS.DNSRecordStatus, &dns.MTASTS.Policy)
+	}
+	if dns.TLSRPT != nil {
+		records = append(records, dns.TLSRPT)
+	}
+	if dns.BIMI != nil {
+		records = append(records, &dns.BIMI.DNSRecordStatus, &dns.BIMI.Indicator)
+		if dns.BIMI.VMC != nil {
+			records = append(records, dns.BIMI.VMC)
+		}
+	}
+	for i := range dns.PTR {
+		records = append(records, &dns.PTR[i].DNSRecordStatus)
+	}
+	for i := range dns.AdditionalDomains {
+		records = append(records, &dns.AdditionalDomains[i].DKIM, &dns.AdditionalDomains[i].SPF.DNSRecordStatus)
+	}
+	if s.Ownership != nil {
+		records = append(records, &s.Ownership.DNSRecordStatus)
+	}
+	return records
+}
+
+// hubDNSRecords returns the DNS checks of the v1alpha1 status, in the
+// order of dnsRecords.
+func hubDNSRecords(s *v1alpha1.DomainStatus) []*v1alpha1.DNSStatusStats {
+	dns := &s.DNS
+	records := []*v1alpha1.DNSStatusStats{&dns.Stats, &dns.DKIM, &dns.SPF.DNSStatusStats, &dns.MX.DNSStatusStats, &dns.DMARC.DNSStatusStats}
+	if dns.MTASTS != nil {
+		records = append(records, &dns.MTASTS.DNSStatusStats, &dns.MTASTS.Policy)
+	}
+	if dns.TLSRPT != nil {
+		records = append(records, dns.TLSRPT)
+	}
+	if dns.BIMI != nil {
+		records = append(records, &dns.BIMI.DNSStatusStats, &dns.BIMI.Indicator)
+		if dns.BIMI.VMC != nil {
+			records = append(records, dns.BIMI.VMC)
+		}
+	}
+	for i := range dns.PTR {
+		records = append(records, &dns.PTR[i].DNSStatusStats)
+	}
+	for i := range dns.AdditionalDomains {
+		records = append(records, &dns.AdditionalDomains[i].DKIM, &dns.AdditionalDomains[i].SPF.DNSStatusStats)
+	}
+	if s.Ownership != nil {
+		records = append(records, &s.Ownership.DNSStatusStats)
+	}
+	return records
+}
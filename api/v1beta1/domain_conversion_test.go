@@ -0,0 +1,188 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kannon-email/k8nnon/api/v1alpha1"
+)
+
+var checkedAt = metav1.NewTime(time.Unix(1672531200, 0))
+
+func checked(ok bool, cntOK, cntErr, cntKO int) v1alpha1.DNSStatusStats {
+	state, message, class := v1alpha1.CheckStateMissing, "the record does not match", v1alpha1.CheckErrorClass("")
+	switch {
+	case ok:
+		state, message = v1alpha1.CheckStateVerified, ""
+	case cntErr > 0:
+		state, message, class = v1alpha1.CheckStateUnknown, "i/o timeout", v1alpha1.CheckErrorTimeout
+	}
+	return v1alpha1.DNSStatusStats{
+		State:      state,
+		Message:    message,
+		ErrorClass: class,
+		CheckedAt:  &checkedAt,
+		Expected:   &v1alpha1.DNSRecord{Type: "TXT", Name: "example.com", Value: "v=spf1"},
+		Observed:   []string{"v=spf1"},
+		Resolvers: []v1alpha1.ResolverStatus{
+			{Resolver: "1.1.1.1:53", State: state, ErrorClass: class},
+		},
+		OK:     ok,
+		CntOK:  cntOK,
+		CntErr: cntErr,
+		CntKO:  cntKO,
+	}
+}
+
+func hubDomain() *v1alpha1.Domain {
+	// the times are second-precise and local once converted
+	now := metav1.NewTime(time.Unix(1672531200, 0))
+	enabled := true
+	return &v1alpha1.Domain{
+		ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "default", Generation: 3},
+		Spec: v1alpha1.DomainSpec{
+			DomainName:  "example.com",
+			BaseDomain:  "mx.kannon.example.com",
+			StatsPrefix: "stats",
+			Stats:       &v1alpha1.DomainStatsSpec{Paths: []string{"/o/", "/c/"}, RewriteHost: "stats.kannon.example.com"},
+			DKIM:        v1alpha1.DKIM{Selector: "kannon", PublicKey: "cHVibGljS2V5"},
+			Ingress: v1alpha1.DomainIngressSpec{
+				Enabled:   &enabled,
+				ClassName: "nginx",
+				Service:   v1alpha1.DomainIngressServiceSpec{Name: "kannon-stats", Port: 80},
+			},
+			MTASTS:            &v1alpha1.MTASTSSpec{Enabled: true, Mode: "enforce"},
+			BIMI:              &v1alpha1.BIMISpec{LogoURL: "https://example.com/logo.svg", VMCURL: "https://example.com/vmc.pem"},
+			SendingIPs:        []string{"192.0.2.1"},
+			AdditionalDomains: []string{"news.example.com"},
+			Monitoring:        &v1alpha1.DomainMonitoringSpec{Alerts: true},
+			DNS: &v1alpha1.DomainDNSSpec{AutoProvision: true, Provider: &v1alpha1.DNSProviderSpec{
+				Name:      "cloudflare",
+				SecretRef: &v1alpha1.CredentialsSecretReference{Name: "cloudflare", Keys: map[string]string{"api-token": "CF_API_TOKEN"}},
+			}},
+			Routing:   "ingress",
+			StatsPath: "/stats",
+			TLSRPT:    &v1alpha1.TLSRPTSpec{ReportURIs: []string{"mailto:tls@example.com"}},
+			Delivery:  &v1alpha1.DeliverySpec{MaxBounceRate: "2%"},
+			SenderPoolRef: &v1alpha1.SenderPoolReference{
+				Name: "pool",
+			},
+			SenderAlias:          "Example",
+			KannonCredentialsRef: &v1alpha1.CredentialsSecretReference{Name: "kannon-credentials"},
+			Reputation:           &v1alpha1.ReputationSpec{SecretName: "reputation"},
+			DMARCReports:         &v1alpha1.DMARCReportsSpec{HTTP: &v1alpha1.DMARCHTTPSpec{SecretName: "dmarc"}},
+		},
+		Status: v1alpha1.DomainStatus{
+			DNS: v1alpha1.DNSStatus{
+				Stats: checked(true, 3, 0, 0),
+				DKIM:  checked(false, 1, 1, 1),
+				SPF:   v1alpha1.SPFStatus{DNSStatusStats: checked(true, 2, 1, 0), Lookups: 4},
+				MX:    v1alpha1.MXStatus{DNSStatusStats: checked(true, 3, 0, 0), Hosts: []string{"mx.kannon.example.com"}},
+				DMARC: v1alpha1.DMARCStatus{DNSStatusStats: checked(false, 0, 0, 3), Policy: "none"},
+				MTASTS: &v1alpha1.MTASTSStatus{
+					DNSStatusStats: checked(true, 3, 0, 0),
+					Policy:         checked(false, 0, 1, 0),
+					PolicyID:       "20230101",
+				},
+				TLSRPT: func() *v1alpha1.DNSStatusStats { s := checked(true, 1, 0, 0); return &s }(),
+				BIMI: &v1alpha1.BIMIStatus{
+					DNSStatusStats: checked(true, 3, 0, 0),
+					Indicator:      checked(true, 1, 0, 0),
+					VMC:            func() *v1alpha1.DNSStatusStats { s := checked(false, 0, 0, 1); return &s }(),
+				},
+				PTR: []v1alpha1.PTRStatus{{IP: "192.0.2.1", DNSStatusStats: checked(true, 3, 0, 0), Host: "mta1.mx.kannon.example.com"}},
+				AdditionalDomains: []v1alpha1.AdditionalDomainStatus{{
+					Domain: "news.example.com",
+					DKIM:   checked(true, 3, 0, 0),
+					SPF:    v1alpha1.SPFStatus{DNSStatusStats: checked(false, 0, 0, 3), Lookups: 2},
+				}},
+			},
+			LastCheckTime: &now,
+			FailedChecks:  2,
+			Certificate:   &v1alpha1.CertificateStatus{SecretName: "example-stats-tls", NotAfter: &now},
+			DKIM:          &v1alpha1.DKIMStatus{DKIMKey: v1alpha1.DKIMKey{Selector: "kannon", SecretName: "example-dkim", KeyType: "rsa", PublicKey: "cHVibGljS2V5"}},
+			Ownership:     &v1alpha1.OwnershipStatus{Domain: "example.com", Token: "k8nnon-challenge=abc", DNSStatusStats: checked(true, 3, 0, 0)},
+			Conditions: []metav1.Condition{
+				{Type: v1alpha1.ConditionReady, Status: metav1.ConditionFalse, Reason: v1alpha1.ReasonDKIMNotVerified, LastTransitionTime: now},
+			},
+		},
+	}
+}
+
+func TestDomainConversion(t *testing.T) {
+	hub := hubDomain()
+
+	domain := &Domain{}
+	require.NoError(t, domain.ConvertFrom(hub))
+	assert.Equal(t, "example.com", domain.Spec.DomainName)
+	assert.Equal(t, "pool", domain.Spec.SenderPoolRef.Name)
+	assert.True(t, domain.Status.DNS.Stats.Verified)
+	assert.False(t, domain.Status.DNS.DKIM.Verified)
+	assert.Equal(t, CheckCounts{Verified: 1, Failed: 1, Mismatch: 1}, domain.Status.DNS.DKIM.Counts)
+	assert.Equal(t, CheckCounts{Mismatch: 1}, domain.Status.DNS.BIMI.VMC.Counts)
+	assert.Equal(t, CheckCounts{Failed: 1}, domain.Status.DNS.MTASTS.Policy.Counts)
+	assert.True(t, domain.Status.DNS.PTR[0].Verified)
+	assert.Equal(t, "mta1.mx.kannon.example.com", domain.Status.DNS.PTR[0].Host)
+	assert.True(t, domain.Status.Ownership.Verified)
+	assert.True(t, domain.Status.DNS.AdditionalDomains[0].DKIM.Verified)
+	assert.Equal(t, CheckCounts{Mismatch: 3}, domain.Status.DNS.AdditionalDomains[0].SPF.Counts)
+
+	dkim := domain.Status.DNS.DKIM
+	assert.Equal(t, "kannon", dkim.Selector)
+	assert.Equal(t, "i/o timeout", dkim.Error)
+	assert.Equal(t, CheckErrorClass("Timeout"), dkim.ErrorClass)
+	assert.Empty(t, dkim.Message)
+	assert.Equal(t, "the record does not match", domain.Status.DNS.DMARC.Message)
+	assert.Empty(t, domain.Status.DNS.DMARC.Error)
+	assert.Equal(t, "v=spf1", domain.Status.DNS.SPF.ObservedValue)
+	assert.Equal(t, &checkedAt, domain.Status.DNS.SPF.LastCheckTime)
+
+	data, err := json.Marshal(domain.Status.DNS.SPF)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"verified":true`)
+	assert.Contains(t, string(data), `"counts":{"verified":2,"failed":1,"mismatch":0}`)
+	assert.Contains(t, string(data), `"lastCheckTime"`)
+	assert.NotContains(t, string(data), "cnt_")
+	assert.NotContains(t, string(data), "checkedAt")
+
+	back := &v1alpha1.Domain{}
+	require.NoError(t, domain.ConvertTo(back))
+	assert.Equal(t, hub, back)
+}
+
+func TestDomainConversionMinimal(t *testing.T) {
+	hub := &v1alpha1.Domain{
+		ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "default"},
+		Spec:       v1alpha1.DomainSpec{DomainName: "example.com"},
+	}
+
+	domain := &Domain{}
+	require.NoError(t, domain.ConvertFrom(hub))
+	assert.Nil(t, domain.Status.DNS.MTASTS)
+	assert.Nil(t, domain.Status.Ownership)
+
+	back := &v1alpha1.Domain{}
+	require.NoError(t, domain.ConvertTo(back))
+	assert.Equal(t, hub, back)
+}
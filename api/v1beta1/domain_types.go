@@ -0,0 +1,1182 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// The spec has the same schema as in v1alpha1. The status fixes the names
+// of the DNS check counters and flags.
+
+// DomainSpec defines the desired state of Domain
+type DomainSpec struct {
+	//+kubebuilder:validation:Required
+	DomainName string `json:"domainName,omitempty"`
+
+	//+kubebuilder:validation:Required
+	BaseDomain string `json:"baseDomain,omitempty"`
+
+	//+kubebuilder:validation:Required
+	StatsPrefix string `json:"statsPrefix,omitempty"`
+
+	// StatsHost is the host serving the stats. Defaults to <statsPrefix>.<domainName>.
+	// +optional
+	StatsHost string `json:"statsHost,omitempty"`
+
+	// StatsPath is the path the stats are served at. Defaults to /stats.
+	// +optional
+	StatsPath string `json:"statsPath,omitempty"`
+
+	// BounceHost is the return-path host whose MX records must point to
+	// Kannon for bounces to be processed. Defaults to <domainName>.
+	// +optional
+	BounceHost string `json:"bounceHost,omitempty"`
+
+	//+kubebuilder:validation:Required
+	DKIM DKIM `json:"dkim,omitempty"`
+
+	Ingress DomainIngressSpec `json:"ingress,omitempty"`
+
+	// IngressLabels are added to the generated Ingresses and HTTPRoute.
+	// +optional
+	IngressLabels map[string]string `json:"ingressLabels,omitempty"`
+
+	// TLS configures the certificate of the stats Ingress.
+	// +optional
+	TLS *DomainTLSSpec `json:"tls,omitempty"`
+
+	// Stats configures the backend serving the stats host.
+	// +optional
+	Stats *DomainStatsSpec `json:"stats,omitempty"`
+
+	// Routing selects how the stats host is exposed: a networking/v1
+	// Ingress, or a Gateway API HTTPRoute attached to spec.gateway.
+	// Defaults to ingress.
+	// +kubebuilder:validation:Enum=ingress;gatewayAPI
+	// +optional
+	Routing string `json:"routing,omitempty"`
+
+	// Gateway is the Gateway the stats HTTPRoute is attached to. Required
+	// with gatewayAPI routing.
+	// +optional
+	Gateway *DomainGatewaySpec `json:"gateway,omitempty"`
+
+	// DNS configures how the DNS records of the domain are published.
+	// +optional
+	DNS *DomainDNSSpec `json:"dns,omitempty"`
+
+	// Monitoring configures the alerting on the DNS records of the domain.
+	// +optional
+	Monitoring *DomainMonitoringSpec `json:"monitoring,omitempty"`
+
+	// CheckInterval is how often the DNS records are checked once they are
+	// verified. Defaults to 1h, between 1m and 24h.
+	// +optional
+	CheckInterval *metav1.Duration `json:"checkInterval,omitempty"`
+
+	// UnverifiedCheckInterval caps the exponential backoff between the
+	// checks of records that are not verified. Defaults to 1m, between 10s
+	// and 24h.
+	// +optional
+	UnverifiedCheckInterval *metav1.Duration `json:"unverifiedCheckInterval,omitempty"`
+
+	// Suspend stops the reconciliation of the domain: no DNS checks, no
+	// changes to the resources it owns. The deletion is still handled.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// ReportOnly checks the DNS records and reports in the status what the
+	// operator would do, without creating, changing or deleting any
+	// resource: the Ingresses, the Secrets, the DNS records and the Kannon
+	// registration are left as they are.
+	// +optional
+	ReportOnly bool `json:"reportOnly,omitempty"`
+
+	// ResourceLabels are set on every resource the operator creates for
+	// the domain, next to the app.kubernetes.io/managed-by,
+	// app.kubernetes.io/component and kannon.email/domain labels. The
+	// spec.ingressLabels take precedence on the stats route.
+	// +optional
+	ResourceLabels map[string]string `json:"resourceLabels,omitempty"`
+
+	// ResourceAnnotations are set on every resource the operator creates
+	// for the domain. The annotations of spec.ingress take precedence on
+	// the stats route.
+	// +optional
+	ResourceAnnotations map[string]string `json:"resourceAnnotations,omitempty"`
+	// MTASTS hosts an MTA-STS policy for the domain at
+	// https://mta-sts.<domainName>/.well-known/mta-sts.txt.
+	// +optional
+	MTASTS *MTASTSSpec `json:"mtaSTS,omitempty"`
+
+	// TLSRPT checks the TLS-RPT record of the domain, telling senders where
+	// to report the failures to deliver mail over TLS.
+	// +optional
+	TLSRPT *TLSRPTSpec `json:"tlsRPT,omitempty"`
+
+	// DMARC is the DMARC record expected for the domain, and published
+	// with the DNS provider. Defaults to a p=none policy without reports.
+	// +optional
+	DMARC *DMARCSpec `json:"dmarc,omitempty"`
+
+	// BIMI checks the BIMI record of the domain, the logo it points to
+	// and, when set, its Verified Mark Certificate.
+	// +optional
+	BIMI *BIMISpec `json:"bimi,omitempty"`
+
+	// SendingIPs are the addresses Kannon sends the mail of the domain
+	// from. Each must have a PTR record naming a host under the domain or
+	// the base domain, which resolves back to the address.
+	// +kubebuilder:validation:MaxItems=64
+	// +optional
+	SendingIPs []string `json:"sendingIPs,omitempty"`
+
+	// AdditionalDomains are subdomains, or other related domains, the mail
+	// of the domain is also sent from. They share the DKIM key and the SPF
+	// include of the domain, and their records are checked and provisioned
+	// along with its own.
+	// +kubebuilder:validation:MaxItems=64
+	// +optional
+	AdditionalDomains []string `json:"additionalDomains,omitempty"`
+
+	// SenderPoolRef is the SenderPool of the namespace the domain sends
+	// from. The pool checks the reverse DNS and the SPF authorization of
+	// its addresses, its readiness is reported in the SenderPoolReady
+	// condition.
+	// +optional
+	SenderPoolRef *SenderPoolReference `json:"senderPoolRef,omitempty"`
+
+	// KannonCredentialsRef references a Secret with the sending
+	// credentials of a domain already registered with Kannon: the key
+	// under key, and the domain name under domain, which defaults to
+	// domainName. The domain is then not registered by the operator, and
+	// no credentials Secret is created for it.
+	// +optional
+	KannonCredentialsRef *CredentialsSecretReference `json:"kannonCredentialsRef,omitempty"`
+
+	// SenderAlias is the display name Kannon sends the mail of the domain
+	// with, when the send request does not set one.
+	// +kubebuilder:validation:MaxLength=128
+	// +optional
+	SenderAlias string `json:"senderAlias,omitempty"`
+
+	// Delivery sets the thresholds of the HighBounceRate condition, when
+	// the operator receives the delivery webhooks of Kannon.
+	// +optional
+	Delivery *DeliverySpec `json:"delivery,omitempty"`
+
+	// Reputation polls the reputation of the domain and of its sending
+	// addresses from Google Postmaster Tools and Microsoft SNDS.
+	// +optional
+	Reputation *ReputationSpec `json:"reputation,omitempty"`
+
+	// DMARCReports collects the DMARC aggregate reports of the domain, the
+	// ones the rua tag of its DMARC record sends, and summarizes them in
+	// the status.
+	// +optional
+	DMARCReports *DMARCReportsSpec `json:"dmarcReports,omitempty"`
+
+	// Export materializes the runtime configuration of the domain in a
+	// Secret, for the workloads sending through Kannon to mount.
+	// +optional
+	Export *DomainExportSpec `json:"export,omitempty"`
+}
+
+type DomainExportSpec struct {
+	// SecretName is the Secret of the namespace holding the domain name,
+	// the active DKIM selector and, once the domain is registered with
+	// Kannon, its API key. It is kept in sync as the keys rotate.
+	// +kubebuilder:validation:MinLength=1
+	SecretName string `json:"secretName"`
+}
+
+type ReputationSpec struct {
+	// SecretName is the Secret of the namespace holding the credentials of
+	// the reputation services. Google Postmaster Tools is polled when it
+	// holds the OAuth client and the refresh token of an account the
+	// domain is verified with, under postmasterClientID,
+	// postmasterClientSecret and postmasterRefreshToken. Microsoft SNDS is
+	// polled when it holds an automated data access key under sndsKey.
+	// +kubebuilder:validation:MinLength=1
+	SecretName string `json:"secretName"`
+
+	// PollInterval is how often the services are polled. Both publish
+	// their data daily. Defaults to 6 hours.
+	// +optional
+	PollInterval *metav1.Duration `json:"pollInterval,omitempty"`
+}
+
+type DMARCReportsSpec struct {
+	// IMAP polls the reports from a mailbox.
+	// +optional
+	IMAP *DMARCIMAPSpec `json:"imap,omitempty"`
+
+	// HTTP receives the reports on the DMARC report receiver of the
+	// operator, at /dmarc/reports/<namespace>/<name>. The report sources
+	// post the raw XML, a gzip or zip attachment or the whole report
+	// email, with the bearer token under the token key of the Secret.
+	// +optional
+	HTTP *DMARCHTTPSpec `json:"http,omitempty"`
+
+	// Window is how far back the summary goes, by the end of the period of
+	// each report. Defaults to 7 days.
+	// +optional
+	Window *metav1.Duration `json:"window,omitempty"`
+}
+
+type DMARCIMAPSpec struct {
+	// Address is the host and the port of the IMAP server. The connection
+	// is over TLS, e.g. imap.example.com:993.
+	// +kubebuilder:validation:MinLength=1
+	Address string `json:"address"`
+
+	// Mailbox is the mailbox the reports are delivered to. Defaults to
+	// INBOX.
+	// +optional
+	Mailbox string `json:"mailbox,omitempty"`
+
+	// SecretName is the Secret of the namespace holding the username and
+	// the password of the mailbox, under the username and password keys.
+	// +kubebuilder:validation:MinLength=1
+	SecretName string `json:"secretName"`
+
+	// PollInterval is how often the mailbox is polled. Defaults to an
+	// hour.
+	// +optional
+	PollInterval *metav1.Duration `json:"pollInterval,omitempty"`
+}
+
+type DMARCHTTPSpec struct {
+	// SecretName is the Secret of the namespace holding the bearer token
+	// of the report sources, under the token key.
+	// +kubebuilder:validation:MinLength=1
+	SecretName string `json:"secretName"`
+}
+
+type DeliverySpec struct {
+	// MaxBounceRate is the share of the sent messages that can bounce,
+	// e.g. 2.5%. Defaults to 5%.
+	// +kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)?%$`
+	// +optional
+	MaxBounceRate string `json:"maxBounceRate,omitempty"`
+
+	// MaxComplaintRate is the share of the sent messages that can be
+	// reported as spam. Defaults to 0.3%.
+	// +kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)?%$`
+	// +optional
+	MaxComplaintRate string `json:"maxComplaintRate,omitempty"`
+
+	// MinSent is how many messages must be sent in the window before the
+	// rates are compared with the thresholds. Defaults to 100.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MinSent *int64 `json:"minSent,omitempty"`
+}
+
+type SenderPoolReference struct {
+	// Name is the name of the SenderPool.
+	Name string `json:"name"`
+}
+
+type MTASTSSpec struct {
+	// Enabled hosts the policy and checks its TXT record.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// Mode is how senders apply the policy. Defaults to testing.
+	// +kubebuilder:validation:Enum=enforce;testing;none
+	// +optional
+	Mode string `json:"mode,omitempty"`
+
+	// MX are the MX hosts receiving mail for the domain, or wildcard
+	// patterns such as *.mail.example.com. Required when enabled.
+	// +optional
+	MX []string `json:"mx,omitempty"`
+
+	// MaxAge is how long senders cache the policy. Defaults to 7 days.
+	// +optional
+	MaxAge *metav1.Duration `json:"maxAge,omitempty"`
+}
+
+type DMARCSpec struct {
+	// Policy is the p tag of the record, what receivers do with the
+	// messages failing DMARC. Defaults to none.
+	// +kubebuilder:validation:Enum=none;quarantine;reject
+	// +optional
+	Policy string `json:"policy,omitempty"`
+
+	// ReportURIs are the addresses the aggregate reports are sent to, the
+	// rua tag of the record, as mailto: or https: URIs.
+	// +optional
+	ReportURIs []string `json:"reportURIs,omitempty"`
+}
+
+type TLSRPTSpec struct {
+	// ReportURIs are the addresses the reports are sent to, as mailto: or
+	// https: URIs. The _smtp._tls record must list all of them.
+	// +kubebuilder:validation:MinItems=1
+	ReportURIs []string `json:"reportURIs"`
+}
+
+type BIMISpec struct {
+	// Selector is the BIMI selector the record is published under.
+	// Defaults to default.
+	// +optional
+	Selector string `json:"selector,omitempty"`
+
+	// LogoURL is the https URL of the SVG Tiny PS logo.
+	LogoURL string `json:"logoURL"`
+
+	// VMCURL is the https URL of the PEM Verified Mark Certificate of the
+	// logo. When set, the certificate is validated too.
+	// +optional
+	VMCURL string `json:"vmcURL,omitempty"`
+}
+
+type DomainMonitoringSpec struct {
+	// Alerts creates a PrometheusRule firing when a DNS record verified in
+	// the last day stops being verified.
+	// +optional
+	Alerts bool `json:"alerts,omitempty"`
+
+	// For is how long a record must stay unverified before the alert fires.
+	// Defaults to 15m.
+	// +optional
+	For *metav1.Duration `json:"for,omitempty"`
+}
+
+type DomainDNSSpec struct {
+	// AutoProvision publishes the stats CNAME, DKIM and SPF records through
+	// an external-dns DNSEndpoint, or the provider when set, instead of
+	// waiting for them to be created by hand.
+	// +optional
+	AutoProvision bool `json:"autoProvision,omitempty"`
+
+	// Provider publishes the records, and the DMARC record, directly with
+	// the API of a DNS provider instead of external-dns.
+	// +optional
+	Provider *DNSProviderSpec `json:"provider,omitempty"`
+}
+
+type DNSProviderSpec struct {
+	// +kubebuilder:validation:Enum=cloudflare;route53
+	Name string `json:"name"`
+
+	// Zone is the Cloudflare zone name or the Route53 hosted zone ID.
+	// Defaults to domainName, which only works for Cloudflare.
+	// +optional
+	Zone string `json:"zone,omitempty"`
+
+	// CredentialsSecretName references a Secret in the namespace of the
+	// Domain with the provider credentials: api-token for Cloudflare,
+	// access-key-id and secret-access-key (and an optional session-token)
+	// for Route53. Either it or secretRef is required.
+	// +optional
+	CredentialsSecretName string `json:"credentialsSecretName,omitempty"`
+
+	// SecretRef references a Secret with the provider credentials under
+	// other keys, mapped to the ones of credentialsSecretName. It takes
+	// precedence over credentialsSecretName.
+	// +optional
+	SecretRef *CredentialsSecretReference `json:"secretRef,omitempty"`
+}
+
+// CredentialsSecretReference references a Secret of the namespace holding
+// credentials, created by other means than the operator, e.g. an
+// ExternalSecret or a SealedSecret. The operator watches it and
+// reconciles again when it changes.
+type CredentialsSecretReference struct {
+	// Name is the name of the Secret.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Keys maps the credentials to the keys of the Secret holding them,
+	// e.g. api-token: CLOUDFLARE_API_TOKEN. The credentials not mapped are
+	// read from the key of their name.
+	// +optional
+	Keys map[string]string `json:"keys,omitempty"`
+}
+
+type DomainGatewaySpec struct {
+	//+kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Namespace of the Gateway. Defaults to the namespace of the Domain.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// SectionName selects a listener of the Gateway.
+	// +optional
+	SectionName string `json:"sectionName,omitempty"`
+}
+
+type DomainTLSSpec struct {
+	// SecretName references an existing Secret with the certificate of the
+	// stats host. It takes precedence over a cert-manager issuer: when set,
+	// the issuer annotations are not propagated to the Ingress.
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+
+	// ClusterIssuer is the cert-manager ClusterIssuer requested to issue the
+	// certificate of the stats host through the Ingress annotations.
+	// +optional
+	ClusterIssuer string `json:"clusterIssuer,omitempty"`
+}
+
+// DomainIngressSpec configures the stats Ingress. The service, labels, extra
+// hosts and extra paths also apply to the stats HTTPRoute.
+type DomainIngressSpec struct {
+	// Enabled controls whether the stats Ingress, or HTTPRoute, is managed
+	// at all. When false, an existing one is deleted. Defaults to true.
+	// +optional
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// ClassName is the IngressClass of the stats Ingress. When empty, the
+	// cluster default IngressClass is used.
+	ClassName string `json:"className"`
+
+	// Service is the backend of the stats route. When its name is empty,
+	// the service of the ClusterDomainConfig of the operator is used.
+	// +optional
+	Service DomainIngressServiceSpec `json:"service"`
+
+	Annotations map[string]string `json:"annotations"`
+
+	// ExtraHosts are served by the stats Ingress next to the stats host,
+	// with the same paths and TLS Secret. Their DNS records are not checked.
+	// +optional
+	ExtraHosts []string `json:"extraHosts,omitempty"`
+
+	// ExtraPaths are routed to the stats service next to the stats path.
+	// +optional
+	ExtraPaths []string `json:"extraPaths,omitempty"`
+}
+
+type DomainStatsSpec struct {
+	// Hosts are the hosts serving the stats, e.g. click.example.com and
+	// open.example.com. Each one must have a CNAME record to the base
+	// domain, and they share the certificate of the first one. Replaces
+	// spec.statsHost.
+	// +kubebuilder:validation:MaxItems=16
+	// +optional
+	Hosts []string `json:"hosts,omitempty"`
+
+	// SubdomainPrefix is the subdomain of spec.domainName serving the
+	// stats without spec.stats.hosts, e.g. track for track.example.com.
+	// Overrides spec.statsPrefix.
+	// +optional
+	SubdomainPrefix string `json:"subdomainPrefix,omitempty"`
+
+	// Paths are the only paths routed to the stats service, e.g. /o/, /c/
+	// and /px/, leaving the rest of the hosts to the site already serving
+	// them. Replaces spec.statsPath and spec.ingress.extraPaths.
+	// +kubebuilder:validation:MaxItems=32
+	// +optional
+	Paths []string `json:"paths,omitempty"`
+
+	// RewriteHost replaces the Host header of the requests forwarded to the
+	// stats service. The HTTPRoute rewrites it with a URLRewrite filter, the
+	// Ingress with the upstream-vhost annotation of ingress-nginx.
+	// +optional
+	RewriteHost string `json:"rewriteHost,omitempty"`
+
+	// NetworkPolicy restricts the traffic to the pods of the stats Service
+	// to the ingress controller.
+	// +optional
+	NetworkPolicy *StatsNetworkPolicySpec `json:"networkPolicy,omitempty"`
+}
+
+type StatsNetworkPolicySpec struct {
+	// Enabled creates a NetworkPolicy owned by the Domain, only allowing
+	// the ingress controller to reach the stats Service on its port.
+	Enabled bool `json:"enabled"`
+
+	// IngressNamespace is the namespace of the ingress controller. Defaults
+	// to the one the operator is configured with.
+	// +optional
+	IngressNamespace string `json:"ingressNamespace,omitempty"`
+
+	// IngressPodSelector narrows the allowed traffic to the pods of the
+	// ingress controller matching it.
+	// +optional
+	IngressPodSelector *metav1.LabelSelector `json:"ingressPodSelector,omitempty"`
+}
+
+type DomainIngressServiceSpec struct {
+	// +optional
+	Name string `json:"name"`
+
+	// +optional
+	Port int32 `json:"port"`
+}
+
+type DKIM struct {
+	//+kubebuilder:default=kannon
+	Selector string `json:"selector,omitempty"`
+
+	// PublicKey is the DKIM public key published in DNS. When empty, a key
+	// pair is generated and stored in a Secret owned by the Domain.
+	// +optional
+	PublicKey string `json:"publicKey,omitempty"`
+
+	// KeyType is the algorithm of the key. Defaults to rsa.
+	// +kubebuilder:validation:Enum=rsa;ed25519
+	// +optional
+	KeyType string `json:"keyType,omitempty"`
+
+	// RotationPeriod is how often a generated key is replaced. A new key is
+	// published under a new selector, and becomes active once its DNS record
+	// is verified. Rotation is disabled when unset.
+	// +optional
+	RotationPeriod *metav1.Duration `json:"rotationPeriod,omitempty"`
+}
+
+// DomainStatus defines the observed state of Domain
+type DomainStatus struct {
+	// ObservedGeneration is the generation of the spec last reconciled.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	DNS DNSStatus `json:"dns"`
+
+	// LastCheckTime is when the DNS records were last checked. While the
+	// checks keep the same outcome it is refreshed at most every hour.
+	// +optional
+	LastCheckTime *metav1.Time `json:"lastCheckTime,omitempty"`
+
+	// FailedChecks counts the consecutive reconciles in which the DNS checks
+	// did not pass. It drives the backoff between rechecks, and once the
+	// backoff is capped it is only written along with other changes.
+	FailedChecks int `json:"failedChecks,omitempty"`
+
+	// LastRecheck is the value of the recheck annotation last acted upon.
+	// +optional
+	LastRecheck string `json:"lastRecheck,omitempty"`
+
+	// Certificate describes the TLS certificate of the stats host.
+	// +optional
+	Certificate *CertificateStatus `json:"certificate,omitempty"`
+
+	// DKIM describes the generated DKIM key, when the spec does not provide
+	// a public key.
+	// +optional
+	DKIM *DKIMStatus `json:"dkim,omitempty"`
+
+	// OriginalSPF are the SPF records amended with the DNS provider, as
+	// they were before, restored when the Domain is deleted.
+	// +optional
+	OriginalSPF []OriginalSPFRecord `json:"originalSPF,omitempty"`
+
+	// Delivery are the counters of the delivery webhooks received for the
+	// domain, when the operator receives them.
+	// +optional
+	Delivery *DeliveryStatus `json:"delivery,omitempty"`
+
+	// Reputation is the reputation of the domain and of its sending
+	// addresses last polled, with spec.reputation.
+	// +optional
+	Reputation *ReputationStatus `json:"reputation,omitempty"`
+
+	// DMARCReports is the summary of the DMARC aggregate reports received
+	// for the domain, with spec.dmarcReports.
+	// +optional
+	DMARCReports *DMARCReportsStatus `json:"dmarcReports,omitempty"`
+
+	// Ownership is the challenge proving the control of the domain, when
+	// the operator requires it. The stats Ingress is created and the domain
+	// registered with Kannon once it is verified.
+	// +optional
+	Ownership *OwnershipStatus `json:"ownership,omitempty"`
+
+	// KannonSettingsHash is the hash of the sender settings last pushed to
+	// Kannon: the active DKIM selector, the sender alias and the sender
+	// pool. They are pushed again when it changes.
+	// +optional
+	KannonSettingsHash string `json:"kannonSettingsHash,omitempty"`
+
+	// Conditions represent the latest available observations of the Domain state.
+	// +optional
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+}
+
+type CertificateStatus struct {
+	// SecretName is the Secret holding the certificate.
+	SecretName string `json:"secretName"`
+
+	// NotAfter is when the certificate expires.
+	// +optional
+	NotAfter *metav1.Time `json:"notAfter,omitempty"`
+}
+
+type DKIMStatus struct {
+	// DKIMKey is the active key.
+	DKIMKey `json:",inline"`
+
+	// Pending is the key being rotated in, waiting for its DNS record to be
+	// verified.
+	// +optional
+	Pending *DKIMKey `json:"pending,omitempty"`
+
+	// RetiringKeys are the keys replaced by the rotations, each kept until
+	// the messages it signed are no longer verified.
+	// +optional
+	RetiringKeys []RetiringDKIMKey `json:"retiringKeys,omitempty"`
+}
+
+type DKIMKey struct {
+	// Selector is the DKIM selector the key is published under.
+	// +optional
+	Selector string `json:"selector,omitempty"`
+
+	// SecretName is the Secret holding the generated key pair.
+	SecretName string `json:"secretName"`
+
+	// KeyType is the algorithm of the generated key.
+	KeyType string `json:"keyType"`
+
+	// PublicKey is the generated public key to publish in DNS.
+	PublicKey string `json:"publicKey"`
+
+	// CreatedAt is when the key was generated.
+	// +optional
+	CreatedAt *metav1.Time `json:"createdAt,omitempty"`
+}
+
+type RetiringDKIMKey struct {
+	// Selector is the DKIM selector the key was published under.
+	Selector string `json:"selector"`
+
+	// SecretName is the Secret holding the retired key pair.
+	SecretName string `json:"secretName"`
+
+	// RetireAt is when the Secret is deleted.
+	RetireAt metav1.Time `json:"retireAt"`
+
+	// KeyType is the algorithm of the key.
+	// +optional
+	KeyType string `json:"keyType,omitempty"`
+
+	// PublicKey is kept published until the key is retired.
+	// +optional
+	PublicKey string `json:"publicKey,omitempty"`
+}
+
+type DNSStatus struct {
+	// LastCheckTime is when the DNS records were last checked.
+	// +optional
+	LastCheckTime *metav1.Time `json:"lastCheckTime,omitempty"`
+
+	Stats DNSRecordStatus  `json:"stats"`
+	DKIM  DKIMRecordStatus `json:"dkim"`
+	SPF   SPFRecordStatus  `json:"spf"`
+
+	// MX reports whether the MX records of the bounce host point to Kannon.
+	// It is informational and does not affect the Ready condition.
+	// +optional
+	MX MXStatus `json:"mx"`
+
+	// DMARC reports whether the domain publishes a DMARC record. It is
+	// informational and does not affect the Ready condition.
+	// +optional
+	DMARC DMARCStatus `json:"dmarc"`
+
+	// MTASTS reports whether the MTA-STS TXT record announces the hosted
+	// policy and the policy is served. It is only set with
+	// spec.mtaSTS.enabled, and does not affect the Ready condition.
+	// +optional
+	MTASTS *MTASTSStatus `json:"mtaSTS,omitempty"`
+
+	// TLSRPT reports whether the _smtp._tls TXT record lists the report
+	// URIs of spec.tlsRPT. It is only set with spec.tlsRPT, and does not
+	// affect the Ready condition.
+	// +optional
+	TLSRPT *DNSRecordStatus `json:"tlsRPT,omitempty"`
+
+	// BIMI reports whether the BIMI record points to the logo and the
+	// certificate of spec.bimi, and whether they are valid. It is only set
+	// with spec.bimi, and does not affect the Ready condition.
+	// +optional
+	BIMI *BIMIStatus `json:"bimi,omitempty"`
+
+	// PTR reports whether the reverse DNS of every address of
+	// spec.sendingIPs is verified, in the same order. It is informational
+	// and does not affect the Ready condition.
+	// +optional
+	PTR []PTRStatus `json:"ptr,omitempty"`
+
+	// AdditionalDomains reports whether the DKIM and SPF records of every
+	// domain of spec.additionalDomains are verified, in the same order. It
+	// does not affect the Ready condition.
+	// +optional
+	AdditionalDomains []AdditionalDomainStatus `json:"additionalDomains,omitempty"`
+}
+
+type AdditionalDomainStatus struct {
+	// Domain is the checked additional domain.
+	Domain string `json:"domain"`
+
+	// DKIM is the check of the DKIM record of the domain, published with
+	// the selector and the key of the Domain.
+	DKIM DNSRecordStatus `json:"dkim"`
+
+	// SPF is the check of the SPF record of the domain.
+	SPF SPFRecordStatus `json:"spf"`
+}
+
+type PTRStatus struct {
+	// IP is the checked sending address.
+	IP string `json:"ip"`
+
+	// DNSRecordStatus is the check of the PTR record of the address.
+	DNSRecordStatus `json:",inline"`
+
+	// Host is the name of the PTR record found resolving back to the
+	// address.
+	// +optional
+	Host string `json:"host,omitempty"`
+}
+
+// OriginalSPFRecord is an SPF record before the operator amended it.
+type OriginalSPFRecord struct {
+	// Name is the name of the record.
+	Name string `json:"name"`
+
+	// Value is the record, empty when the name had none.
+	// +optional
+	Value string `json:"value,omitempty"`
+}
+
+type DeliveryStatus struct {
+	// Window is how far back the counters go.
+	Window metav1.Duration `json:"window"`
+
+	Sent       int64 `json:"sent"`
+	Delivered  int64 `json:"delivered"`
+	Bounced    int64 `json:"bounced"`
+	Complained int64 `json:"complained"`
+
+	// BounceRate is the share of the sent messages that bounced, e.g.
+	// 1.20%.
+	BounceRate string `json:"bounceRate"`
+
+	// ComplaintRate is the share of the sent messages reported as spam.
+	ComplaintRate string `json:"complaintRate"`
+
+	// UpdatedAt is when the counters were last published.
+	// +optional
+	UpdatedAt *metav1.Time `json:"updatedAt,omitempty"`
+
+	// Buckets are the hourly counters the totals are summed from. Every
+	// replica receiving the webhooks adds its counts to them.
+	// +optional
+	Buckets []DeliveryBucket `json:"buckets,omitempty"`
+}
+
+// DeliveryBucket are the counters of the hour starting at Start.
+type DeliveryBucket struct {
+	Start metav1.Time `json:"start"`
+
+	Sent       int64 `json:"sent,omitempty"`
+	Delivered  int64 `json:"delivered,omitempty"`
+	Bounced    int64 `json:"bounced,omitempty"`
+	Complained int64 `json:"complained,omitempty"`
+}
+
+type DMARCReportsStatus struct {
+	// Window is how far back the summary goes.
+	Window metav1.Duration `json:"window"`
+
+	// Reports is how many reports the summary counts.
+	Reports int `json:"reports"`
+
+	// Reporters are the organizations that sent the reports, e.g.
+	// google.com.
+	// +optional
+	Reporters []string `json:"reporters,omitempty"`
+
+	// Messages is how many messages the reports evaluated.
+	Messages int64 `json:"messages"`
+
+	// DKIMAlignedRate is the share of the messages with an aligned DKIM
+	// signature that verified, e.g. 98.50%.
+	DKIMAlignedRate string `json:"dkimAlignedRate"`
+
+	// SPFAlignedRate is the share of the messages sent from an address the
+	// aligned SPF record authorizes.
+	SPFAlignedRate string `json:"spfAlignedRate"`
+
+	// PassRate is the share of the messages that passed DMARC, with either
+	// aligned mechanism.
+	PassRate string `json:"passRate"`
+
+	// TopFailingSources are the sources of most of the messages that
+	// failed DMARC, by decreasing count.
+	// +optional
+	TopFailingSources []DMARCSource `json:"topFailingSources,omitempty"`
+
+	// LastReportEnd is the end of the period of the latest report.
+	// +optional
+	LastReportEnd *metav1.Time `json:"lastReportEnd,omitempty"`
+
+	// PolledAt is when the mailbox of spec.dmarcReports.imap was last
+	// polled.
+	// +optional
+	PolledAt *metav1.Time `json:"polledAt,omitempty"`
+
+	// Error is why the mailbox could not be polled. It is polled again by
+	// the next reconcile.
+	// +optional
+	Error string `json:"error,omitempty"`
+}
+
+type DMARCSource struct {
+	// IP is the address the messages were sent from.
+	IP string `json:"ip"`
+
+	// HeaderFrom is the domain of the From header of the messages.
+	// +optional
+	HeaderFrom string `json:"headerFrom,omitempty"`
+
+	// DKIM and SPF are the aligned results of the mechanisms, pass or
+	// fail.
+	DKIM string `json:"dkim"`
+	SPF  string `json:"spf"`
+
+	// Messages is how many messages failed DMARC.
+	Messages int64 `json:"messages"`
+}
+
+type ReputationStatus struct {
+	// PolledAt is when the services were last polled.
+	// +optional
+	PolledAt *metav1.Time `json:"polledAt,omitempty"`
+
+	// Error is why the services could not be polled, e.g. a missing
+	// Secret. They are polled again by the next reconcile.
+	// +optional
+	Error string `json:"error,omitempty"`
+
+	// Postmaster is the latest traffic statistics of Google Postmaster
+	// Tools.
+	// +optional
+	Postmaster *PostmasterReputation `json:"postmaster,omitempty"`
+
+	// SNDS is the latest data of Microsoft SNDS.
+	// +optional
+	SNDS *SNDSReputation `json:"snds,omitempty"`
+}
+
+type PostmasterReputation struct {
+	// Date is the day of the statistics, e.g. 2023-01-31.
+	// +optional
+	Date string `json:"date,omitempty"`
+
+	// DomainReputation is HIGH, MEDIUM, LOW or BAD.
+	// +optional
+	DomainReputation string `json:"domainReputation,omitempty"`
+
+	// SpamRate is the share of the messages delivered to the inbox that
+	// the users reported as spam, e.g. 0.10%.
+	// +optional
+	SpamRate string `json:"spamRate,omitempty"`
+
+	// IPReputations are how many sending addresses have each reputation.
+	// +optional
+	IPReputations []PostmasterIPReputation `json:"ipReputations,omitempty"`
+
+	// Error is why the last poll failed. The statistics are the ones of the
+	// previous poll.
+	// +optional
+	Error string `json:"error,omitempty"`
+}
+
+type PostmasterIPReputation struct {
+	// Reputation is HIGH, MEDIUM, LOW or BAD.
+	Reputation string `json:"reputation"`
+
+	// IPCount is how many addresses have the reputation.
+	IPCount int64 `json:"ipCount"`
+
+	// SampleIPs are some of the addresses.
+	// +optional
+	SampleIPs []string `json:"sampleIPs,omitempty"`
+}
+
+type SNDSReputation struct {
+	// IPs is the data of the sending addresses of the domain: the ones of
+	// its SenderPool, or all the ones of the key without one.
+	// +optional
+	IPs []SNDSIPReputation `json:"ips,omitempty"`
+
+	// Error is why the last poll failed. The data is the one of the
+	// previous poll.
+	// +optional
+	Error string `json:"error,omitempty"`
+}
+
+type SNDSIPReputation struct {
+	// IP is the sending address.
+	IP string `json:"ip"`
+
+	// ActivityEnd is the end of the period of the data.
+	// +optional
+	ActivityEnd *metav1.Time `json:"activityEnd,omitempty"`
+
+	// MessageRecipients is how many recipients the address sent to.
+	MessageRecipients int64 `json:"messageRecipients"`
+
+	// FilterResult is GREEN, YELLOW or RED, how much of the mail of the
+	// address the Outlook.com filters consider spam.
+	FilterResult string `json:"filterResult"`
+
+	// ComplaintRate is the share of the recipients who reported the mail
+	// of the address as junk, e.g. < 0.1%.
+	ComplaintRate string `json:"complaintRate"`
+
+	// TrapHits is how many messages reached spam traps.
+	TrapHits int64 `json:"trapHits"`
+}
+
+type OwnershipStatus struct {
+	// Domain is the domain name the token was generated for. A new token is
+	// generated when spec.domainName changes.
+	Domain string `json:"domain"`
+
+	// Token is the random value the challenge TXT record must hold.
+	Token string `json:"token"`
+
+	// DNSRecordStatus is the check of the challenge TXT record. Its expected
+	// record is the one to publish. Once verified the record is not checked
+	// anymore and can be removed.
+	DNSRecordStatus `json:",inline"`
+}
+
+type BIMIStatus struct {
+	// DNSRecordStatus is the check of the BIMI TXT record.
+	DNSRecordStatus `json:",inline"`
+
+	// Indicator is the check of the SVG logo served at spec.bimi.logoURL.
+	// +optional
+	Indicator DNSRecordStatus `json:"indicator"`
+
+	// VMC is the check of the certificate served at spec.bimi.vmcURL. It
+	// is only set with a vmcURL.
+	// +optional
+	VMC *DNSRecordStatus `json:"vmc,omitempty"`
+}
+
+type MTASTSStatus struct {
+	// DNSRecordStatus is the check of the _mta-sts TXT record.
+	DNSRecordStatus `json:",inline"`
+
+	// Policy is the check of the policy served at the policy host.
+	// +optional
+	Policy DNSRecordStatus `json:"policy"`
+
+	// PolicyID is the id of the hosted policy.
+	// +optional
+	PolicyID string `json:"policyID,omitempty"`
+}
+
+type MXStatus struct {
+	DNSRecordStatus `json:",inline"`
+
+	// Hosts are the MX hosts found for the bounce host.
+	// +optional
+	Hosts []string `json:"hosts,omitempty"`
+}
+
+type SPFRecordStatus struct {
+	DNSRecordStatus `json:",inline"`
+
+	// Lookups is how many DNS lookups receivers do to evaluate the SPF
+	// record and the records it includes or redirects to. Past 10 the
+	// record fails with a permerror.
+	// +optional
+	Lookups int `json:"lookups,omitempty"`
+
+	// PermError explains why receivers fail the SPF record with a
+	// permerror: too many lookups, an include without a SPF record, more
+	// than one SPF record or a syntax error.
+	// +optional
+	PermError string `json:"permError,omitempty"`
+}
+
+type DMARCStatus struct {
+	DNSRecordStatus `json:",inline"`
+
+	// Policy is the p tag of the DMARC record: none, quarantine or reject.
+	// +optional
+	Policy string `json:"policy,omitempty"`
+}
+
+// CheckState is the outcome of a DNS check.
+// +kubebuilder:validation:Enum=Verified;Missing;Unknown
+type CheckState string
+
+const (
+	// CheckStateVerified means the expected record was found.
+	CheckStateVerified CheckState = "Verified"
+	// CheckStateMissing means the resolvers answered but the expected record was not there.
+	CheckStateMissing CheckState = "Missing"
+	// CheckStateUnknown means the resolvers failed to answer, so the record state is not known.
+	CheckStateUnknown CheckState = "Unknown"
+)
+
+// CheckErrorClass tells why the resolvers failed to answer a check.
+// +kubebuilder:validation:Enum=Timeout;ServerFailure;Temporary;ResolverUnavailable
+type CheckErrorClass string
+
+type DNSRecordStatus struct {
+	// Verified is true when State is Verified. It stays true when the
+	// record was verified and the last check could not tell, as resolver
+	// errors don't undo a verification.
+	Verified bool `json:"verified"`
+
+	// State is the outcome of the check.
+	// +optional
+	State CheckState `json:"state,omitempty"`
+
+	// Message is why the record does not match when State is Missing.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Error describes the resolver errors when State is Unknown.
+	// +optional
+	Error string `json:"error,omitempty"`
+
+	// ErrorClass classifies the resolver errors when State is Unknown,
+	// empty when they are of no known class.
+	// +optional
+	ErrorClass CheckErrorClass `json:"errorClass,omitempty"`
+
+	// Expected is the record the check looks for, ready to be entered in
+	// a DNS provider.
+	// +optional
+	Expected *DNSRecord `json:"expected,omitempty"`
+
+	// ObservedValue is the value the resolvers returned for the record
+	// name, when they returned a single one.
+	// +optional
+	ObservedValue string `json:"observedValue,omitempty"`
+
+	// Observed are the values the resolvers returned for the record name.
+	// +optional
+	Observed []string `json:"observed,omitempty"`
+
+	// Resolvers are the outcomes of the check with each resolver.
+	// +optional
+	Resolvers []ResolverStatus `json:"resolvers,omitempty"`
+
+	// LastCheckTime is when the record was last checked.
+	// +optional
+	LastCheckTime *metav1.Time `json:"lastCheckTime,omitempty"`
+
+	// LastVerified is when the record was last found verified.
+	// +optional
+	LastVerified *metav1.Time `json:"lastVerified,omitempty"`
+
+	// LastTransitionTime is when the state of the check last changed.
+	// +optional
+	LastTransitionTime *metav1.Time `json:"lastTransitionTime,omitempty"`
+
+	// Counts are how many resolvers verified the record, failed to answer
+	// and returned a mismatching record.
+	// +optional
+	Counts CheckCounts `json:"counts"`
+}
+
+type DKIMRecordStatus struct {
+	DNSRecordStatus `json:",inline"`
+
+	// Selector is the DKIM selector the checked record is published under.
+	// +optional
+	Selector string `json:"selector,omitempty"`
+}
+
+type CheckCounts struct {
+	Verified int `json:"verified"`
+	Failed   int `json:"failed"`
+	Mismatch int `json:"mismatch"`
+}
+
+// ResolverStatus is the outcome of a DNS check with a single resolver.
+type ResolverStatus struct {
+	// Resolver is the address or the endpoint of the resolver.
+	Resolver string `json:"resolver"`
+
+	// State is the outcome of the check with the resolver.
+	State CheckState `json:"state"`
+
+	// Message is the error of the resolver when State is Unknown.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// ErrorClass classifies the error of the resolver when State is
+	// Unknown.
+	// +optional
+	ErrorClass CheckErrorClass `json:"errorClass,omitempty"`
+}
+
+type DNSRecord struct {
+	// Type is the record type, e.g. TXT or CNAME.
+	Type string `json:"type"`
+	// Name is the fully qualified name of the record.
+	Name string `json:"name"`
+	// Value is the content of the record.
+	Value string `json:"value"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:shortName=dom
+
+// Domain is the Schema for the domains API
+// +kubebuilder:printcolumn:name="Domain",type=string,JSONPath=`.spec.domainName`
+// +kubebuilder:printcolumn:name="Base Domain",type=string,JSONPath=`.spec.baseDomain`
+// +kubebuilder:printcolumn:name="DNS Check DKIM",type=string,JSONPath=`.status.dns.dkim.state`
+// +kubebuilder:printcolumn:name="DNS Check SPF",type=string,JSONPath=`.status.dns.spf.state`
+// +kubebuilder:printcolumn:name="DNS Check Stats",type=string,JSONPath=`.status.dns.stats.state`
+// +kubebuilder:printcolumn:name="DNS Check DMARC",type=string,JSONPath=`.status.dns.dmarc.state`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Last Check",type=date,JSONPath=`.status.lastCheckTime`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+type Domain struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DomainSpec   `json:"spec,omitempty"`
+	Status DomainStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// DomainList contains a list of Domain
+type DomainList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Domain `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Domain{}, &DomainList{})
+}
@@ -0,0 +1,1330 @@
+//go:build !ignore_autogenerated
+// +build !ignore_autogenerated
+
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by controller-gen. DO NOT EDIT.
+
+package v1beta1
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+)
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdditionalDomainStatus) DeepCopyInto(out *AdditionalDomainStatus) {
+	*out = *in
+	in.DKIM.DeepCopyInto(&out.DKIM)
+	in.SPF.DeepCopyInto(&out.SPF)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdditionalDomainStatus.
+func (in *AdditionalDomainStatus) DeepCopy() *AdditionalDomainStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AdditionalDomainStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BIMISpec) DeepCopyInto(out *BIMISpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BIMISpec.
+func (in *BIMISpec) DeepCopy() *BIMISpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BIMISpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BIMIStatus) DeepCopyInto(out *BIMIStatus) {
+	*out = *in
+	in.DNSRecordStatus.DeepCopyInto(&out.DNSRecordStatus)
+	in.Indicator.DeepCopyInto(&out.Indicator)
+	if in.VMC != nil {
+		in, out := &in.VMC, &out.VMC
+		*out = new(DNSRecordStatus)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BIMIStatus.
+func (in *BIMIStatus) DeepCopy() *BIMIStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BIMIStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertificateStatus) DeepCopyInto(out *CertificateStatus) {
+	*out = *in
+	if in.NotAfter != nil {
+		in, out := &in.NotAfter, &out.NotAfter
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertificateStatus.
+func (in *CertificateStatus) DeepCopy() *CertificateStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CertificateStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CheckCounts) DeepCopyInto(out *CheckCounts) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CheckCounts.
+func (in *CheckCounts) DeepCopy() *CheckCounts {
+	if in == nil {
+		return nil
+	}
+	out := new(CheckCounts)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CredentialsSecretReference) DeepCopyInto(out *CredentialsSecretReference) {
+	*out = *in
+	if in.Keys != nil {
+		in, out := &in.Keys, &out.Keys
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CredentialsSecretReference.
+func (in *CredentialsSecretReference) DeepCopy() *CredentialsSecretReference {
+	if in == nil {
+		return nil
+	}
+	out := new(CredentialsSecretReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DKIM) DeepCopyInto(out *DKIM) {
+	*out = *in
+	if in.RotationPeriod != nil {
+		in, out := &in.RotationPeriod, &out.RotationPeriod
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DKIM.
+func (in *DKIM) DeepCopy() *DKIM {
+	if in == nil {
+		return nil
+	}
+	out := new(DKIM)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DKIMKey) DeepCopyInto(out *DKIMKey) {
+	*out = *in
+	if in.CreatedAt != nil {
+		in, out := &in.CreatedAt, &out.CreatedAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DKIMKey.
+func (in *DKIMKey) DeepCopy() *DKIMKey {
+	if in == nil {
+		return nil
+	}
+	out := new(DKIMKey)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DKIMRecordStatus) DeepCopyInto(out *DKIMRecordStatus) {
+	*out = *in
+	in.DNSRecordStatus.DeepCopyInto(&out.DNSRecordStatus)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DKIMRecordStatus.
+func (in *DKIMRecordStatus) DeepCopy() *DKIMRecordStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DKIMRecordStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DKIMStatus) DeepCopyInto(out *DKIMStatus) {
+	*out = *in
+	in.DKIMKey.DeepCopyInto(&out.DKIMKey)
+	if in.Pending != nil {
+		in, out := &in.Pending, &out.Pending
+		*out = new(DKIMKey)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RetiringKeys != nil {
+		in, out := &in.RetiringKeys, &out.RetiringKeys
+		*out = make([]RetiringDKIMKey, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DKIMStatus.
+func (in *DKIMStatus) DeepCopy() *DKIMStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DKIMStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DMARCHTTPSpec) DeepCopyInto(out *DMARCHTTPSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DMARCHTTPSpec.
+func (in *DMARCHTTPSpec) DeepCopy() *DMARCHTTPSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DMARCHTTPSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DMARCIMAPSpec) DeepCopyInto(out *DMARCIMAPSpec) {
+	*out = *in
+	if in.PollInterval != nil {
+		in, out := &in.PollInterval, &out.PollInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DMARCIMAPSpec.
+func (in *DMARCIMAPSpec) DeepCopy() *DMARCIMAPSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DMARCIMAPSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DMARCReportsSpec) DeepCopyInto(out *DMARCReportsSpec) {
+	*out = *in
+	if in.IMAP != nil {
+		in, out := &in.IMAP, &out.IMAP
+		*out = new(DMARCIMAPSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HTTP != nil {
+		in, out := &in.HTTP, &out.HTTP
+		*out = new(DMARCHTTPSpec)
+		**out = **in
+	}
+	if in.Window != nil {
+		in, out := &in.Window, &out.Window
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DMARCReportsSpec.
+func (in *DMARCReportsSpec) DeepCopy() *DMARCReportsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DMARCReportsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DMARCReportsStatus) DeepCopyInto(out *DMARCReportsStatus) {
+	*out = *in
+	out.Window = in.Window
+	if in.Reporters != nil {
+		in, out := &in.Reporters, &out.Reporters
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.TopFailingSources != nil {
+		in, out := &in.TopFailingSources, &out.TopFailingSources
+		*out = make([]DMARCSource, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastReportEnd != nil {
+		in, out := &in.LastReportEnd, &out.LastReportEnd
+		*out = (*in).DeepCopy()
+	}
+	if in.PolledAt != nil {
+		in, out := &in.PolledAt, &out.PolledAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DMARCReportsStatus.
+func (in *DMARCReportsStatus) DeepCopy() *DMARCReportsStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DMARCReportsStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DMARCSource) DeepCopyInto(out *DMARCSource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DMARCSource.
+func (in *DMARCSource) DeepCopy() *DMARCSource {
+	if in == nil {
+		return nil
+	}
+	out := new(DMARCSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DMARCSpec) DeepCopyInto(out *DMARCSpec) {
+	*out = *in
+	if in.ReportURIs != nil {
+		in, out := &in.ReportURIs, &out.ReportURIs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DMARCSpec.
+func (in *DMARCSpec) DeepCopy() *DMARCSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DMARCSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DMARCStatus) DeepCopyInto(out *DMARCStatus) {
+	*out = *in
+	in.DNSRecordStatus.DeepCopyInto(&out.DNSRecordStatus)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DMARCStatus.
+func (in *DMARCStatus) DeepCopy() *DMARCStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DMARCStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DNSProviderSpec) DeepCopyInto(out *DNSProviderSpec) {
+	*out = *in
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(CredentialsSecretReference)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DNSProviderSpec.
+func (in *DNSProviderSpec) DeepCopy() *DNSProviderSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DNSProviderSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DNSRecord) DeepCopyInto(out *DNSRecord) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DNSRecord.
+func (in *DNSRecord) DeepCopy() *DNSRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(DNSRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DNSRecordStatus) DeepCopyInto(out *DNSRecordStatus) {
+	*out = *in
+	if in.Expected != nil {
+		in, out := &in.Expected, &out.Expected
+		*out = new(DNSRecord)
+		**out = **in
+	}
+	if in.Observed != nil {
+		in, out := &in.Observed, &out.Observed
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Resolvers != nil {
+		in, out := &in.Resolvers, &out.Resolvers
+		*out = make([]ResolverStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastCheckTime != nil {
+		in, out := &in.LastCheckTime, &out.LastCheckTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastVerified != nil {
+		in, out := &in.LastVerified, &out.LastVerified
+		*out = (*in).DeepCopy()
+	}
+	if in.LastTransitionTime != nil {
+		in, out := &in.LastTransitionTime, &out.LastTransitionTime
+		*out = (*in).DeepCopy()
+	}
+	out.Counts = in.Counts
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DNSRecordStatus.
+func (in *DNSRecordStatus) DeepCopy() *DNSRecordStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DNSRecordStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DNSStatus) DeepCopyInto(out *DNSStatus) {
+	*out = *in
+	if in.LastCheckTime != nil {
+		in, out := &in.LastCheckTime, &out.LastCheckTime
+		*out = (*in).DeepCopy()
+	}
+	in.Stats.DeepCopyInto(&out.Stats)
+	in.DKIM.DeepCopyInto(&out.DKIM)
+	in.SPF.DeepCopyInto(&out.SPF)
+	in.MX.DeepCopyInto(&out.MX)
+	in.DMARC.DeepCopyInto(&out.DMARC)
+	if in.MTASTS != nil {
+		in, out := &in.MTASTS, &out.MTASTS
+		*out = new(MTASTSStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TLSRPT != nil {
+		in, out := &in.TLSRPT, &out.TLSRPT
+		*out = new(DNSRecordStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BIMI != nil {
+		in, out := &in.BIMI, &out.BIMI
+		*out = new(BIMIStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PTR != nil {
+		in, out := &in.PTR, &out.PTR
+		*out = make([]PTRStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.AdditionalDomains != nil {
+		in, out := &in.AdditionalDomains, &out.AdditionalDomains
+		*out = make([]AdditionalDomainStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DNSStatus.
+func (in *DNSStatus) DeepCopy() *DNSStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DNSStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeliveryBucket) DeepCopyInto(out *DeliveryBucket) {
+	*out = *in
+	in.Start.DeepCopyInto(&out.Start)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeliveryBucket.
+func (in *DeliveryBucket) DeepCopy() *DeliveryBucket {
+	if in == nil {
+		return nil
+	}
+	out := new(DeliveryBucket)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeliverySpec) DeepCopyInto(out *DeliverySpec) {
+	*out = *in
+	if in.MinSent != nil {
+		in, out := &in.MinSent, &out.MinSent
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeliverySpec.
+func (in *DeliverySpec) DeepCopy() *DeliverySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DeliverySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeliveryStatus) DeepCopyInto(out *DeliveryStatus) {
+	*out = *in
+	out.Window = in.Window
+	if in.UpdatedAt != nil {
+		in, out := &in.UpdatedAt, &out.UpdatedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Buckets != nil {
+		in, out := &in.Buckets, &out.Buckets
+		*out = make([]DeliveryBucket, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeliveryStatus.
+func (in *DeliveryStatus) DeepCopy() *DeliveryStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DeliveryStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Domain) DeepCopyInto(out *Domain) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Domain.
+func (in *Domain) DeepCopy() *Domain {
+	if in == nil {
+		return nil
+	}
+	out := new(Domain)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Domain) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DomainDNSSpec) DeepCopyInto(out *DomainDNSSpec) {
+	*out = *in
+	if in.Provider != nil {
+		in, out := &in.Provider, &out.Provider
+		*out = new(DNSProviderSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DomainDNSSpec.
+func (in *DomainDNSSpec) DeepCopy() *DomainDNSSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DomainDNSSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DomainExportSpec) DeepCopyInto(out *DomainExportSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DomainExportSpec.
+func (in *DomainExportSpec) DeepCopy() *DomainExportSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DomainExportSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DomainGatewaySpec) DeepCopyInto(out *DomainGatewaySpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DomainGatewaySpec.
+func (in *DomainGatewaySpec) DeepCopy() *DomainGatewaySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DomainGatewaySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DomainIngressServiceSpec) DeepCopyInto(out *DomainIngressServiceSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DomainIngressServiceSpec.
+func (in *DomainIngressServiceSpec) DeepCopy() *DomainIngressServiceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DomainIngressServiceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DomainIngressSpec) DeepCopyInto(out *DomainIngressSpec) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+	out.Service = in.Service
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ExtraHosts != nil {
+		in, out := &in.ExtraHosts, &out.ExtraHosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExtraPaths != nil {
+		in, out := &in.ExtraPaths, &out.ExtraPaths
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DomainIngressSpec.
+func (in *DomainIngressSpec) DeepCopy() *DomainIngressSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DomainIngressSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DomainList) DeepCopyInto(out *DomainList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Domain, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DomainList.
+func (in *DomainList) DeepCopy() *DomainList {
+	if in == nil {
+		return nil
+	}
+	out := new(DomainList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DomainList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DomainMonitoringSpec) DeepCopyInto(out *DomainMonitoringSpec) {
+	*out = *in
+	if in.For != nil {
+		in, out := &in.For, &out.For
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DomainMonitoringSpec.
+func (in *DomainMonitoringSpec) DeepCopy() *DomainMonitoringSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DomainMonitoringSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DomainSpec) DeepCopyInto(out *DomainSpec) {
+	*out = *in
+	in.DKIM.DeepCopyInto(&out.DKIM)
+	in.Ingress.DeepCopyInto(&out.Ingress)
+	if in.IngressLabels != nil {
+		in, out := &in.IngressLabels, &out.IngressLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(DomainTLSSpec)
+		**out = **in
+	}
+	if in.Stats != nil {
+		in, out := &in.Stats, &out.Stats
+		*out = new(DomainStatsSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Gateway != nil {
+		in, out := &in.Gateway, &out.Gateway
+		*out = new(DomainGatewaySpec)
+		**out = **in
+	}
+	if in.DNS != nil {
+		in, out := &in.DNS, &out.DNS
+		*out = new(DomainDNSSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Monitoring != nil {
+		in, out := &in.Monitoring, &out.Monitoring
+		*out = new(DomainMonitoringSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CheckInterval != nil {
+		in, out := &in.CheckInterval, &out.CheckInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.UnverifiedCheckInterval != nil {
+		in, out := &in.UnverifiedCheckInterval, &out.UnverifiedCheckInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.ResourceLabels != nil {
+		in, out := &in.ResourceLabels, &out.ResourceLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ResourceAnnotations != nil {
+		in, out := &in.ResourceAnnotations, &out.ResourceAnnotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.MTASTS != nil {
+		in, out := &in.MTASTS, &out.MTASTS
+		*out = new(MTASTSSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TLSRPT != nil {
+		in, out := &in.TLSRPT, &out.TLSRPT
+		*out = new(TLSRPTSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DMARC != nil {
+		in, out := &in.DMARC, &out.DMARC
+		*out = new(DMARCSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BIMI != nil {
+		in, out := &in.BIMI, &out.BIMI
+		*out = new(BIMISpec)
+		**out = **in
+	}
+	if in.SendingIPs != nil {
+		in, out := &in.SendingIPs, &out.SendingIPs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AdditionalDomains != nil {
+		in, out := &in.AdditionalDomains, &out.AdditionalDomains
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SenderPoolRef != nil {
+		in, out := &in.SenderPoolRef, &out.SenderPoolRef
+		*out = new(SenderPoolReference)
+		**out = **in
+	}
+	if in.KannonCredentialsRef != nil {
+		in, out := &in.KannonCredentialsRef, &out.KannonCredentialsRef
+		*out = new(CredentialsSecretReference)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Delivery != nil {
+		in, out := &in.Delivery, &out.Delivery
+		*out = new(DeliverySpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Reputation != nil {
+		in, out := &in.Reputation, &out.Reputation
+		*out = new(ReputationSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DMARCReports != nil {
+		in, out := &in.DMARCReports, &out.DMARCReports
+		*out = new(DMARCReportsSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Export != nil {
+		in, out := &in.Export, &out.Export
+		*out = new(DomainExportSpec)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DomainSpec.
+func (in *DomainSpec) DeepCopy() *DomainSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DomainSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DomainStatsSpec) DeepCopyInto(out *DomainStatsSpec) {
+	*out = *in
+	if in.Hosts != nil {
+		in, out := &in.Hosts, &out.Hosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Paths != nil {
+		in, out := &in.Paths, &out.Paths
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NetworkPolicy != nil {
+		in, out := &in.NetworkPolicy, &out.NetworkPolicy
+		*out = new(StatsNetworkPolicySpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DomainStatsSpec.
+func (in *DomainStatsSpec) DeepCopy() *DomainStatsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DomainStatsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DomainStatus) DeepCopyInto(out *DomainStatus) {
+	*out = *in
+	in.DNS.DeepCopyInto(&out.DNS)
+	if in.LastCheckTime != nil {
+		in, out := &in.LastCheckTime, &out.LastCheckTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Certificate != nil {
+		in, out := &in.Certificate, &out.Certificate
+		*out = new(CertificateStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DKIM != nil {
+		in, out := &in.DKIM, &out.DKIM
+		*out = new(DKIMStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.OriginalSPF != nil {
+		in, out := &in.OriginalSPF, &out.OriginalSPF
+		*out = make([]OriginalSPFRecord, len(*in))
+		copy(*out, *in)
+	}
+	if in.Delivery != nil {
+		in, out := &in.Delivery, &out.Delivery
+		*out = new(DeliveryStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Reputation != nil {
+		in, out := &in.Reputation, &out.Reputation
+		*out = new(ReputationStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DMARCReports != nil {
+		in, out := &in.DMARCReports, &out.DMARCReports
+		*out = new(DMARCReportsStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Ownership != nil {
+		in, out := &in.Ownership, &out.Ownership
+		*out = new(OwnershipStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DomainStatus.
+func (in *DomainStatus) DeepCopy() *DomainStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DomainStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DomainTLSSpec) DeepCopyInto(out *DomainTLSSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DomainTLSSpec.
+func (in *DomainTLSSpec) DeepCopy() *DomainTLSSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DomainTLSSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MTASTSSpec) DeepCopyInto(out *MTASTSSpec) {
+	*out = *in
+	if in.MX != nil {
+		in, out := &in.MX, &out.MX
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MaxAge != nil {
+		in, out := &in.MaxAge, &out.MaxAge
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MTASTSSpec.
+func (in *MTASTSSpec) DeepCopy() *MTASTSSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MTASTSSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MTASTSStatus) DeepCopyInto(out *MTASTSStatus) {
+	*out = *in
+	in.DNSRecordStatus.DeepCopyInto(&out.DNSRecordStatus)
+	in.Policy.DeepCopyInto(&out.Policy)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MTASTSStatus.
+func (in *MTASTSStatus) DeepCopy() *MTASTSStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MTASTSStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MXStatus) DeepCopyInto(out *MXStatus) {
+	*out = *in
+	in.DNSRecordStatus.DeepCopyInto(&out.DNSRecordStatus)
+	if in.Hosts != nil {
+		in, out := &in.Hosts, &out.Hosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MXStatus.
+func (in *MXStatus) DeepCopy() *MXStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MXStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OriginalSPFRecord) DeepCopyInto(out *OriginalSPFRecord) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OriginalSPFRecord.
+func (in *OriginalSPFRecord) DeepCopy() *OriginalSPFRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(OriginalSPFRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OwnershipStatus) DeepCopyInto(out *OwnershipStatus) {
+	*out = *in
+	in.DNSRecordStatus.DeepCopyInto(&out.DNSRecordStatus)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OwnershipStatus.
+func (in *OwnershipStatus) DeepCopy() *OwnershipStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(OwnershipStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PTRStatus) DeepCopyInto(out *PTRStatus) {
+	*out = *in
+	in.DNSRecordStatus.DeepCopyInto(&out.DNSRecordStatus)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PTRStatus.
+func (in *PTRStatus) DeepCopy() *PTRStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PTRStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostmasterIPReputation) DeepCopyInto(out *PostmasterIPReputation) {
+	*out = *in
+	if in.SampleIPs != nil {
+		in, out := &in.SampleIPs, &out.SampleIPs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostmasterIPReputation.
+func (in *PostmasterIPReputation) DeepCopy() *PostmasterIPReputation {
+	if in == nil {
+		return nil
+	}
+	out := new(PostmasterIPReputation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostmasterReputation) DeepCopyInto(out *PostmasterReputation) {
+	*out = *in
+	if in.IPReputations != nil {
+		in, out := &in.IPReputations, &out.IPReputations
+		*out = make([]PostmasterIPReputation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostmasterReputation.
+func (in *PostmasterReputation) DeepCopy() *PostmasterReputation {
+	if in == nil {
+		return nil
+	}
+	out := new(PostmasterReputation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReputationSpec) DeepCopyInto(out *ReputationSpec) {
+	*out = *in
+	if in.PollInterval != nil {
+		in, out := &in.PollInterval, &out.PollInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReputationSpec.
+func (in *ReputationSpec) DeepCopy() *ReputationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ReputationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReputationStatus) DeepCopyInto(out *ReputationStatus) {
+	*out = *in
+	if in.PolledAt != nil {
+		in, out := &in.PolledAt, &out.PolledAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Postmaster != nil {
+		in, out := &in.Postmaster, &out.Postmaster
+		*out = new(PostmasterReputation)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SNDS != nil {
+		in, out := &in.SNDS, &out.SNDS
+		*out = new(SNDSReputation)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReputationStatus.
+func (in *ReputationStatus) DeepCopy() *ReputationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ReputationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResolverStatus) DeepCopyInto(out *ResolverStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResolverStatus.
+func (in *ResolverStatus) DeepCopy() *ResolverStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ResolverStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RetiringDKIMKey) DeepCopyInto(out *RetiringDKIMKey) {
+	*out = *in
+	in.RetireAt.DeepCopyInto(&out.RetireAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RetiringDKIMKey.
+func (in *RetiringDKIMKey) DeepCopy() *RetiringDKIMKey {
+	if in == nil {
+		return nil
+	}
+	out := new(RetiringDKIMKey)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SNDSIPReputation) DeepCopyInto(out *SNDSIPReputation) {
+	*out = *in
+	if in.ActivityEnd != nil {
+		in, out := &in.ActivityEnd, &out.ActivityEnd
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SNDSIPReputation.
+func (in *SNDSIPReputation) DeepCopy() *SNDSIPReputation {
+	if in == nil {
+		return nil
+	}
+	out := new(SNDSIPReputation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SNDSReputation) DeepCopyInto(out *SNDSReputation) {
+	*out = *in
+	if in.IPs != nil {
+		in, out := &in.IPs, &out.IPs
+		*out = make([]SNDSIPReputation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SNDSReputation.
+func (in *SNDSReputation) DeepCopy() *SNDSReputation {
+	if in == nil {
+		return nil
+	}
+	out := new(SNDSReputation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SPFRecordStatus) DeepCopyInto(out *SPFRecordStatus) {
+	*out = *in
+	in.DNSRecordStatus.DeepCopyInto(&out.DNSRecordStatus)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SPFRecordStatus.
+func (in *SPFRecordStatus) DeepCopy() *SPFRecordStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SPFRecordStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SenderPoolReference) DeepCopyInto(out *SenderPoolReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SenderPoolReference.
+func (in *SenderPoolReference) DeepCopy() *SenderPoolReference {
+	if in == nil {
+		return nil
+	}
+	out := new(SenderPoolReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StatsNetworkPolicySpec) DeepCopyInto(out *StatsNetworkPolicySpec) {
+	*out = *in
+	if in.IngressPodSelector != nil {
+		in, out := &in.IngressPodSelector, &out.IngressPodSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StatsNetworkPolicySpec.
+func (in *StatsNetworkPolicySpec) DeepCopy() *StatsNetworkPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(StatsNetworkPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TLSRPTSpec) DeepCopyInto(out *TLSRPTSpec) {
+	*out = *in
+	if in.ReportURIs != nil {
+		in, out := &in.ReportURIs, &out.ReportURIs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSRPTSpec.
+func (in *TLSRPTSpec) DeepCopy() *TLSRPTSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TLSRPTSpec)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"golang.org/x/sync/errgroup"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+	"github.com/kannon-email/k8nnon/internal/dns/checker"
+)
+
+// maxColumnWidth truncates the long values, such as the DKIM keys, in the
+// table. The records to add are printed in full.
+const maxColumnWidth = 48
+
+// recordCheck is the check of a record of the Domain.
+type recordCheck struct {
+	Record string
+	Stats  checker.DNSCheckStats
+}
+
+// checkDomain runs the checks the operator runs on the Domain, in parallel.
+func checkDomain(ctx context.Context, c checker.DNSChecker, domain *corev1alpha1.Domain) []recordCheck {
+	var checks []recordCheck
+	var runs []func() checker.DNSCheckStats
+	add := func(record string, run func() checker.DNSCheckStats) {
+		checks = append(checks, recordCheck{Record: record})
+		runs = append(runs, run)
+	}
+	addDomain := func(record string, check func(context.Context, *corev1alpha1.Domain) checker.DNSCheckStats) {
+		add(record, func() checker.DNSCheckStats { return check(ctx, domain) })
+	}
+
+	addDomain("dkim", c.CheckDomainDKIM)
+	addDomain("spf", c.CheckDomainSPF)
+	addDomain("stats", c.CheckDomainStatsDNS)
+	addDomain("mx", c.CheckDomainMX)
+	addDomain("dmarc", c.CheckDomainDMARC)
+	if domain.Spec.MTASTSEnabled() {
+		addDomain("mta-sts", c.CheckDomainMTASTS)
+		addDomain("mta-sts-policy", c.CheckDomainMTASTSPolicy)
+	}
+	if domain.Spec.TLSRPT != nil {
+		addDomain("tls-rpt", c.CheckDomainTLSRPT)
+	}
+	if domain.Spec.BIMI != nil {
+		add("bimi", func() checker.DNSCheckStats { return c.CheckDomainBIMI(ctx, domain).Record })
+	}
+	for _, ip := range domain.Spec.SendingIPs {
+		ip := ip
+		add("ptr "+ip, func() checker.DNSCheckStats {
+			return c.CheckPTR(ctx, ip, domain.Spec.DomainName, domain.Spec.BaseDomain)
+		})
+	}
+
+	g := errgroup.Group{}
+	for i := range runs {
+		i := i
+		g.Go(func() error {
+			checks[i].Stats = runs[i]()
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	return checks
+}
+
+// printReport prints the checks as a table, then the records to add, and
+// reports whether every record is verified.
+func printReport(w io.Writer, domain *corev1alpha1.Domain, checks []recordCheck) bool {
+	fmt.Fprintf(w, "Domain %s/%s (%s)\n\n", domain.Namespace, domain.Name, domain.Spec.DomainName)
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "RECORD\tSTATE\tTYPE\tNAME\tEXPECTED\tOBSERVED")
+	var failing []recordCheck
+	for _, check := range checks {
+		stats := check.Stats
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			check.Record, stats.State(), stats.Expected.Type, stats.Expected.Name,
+			truncate(stats.Expected.Value), truncate(strings.Join(stats.Observed, ", ")))
+		if !stats.Result() {
+			failing = append(failing, check)
+		}
+	}
+	_ = tw.Flush()
+
+	if len(failing) == 0 {
+		fmt.Fprintln(w, "\nEvery record is verified.")
+		return true
+	}
+
+	fmt.Fprintln(w, "\nProblems:")
+	for _, check := range failing {
+		fmt.Fprintf(w, "  %s: %s\n", check.Record, problem(check.Stats))
+	}
+
+	fmt.Fprintln(w, "\nRecords to add or fix:")
+	tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, check := range failing {
+		if rec := check.Stats.Expected; rec.Name != "" && rec.Value != "" {
+			fmt.Fprintf(tw, "  %s.\t%s\t%s\n", strings.TrimSuffix(rec.Name, "."), rec.Type, zoneValue(rec))
+		}
+	}
+	_ = tw.Flush()
+
+	return false
+}
+
+// problem explains why a record is not verified.
+func problem(stats checker.DNSCheckStats) string {
+	switch {
+	case stats.State() == corev1alpha1.CheckStateUnknown && stats.Err != nil:
+		return fmt.Sprintf("most resolvers failed to answer: %v", stats.Err)
+	case stats.Reason != "":
+		return stats.Reason
+	case len(stats.Observed) == 0:
+		return "the record is missing"
+	default:
+		return fmt.Sprintf("the record does not match, %d of %d resolvers found it", stats.CntOK, stats.CntOK+stats.CntKO)
+	}
+}
+
+// zoneValue formats the value as it is written in a zone file.
+func zoneValue(rec checker.Record) string {
+	switch rec.Type {
+	case "TXT":
+		// the strings of a TXT record are at most 255 bytes long
+		var parts []string
+		for value := rec.Value; value != ""; {
+			n := len(value)
+			if n > 255 {
+				n = 255
+			}
+			parts = append(parts, fmt.Sprintf("%q", value[:n]))
+			value = value[n:]
+		}
+		return strings.Join(parts, " ")
+	case "MX":
+		return fmt.Sprintf("10 %s.", strings.TrimSuffix(rec.Value, "."))
+	default:
+		return strings.TrimSuffix(rec.Value, ".") + "."
+	}
+}
+
+func truncate(s string) string {
+	if len(s) <= maxColumnWidth {
+		return s
+	}
+	return s[:maxColumnWidth-3] + "..."
+}
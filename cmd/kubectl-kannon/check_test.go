@@ -0,0 +1,110 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+	"github.com/kannon-email/k8nnon/internal/dns/checker"
+)
+
+func TestCheckDomain(t *testing.T) {
+	domain := testDomain()
+	domain.Spec.SendingIPs = []string{"192.0.2.1"}
+	domain.Spec.TLSRPT = &corev1alpha1.TLSRPTSpec{ReportURIs: []string{"mailto:tls@example.com"}}
+
+	checks := checkDomain(context.Background(), checker.NewFakeDNSChecker(checker.WithAll(true)), domain)
+
+	var records []string
+	for _, check := range checks {
+		records = append(records, check.Record)
+		assert.True(t, check.Stats.Result(), check.Record)
+	}
+	assert.Equal(t, []string{"dkim", "spf", "stats", "mx", "dmarc", "tls-rpt", "ptr 192.0.2.1"}, records)
+
+	out := &bytes.Buffer{}
+	assert.True(t, printReport(out, domain, checks))
+	assert.Contains(t, out.String(), "Every record is verified.")
+}
+
+func TestPrintReport(t *testing.T) {
+	domain := testDomain()
+	key := strings.Repeat("k", 300)
+	checks := []recordCheck{
+		{Record: "dkim", Stats: checker.DNSCheckStats{
+			CntKO:    2,
+			Expected: checker.Record{Type: "TXT", Name: "selector._domainkey.example.com", Value: "v=DKIM1; k=rsa; p=" + key},
+		}},
+		{Record: "spf", Stats: checker.DNSCheckStats{
+			CntKO:    2,
+			Reason:   "the SPF record does not include mx.example.com",
+			Observed: []string{"v=spf1 -all"},
+			Expected: checker.Record{Type: "TXT", Name: "example.com", Value: "v=spf1 include:mx.example.com ~all"},
+		}},
+		{Record: "mx", Stats: checker.DNSCheckStats{
+			CntOK:    2,
+			Observed: []string{"mx.example.com"},
+			Expected: checker.Record{Type: "MX", Name: "bounces.example.com", Value: "mx.example.com"},
+		}},
+		{Record: "stats", Stats: checker.DNSCheckStats{
+			CntKO:    2,
+			Expected: checker.Record{Type: "CNAME", Name: "stats.example.com", Value: "mx.example.com"},
+		}},
+	}
+
+	out := &bytes.Buffer{}
+	require.False(t, printReport(out, domain, checks))
+	report := out.String()
+
+	assert.Contains(t, report, "Domain default/example (example.com)")
+	assert.Contains(t, report, "dkim: the record is missing")
+	assert.Contains(t, report, "spf: the SPF record does not include mx.example.com")
+	assert.NotContains(t, report, "mx: ")
+
+	toAdd := report[strings.Index(report, "Records to add or fix:"):]
+	assert.Contains(t, toAdd, `selector._domainkey.example.com.  TXT    "v=DKIM1; k=rsa; p=`)
+	assert.Contains(t, toAdd, `" "`+strings.Repeat("k", 300-(255-len("v=DKIM1; k=rsa; p=")))+`"`)
+	assert.Contains(t, toAdd, `example.com.                      TXT    "v=spf1 include:mx.example.com ~all"`)
+	assert.Contains(t, toAdd, "stats.example.com.                CNAME  mx.example.com.")
+	assert.NotContains(t, toAdd, "bounces.example.com")
+}
+
+func testDomain() *corev1alpha1.Domain {
+	return &corev1alpha1.Domain{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "example",
+			Namespace: "default",
+		},
+		Spec: corev1alpha1.DomainSpec{
+			DomainName:  "example.com",
+			BaseDomain:  "mx.example.com",
+			StatsPrefix: "stats",
+			DKIM: corev1alpha1.DKIM{
+				Selector:  "selector",
+				PublicKey: "publicKey",
+			},
+		},
+	}
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// kubectl-kannon is a kubectl plugin diagnosing the DNS records of the
+// Domains from the workstation of the operator user:
+//
+//	kubectl kannon check <domain> [-n namespace] [--resolvers 1.1.1.1,8.8.8.8]
+//
+// It reads the Domain from the cluster, runs the checks of the operator
+// against the given resolvers and prints the expected and the observed
+// records, then the records to add to the zone.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+	"github.com/kannon-email/k8nnon/internal/dns/checker"
+	"github.com/kannon-email/k8nnon/internal/dns/resolver"
+)
+
+const usage = `Usage:
+  kubectl kannon check <domain> [flags]
+
+Checks the DNS records of a Domain against the resolvers and prints the
+records to add. It exits with status 1 when a record is not verified.
+
+Flags:
+`
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] != "check" {
+		fmt.Fprint(os.Stderr, usage)
+		newCheckFlags(io.Discard).fs.PrintDefaults()
+		os.Exit(2)
+	}
+
+	ok, err := runCheck(context.Background(), os.Args[2:], os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(2)
+	}
+	if !ok {
+		os.Exit(1)
+	}
+}
+
+type checkFlags struct {
+	fs *flag.FlagSet
+
+	kubeconfig    string
+	context       string
+	namespace     string
+	resolvers     string
+	quorum        int
+	lookupTimeout time.Duration
+	mxHost        string
+	spfInclude    string
+}
+
+func newCheckFlags(output io.Writer) *checkFlags {
+	f := &checkFlags{fs: flag.NewFlagSet("check", flag.ContinueOnError)}
+	f.fs.SetOutput(output)
+
+	f.fs.StringVar(&f.kubeconfig, "kubeconfig", "", "Path to the kubeconfig file. Defaults to the kubectl one.")
+	f.fs.StringVar(&f.context, "context", "", "The kubeconfig context to use.")
+	f.fs.StringVar(&f.namespace, "namespace", "", "The namespace of the Domain. Defaults to the one of the context.")
+	f.fs.StringVar(&f.namespace, "n", "", "Shorthand for --namespace.")
+	f.fs.StringVar(&f.resolvers, "resolvers", strings.Join(checker.ServerAddresses, ","),
+		"Comma-separated DNS servers the records are checked against.")
+	f.fs.IntVar(&f.quorum, "quorum", 0, "How many resolvers must find a record for it to be verified. 0 requires a majority.")
+	f.fs.DurationVar(&f.lookupTimeout, "lookup-timeout", checker.DefaultLookupTimeout, "The timeout of a single DNS lookup.")
+	f.fs.StringVar(&f.mxHost, "mx-host", "", "The MX host the bounce domain must point to, as set on the operator. Defaults to the base domain.")
+	f.fs.StringVar(&f.spfInclude, "spf-include", "", "The domain the SPF record must include, as set on the operator. Defaults to the base domain.")
+
+	return f
+}
+
+// parse parses the flags wherever they are, kubectl users are used to
+// type them after the name of the object.
+func (f *checkFlags) parse(args []string) ([]string, error) {
+	var names []string
+	for {
+		if err := f.fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if f.fs.NArg() == 0 {
+			return names, nil
+		}
+		names = append(names, f.fs.Arg(0))
+		args = f.fs.Args()[1:]
+	}
+}
+
+func runCheck(ctx context.Context, args []string, out io.Writer) (bool, error) {
+	f := newCheckFlags(os.Stderr)
+	names, err := f.parse(args)
+	if err != nil {
+		return false, err
+	}
+	if len(names) != 1 {
+		return false, fmt.Errorf("expected the name of a single Domain, got %d", len(names))
+	}
+
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = f.kubeconfig
+	config := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{CurrentContext: f.context})
+
+	namespace := f.namespace
+	if namespace == "" {
+		if namespace, _, err = config.Namespace(); err != nil {
+			return false, err
+		}
+	}
+
+	restConfig, err := config.ClientConfig()
+	if err != nil {
+		return false, err
+	}
+	scheme := runtime.NewScheme()
+	if err := corev1alpha1.AddToScheme(scheme); err != nil {
+		return false, err
+	}
+	c, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		return false, err
+	}
+
+	domain := &corev1alpha1.Domain{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: names[0]}, domain); err != nil {
+		return false, err
+	}
+
+	resolvers := resolver.NewResolvers(strings.Split(f.resolvers, ",")...)
+	if f.quorum < 0 || f.quorum > len(resolvers) {
+		return false, fmt.Errorf("the quorum must be between 0 and the number of resolvers")
+	}
+	dnsChecker := checker.New(resolvers,
+		checker.WithQuorum(f.quorum),
+		checker.WithLookupTimeout(f.lookupTimeout),
+		checker.WithMXHost(f.mxHost),
+		checker.WithSPFInclude(f.spfInclude),
+	)
+
+	return printReport(out, domain, checkDomain(ctx, dnsChecker, domain)), nil
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/prometheus/common/model"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+)
+
+// prometheusRuleGVK is the Prometheus Operator CRD the alerts are defined
+// with. It is handled as unstructured to avoid depending on the operator.
+var prometheusRuleGVK = schema.GroupVersionKind{
+	Group:   "monitoring.coreos.com",
+	Version: "v1",
+	Kind:    "PrometheusRule",
+}
+
+// alertDNSRecordRegressed is the alert firing when a verified record of a
+// domain stops being verified.
+const alertDNSRecordRegressed = "KannonDomainDNSRecordRegressed"
+
+// errPrometheusRulesDisabled is returned when a Domain requests alerts but
+// the operator runs without the Prometheus Operator integration.
+var errPrometheusRulesDisabled = errors.New("the PrometheusRule integration is not enabled in the operator")
+
+func newPrometheusRule() *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(prometheusRuleGVK)
+	return obj
+}
+
+func prometheusRuleName(domain *corev1alpha1.Domain) string {
+	return fmt.Sprintf("%s-alerts", domain.Name)
+}
+
+// reconcilePrometheusRule creates the PrometheusRule alerting on the DNS
+// records of the Domain, and deletes it once the alerts are turned off.
+func (r *DomainReconciler) reconcilePrometheusRule(ctx context.Context, domain *corev1alpha1.Domain) error {
+	wanted := domain.Spec.AlertsEnabled()
+	if !r.PrometheusRules {
+		if wanted {
+			return errPrometheusRulesDisabled
+		}
+		return nil
+	}
+
+	rule := newPrometheusRule()
+	err := r.Get(ctx, types.NamespacedName{Name: prometheusRuleName(domain), Namespace: domain.Namespace}, rule)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	found := err == nil
+
+	if !wanted {
+		if found && v1.IsControlledBy(rule, domain) && rule.GetDeletionTimestamp() == nil {
+			return r.Delete(ctx, rule)
+		}
+		return nil
+	}
+
+	groups := alertRuleGroups(domain)
+
+	if !found {
+		rule = newPrometheusRule()
+		rule.SetName(prometheusRuleName(domain))
+		rule.SetNamespace(domain.Namespace)
+		applyResourceMetadata(rule, domain, componentAlerts)
+		if err := unstructured.SetNestedSlice(rule.Object, groups, "spec", "groups"); err != nil {
+			return err
+		}
+		if err := ctrl.SetControllerReference(domain, rule, r.Scheme); err != nil {
+			return err
+		}
+		return r.Create(ctx, rule, client.FieldOwner(fieldManager))
+	}
+
+	if !v1.IsControlledBy(rule, domain) {
+		return fmt.Errorf("prometheusrule %s is not controlled by the domain", rule.GetName())
+	}
+	if err := r.syncResourceMetadata(ctx, domain, rule, componentAlerts); err != nil {
+		return err
+	}
+
+	current, _, err := unstructured.NestedSlice(rule.Object, "spec", "groups")
+	if err != nil {
+		return err
+	}
+	if reflect.DeepEqual(current, groups) {
+		return nil
+	}
+
+	// the groups are applied, so that the rules edited by hand or by
+	// another manager are taken back without a read-modify-write race
+	desired := newPrometheusRule()
+	desired.SetName(rule.GetName())
+	desired.SetNamespace(rule.GetNamespace())
+	if err := unstructured.SetNestedSlice(desired.Object, groups, "spec", "groups"); err != nil {
+		return err
+	}
+	if err := ctrl.SetControllerReference(domain, desired, r.Scheme); err != nil {
+		return err
+	}
+
+	return r.Patch(ctx, desired, client.Apply, client.FieldOwner(fieldManager), client.ForceOwnership)
+}
+
+// alertRuleGroups returns the rule groups of the Domain PrometheusRule. The
+// alert fires for the records that were verified in the last day, so that
+// a domain being set up doesn't page anyone.
+func alertRuleGroups(domain *corev1alpha1.Domain) []interface{} {
+	series := fmt.Sprintf("k8nnon_domain_dns_verified{domain=%q}", domain.Spec.DomainName)
+
+	return []interface{}{
+		map[string]interface{}{
+			"name": fmt.Sprintf("k8nnon.%s.%s", domain.Namespace, domain.Name),
+			"rules": []interface{}{
+				map[string]interface{}{
+					"alert": alertDNSRecordRegressed,
+					"expr":  fmt.Sprintf("%s == 0 and max_over_time(%s[1d]) == 1", series, series),
+					"for":   model.Duration(domain.Spec.Monitoring.ForOrDefault()).String(),
+					"labels": map[string]interface{}{
+						"severity": "warning",
+					},
+					"annotations": map[string]interface{}{
+						"summary":     fmt.Sprintf("The {{ $labels.record }} record of %s is no longer verified", domain.Spec.DomainName),
+						"description": fmt.Sprintf("The {{ $labels.record }} record of the domain %s/%s was verified in the last day but the DNS checks no longer find it.", domain.Namespace, domain.Name),
+					},
+				},
+			},
+		},
+	}
+}
+
+// alertsCondition computes the AlertsConfigured condition from the outcome
+// of the PrometheusRule reconciliation.
+func alertsCondition(domain *corev1alpha1.Domain, alertsErr error) v1.Condition {
+	cond := v1.Condition{
+		Type:               corev1alpha1.ConditionAlertsConfigured,
+		Status:             v1.ConditionFalse,
+		ObservedGeneration: domain.Generation,
+	}
+
+	switch {
+	case errors.Is(alertsErr, errPrometheusRulesDisabled):
+		cond.Reason = corev1alpha1.ReasonPrometheusOperatorDisabled
+		cond.Message = alertsErr.Error()
+	case alertsErr != nil:
+		cond.Reason = corev1alpha1.ReasonAlertRuleFailed
+		cond.Message = alertsErr.Error()
+	default:
+		cond.Status = v1.ConditionTrue
+		cond.Reason = corev1alpha1.ReasonAlertRuleApplied
+		cond.Message = fmt.Sprintf("the alerts are defined in the prometheusrule %s", prometheusRuleName(domain))
+	}
+
+	return cond
+}
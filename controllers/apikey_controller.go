@@ -0,0 +1,435 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+	"github.com/kannon-email/k8nnon/internal/kannon"
+	"github.com/kannon-email/k8nnon/internal/shard"
+	"github.com/kannon-email/k8nnon/internal/tracing"
+)
+
+// apiKeyFinalizer delays the deletion of an ApiKey until its key is revoked.
+const apiKeyFinalizer = "core.k8s.kannon.email/apikey-revoke"
+
+// The Secret of an ApiKey is annotated with the identifier and the issue
+// time of the key it holds, written together with the key, so that a key
+// is not lost when the status write following its issue fails.
+const (
+	apiKeyIDAnnotation       = "core.k8s.kannon.email/api-key-id"
+	apiKeyIssuedAtAnnotation = "core.k8s.kannon.email/api-key-issued-at"
+)
+
+// ApiKeyReconciler reconciles a ApiKey object
+type ApiKeyReconciler struct {
+	client.Client
+	clock
+	Scheme *runtime.Scheme
+
+	// Kannon issues and revokes the keys.
+	Kannon kannon.Client
+
+	// Shard is the share of the ApiKeys the replica reconciles.
+	Shard shard.Shard
+}
+
+//+kubebuilder:rbac:groups=core.k8s.kannon.email,resources=apikeys,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=core.k8s.kannon.email,resources=apikeys/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=core.k8s.kannon.email,resources=apikeys/finalizers,verbs=update
+
+// Reconcile issues the key of an ApiKey and stores it in its Secret. A new
+// key is issued when the Domain changes its name, when the Secret is lost
+// and when spec.rotateAfter elapses; the previous key is then revoked once
+// spec.rotationOverlap elapses.
+func (r *ApiKeyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	if !r.Shard.Owns(req.Namespace, req.Name) {
+		return ctrl.Result{}, nil
+	}
+
+	l := log.FromContext(ctx)
+	l.Info("reconciling api key", "apiKey", req.NamespacedName)
+
+	key := &corev1alpha1.ApiKey{}
+	if err := r.Get(ctx, req.NamespacedName, key); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if !key.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, r.finalizeApiKey(ctx, key, l)
+	}
+
+	if !controllerutil.ContainsFinalizer(key, apiKeyFinalizer) {
+		controllerutil.AddFinalizer(key, apiKeyFinalizer)
+		if err := r.Update(ctx, key); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	cond, issueErr := r.reconcileKey(ctx, key, l)
+	key.Status.ObservedGeneration = key.Generation
+	meta.SetStatusCondition(&key.Status.Conditions, cond)
+	revokeErr := r.revokeRetired(ctx, key, l)
+
+	if err := r.Status().Update(ctx, key); err != nil {
+		return ctrl.Result{}, err
+	}
+	if issueErr != nil {
+		return ctrl.Result{}, issueErr
+	}
+	if revokeErr != nil {
+		return ctrl.Result{}, revokeErr
+	}
+
+	if d := r.requeueIn(key); d > 0 {
+		return ctrl.Result{RequeueAfter: d}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// reconcileKey makes sure the Secret holds a valid key of the Domain and
+// returns the resulting Ready condition. The returned error is retried.
+func (r *ApiKeyReconciler) reconcileKey(ctx context.Context, key *corev1alpha1.ApiKey, l logr.Logger) (v1.Condition, error) {
+	cond := v1.Condition{
+		Type:               corev1alpha1.ConditionReady,
+		Status:             v1.ConditionFalse,
+		ObservedGeneration: key.Generation,
+	}
+
+	domain := &corev1alpha1.Domain{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: key.Namespace, Name: key.Spec.DomainRef.Name}, domain)
+	if apierrors.IsNotFound(err) {
+		cond.Reason = corev1alpha1.ReasonApiKeyDomainNotFound
+		cond.Message = fmt.Sprintf("the domain %s does not exist", key.Spec.DomainRef.Name)
+		return cond, nil
+	}
+	if err != nil {
+		return cond, err
+	}
+
+	secretName := key.SecretNameOrDefault()
+	secret := &corev1.Secret{}
+	err = r.Get(ctx, types.NamespacedName{Namespace: key.Namespace, Name: secretName}, secret)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return cond, err
+	}
+	secretFound := err == nil
+	if secretFound && !v1.IsControlledBy(secret, key) {
+		cond.Reason = corev1alpha1.ReasonApiKeySecretConflict
+		cond.Message = fmt.Sprintf("the secret %s is not controlled by the api key", secretName)
+		return cond, nil
+	}
+
+	if secretFound {
+		r.adoptStoredKey(key, secret)
+	}
+
+	name := domain.Spec.DomainName
+	value := secret.Data[key.Spec.Secret.KeyKeyOrDefault()]
+	valid := len(value) > 0 &&
+		key.Status.KeyID != "" &&
+		key.Status.Domain == name &&
+		key.Status.SecretName == secretName &&
+		!r.rotationDue(key)
+	if valid {
+		if err := r.writeApiKeySecret(ctx, key, secret, name, value); err != nil {
+			return cond, err
+		}
+		return apiKeyIssuedCondition(cond, key), nil
+	}
+
+	l.Info("issuing api key", "domain", name)
+	issued, err := r.Kannon.CreateAPIKey(ctx, name, fmt.Sprintf("%s/%s", key.Namespace, key.Name))
+	if err != nil {
+		cond.Reason = corev1alpha1.ReasonApiKeyIssueFailed
+		cond.Message = err.Error()
+		return cond, err
+	}
+
+	if !secretFound {
+		secret = &corev1.Secret{ObjectMeta: v1.ObjectMeta{Name: secretName, Namespace: key.Namespace}}
+	}
+	issuedAt := v1.Time{Time: r.now().Truncate(time.Second)}
+	setKey(&secret.Annotations, apiKeyIDAnnotation, issued.ID)
+	setKey(&secret.Annotations, apiKeyIssuedAtAnnotation, issuedAt.UTC().Format(time.RFC3339))
+	if err := r.writeApiKeySecret(ctx, key, secret, name, []byte(issued.Key)); err != nil {
+		// the issued key is not stored anywhere, drop it
+		if revokeErr := r.revokeKey(ctx, name, issued.ID, l); revokeErr != nil {
+			l.Error(revokeErr, "failed to revoke the unstored api key", "domain", name)
+		}
+		return cond, err
+	}
+
+	previousSecret := key.Status.SecretName
+	r.retireCurrentKey(key)
+	key.Status.KeyID = issued.ID
+	key.Status.Domain = name
+	key.Status.SecretName = secretName
+	key.Status.IssuedAt = &issuedAt
+
+	if previousSecret != "" && previousSecret != secretName {
+		if err := r.deleteApiKeySecret(ctx, key, previousSecret); err != nil {
+			return cond, err
+		}
+	}
+
+	return apiKeyIssuedCondition(cond, key), nil
+}
+
+// adoptStoredKey makes the key held by the Secret the current one when the
+// status doesn't know it yet, which happens when the status write after
+// its issue failed.
+func (r *ApiKeyReconciler) adoptStoredKey(key *corev1alpha1.ApiKey, secret *corev1.Secret) {
+	id := secret.Annotations[apiKeyIDAnnotation]
+	if id == "" || id == key.Status.KeyID {
+		return
+	}
+
+	issuedAt, err := time.Parse(time.RFC3339, secret.Annotations[apiKeyIssuedAtAnnotation])
+	if err != nil {
+		issuedAt = r.now()
+	}
+
+	r.retireCurrentKey(key)
+	key.Status.KeyID = id
+	key.Status.Domain = string(secret.Data[key.Spec.Secret.DomainKeyOrDefault()])
+	key.Status.SecretName = secret.Name
+	key.Status.IssuedAt = &v1.Time{Time: issuedAt}
+}
+
+// retireCurrentKey moves the current key, if any, to the retiring keys,
+// to be revoked once the rotation overlap ends.
+func (r *ApiKeyReconciler) retireCurrentKey(key *corev1alpha1.ApiKey) {
+	if key.Status.KeyID == "" {
+		return
+	}
+
+	key.Status.Retiring = append(key.Status.Retiring, corev1alpha1.RetiringApiKey{
+		KeyID:    key.Status.KeyID,
+		Domain:   key.Status.Domain,
+		RevokeAt: v1.Time{Time: r.now().Add(key.Spec.RotationOverlapOrDefault())},
+	})
+}
+
+// revokeRetired revokes the retiring keys whose overlap ended. The keys
+// failing to be revoked are kept and retried.
+func (r *ApiKeyReconciler) revokeRetired(ctx context.Context, key *corev1alpha1.ApiKey, l logr.Logger) error {
+	var errs []error
+	retiring := key.Status.Retiring[:0]
+	for _, k := range key.Status.Retiring {
+		if r.now().Before(k.RevokeAt.Time) {
+			retiring = append(retiring, k)
+			continue
+		}
+		if err := r.revokeKey(ctx, k.Domain, k.KeyID, l); err != nil {
+			l.Error(err, "failed to revoke the previous api key", "domain", k.Domain)
+			errs = append(errs, err)
+			retiring = append(retiring, k)
+			continue
+		}
+		l.Info("revoked the previous api key", "domain", k.Domain)
+	}
+
+	key.Status.Retiring = retiring
+	if len(key.Status.Retiring) == 0 {
+		key.Status.Retiring = nil
+	}
+	return errors.Join(errs...)
+}
+
+// writeApiKeySecret stores the key in the Secret, creating it when new.
+func (r *ApiKeyReconciler) writeApiKeySecret(ctx context.Context, key *corev1alpha1.ApiKey, secret *corev1.Secret, domain string, value []byte) error {
+	data := map[string][]byte{
+		key.Spec.Secret.KeyKeyOrDefault():    value,
+		key.Spec.Secret.DomainKeyOrDefault(): []byte(domain),
+	}
+
+	if secret.ResourceVersion == "" {
+		secret.Type = corev1.SecretTypeOpaque
+		secret.Data = data
+		if err := ctrl.SetControllerReference(key, secret, r.Scheme); err != nil {
+			return err
+		}
+		return r.Create(ctx, secret)
+	}
+
+	if equalSecretData(secret.Data, data) {
+		return nil
+	}
+	secret.Data = data
+	return r.Update(ctx, secret)
+}
+
+func equalSecretData(a, b map[string][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if w, ok := b[k]; !ok || string(v) != string(w) {
+			return false
+		}
+	}
+	return true
+}
+
+// deleteApiKeySecret deletes the Secret name when the ApiKey controls it.
+func (r *ApiKeyReconciler) deleteApiKeySecret(ctx context.Context, key *corev1alpha1.ApiKey, name string) error {
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: key.Namespace, Name: name}, secret)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !v1.IsControlledBy(secret, key) {
+		return nil
+	}
+
+	return client.IgnoreNotFound(r.Delete(ctx, secret))
+}
+
+// revokeKey revokes a key. A key already gone is not an error, and a Kannon
+// API without the revocation call leaves the key behind.
+func (r *ApiKeyReconciler) revokeKey(ctx context.Context, domain, id string, l logr.Logger) error {
+	err := r.Kannon.RevokeAPIKey(ctx, domain, id)
+	switch {
+	case errors.Is(err, kannon.ErrNotFound):
+		return nil
+	case errors.Is(err, kannon.ErrUnsupported):
+		l.Info("kannon can't revoke api keys, the key is left behind", "domain", domain, "id", id)
+		return nil
+	}
+
+	return err
+}
+
+// finalizeApiKey revokes the key of a deleted ApiKey before releasing it.
+// The Secret is garbage collected with the ApiKey.
+func (r *ApiKeyReconciler) finalizeApiKey(ctx context.Context, key *corev1alpha1.ApiKey, l logr.Logger) error {
+	if !controllerutil.ContainsFinalizer(key, apiKeyFinalizer) {
+		return nil
+	}
+
+	for _, k := range key.Status.Retiring {
+		if err := r.revokeKey(ctx, k.Domain, k.KeyID, l); err != nil {
+			return err
+		}
+	}
+	if key.Status.KeyID != "" {
+		if err := r.revokeKey(ctx, key.Status.Domain, key.Status.KeyID, l); err != nil {
+			return err
+		}
+		l.Info("revoked api key", "domain", key.Status.Domain)
+	}
+
+	controllerutil.RemoveFinalizer(key, apiKeyFinalizer)
+	return r.Update(ctx, key)
+}
+
+func apiKeyIssuedCondition(cond v1.Condition, key *corev1alpha1.ApiKey) v1.Condition {
+	cond.Status = v1.ConditionTrue
+	cond.Reason = corev1alpha1.ReasonApiKeyIssued
+	cond.Message = fmt.Sprintf("the api key of %s is stored in the secret %s", key.Status.Domain, key.Status.SecretName)
+	return cond
+}
+
+func (r *ApiKeyReconciler) rotationDue(key *corev1alpha1.ApiKey) bool {
+	if key.Spec.RotateAfter == nil || key.Spec.RotateAfter.Duration <= 0 || key.Status.IssuedAt == nil {
+		return false
+	}
+	return !r.now().Before(key.Status.IssuedAt.Add(key.Spec.RotateAfter.Duration))
+}
+
+// requeueIn returns how long until the key is rotated or a retiring key
+// is revoked, 0 when neither happens.
+func (r *ApiKeyReconciler) requeueIn(key *corev1alpha1.ApiKey) time.Duration {
+	d := r.rotateIn(key)
+	for _, k := range key.Status.Retiring {
+		revokeIn := k.RevokeAt.Sub(r.now())
+		if revokeIn <= 0 {
+			// the revocation failed, retried with the backoff of the error
+			continue
+		}
+		if d == 0 || revokeIn < d {
+			d = revokeIn
+		}
+	}
+	return d
+}
+
+// rotateIn returns how long until the key is rotated, 0 when it never is.
+func (r *ApiKeyReconciler) rotateIn(key *corev1alpha1.ApiKey) time.Duration {
+	if key.Spec.RotateAfter == nil || key.Spec.RotateAfter.Duration <= 0 || key.Status.IssuedAt == nil {
+		return 0
+	}
+
+	d := key.Status.IssuedAt.Add(key.Spec.RotateAfter.Duration).Sub(r.now())
+	if d <= 0 {
+		// overdue, rotated by the next reconcile
+		return time.Nanosecond
+	}
+	return d
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ApiKeyReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&corev1alpha1.ApiKey{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Owns(&corev1.Secret{}).
+		// a new domain name needs a new key
+		Watches(&source.Kind{Type: &corev1alpha1.Domain{}}, handler.EnqueueRequestsFromMapFunc(r.apiKeysForDomain),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Complete(tracing.Reconciler("APIKey", r))
+}
+
+// apiKeysForDomain maps a Domain to the ApiKeys referencing it.
+func (r *ApiKeyReconciler) apiKeysForDomain(obj client.Object) []reconcile.Request {
+	keys := &corev1alpha1.ApiKeyList{}
+	if err := r.List(context.Background(), keys, client.InNamespace(obj.GetNamespace())); err != nil {
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, key := range keys.Items {
+		if key.Spec.DomainRef.Name == obj.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&key)})
+		}
+	}
+	return requests
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+	"github.com/kannon-email/k8nnon/internal/kannon"
+)
+
+func TestApiKeyIssued(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	key := createApiKey(t)
+	key.Spec.Secret = &corev1alpha1.ApiKeySecretSpec{Name: "mailer", KeyKey: "KANNON_API_KEY"}
+	kannonClient := createKannonClient(t, domain)
+	r := createApiKeyReconciler(t, kannonClient, time.Now, domain, key)
+
+	res := reconcileObject(t, r, key)
+	assert.Zero(t, res.RequeueAfter, "a key without rotation should not be requeued")
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(key), key))
+	assert.Contains(t, key.Finalizers, apiKeyFinalizer)
+	assert.Equal(t, "example.com", key.Status.Domain)
+	assert.Equal(t, "mailer", key.Status.SecretName)
+	assert.True(t, meta.IsStatusConditionTrue(key.Status.Conditions, corev1alpha1.ConditionReady))
+
+	secret := &corev1.Secret{}
+	require.NoError(t, r.Get(ctx, types.NamespacedName{Name: "mailer", Namespace: "default"}, secret))
+	assert.True(t, v1.IsControlledBy(secret, key), "the secret should be owned by the api key")
+	assert.Equal(t, "secret-default/sender-1", string(secret.Data["KANNON_API_KEY"]))
+	assert.Equal(t, "example.com", string(secret.Data["domain"]))
+
+	// the key is issued once
+	reconcileObject(t, r, key)
+	assert.Equal(t, []string{"key-1"}, kannonClient.APIKeys("example.com"))
+
+	// deleting the api key revokes the key
+	require.NoError(t, r.Delete(ctx, key))
+	reconcileObject(t, r, key)
+
+	assert.Empty(t, kannonClient.APIKeys("example.com"))
+	err := r.Get(ctx, client.ObjectKeyFromObject(key), key)
+	assert.True(t, apierrors.IsNotFound(err), "the api key should be released: %v", err)
+}
+
+func TestApiKeyRotation(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	key := createApiKey(t)
+	key.Spec.RotateAfter = &v1.Duration{Duration: 24 * time.Hour}
+	kannonClient := createKannonClient(t, domain)
+	now := time.Now().Truncate(time.Second)
+	r := createApiKeyReconciler(t, kannonClient, func() time.Time { return now }, domain, key)
+
+	res := reconcileObject(t, r, key)
+	assert.Equal(t, 24*time.Hour, res.RequeueAfter)
+
+	now = now.Add(25 * time.Hour)
+	res = reconcileObject(t, r, key)
+	assert.Equal(t, time.Hour, res.RequeueAfter, "should be requeued when the previous key is revoked")
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(key), key))
+	assert.Equal(t, "key-2", key.Status.KeyID)
+	require.Len(t, key.Status.Retiring, 1)
+	assert.Equal(t, "key-1", key.Status.Retiring[0].KeyID)
+	assert.Equal(t, now.Add(time.Hour), key.Status.Retiring[0].RevokeAt.Time)
+	assert.Equal(t, []string{"key-1", "key-2"}, kannonClient.APIKeys("example.com"), "the previous key should work during the overlap")
+
+	secret := &corev1.Secret{}
+	require.NoError(t, r.Get(ctx, types.NamespacedName{Name: "sender-apikey", Namespace: "default"}, secret))
+	assert.Equal(t, "secret-default/sender-2", string(secret.Data["key"]))
+	assert.Equal(t, "key-2", secret.Annotations[apiKeyIDAnnotation])
+
+	now = now.Add(time.Hour)
+	res = reconcileObject(t, r, key)
+	assert.Equal(t, 23*time.Hour, res.RequeueAfter)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(key), key))
+	assert.Empty(t, key.Status.Retiring)
+	assert.Equal(t, []string{"key-2"}, kannonClient.APIKeys("example.com"), "the previous key should be revoked")
+}
+
+func TestApiKeyRotationStatusWriteFailure(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	key := createApiKey(t)
+	key.Spec.RotateAfter = &v1.Duration{Duration: 24 * time.Hour}
+	key.Spec.RotationOverlap = &v1.Duration{}
+	kannonClient := createKannonClient(t, domain)
+	now := time.Now().Truncate(time.Second)
+	r := createApiKeyReconciler(t, kannonClient, func() time.Time { return now }, domain, key)
+	reconcileObject(t, r, key)
+
+	// the status write following the rotation fails once
+	now = now.Add(25 * time.Hour)
+	c := r.Client
+	failures := 1
+	r.Client = failingStatusClient{Client: c, failures: &failures}
+	_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(key)})
+	require.Error(t, err)
+	r.Client = c
+
+	reconcileObject(t, r, key)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(key), key))
+	assert.Equal(t, "key-2", key.Status.KeyID, "the key stored in the secret should be adopted")
+	assert.Equal(t, now, key.Status.IssuedAt.Time)
+	assert.Equal(t, []string{"key-2"}, kannonClient.APIKeys("example.com"), "no other key should be issued")
+}
+
+func TestApiKeyDomainNotFound(t *testing.T) {
+	ctx := context.Background()
+
+	key := createApiKey(t)
+	r := createApiKeyReconciler(t, kannon.NewFakeClient(), time.Now, key)
+	reconcileObject(t, r, key)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(key), key))
+	cond := meta.FindStatusCondition(key.Status.Conditions, corev1alpha1.ConditionReady)
+	require.NotNil(t, cond)
+	assert.Equal(t, corev1alpha1.ReasonApiKeyDomainNotFound, cond.Reason)
+	assert.Equal(t, "the domain example does not exist", cond.Message)
+}
+
+func createKannonClient(t *testing.T, domains ...*corev1alpha1.Domain) *kannon.FakeClient {
+	t.Helper()
+
+	c := kannon.NewFakeClient()
+	for _, domain := range domains {
+		_, err := c.CreateDomain(context.Background(), domain.Spec.DomainName)
+		require.NoError(t, err)
+	}
+	return c
+}
+
+func createApiKeyReconciler(t *testing.T, kannonClient kannon.Client, clock func() time.Time, objs ...client.Object) *ApiKeyReconciler {
+	t.Helper()
+
+	c, scheme := newFakeClient(t, objs...)
+	return &ApiKeyReconciler{Client: c, Scheme: scheme, Kannon: kannonClient, clock: clock}
+}
+
+func createApiKey(t *testing.T) *corev1alpha1.ApiKey {
+	t.Helper()
+
+	return &corev1alpha1.ApiKey{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "sender",
+			Namespace: "default",
+		},
+		Spec: corev1alpha1.ApiKeySpec{
+			DomainRef: corev1alpha1.DomainReference{Name: "example"},
+		},
+	}
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+	"github.com/kannon-email/k8nnon/internal/notify"
+)
+
+// fieldManager owns the fields of the objects the operator manages.
+const fieldManager = "k8nnon"
+
+// applyStatsRoute converges an existing stats Ingress or HTTPRoute to the
+// desired object with server-side apply, taking back the fields that were
+// edited by hand. Apply only prunes the fields it applied earlier, so the
+// annotations and labels dropped from the spec are removed first with a
+// merge patch.
+func (r *DomainReconciler) applyStatsRoute(ctx context.Context, current, desired client.Object, domain *corev1alpha1.Domain) error {
+	pruned := current.DeepCopyObject().(client.Object)
+	if pruneManagedMetadata(pruned, domain) {
+		if err := r.Patch(ctx, pruned, client.MergeFrom(current)); err != nil {
+			return err
+		}
+	}
+
+	gvk, err := apiutil.GVKForObject(desired, r.Scheme)
+	if err != nil {
+		return err
+	}
+	desired.GetObjectKind().SetGroupVersionKind(gvk)
+
+	return r.Patch(ctx, desired, client.Apply, client.FieldOwner(fieldManager), client.ForceOwnership)
+}
+
+// createStatsRoute creates the stats Ingress or HTTPRoute.
+func (r *DomainReconciler) createStatsRoute(ctx context.Context, obj client.Object, domain *corev1alpha1.Domain) error {
+	if err := r.Create(ctx, obj, client.FieldOwner(fieldManager)); err != nil {
+		return err
+	}
+	r.recordStatsRouteEvent(domain, obj, "Created")
+
+	return nil
+}
+
+// deleteStatsRoute deletes the stats Ingress or HTTPRoute, unless it is
+// already being deleted.
+func (r *DomainReconciler) deleteStatsRoute(ctx context.Context, obj client.Object, domain *corev1alpha1.Domain) error {
+	if obj.GetDeletionTimestamp() != nil {
+		return nil
+	}
+	if err := r.Delete(ctx, obj); err != nil {
+		return err
+	}
+	r.recordStatsRouteEvent(domain, obj, "Deleted")
+	r.notify(ctx, domain, notify.EventIngressDeleted, "deleted the stats %s %s", statsRouteKind(obj), obj.GetName())
+
+	return nil
+}
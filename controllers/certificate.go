@@ -0,0 +1,100 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+)
+
+// certificateCondition inspects the TLS Secret of the stats Ingress, either
+// provided or issued by cert-manager, and records the certificate expiry.
+func (r *DomainReconciler) certificateCondition(ctx context.Context, domain *corev1alpha1.Domain) (v1.Condition, error) {
+	name := domain.Spec.TLSSecretNameOrDefault()
+	hosts := servedStatsHosts(domain)
+
+	cond := v1.Condition{
+		Type:               corev1alpha1.ConditionCertificateReady,
+		Status:             v1.ConditionFalse,
+		ObservedGeneration: domain.Generation,
+	}
+	domain.Status.Certificate = &corev1alpha1.CertificateStatus{SecretName: name}
+
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: domain.Namespace}, secret)
+	if apierrors.IsNotFound(err) {
+		cond.Reason = corev1alpha1.ReasonCertificateMissing
+		cond.Message = fmt.Sprintf("secret %s does not exist yet", name)
+		return cond, nil
+	}
+	if err != nil {
+		return cond, err
+	}
+
+	cert, err := parseCertificate(secret.Data[corev1.TLSCertKey])
+	if err != nil {
+		cond.Reason = corev1alpha1.ReasonCertificateInvalid
+		cond.Message = fmt.Sprintf("secret %s: %v", name, err)
+		return cond, nil
+	}
+
+	notAfter := v1.NewTime(cert.NotAfter)
+	domain.Status.Certificate.NotAfter = &notAfter
+
+	mismatch := ""
+	for _, host := range hosts {
+		if cert.VerifyHostname(host) != nil {
+			mismatch = host
+			break
+		}
+	}
+
+	switch {
+	case !r.now().Before(cert.NotAfter):
+		cond.Reason = corev1alpha1.ReasonCertificateExpired
+		cond.Message = fmt.Sprintf("the certificate expired at %s", cert.NotAfter.UTC().Format(time.RFC3339))
+	case mismatch != "":
+		cond.Reason = corev1alpha1.ReasonCertificateMismatch
+		cond.Message = fmt.Sprintf("the certificate is not valid for %s", mismatch)
+	default:
+		cond.Status = v1.ConditionTrue
+		cond.Reason = corev1alpha1.ReasonCertificateValid
+		cond.Message = fmt.Sprintf("the certificate is valid until %s", cert.NotAfter.UTC().Format(time.RFC3339))
+	}
+
+	return cond, nil
+}
+
+// parseCertificate returns the leaf certificate of a PEM chain.
+func parseCertificate(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("%s is not a PEM certificate", corev1.TLSCertKey)
+	}
+
+	return x509.ParseCertificate(block.Bytes)
+}
@@ -0,0 +1,30 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import "time"
+
+// clock returns the current time to the reconcilers embedding it, it
+// defaults to time.Now and is replaced by the tests.
+type clock func() time.Time
+
+func (c clock) now() time.Time {
+	if c != nil {
+		return c()
+	}
+	return time.Now()
+}
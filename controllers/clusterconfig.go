@@ -0,0 +1,106 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+)
+
+// loadClusterDefaults reads the ClusterDomainConfig of the operator, so
+// that the reconcile uses its current defaults.
+func (r *DomainReconciler) loadClusterDefaults(ctx context.Context) error {
+	if r.ClusterConfigName == "" {
+		return nil
+	}
+
+	cfg := &corev1alpha1.ClusterDomainConfig{}
+	err := r.Get(ctx, types.NamespacedName{Name: r.ClusterConfigName}, cfg)
+	switch {
+	case apierrors.IsNotFound(err):
+		r.clusterConfig.Store(nil)
+		return nil
+	case err != nil:
+		return err
+	}
+
+	r.clusterConfig.Store(&cfg.Spec)
+	return nil
+}
+
+// clusterDefaults returns the spec of the ClusterDomainConfig, nil when
+// there is none.
+func (r *DomainReconciler) clusterDefaults() *corev1alpha1.ClusterDomainConfigSpec {
+	return r.clusterConfig.Load()
+}
+
+// withClusterDefaults returns domain with the defaults of the
+// ClusterDomainConfig filling its unset fields. The resources are built
+// from it, while the status is kept on domain: the copy shares it and must
+// not be written.
+func (r *DomainReconciler) withClusterDefaults(domain *corev1alpha1.Domain) *corev1alpha1.Domain {
+	defaults := r.clusterDefaults()
+	if defaults == nil {
+		return domain
+	}
+
+	defaulted := *domain
+	spec := &defaulted.Spec
+	if ingress := defaults.Ingress; ingress != nil {
+		if spec.Ingress.ClassName == "" {
+			spec.Ingress.ClassName = ingress.ClassName
+		}
+		if spec.Ingress.Service.Name == "" && ingress.Service != nil {
+			spec.Ingress.Service = *ingress.Service
+		}
+	}
+	if spec.CheckInterval == nil {
+		spec.CheckInterval = defaults.CheckInterval
+	}
+	if spec.UnverifiedCheckInterval == nil {
+		spec.UnverifiedCheckInterval = defaults.UnverifiedCheckInterval
+	}
+
+	return &defaulted
+}
+
+// domainsForClusterConfig enqueues the Domains watched by the operator when
+// its ClusterDomainConfig changes, any of them may use the defaults.
+func (r *DomainReconciler) domainsForClusterConfig(obj client.Object) []reconcile.Request {
+	if obj.GetName() != r.ClusterConfigName {
+		return nil
+	}
+
+	domains := &corev1alpha1.DomainList{}
+	if err := r.List(context.Background(), domains); err != nil {
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(domains.Items))
+	for _, domain := range domains.Items {
+		if r.Shard.Owns(domain.Namespace, domain.Name) {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: domain.Namespace, Name: domain.Name}})
+		}
+	}
+	return requests
+}
@@ -0,0 +1,189 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+	"github.com/kannon-email/k8nnon/internal/dns/checker"
+	"github.com/kannon-email/k8nnon/internal/notify"
+	"github.com/kannon-email/k8nnon/internal/shard"
+	"github.com/kannon-email/k8nnon/internal/tracing"
+)
+
+// ClusterDomainConfigReconciler applies the operator-wide settings of the
+// ClusterDomainConfig: the resolvers and the notification sinks. The
+// defaults of the Domains are read by the Domain controller itself.
+type ClusterDomainConfigReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// Name is the ClusterDomainConfig of the operator, the others are
+	// ignored.
+	Name string
+
+	// DNSChecker is switched to the resolvers of spec.dns. Nil ignores
+	// them.
+	DNSChecker *checker.Switch
+
+	// DefaultDNSChecker queries the resolvers of the flags, it is used
+	// when spec.dns sets none.
+	DefaultDNSChecker checker.DNSChecker
+
+	// NewDNSChecker creates a checker querying resolvers, with the other
+	// options of the flags.
+	NewDNSChecker func(resolvers []string) (checker.DNSChecker, error)
+
+	// Notifier is configured with the sinks of the flags and of
+	// spec.notifications. Nil ignores them.
+	Notifier *notify.Dispatcher
+
+	// Notifications are the sinks of the flags.
+	Notifications notify.Config
+
+	// Shard is the share of the resources the replica reconciles. Every
+	// replica applies the config, only the owner of its name reports it.
+	Shard shard.Shard
+
+	m sync.Mutex
+	// resolvers are the resolvers of spec.dns in use.
+	resolvers []string
+}
+
+//+kubebuilder:rbac:groups=core.k8s.kannon.email,resources=clusterdomainconfigs,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core.k8s.kannon.email,resources=clusterdomainconfigs/status,verbs=get;update;patch
+
+// Reconcile applies the resolvers and the notification sinks of the
+// ClusterDomainConfig, or restores the ones of the flags once it is
+// deleted.
+func (r *ClusterDomainConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	if req.Name != r.Name {
+		return ctrl.Result{}, nil
+	}
+
+	l := log.FromContext(ctx)
+	l.Info("reconciling cluster domain config", "clusterDomainConfig", req.Name)
+
+	cfg := &corev1alpha1.ClusterDomainConfig{}
+	if err := r.Get(ctx, req.NamespacedName, cfg); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			return ctrl.Result{}, err
+		}
+		cfg = &corev1alpha1.ClusterDomainConfig{}
+		if err := r.apply(cfg); err != nil {
+			l.Error(err, "failed to restore the settings of the flags")
+		}
+		return ctrl.Result{}, nil
+	}
+
+	cond := v1.Condition{
+		Type:               corev1alpha1.ConditionConfigApplied,
+		Status:             v1.ConditionTrue,
+		Reason:             corev1alpha1.ReasonConfigApplied,
+		Message:            "the resolvers and the notification sinks are in use",
+		ObservedGeneration: cfg.Generation,
+	}
+	if err := r.apply(cfg); err != nil {
+		l.Error(err, "invalid cluster domain config", "clusterDomainConfig", req.Name)
+		cond.Status = v1.ConditionFalse
+		cond.Reason = corev1alpha1.ReasonConfigInvalid
+		cond.Message = err.Error()
+	}
+
+	if !r.Shard.Owns("", cfg.Name) {
+		return ctrl.Result{}, nil
+	}
+	cfg.Status.ObservedGeneration = cfg.Generation
+	meta.SetStatusCondition(&cfg.Status.Conditions, cond)
+	return ctrl.Result{}, r.Status().Update(ctx, cfg)
+}
+
+// apply switches to the resolvers and the notification sinks of cfg. The
+// previous ones are kept when they are invalid.
+func (r *ClusterDomainConfigReconciler) apply(cfg *corev1alpha1.ClusterDomainConfig) error {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if r.DNSChecker != nil {
+		var resolvers []string
+		if cfg.Spec.DNS != nil {
+			resolvers = cfg.Spec.DNS.Resolvers
+		}
+		if !reflect.DeepEqual(resolvers, r.resolvers) {
+			next := r.DefaultDNSChecker
+			if len(resolvers) > 0 {
+				var err error
+				if next, err = r.NewDNSChecker(resolvers); err != nil {
+					return err
+				}
+			}
+			r.DNSChecker.Set(next)
+			r.resolvers = resolvers
+		}
+	}
+
+	if r.Notifier != nil {
+		if err := r.Notifier.Configure(notificationsConfig(r.Notifications, cfg.Spec.Notifications)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// notificationsConfig returns the sinks of the flags followed by the ones
+// of spec.notifications.
+func notificationsConfig(flags notify.Config, spec *corev1alpha1.NotificationsSpec) notify.Config {
+	cfg := notify.Config{
+		Slack:    append([]notify.SinkConfig{}, flags.Slack...),
+		Webhooks: append([]notify.SinkConfig{}, flags.Webhooks...),
+	}
+	if spec == nil {
+		return cfg
+	}
+
+	sinkConfig := func(s corev1alpha1.NotificationSink) notify.SinkConfig {
+		return notify.SinkConfig{URL: strings.TrimSpace(s.URL), Template: s.Template, Headers: s.Headers, Events: s.Events}
+	}
+	for _, s := range spec.Slack {
+		cfg.Slack = append(cfg.Slack, sinkConfig(s))
+	}
+	for _, s := range spec.Webhooks {
+		cfg.Webhooks = append(cfg.Webhooks, sinkConfig(s))
+	}
+	return cfg
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ClusterDomainConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&corev1alpha1.ClusterDomainConfig{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Complete(tracing.Reconciler("ClusterDomainConfig", r))
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+	"github.com/kannon-email/k8nnon/internal/dns/checker"
+	"github.com/kannon-email/k8nnon/internal/notify"
+)
+
+func TestClusterDomainConfigResolvers(t *testing.T) {
+	ctx := context.Background()
+
+	cfg := &corev1alpha1.ClusterDomainConfig{
+		ObjectMeta: v1.ObjectMeta{Name: "default"},
+		Spec: corev1alpha1.ClusterDomainConfigSpec{
+			DNS: &corev1alpha1.DNSDefaults{Resolvers: []string{"192.0.2.53"}},
+		},
+	}
+	flagsChecker := checker.NewFakeDNSChecker(checker.WithAll(true))
+	r := createClusterDomainConfigReconciler(t, flagsChecker, cfg)
+
+	var created [][]string
+	configChecker := checker.NewFakeDNSChecker(checker.WithAll(true))
+	r.NewDNSChecker = func(resolvers []string) (checker.DNSChecker, error) {
+		created = append(created, resolvers)
+		return configChecker, nil
+	}
+
+	reconcileObject(t, r, cfg)
+	assert.Equal(t, [][]string{{"192.0.2.53"}}, created)
+	assert.Same(t, configChecker, r.DNSChecker.Current())
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cfg), cfg))
+	assert.True(t, meta.IsStatusConditionTrue(cfg.Status.Conditions, corev1alpha1.ConditionConfigApplied))
+	assert.Equal(t, cfg.Generation, cfg.Status.ObservedGeneration)
+
+	reconcileObject(t, r, cfg)
+	assert.Len(t, created, 1, "should keep the checker while the resolvers are the same")
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cfg), cfg))
+	cfg.Spec.DNS = nil
+	require.NoError(t, r.Update(ctx, cfg))
+	reconcileObject(t, r, cfg)
+	assert.Same(t, flagsChecker, r.DNSChecker.Current(), "should restore the resolvers of the flags")
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cfg), cfg))
+	cfg.Spec.DNS = &corev1alpha1.DNSDefaults{Resolvers: []string{"192.0.2.54"}}
+	require.NoError(t, r.Update(ctx, cfg))
+	reconcileObject(t, r, cfg)
+	assert.Same(t, configChecker, r.DNSChecker.Current())
+
+	require.NoError(t, r.Delete(ctx, cfg))
+	reconcileObject(t, r, cfg)
+	assert.Same(t, flagsChecker, r.DNSChecker.Current(), "should restore the resolvers of the flags once deleted")
+}
+
+func TestClusterDomainConfigInvalid(t *testing.T) {
+	ctx := context.Background()
+
+	cfg := &corev1alpha1.ClusterDomainConfig{
+		ObjectMeta: v1.ObjectMeta{Name: "default"},
+		Spec: corev1alpha1.ClusterDomainConfigSpec{
+			Notifications: &corev1alpha1.NotificationsSpec{
+				Webhooks: []corev1alpha1.NotificationSink{{URL: "not a url"}},
+			},
+		},
+	}
+	r := createClusterDomainConfigReconciler(t, checker.NewFakeDNSChecker(), cfg)
+	reconcileObject(t, r, cfg)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cfg), cfg))
+	cond := meta.FindStatusCondition(cfg.Status.Conditions, corev1alpha1.ConditionConfigApplied)
+	require.NotNil(t, cond)
+	assert.Equal(t, v1.ConditionFalse, cond.Status)
+	assert.Equal(t, corev1alpha1.ReasonConfigInvalid, cond.Reason)
+	assert.Contains(t, cond.Message, "invalid notification url")
+
+	cfg.Spec.Notifications.Webhooks[0].URL = "https://hooks.example.com/domains"
+	require.NoError(t, r.Update(ctx, cfg))
+	reconcileObject(t, r, cfg)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cfg), cfg))
+	assert.True(t, meta.IsStatusConditionTrue(cfg.Status.Conditions, corev1alpha1.ConditionConfigApplied))
+}
+
+func TestClusterDomainConfigOtherName(t *testing.T) {
+	ctx := context.Background()
+
+	cfg := &corev1alpha1.ClusterDomainConfig{ObjectMeta: v1.ObjectMeta{Name: "other"}}
+	r := createClusterDomainConfigReconciler(t, checker.NewFakeDNSChecker(), cfg)
+	reconcileObject(t, r, cfg)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cfg), cfg))
+	assert.Empty(t, cfg.Status.Conditions)
+}
+
+func TestNotificationsConfig(t *testing.T) {
+	flags := notify.Config{Slack: []notify.SinkConfig{{URL: "https://hooks.slack.com/services/flag"}}}
+	spec := &corev1alpha1.NotificationsSpec{
+		Slack:    []corev1alpha1.NotificationSink{{URL: "https://hooks.slack.com/services/config", Events: []string{notify.EventVerified}}},
+		Webhooks: []corev1alpha1.NotificationSink{{URL: "https://hooks.example.com", Headers: map[string]string{"X-Token": "token"}}},
+	}
+
+	cfg := notificationsConfig(flags, spec)
+	assert.Equal(t, notify.Config{
+		Slack: []notify.SinkConfig{
+			{URL: "https://hooks.slack.com/services/flag"},
+			{URL: "https://hooks.slack.com/services/config", Events: []string{notify.EventVerified}},
+		},
+		Webhooks: []notify.SinkConfig{{URL: "https://hooks.example.com", Headers: map[string]string{"X-Token": "token"}}},
+	}, cfg)
+	assert.Len(t, flags.Slack, 1, "should not change the sinks of the flags")
+
+	assert.Equal(t, flags.Slack, notificationsConfig(flags, nil).Slack)
+}
+
+func createClusterDomainConfigReconciler(t *testing.T, dnsChecker checker.DNSChecker, objs ...client.Object) *ClusterDomainConfigReconciler {
+	t.Helper()
+
+	notifier, err := notify.NewDispatcher(notify.Config{}, nil)
+	require.NoError(t, err)
+
+	c, scheme := newFakeClient(t, objs...)
+	return &ClusterDomainConfigReconciler{
+		Client:            c,
+		Scheme:            scheme,
+		Name:              "default",
+		DNSChecker:        checker.NewSwitch(dnsChecker),
+		DefaultDNSChecker: dnsChecker,
+		Notifier:          notifier,
+	}
+}
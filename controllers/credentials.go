@@ -0,0 +1,154 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+	"github.com/kannon-email/k8nnon/internal/kannon"
+)
+
+// readCredentials reads the Secret of ref in the namespace. The data keeps
+// every key of the Secret, plus the credentials mapped by ref.keys under
+// their own name, so that Secrets laid out by an ExternalSecret or a
+// SealedSecret are read like the ones written by hand.
+func readCredentials(ctx context.Context, c client.Reader, namespace string, ref *corev1alpha1.CredentialsSecretReference) (map[string][]byte, error) {
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: namespace}, secret); err != nil {
+		return nil, err
+	}
+
+	data := make(map[string][]byte, len(secret.Data))
+	for key, value := range secret.Data {
+		data[key] = value
+	}
+	for credential, key := range ref.Keys {
+		if value, ok := secret.Data[key]; ok {
+			data[credential] = value
+		} else {
+			delete(data, credential)
+		}
+	}
+	return data, nil
+}
+
+// kannonCredentials returns the sending credentials of the Domain: the
+// ones of spec.kannonCredentialsRef, or the ones stored on the registration
+// of the domain. They are empty until the domain is registered.
+func kannonCredentials(ctx context.Context, c client.Reader, domain *corev1alpha1.Domain) (kannon.Credentials, error) {
+	if ref := domain.Spec.KannonCredentialsRef; ref != nil {
+		data, err := readCredentials(ctx, c, domain.Namespace, ref)
+		if apierrors.IsNotFound(err) {
+			return kannon.Credentials{}, nil
+		}
+		if err != nil {
+			return kannon.Credentials{}, err
+		}
+		return referencedKannonCredentials(domain, data), nil
+	}
+
+	secret := &corev1.Secret{}
+	err := c.Get(ctx, types.NamespacedName{Name: kannonSecretName(domain), Namespace: domain.Namespace}, secret)
+	if apierrors.IsNotFound(err) {
+		return kannon.Credentials{}, nil
+	}
+	if err != nil {
+		return kannon.Credentials{}, err
+	}
+	// a Secret left by a previous domain name holds a stale registration
+	if string(secret.Data[kannonDomainKey]) != domain.Spec.DomainName {
+		return kannon.Credentials{}, nil
+	}
+	return kannon.Credentials{Domain: domain.Spec.DomainName, Key: string(secret.Data[kannonKeyKey])}, nil
+}
+
+// referencedKannonCredentials maps the data of the referenced Secret to
+// the credentials, the domain defaulting to the one of the Domain.
+func referencedKannonCredentials(domain *corev1alpha1.Domain, data map[string][]byte) kannon.Credentials {
+	creds := kannon.Credentials{
+		Domain: string(data[kannonDomainKey]),
+		Key:    string(data[kannonKeyKey]),
+	}
+	if creds.Domain == "" {
+		creds.Domain = domain.Spec.DomainName
+	}
+	if creds.Domain != domain.Spec.DomainName {
+		return kannon.Credentials{}
+	}
+	return creds
+}
+
+// kannonCredentialsSecretName returns the name of the Secret holding the
+// sending credentials of the Domain.
+func kannonCredentialsSecretName(domain *corev1alpha1.Domain) string {
+	if ref := domain.Spec.KannonCredentialsRef; ref != nil {
+		return ref.Name
+	}
+	return kannonSecretName(domain)
+}
+
+// credentialSecretNames returns the names of the Secrets the Domain reads
+// credentials from without owning them.
+func credentialSecretNames(domain *corev1alpha1.Domain) []string {
+	var names []string
+	if dns := domain.Spec.DNS; dns != nil && dns.Provider != nil {
+		if ref := dns.Provider.SecretRef; ref != nil {
+			names = append(names, ref.Name)
+		} else if dns.Provider.CredentialsSecretName != "" {
+			names = append(names, dns.Provider.CredentialsSecretName)
+		}
+	}
+	if ref := domain.Spec.KannonCredentialsRef; ref != nil {
+		names = append(names, ref.Name)
+	}
+	if rep := domain.Spec.Reputation; rep != nil && rep.SecretName != "" {
+		names = append(names, rep.SecretName)
+	}
+	// the token of the report receiver is read on each request
+	if reports := domain.Spec.DMARCReports; reports != nil && reports.IMAP != nil && reports.IMAP.SecretName != "" {
+		names = append(names, reports.IMAP.SecretName)
+	}
+	return names
+}
+
+// domainsForSecret maps a Secret to the Domains of its namespace reading
+// credentials from it, so that rotated credentials are picked up without
+// waiting for the next check.
+func (r *DomainReconciler) domainsForSecret(obj client.Object) []reconcile.Request {
+	domains := &corev1alpha1.DomainList{}
+	if err := r.List(context.Background(), domains, client.InNamespace(obj.GetNamespace())); err != nil {
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, domain := range domains.Items {
+		for _, name := range credentialSecretNames(&domain) {
+			if name == obj.GetName() {
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: domain.Namespace, Name: domain.Name}})
+				break
+			}
+		}
+	}
+	return requests
+}
@@ -0,0 +1,226 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+	"github.com/kannon-email/k8nnon/internal/delivery"
+)
+
+// domainNameIndex indexes the Domains by spec.domainName, lowercased.
+const domainNameIndex = "spec.domainName"
+
+func indexDomainName(obj client.Object) []string {
+	domain, ok := obj.(*corev1alpha1.Domain)
+	if !ok || domain.Spec.DomainName == "" {
+		return nil
+	}
+	return []string{strings.ToLower(domain.Spec.DomainName)}
+}
+
+// DeliveryFlushInterval is how often the counters received by a replica
+// are added to the status of the Domains.
+const DeliveryFlushInterval = time.Minute
+
+// KnownDeliveryDomain reports whether a Domain of the domain name exists,
+// so that the webhooks of other domains are not counted.
+func KnownDeliveryDomain(c client.Reader) func(string) bool {
+	return func(name string) bool {
+		domains := &corev1alpha1.DomainList{}
+		if err := c.List(context.Background(), domains, client.MatchingFields{domainNameIndex: name}); err != nil {
+			return false
+		}
+		return len(domains.Items) > 0
+	}
+}
+
+// DeliveryFlusher adds the counters of the delivery webhooks received by
+// the replica to the status of the Domains. It runs on every replica, each
+// receiving a share of the webhooks.
+type DeliveryFlusher struct {
+	Client     client.Client
+	Aggregator *delivery.Aggregator
+
+	// Interval is how often the counters are flushed, it defaults to
+	// DeliveryFlushInterval.
+	Interval time.Duration
+
+	clock
+}
+
+// Start flushes the counters every interval, and once more when ctx is
+// done.
+func (f *DeliveryFlusher) Start(ctx context.Context) error {
+	interval := f.Interval
+	if interval <= 0 {
+		interval = DeliveryFlushInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// the counters received since the last flush would be lost
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			f.flush(log.IntoContext(flushCtx, log.FromContext(ctx)))
+			cancel()
+			return nil
+		case <-ticker.C:
+			f.flush(ctx)
+		}
+	}
+}
+
+// NeedLeaderElection reports that the flusher runs on every replica.
+func (f *DeliveryFlusher) NeedLeaderElection() bool {
+	return false
+}
+
+// flush adds the drained counters to the Domains of their domain name. The
+// counters of a domain name none of whose Domains could be updated are
+// restored, to be flushed again.
+func (f *DeliveryFlusher) flush(ctx context.Context) {
+	for name, buckets := range f.Aggregator.Drain() {
+		domains := &corev1alpha1.DomainList{}
+		if err := f.Client.List(ctx, domains, client.MatchingFields{domainNameIndex: name}); err != nil {
+			log.FromContext(ctx).Error(err, "failed to list domains", "domainName", name)
+			f.Aggregator.Restore(name, buckets)
+			continue
+		}
+
+		var errs []error
+		published := 0
+		for i := range domains.Items {
+			if err := f.addCounters(ctx, client.ObjectKeyFromObject(&domains.Items[i]), buckets); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			published++
+		}
+		if err := errors.Join(errs...); err != nil {
+			log.FromContext(ctx).Error(err, "failed to publish the delivery counters", "domainName", name)
+			if published == 0 {
+				f.Aggregator.Restore(name, buckets)
+			}
+		}
+	}
+}
+
+// addCounters adds buckets to the delivery status of a Domain, retrying on
+// the conflicts with the other writers of the status.
+func (f *DeliveryFlusher) addCounters(ctx context.Context, key client.ObjectKey, buckets []delivery.Bucket) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		domain := &corev1alpha1.Domain{}
+		if err := f.Client.Get(ctx, key, domain); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		base := domain.DeepCopy()
+
+		applyDelivery(domain, buckets, f.Aggregator.Window(), f.now())
+		domain.Status.Delivery.UpdatedAt = &v1.Time{Time: f.now()}
+		return f.Client.Status().Patch(ctx, domain, client.MergeFromWithOptions(base, client.MergeFromWithOptimisticLock{}))
+	})
+}
+
+// applyDelivery adds buckets to the persisted counters of the domain,
+// drops the ones out of the window, and computes the totals and the
+// HighBounceRate condition from them.
+func applyDelivery(domain *corev1alpha1.Domain, buckets []delivery.Bucket, window time.Duration, now time.Time) {
+	status := domain.Status.Delivery
+	if status == nil {
+		status = &corev1alpha1.DeliveryStatus{}
+		domain.Status.Delivery = status
+	}
+
+	persisted := make([]delivery.Bucket, 0, len(status.Buckets))
+	for _, b := range status.Buckets {
+		persisted = append(persisted, delivery.Bucket{Start: b.Start.Time, Stats: delivery.Stats{
+			Sent: b.Sent, Delivered: b.Delivered, Bounced: b.Bounced, Complained: b.Complained,
+		}})
+	}
+	if len(buckets) > 0 {
+		persisted = delivery.Merge(persisted, buckets, window, now)
+		status.Buckets = make([]corev1alpha1.DeliveryBucket, 0, len(persisted))
+		for _, b := range persisted {
+			status.Buckets = append(status.Buckets, corev1alpha1.DeliveryBucket{
+				Start: v1.Time{Time: b.Start}, Sent: b.Sent, Delivered: b.Delivered, Bounced: b.Bounced, Complained: b.Complained,
+			})
+		}
+	}
+
+	stats := delivery.Sum(persisted, window, now)
+	status.Window = v1.Duration{Duration: window}
+	status.Sent = stats.Sent
+	status.Delivered = stats.Delivered
+	status.Bounced = stats.Bounced
+	status.Complained = stats.Complained
+	status.BounceRate = formatRate(stats.BounceRate())
+	status.ComplaintRate = formatRate(stats.ComplaintRate())
+
+	meta.SetStatusCondition(&domain.Status.Conditions, highBounceRateCondition(domain))
+}
+
+func formatRate(rate float64) string {
+	return fmt.Sprintf("%.2f%%", rate*100)
+}
+
+// highBounceRateCondition compares the delivery counters of the domain with
+// the thresholds of spec.delivery. It is informational and does not affect
+// the Ready condition.
+func highBounceRateCondition(domain *corev1alpha1.Domain) v1.Condition {
+	cond := v1.Condition{
+		Type:               corev1alpha1.ConditionHighBounceRate,
+		Status:             v1.ConditionFalse,
+		ObservedGeneration: domain.Generation,
+	}
+
+	status := domain.Status.Delivery
+	spec := domain.Spec.Delivery
+	stats := delivery.Stats{Sent: status.Sent, Bounced: status.Bounced, Complained: status.Complained}
+
+	switch {
+	case stats.Sent < spec.MinSentOrDefault():
+		cond.Reason = corev1alpha1.ReasonNotEnoughMessages
+		cond.Message = fmt.Sprintf("%d messages sent in the last %s, the rates are evaluated from %d", stats.Sent, status.Window.Duration, spec.MinSentOrDefault())
+	case stats.BounceRate() > spec.MaxBounceRateOrDefault():
+		cond.Status = v1.ConditionTrue
+		cond.Reason = corev1alpha1.ReasonBounceRateExceeded
+		cond.Message = fmt.Sprintf("%s of the messages bounced in the last %s, over %s", status.BounceRate, status.Window.Duration, formatRate(spec.MaxBounceRateOrDefault()))
+	case stats.ComplaintRate() > spec.MaxComplaintRateOrDefault():
+		cond.Status = v1.ConditionTrue
+		cond.Reason = corev1alpha1.ReasonComplaintRateExceeded
+		cond.Message = fmt.Sprintf("%s of the messages were reported as spam in the last %s, over %s", status.ComplaintRate, status.Window.Duration, formatRate(spec.MaxComplaintRateOrDefault()))
+	default:
+		cond.Reason = corev1alpha1.ReasonWithinThresholds
+		cond.Message = "the bounce and the complaint rates are within the thresholds"
+	}
+
+	return cond
+}
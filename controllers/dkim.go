@@ -0,0 +1,451 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+	"github.com/kannon-email/k8nnon/internal/dkim"
+)
+
+const (
+	// dkimRetireGracePeriod is how long a replaced key is kept, so that the
+	// messages it signed can still be verified.
+	dkimRetireGracePeriod = 7 * 24 * time.Hour
+
+	// pendingDKIMRecheckInterval bounds the wait between the checks of a
+	// key being rotated in.
+	pendingDKIMRecheckInterval = 5 * time.Minute
+)
+
+// reconcileDKIMKey makes sure a Domain without a public key in its spec has
+// a generated key pair, rotates it when due or when its selector or key
+// type no longer match the spec, and records the keys in its status.
+func (r *DomainReconciler) reconcileDKIMKey(ctx context.Context, domain *corev1alpha1.Domain) error {
+	if domain.Spec.DKIM.PublicKey != "" {
+		domain.Status.DKIM = nil
+		meta.RemoveStatusCondition(&domain.Status.Conditions, corev1alpha1.ConditionDKIMRotating)
+		return nil
+	}
+
+	now := r.now()
+
+	status := &corev1alpha1.DKIMStatus{}
+	if domain.Status.DKIM != nil {
+		status = domain.Status.DKIM.DeepCopy()
+	}
+
+	selector := status.Selector
+	if selector == "" {
+		selector = domain.Spec.DKIM.SelectorOrDefault()
+	}
+
+	active, err := r.ensureDKIMKey(ctx, domain, selector, status.CreatedAt, now)
+	if err != nil {
+		return err
+	}
+	status.DKIMKey = *active
+
+	// a pending key generated for a spec since changed is replaced
+	if status.Pending != nil && !dkimKeyMatchesSpec(domain, *status.Pending) {
+		if err := r.deleteDKIMSecret(ctx, domain, status.Pending.SecretName); err != nil {
+			return err
+		}
+		status.Pending = nil
+	}
+
+	if status.Pending == nil {
+		if selector, ok := nextDKIMSelector(domain, status, now); ok {
+			pending, err := r.ensureDKIMKey(ctx, domain, selector, nil, now)
+			if err != nil {
+				return err
+			}
+			status.Pending = pending
+		}
+	}
+
+	// the pending key becomes active only once receivers can verify it
+	if status.Pending != nil && r.DNSChecker.CheckDomainDKIM(ctx, withDKIMKey(domain, *status.Pending)).Result() {
+		status.RetiringKeys = append(status.RetiringKeys, corev1alpha1.RetiringDKIMKey{
+			Selector:   status.Selector,
+			SecretName: status.SecretName,
+			RetireAt:   v1.NewTime(now.Add(dkimRetireGracePeriod)),
+			KeyType:    status.KeyType,
+			PublicKey:  status.PublicKey,
+		})
+		status.DKIMKey = *status.Pending
+		status.Pending = nil
+	}
+
+	// every replaced key is kept for its own grace period
+	var retiring []corev1alpha1.RetiringDKIMKey
+	for _, key := range status.RetiringKeys {
+		if now.Before(key.RetireAt.Time) {
+			retiring = append(retiring, key)
+			continue
+		}
+		if err := r.deleteDKIMSecret(ctx, domain, key.SecretName); err != nil {
+			return err
+		}
+	}
+	status.RetiringKeys = retiring
+
+	domain.Status.DKIM = status
+	meta.SetStatusCondition(&domain.Status.Conditions, dkimRotationCondition(domain))
+
+	return nil
+}
+
+// nextDKIMSelector returns the selector of the key replacing the active one
+// of status, and false when it is not replaced. The key is replaced when
+// the spec changes its selector or key type, and when its rotation is due.
+// The selector is the same on every retry of the rotation, so that a
+// retried rotation finds the Secret it already created.
+func nextDKIMSelector(domain *corev1alpha1.Domain, status *corev1alpha1.DKIMStatus, now time.Time) (string, bool) {
+	selector := domain.Spec.DKIM.SelectorOrDefault()
+	switch {
+	case !dkimSelectorOf(selector, status.Selector) && !dkimSelectorInUse(status, selector):
+		return selector, true
+	case !dkimKeyMatchesSpec(domain, status.DKIMKey):
+		// the selector of the spec is still in use, or the key type
+		// changed: the key gets a selector of its own
+		return fmt.Sprintf("%s-g%d", selector, domain.Generation), true
+	case dkimRotationDue(domain, status, now):
+		return rotatedDKIMSelector(domain, status), true
+	}
+
+	return "", false
+}
+
+// dkimKeyMatchesSpec reports whether key was generated with the selector
+// and the key type of the spec. A key without its type predates the key
+// types and is kept.
+func dkimKeyMatchesSpec(domain *corev1alpha1.Domain, key corev1alpha1.DKIMKey) bool {
+	return dkimSelectorOf(domain.Spec.DKIM.SelectorOrDefault(), key.Selector) &&
+		(key.KeyType == "" || key.KeyType == domain.Spec.DKIM.KeyTypeOrDefault())
+}
+
+// dkimSelectorOf reports whether selector is specSelector or one of its
+// rotated selectors.
+func dkimSelectorOf(specSelector, selector string) bool {
+	return selector == specSelector || strings.HasPrefix(selector, specSelector+"-")
+}
+
+// dkimSelectorInUse reports whether a retiring key of status is published
+// under selector.
+func dkimSelectorInUse(status *corev1alpha1.DKIMStatus, selector string) bool {
+	for _, key := range status.RetiringKeys {
+		if key.Selector == selector {
+			return true
+		}
+	}
+	return false
+}
+
+// ensureDKIMKey returns the key published under selector, generating it if
+// its Secret does not exist yet.
+func (r *DomainReconciler) ensureDKIMKey(ctx context.Context, domain *corev1alpha1.Domain, selector string, createdAt *v1.Time, now time.Time) (*corev1alpha1.DKIMKey, error) {
+	secret := &corev1.Secret{}
+	name := dkimSecretName(domain, selector)
+
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: domain.Namespace}, secret)
+	if apierrors.IsNotFound(err) {
+		secret, err = r.createDKIMSecret(ctx, domain, name)
+		createdAt = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !v1.IsControlledBy(secret, domain) {
+		return nil, fmt.Errorf("dkim secret %s is not controlled by the domain", name)
+	}
+	if err := r.syncResourceMetadata(ctx, domain, secret, componentDKIMKey); err != nil {
+		return nil, err
+	}
+
+	publicKey := string(secret.Data[dkim.PublicKeyKey])
+	if publicKey == "" {
+		return nil, fmt.Errorf("dkim secret %s has no %s", name, dkim.PublicKeyKey)
+	}
+
+	if createdAt == nil {
+		t := v1.NewTime(now)
+		createdAt = &t
+	}
+
+	return &corev1alpha1.DKIMKey{
+		Selector:   selector,
+		SecretName: name,
+		KeyType:    string(secret.Data[dkim.KeyTypeKey]),
+		PublicKey:  publicKey,
+		CreatedAt:  createdAt,
+	}, nil
+}
+
+func (r *DomainReconciler) createDKIMSecret(ctx context.Context, domain *corev1alpha1.Domain, name string) (*corev1.Secret, error) {
+	keyType := domain.Spec.DKIM.KeyTypeOrDefault()
+
+	kp, err := dkim.GenerateBits(dkim.KeyType(keyType), r.rsaKeyBits())
+	if err != nil {
+		return nil, err
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      name,
+			Namespace: domain.Namespace,
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			dkim.PrivateKeyKey: kp.PrivateKeyPEM,
+			dkim.PublicKeyKey:  []byte(kp.PublicKey),
+			dkim.KeyTypeKey:    []byte(keyType),
+		},
+	}
+	applyResourceMetadata(secret, domain, componentDKIMKey)
+
+	if err := ctrl.SetControllerReference(domain, secret, r.Scheme); err != nil {
+		return nil, err
+	}
+
+	if err := r.Create(ctx, secret); err != nil {
+		return nil, err
+	}
+
+	return secret, nil
+}
+
+// deleteDKIMSecret deletes the Secret of a replaced key, when the Domain
+// controls it.
+func (r *DomainReconciler) deleteDKIMSecret(ctx context.Context, domain *corev1alpha1.Domain, name string) error {
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: domain.Namespace}, secret)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if !v1.IsControlledBy(secret, domain) {
+		return nil
+	}
+
+	if err := r.Delete(ctx, secret); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+func dkimRotationDue(domain *corev1alpha1.Domain, status *corev1alpha1.DKIMStatus, now time.Time) bool {
+	period := domain.Spec.DKIM.RotationPeriod
+	if period == nil || period.Duration <= 0 || status.CreatedAt == nil {
+		return false
+	}
+
+	return !now.Before(status.CreatedAt.Add(period.Duration))
+}
+
+// dkimRequeueAfter returns the time until the next step of the key
+// rotation, or zero when there is nothing scheduled.
+func dkimRequeueAfter(domain *corev1alpha1.Domain, now time.Time) time.Duration {
+	status := domain.Status.DKIM
+	if status == nil {
+		return 0
+	}
+
+	if status.Pending != nil {
+		return pendingDKIMRecheckInterval
+	}
+
+	next := time.Duration(0)
+	schedule := func(at time.Time) {
+		if d := at.Sub(now); d > 0 && (next == 0 || d < next) {
+			next = d
+		}
+	}
+
+	if period := domain.Spec.DKIM.RotationPeriod; period != nil && period.Duration > 0 && status.CreatedAt != nil {
+		schedule(status.CreatedAt.Add(period.Duration))
+	}
+	for _, key := range status.RetiringKeys {
+		schedule(key.RetireAt.Time)
+	}
+
+	return next
+}
+
+// dkimRotationCondition computes the DKIMRotating condition from the
+// generated keys.
+func dkimRotationCondition(domain *corev1alpha1.Domain) v1.Condition {
+	cond := v1.Condition{
+		Type:               corev1alpha1.ConditionDKIMRotating,
+		Status:             v1.ConditionFalse,
+		ObservedGeneration: domain.Generation,
+		Reason:             corev1alpha1.ReasonDKIMNotRotating,
+		Message:            fmt.Sprintf("selector %s is active", domain.Status.DKIM.Selector),
+	}
+
+	status := domain.Status.DKIM
+	switch {
+	case status.Pending != nil:
+		cond.Status = v1.ConditionTrue
+		cond.Reason = corev1alpha1.ReasonDKIMRotationPending
+		cond.Message = fmt.Sprintf("waiting for the DKIM record of selector %s to be verified", status.Pending.Selector)
+	case len(status.RetiringKeys) > 0:
+		retiring := make([]string, 0, len(status.RetiringKeys))
+		for _, key := range status.RetiringKeys {
+			retiring = append(retiring, fmt.Sprintf("selector %s is retired at %s", key.Selector, key.RetireAt.UTC().Format(time.RFC3339)))
+		}
+		cond.Reason = corev1alpha1.ReasonDKIMRotated
+		cond.Message = fmt.Sprintf("selector %s is active, %s", status.Selector, strings.Join(retiring, ", "))
+	}
+
+	return cond
+}
+
+// dkimKeyCondition compares the generated private key with its Secret
+// public key and with the DKIM records found in DNS, and checks it against
+// the key policy. It returns false when there is nothing to compare: the
+// spec has the public key, or no record or Secret is found.
+func (r *DomainReconciler) dkimKeyCondition(ctx context.Context, domain *corev1alpha1.Domain) (v1.Condition, bool, error) {
+	status := domain.Status.DKIM
+	if domain.Spec.DKIM.PublicKey != "" || status == nil {
+		return v1.Condition{}, false, nil
+	}
+
+	type record struct {
+		keyType   dkim.KeyType
+		publicKey string
+	}
+	var records []record
+	for _, txt := range domain.Status.DNS.DKIM.Observed {
+		if keyType, publicKey, ok := dkim.ParseRecord(txt); ok {
+			records = append(records, record{keyType, publicKey})
+		}
+	}
+	if len(records) == 0 {
+		return v1.Condition{}, false, nil
+	}
+
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Name: status.SecretName, Namespace: domain.Namespace}, secret)
+	if apierrors.IsNotFound(err) {
+		return v1.Condition{}, false, nil
+	}
+	if err != nil {
+		return v1.Condition{}, false, err
+	}
+
+	cond := v1.Condition{
+		Type:               corev1alpha1.ConditionDKIMKeyMismatch,
+		Status:             v1.ConditionTrue,
+		ObservedGeneration: domain.Generation,
+		Reason:             corev1alpha1.ReasonDKIMKeyMismatch,
+	}
+
+	keyType, publicKey, err := dkim.PublicKeyOf(secret.Data[dkim.PrivateKeyKey])
+	if err != nil {
+		cond.Reason = corev1alpha1.ReasonDKIMKeyInvalid
+		cond.Message = fmt.Sprintf("the secret %s has no valid %s: %v", secret.Name, dkim.PrivateKeyKey, err)
+		return cond, true, nil
+	}
+	if publicKey != string(secret.Data[dkim.PublicKeyKey]) {
+		cond.Message = fmt.Sprintf("the %s of the secret %s does not match its %s", dkim.PublicKeyKey, secret.Name, dkim.PrivateKeyKey)
+		return cond, true, nil
+	}
+	for _, rec := range records {
+		switch {
+		case rec.publicKey == "":
+			cond.Message = fmt.Sprintf("the DKIM record of selector %s revokes the key", status.Selector)
+			return cond, true, nil
+		case rec.keyType != keyType || rec.publicKey != publicKey:
+			cond.Message = fmt.Sprintf("the DKIM record of selector %s publishes a key other than the one of the secret %s, "+
+				"messages signed with it fail verification", status.Selector, secret.Name)
+			return cond, true, nil
+		}
+	}
+
+	bits, err := dkim.KeyBits(keyType, publicKey)
+	if err != nil {
+		cond.Reason = corev1alpha1.ReasonDKIMKeyInvalid
+		cond.Message = fmt.Sprintf("the key of the secret %s is invalid: %v", secret.Name, err)
+		return cond, true, nil
+	}
+	if required := r.minRSAKeyBits(); keyType == dkim.KeyTypeRSA && bits < required {
+		cond.Reason = corev1alpha1.ReasonDKIMKeyPolicyViolation
+		cond.Message = fmt.Sprintf("the %d-bit RSA key of the secret %s is shorter than the %d bits required", bits, secret.Name, required)
+		return cond, true, nil
+	}
+
+	cond.Status = v1.ConditionFalse
+	cond.Reason = corev1alpha1.ReasonDKIMKeyMatches
+	cond.Message = fmt.Sprintf("the DKIM record of selector %s publishes the key of the secret %s", status.Selector, secret.Name)
+	return cond, true, nil
+}
+
+func (r *DomainReconciler) minRSAKeyBits() int {
+	if defaults := r.clusterDefaults(); defaults != nil && defaults.DKIM != nil && defaults.DKIM.MinRSAKeyBits > 0 {
+		return defaults.DKIM.MinRSAKeyBits
+	}
+	if r.DKIMMinRSAKeyBits > 0 {
+		return r.DKIMMinRSAKeyBits
+	}
+	return dkim.MinRSAKeyBits
+}
+
+// rsaKeyBits returns the size of the RSA keys to generate.
+func (r *DomainReconciler) rsaKeyBits() int {
+	if defaults := r.clusterDefaults(); defaults != nil && defaults.DKIM != nil && defaults.DKIM.RSAKeyBits > 0 {
+		return defaults.DKIM.RSAKeyBits
+	}
+	return dkim.RSAKeyBits
+}
+
+// withDKIMKey returns a copy of domain using key as its active DKIM key.
+func withDKIMKey(domain *corev1alpha1.Domain, key corev1alpha1.DKIMKey) *corev1alpha1.Domain {
+	d := domain.DeepCopy()
+	d.Status.DKIM = &corev1alpha1.DKIMStatus{DKIMKey: key}
+
+	return d
+}
+
+// rotatedDKIMSelector names the key replacing the active one of status
+// after the time its rotation was due, not the time it is done: when the
+// status recording the pending key fails to be written, the retried
+// rotation finds the Secret it already created.
+func rotatedDKIMSelector(domain *corev1alpha1.Domain, status *corev1alpha1.DKIMStatus) string {
+	due := status.CreatedAt.Add(domain.Spec.DKIM.RotationPeriod.Duration)
+	return fmt.Sprintf("%s-%d", domain.Spec.DKIM.SelectorOrDefault(), due.Unix())
+}
+
+func dkimSecretName(domain *corev1alpha1.Domain, selector string) string {
+	return fmt.Sprintf("%s-dkim-%s", domain.Name, selector)
+}
@@ -0,0 +1,189 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+	"github.com/kannon-email/k8nnon/internal/dmarc"
+)
+
+// reconcileDMARCReports polls the mailbox of spec.dmarcReports.imap when
+// due, then summarizes the reports of the window in the status. A failed
+// poll is reported in the status, and retried by the next reconcile.
+func (r *DomainReconciler) reconcileDMARCReports(ctx context.Context, domain *corev1alpha1.Domain) {
+	spec := domain.Spec.DMARCReports
+	if r.DMARCReports == nil || spec == nil {
+		domain.Status.DMARCReports = nil
+		forgetDMARCReportMetrics(domain.Spec.DomainName)
+		return
+	}
+
+	status := &corev1alpha1.DMARCReportsStatus{}
+	if previous := domain.Status.DMARCReports; previous != nil && spec.IMAP != nil {
+		status.PolledAt, status.Error = previous.PolledAt, previous.Error
+	}
+	domain.Status.DMARCReports = status
+
+	if spec.IMAP != nil && r.DMARCFetcher != nil && dmarcReportsRequeueAfter(domain, r.now()) == 0 {
+		status.PolledAt = &v1.Time{Time: r.now()}
+		status.Error = ""
+		if err := r.pollDMARCReports(ctx, domain); err != nil {
+			status.Error = err.Error()
+		}
+	}
+
+	window := spec.WindowOrDefault()
+	summary := r.DMARCReports.Summary(domain.Spec.DomainName, window)
+	recordDMARCReports(domain.Spec.DomainName, summary)
+	status.Window = v1.Duration{Duration: window}
+	status.Reports = summary.Reports
+	status.Reporters = summary.Reporters
+	status.Messages = summary.Messages
+	status.DKIMAlignedRate = formatRate(ratio(summary.DKIMAligned, summary.Messages))
+	status.SPFAlignedRate = formatRate(ratio(summary.SPFAligned, summary.Messages))
+	status.PassRate = formatRate(ratio(summary.Passed, summary.Messages))
+	status.TopFailingSources = nil
+	for _, source := range summary.TopFailingSources {
+		status.TopFailingSources = append(status.TopFailingSources, corev1alpha1.DMARCSource{
+			IP:         source.IP,
+			HeaderFrom: source.HeaderFrom,
+			DKIM:       source.DKIM,
+			SPF:        source.SPF,
+			Messages:   source.Messages,
+		})
+	}
+	if !summary.LastReportEnd.IsZero() {
+		status.LastReportEnd = &v1.Time{Time: summary.LastReportEnd}
+	}
+}
+
+// pollDMARCReports fetches the messages of the window from the mailbox and
+// keeps the reports of the domain and of its subdomains. The messages that
+// are not reports are skipped.
+func (r *DomainReconciler) pollDMARCReports(ctx context.Context, domain *corev1alpha1.Domain) error {
+	spec := domain.Spec.DMARCReports.IMAP
+
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: domain.Namespace, Name: spec.SecretName}, secret)
+	if apierrors.IsNotFound(err) {
+		return fmt.Errorf("the secret %s does not exist", spec.SecretName)
+	}
+	if err != nil {
+		return err
+	}
+
+	mailbox := dmarc.Mailbox{
+		Address:  spec.Address,
+		Name:     spec.MailboxOrDefault(),
+		Username: string(secret.Data[corev1alpha1.DMARCIMAPUsernameKey]),
+		Password: string(secret.Data[corev1alpha1.DMARCIMAPPasswordKey]),
+	}
+	since := r.now().Add(-domain.Spec.DMARCReports.WindowOrDefault())
+	messages, err := r.DMARCFetcher.Fetch(ctx, mailbox, since)
+	if err != nil {
+		return err
+	}
+
+	added := 0
+	for _, msg := range messages {
+		reports, err := dmarc.Parse(msg)
+		if err != nil {
+			continue
+		}
+		for _, report := range reports {
+			if dmarc.InDomain(report.Domain, domain.Spec.DomainName) && r.DMARCReports.Add(report) {
+				added++
+			}
+		}
+	}
+	log.FromContext(ctx).V(1).Info("dmarc reports polled", "domain", client.ObjectKeyFromObject(domain),
+		"messages", len(messages), "added", added)
+
+	return nil
+}
+
+func ratio(n, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
+
+// dmarcReportsRequeueAfter returns how long until the mailbox of the domain
+// is polled again, zero when it is due.
+func dmarcReportsRequeueAfter(domain *corev1alpha1.Domain, now time.Time) time.Duration {
+	if domain.Spec.DMARCReports == nil || domain.Spec.DMARCReports.IMAP == nil {
+		return 0
+	}
+	status := domain.Status.DMARCReports
+	if status == nil || status.PolledAt == nil || status.Error != "" {
+		return 0
+	}
+
+	next := status.PolledAt.Add(domain.Spec.DMARCReports.IMAP.PollIntervalOrDefault())
+	if !next.After(now) {
+		return 0
+	}
+	return next.Sub(now)
+}
+
+// DMARCReportAuthorizer authorizes the reports posted for a Domain with
+// the token of the Secret of its spec.dmarcReports.http.
+func DMARCReportAuthorizer(c client.Reader) dmarc.Authorizer {
+	return func(ctx context.Context, namespace, name, token string) (string, error) {
+		domain := &corev1alpha1.Domain{}
+		err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, domain)
+		if apierrors.IsNotFound(err) {
+			return "", dmarc.ErrUnauthorized
+		}
+		if err != nil {
+			return "", err
+		}
+
+		spec := domain.Spec.DMARCReports
+		if spec == nil || spec.HTTP == nil {
+			return "", dmarc.ErrUnauthorized
+		}
+
+		secret := &corev1.Secret{}
+		err = c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: spec.HTTP.SecretName}, secret)
+		if apierrors.IsNotFound(err) {
+			return "", dmarc.ErrUnauthorized
+		}
+		if err != nil {
+			return "", err
+		}
+
+		expected := secret.Data[corev1alpha1.DMARCHTTPTokenKey]
+		if len(expected) == 0 || subtle.ConstantTimeCompare(expected, []byte(token)) != 1 {
+			return "", dmarc.ErrUnauthorized
+		}
+		return domain.Spec.DomainName, nil
+	}
+}
@@ -0,0 +1,294 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+	"github.com/kannon-email/k8nnon/internal/dns/provider"
+)
+
+// newDNSProvider returns the provider client of a Domain, it defaults to
+// provider.New.
+func (r *DomainReconciler) newDNSProvider(name, zone string, credentials map[string][]byte) (provider.Provider, error) {
+	if r.dnsProvider != nil {
+		return r.dnsProvider(name, zone, credentials)
+	}
+	return provider.New(name, zone, credentials, r.HTTPClient)
+}
+
+// reconcileProviderRecords publishes the records of the Domain with its DNS
+// provider. Existing SPF and DMARC records are amended rather than
+// replaced, so that the other senders and policies of the domain survive.
+func (r *DomainReconciler) reconcileProviderRecords(ctx context.Context, domain *corev1alpha1.Domain) error {
+	p, err := r.domainDNSProvider(ctx, domain)
+	if err != nil {
+		return err
+	}
+
+	records := provisionedRecords(domain)
+	if dmarc := domain.Status.DNS.DMARC.Expected; dmarc != nil {
+		records = append(records, dmarc)
+	}
+
+	for _, record := range records {
+		current, err := p.Get(ctx, record.Name, record.Type)
+		if err != nil {
+			return err
+		}
+		if err := r.saveOriginalSPF(ctx, domain, record, current); err != nil {
+			return err
+		}
+
+		desired := desiredRecordValues(record, current)
+		if sameValues(current, desired) {
+			continue
+		}
+
+		if err := p.Set(ctx, record.Name, record.Type, desired, dnsEndpointTTL); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// cleanupProviderRecords withdraws the records published with the DNS
+// provider of a deleted Domain. The SPF include is removed from the SPF
+// record, while the DMARC record is kept as it may predate the Domain.
+func (r *DomainReconciler) cleanupProviderRecords(ctx context.Context, domain *corev1alpha1.Domain) error {
+	p, err := r.domainDNSProvider(ctx, domain)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range provisionedRecords(domain) {
+		current, err := p.Get(ctx, record.Name, record.Type)
+		if err != nil {
+			return err
+		}
+
+		remaining := remainingRecordValues(record, current, originalSPF(domain, record.Name))
+		if sameValues(current, remaining) {
+			continue
+		}
+
+		if err := p.Set(ctx, record.Name, record.Type, remaining, dnsEndpointTTL); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// saveOriginalSPF persists the SPF record of the name before the operator
+// first amends it, so that it is restored as it was once the Domain is
+// deleted. It is written right away: the record is amended before the
+// status of the reconcile is.
+func (r *DomainReconciler) saveOriginalSPF(ctx context.Context, domain *corev1alpha1.Domain, record *corev1alpha1.DNSRecord, current []string) error {
+	if !isSPFRecord(record.Value) || originalSPF(domain, record.Name) != nil {
+		return nil
+	}
+
+	original := corev1alpha1.OriginalSPFRecord{Name: record.Name}
+	for _, value := range current {
+		if isSPFRecord(value) {
+			original.Value = value
+			break
+		}
+	}
+
+	saved := domain.DeepCopy()
+	saved.Status.OriginalSPF = append(saved.Status.OriginalSPF, original)
+	if err := r.Status().Patch(ctx, saved, client.MergeFrom(domain)); err != nil {
+		return err
+	}
+	domain.Status.OriginalSPF = saved.Status.OriginalSPF
+
+	return nil
+}
+
+// originalSPF returns the saved SPF record of the name, nil when there is
+// none.
+func originalSPF(domain *corev1alpha1.Domain, name string) *corev1alpha1.OriginalSPFRecord {
+	for i := range domain.Status.OriginalSPF {
+		if domain.Status.OriginalSPF[i].Name == name {
+			return &domain.Status.OriginalSPF[i]
+		}
+	}
+	return nil
+}
+
+func isSPFRecord(value string) bool {
+	return strings.HasPrefix(value, "v=spf1")
+}
+
+func (r *DomainReconciler) domainDNSProvider(ctx context.Context, domain *corev1alpha1.Domain) (provider.Provider, error) {
+	spec := domain.Spec.DNS.Provider
+
+	ref := spec.SecretRef
+	if ref == nil {
+		ref = &corev1alpha1.CredentialsSecretReference{Name: spec.CredentialsSecretName}
+	}
+	credentials, err := readCredentials(ctx, r.Client, domain.Namespace, ref)
+	if err != nil {
+		return nil, fmt.Errorf("dns provider credentials: %w", err)
+	}
+
+	return r.newDNSProvider(spec.Name, spec.ZoneOrDefault(domain.Spec.DomainName), credentials)
+}
+
+// remainingRecordValues returns the values of the record set once record
+// is withdrawn from it. The SPF record is restored to original, or has the
+// mechanisms of record removed when it was not saved.
+func remainingRecordValues(record *corev1alpha1.DNSRecord, current []string, original *corev1alpha1.OriginalSPFRecord) []string {
+	remaining := []string{}
+	for _, value := range current {
+		switch {
+		case isSPFRecord(record.Value) && isSPFRecord(value) && original != nil:
+			if original.Value == "" {
+				continue
+			}
+			value = original.Value
+		case value == record.Value:
+			continue
+		case isSPFRecord(record.Value) && isSPFRecord(value):
+			value = withoutSPFTerms(value, record.Value)
+			if value == withoutSPFTerms(record.Value, record.Value) {
+				// only the all mechanism of record is left, the record was
+				// created from it
+				continue
+			}
+		}
+		remaining = append(remaining, value)
+	}
+
+	return remaining
+}
+
+// withoutSPFTerms removes from the SPF record current the mechanisms
+// mergeSPF added from expected.
+func withoutSPFTerms(current, expected string) string {
+	added := map[string]bool{}
+	for _, term := range strings.Fields(expected)[1:] {
+		if !isSPFTerminal(term) {
+			added[term] = true
+		}
+	}
+
+	terms := []string{}
+	for _, term := range strings.Fields(current) {
+		if !added[term] {
+			terms = append(terms, term)
+		}
+	}
+
+	return strings.Join(terms, " ")
+}
+
+// desiredRecordValues returns the values of the record set once record is
+// published in it.
+func desiredRecordValues(record *corev1alpha1.DNSRecord, current []string) []string {
+	if record.Type != "TXT" {
+		return []string{record.Value}
+	}
+
+	var prefix string
+	switch {
+	case strings.HasPrefix(record.Value, "v=spf1"):
+		prefix = "v=spf1"
+	case strings.HasPrefix(record.Value, "v=DMARC1"):
+		prefix = "v=DMARC1"
+	default:
+		return []string{record.Value}
+	}
+
+	desired := []string{}
+	found := false
+	for _, value := range current {
+		if !strings.HasPrefix(value, prefix) || found {
+			desired = append(desired, value)
+			continue
+		}
+
+		found = true
+		if prefix == "v=spf1" {
+			value = mergeSPF(value, record.Value)
+		}
+		// any DMARC policy is accepted, an existing one is kept
+		desired = append(desired, value)
+	}
+	if !found {
+		desired = append(desired, record.Value)
+	}
+
+	return desired
+}
+
+// mergeSPF adds to the SPF record current the mechanisms of expected it
+// lacks, before its all mechanism or redirect modifier.
+func mergeSPF(current, expected string) string {
+	terms := strings.Fields(current)
+	present := map[string]bool{}
+	for _, term := range terms {
+		present[term] = true
+	}
+
+	missing := []string{}
+	for _, term := range strings.Fields(expected)[1:] {
+		if !present[term] && !isSPFTerminal(term) {
+			missing = append(missing, term)
+		}
+	}
+	if len(missing) == 0 {
+		return current
+	}
+
+	at := len(terms)
+	for i, term := range terms {
+		if isSPFTerminal(term) {
+			at = i
+			break
+		}
+	}
+
+	merged := append([]string{}, terms[:at]...)
+	merged = append(merged, missing...)
+	merged = append(merged, terms[at:]...)
+
+	return strings.Join(merged, " ")
+}
+
+func isSPFTerminal(term string) bool {
+	return strings.TrimLeft(term, "+-~?") == "all" || strings.HasPrefix(term, "redirect=")
+}
+
+func sameValues(a, b []string) bool {
+	a = append([]string{}, a...)
+	b = append([]string{}, b...)
+	sort.Strings(a)
+	sort.Strings(b)
+
+	return reflect.DeepEqual(a, b)
+}
@@ -18,36 +18,193 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math/rand"
+	"net/http"
 	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	netwrkingv1 "k8s.io/api/networking/v1"
-	"k8s.io/apimachinery/pkg/api/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	gatewayv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
 
 	"github.com/go-logr/logr"
 	"github.com/kannon-email/k8nnon/api/v1alpha1"
 	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+	"github.com/kannon-email/k8nnon/internal/delivery"
+	"github.com/kannon-email/k8nnon/internal/dmarc"
 	"github.com/kannon-email/k8nnon/internal/dns/checker"
+	"github.com/kannon-email/k8nnon/internal/dns/provider"
+	"github.com/kannon-email/k8nnon/internal/kannon"
+	"github.com/kannon-email/k8nnon/internal/logging"
+	"github.com/kannon-email/k8nnon/internal/mtasts"
+	"github.com/kannon-email/k8nnon/internal/notify"
+	"github.com/kannon-email/k8nnon/internal/reputation"
+	"github.com/kannon-email/k8nnon/internal/resilience"
+	"github.com/kannon-email/k8nnon/internal/shard"
+	"github.com/kannon-email/k8nnon/internal/tracing"
+	"golang.org/x/sync/errgroup"
 )
 
 // DomainReconciler reconciles a Domain object
 type DomainReconciler struct {
 	client.Client
+	clock
 	Scheme *runtime.Scheme
 
 	DNSChecker checker.DNSChecker
+
+	// DNSCheckTimeout bounds each DNS check, including all of its lookups.
+	// Zero leaves the checks bounded by the lookup timeout only.
+	DNSCheckTimeout time.Duration
+
+	// MaxConcurrentReconciles is the maximum number of Domains reconciled in parallel.
+	MaxConcurrentReconciles int
+
+	// GatewayAPI enables the gatewayAPI routing. It requires the Gateway
+	// API CRDs to be installed.
+	GatewayAPI bool
+
+	// ExternalDNS enables the provisioning of the DNS records through
+	// external-dns DNSEndpoints. It requires the external-dns CRD.
+	ExternalDNS bool
+
+	// LookupTXT looks up the TXT records the names of the DNSEndpoint
+	// already have, as external-dns would replace the ones of others. Nil
+	// publishes the records without looking.
+	LookupTXT func(ctx context.Context, name string) ([]string, error)
+
+	// PrometheusRules enables the PrometheusRules alerting on the DNS
+	// records of Domains with spec.monitoring.alerts. It requires the
+	// Prometheus Operator CRDs.
+	PrometheusRules bool
+
+	// MTASTSService is the host of the operator Service serving the
+	// MTA-STS policies, the target of the ExternalName Services of the
+	// Domains with spec.mtaSTS.enabled. Empty disables the MTA-STS hosting.
+	MTASTSService string
+
+	// MTASTSPort is the port of the MTA-STS policy server.
+	MTASTSPort int32
+
+	// DKIMMinRSAKeyBits is the smallest generated RSA key the DKIM key
+	// check accepts. Zero defaults to the 1024 bits of RFC 8301.
+	DKIMMinRSAKeyBits int
+
+	// HTTPClient calls the APIs of the DNS providers. Nil uses a client
+	// with a 10 seconds timeout.
+	HTTPClient *http.Client
+
+	// IngressControllerNamespace is the namespace the stats NetworkPolicies
+	// allow the traffic from, unless the Domain sets one. Empty defaults to
+	// ingress-nginx.
+	IngressControllerNamespace string
+
+	// Kannon registers the Domains with the Kannon admin API. Nil disables
+	// the registration.
+	Kannon kannon.Client
+
+	// RequireOwnership holds the stats route and the Kannon registration of
+	// the Domains until a challenge TXT record proves their control.
+	RequireOwnership bool
+
+	// Delivery counts the delivery webhooks of Kannon received by the
+	// replica, flushed into the status of the Domains by a DeliveryFlusher.
+	// Nil disables the delivery statistics.
+	Delivery *delivery.Aggregator
+
+	// Reputation polls the reputation services of the Domains with
+	// spec.reputation. Nil disables the polls.
+	Reputation reputation.Poller
+
+	// DMARCReports keeps the DMARC aggregate reports of the Domains with
+	// spec.dmarcReports, polled from their mailboxes with DMARCFetcher or
+	// received over HTTP. Nil disables the reports.
+	DMARCReports *dmarc.Store
+	DMARCFetcher dmarc.Fetcher
+
+	// Notifier notifies the verification changes of the Domains and the
+	// deletions of their stats routes. Nil disables the notifications.
+	Notifier notify.Notifier
+
+	// Recorder records Events on the Domains. Nil disables the Events.
+	Recorder record.EventRecorder
+
+	// DryRun only checks the Domains, as if they all had spec.reportOnly:
+	// no resource is created, changed or deleted.
+	DryRun bool
+
+	// Shard is the share of the Domains the replica reconciles, split by a
+	// hash of their namespace and name. The zero value reconciles them all.
+	Shard shard.Shard
+
+	// FreshPeriod is how long after their creation or the last change of
+	// their spec the Domains are rechecked every few seconds, and their
+	// failed reconciles retried sooner. Zero disables it.
+	FreshPeriod time.Duration
+
+	// ClusterConfigName is the ClusterDomainConfig whose defaults apply to
+	// the Domains. Empty disables the cluster defaults.
+	ClusterConfigName string
+
+	// RecheckBatchWindow enables the recheck scheduler: the Domains are
+	// rechecked by a background scheduler rather than requeued one by one,
+	// together with the Domains of their base domain due within the
+	// window. Zero requeues every Domain after its own interval.
+	RecheckBatchWindow time.Duration
+
+	// clusterConfig is the spec of the ClusterDomainConfig read by the
+	// last reconcile, nil when there is none.
+	clusterConfig atomic.Pointer[corev1alpha1.ClusterDomainConfigSpec]
+
+	// fresh holds since when the fresh Domains are, by NamespacedName.
+	fresh sync.Map
+
+	// metricDomains holds the domain names the series are exported for, by
+	// NamespacedName.
+	metricDomains sync.Map
+
+	// scheduler schedules the next checks of the Domains, nil without
+	// RecheckBatchWindow.
+	scheduler *recheckScheduler
+
+	// dnsProvider creates the DNS provider clients, it defaults to
+	// provider.New.
+	dnsProvider func(name, zone string, credentials map[string][]byte) (provider.Provider, error)
 }
 
 //+kubebuilder:rbac:groups=core.k8s.kannon.email,resources=domains,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=core.k8s.kannon.email,resources=domains/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=core.k8s.kannon.email,resources=domains/finalizers,verbs=update
+//+kubebuilder:rbac:groups=core.k8s.kannon.email,resources=senderpools,verbs=get;list;watch
+//+kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses;networkpolicies,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=httproutes,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=externaldns.k8s.io,resources=dnsendpoints,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=monitoring.coreos.com,resources=prometheusrules,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=configmaps;services,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -59,43 +216,526 @@ type DomainReconciler struct {
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.14.1/pkg/reconcile
 func (r *DomainReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	if !r.Shard.Owns(req.Namespace, req.Name) {
+		// the series are exported by the replica of the shard owning it
+		r.forgetMetricDomain(req.NamespacedName)
+		return ctrl.Result{}, nil
+	}
+
 	l := log.FromContext(ctx)
 	l.Info("reconciling domain", "domain", req.NamespacedName)
 
 	domain := &corev1alpha1.Domain{}
 	if err := r.Get(ctx, req.NamespacedName, domain); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.fresh.Delete(req.NamespacedName)
+			r.forgetRecheck(req.NamespacedName)
+			r.forgetMetricDomain(req.NamespacedName)
+		}
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
+	ctx, l = withDomainLogLevel(ctx, l, domain)
+	// the lookups and the API calls of the Domain share a retry budget
+	ctx = resilience.WithBudgetKey(ctx, "domain/"+req.NamespacedName.String())
+	if err := r.loadClusterDefaults(ctx); err != nil {
+		return ctrl.Result{}, err
+	}
 
-	dnsStatus, err := r.checkDomainDNS(ctx, l, domain)
-	if err != nil {
-		return ctrl.Result{RequeueAfter: 1 * time.Minute}, err
+	if !domain.DeletionTimestamp.IsZero() {
+		r.fresh.Delete(req.NamespacedName)
+		r.forgetRecheck(req.NamespacedName)
+		return ctrl.Result{}, r.finalizeDomain(ctx, domain, l)
+	}
+	r.trackMetricDomain(domain)
+	reportOnly := r.reportOnly(domain)
+	// nothing is cleaned up for a Domain that is only checked
+	if !reportOnly && r.needsCleanup(domain) && controllerutil.AddFinalizer(domain, cleanupFinalizer) {
+		if err := r.Update(ctx, domain); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	// the status is patched with the changes made from here on
+	base := domain.DeepCopy()
+
+	if domain.Spec.Suspend {
+		l.Info("domain is suspended", "domain", req.NamespacedName)
+		r.forgetRecheck(req.NamespacedName)
+		return ctrl.Result{}, r.suspend(ctx, domain, base)
+	}
+	meta.RemoveStatusCondition(&domain.Status.Conditions, corev1alpha1.ConditionSuspended)
+	fresh := r.trackFresh(domain)
+
+	if reportOnly {
+		meta.SetStatusCondition(&domain.Status.Conditions, r.reportOnlyCondition(domain))
+	} else {
+		meta.RemoveStatusCondition(&domain.Status.Conditions, corev1alpha1.ConditionReportOnly)
+		if err := r.reconcileDKIMKey(ctx, domain); err != nil {
+			l.Error(err, "failed to reconcile dkim key", "domain", req.NamespacedName)
+			return ctrl.Result{}, err
+		}
 	}
 
-	domain.Status = corev1alpha1.DomainStatus{
-		DNS: dnsStatus,
+	recheck, recheckRequested := recheckNonce(domain)
+	checkCtx := ctx
+	if recheckRequested {
+		l.Info("recheck requested", "nonce", recheck)
+		checkCtx = checker.WithoutCache(ctx)
+		// a requested recheck restarts the backoff
+		domain.Status.FailedChecks = 0
+	}
+
+	dnsStatus := r.checkDomainDNS(checkCtx, l, domain)
+	dnsChanged := !sameDNSVerdicts(domain.Status.DNS, dnsStatus)
+
+	domain.Status.DNS = dnsStatus
+	domain.Status.LastCheckTime = &v1.Time{Time: r.now()}
+	domain.Status.DNS.LastCheckTime = domain.Status.LastCheckTime
+	recordDNSVerified(domain)
+	if recheckRequested {
+		domain.Status.LastRecheck = recheck
+	}
+	if dnsVerified(dnsStatus) {
+		domain.Status.FailedChecks = 0
+	} else {
+		domain.Status.FailedChecks++
+	}
+	for _, cond := range checkConditions(domain) {
+		r.recordCheckTransition(domain, cond)
+		meta.SetStatusCondition(&domain.Status.Conditions, cond)
 	}
+	wasReady := meta.IsStatusConditionTrue(domain.Status.Conditions, corev1alpha1.ConditionReady)
+	ready := readyCondition(domain)
+	// the transition is notified once its time is persisted
+	ready.LastTransitionTime = v1.NewTime(r.now())
+	meta.SetStatusCondition(&domain.Status.Conditions, ready)
+	ready = *meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionReady)
 
-	if err := r.reconcileIngress(ctx, domain, l); err != nil {
-		l.Error(err, "failed to reconcile ingress", "domain", domain)
+	if len(domain.Spec.AdditionalDomains) > 0 {
+		meta.SetStatusCondition(&domain.Status.Conditions, additionalDomainsCondition(domain))
+	} else {
+		meta.RemoveStatusCondition(&domain.Status.Conditions, corev1alpha1.ConditionAdditionalDomainsVerified)
+	}
+
+	keyCond, keyChecked, err := r.dkimKeyCondition(ctx, domain)
+	if err != nil {
+		l.Error(err, "failed to check dkim key", "domain", req.NamespacedName)
 		return ctrl.Result{}, err
 	}
+	if keyChecked {
+		r.recordDKIMKeyMismatch(domain, keyCond)
+		meta.SetStatusCondition(&domain.Status.Conditions, keyCond)
+	} else {
+		meta.RemoveStatusCondition(&domain.Status.Conditions, corev1alpha1.ConditionDKIMKeyMismatch)
+	}
+
+	if r.RequireOwnership {
+		if err := r.verifyOwnership(checkCtx, domain); err != nil {
+			l.Error(err, "failed to verify domain ownership", "domain", req.NamespacedName)
+			return ctrl.Result{}, err
+		}
+		meta.SetStatusCondition(&domain.Status.Conditions, ownershipCondition(domain))
+	} else {
+		domain.Status.Ownership = nil
+		meta.RemoveStatusCondition(&domain.Status.Conditions, corev1alpha1.ConditionOwnershipVerified)
+	}
+
+	// the DNS status is persisted even when the ingress can't be reconciled,
+	// so that the fresh check results are not lost until the next success.
+	var ingressErr error
+	if reportOnly {
+		cond, err := r.reportStatsRoute(ctx, domain)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		meta.SetStatusCondition(&domain.Status.Conditions, cond)
+	} else {
+		ingressErr = tracing.Run(ctx, "ApplyStatsRoute", func(ctx context.Context) error {
+			if err := r.reconcileIngress(ctx, domain, l); err != nil {
+				return err
+			}
+			return r.reconcileHTTPRoute(ctx, domain, l)
+		})
+		recordStatsRouteReconcile(ingressErr)
+		if isPermanentRoutingError(ingressErr) {
+			// retrying won't help until someone resolves the conflict
+			l.Info("not managing stats ingress", "reason", ingressErr.Error())
+		} else if ingressErr != nil {
+			l.Error(ingressErr, "failed to reconcile ingress", "domain", req.NamespacedName)
+		}
+		meta.SetStatusCondition(&domain.Status.Conditions, ingressCondition(domain, ingressErr))
+	}
+
+	if wantsStatsRoute(domain, corev1alpha1.RoutingIngress) {
+		cond, err := r.certificateCondition(ctx, domain)
+		if err != nil {
+			l.Error(err, "failed to inspect stats certificate", "domain", req.NamespacedName)
+			return ctrl.Result{}, err
+		}
+		meta.SetStatusCondition(&domain.Status.Conditions, cond)
+	} else {
+		domain.Status.Certificate = nil
+		meta.RemoveStatusCondition(&domain.Status.Conditions, corev1alpha1.ConditionCertificateReady)
+	}
+
+	var provisionErr error
+	if !reportOnly {
+		provisionErr = r.reconcileDNSEndpoint(ctx, domain)
+		if provisionErr == nil && domain.Spec.AutoProvisionDNS() && domain.Spec.DNS.Provider != nil {
+			provisionErr = tracing.Run(ctx, "ProvisionDNSRecords", func(ctx context.Context) error {
+				return r.reconcileProviderRecords(ctx, domain)
+			})
+		}
+		if provisionErr != nil && !isPermanentProvisionError(provisionErr) {
+			l.Error(provisionErr, "failed to provision dns records", "domain", req.NamespacedName)
+		}
+	}
+	if domain.Spec.AutoProvisionDNS() && reportOnly {
+		meta.SetStatusCondition(&domain.Status.Conditions, notManagedCondition(domain, corev1alpha1.ConditionDNSProvisioned,
+			"the DNS records would be provisioned"))
+	} else if domain.Spec.AutoProvisionDNS() {
+		meta.SetStatusCondition(&domain.Status.Conditions, dnsProvisionedCondition(domain, provisionErr))
+	} else {
+		meta.RemoveStatusCondition(&domain.Status.Conditions, corev1alpha1.ConditionDNSProvisioned)
+	}
+
+	var alertsErr error
+	if !reportOnly {
+		alertsErr = r.reconcilePrometheusRule(ctx, domain)
+		if alertsErr != nil && !errors.Is(alertsErr, errPrometheusRulesDisabled) {
+			l.Error(alertsErr, "failed to reconcile prometheusrule", "domain", req.NamespacedName)
+		}
+	}
+	if domain.Spec.AlertsEnabled() && reportOnly {
+		meta.SetStatusCondition(&domain.Status.Conditions, notManagedCondition(domain, corev1alpha1.ConditionAlertsConfigured,
+			fmt.Sprintf("the PrometheusRule %s would be applied", prometheusRuleName(domain))))
+	} else if domain.Spec.AlertsEnabled() {
+		meta.SetStatusCondition(&domain.Status.Conditions, alertsCondition(domain, alertsErr))
+	} else {
+		meta.RemoveStatusCondition(&domain.Status.Conditions, corev1alpha1.ConditionAlertsConfigured)
+	}
 
-	if err := r.Status().Update(ctx, domain); err != nil {
+	var mtaSTSErr error
+	if !reportOnly {
+		mtaSTSErr = r.reconcileMTASTS(ctx, domain)
+		if mtaSTSErr != nil && !errors.Is(mtaSTSErr, errMTASTSDisabled) {
+			l.Error(mtaSTSErr, "failed to host mta-sts policy", "domain", req.NamespacedName)
+		}
+	}
+	if domain.Spec.MTASTSEnabled() && reportOnly {
+		meta.SetStatusCondition(&domain.Status.Conditions, notManagedCondition(domain, corev1alpha1.ConditionMTASTSConfigured,
+			"the MTA-STS policy would be hosted"))
+	} else if domain.Spec.MTASTSEnabled() {
+		meta.SetStatusCondition(&domain.Status.Conditions, mtaSTSCondition(domain, mtaSTSErr))
+	} else {
+		meta.RemoveStatusCondition(&domain.Status.Conditions, corev1alpha1.ConditionMTASTSConfigured)
+	}
+
+	var policyErr error
+	if !reportOnly {
+		policyErr = r.reconcileNetworkPolicy(ctx, domain)
+		if policyErr != nil {
+			l.Error(policyErr, "failed to reconcile stats networkpolicy", "domain", req.NamespacedName)
+		}
+	}
+	if domain.Spec.StatsNetworkPolicyEnabled() && reportOnly {
+		meta.SetStatusCondition(&domain.Status.Conditions, notManagedCondition(domain, corev1alpha1.ConditionNetworkPolicyConfigured,
+			fmt.Sprintf("the NetworkPolicy %s would be applied", statsIngressName(domain))))
+	} else if domain.Spec.StatsNetworkPolicyEnabled() {
+		meta.SetStatusCondition(&domain.Status.Conditions, networkPolicyCondition(r.withClusterDefaults(domain), policyErr))
+	} else {
+		meta.RemoveStatusCondition(&domain.Status.Conditions, corev1alpha1.ConditionNetworkPolicyConfigured)
+	}
+
+	if domain.Spec.SenderPoolRef != nil {
+		cond, err := r.senderPoolCondition(ctx, domain)
+		if err != nil {
+			l.Error(err, "failed to get sender pool", "domain", req.NamespacedName)
+			return ctrl.Result{}, err
+		}
+		meta.SetStatusCondition(&domain.Status.Conditions, cond)
+	} else {
+		meta.RemoveStatusCondition(&domain.Status.Conditions, corev1alpha1.ConditionSenderPoolReady)
+	}
+
+	if r.Delivery != nil {
+		// the counters are added to the status by the flushers
+		applyDelivery(domain, nil, r.Delivery.Window(), r.now())
+	} else {
+		domain.Status.Delivery = nil
+		meta.RemoveStatusCondition(&domain.Status.Conditions, corev1alpha1.ConditionHighBounceRate)
+	}
+
+	if err := tracing.Run(ctx, "PollReputation", func(ctx context.Context) error {
+		return r.reconcileReputation(ctx, domain)
+	}); err != nil {
+		l.Error(err, "failed to poll the reputation services", "domain", req.NamespacedName)
 		return ctrl.Result{}, err
 	}
+	recordReputation(domain)
+
+	r.reconcileDMARCReports(ctx, domain)
+
+	var kannonErr error
+	if r.Kannon != nil && reportOnly {
+		meta.SetStatusCondition(&domain.Status.Conditions, notManagedCondition(domain, corev1alpha1.ConditionKannonRegistered,
+			"the domain would be registered with Kannon"))
+	} else if r.Kannon != nil && ownershipPending(domain) {
+		meta.SetStatusCondition(&domain.Status.Conditions, kannonOwnershipPendingCondition(domain))
+	} else if r.Kannon != nil {
+		kannonErr = tracing.Run(ctx, "RegisterKannonDomain", func(ctx context.Context) error {
+			return r.reconcileKannonRegistration(ctx, domain, l)
+		})
+		if kannonErr != nil {
+			l.Error(kannonErr, "failed to register domain with kannon", "domain", req.NamespacedName)
+		}
+		meta.SetStatusCondition(&domain.Status.Conditions, kannonCondition(domain, kannonErr))
+	}
+
+	var exportErr error
+	if !reportOnly {
+		exportErr = r.reconcileExport(ctx, domain)
+		if exportErr != nil {
+			l.Error(exportErr, "failed to export runtime config", "domain", req.NamespacedName)
+		}
+	}
+	if domain.Spec.Export != nil && reportOnly {
+		meta.SetStatusCondition(&domain.Status.Conditions, notManagedCondition(domain, corev1alpha1.ConditionConfigExported,
+			fmt.Sprintf("the runtime configuration would be exported to the secret %s", domain.Spec.Export.SecretName)))
+	} else if domain.Spec.Export != nil {
+		meta.SetStatusCondition(&domain.Status.Conditions, exportCondition(domain, exportErr))
+	} else {
+		meta.RemoveStatusCondition(&domain.Status.Conditions, corev1alpha1.ConditionConfigExported)
+	}
+
+	domain.Status.ObservedGeneration = domain.Generation
+	if r.statusChanged(domain, base) {
+		if err := r.patchStatus(ctx, domain, base); err != nil {
+			return ctrl.Result{}, err
+		}
+		r.notifyReadyTransition(ctx, domain, wasReady, ready)
+	} else {
+		statusWritesSkipped.Inc()
+		l.V(1).Info("status unchanged, not written", "domain", req.NamespacedName)
+	}
+
+	if recheckRequested && recheck == corev1alpha1.RecheckNow {
+		if err := r.clearRecheck(ctx, domain); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	if ingressErr != nil && !isPermanentRoutingError(ingressErr) {
+		return ctrl.Result{}, ingressErr
+	}
+	if provisionErr != nil && !isPermanentProvisionError(provisionErr) {
+		return ctrl.Result{}, provisionErr
+	}
+	if alertsErr != nil && !errors.Is(alertsErr, errPrometheusRulesDisabled) {
+		return ctrl.Result{}, alertsErr
+	}
+	if mtaSTSErr != nil && !errors.Is(mtaSTSErr, errMTASTSDisabled) {
+		return ctrl.Result{}, mtaSTSErr
+	}
+	if kannonErr != nil {
+		return ctrl.Result{}, kannonErr
+	}
 
+	interval := computeReconcileInterval(r.withClusterDefaults(domain))
+	if dnsChanged && interval > transitionRecheckInterval {
+		// recheck soon to confirm the new state before settling on the
+		// long interval
+		interval = transitionRecheckInterval
+	}
+	if fresh && interval > freshRecheckInterval {
+		interval = freshRecheckInterval
+	}
+	if d := dkimRequeueAfter(domain, r.now()); d > 0 && d < interval {
+		interval = d
+	}
+	if d := reputationRequeueAfter(domain, r.now()); d > 0 && d < interval {
+		interval = d
+	}
+	if d := dmarcReportsRequeueAfter(domain, r.now()); d > 0 && d < interval {
+		interval = d
+	}
+
+	l.V(1).Info("domain reconciled", "domain", req.NamespacedName, "requeueAfter", interval,
+		"dnsChanged", dnsChanged, "fresh", fresh, "failedChecks", domain.Status.FailedChecks)
+
+	if r.scheduler != nil {
+		r.scheduler.schedule(domain, interval)
+		return ctrl.Result{}, nil
+	}
 	return ctrl.Result{
-		RequeueAfter: computeReconcileInterval(domain),
+		RequeueAfter: interval,
 	}, nil
 }
 
+// forgetRecheck drops the next check scheduled for the Domain, if any.
+func (r *DomainReconciler) forgetRecheck(key types.NamespacedName) {
+	if r.scheduler != nil {
+		r.scheduler.forget(key)
+	}
+}
+
+// withDomainLogLevel applies the log level annotation of the Domain to the
+// logger of its reconcile, which is also set in the context for the checks.
+func withDomainLogLevel(ctx context.Context, l logr.Logger, domain *corev1alpha1.Domain) (context.Context, logr.Logger) {
+	value, ok := domain.Annotations[corev1alpha1.AnnotationLogLevel]
+	if !ok {
+		return ctx, l
+	}
+
+	verbosity, err := logging.ParseLevel(value)
+	if err != nil {
+		l.Error(err, "ignoring the log level annotation", "domain", client.ObjectKeyFromObject(domain))
+		return ctx, l
+	}
+
+	l = logging.WithVerbosity(l, verbosity)
+	return log.IntoContext(ctx, l), l
+}
+
+// suspend reports the Domain as suspended. Nothing is requeued: clearing
+// spec.suspend changes the generation, which triggers a reconcile.
+func (r *DomainReconciler) suspend(ctx context.Context, domain, base *corev1alpha1.Domain) error {
+	if meta.IsStatusConditionTrue(domain.Status.Conditions, corev1alpha1.ConditionSuspended) && domain.Status.ObservedGeneration == domain.Generation {
+		return nil
+	}
+
+	domain.Status.ObservedGeneration = domain.Generation
+	meta.SetStatusCondition(&domain.Status.Conditions, v1.Condition{
+		Type:               corev1alpha1.ConditionSuspended,
+		Status:             v1.ConditionTrue,
+		Reason:             corev1alpha1.ReasonSuspended,
+		Message:            "the reconciliation is suspended by spec.suspend",
+		ObservedGeneration: domain.Generation,
+	})
+
+	return r.patchStatus(ctx, domain, base)
+}
+
+// recheckNonce returns the value of the recheck annotation and whether it
+// has not been acted upon yet. RecheckNow is pending until it is removed.
+func recheckNonce(domain *corev1alpha1.Domain) (string, bool) {
+	nonce, ok := domain.Annotations[corev1alpha1.AnnotationRecheck]
+	if nonce == corev1alpha1.RecheckNow {
+		return nonce, true
+	}
+	return nonce, ok && nonce != "" && nonce != domain.Status.LastRecheck
+}
+
+// clearRecheck removes the recheck annotation once the recheck it requested
+// is done.
+func (r *DomainReconciler) clearRecheck(ctx context.Context, domain *corev1alpha1.Domain) error {
+	patch := client.MergeFrom(domain.DeepCopy())
+	delete(domain.Annotations, corev1alpha1.AnnotationRecheck)
+	return r.Patch(ctx, domain, patch)
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *DomainReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&corev1alpha1.Domain{}).
+	b := ctrl.NewControllerManagedBy(mgr).
+		// status updates must not trigger a new reconcile, only changes to
+		// the spec or to the metadata do.
+		For(&corev1alpha1.Domain{}, builder.WithPredicates(predicate.Or(
+			predicate.GenerationChangedPredicate{},
+			predicate.AnnotationChangedPredicate{},
+			predicate.LabelChangedPredicate{},
+		))).
 		Owns(&netwrkingv1.Ingress{}).
-		Complete(r)
+		Owns(&netwrkingv1.NetworkPolicy{}).
+		Watches(&source.Kind{Type: &corev1alpha1.SenderPool{}}, handler.EnqueueRequestsFromMapFunc(r.domainsForSenderPool)).
+		// the referenced credentials may be rotated by another controller
+		Watches(&source.Kind{Type: &corev1.Secret{}}, handler.EnqueueRequestsFromMapFunc(r.domainsForSecret))
+	if r.ClusterConfigName != "" {
+		b = b.Watches(&source.Kind{Type: &corev1alpha1.ClusterDomainConfig{}}, handler.EnqueueRequestsFromMapFunc(r.domainsForClusterConfig),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}))
+	}
+	if r.GatewayAPI {
+		b = b.Owns(&gatewayv1beta1.HTTPRoute{})
+	}
+	if r.ExternalDNS {
+		b = b.Owns(newDNSEndpoint())
+	}
+	if r.PrometheusRules {
+		b = b.Owns(newPrometheusRule())
+	}
+	if r.MTASTSService != "" {
+		// the policy server looks the policies up by host
+		if err := mgr.GetFieldIndexer().IndexField(context.Background(), &corev1.ConfigMap{}, mtaSTSHostIndex, indexMTASTSHost); err != nil {
+			return err
+		}
+		b = b.Owns(&corev1.ConfigMap{}).Owns(&corev1.Service{})
+	}
+	if r.Delivery != nil {
+		// the flushers add the counters to the Domains of a domain name
+		if err := mgr.GetFieldIndexer().IndexField(context.Background(), &corev1alpha1.Domain{}, domainNameIndex, indexDomainName); err != nil {
+			return err
+		}
+	}
+	// the DMARC reports change the status, not the Domains
+	var changes []<-chan string
+	if r.DMARCReports != nil {
+		changes = append(changes, r.DMARCReports.Changes())
+	}
+	if len(changes) > 0 {
+		events := make(chan event.GenericEvent)
+		for _, c := range changes {
+			if err := mgr.Add(manager.RunnableFunc(r.domainNameEvents(c, events))); err != nil {
+				return err
+			}
+		}
+		b = b.Watches(&source.Channel{Source: events}, &handler.EnqueueRequestForObject{})
+	}
+
+	// the scheduler batches the Domains by base domain
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &corev1alpha1.Domain{}, baseDomainIndex, indexBaseDomain); err != nil {
+		return err
+	}
+	if r.RecheckBatchWindow > 0 {
+		r.scheduler = newRecheckScheduler(mgr.GetClient(), r.RecheckBatchWindow, r.now)
+		rechecks := make(chan event.GenericEvent)
+		if err := mgr.Add(manager.RunnableFunc(r.scheduler.run(rechecks))); err != nil {
+			return err
+		}
+		b = b.Watches(&source.Channel{Source: rechecks}, &handler.EnqueueRequestForObject{})
+	}
+
+	return b.WithOptions(controller.Options{
+		MaxConcurrentReconciles: r.MaxConcurrentReconciles,
+		RateLimiter:             newFreshRateLimiter(r.isFresh),
+	}).
+		Complete(tracing.Reconciler("Domain", r))
+}
+
+// domainNameEvents turns the domain names received from changes into
+// events for the Domains of these names, until ctx is done.
+func (r *DomainReconciler) domainNameEvents(changes <-chan string, events chan<- event.GenericEvent) func(context.Context) error {
+	return func(ctx context.Context) error {
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case name := <-changes:
+				domains := &corev1alpha1.DomainList{}
+				if err := r.List(ctx, domains); err != nil {
+					log.FromContext(ctx).Error(err, "failed to list domains", "domainName", name)
+					continue
+				}
+				for i := range domains.Items {
+					if !strings.EqualFold(domains.Items[i].Spec.DomainName, name) {
+						continue
+					}
+					select {
+					case events <- event.GenericEvent{Object: &domains.Items[i]}:
+					case <-ctx.Done():
+						return nil
+					}
+				}
+			}
+		}
+	}
 }
 
 func (r *DomainReconciler) reconcileIngress(ctx context.Context, domain *v1alpha1.Domain, l logr.Logger) error {
@@ -103,15 +743,26 @@ func (r *DomainReconciler) reconcileIngress(ctx context.Context, domain *v1alpha
 	name := statsIngressName(domain)
 
 	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: domain.Namespace}, ingress)
-	if err == nil {
-		return r.handleFoundIngress(ctx, ingress, domain, l)
-	} else if !errors.IsNotFound(err) {
+	if err != nil && !apierrors.IsNotFound(err) {
 		return err
 	}
+	found := err == nil
+
+	if !wantsStatsRoute(domain, corev1alpha1.RoutingIngress) {
+		if found && v1.IsControlledBy(ingress, domain) {
+			return r.deleteStatsRoute(ctx, ingress, domain)
+		}
+		return nil
+	}
+
+	if found {
+		return r.handleFoundIngress(ctx, ingress, domain, l)
+	}
 
-	if !domain.Status.DNS.Stats.OK {
+	if !statsRouteAllowed(domain) {
 		return nil
 	}
+	r.notifyMissingStatsRoute(ctx, domain, "Ingress", name)
 
 	ingress, err = r.buildDesiredIngress(domain)
 
@@ -119,81 +770,338 @@ func (r *DomainReconciler) reconcileIngress(ctx context.Context, domain *v1alpha
 		return err
 	}
 
-	return r.Create(ctx, ingress)
+	return r.createStatsRoute(ctx, ingress, domain)
+}
+
+// errIngressConflict is returned when an Ingress or HTTPRoute with the stats
+// ingress name exists but is not controlled by the Domain.
+var errIngressConflict = errors.New("stats route is not controlled by the domain")
+
+// errGatewayAPIDisabled is returned when a Domain requests the gatewayAPI
+// routing but the operator runs without it.
+var errGatewayAPIDisabled = errors.New("the gatewayAPI routing is not enabled in the operator")
+
+// errNoStatsService is returned when neither the Domain nor the
+// ClusterDomainConfig names the service of the stats route.
+var errNoStatsService = errors.New("no stats service is set by the domain or the ClusterDomainConfig")
+
+// isPermanentRoutingError reports whether retrying the stats routing won't
+// help until someone changes the cluster or the operator configuration.
+func isPermanentRoutingError(err error) bool {
+	return errors.Is(err, errIngressConflict) || errors.Is(err, errGatewayAPIDisabled) || errors.Is(err, errNoStatsService)
+}
+
+// statsRouteAllowed reports whether the stats route can be exposed: the
+// stats CNAME record is verified, and so is the ownership when required.
+func statsRouteAllowed(domain *corev1alpha1.Domain) bool {
+	return domain.Status.DNS.Stats.OK && !ownershipPending(domain)
+}
+
+// wantsStatsRoute reports whether the stats host must be exposed with the
+// given routing.
+func wantsStatsRoute(domain *corev1alpha1.Domain, routing string) bool {
+	return domain.Spec.Ingress.IsEnabled() && domain.Spec.RoutingOrDefault() == routing
 }
 
 func (r *DomainReconciler) handleFoundIngress(ctx context.Context, ingress *netwrkingv1.Ingress, domain *v1alpha1.Domain, l logr.Logger) error {
-	if domain.Status.DNS.Stats.OK {
-		return r.reconcileExistingIngress(ctx, ingress, domain, l)
+	if !v1.IsControlledBy(ingress, domain) {
+		if err := r.adopt(ctx, ingress, domain, l); err != nil {
+			return err
+		}
 	}
 
-	if ingress.DeletionTimestamp == nil {
-		return r.Delete(ctx, ingress)
+	if statsRouteAllowed(domain) {
+		return r.reconcileExistingIngress(ctx, ingress, domain, l)
 	}
 
-	return nil
+	return r.deleteStatsRoute(ctx, ingress, domain)
 }
 
-func (r *DomainReconciler) reconcileExistingIngress(ctx context.Context, ingress *netwrkingv1.Ingress, domain *v1alpha1.Domain, l logr.Logger) error {
-	toUpdate := false
-	for key, value := range domain.Spec.Ingress.Annotations {
-		if v, ok := ingress.Annotations[key]; !ok || v != value {
-			toUpdate = true
-			ingress.Annotations[key] = value
-		}
+// adopt takes control of an Ingress or HTTPRoute that is not controlled by
+// anyone and has been explicitly marked for adoption. Any other object is
+// left untouched and reported as a conflict.
+func (r *DomainReconciler) adopt(ctx context.Context, obj client.Object, domain *v1alpha1.Domain, l logr.Logger) error {
+	if owner := v1.GetControllerOf(obj); owner != nil {
+		return fmt.Errorf("%w: %s is controlled by %s %s", errIngressConflict, obj.GetName(), owner.Kind, owner.Name)
 	}
 
-	desiredSpec := buildIngressSpec(domain)
-	if !reflect.DeepEqual(ingress.Spec, desiredSpec) {
-		toUpdate = true
-		ingress.Spec = desiredSpec
+	if obj.GetAnnotations()[corev1alpha1.AnnotationAdopt] != "true" {
+		return fmt.Errorf("%w: %s has no controller, set the %s annotation to adopt it", errIngressConflict, obj.GetName(), corev1alpha1.AnnotationAdopt)
+	}
+
+	if err := ctrl.SetControllerReference(domain, obj, r.Scheme); err != nil {
+		return err
 	}
 
-	l.Info("updating ingress", "ingress", ingress, "to update", toUpdate)
+	l.Info("adopting stats route", "name", obj.GetName())
+
+	return r.Update(ctx, obj)
+}
+
+// reconcileExistingIngress restores the stats Ingress when its spec or its
+// managed metadata drifted from the desired state.
+func (r *DomainReconciler) reconcileExistingIngress(ctx context.Context, ingress *netwrkingv1.Ingress, domain *v1alpha1.Domain, l logr.Logger) error {
+	desired, err := r.buildDesiredIngress(domain)
+	if err != nil {
+		return err
+	}
 
-	if !toUpdate {
+	drifted := applyManagedMetadata(&ingress.DeepCopy().ObjectMeta, domain) ||
+		!reflect.DeepEqual(ingress.Spec, desired.Spec)
+	if !drifted {
 		return nil
 	}
 
-	l.Info("updating ingress", "ingress", ingress.Spec)
+	l.Info("applying ingress", "ingress", ingress.Name)
+
+	return r.applyStatsRoute(ctx, ingress, desired, domain)
+}
 
-	return r.Update(ctx, ingress)
+// mapSPFCheckStats maps the SPF check along with the evaluation of the
+// record.
+func mapSPFCheckStats(stats checker.DNSCheckStats) corev1alpha1.SPFStatus {
+	status := corev1alpha1.SPFStatus{DNSStatusStats: mapDNSCheckStats2DomainDNSResult(stats)}
+	if stats.SPF != nil {
+		status.Lookups, status.PermError = stats.SPF.Lookups, stats.SPF.PermError
+	}
+	return status
 }
 
 func mapDNSCheckStats2DomainDNSResult(stats checker.DNSCheckStats) corev1alpha1.DNSStatusStats {
-	return corev1alpha1.DNSStatusStats{
+	res := corev1alpha1.DNSStatusStats{
+		State:  stats.State(),
 		OK:     stats.Result(),
 		CntOK:  stats.CntOK,
 		CntErr: stats.CntErr,
 		CntKO:  stats.CntKO,
+
+		Observed: stats.Observed,
+	}
+
+	for _, rr := range stats.Resolvers {
+		status := corev1alpha1.ResolverStatus{Resolver: rr.Resolver, State: corev1alpha1.CheckStateMissing}
+		switch {
+		case rr.OK:
+			status.State = corev1alpha1.CheckStateVerified
+		case rr.Err != nil:
+			status.State = corev1alpha1.CheckStateUnknown
+			status.Message = rr.Err.Error()
+			status.ErrorClass = checkErrorClass(rr.Err)
+		}
+		res.Resolvers = append(res.Resolvers, status)
+	}
+
+	if stats.Expected != (checker.Record{}) {
+		res.Expected = &corev1alpha1.DNSRecord{
+			Type:  stats.Expected.Type,
+			Name:  stats.Expected.Name,
+			Value: stats.Expected.Value,
+		}
+	}
+
+	switch {
+	case res.State == corev1alpha1.CheckStateUnknown && stats.Err != nil:
+		res.Message = stats.Err.Error()
+		res.ErrorClass = checkErrorClass(stats.Err)
+	case res.State == corev1alpha1.CheckStateMissing:
+		res.Message = stats.Reason
+	}
+
+	return res
+}
+
+// checkErrorClass classifies the error of a check for the status, empty
+// for the errors of no known class.
+func checkErrorClass(err error) corev1alpha1.CheckErrorClass {
+	switch checker.ErrorClass(err) {
+	case checker.ErrorClassTimeout:
+		return corev1alpha1.CheckErrorTimeout
+	case checker.ErrorClassServerFailure:
+		return corev1alpha1.CheckErrorServerFailure
+	case checker.ErrorClassTemporary:
+		return corev1alpha1.CheckErrorTemporary
+	case checker.ErrorClassUnavailable:
+		return corev1alpha1.CheckErrorResolverUnavailable
+	default:
+		return ""
 	}
 }
 
-func (r *DomainReconciler) checkDomainDNS(ctx context.Context, l logr.Logger, domain *corev1alpha1.Domain) (corev1alpha1.DNSStatus, error) {
+func (r *DomainReconciler) checkDomainDNS(ctx context.Context, l logr.Logger, domain *corev1alpha1.Domain) corev1alpha1.DNSStatus {
 	l.Info("checking domain dns", "domain", domain.Spec.BaseDomain)
 
-	dkimStats := r.DNSChecker.CheckDomainDKim(ctx, domain)
-	spfStats := r.DNSChecker.CheckDomainSPF(ctx, domain)
-	domainStats := r.DNSChecker.CheckDomainStatsDNS(ctx, domain)
+	var dkimStats, spfStats, domainStats, mxStats, dmarcStats checker.DNSCheckStats
+
+	// the checks are independent, a slow resolver only delays the reconcile
+	// by the slowest of them
+	g := errgroup.Group{}
+	run := func(record string, stats *checker.DNSCheckStats, check func(context.Context, *corev1alpha1.Domain) checker.DNSCheckStats) {
+		g.Go(func() error {
+			checkCtx := ctx
+			if r.DNSCheckTimeout > 0 {
+				var cancel context.CancelFunc
+				checkCtx, cancel = context.WithTimeout(ctx, r.DNSCheckTimeout)
+				defer cancel()
+			}
+
+			*stats = observeCheck(checkCtx, record, func(ctx context.Context) checker.DNSCheckStats { return check(ctx, domain) })
+			return nil
+		})
+	}
+	run("dkim", &dkimStats, r.DNSChecker.CheckDomainDKIM)
+	run("spf", &spfStats, r.DNSChecker.CheckDomainSPF)
+	run("stats", &domainStats, r.DNSChecker.CheckDomainStatsDNS)
+	run("mx", &mxStats, r.DNSChecker.CheckDomainMX)
+	run("dmarc", &dmarcStats, r.DNSChecker.CheckDomainDMARC)
+	var mtaSTSStats, mtaSTSPolicyStats checker.DNSCheckStats
+	if domain.Spec.MTASTSEnabled() {
+		run("mta_sts", &mtaSTSStats, r.DNSChecker.CheckDomainMTASTS)
+		run("mta_sts_policy", &mtaSTSPolicyStats, r.DNSChecker.CheckDomainMTASTSPolicy)
+	}
+	var tlsRPTStats checker.DNSCheckStats
+	if domain.Spec.TLSRPT != nil {
+		run("tls_rpt", &tlsRPTStats, r.DNSChecker.CheckDomainTLSRPT)
+	}
+	ptrStats := make([]checker.DNSCheckStats, len(domain.Spec.SendingIPs))
+	for i, ip := range domain.Spec.SendingIPs {
+		ip := ip
+		run("ptr", &ptrStats[i], func(ctx context.Context, domain *corev1alpha1.Domain) checker.DNSCheckStats {
+			return r.DNSChecker.CheckPTR(ctx, ip, domain.Spec.DomainName, domain.Spec.BaseDomain)
+		})
+	}
+	// the additional domains are checked like the domain itself, with its
+	// DKIM key and SPF include
+	additionalDKIMStats := make([]checker.DNSCheckStats, len(domain.Spec.AdditionalDomains))
+	additionalSPFStats := make([]checker.DNSCheckStats, len(domain.Spec.AdditionalDomains))
+	for i, name := range domain.Spec.AdditionalDomains {
+		additional := withDomainName(domain, name)
+		run("additional_dkim", &additionalDKIMStats[i], func(ctx context.Context, _ *corev1alpha1.Domain) checker.DNSCheckStats {
+			return r.DNSChecker.CheckDomainDKIM(ctx, additional)
+		})
+		run("additional_spf", &additionalSPFStats[i], func(ctx context.Context, _ *corev1alpha1.Domain) checker.DNSCheckStats {
+			return r.DNSChecker.CheckDomainSPF(ctx, additional)
+		})
+	}
+	var bimiStats checker.BIMICheckStats
+	if domain.Spec.BIMI != nil {
+		g.Go(func() error {
+			checkCtx := ctx
+			if r.DNSCheckTimeout > 0 {
+				var cancel context.CancelFunc
+				checkCtx, cancel = context.WithTimeout(ctx, r.DNSCheckTimeout)
+				defer cancel()
+			}
+
+			_ = observeCheck(checkCtx, "bimi", func(ctx context.Context) checker.DNSCheckStats {
+				bimiStats = r.DNSChecker.CheckDomainBIMI(ctx, domain)
+				return bimiStats.Record
+			})
+			return nil
+		})
+	}
+	_ = g.Wait()
 
-	return corev1alpha1.DNSStatus{
+	status := corev1alpha1.DNSStatus{
 		Stats: mapDNSCheckStats2DomainDNSResult(domainStats),
 		DKIM:  mapDNSCheckStats2DomainDNSResult(dkimStats),
-		SFP:   mapDNSCheckStats2DomainDNSResult(spfStats),
-	}, nil
+		SPF:   mapSPFCheckStats(spfStats),
+		MX: corev1alpha1.MXStatus{
+			DNSStatusStats: mapDNSCheckStats2DomainDNSResult(mxStats),
+			Hosts:          mxStats.Observed,
+		},
+		DMARC: corev1alpha1.DMARCStatus{
+			DNSStatusStats: mapDNSCheckStats2DomainDNSResult(dmarcStats),
+			Policy:         dmarcStats.Value,
+		},
+	}
+	if domain.Spec.MTASTSEnabled() {
+		status.MTASTS = &corev1alpha1.MTASTSStatus{
+			DNSStatusStats: mapDNSCheckStats2DomainDNSResult(mtaSTSStats),
+			Policy:         mapDNSCheckStats2DomainDNSResult(mtaSTSPolicyStats),
+			PolicyID:       mtasts.PolicyID(mtasts.DomainPolicy(domain)),
+		}
+	}
+	if domain.Spec.TLSRPT != nil {
+		tlsRPT := mapDNSCheckStats2DomainDNSResult(tlsRPTStats)
+		status.TLSRPT = &tlsRPT
+	}
+	for i, ip := range domain.Spec.SendingIPs {
+		status.PTR = append(status.PTR, corev1alpha1.PTRStatus{
+			IP:             ip,
+			DNSStatusStats: mapDNSCheckStats2DomainDNSResult(ptrStats[i]),
+			Host:           ptrStats[i].Value,
+		})
+	}
+	for i, name := range domain.Spec.AdditionalDomains {
+		status.AdditionalDomains = append(status.AdditionalDomains, corev1alpha1.AdditionalDomainStatus{
+			Domain: name,
+			DKIM:   mapDNSCheckStats2DomainDNSResult(additionalDKIMStats[i]),
+			SPF:    mapSPFCheckStats(additionalSPFStats[i]),
+		})
+	}
+	if domain.Spec.BIMI != nil {
+		status.BIMI = &corev1alpha1.BIMIStatus{
+			DNSStatusStats: mapDNSCheckStats2DomainDNSResult(bimiStats.Record),
+			Indicator:      mapDNSCheckStats2DomainDNSResult(bimiStats.Indicator),
+		}
+		if bimiStats.VMC != nil {
+			vmc := mapDNSCheckStats2DomainDNSResult(*bimiStats.VMC)
+			status.BIMI.VMC = &vmc
+		}
+	}
+
+	type namedCheck struct {
+		name  string
+		stats checker.DNSCheckStats
+	}
+	checks := []namedCheck{
+		{"dkim", dkimStats},
+		{"spf", spfStats},
+		{"stats", domainStats},
+		{"mx", mxStats},
+		{"dmarc", dmarcStats},
+		{"mta_sts", mtaSTSStats},
+		{"mta_sts_policy", mtaSTSPolicyStats},
+		{"tls_rpt", tlsRPTStats},
+		{"bimi", bimiStats.Record},
+		{"bimi_indicator", bimiStats.Indicator},
+	}
+	if bimiStats.VMC != nil {
+		checks = append(checks, namedCheck{"bimi_vmc", *bimiStats.VMC})
+	}
+	for _, stats := range ptrStats {
+		checks = append(checks, namedCheck{"ptr", stats})
+	}
+	for i := range domain.Spec.AdditionalDomains {
+		checks = append(checks, namedCheck{"additional_dkim", additionalDKIMStats[i]}, namedCheck{"additional_spf", additionalSPFStats[i]})
+	}
+	for _, c := range checks {
+		if c.stats.State() == corev1alpha1.CheckStateUnknown {
+			l.Error(c.stats.Err, "dns check failed", "check", c.name, "domain", domain.Spec.DomainName)
+		}
+	}
+
+	keepLastKnownGood(&status, domain.Status.DNS, r.now())
+
+	return status
 }
 
 func (r *DomainReconciler) buildDesiredIngress(domain *corev1alpha1.Domain) (*netwrkingv1.Ingress, error) {
 	name := statsIngressName(domain)
 
+	defaulted := r.withClusterDefaults(domain)
+	if defaulted.Spec.Ingress.Service.Name == "" {
+		return nil, errNoStatsService
+	}
+
 	ing := &netwrkingv1.Ingress{
 		ObjectMeta: v1.ObjectMeta{
-			Name:        name,
-			Namespace:   domain.Namespace,
-			Annotations: domain.Spec.Ingress.Annotations,
+			Name:      name,
+			Namespace: domain.Namespace,
 		},
-		Spec: buildIngressSpec(domain),
+		Spec: buildIngressSpec(defaulted),
 	}
+	applyManagedMetadata(&ing.ObjectMeta, domain)
 
 	if err := ctrl.SetControllerReference(domain, ing, r.Scheme); err != nil {
 		return ing, err
@@ -202,38 +1110,60 @@ func (r *DomainReconciler) buildDesiredIngress(domain *corev1alpha1.Domain) (*ne
 	return ing, nil
 }
 
+// servedStatsHosts returns the stats hosts followed by the extra hosts of
+// the stats route, without duplicates.
+func servedStatsHosts(domain *corev1alpha1.Domain) []string {
+	hosts := []string{}
+	seen := map[string]bool{}
+	for _, host := range append(append([]string{}, domain.Spec.StatsHosts()...), domain.Spec.Ingress.ExtraHosts...) {
+		if key := strings.ToLower(host); !seen[key] {
+			seen[key] = true
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
 func buildIngressSpec(domain *corev1alpha1.Domain) netwrkingv1.IngressSpec {
 	pathPrefix := netwrkingv1.PathTypePrefix
-	statsDomain := fmt.Sprintf("%s.%s", domain.Spec.StatsPrefix, domain.Spec.DomainName)
+	hosts := servedStatsHosts(domain)
+	tlsSecret := domain.Spec.TLSSecretNameOrDefault()
 
-	tlsSecret := fmt.Sprintf("%s-tls", statsDomain)
-
-	return netwrkingv1.IngressSpec{
-		Rules: []netwrkingv1.IngressRule{
-			{
-				Host: statsDomain,
-				IngressRuleValue: netwrkingv1.IngressRuleValue{
-					HTTP: &netwrkingv1.HTTPIngressRuleValue{
-						Paths: []netwrkingv1.HTTPIngressPath{
-							{
-								Path:     "/stats",
-								PathType: &pathPrefix,
-								Backend: netwrkingv1.IngressBackend{
-									Service: ingressService(domain),
-								},
-							},
-						},
-					},
-				},
+	paths := []netwrkingv1.HTTPIngressPath{}
+	for _, path := range domain.Spec.StatsRoutePaths() {
+		paths = append(paths, netwrkingv1.HTTPIngressPath{
+			Path:     path,
+			PathType: &pathPrefix,
+			Backend: netwrkingv1.IngressBackend{
+				Service: ingressService(domain),
 			},
-		},
+		})
+	}
+
+	spec := netwrkingv1.IngressSpec{
 		TLS: []netwrkingv1.IngressTLS{
 			{
-				Hosts:      []string{statsDomain},
+				Hosts:      hosts,
 				SecretName: tlsSecret,
 			},
 		},
 	}
+	for _, host := range hosts {
+		spec.Rules = append(spec.Rules, netwrkingv1.IngressRule{
+			Host: host,
+			IngressRuleValue: netwrkingv1.IngressRuleValue{
+				HTTP: &netwrkingv1.HTTPIngressRuleValue{
+					Paths: paths,
+				},
+			},
+		})
+	}
+
+	if className := domain.Spec.Ingress.ClassName; className != "" {
+		spec.IngressClassName = &className
+	}
+
+	return spec
 }
 
 func ingressService(domain *corev1alpha1.Domain) *netwrkingv1.IngressServiceBackend {
@@ -250,13 +1180,430 @@ func statsIngressName(domain *corev1alpha1.Domain) string {
 }
 
 func dnsReady(dnsStatus corev1alpha1.DNSStatus) bool {
-	return dnsStatus.DKIM.OK && dnsStatus.Stats.OK && dnsStatus.SFP.OK
+	return dnsStatus.DKIM.OK && dnsStatus.Stats.OK && dnsStatus.SPF.OK
+}
+
+// dnsVerified reports whether the last check verified the DKIM, SPF and
+// stats records, unlike dnsReady which keeps trusting the records that
+// could not be checked.
+func dnsVerified(dnsStatus corev1alpha1.DNSStatus) bool {
+	for _, stats := range []corev1alpha1.DNSStatusStats{dnsStatus.DKIM, dnsStatus.Stats, dnsStatus.SPF.DNSStatusStats} {
+		if stats.State != corev1alpha1.CheckStateVerified {
+			return false
+		}
+	}
+	return true
+}
+
+// keepLastKnownGood stamps the records of status with the check time and
+// the time their state last changed, and carries the previous verdict of
+// the verified records that could not be checked, so that a failed lookup
+// doesn't undo a verification. A record whose expected value changed is not
+// carried.
+func keepLastKnownGood(status *corev1alpha1.DNSStatus, prev corev1alpha1.DNSStatus, now time.Time) {
+	carry := func(cur *corev1alpha1.DNSStatusStats, prev corev1alpha1.DNSStatusStats) bool {
+		cur.CheckedAt = &v1.Time{Time: now}
+		cur.LastTransitionTime = &v1.Time{Time: now}
+		if cur.State == prev.State && prev.LastTransitionTime != nil {
+			cur.LastTransitionTime = prev.LastTransitionTime
+		}
+		sameRecord := reflect.DeepEqual(cur.Expected, prev.Expected)
+
+		switch {
+		case cur.State == corev1alpha1.CheckStateVerified:
+			cur.LastVerified = &v1.Time{Time: now}
+			return false
+		case !sameRecord:
+			return false
+		}
+
+		cur.LastVerified = prev.LastVerified
+		if cur.State != corev1alpha1.CheckStateUnknown || !prev.OK {
+			return false
+		}
+
+		cur.OK = true
+		cur.Observed = prev.Observed
+		return true
+	}
+
+	carry(&status.DKIM, prev.DKIM)
+	if carry(&status.SPF.DNSStatusStats, prev.SPF.DNSStatusStats) {
+		status.SPF.Lookups, status.SPF.PermError = prev.SPF.Lookups, prev.SPF.PermError
+	}
+	carry(&status.Stats, prev.Stats)
+	if carry(&status.MX.DNSStatusStats, prev.MX.DNSStatusStats) {
+		status.MX.Hosts = prev.MX.Hosts
+	}
+	if carry(&status.DMARC.DNSStatusStats, prev.DMARC.DNSStatusStats) {
+		status.DMARC.Policy = prev.DMARC.Policy
+	}
+	if status.MTASTS != nil {
+		prevMTASTS := corev1alpha1.MTASTSStatus{}
+		if prev.MTASTS != nil {
+			prevMTASTS = *prev.MTASTS
+		}
+		carry(&status.MTASTS.DNSStatusStats, prevMTASTS.DNSStatusStats)
+		carry(&status.MTASTS.Policy, prevMTASTS.Policy)
+	}
+	if status.TLSRPT != nil {
+		prevTLSRPT := corev1alpha1.DNSStatusStats{}
+		if prev.TLSRPT != nil {
+			prevTLSRPT = *prev.TLSRPT
+		}
+		carry(status.TLSRPT, prevTLSRPT)
+	}
+	if status.BIMI != nil {
+		prevBIMI := corev1alpha1.BIMIStatus{}
+		if prev.BIMI != nil {
+			prevBIMI = *prev.BIMI
+		}
+		carry(&status.BIMI.DNSStatusStats, prevBIMI.DNSStatusStats)
+		carry(&status.BIMI.Indicator, prevBIMI.Indicator)
+		if status.BIMI.VMC != nil && prevBIMI.VMC != nil {
+			carry(status.BIMI.VMC, *prevBIMI.VMC)
+		}
+	}
+	for i := range status.PTR {
+		prevPTR := corev1alpha1.PTRStatus{}
+		for _, p := range prev.PTR {
+			if p.IP == status.PTR[i].IP {
+				prevPTR = p
+			}
+		}
+		if carry(&status.PTR[i].DNSStatusStats, prevPTR.DNSStatusStats) {
+			status.PTR[i].Host = prevPTR.Host
+		}
+	}
+	for i := range status.AdditionalDomains {
+		cur := &status.AdditionalDomains[i]
+		prevDomain := corev1alpha1.AdditionalDomainStatus{}
+		for _, p := range prev.AdditionalDomains {
+			if p.Domain == cur.Domain {
+				prevDomain = p
+			}
+		}
+		carry(&cur.DKIM, prevDomain.DKIM)
+		if carry(&cur.SPF.DNSStatusStats, prevDomain.SPF.DNSStatusStats) {
+			cur.SPF.Lookups, cur.SPF.PermError = prevDomain.SPF.Lookups, prevDomain.SPF.PermError
+		}
+	}
+}
+
+// withDomainName returns a shallow copy of domain checked as name, the
+// copy shares the rest of the spec and the status of domain.
+func withDomainName(domain *corev1alpha1.Domain, name string) *corev1alpha1.Domain {
+	copied := *domain
+	copied.Spec.DomainName = name
+	return &copied
+}
+
+// dnsCheck describes how a DNS check is reported in the conditions.
+type dnsCheck struct {
+	record        string
+	conditionType string
+	stats         corev1alpha1.DNSStatusStats
+	notVerified   string
+	checkFailed   string
+}
+
+// dnsChecks lists the DNS checks required for the domain to be ready, in
+// the order they are reported.
+func dnsChecks(domain *corev1alpha1.Domain) []dnsCheck {
+	dns := domain.Status.DNS
+
+	return []dnsCheck{
+		{"DKIM record", corev1alpha1.ConditionDKIMVerified, dns.DKIM, corev1alpha1.ReasonDKIMNotVerified, corev1alpha1.ReasonDKIMCheckFailed},
+		{"SPF record", corev1alpha1.ConditionSPFVerified, dns.SPF.DNSStatusStats, corev1alpha1.ReasonSPFNotVerified, corev1alpha1.ReasonSPFCheckFailed},
+		{"stats CNAME record", corev1alpha1.ConditionStatsDNSVerified, dns.Stats, corev1alpha1.ReasonStatsDNSNotVerified, corev1alpha1.ReasonStatsDNSCheckFailed},
+	}
+}
+
+// checkConditions computes a condition per DNS check of the domain.
+func checkConditions(domain *corev1alpha1.Domain) []v1.Condition {
+	checks := dnsChecks(domain)
+	conds := make([]v1.Condition, 0, len(checks))
+
+	for _, c := range checks {
+		cond := v1.Condition{
+			Type:               c.conditionType,
+			ObservedGeneration: domain.Generation,
+		}
+
+		switch {
+		case c.stats.State == corev1alpha1.CheckStateUnknown:
+			cond.Status = v1.ConditionUnknown
+			cond.Reason = c.checkFailed
+			cond.Message = checkFailedMessage(c)
+		case c.stats.OK:
+			cond.Status = v1.ConditionTrue
+			cond.Reason = corev1alpha1.ReasonRecordVerified
+			cond.Message = fmt.Sprintf("%s is verified", c.record)
+		default:
+			cond.Status = v1.ConditionFalse
+			cond.Reason = c.notVerified
+			cond.Message = notVerifiedMessage(c)
+		}
+
+		conds = append(conds, cond)
+	}
+
+	return conds
 }
 
+// uncheckedReason explains why a record could not be checked: the class of
+// the resolver errors, when it tells more than the errors themselves, then
+// the errors.
+func uncheckedReason(stats corev1alpha1.DNSStatusStats) string {
+	var class string
+	switch stats.ErrorClass {
+	case corev1alpha1.CheckErrorTimeout:
+		class = "the resolvers timed out"
+	case corev1alpha1.CheckErrorServerFailure:
+		class = "the resolvers answered SERVFAIL"
+	case corev1alpha1.CheckErrorResolverUnavailable:
+		class = "the resolvers are unavailable after failing repeatedly"
+	default:
+		return stats.Message
+	}
+	return fmt.Sprintf("%s, %s", class, stats.Message)
+}
+
+func checkFailedMessage(c dnsCheck) string {
+	msg := fmt.Sprintf("%s could not be checked: %s", c.record, uncheckedReason(c.stats))
+	if c.stats.OK && c.stats.LastVerified != nil {
+		msg += fmt.Sprintf(", last verified at %s", c.stats.LastVerified.UTC().Format(time.RFC3339))
+	}
+	return msg
+}
+
+func notVerifiedMessage(c dnsCheck) string {
+	if c.stats.Message != "" {
+		return fmt.Sprintf("%s is not verified: %s", c.record, c.stats.Message)
+	}
+	return fmt.Sprintf("%s is not verified", c.record)
+}
+
+// readyCondition computes the Ready condition of the domain from its DNS
+// status, naming the first failing check as the reason.
+func readyCondition(domain *corev1alpha1.Domain) v1.Condition {
+	cond := v1.Condition{
+		Type:               corev1alpha1.ConditionReady,
+		Status:             v1.ConditionFalse,
+		ObservedGeneration: domain.Generation,
+	}
+
+	for _, c := range dnsChecks(domain) {
+		switch {
+		case c.stats.OK:
+			continue
+		case c.stats.State == corev1alpha1.CheckStateUnknown:
+			cond.Reason = c.checkFailed
+			cond.Message = fmt.Sprintf("%s could not be checked: %s", c.record, uncheckedReason(c.stats))
+		default:
+			cond.Reason = c.notVerified
+			cond.Message = notVerifiedMessage(c)
+		}
+
+		return cond
+	}
+
+	cond.Status = v1.ConditionTrue
+	cond.Reason = corev1alpha1.ReasonDNSVerified
+	cond.Message = "all DNS records are verified"
+
+	return cond
+}
+
+// additionalDomainsCondition computes the AdditionalDomainsVerified
+// condition from the checks of the additional domains. A record that could
+// not be checked makes it Unknown unless another one is not verified.
+func additionalDomainsCondition(domain *corev1alpha1.Domain) v1.Condition {
+	cond := v1.Condition{
+		Type:               corev1alpha1.ConditionAdditionalDomainsVerified,
+		Status:             v1.ConditionTrue,
+		Reason:             corev1alpha1.ReasonAdditionalDomainsVerified,
+		Message:            "the DKIM and SPF records of every additional domain are verified",
+		ObservedGeneration: domain.Generation,
+	}
+
+	var failing, unchecked []string
+	for _, status := range domain.Status.DNS.AdditionalDomains {
+		records := []struct {
+			name  string
+			stats corev1alpha1.DNSStatusStats
+		}{
+			{"the DKIM record", status.DKIM},
+			{"the SPF record", status.SPF.DNSStatusStats},
+		}
+		for _, record := range records {
+			switch {
+			case record.stats.OK:
+			case record.stats.State == corev1alpha1.CheckStateUnknown:
+				unchecked = append(unchecked, fmt.Sprintf("%s of %s could not be checked: %s", record.name, status.Domain, uncheckedReason(record.stats)))
+			case record.stats.Message != "":
+				failing = append(failing, fmt.Sprintf("%s of %s is not verified: %s", record.name, status.Domain, record.stats.Message))
+			default:
+				failing = append(failing, fmt.Sprintf("%s of %s is not verified", record.name, status.Domain))
+			}
+		}
+	}
+
+	switch {
+	case len(failing) > 0:
+		cond.Status = v1.ConditionFalse
+		cond.Reason = corev1alpha1.ReasonAdditionalDomainsNotVerified
+		cond.Message = strings.Join(failing, "; ")
+	case len(unchecked) > 0:
+		cond.Status = v1.ConditionUnknown
+		cond.Reason = corev1alpha1.ReasonAdditionalDomainsCheckFailed
+		cond.Message = strings.Join(unchecked, "; ")
+	}
+
+	return cond
+}
+
+// ingressCondition computes the IngressReady condition from the outcome of
+// the stats ingress reconciliation.
+func ingressCondition(domain *corev1alpha1.Domain, ingressErr error) v1.Condition {
+	cond := v1.Condition{
+		Type:               corev1alpha1.ConditionIngressReady,
+		Status:             v1.ConditionFalse,
+		ObservedGeneration: domain.Generation,
+	}
+
+	kind := "ingress"
+	if domain.Spec.RoutingOrDefault() == corev1alpha1.RoutingGatewayAPI {
+		kind = "HTTPRoute"
+	}
+
+	switch {
+	case ingressErr == nil && !domain.Spec.Ingress.IsEnabled():
+		cond.Reason = corev1alpha1.ReasonIngressDisabled
+		cond.Message = fmt.Sprintf("the stats %s is disabled", kind)
+	case errors.Is(ingressErr, errIngressConflict):
+		cond.Reason = corev1alpha1.ReasonIngressConflict
+		cond.Message = ingressErr.Error()
+	case errors.Is(ingressErr, errGatewayAPIDisabled):
+		cond.Reason = corev1alpha1.ReasonGatewayAPIDisabled
+		cond.Message = ingressErr.Error()
+	case errors.Is(ingressErr, errNoStatsService):
+		cond.Reason = corev1alpha1.ReasonNoStatsService
+		cond.Message = ingressErr.Error()
+	case ingressErr != nil:
+		cond.Reason = corev1alpha1.ReasonIngressFailed
+		cond.Message = ingressErr.Error()
+	case ownershipPending(domain):
+		cond.Reason = corev1alpha1.ReasonOwnershipNotVerified
+		cond.Message = fmt.Sprintf("the stats %s is created once the ownership of the domain is verified", kind)
+	case !domain.Status.DNS.Stats.OK:
+		cond.Reason = corev1alpha1.ReasonStatsDNSNotVerified
+		cond.Message = fmt.Sprintf("the stats %s is created once the stats CNAME record is verified", kind)
+	default:
+		cond.Status = v1.ConditionTrue
+		cond.Reason = corev1alpha1.ReasonIngressReconciled
+		cond.Message = fmt.Sprintf("the stats %s is up to date", kind)
+	}
+
+	return cond
+}
+
+// sameDNSVerdicts reports whether two DNS statuses agree on the outcome of
+// every check, regardless of the resolver counters.
+func sameDNSVerdicts(a, b corev1alpha1.DNSStatus) bool {
+	same := func(x, y corev1alpha1.DNSStatusStats) bool {
+		return x.OK == y.OK && x.State == y.State
+	}
+
+	sameMTASTS := func(x, y *corev1alpha1.MTASTSStatus) bool {
+		if x == nil || y == nil {
+			return x == y
+		}
+		return same(x.DNSStatusStats, y.DNSStatusStats) && same(x.Policy, y.Policy)
+	}
+	sameOptional := func(x, y *corev1alpha1.DNSStatusStats) bool {
+		if x == nil || y == nil {
+			return x == y
+		}
+		return same(*x, *y)
+	}
+	sameBIMI := func(x, y *corev1alpha1.BIMIStatus) bool {
+		if x == nil || y == nil {
+			return x == y
+		}
+		return same(x.DNSStatusStats, y.DNSStatusStats) && same(x.Indicator, y.Indicator) && sameOptional(x.VMC, y.VMC)
+	}
+
+	samePTR := func(x, y []corev1alpha1.PTRStatus) bool {
+		if len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if x[i].IP != y[i].IP || !same(x[i].DNSStatusStats, y[i].DNSStatusStats) {
+				return false
+			}
+		}
+		return true
+	}
+
+	sameSPF := func(x, y corev1alpha1.SPFStatus) bool {
+		return same(x.DNSStatusStats, y.DNSStatusStats) && x.Lookups == y.Lookups && x.PermError == y.PermError
+	}
+
+	sameAdditional := func(x, y []corev1alpha1.AdditionalDomainStatus) bool {
+		if len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if x[i].Domain != y[i].Domain || !same(x[i].DKIM, y[i].DKIM) || !sameSPF(x[i].SPF, y[i].SPF) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return same(a.DKIM, b.DKIM) && sameSPF(a.SPF, b.SPF) && same(a.Stats, b.Stats) && same(a.MX.DNSStatusStats, b.MX.DNSStatusStats) &&
+		same(a.DMARC.DNSStatusStats, b.DMARC.DNSStatusStats) && sameMTASTS(a.MTASTS, b.MTASTS) &&
+		sameOptional(a.TLSRPT, b.TLSRPT) && sameBIMI(a.BIMI, b.BIMI) &&
+		samePTR(a.PTR, b.PTR) && sameAdditional(a.AdditionalDomains, b.AdditionalDomains)
+}
+
+const (
+	// transitionRecheckInterval is used right after a check changed outcome.
+	transitionRecheckInterval = 10 * time.Second
+
+	// failing checks are retried with an exponential backoff starting from
+	// failedRecheckBaseInterval and capped at spec.unverifiedCheckInterval.
+	failedRecheckBaseInterval = 10 * time.Second
+
+	// failedRecheckJitter is the fraction of the backoff randomly shaved off
+	// so that domains failing together don't retry in synchronized waves.
+	failedRecheckJitter = 0.2
+)
+
 func computeReconcileInterval(domain *corev1alpha1.Domain) time.Duration {
-	if dnsReady(domain.Status.DNS) {
-		return 1 * time.Hour
+	if dnsVerified(domain.Status.DNS) {
+		return domain.Spec.CheckIntervalOrDefault()
 	}
 
-	return 1 * time.Minute
+	return failedRecheckInterval(domain.Status.FailedChecks, domain.Spec.UnverifiedCheckIntervalOrDefault())
+}
+
+func failedRecheckInterval(failedChecks int, maxInterval time.Duration) time.Duration {
+	backoff := failedRecheckBackoff(failedChecks, maxInterval)
+	return backoff - time.Duration(rand.Float64()*failedRecheckJitter*float64(backoff))
+}
+
+// failedRecheckBackoff returns the backoff after failedChecks, before the
+// jitter.
+func failedRecheckBackoff(failedChecks int, maxInterval time.Duration) time.Duration {
+	backoff := failedRecheckBaseInterval
+	for i := 1; i < failedChecks && backoff < maxInterval; i++ {
+		backoff *= 2
+	}
+
+	if backoff > maxInterval {
+		backoff = maxInterval
+	}
+	return backoff
 }
@@ -0,0 +1,3565 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr/funcr"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	netwrkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	gatewayv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+	"github.com/kannon-email/k8nnon/internal/delivery"
+	"github.com/kannon-email/k8nnon/internal/dkim"
+	"github.com/kannon-email/k8nnon/internal/dmarc"
+	"github.com/kannon-email/k8nnon/internal/dns/checker"
+	"github.com/kannon-email/k8nnon/internal/dns/provider"
+	"github.com/kannon-email/k8nnon/internal/kannon"
+	"github.com/kannon-email/k8nnon/internal/logging"
+	"github.com/kannon-email/k8nnon/internal/notify"
+	"github.com/kannon-email/k8nnon/internal/reputation"
+	"github.com/kannon-email/k8nnon/internal/resilience"
+	"github.com/kannon-email/k8nnon/internal/shard"
+)
+
+func TestFailedRecheckIntervalBackoff(t *testing.T) {
+	minInterval := func(d time.Duration) time.Duration {
+		return d - time.Duration(failedRecheckJitter*float64(d))
+	}
+
+	cases := []struct {
+		failedChecks int
+		backoff      time.Duration
+	}{
+		{0, failedRecheckBaseInterval},
+		{1, failedRecheckBaseInterval},
+		{2, 2 * failedRecheckBaseInterval},
+		{3, 4 * failedRecheckBaseInterval},
+		{10, corev1alpha1.DefaultUnverifiedCheckInterval},
+	}
+
+	for _, c := range cases {
+		interval := failedRecheckInterval(c.failedChecks, corev1alpha1.DefaultUnverifiedCheckInterval)
+		assert.LessOrEqual(t, interval, c.backoff, "failed checks: %d", c.failedChecks)
+		assert.GreaterOrEqual(t, interval, minInterval(c.backoff), "failed checks: %d", c.failedChecks)
+	}
+
+	interval := failedRecheckInterval(10, 10*time.Minute)
+	assert.LessOrEqual(t, interval, 10*time.Minute, "should be capped at the configured interval")
+	assert.GreaterOrEqual(t, interval, minInterval(10*time.Minute), "should keep backing off past the default cap")
+}
+
+func TestConfiguredCheckInterval(t *testing.T) {
+	domain := createDomain(t)
+	domain.Spec.CheckInterval = &v1.Duration{Duration: 6 * time.Hour}
+	r := createReconciler(t, checker.NewFakeDNSChecker(checker.WithAll(true)), domain)
+
+	reconcileObject(t, r, domain)
+	res := reconcileObject(t, r, domain)
+	assert.Equal(t, 6*time.Hour, res.RequeueAfter)
+}
+
+func TestIngressAnnotationsSurviveReconcile(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	domain.Spec.Ingress.Annotations = map[string]string{
+		"nginx.ingress.kubernetes.io/auth-url":  "https://auth.example.com",
+		"nginx.ingress.kubernetes.io/limit-rps": "10",
+	}
+	domain.Spec.IngressLabels = map[string]string{"team": "mail"}
+
+	r := createReconciler(t, checker.NewFakeDNSChecker(checker.WithAll(true)), domain)
+	reconcileObject(t, r, domain)
+
+	ingress := getStatsIngress(t, r, domain)
+	assert.Equal(t, "https://auth.example.com", ingress.Annotations["nginx.ingress.kubernetes.io/auth-url"])
+	assert.Equal(t, "mail", ingress.Labels["team"])
+
+	// another controller annotates the ingress
+	ingress.Annotations["other.io/managed"] = "true"
+	require.NoError(t, r.Update(ctx, ingress))
+
+	// the user drops an annotation from the spec
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	delete(domain.Spec.Ingress.Annotations, "nginx.ingress.kubernetes.io/limit-rps")
+	require.NoError(t, r.Update(ctx, domain))
+	reconcileObject(t, r, domain)
+
+	ingress = getStatsIngress(t, r, domain)
+	assert.Equal(t, "https://auth.example.com", ingress.Annotations["nginx.ingress.kubernetes.io/auth-url"])
+	assert.Equal(t, "true", ingress.Annotations["other.io/managed"], "should not remove foreign annotations")
+	assert.NotContains(t, ingress.Annotations, "nginx.ingress.kubernetes.io/limit-rps")
+	assert.Equal(t, "mail", ingress.Labels["team"])
+}
+
+func TestIngressDriftIsCorrected(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	r := createReconciler(t, checker.NewFakeDNSChecker(checker.WithAll(true)), domain)
+	reconcileObject(t, r, domain)
+
+	// an up to date ingress is not rewritten
+	ingress := getStatsIngress(t, r, domain)
+	version := ingress.ResourceVersion
+	reconcileObject(t, r, domain)
+	assert.Equal(t, version, getStatsIngress(t, r, domain).ResourceVersion)
+
+	// someone edits the rules by hand
+	ingress.Spec.Rules[0].Host = "other.example.com"
+	ingress.Spec.Rules[0].HTTP.Paths[0].Path = "/other"
+	ingress.Spec.Rules[0].HTTP.Paths[0].Backend.Service.Name = "other"
+	require.NoError(t, r.Update(ctx, ingress))
+	reconcileObject(t, r, domain)
+
+	ingress = getStatsIngress(t, r, domain)
+	assert.Equal(t, buildIngressSpec(domain), ingress.Spec)
+	assert.True(t, v1.IsControlledBy(ingress, domain))
+}
+
+// failingIngressClient fails the creation of every Ingress.
+type failingIngressClient struct {
+	client.Client
+}
+
+func (c failingIngressClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	if _, ok := obj.(*netwrkingv1.Ingress); ok {
+		return errors.New("ingress creation failed")
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+// hangingDKIMChecker never gets an answer for the DKIM record.
+type hangingDKIMChecker struct {
+	*checker.FakeDNSChecker
+}
+
+func (c hangingDKIMChecker) CheckDomainDKIM(ctx context.Context, domain *corev1alpha1.Domain) checker.DNSCheckStats {
+	<-ctx.Done()
+	return checker.DNSCheckStats{CntKO: 1, CntErr: 1, Err: ctx.Err()}
+}
+
+func TestDNSCheckTimeout(t *testing.T) {
+	domain := createDomain(t)
+	r := createReconciler(t, hangingDKIMChecker{checker.NewFakeDNSChecker(checker.WithAll(true))}, domain)
+	r.DNSCheckTimeout = 50 * time.Millisecond
+
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(context.Background(), client.ObjectKeyFromObject(domain), domain))
+	assert.Equal(t, corev1alpha1.CheckStateUnknown, domain.Status.DNS.DKIM.State)
+	assert.Equal(t, context.DeadlineExceeded.Error(), domain.Status.DNS.DKIM.Message)
+	assert.True(t, domain.Status.DNS.SPF.OK, "the other checks should complete")
+	assert.True(t, domain.Status.DNS.Stats.OK, "the other checks should complete")
+}
+
+func TestDNSStatusPersistedWhenIngressFails(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	r := createReconciler(t, checker.NewFakeDNSChecker(checker.WithAll(true)), domain)
+	r.Client = failingIngressClient{Client: r.Client}
+
+	_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(domain)})
+	assert.Error(t, err)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.True(t, domain.Status.DNS.DKIM.OK)
+	assert.True(t, domain.Status.DNS.SPF.OK)
+	assert.True(t, domain.Status.DNS.Stats.OK)
+	assert.True(t, meta.IsStatusConditionTrue(domain.Status.Conditions, corev1alpha1.ConditionReady))
+}
+
+func TestForeignIngressIsNotManaged(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	foreign := &netwrkingv1.Ingress{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      statsIngressName(domain),
+			Namespace: domain.Namespace,
+		},
+	}
+
+	r := createReconciler(t, checker.NewFakeDNSChecker(checker.WithAll(true)), domain, foreign)
+	reconcileObject(t, r, domain)
+
+	ingress := getStatsIngress(t, r, domain)
+	assert.Nil(t, v1.GetControllerOf(ingress), "should not have adopted the ingress")
+	assert.Empty(t, ingress.Spec.Rules, "should not have modified the ingress")
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	cond := meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionIngressReady)
+	require.NotNil(t, cond)
+	assert.Equal(t, corev1alpha1.ReasonIngressConflict, cond.Reason)
+
+	// a foreign ingress must not be deleted when the stats DNS is not verified
+	r.DNSChecker = checker.NewFakeDNSChecker(checker.WithAll(false))
+	reconcileObject(t, r, domain)
+	getStatsIngress(t, r, domain)
+}
+
+func TestIngressAdoption(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	domain.UID = "domain-uid"
+	leftover := &netwrkingv1.Ingress{
+		ObjectMeta: v1.ObjectMeta{
+			Name:        statsIngressName(domain),
+			Namespace:   domain.Namespace,
+			Annotations: map[string]string{corev1alpha1.AnnotationAdopt: "true"},
+		},
+	}
+
+	r := createReconciler(t, checker.NewFakeDNSChecker(checker.WithAll(true)), domain, leftover)
+	reconcileObject(t, r, domain)
+
+	ingress := getStatsIngress(t, r, domain)
+	assert.True(t, v1.IsControlledBy(ingress, domain), "should have adopted the ingress")
+	assert.Equal(t, buildIngressSpec(domain), ingress.Spec)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.True(t, meta.IsStatusConditionTrue(domain.Status.Conditions, corev1alpha1.ConditionIngressReady))
+}
+
+func TestDisabledIngressIsDeleted(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	r := createReconciler(t, checker.NewFakeDNSChecker(checker.WithAll(true)), domain)
+	reconcileObject(t, r, domain)
+	getStatsIngress(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	disabled := false
+	domain.Spec.Ingress.Enabled = &disabled
+	require.NoError(t, r.Update(ctx, domain))
+	reconcileObject(t, r, domain)
+
+	ingress := &netwrkingv1.Ingress{}
+	key := types.NamespacedName{Name: statsIngressName(domain), Namespace: domain.Namespace}
+	assert.True(t, apierrors.IsNotFound(r.Get(ctx, key, ingress)), "should have deleted the ingress")
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	cond := meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionIngressReady)
+	require.NotNil(t, cond)
+	assert.Equal(t, corev1alpha1.ReasonIngressDisabled, cond.Reason)
+}
+
+func TestDomainEvents(t *testing.T) {
+	domain := createDomain(t)
+	dnsChecker := checker.NewFakeDNSChecker(
+		checker.WithAll(true),
+		checker.WithSPFStats(checker.DNSCheckStats{CntErr: 1, Err: errors.New("i/o timeout")}),
+	)
+	r := createReconciler(t, dnsChecker, domain)
+	recorder := record.NewFakeRecorder(10)
+	r.Recorder = recorder
+
+	reconcileObject(t, r, domain)
+	assert.ElementsMatch(t, []string{
+		"Normal DKIMVerified DKIM record is verified",
+		"Normal StatsDNSVerified stats CNAME record is verified",
+		"Warning SPFCheckFailed SPF record could not be checked: i/o timeout",
+		"Normal IngressCreated created stats Ingress example-stats",
+	}, recordedEvents(recorder))
+
+	// unchanged checks are not reported again
+	reconcileObject(t, r, domain)
+	assert.Empty(t, recordedEvents(recorder))
+
+	dnsChecker.Set(checker.WithStats(false))
+	reconcileObject(t, r, domain)
+	assert.ElementsMatch(t, []string{
+		"Warning StatsDNSNotVerified stats CNAME record is not verified",
+		"Normal IngressDeleted deleted stats Ingress example-stats",
+	}, recordedEvents(recorder))
+}
+
+func TestDomainMetrics(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	domain.Spec.DomainName = "metrics.example.com"
+	r := createReconciler(t, checker.NewFakeDNSChecker(
+		checker.WithAll(true),
+		checker.WithSPFStats(checker.DNSCheckStats{CntErr: 1, Err: fmt.Errorf("lookup: %w", checker.ErrLookupTimeout)}),
+	), domain)
+
+	spfTimeouts := testutil.ToFloat64(dnsCheckErrors.WithLabelValues("spf", checker.ErrorClassTimeout))
+	reconciles := testutil.ToFloat64(statsRouteReconciles.WithLabelValues("success"))
+	reconcileObject(t, r, domain)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(domainDNSVerified.WithLabelValues("metrics.example.com", "dkim")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(domainDNSVerified.WithLabelValues("metrics.example.com", "spf")))
+	assert.Equal(t, spfTimeouts+1, testutil.ToFloat64(dnsCheckErrors.WithLabelValues("spf", checker.ErrorClassTimeout)))
+	assert.Equal(t, reconciles+1, testutil.ToFloat64(statsRouteReconciles.WithLabelValues("success")))
+
+	// the series of a renamed domain are dropped
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	domain.Spec.DomainName = "renamed.example.com"
+	require.NoError(t, r.Update(ctx, domain))
+	reconcileObject(t, r, domain)
+	assert.Zero(t, domainDNSVerified.DeletePartialMatch(prometheus.Labels{"domain": "metrics.example.com"}))
+	assert.Equal(t, 1.0, testutil.ToFloat64(domainDNSVerified.WithLabelValues("renamed.example.com", "dkim")))
+
+	// and so are the ones of a deleted domain, without the cleanup finalizer
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	require.NotContains(t, domain.Finalizers, cleanupFinalizer)
+	require.NoError(t, r.Delete(ctx, domain))
+	reconcileObject(t, r, domain)
+	assert.Zero(t, domainDNSVerified.DeletePartialMatch(prometheus.Labels{"domain": "renamed.example.com"}))
+}
+
+func recordedEvents(recorder *record.FakeRecorder) []string {
+	events := []string{}
+	for {
+		select {
+		case event := <-recorder.Events:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func TestTransitionRequeuesQuickly(t *testing.T) {
+	domain := createDomain(t)
+	dnsChecker := checker.NewFakeDNSChecker(checker.WithAll(true))
+	r := createReconciler(t, dnsChecker, domain)
+
+	res := reconcileObject(t, r, domain)
+	assert.Equal(t, transitionRecheckInterval, res.RequeueAfter, "should recheck soon after the first verification")
+
+	res = reconcileObject(t, r, domain)
+	assert.Equal(t, corev1alpha1.DefaultCheckInterval, res.RequeueAfter, "should settle once two reconciles agree")
+
+	dnsChecker.Set(checker.WithDKIM(false))
+	res = reconcileObject(t, r, domain)
+	assert.LessOrEqual(t, res.RequeueAfter, transitionRecheckInterval, "should recheck soon after a regression")
+}
+
+func TestSuspendedDomain(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	domain.Spec.Suspend = true
+	dnsChecker := checker.NewFakeDNSChecker(checker.WithAll(true))
+	r := createReconciler(t, dnsChecker, domain)
+
+	res := reconcileObject(t, r, domain)
+	assert.Zero(t, res.RequeueAfter, "should not requeue while suspended")
+	assert.Empty(t, dnsChecker.Calls(), "should not check the dns")
+	err := r.Get(ctx, types.NamespacedName{Name: statsIngressName(domain), Namespace: domain.Namespace}, &netwrkingv1.Ingress{})
+	assert.True(t, apierrors.IsNotFound(err), "should not create the ingress: %v", err)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.True(t, meta.IsStatusConditionTrue(domain.Status.Conditions, corev1alpha1.ConditionSuspended))
+
+	domain.Spec.Suspend = false
+	require.NoError(t, r.Update(ctx, domain))
+	res = reconcileObject(t, r, domain)
+	assert.NotZero(t, res.RequeueAfter)
+	assert.NotEmpty(t, dnsChecker.Calls())
+	getStatsIngress(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.Nil(t, meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionSuspended))
+}
+
+func TestPatchStatusConflict(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	r := createReconciler(t, checker.NewFakeDNSChecker(checker.WithAll(true)), domain)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	base := domain.DeepCopy()
+
+	// another writer updates the status after the domain was read
+	other := base.DeepCopy()
+	other.Status.LastRecheck = "from-another-writer"
+	require.NoError(t, r.Status().Update(ctx, other))
+
+	domain.Status.FailedChecks = 3
+	require.NoError(t, r.patchStatus(ctx, domain, base))
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.Equal(t, 3, domain.Status.FailedChecks)
+	assert.Equal(t, "from-another-writer", domain.Status.LastRecheck, "should keep the fields of the other writer")
+}
+
+func TestPatchStatusUnchanged(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	r := createReconciler(t, checker.NewFakeDNSChecker(checker.WithAll(true)), domain)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	resourceVersion := domain.ResourceVersion
+
+	require.NoError(t, r.patchStatus(ctx, domain, domain.DeepCopy()))
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.Equal(t, resourceVersion, domain.ResourceVersion, "should not write an unchanged status")
+}
+
+func TestStatusWritesSkipped(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2023, 6, 1, 8, 0, 0, 0, time.UTC)
+
+	domain := createDomain(t)
+	r := createReconciler(t, checker.NewFakeDNSChecker(), domain)
+	r.clock = func() time.Time { return now }
+
+	// the backoff reaches the unverified check interval after a few
+	// failed checks
+	for i := 0; i < 4; i++ {
+		reconcileObject(t, r, domain)
+		now = now.Add(time.Minute)
+	}
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	resourceVersion := domain.ResourceVersion
+	skipped := testutil.ToFloat64(statusWritesSkipped)
+
+	res := reconcileObject(t, r, domain)
+	assert.LessOrEqual(t, res.RequeueAfter, corev1alpha1.DefaultUnverifiedCheckInterval)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.Equal(t, resourceVersion, domain.ResourceVersion, "should not write a status with the same outcome")
+	assert.Equal(t, skipped+1, testutil.ToFloat64(statusWritesSkipped))
+
+	now = now.Add(lastCheckRefreshInterval)
+	reconcileObject(t, r, domain)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.NotEqual(t, resourceVersion, domain.ResourceVersion, "should refresh the check time")
+	assert.Equal(t, now, domain.Status.LastCheckTime.Time.UTC())
+	resourceVersion = domain.ResourceVersion
+
+	now = now.Add(time.Minute)
+	r.DNSChecker = checker.NewFakeDNSChecker(checker.WithAll(true))
+	reconcileObject(t, r, domain)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.NotEqual(t, resourceVersion, domain.ResourceVersion, "should write the new outcome")
+	assert.Zero(t, domain.Status.FailedChecks)
+}
+
+func TestRecheckAnnotationIsOneShot(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	r := createReconciler(t, checker.NewFakeDNSChecker(), domain)
+
+	reconcileObject(t, r, domain)
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.Equal(t, 2, domain.Status.FailedChecks)
+
+	domain.Annotations = map[string]string{corev1alpha1.AnnotationRecheck: "2023-05-01T10:00:00Z"}
+	require.NoError(t, r.Update(ctx, domain))
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.Equal(t, "2023-05-01T10:00:00Z", domain.Status.LastRecheck, "should ack the nonce")
+	assert.Equal(t, 1, domain.Status.FailedChecks, "should restart the backoff")
+
+	// the acked nonce does not restart the backoff again
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.Equal(t, 2, domain.Status.FailedChecks)
+}
+
+func TestRecheckNowAnnotationIsCleared(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	r := createReconciler(t, checker.NewFakeDNSChecker(), domain)
+
+	reconcileObject(t, r, domain)
+	reconcileObject(t, r, domain)
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+		domain.Annotations = map[string]string{corev1alpha1.AnnotationRecheck: corev1alpha1.RecheckNow}
+		require.NoError(t, r.Update(ctx, domain))
+		reconcileObject(t, r, domain)
+
+		require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+		assert.Equal(t, 1, domain.Status.FailedChecks, "should restart the backoff")
+		assert.NotContains(t, domain.Annotations, corev1alpha1.AnnotationRecheck, "should clear the annotation")
+
+		reconcileObject(t, r, domain)
+	}
+}
+
+func TestBuildIngressSpecStatsHostAndPath(t *testing.T) {
+	domain := createDomain(t)
+
+	spec := buildIngressSpec(domain)
+	assert.Equal(t, "stats.example.com", spec.Rules[0].Host)
+	assert.Equal(t, "/stats", spec.Rules[0].HTTP.Paths[0].Path)
+
+	domain.Spec.StatsHost = "track.example.com"
+	domain.Spec.StatsPath = "/"
+
+	spec = buildIngressSpec(domain)
+	assert.Equal(t, "track.example.com", spec.Rules[0].Host)
+	assert.Equal(t, "/", spec.Rules[0].HTTP.Paths[0].Path)
+	assert.Equal(t, []string{"track.example.com"}, spec.TLS[0].Hosts)
+	assert.Nil(t, spec.IngressClassName, "should use the cluster default class")
+
+	domain.Spec.Ingress.ClassName = "nginx"
+	spec = buildIngressSpec(domain)
+	require.NotNil(t, spec.IngressClassName)
+	assert.Equal(t, "nginx", *spec.IngressClassName)
+}
+
+func TestBuildIngressSpecExtraHostsAndPaths(t *testing.T) {
+	domain := createDomain(t)
+	domain.Spec.Ingress.ExtraHosts = []string{"stats.example.org"}
+	domain.Spec.Ingress.ExtraPaths = []string{"/open", "/click"}
+
+	spec := buildIngressSpec(domain)
+	require.Len(t, spec.Rules, 2)
+	assert.Equal(t, "stats.example.com", spec.Rules[0].Host)
+	assert.Equal(t, "stats.example.org", spec.Rules[1].Host)
+	for _, rule := range spec.Rules {
+		require.Len(t, rule.HTTP.Paths, 3)
+		assert.Equal(t, "/stats", rule.HTTP.Paths[0].Path)
+		assert.Equal(t, "/open", rule.HTTP.Paths[1].Path)
+		assert.Equal(t, "/click", rule.HTTP.Paths[2].Path)
+		assert.Equal(t, "kannon-stats", rule.HTTP.Paths[2].Backend.Service.Name)
+	}
+	assert.Equal(t, []string{"stats.example.com", "stats.example.org"}, spec.TLS[0].Hosts)
+}
+
+func TestBuildIngressSpecStatsHosts(t *testing.T) {
+	domain := createDomain(t)
+	domain.Spec.Stats = &corev1alpha1.DomainStatsSpec{Hosts: []string{"click.example.com", "open.example.com"}}
+	domain.Spec.Ingress.ExtraHosts = []string{"open.example.com", "stats.example.org"}
+
+	spec := buildIngressSpec(domain)
+	require.Len(t, spec.Rules, 3)
+	assert.Equal(t, "click.example.com", spec.Rules[0].Host)
+	assert.Equal(t, "open.example.com", spec.Rules[1].Host)
+	assert.Equal(t, "stats.example.org", spec.Rules[2].Host)
+	assert.Equal(t, []string{"click.example.com", "open.example.com", "stats.example.org"}, spec.TLS[0].Hosts)
+	assert.Equal(t, "click.example.com-tls", spec.TLS[0].SecretName)
+
+	domain.Spec.Stats = &corev1alpha1.DomainStatsSpec{SubdomainPrefix: "track"}
+	domain.Spec.Ingress.ExtraHosts = nil
+	spec = buildIngressSpec(domain)
+	require.Len(t, spec.Rules, 1)
+	assert.Equal(t, "track.example.com", spec.Rules[0].Host)
+}
+
+func TestStatsRoutePaths(t *testing.T) {
+	domain := createDomain(t)
+	domain.Spec.Ingress.ExtraPaths = []string{"/open"}
+	domain.Spec.Stats = &corev1alpha1.DomainStatsSpec{
+		Paths:       []string{"/o/", "/c/", "/px/"},
+		RewriteHost: "stats.kannon.example.com",
+	}
+
+	spec := buildIngressSpec(domain)
+	require.Len(t, spec.Rules, 1)
+	paths := []string{}
+	for _, path := range spec.Rules[0].HTTP.Paths {
+		paths = append(paths, path.Path)
+	}
+	assert.Equal(t, []string{"/o/", "/c/", "/px/"}, paths, "the paths should replace the stats and extra paths")
+	assert.Equal(t, "stats.kannon.example.com", ingressAnnotations(domain)["nginx.ingress.kubernetes.io/upstream-vhost"])
+
+	domain.Spec.Gateway = &corev1alpha1.DomainGatewaySpec{Name: "public"}
+	route := buildHTTPRouteSpec(domain)
+	require.Len(t, route.Rules, 1)
+	require.Len(t, route.Rules[0].Matches, 3)
+	assert.Equal(t, "/px/", *route.Rules[0].Matches[2].Path.Value)
+	require.Len(t, route.Rules[0].Filters, 1)
+	assert.Equal(t, gatewayv1beta1.HTTPRouteFilterURLRewrite, route.Rules[0].Filters[0].Type)
+	assert.Equal(t, gatewayv1beta1.PreciseHostname("stats.kannon.example.com"), *route.Rules[0].Filters[0].URLRewrite.Hostname)
+
+	// without a rewrite the host of the request is forwarded
+	domain.Spec.Stats.RewriteHost = ""
+	assert.NotContains(t, ingressAnnotations(domain), "nginx.ingress.kubernetes.io/upstream-vhost")
+	assert.Empty(t, buildHTTPRouteSpec(domain).Rules[0].Filters)
+}
+
+func TestExplicitTLSSecret(t *testing.T) {
+	domain := createDomain(t)
+	domain.Spec.Ingress.Annotations = map[string]string{
+		"cert-manager.io/cluster-issuer":        "letsencrypt",
+		"nginx.ingress.kubernetes.io/limit-rps": "10",
+	}
+	domain.Spec.TLS = &corev1alpha1.DomainTLSSpec{SecretName: "stats-cert"}
+
+	r := createReconciler(t, checker.NewFakeDNSChecker(checker.WithAll(true)), domain)
+	reconcileObject(t, r, domain)
+
+	ingress := getStatsIngress(t, r, domain)
+	require.Len(t, ingress.Spec.TLS, 1)
+	assert.Equal(t, "stats-cert", ingress.Spec.TLS[0].SecretName)
+	assert.Equal(t, []string{"stats.example.com"}, ingress.Spec.TLS[0].Hosts)
+	assert.NotContains(t, ingress.Annotations, "cert-manager.io/cluster-issuer", "explicit secret should win over the issuer")
+	assert.Equal(t, "10", ingress.Annotations["nginx.ingress.kubernetes.io/limit-rps"])
+}
+
+func TestClusterIssuerCertificate(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	domain.Spec.TLS = &corev1alpha1.DomainTLSSpec{ClusterIssuer: "letsencrypt"}
+
+	r := createReconciler(t, checker.NewFakeDNSChecker(checker.WithAll(true)), domain)
+	reconcileObject(t, r, domain)
+
+	ingress := getStatsIngress(t, r, domain)
+	assert.Equal(t, "letsencrypt", ingress.Annotations["cert-manager.io/cluster-issuer"])
+	require.Len(t, ingress.Spec.TLS, 1)
+	assert.Equal(t, "stats.example.com-tls", ingress.Spec.TLS[0].SecretName)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	cond := meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionCertificateReady)
+	require.NotNil(t, cond)
+	assert.Equal(t, v1.ConditionFalse, cond.Status)
+	assert.Equal(t, corev1alpha1.ReasonCertificateMissing, cond.Reason)
+
+	// cert-manager issues the certificate
+	notAfter := time.Now().Add(90 * 24 * time.Hour).Truncate(time.Second)
+	require.NoError(t, r.Create(ctx, &corev1.Secret{
+		ObjectMeta: v1.ObjectMeta{Name: "stats.example.com-tls", Namespace: "default"},
+		Type:       corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       createCertificate(t, "stats.example.com", notAfter),
+			corev1.TLSPrivateKeyKey: []byte("key"),
+		},
+	}))
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	cond = meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionCertificateReady)
+	require.NotNil(t, cond)
+	assert.Equal(t, v1.ConditionTrue, cond.Status)
+	assert.Equal(t, corev1alpha1.ReasonCertificateValid, cond.Reason)
+	require.NotNil(t, domain.Status.Certificate)
+	require.NotNil(t, domain.Status.Certificate.NotAfter)
+	assert.True(t, notAfter.Equal(domain.Status.Certificate.NotAfter.Time))
+}
+
+func TestCertificateHostMismatch(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	domain.Spec.TLS = &corev1alpha1.DomainTLSSpec{SecretName: "stats-cert"}
+	secret := &corev1.Secret{
+		ObjectMeta: v1.ObjectMeta{Name: "stats-cert", Namespace: "default"},
+		Data: map[string][]byte{
+			corev1.TLSCertKey: createCertificate(t, "other.example.com", time.Now().Add(time.Hour)),
+		},
+	}
+
+	r := createReconciler(t, checker.NewFakeDNSChecker(checker.WithAll(true)), domain, secret)
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	cond := meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionCertificateReady)
+	require.NotNil(t, cond)
+	assert.Equal(t, v1.ConditionFalse, cond.Status)
+	assert.Equal(t, corev1alpha1.ReasonCertificateMismatch, cond.Reason)
+}
+
+func TestGatewayAPIRouting(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	r := createReconciler(t, checker.NewFakeDNSChecker(checker.WithAll(true)), domain)
+	r.GatewayAPI = true
+	reconcileObject(t, r, domain)
+	getStatsIngress(t, r, domain)
+
+	// switching the routing replaces the ingress with an HTTPRoute
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	domain.Spec.Routing = corev1alpha1.RoutingGatewayAPI
+	domain.Spec.Gateway = &corev1alpha1.DomainGatewaySpec{Name: "public", Namespace: "gateways"}
+	require.NoError(t, r.Update(ctx, domain))
+	reconcileObject(t, r, domain)
+
+	key := types.NamespacedName{Name: "example-stats", Namespace: "default"}
+	err := r.Get(ctx, key, &netwrkingv1.Ingress{})
+	assert.True(t, apierrors.IsNotFound(err), "the ingress should be deleted: %v", err)
+
+	route := &gatewayv1beta1.HTTPRoute{}
+	require.NoError(t, r.Get(ctx, key, route))
+	assert.True(t, v1.IsControlledBy(route, domain), "the route should be owned by the domain")
+	require.Len(t, route.Spec.ParentRefs, 1)
+	assert.Equal(t, gatewayv1beta1.ObjectName("public"), route.Spec.ParentRefs[0].Name)
+	require.NotNil(t, route.Spec.ParentRefs[0].Namespace)
+	assert.Equal(t, gatewayv1beta1.Namespace("gateways"), *route.Spec.ParentRefs[0].Namespace)
+	assert.Equal(t, []gatewayv1beta1.Hostname{"stats.example.com"}, route.Spec.Hostnames)
+	require.Len(t, route.Spec.Rules, 1)
+	require.Len(t, route.Spec.Rules[0].Matches, 1)
+	assert.Equal(t, "/stats", *route.Spec.Rules[0].Matches[0].Path.Value)
+	require.Len(t, route.Spec.Rules[0].BackendRefs, 1)
+	assert.Equal(t, gatewayv1beta1.ObjectName("kannon-stats"), route.Spec.Rules[0].BackendRefs[0].Name)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	cond := meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionIngressReady)
+	require.NotNil(t, cond)
+	assert.Equal(t, v1.ConditionTrue, cond.Status)
+	assert.Nil(t, meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionCertificateReady),
+		"the certificate is terminated by the gateway")
+
+	// an up to date route is not rewritten
+	version := route.ResourceVersion
+	reconcileObject(t, r, domain)
+	require.NoError(t, r.Get(ctx, key, route))
+	assert.Equal(t, version, route.ResourceVersion)
+}
+
+func TestHTTPRouteDriftIsCorrected(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	domain.Spec.Routing = corev1alpha1.RoutingGatewayAPI
+	domain.Spec.Gateway = &corev1alpha1.DomainGatewaySpec{Name: "public", Namespace: "gateways"}
+	r := createReconciler(t, checker.NewFakeDNSChecker(checker.WithAll(true)), domain)
+	r.GatewayAPI = true
+	reconcileObject(t, r, domain)
+
+	// someone edits the route by hand
+	key := types.NamespacedName{Name: "example-stats", Namespace: "default"}
+	route := &gatewayv1beta1.HTTPRoute{}
+	require.NoError(t, r.Get(ctx, key, route))
+	route.Spec.Hostnames = []gatewayv1beta1.Hostname{"other.example.com"}
+	other := "/other"
+	route.Spec.Rules[0].Matches[0].Path.Value = &other
+	route.Spec.Rules[0].BackendRefs[0].Name = "other"
+	require.NoError(t, r.Update(ctx, route))
+	reconcileObject(t, r, domain)
+
+	route = &gatewayv1beta1.HTTPRoute{}
+	require.NoError(t, r.Get(ctx, key, route))
+	assert.Equal(t, buildHTTPRouteSpec(domain), route.Spec)
+	assert.True(t, v1.IsControlledBy(route, domain))
+}
+
+func TestGatewayAPIRoutingDisabled(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	domain.Spec.Routing = corev1alpha1.RoutingGatewayAPI
+	domain.Spec.Gateway = &corev1alpha1.DomainGatewaySpec{Name: "public"}
+
+	r := createReconciler(t, checker.NewFakeDNSChecker(checker.WithAll(true)), domain)
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	cond := meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionIngressReady)
+	require.NotNil(t, cond)
+	assert.Equal(t, v1.ConditionFalse, cond.Status)
+	assert.Equal(t, corev1alpha1.ReasonGatewayAPIDisabled, cond.Reason)
+
+	err := r.Get(ctx, types.NamespacedName{Name: "example-stats", Namespace: "default"}, &netwrkingv1.Ingress{})
+	assert.True(t, apierrors.IsNotFound(err), "no ingress should be created: %v", err)
+}
+
+func TestFailedLookupKeepsLastKnownGood(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	dnsChecker := checker.NewFakeDNSChecker(checker.WithAll(true))
+	r := createReconciler(t, dnsChecker, domain)
+	verifiedAt := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+	r.clock = func() time.Time { return verifiedAt }
+	reconcileObject(t, r, domain)
+
+	checkedAt := verifiedAt.Add(time.Hour)
+	r.clock = func() time.Time { return checkedAt }
+	dnsChecker.Set(checker.WithSPFStats(checker.DNSCheckStats{CntKO: 1, CntErr: 1, Err: errors.New("i/o timeout")}))
+	res := reconcileObject(t, r, domain)
+	assert.Less(t, res.RequeueAfter, corev1alpha1.DefaultCheckInterval, "should retry the failed check")
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	spf := domain.Status.DNS.SPF
+	assert.Equal(t, corev1alpha1.CheckStateUnknown, spf.State)
+	assert.True(t, spf.OK, "should keep the last known good verdict")
+	assert.Equal(t, "i/o timeout", spf.Message)
+	require.NotNil(t, spf.CheckedAt)
+	assert.True(t, checkedAt.Equal(spf.CheckedAt.Time))
+	require.NotNil(t, spf.LastVerified)
+	assert.True(t, verifiedAt.Equal(spf.LastVerified.Time))
+	assert.True(t, domain.Status.DNS.DKIM.OK, "other records are not affected")
+
+	cond := meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionSPFVerified)
+	require.NotNil(t, cond)
+	assert.Equal(t, v1.ConditionUnknown, cond.Status)
+	assert.Equal(t, "SPF record could not be checked: i/o timeout, last verified at 2023-03-01T12:00:00Z", cond.Message)
+	assert.True(t, meta.IsStatusConditionTrue(domain.Status.Conditions, corev1alpha1.ConditionReady))
+	getStatsIngress(t, r, domain)
+
+	// a record found missing is no longer trusted
+	dnsChecker.Set(checker.WithSPF(false))
+	reconcileObject(t, r, domain)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.False(t, domain.Status.DNS.SPF.OK)
+	assert.True(t, verifiedAt.Equal(domain.Status.DNS.SPF.LastVerified.Time))
+	assert.True(t, meta.IsStatusConditionFalse(domain.Status.Conditions, corev1alpha1.ConditionReady))
+}
+
+func TestReadyConditionReason(t *testing.T) {
+	domain := createDomain(t)
+	verified := corev1alpha1.DNSStatusStats{State: corev1alpha1.CheckStateVerified, OK: true}
+
+	domain.Status.DNS = corev1alpha1.DNSStatus{
+		DKIM:  verified,
+		SPF:   corev1alpha1.SPFStatus{DNSStatusStats: corev1alpha1.DNSStatusStats{State: corev1alpha1.CheckStateUnknown, Message: "timeout"}},
+		Stats: corev1alpha1.DNSStatusStats{State: corev1alpha1.CheckStateMissing},
+	}
+	cond := readyCondition(domain)
+	assert.Equal(t, v1.ConditionFalse, cond.Status)
+	assert.Equal(t, corev1alpha1.ReasonSPFCheckFailed, cond.Reason)
+
+	domain.Status.DNS.SPF.DNSStatusStats = verified
+	cond = readyCondition(domain)
+	assert.Equal(t, corev1alpha1.ReasonStatsDNSNotVerified, cond.Reason)
+
+	domain.Status.DNS.Stats = verified
+	cond = readyCondition(domain)
+	assert.Equal(t, v1.ConditionTrue, cond.Status)
+	assert.Equal(t, corev1alpha1.ReasonDNSVerified, cond.Reason)
+}
+
+func TestCheckConditions(t *testing.T) {
+	domain := createDomain(t)
+	r := createReconciler(t, checker.NewFakeDNSChecker(
+		checker.WithDKIM(true),
+		checker.WithSPFStats(checker.DNSCheckStats{CntKO: 1, CntErr: 1, Err: errors.New("timeout")}),
+	), domain)
+
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(context.Background(), client.ObjectKeyFromObject(domain), domain))
+	conds := domain.Status.Conditions
+
+	assert.True(t, meta.IsStatusConditionTrue(conds, corev1alpha1.ConditionDKIMVerified))
+
+	spf := meta.FindStatusCondition(conds, corev1alpha1.ConditionSPFVerified)
+	require.NotNil(t, spf)
+	assert.Equal(t, v1.ConditionUnknown, spf.Status)
+	assert.Equal(t, corev1alpha1.ReasonSPFCheckFailed, spf.Reason)
+
+	stats := meta.FindStatusCondition(conds, corev1alpha1.ConditionStatsDNSVerified)
+	require.NotNil(t, stats)
+	assert.Equal(t, v1.ConditionFalse, stats.Status)
+	assert.Equal(t, corev1alpha1.ReasonStatsDNSNotVerified, stats.Reason)
+
+	assert.True(t, meta.IsStatusConditionFalse(conds, corev1alpha1.ConditionReady))
+}
+
+func TestDMARCPolicyInStatus(t *testing.T) {
+	domain := createDomain(t)
+	r := createReconciler(t, checker.NewFakeDNSChecker(
+		checker.WithAll(true),
+		checker.WithDMARCStats(checker.DNSCheckStats{CntOK: 1, Value: "reject"}),
+	), domain)
+
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(context.Background(), client.ObjectKeyFromObject(domain), domain))
+	assert.Equal(t, corev1alpha1.CheckStateVerified, domain.Status.DNS.DMARC.State)
+	assert.Equal(t, "reject", domain.Status.DNS.DMARC.Policy)
+}
+
+func TestLastCheckTimeInStatus(t *testing.T) {
+	domain := createDomain(t)
+	dnsChecker := checker.NewFakeDNSChecker(checker.WithAll(true))
+	r := createReconciler(t, dnsChecker, domain)
+	now := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+	r.clock = func() time.Time { return now }
+
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(context.Background(), client.ObjectKeyFromObject(domain), domain))
+	require.NotNil(t, domain.Status.LastCheckTime)
+	assert.True(t, now.Equal(domain.Status.LastCheckTime.Time))
+	require.NotNil(t, domain.Status.DNS.LastCheckTime)
+	assert.True(t, now.Equal(domain.Status.DNS.LastCheckTime.Time))
+	assert.Equal(t, domain.Generation, domain.Status.ObservedGeneration)
+	verifiedAt := now
+
+	// the transition time only moves with the state
+	now = now.Add(time.Hour)
+	reconcileObject(t, r, domain)
+	require.NoError(t, r.Get(context.Background(), client.ObjectKeyFromObject(domain), domain))
+	assert.True(t, now.Equal(domain.Status.DNS.LastCheckTime.Time))
+	require.NotNil(t, domain.Status.DNS.SPF.LastTransitionTime)
+	assert.True(t, verifiedAt.Equal(domain.Status.DNS.SPF.LastTransitionTime.Time))
+
+	now = now.Add(time.Hour)
+	dnsChecker.Set(checker.WithSPF(false))
+	reconcileObject(t, r, domain)
+	require.NoError(t, r.Get(context.Background(), client.ObjectKeyFromObject(domain), domain))
+	assert.True(t, now.Equal(domain.Status.DNS.SPF.LastTransitionTime.Time))
+	assert.True(t, verifiedAt.Equal(domain.Status.DNS.DKIM.LastTransitionTime.Time))
+
+	// a spec change is observed
+	domain.Spec.StatsPrefix = "metrics"
+	domain.Generation++
+	require.NoError(t, r.Update(context.Background(), domain))
+	reconcileObject(t, r, domain)
+	require.NoError(t, r.Get(context.Background(), client.ObjectKeyFromObject(domain), domain))
+	assert.Equal(t, domain.Generation, domain.Status.ObservedGeneration)
+}
+
+func TestMXHostsInStatus(t *testing.T) {
+	domain := createDomain(t)
+	r := createReconciler(t, checker.NewFakeDNSChecker(
+		checker.WithAll(true),
+		checker.WithMXStats(checker.DNSCheckStats{CntKO: 1, Observed: []string{"mx.other.com"}}),
+	), domain)
+
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(context.Background(), client.ObjectKeyFromObject(domain), domain))
+	assert.Equal(t, corev1alpha1.CheckStateMissing, domain.Status.DNS.MX.State)
+	assert.Equal(t, []string{"mx.other.com"}, domain.Status.DNS.MX.Hosts)
+}
+
+func TestSPFPermErrorInStatus(t *testing.T) {
+	domain := createDomain(t)
+	r := createReconciler(t, checker.NewFakeDNSChecker(
+		checker.WithAll(true),
+		checker.WithSPFStats(checker.DNSCheckStats{
+			CntKO:  1,
+			Reason: "SPF record needs more than 10 DNS lookups",
+			SPF:    &checker.SPFEvaluation{Lookups: 11, PermError: "SPF record needs more than 10 DNS lookups"},
+		}),
+	), domain)
+
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(context.Background(), client.ObjectKeyFromObject(domain), domain))
+	assert.Equal(t, corev1alpha1.CheckStateMissing, domain.Status.DNS.SPF.State)
+	assert.Equal(t, 11, domain.Status.DNS.SPF.Lookups)
+	assert.Equal(t, "SPF record needs more than 10 DNS lookups", domain.Status.DNS.SPF.PermError)
+
+	cond := meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionSPFVerified)
+	require.NotNil(t, cond)
+	assert.Equal(t, v1.ConditionFalse, cond.Status)
+	assert.Contains(t, cond.Message, "more than 10 DNS lookups")
+}
+
+func TestResolverResultsInStatus(t *testing.T) {
+	domain := createDomain(t)
+	r := createReconciler(t, checker.NewFakeDNSChecker(
+		checker.WithAll(true),
+		checker.WithSPFStats(checker.DNSCheckStats{
+			CntOK: 1, CntKO: 2, CntErr: 1,
+			Resolvers: []checker.ResolverResult{
+				{Resolver: "1.0.0.1:53", OK: true},
+				{Resolver: "8.8.8.8:53"},
+				{Resolver: "9.9.9.9:53", Err: errors.New("i/o timeout")},
+			},
+		}),
+	), domain)
+
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(context.Background(), client.ObjectKeyFromObject(domain), domain))
+	assert.Equal(t, []corev1alpha1.ResolverStatus{
+		{Resolver: "1.0.0.1:53", State: corev1alpha1.CheckStateVerified},
+		{Resolver: "8.8.8.8:53", State: corev1alpha1.CheckStateMissing},
+		{Resolver: "9.9.9.9:53", State: corev1alpha1.CheckStateUnknown, Message: "i/o timeout"},
+	}, domain.Status.DNS.SPF.Resolvers)
+}
+
+func TestReadyCondition(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	dnsChecker := checker.NewFakeDNSChecker(checker.WithAll(true))
+	r := createReconciler(t, dnsChecker, domain)
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	cond := meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionReady)
+	require.NotNil(t, cond)
+	assert.Equal(t, v1.ConditionTrue, cond.Status)
+	assert.Equal(t, corev1alpha1.ReasonDNSVerified, cond.Reason)
+	assert.Equal(t, "all DNS records are verified", cond.Message)
+
+	dnsChecker.Set(checker.WithSPFStats(checker.DNSCheckStats{CntKO: 1, Reason: "example.com does not include mx.example.com"}))
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	cond = meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionReady)
+	require.NotNil(t, cond)
+	assert.Equal(t, v1.ConditionFalse, cond.Status)
+	assert.Equal(t, corev1alpha1.ReasonSPFNotVerified, cond.Reason)
+	assert.Equal(t, "SPF record is not verified: example.com does not include mx.example.com", cond.Message)
+}
+
+func TestCheckErrorClassInStatus(t *testing.T) {
+	domain := createDomain(t)
+	timeout := fmt.Errorf("lookup example.com: %w", checker.ErrLookupTimeout)
+	r := createReconciler(t, checker.NewFakeDNSChecker(
+		checker.WithAll(true),
+		checker.WithSPFStats(checker.DNSCheckStats{
+			CntErr: 2,
+			Err:    timeout,
+			Resolvers: []checker.ResolverResult{
+				{Resolver: "1.0.0.1:53", Err: timeout},
+				{Resolver: "8.8.8.8:53", Err: fmt.Errorf("lookup example.com: %w", resilience.ErrOpen)},
+			},
+		}),
+	), domain)
+
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(context.Background(), client.ObjectKeyFromObject(domain), domain))
+	spf := domain.Status.DNS.SPF
+	assert.Equal(t, corev1alpha1.CheckStateUnknown, spf.State)
+	assert.Equal(t, corev1alpha1.CheckErrorTimeout, spf.ErrorClass)
+	assert.Equal(t, corev1alpha1.CheckErrorTimeout, spf.Resolvers[0].ErrorClass)
+	assert.Equal(t, corev1alpha1.CheckErrorResolverUnavailable, spf.Resolvers[1].ErrorClass)
+	assert.Empty(t, domain.Status.DNS.DKIM.ErrorClass, "a verified record has no error")
+
+	cond := meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionSPFVerified)
+	require.NotNil(t, cond)
+	assert.Equal(t, v1.ConditionUnknown, cond.Status)
+	assert.Equal(t, "SPF record could not be checked: the resolvers timed out, "+timeout.Error(), cond.Message)
+}
+
+func TestExpectedRecordInStatus(t *testing.T) {
+	domain := createDomain(t)
+	r := createReconciler(t, checker.NewFakeDNSChecker(
+		checker.WithAll(true),
+		checker.WithSPFStats(checker.DNSCheckStats{
+			CntKO:    1,
+			Expected: checker.Record{Type: "TXT", Name: "example.com", Value: "v=spf1 include:mx.example.com ~all"},
+			Observed: []string{"v=spf1 -all"},
+		}),
+	), domain)
+
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(context.Background(), client.ObjectKeyFromObject(domain), domain))
+	spf := domain.Status.DNS.SPF
+	require.NotNil(t, spf.Expected)
+	assert.Equal(t, corev1alpha1.DNSRecord{Type: "TXT", Name: "example.com", Value: "v=spf1 include:mx.example.com ~all"}, *spf.Expected)
+	assert.Equal(t, []string{"v=spf1 -all"}, spf.Observed)
+	assert.Nil(t, domain.Status.DNS.DKIM.Expected, "should omit the expected record when unknown")
+}
+
+func TestDNSEndpointProvisioning(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	domain.Spec.DNS = &corev1alpha1.DomainDNSSpec{AutoProvision: true}
+
+	r := createReconciler(t, checker.NewFakeDNSChecker(
+		checker.WithStatsDNSStats(checker.DNSCheckStats{
+			CntKO:    1,
+			Expected: checker.Record{Type: "CNAME", Name: "stats.example.com", Value: "mx.example.com"},
+		}),
+		checker.WithDKIMStats(checker.DNSCheckStats{
+			CntKO:    1,
+			Expected: checker.Record{Type: "TXT", Name: "selector._domainkey.example.com", Value: "k=rsa; p=publicKey"},
+		}),
+	), domain)
+	r.ExternalDNS = true
+	reconcileObject(t, r, domain)
+
+	endpoint := newDNSEndpoint()
+	key := types.NamespacedName{Name: "example-dns", Namespace: "default"}
+	require.NoError(t, r.Get(ctx, key, endpoint))
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.True(t, v1.IsControlledBy(endpoint, domain), "the dnsendpoint should be owned by the domain")
+
+	endpoints, _, err := unstructured.NestedSlice(endpoint.Object, "spec", "endpoints")
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{
+			"dnsName":    "stats.example.com",
+			"recordType": "CNAME",
+			"recordTTL":  int64(300),
+			"targets":    []interface{}{"mx.example.com"},
+		},
+		map[string]interface{}{
+			"dnsName":    "selector._domainkey.example.com",
+			"recordType": "TXT",
+			"recordTTL":  int64(300),
+			"targets":    []interface{}{"k=rsa; p=publicKey"},
+		},
+	}, endpoints)
+	assert.True(t, meta.IsStatusConditionTrue(domain.Status.Conditions, corev1alpha1.ConditionDNSProvisioned))
+
+	// an up to date dnsendpoint is not rewritten
+	version := endpoint.GetResourceVersion()
+	reconcileObject(t, r, domain)
+	require.NoError(t, r.Get(ctx, key, endpoint))
+	assert.Equal(t, version, endpoint.GetResourceVersion())
+
+	// turning the provisioning off deletes the records
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	domain.Spec.DNS.AutoProvision = false
+	require.NoError(t, r.Update(ctx, domain))
+	reconcileObject(t, r, domain)
+
+	err = r.Get(ctx, key, newDNSEndpoint())
+	assert.True(t, apierrors.IsNotFound(err), "the dnsendpoint should be deleted: %v", err)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.Nil(t, meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionDNSProvisioned))
+}
+
+func TestDNSEndpointProvisioningSharedTXT(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	domain.Spec.DNS = &corev1alpha1.DomainDNSSpec{AutoProvision: true}
+
+	r := createReconciler(t, checker.NewFakeDNSChecker(
+		checker.WithDKIMStats(checker.DNSCheckStats{
+			CntKO:    1,
+			Expected: checker.Record{Type: "TXT", Name: "selector._domainkey.example.com", Value: "k=rsa; p=publicKey"},
+		}),
+		checker.WithSPFStats(checker.DNSCheckStats{
+			CntKO:    1,
+			Expected: checker.Record{Type: "TXT", Name: "example.com", Value: "v=spf1 include:spf.kannon.email ~all"},
+		}),
+	), domain)
+	r.ExternalDNS = true
+	records := map[string][]string{
+		"example.com": {"google-site-verification=token", "heritage=external-dns,external-dns/owner=default"},
+	}
+	r.LookupTXT = func(ctx context.Context, name string) ([]string, error) {
+		values, ok := records[name]
+		if !ok {
+			return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+		}
+		return values, nil
+	}
+
+	dnsNames := func() []string {
+		endpoint := newDNSEndpoint()
+		require.NoError(t, r.Get(ctx, types.NamespacedName{Name: "example-dns", Namespace: "default"}, endpoint))
+		endpoints, _, err := unstructured.NestedSlice(endpoint.Object, "spec", "endpoints")
+		require.NoError(t, err)
+		names := []string{}
+		for _, e := range endpoints {
+			names = append(names, e.(map[string]interface{})["dnsName"].(string))
+		}
+		return names
+	}
+
+	reconcileObject(t, r, domain)
+	assert.Equal(t, []string{"selector._domainkey.example.com"}, dnsNames(), "the apex TXT records of others should not be replaced")
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	cond := meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionDNSProvisioned)
+	require.NotNil(t, cond)
+	assert.Equal(t, v1.ConditionFalse, cond.Status)
+	assert.Equal(t, corev1alpha1.ReasonDNSRecordsShared, cond.Reason)
+	assert.Contains(t, cond.Message, "example.com")
+
+	// the name only has the records of external-dns and the domain
+	records["example.com"] = []string{"v=spf1 include:spf.kannon.email ~all", "heritage=external-dns,external-dns/owner=default"}
+	reconcileObject(t, r, domain)
+	assert.Equal(t, []string{"selector._domainkey.example.com", "example.com"}, dnsNames())
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.True(t, meta.IsStatusConditionTrue(domain.Status.Conditions, corev1alpha1.ConditionDNSProvisioned))
+}
+
+func TestDNSEndpointProvisioningStatsHosts(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	domain.Spec.DNS = &corev1alpha1.DomainDNSSpec{AutoProvision: true}
+	domain.Spec.Stats = &corev1alpha1.DomainStatsSpec{Hosts: []string{"click.example.com", "open.example.com"}}
+
+	r := createReconciler(t, checker.NewFakeDNSChecker(
+		checker.WithStatsDNSStats(checker.DNSCheckStats{
+			CntKO:    1,
+			Expected: checker.Record{Type: "CNAME", Name: "click.example.com", Value: "mx.example.com"},
+		}),
+	), domain)
+	r.ExternalDNS = true
+	reconcileObject(t, r, domain)
+
+	endpoint := newDNSEndpoint()
+	require.NoError(t, r.Get(ctx, types.NamespacedName{Name: "example-dns", Namespace: "default"}, endpoint))
+
+	endpoints, _, err := unstructured.NestedSlice(endpoint.Object, "spec", "endpoints")
+	require.NoError(t, err)
+	names := []string{}
+	for _, e := range endpoints {
+		names = append(names, e.(map[string]interface{})["dnsName"].(string))
+	}
+	assert.Equal(t, []string{"click.example.com", "open.example.com"}, names, "should publish a CNAME per stats host")
+}
+
+func TestDNSEndpointProvisioningDisabled(t *testing.T) {
+	domain := createDomain(t)
+	domain.Spec.DNS = &corev1alpha1.DomainDNSSpec{AutoProvision: true}
+
+	r := createReconciler(t, checker.NewFakeDNSChecker(checker.WithAll(true)), domain)
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(context.Background(), client.ObjectKeyFromObject(domain), domain))
+	cond := meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionDNSProvisioned)
+	require.NotNil(t, cond)
+	assert.Equal(t, v1.ConditionFalse, cond.Status)
+	assert.Equal(t, corev1alpha1.ReasonExternalDNSDisabled, cond.Reason)
+}
+
+func TestPrometheusRuleAlerts(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	domain.Spec.Monitoring = &corev1alpha1.DomainMonitoringSpec{
+		Alerts: true,
+		For:    &v1.Duration{Duration: 30 * time.Minute},
+	}
+
+	r := createReconciler(t, checker.NewFakeDNSChecker(checker.WithAll(true)), domain)
+	r.PrometheusRules = true
+	r.Client = unstructuredApplyClient{r.Client}
+	reconcileObject(t, r, domain)
+
+	rule := newPrometheusRule()
+	key := types.NamespacedName{Name: "example-alerts", Namespace: "default"}
+	require.NoError(t, r.Get(ctx, key, rule))
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.True(t, v1.IsControlledBy(rule, domain), "the prometheusrule should be owned by the domain")
+	assert.True(t, meta.IsStatusConditionTrue(domain.Status.Conditions, corev1alpha1.ConditionAlertsConfigured))
+
+	groups, _, err := unstructured.NestedSlice(rule.Object, "spec", "groups")
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	rules := groups[0].(map[string]interface{})["rules"].([]interface{})
+	require.Len(t, rules, 1)
+	alert := rules[0].(map[string]interface{})
+	assert.Equal(t, "KannonDomainDNSRecordRegressed", alert["alert"])
+	assert.Equal(t, `k8nnon_domain_dns_verified{domain="example.com"} == 0 and max_over_time(k8nnon_domain_dns_verified{domain="example.com"}[1d]) == 1`, alert["expr"])
+	assert.Equal(t, "30m", alert["for"])
+
+	// the rules edited by hand are restored
+	require.NoError(t, unstructured.SetNestedSlice(rule.Object, []interface{}{
+		map[string]interface{}{"name": "edited", "rules": []interface{}{}},
+	}, "spec", "groups"))
+	require.NoError(t, r.Update(ctx, rule))
+	reconcileObject(t, r, domain)
+
+	rule = newPrometheusRule()
+	require.NoError(t, r.Get(ctx, key, rule))
+	restored, _, err := unstructured.NestedSlice(rule.Object, "spec", "groups")
+	require.NoError(t, err)
+	assert.Equal(t, groups, restored)
+	assert.True(t, v1.IsControlledBy(rule, domain))
+
+	// turning the alerts off deletes the rule
+	domain.Spec.Monitoring.Alerts = false
+	require.NoError(t, r.Update(ctx, domain))
+	reconcileObject(t, r, domain)
+
+	err = r.Get(ctx, key, newPrometheusRule())
+	assert.True(t, apierrors.IsNotFound(err), "the prometheusrule should be deleted: %v", err)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.Nil(t, meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionAlertsConfigured))
+}
+
+// unstructuredApplyClient applies the unstructured objects as merge
+// patches, the fake client can't apply the types missing from its scheme.
+type unstructuredApplyClient struct {
+	client.Client
+}
+
+func (c unstructuredApplyClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if _, ok := obj.(*unstructured.Unstructured); ok && patch == client.Apply {
+		return c.Client.Patch(ctx, obj, client.Merge)
+	}
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+func TestPrometheusRuleAlertsDisabled(t *testing.T) {
+	domain := createDomain(t)
+	domain.Spec.Monitoring = &corev1alpha1.DomainMonitoringSpec{Alerts: true}
+
+	r := createReconciler(t, checker.NewFakeDNSChecker(checker.WithAll(true)), domain)
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(context.Background(), client.ObjectKeyFromObject(domain), domain))
+	cond := meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionAlertsConfigured)
+	require.NotNil(t, cond)
+	assert.Equal(t, v1.ConditionFalse, cond.Status)
+	assert.Equal(t, corev1alpha1.ReasonPrometheusOperatorDisabled, cond.Reason)
+}
+
+func TestMTASTSHosting(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	domain.Spec.MTASTS = &corev1alpha1.MTASTSSpec{
+		Enabled: true,
+		Mode:    "enforce",
+		MX:      []string{"mx.example.com"},
+	}
+
+	dnsChecker := checker.NewFakeDNSChecker(checker.WithAll(true))
+	r := createReconciler(t, dnsChecker, domain)
+	r.MTASTSService = "k8nnon-mta-sts.k8nnon-system.svc.cluster.local"
+	r.MTASTSPort = 8082
+	reconcileObject(t, r, domain)
+
+	key := types.NamespacedName{Name: "example-mta-sts", Namespace: "default"}
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+
+	cm := &corev1.ConfigMap{}
+	require.NoError(t, r.Get(ctx, key, cm))
+	assert.True(t, v1.IsControlledBy(cm, domain), "the configmap should be owned by the domain")
+	assert.Equal(t, "version: STSv1\nmode: enforce\nmx: mx.example.com\nmax_age: 604800\n", cm.Data["mta-sts.txt"])
+
+	svc := &corev1.Service{}
+	require.NoError(t, r.Get(ctx, key, svc))
+	assert.Equal(t, corev1.ServiceTypeExternalName, svc.Spec.Type)
+	assert.Equal(t, "k8nnon-mta-sts.k8nnon-system.svc.cluster.local", svc.Spec.ExternalName)
+
+	ing := &netwrkingv1.Ingress{}
+	require.NoError(t, r.Get(ctx, key, ing))
+	assert.Equal(t, "mta-sts.example.com", ing.Spec.Rules[0].Host)
+	assert.Equal(t, "/.well-known/mta-sts.txt", ing.Spec.Rules[0].HTTP.Paths[0].Path)
+	assert.Equal(t, "example-mta-sts", ing.Spec.Rules[0].HTTP.Paths[0].Backend.Service.Name)
+
+	assert.True(t, meta.IsStatusConditionTrue(domain.Status.Conditions, corev1alpha1.ConditionMTASTSConfigured))
+	require.NotNil(t, domain.Status.DNS.MTASTS)
+	assert.True(t, domain.Status.DNS.MTASTS.OK)
+	assert.True(t, domain.Status.DNS.MTASTS.Policy.OK)
+	assert.NotEmpty(t, domain.Status.DNS.MTASTS.PolicyID)
+
+	store := MTASTSPolicyStore(r.Client)
+	policy, ok, err := store(ctx, "mta-sts.example.com")
+	require.NoError(t, err)
+	assert.True(t, ok, "the policy server should find the policy")
+	assert.Equal(t, cm.Data["mta-sts.txt"], policy)
+
+	// turning MTA-STS off deletes the hosting resources
+	domain.Spec.MTASTS.Enabled = false
+	require.NoError(t, r.Update(ctx, domain))
+	reconcileObject(t, r, domain)
+
+	for _, obj := range []client.Object{&corev1.ConfigMap{}, &corev1.Service{}, &netwrkingv1.Ingress{}} {
+		err := r.Get(ctx, key, obj)
+		assert.True(t, apierrors.IsNotFound(err), "the %T should be deleted: %v", obj, err)
+	}
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.Nil(t, meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionMTASTSConfigured))
+	assert.Nil(t, domain.Status.DNS.MTASTS)
+}
+
+func TestMTASTSHostingDisabled(t *testing.T) {
+	domain := createDomain(t)
+	domain.Spec.MTASTS = &corev1alpha1.MTASTSSpec{Enabled: true, MX: []string{"mx.example.com"}}
+
+	r := createReconciler(t, checker.NewFakeDNSChecker(checker.WithAll(true)), domain)
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(context.Background(), client.ObjectKeyFromObject(domain), domain))
+	cond := meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionMTASTSConfigured)
+	require.NotNil(t, cond)
+	assert.Equal(t, v1.ConditionFalse, cond.Status)
+	assert.Equal(t, corev1alpha1.ReasonMTASTSDisabled, cond.Reason)
+}
+
+func TestMTASTSPolicyStoreOwnership(t *testing.T) {
+	ctx := context.Background()
+
+	// another tenant claims the policy host of example.com, with a
+	// ConfigMap controlled by its own Domain and with no owner at all
+	other := createDomain(t)
+	other.Namespace = "tenant"
+	other.UID = "other-uid"
+	other.Spec.DomainName = "other.com"
+
+	policy := func(name string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: v1.ObjectMeta{
+				Name:        name,
+				Namespace:   "tenant",
+				Labels:      map[string]string{mtaSTSPolicyLabel: "true"},
+				Annotations: map[string]string{mtaSTSHostAnnotation: "mta-sts.example.com"},
+			},
+			Data: map[string]string{"mta-sts.txt": "version: STSv1\nmode: none\nmax_age: 86400\n"},
+		}
+	}
+	owned := policy("owned")
+	unowned := policy("unowned")
+
+	r := createReconciler(t, checker.NewFakeDNSChecker(), other, unowned)
+	require.NoError(t, ctrl.SetControllerReference(other, owned, r.Scheme))
+	require.NoError(t, r.Create(ctx, owned))
+
+	store := MTASTSPolicyStore(r.Client)
+	_, ok, err := store(ctx, "mta-sts.example.com")
+	require.NoError(t, err)
+	assert.False(t, ok, "only the Domain of example.com should publish its policy")
+
+	_, ok, err = store(ctx, "mta-sts.other.com")
+	require.NoError(t, err)
+	assert.False(t, ok, "the ConfigMaps are looked up by the host they are annotated with")
+}
+
+func TestStatsNetworkPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	domain.Spec.Stats = &corev1alpha1.DomainStatsSpec{
+		NetworkPolicy: &corev1alpha1.StatsNetworkPolicySpec{Enabled: true},
+	}
+	svc := &corev1.Service{
+		ObjectMeta: v1.ObjectMeta{Name: "kannon-stats", Namespace: "default"},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{"app": "kannon", "component": "stats"},
+			Ports:    []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromString("stats")}},
+		},
+	}
+
+	r := createReconciler(t, checker.NewFakeDNSChecker(checker.WithAll(true)), domain, svc)
+	r.IngressControllerNamespace = "traefik"
+	reconcileObject(t, r, domain)
+
+	key := types.NamespacedName{Name: "example-stats", Namespace: "default"}
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.True(t, meta.IsStatusConditionTrue(domain.Status.Conditions, corev1alpha1.ConditionNetworkPolicyConfigured))
+
+	policy := &netwrkingv1.NetworkPolicy{}
+	require.NoError(t, r.Get(ctx, key, policy))
+	assert.True(t, v1.IsControlledBy(policy, domain), "the networkpolicy should be owned by the domain")
+	assert.Equal(t, "network-policy", policy.Labels[componentLabel])
+	assert.Equal(t, svc.Spec.Selector, policy.Spec.PodSelector.MatchLabels)
+	assert.Equal(t, []netwrkingv1.PolicyType{netwrkingv1.PolicyTypeIngress}, policy.Spec.PolicyTypes)
+	require.Len(t, policy.Spec.Ingress, 1)
+	rule := policy.Spec.Ingress[0]
+	require.Len(t, rule.From, 1)
+	assert.Equal(t, map[string]string{"kubernetes.io/metadata.name": "traefik"}, rule.From[0].NamespaceSelector.MatchLabels)
+	assert.Nil(t, rule.From[0].PodSelector)
+	require.Len(t, rule.Ports, 1)
+	assert.Equal(t, intstr.FromString("stats"), *rule.Ports[0].Port)
+	assert.Equal(t, corev1.ProtocolTCP, *rule.Ports[0].Protocol)
+
+	// the Domain overrides the namespace and narrows the allowed pods
+	domain.Spec.Stats.NetworkPolicy.IngressNamespace = "ingress"
+	domain.Spec.Stats.NetworkPolicy.IngressPodSelector = &v1.LabelSelector{MatchLabels: map[string]string{"app": "controller"}}
+	require.NoError(t, r.Update(ctx, domain))
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, key, policy))
+	peer := policy.Spec.Ingress[0].From[0]
+	assert.Equal(t, map[string]string{"kubernetes.io/metadata.name": "ingress"}, peer.NamespaceSelector.MatchLabels)
+	require.NotNil(t, peer.PodSelector)
+	assert.Equal(t, map[string]string{"app": "controller"}, peer.PodSelector.MatchLabels)
+
+	// turning the policy off deletes it
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	domain.Spec.Stats.NetworkPolicy.Enabled = false
+	require.NoError(t, r.Update(ctx, domain))
+	reconcileObject(t, r, domain)
+
+	err := r.Get(ctx, key, &netwrkingv1.NetworkPolicy{})
+	assert.True(t, apierrors.IsNotFound(err), "the networkpolicy should be deleted: %v", err)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.Nil(t, meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionNetworkPolicyConfigured))
+}
+
+func TestStatsNetworkPolicyMissingService(t *testing.T) {
+	domain := createDomain(t)
+	domain.Spec.Stats = &corev1alpha1.DomainStatsSpec{
+		NetworkPolicy: &corev1alpha1.StatsNetworkPolicySpec{Enabled: true},
+	}
+
+	r := createReconciler(t, checker.NewFakeDNSChecker(checker.WithAll(true)), domain)
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(context.Background(), client.ObjectKeyFromObject(domain), domain))
+	cond := meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionNetworkPolicyConfigured)
+	require.NotNil(t, cond)
+	assert.Equal(t, v1.ConditionFalse, cond.Status)
+	assert.Equal(t, corev1alpha1.ReasonNetworkPolicyFailed, cond.Reason)
+	assert.Equal(t, "the stats service kannon-stats was not found", cond.Message)
+	assert.True(t, meta.IsStatusConditionTrue(domain.Status.Conditions, corev1alpha1.ConditionReady), "the policy does not affect the readiness")
+}
+
+func TestTLSRPTStatus(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	domain.Spec.TLSRPT = &corev1alpha1.TLSRPTSpec{ReportURIs: []string{"mailto:tls@example.com"}}
+
+	dnsChecker := checker.NewFakeDNSChecker(checker.WithAll(true), checker.WithTLSRPT(false))
+	r := createReconciler(t, dnsChecker, domain)
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	require.NotNil(t, domain.Status.DNS.TLSRPT)
+	assert.Equal(t, corev1alpha1.CheckStateMissing, domain.Status.DNS.TLSRPT.State)
+	assert.True(t, meta.IsStatusConditionTrue(domain.Status.Conditions, corev1alpha1.ConditionReady), "tls-rpt should not affect readiness")
+
+	domain.Spec.TLSRPT = nil
+	require.NoError(t, r.Update(ctx, domain))
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.Nil(t, domain.Status.DNS.TLSRPT)
+
+	tlsRPTChecks := 0
+	for _, call := range dnsChecker.Calls() {
+		if call.Method == "CheckDomainTLSRPT" {
+			tlsRPTChecks++
+		}
+	}
+	assert.Equal(t, 1, tlsRPTChecks, "the record should only be checked with spec.tlsRPT")
+}
+
+func TestBIMIStatus(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	domain.Spec.BIMI = &corev1alpha1.BIMISpec{LogoURL: "https://example.com/logo.svg"}
+
+	dnsChecker := checker.NewFakeDNSChecker(checker.WithAll(true), checker.WithBIMIStats(checker.BIMICheckStats{
+		Record:    checker.DNSCheckStats{CntOK: 1},
+		Indicator: checker.DNSCheckStats{CntKO: 1, Reason: "the logo has no title"},
+	}))
+	r := createReconciler(t, dnsChecker, domain)
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	require.NotNil(t, domain.Status.DNS.BIMI)
+	assert.Equal(t, corev1alpha1.CheckStateVerified, domain.Status.DNS.BIMI.State)
+	assert.Equal(t, corev1alpha1.CheckStateMissing, domain.Status.DNS.BIMI.Indicator.State)
+	assert.Equal(t, "the logo has no title", domain.Status.DNS.BIMI.Indicator.Message)
+	assert.Nil(t, domain.Status.DNS.BIMI.VMC, "the certificate is only checked with a vmcURL")
+	assert.True(t, meta.IsStatusConditionTrue(domain.Status.Conditions, corev1alpha1.ConditionReady), "bimi should not affect readiness")
+
+	domain.Spec.BIMI.VMCURL = "https://example.com/vmc.pem"
+	require.NoError(t, r.Update(ctx, domain))
+	dnsChecker.Set(checker.WithBIMI(true))
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	require.NotNil(t, domain.Status.DNS.BIMI.VMC)
+	assert.True(t, domain.Status.DNS.BIMI.VMC.OK)
+	assert.True(t, domain.Status.DNS.BIMI.Indicator.OK)
+
+	domain.Spec.BIMI = nil
+	require.NoError(t, r.Update(ctx, domain))
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.Nil(t, domain.Status.DNS.BIMI)
+}
+
+func TestPTRStatus(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	domain.Spec.SendingIPs = []string{"192.0.2.1", "2001:db8::1"}
+
+	dnsChecker := checker.NewFakeDNSChecker(checker.WithAll(true),
+		checker.WithPTRStats("192.0.2.1", checker.DNSCheckStats{CntOK: 1, Value: "out1.mx.example.com"}),
+		checker.WithPTRStats("2001:db8::1", checker.DNSCheckStats{CntKO: 1, Reason: "2001:db8::1 has no PTR record"}),
+	)
+	r := createReconciler(t, dnsChecker, domain)
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	require.Len(t, domain.Status.DNS.PTR, 2)
+	assert.Equal(t, "192.0.2.1", domain.Status.DNS.PTR[0].IP)
+	assert.True(t, domain.Status.DNS.PTR[0].OK)
+	assert.Equal(t, "out1.mx.example.com", domain.Status.DNS.PTR[0].Host)
+	assert.Equal(t, "2001:db8::1", domain.Status.DNS.PTR[1].IP)
+	assert.Equal(t, corev1alpha1.CheckStateMissing, domain.Status.DNS.PTR[1].State)
+	assert.Equal(t, "2001:db8::1 has no PTR record", domain.Status.DNS.PTR[1].Message)
+	assert.True(t, meta.IsStatusConditionTrue(domain.Status.Conditions, corev1alpha1.ConditionReady), "the reverse dns should not affect readiness")
+
+	domain.Spec.SendingIPs = nil
+	require.NoError(t, r.Update(ctx, domain))
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.Empty(t, domain.Status.DNS.PTR)
+}
+
+func TestAdditionalDomains(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	domain.Spec.AdditionalDomains = []string{"news.example.com", "example.org"}
+
+	dnsChecker := checker.NewFakeDNSChecker(checker.WithAll(true),
+		checker.WithDomainSPFStats("example.org", checker.DNSCheckStats{CntKO: 1, Reason: "example.org does not include mx.example.com"}),
+	)
+	r := createReconciler(t, dnsChecker, domain)
+	reconcileObject(t, r, domain)
+
+	checked := map[string][]string{}
+	for _, call := range dnsChecker.Calls() {
+		checked[call.Method] = append(checked[call.Method], call.Domain)
+	}
+	assert.ElementsMatch(t, []string{"example.com", "news.example.com", "example.org"}, checked["CheckDomainDKIM"])
+	assert.ElementsMatch(t, []string{"example.com", "news.example.com", "example.org"}, checked["CheckDomainSPF"])
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	require.Len(t, domain.Status.DNS.AdditionalDomains, 2)
+	news, org := domain.Status.DNS.AdditionalDomains[0], domain.Status.DNS.AdditionalDomains[1]
+	assert.Equal(t, "news.example.com", news.Domain)
+	assert.True(t, news.DKIM.OK)
+	assert.True(t, news.SPF.OK)
+	assert.Equal(t, "example.org", org.Domain)
+	assert.True(t, org.DKIM.OK)
+	assert.Equal(t, corev1alpha1.CheckStateMissing, org.SPF.State)
+
+	cond := meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionAdditionalDomainsVerified)
+	require.NotNil(t, cond)
+	assert.Equal(t, v1.ConditionFalse, cond.Status)
+	assert.Equal(t, corev1alpha1.ReasonAdditionalDomainsNotVerified, cond.Reason)
+	assert.Equal(t, "the SPF record of example.org is not verified: example.org does not include mx.example.com", cond.Message)
+	assert.True(t, meta.IsStatusConditionTrue(domain.Status.Conditions, corev1alpha1.ConditionReady), "the additional domains should not affect readiness")
+
+	dnsChecker.Set(checker.WithDomainSPFStats("example.org", checker.DNSCheckStats{CntOK: 1}))
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.True(t, meta.IsStatusConditionTrue(domain.Status.Conditions, corev1alpha1.ConditionAdditionalDomainsVerified))
+
+	domain.Spec.AdditionalDomains = nil
+	require.NoError(t, r.Update(ctx, domain))
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.Empty(t, domain.Status.DNS.AdditionalDomains)
+	assert.Nil(t, meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionAdditionalDomainsVerified))
+}
+
+func TestDNSEndpointProvisioningAdditionalDomains(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	domain.Spec.DNS = &corev1alpha1.DomainDNSSpec{AutoProvision: true}
+	domain.Spec.AdditionalDomains = []string{"news.example.com"}
+
+	r := createReconciler(t, checker.NewFakeDNSChecker(
+		checker.WithDomainDKIMStats("news.example.com", checker.DNSCheckStats{
+			CntKO:    1,
+			Expected: checker.Record{Type: "TXT", Name: "selector._domainkey.news.example.com", Value: "v=DKIM1; k=rsa; p=cHVibGljS2V5"},
+		}),
+		checker.WithDomainSPFStats("news.example.com", checker.DNSCheckStats{
+			CntKO:    1,
+			Expected: checker.Record{Type: "TXT", Name: "news.example.com", Value: "v=spf1 include:mx.example.com ~all"},
+		}),
+	), domain)
+	r.ExternalDNS = true
+	reconcileObject(t, r, domain)
+
+	endpoint := newDNSEndpoint()
+	require.NoError(t, r.Get(ctx, types.NamespacedName{Name: "example-dns", Namespace: "default"}, endpoint))
+
+	endpoints, _, err := unstructured.NestedSlice(endpoint.Object, "spec", "endpoints")
+	require.NoError(t, err)
+	names := []string{}
+	for _, e := range endpoints {
+		names = append(names, e.(map[string]interface{})["dnsName"].(string))
+	}
+	assert.Equal(t, []string{"selector._domainkey.news.example.com", "news.example.com"}, names)
+}
+
+func TestDNSProviderRecords(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	domain.Spec.DNS = &corev1alpha1.DomainDNSSpec{
+		AutoProvision: true,
+		Provider:      &corev1alpha1.DNSProviderSpec{Name: "cloudflare", CredentialsSecretName: "cloudflare"},
+	}
+	credentials := &corev1.Secret{
+		ObjectMeta: v1.ObjectMeta{Name: "cloudflare", Namespace: "default"},
+		Data:       map[string][]byte{"api-token": []byte("token")},
+	}
+
+	zone := provider.NewFakeProvider()
+	require.NoError(t, zone.Set(ctx, "example.com", "TXT", []string{"v=spf1 include:_spf.google.com -all", "site-verification=abc"}, 300))
+
+	r := createReconciler(t, checker.NewFakeDNSChecker(
+		checker.WithStatsDNSStats(checker.DNSCheckStats{
+			CntKO:    1,
+			Expected: checker.Record{Type: "CNAME", Name: "stats.example.com", Value: "mx.example.com"},
+		}),
+		checker.WithSPFStats(checker.DNSCheckStats{
+			CntKO:    1,
+			Expected: checker.Record{Type: "TXT", Name: "example.com", Value: "v=spf1 include:mx.example.com ~all"},
+		}),
+		checker.WithDMARCStats(checker.DNSCheckStats{
+			CntKO:    1,
+			Expected: checker.Record{Type: "TXT", Name: "_dmarc.example.com", Value: "v=DMARC1; p=none"},
+		}),
+	), domain, credentials)
+	r.dnsProvider = func(name, zoneName string, creds map[string][]byte) (provider.Provider, error) {
+		assert.Equal(t, "cloudflare", name)
+		assert.Equal(t, "example.com", zoneName)
+		assert.Equal(t, "token", string(creds["api-token"]))
+		return zone, nil
+	}
+	reconcileObject(t, r, domain)
+
+	values, err := zone.Get(ctx, "stats.example.com", "CNAME")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"mx.example.com"}, values)
+
+	values, err = zone.Get(ctx, "example.com", "TXT")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"v=spf1 include:_spf.google.com include:mx.example.com -all", "site-verification=abc"}, values,
+		"should extend the existing SPF record")
+
+	values, err = zone.Get(ctx, "_dmarc.example.com", "TXT")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"v=DMARC1; p=none"}, values)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	cond := meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionDNSProvisioned)
+	require.NotNil(t, cond)
+	assert.Equal(t, v1.ConditionTrue, cond.Status)
+	assert.Equal(t, corev1alpha1.ReasonDNSRecordsPublished, cond.Reason)
+
+	// published records are not written again
+	sets := zone.Sets
+	reconcileObject(t, r, domain)
+	assert.Equal(t, sets, zone.Sets)
+}
+
+func TestDNSProviderSecretRef(t *testing.T) {
+	domain := createDomain(t)
+	domain.Spec.DNS = &corev1alpha1.DomainDNSSpec{
+		AutoProvision: true,
+		Provider: &corev1alpha1.DNSProviderSpec{Name: "cloudflare", SecretRef: &corev1alpha1.CredentialsSecretReference{
+			Name: "dns-credentials",
+			Keys: map[string]string{"api-token": "CF_API_TOKEN"},
+		}},
+	}
+	// laid out like the Secret synced by an ExternalSecret
+	credentials := &corev1.Secret{
+		ObjectMeta: v1.ObjectMeta{Name: "dns-credentials", Namespace: "default"},
+		Data:       map[string][]byte{"CF_API_TOKEN": []byte("token")},
+	}
+
+	r := createReconciler(t, checker.NewFakeDNSChecker(checker.WithAll(true)), domain, credentials)
+	var got map[string][]byte
+	r.dnsProvider = func(name, zoneName string, creds map[string][]byte) (provider.Provider, error) {
+		got = creds
+		return provider.NewFakeProvider(), nil
+	}
+	reconcileObject(t, r, domain)
+
+	assert.Equal(t, "token", string(got["api-token"]), "the mapped key should be read under the credential name")
+	assert.Equal(t, []ctrl.Request{{NamespacedName: client.ObjectKeyFromObject(domain)}}, r.domainsForSecret(credentials))
+	assert.Empty(t, r.domainsForSecret(&corev1.Secret{ObjectMeta: v1.ObjectMeta{Name: "other", Namespace: "default"}}))
+}
+
+func TestMergeSPF(t *testing.T) {
+	cases := []struct {
+		current, merged string
+	}{
+		{"v=spf1 -all", "v=spf1 include:mx.example.com -all"},
+		{"v=spf1 include:mx.example.com ~all", "v=spf1 include:mx.example.com ~all"},
+		{"v=spf1 ip4:192.0.2.1", "v=spf1 ip4:192.0.2.1 include:mx.example.com"},
+		{"v=spf1 a redirect=_spf.example.org", "v=spf1 a include:mx.example.com redirect=_spf.example.org"},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.merged, mergeSPF(c.current, "v=spf1 include:mx.example.com ~all"), c.current)
+	}
+}
+
+func TestKannonRegistration(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	kannonClient := kannon.NewFakeClient()
+
+	r := createReconciler(t, checker.NewFakeDNSChecker(checker.WithAll(true)), domain)
+	r.Kannon = kannonClient
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.Contains(t, domain.Finalizers, cleanupFinalizer)
+	assert.True(t, meta.IsStatusConditionTrue(domain.Status.Conditions, corev1alpha1.ConditionKannonRegistered))
+
+	secret := &corev1.Secret{}
+	require.NoError(t, r.Get(ctx, types.NamespacedName{Name: "example-kannon", Namespace: "default"}, secret))
+	assert.True(t, v1.IsControlledBy(secret, domain), "the secret should be owned by the domain")
+	assert.Equal(t, "example.com", string(secret.Data["domain"]))
+	assert.Equal(t, "key-example.com", string(secret.Data["key"]))
+
+	// the registration is done once
+	reconcileObject(t, r, domain)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.True(t, meta.IsStatusConditionTrue(domain.Status.Conditions, corev1alpha1.ConditionKannonRegistered))
+
+	// deleting the domain removes the registration
+	require.NoError(t, r.Delete(ctx, domain))
+	reconcileObject(t, r, domain)
+
+	_, err := kannonClient.GetDomain(ctx, "example.com")
+	assert.True(t, errors.Is(err, kannon.ErrNotFound), "the registration should be deleted: %v", err)
+	err = r.Get(ctx, client.ObjectKeyFromObject(domain), domain)
+	assert.True(t, apierrors.IsNotFound(err), "the domain should be released: %v", err)
+}
+
+func TestKannonSenderSettings(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	kannonClient := kannon.NewFakeClient()
+
+	r := createReconciler(t, checker.NewFakeDNSChecker(checker.WithAll(true)), domain)
+	r.Kannon = kannonClient
+	reconcileObject(t, r, domain)
+
+	settings, updates := kannonClient.Settings("example.com")
+	assert.Equal(t, kannon.DomainSettings{DKIMSelector: "selector"}, settings)
+	assert.Equal(t, 1, updates)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.NotEmpty(t, domain.Status.KannonSettingsHash)
+
+	// the settings are pushed only when they change
+	reconcileObject(t, r, domain)
+	_, updates = kannonClient.Settings("example.com")
+	assert.Equal(t, 1, updates)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	domain.Spec.SenderAlias = "Example"
+	domain.Spec.SenderPoolRef = &corev1alpha1.SenderPoolReference{Name: "pool"}
+	require.NoError(t, r.Update(ctx, domain))
+	reconcileObject(t, r, domain)
+
+	settings, updates = kannonClient.Settings("example.com")
+	assert.Equal(t, kannon.DomainSettings{DKIMSelector: "selector", SenderAlias: "Example", SenderPool: "default/pool"}, settings)
+	assert.Equal(t, 2, updates)
+
+	// a new registration gets the settings again
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	domain.Spec.DomainName = "example.org"
+	require.NoError(t, r.Update(ctx, domain))
+	reconcileObject(t, r, domain)
+
+	settings, updates = kannonClient.Settings("example.org")
+	assert.Equal(t, "Example", settings.SenderAlias)
+	assert.Equal(t, 1, updates)
+}
+
+func TestKannonSenderSettingsUnsupported(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	kannonClient := kannon.NewFakeClient()
+
+	r := createReconciler(t, checker.NewFakeDNSChecker(checker.WithAll(true)), domain)
+	r.Kannon = noUpdateKannonClient{kannonClient}
+	reconcileObject(t, r, domain)
+
+	// the settings Kannon could not take are not recorded as pushed
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.True(t, meta.IsStatusConditionTrue(domain.Status.Conditions, corev1alpha1.ConditionKannonRegistered))
+	assert.Empty(t, domain.Status.KannonSettingsHash)
+
+	r.Kannon = kannonClient
+	reconcileObject(t, r, domain)
+
+	settings, updates := kannonClient.Settings("example.com")
+	assert.Equal(t, kannon.DomainSettings{DKIMSelector: "selector"}, settings)
+	assert.Equal(t, 1, updates)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.NotEmpty(t, domain.Status.KannonSettingsHash)
+}
+
+// noUpdateKannonClient is a Kannon API without the update call.
+type noUpdateKannonClient struct {
+	kannon.Client
+}
+
+func (noUpdateKannonClient) UpdateDomain(ctx context.Context, domain string, settings kannon.DomainSettings) error {
+	return kannon.ErrUnsupported
+}
+
+func TestKannonCredentialsRef(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	domain.Spec.KannonCredentialsRef = &corev1alpha1.CredentialsSecretReference{
+		Name: "sealed-kannon",
+		Keys: map[string]string{"key": "api-key"},
+	}
+	domain.Spec.Export = &corev1alpha1.DomainExportSpec{SecretName: "mailer-config"}
+	credentials := &corev1.Secret{
+		ObjectMeta: v1.ObjectMeta{Name: "sealed-kannon", Namespace: "default"},
+		Data:       map[string][]byte{"api-key": []byte("external-key")},
+	}
+
+	// the domain is registered outside of the operator
+	kannonClient := kannon.NewFakeClient()
+	_, err := kannonClient.CreateDomain(ctx, "example.com")
+	require.NoError(t, err)
+
+	r := createReconciler(t, checker.NewFakeDNSChecker(checker.WithAll(true)), domain, credentials)
+	r.Kannon = kannonClient
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	cond := meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionKannonRegistered)
+	require.NotNil(t, cond)
+	assert.Equal(t, v1.ConditionTrue, cond.Status)
+	assert.Contains(t, cond.Message, "sealed-kannon")
+	_, updates := kannonClient.Settings("example.com")
+	assert.Equal(t, 1, updates, "the sender settings should still be pushed")
+
+	err = r.Get(ctx, types.NamespacedName{Name: "example-kannon", Namespace: "default"}, &corev1.Secret{})
+	assert.True(t, apierrors.IsNotFound(err), "no credentials secret should be created: %v", err)
+
+	secret := &corev1.Secret{}
+	require.NoError(t, r.Get(ctx, types.NamespacedName{Name: "mailer-config", Namespace: "default"}, secret))
+	assert.Equal(t, "external-key", string(secret.Data["KANNON_API_KEY"]))
+
+	// the rotated key is exported once the Secret changes
+	credentials.Data = map[string][]byte{"api-key": []byte("rotated-key")}
+	require.NoError(t, r.Update(ctx, credentials))
+	assert.Equal(t, []ctrl.Request{{NamespacedName: client.ObjectKeyFromObject(domain)}}, r.domainsForSecret(credentials))
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, types.NamespacedName{Name: "mailer-config", Namespace: "default"}, secret))
+	assert.Equal(t, "rotated-key", string(secret.Data["KANNON_API_KEY"]))
+
+	// credentials of another domain are refused
+	credentials.Data = map[string][]byte{"api-key": []byte("rotated-key"), "domain": []byte("example.org")}
+	require.NoError(t, r.Update(ctx, credentials))
+	_, err = r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(domain)})
+	assert.Error(t, err)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	cond = meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionKannonRegistered)
+	require.NotNil(t, cond)
+	assert.Equal(t, corev1alpha1.ReasonKannonRegistrationFailed, cond.Reason)
+
+	// the registration is left to its owner
+	require.NoError(t, r.Delete(ctx, domain))
+	reconcileObject(t, r, domain)
+
+	_, err = kannonClient.GetDomain(ctx, "example.com")
+	assert.NoError(t, err)
+}
+
+func TestRuntimeConfigExport(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	domain.Spec.Export = &corev1alpha1.DomainExportSpec{SecretName: "mailer-config"}
+
+	r := createReconciler(t, checker.NewFakeDNSChecker(checker.WithAll(true)), domain)
+	r.Kannon = kannon.NewFakeClient()
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.True(t, meta.IsStatusConditionTrue(domain.Status.Conditions, corev1alpha1.ConditionConfigExported))
+
+	secret := &corev1.Secret{}
+	require.NoError(t, r.Get(ctx, types.NamespacedName{Name: "mailer-config", Namespace: "default"}, secret))
+	assert.True(t, v1.IsControlledBy(secret, domain), "the secret should be owned by the domain")
+	assert.Equal(t, map[string][]byte{
+		"KANNON_DOMAIN":        []byte("example.com"),
+		"KANNON_DKIM_SELECTOR": []byte("selector"),
+		"KANNON_API_KEY":       []byte("key-example.com"),
+	}, secret.Data)
+
+	// the export follows the DKIM selector
+	domain.Spec.DKIM.Selector = "rotated"
+	require.NoError(t, r.Update(ctx, domain))
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, types.NamespacedName{Name: "mailer-config", Namespace: "default"}, secret))
+	assert.Equal(t, "rotated", string(secret.Data["KANNON_DKIM_SELECTOR"]))
+
+	// renaming the export moves it to the new Secret
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	domain.Spec.Export.SecretName = "sender-config"
+	require.NoError(t, r.Update(ctx, domain))
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, types.NamespacedName{Name: "sender-config", Namespace: "default"}, secret))
+	err := r.Get(ctx, types.NamespacedName{Name: "mailer-config", Namespace: "default"}, &corev1.Secret{})
+	assert.True(t, apierrors.IsNotFound(err), "the old secret should be deleted: %v", err)
+
+	// the export can't overwrite the Kannon credentials
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	domain.Spec.Export.SecretName = "example-kannon"
+	require.NoError(t, r.Update(ctx, domain))
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	cond := meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionConfigExported)
+	require.NotNil(t, cond)
+	assert.Equal(t, corev1alpha1.ReasonConfigExportFailed, cond.Reason)
+	require.NoError(t, r.Get(ctx, types.NamespacedName{Name: "example-kannon", Namespace: "default"}, secret))
+	assert.Equal(t, "key-example.com", string(secret.Data["key"]))
+	assert.NotContains(t, secret.Data, "KANNON_DOMAIN")
+}
+
+func TestDomainOwnership(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	dnsChecker := checker.NewFakeDNSChecker(checker.WithAll(true), checker.WithOwnership(false))
+	kannonClient := kannon.NewFakeClient()
+	r := createReconciler(t, dnsChecker, domain)
+	r.Kannon = kannonClient
+	r.RequireOwnership = true
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	require.NotNil(t, domain.Status.Ownership)
+	token := domain.Status.Ownership.Token
+	assert.Regexp(t, "^k8nnon-challenge=[0-9a-f]{32}$", token)
+	assert.Equal(t, &corev1alpha1.DNSRecord{Type: "TXT", Name: "_k8nnon-challenge.example.com", Value: token}, domain.Status.Ownership.Expected)
+
+	cond := meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionOwnershipVerified)
+	require.NotNil(t, cond)
+	assert.Equal(t, v1.ConditionFalse, cond.Status)
+	assert.Contains(t, cond.Message, token)
+
+	cond = meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionIngressReady)
+	require.NotNil(t, cond)
+	assert.Equal(t, corev1alpha1.ReasonOwnershipNotVerified, cond.Reason)
+	err := r.Get(ctx, types.NamespacedName{Name: statsIngressName(domain), Namespace: "default"}, &netwrkingv1.Ingress{})
+	assert.True(t, apierrors.IsNotFound(err), "the ingress should wait for the ownership: %v", err)
+	_, err = kannonClient.GetDomain(ctx, "example.com")
+	assert.True(t, errors.Is(err, kannon.ErrNotFound), "the registration should wait for the ownership: %v", err)
+
+	// the token is kept until verified
+	dnsChecker.Set(checker.WithOwnership(true))
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.Equal(t, token, domain.Status.Ownership.Token)
+	assert.True(t, meta.IsStatusConditionTrue(domain.Status.Conditions, corev1alpha1.ConditionOwnershipVerified))
+	assert.True(t, meta.IsStatusConditionTrue(domain.Status.Conditions, corev1alpha1.ConditionKannonRegistered))
+	getStatsIngress(t, r, domain)
+
+	// once verified the record is not checked anymore
+	dnsChecker.Set(checker.WithOwnership(false))
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.True(t, meta.IsStatusConditionTrue(domain.Status.Conditions, corev1alpha1.ConditionOwnershipVerified))
+}
+
+func TestDeliveryStatus(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	minSent := int64(10)
+	domain.Spec.Delivery = &corev1alpha1.DeliverySpec{MaxBounceRate: "10%", MinSent: &minSent}
+	r := createReconciler(t, checker.NewFakeDNSChecker(checker.WithAll(true)), domain)
+	r.Delivery = delivery.NewAggregator(0, KnownDeliveryDomain(r.Client))
+	reconcileObject(t, r, domain)
+
+	// each replica counts the webhooks it receives
+	replicas := []*DeliveryFlusher{
+		{Client: r.Client, Aggregator: r.Delivery},
+		{Client: r.Client, Aggregator: delivery.NewAggregator(0, KnownDeliveryDomain(r.Client))},
+	}
+	record := func(replica int, typ string, n int) {
+		for i := 0; i < n; i++ {
+			require.True(t, replicas[replica].Aggregator.Record(delivery.Event{Type: typ, Domain: "example.com"}))
+		}
+	}
+	flush := func() {
+		for _, f := range replicas {
+			f.flush(ctx)
+		}
+	}
+
+	assert.False(t, r.Delivery.Record(delivery.Event{Type: delivery.EventSent, Domain: "example.org"}), "the webhooks of unknown domains should be dropped")
+
+	record(0, delivery.EventSent, 5)
+	flush()
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	require.NotNil(t, domain.Status.Delivery)
+	assert.Equal(t, int64(5), domain.Status.Delivery.Sent)
+	assert.Equal(t, 24*time.Hour, domain.Status.Delivery.Window.Duration)
+	assert.NotNil(t, domain.Status.Delivery.UpdatedAt)
+	cond := meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionHighBounceRate)
+	require.NotNil(t, cond)
+	assert.Equal(t, v1.ConditionFalse, cond.Status)
+	assert.Equal(t, corev1alpha1.ReasonNotEnoughMessages, cond.Reason)
+
+	record(0, delivery.EventSent, 10)
+	record(1, delivery.EventSent, 5)
+	record(1, delivery.EventDelivered, 18)
+	record(1, delivery.EventBounced, 2)
+	flush()
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.Equal(t, int64(20), domain.Status.Delivery.Sent, "the counters of every replica should be added")
+	assert.Equal(t, "10.00%", domain.Status.Delivery.BounceRate)
+	assert.Equal(t, "0.00%", domain.Status.Delivery.ComplaintRate)
+	cond = meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionHighBounceRate)
+	assert.Equal(t, v1.ConditionFalse, cond.Status)
+	assert.Equal(t, corev1alpha1.ReasonWithinThresholds, cond.Reason)
+
+	record(1, delivery.EventBounced, 1)
+	record(1, delivery.EventComplained, 1)
+	flush()
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.Equal(t, "15.00%", domain.Status.Delivery.BounceRate)
+	cond = meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionHighBounceRate)
+	assert.Equal(t, v1.ConditionTrue, cond.Status)
+	assert.Equal(t, corev1alpha1.ReasonBounceRateExceeded, cond.Reason)
+
+	// the counters are persisted, a new aggregator starts from them
+	r.Delivery = delivery.NewAggregator(0, nil)
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.Equal(t, int64(20), domain.Status.Delivery.Sent)
+	assert.Equal(t, "15.00%", domain.Status.Delivery.BounceRate)
+	assert.True(t, meta.IsStatusConditionTrue(domain.Status.Conditions, corev1alpha1.ConditionHighBounceRate))
+	// the rates are informational
+	assert.True(t, meta.IsStatusConditionTrue(domain.Status.Conditions, corev1alpha1.ConditionReady))
+
+	// the counters out of the window are not summed
+	r.clock = func() time.Time { return time.Now().Add(26 * time.Hour) }
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.Equal(t, int64(0), domain.Status.Delivery.Sent)
+
+	// without the receiver the counters are dropped
+	r.Delivery = nil
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.Nil(t, domain.Status.Delivery)
+	assert.Nil(t, meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionHighBounceRate))
+}
+
+func TestDeliveryFlushFailure(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	r := createReconciler(t, checker.NewFakeDNSChecker(checker.WithAll(true)), domain)
+	failures := 1
+	f := &DeliveryFlusher{
+		Client:     failingStatusClient{Client: r.Client, failures: &failures},
+		Aggregator: delivery.NewAggregator(0, nil),
+	}
+
+	require.True(t, f.Aggregator.Record(delivery.Event{Type: delivery.EventSent, Domain: "example.com"}))
+	f.flush(ctx)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.Nil(t, domain.Status.Delivery)
+
+	f.flush(ctx)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	require.NotNil(t, domain.Status.Delivery)
+	assert.Equal(t, int64(1), domain.Status.Delivery.Sent, "the counters that failed to be published should be flushed again")
+}
+
+func TestReputationStatus(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	domain.Spec.Reputation = &corev1alpha1.ReputationSpec{SecretName: "reputation"}
+	domain.Spec.SenderPoolRef = &corev1alpha1.SenderPoolReference{Name: "pool"}
+	pool := &corev1alpha1.SenderPool{
+		ObjectMeta: v1.ObjectMeta{Name: "pool", Namespace: domain.Namespace},
+		Spec:       corev1alpha1.SenderPoolSpec{IPs: []string{"192.0.2.1"}, ReverseDomain: "mx.kannon.email", SPFDomain: "spf.kannon.email"},
+	}
+	r := createReconciler(t, checker.NewFakeDNSChecker(checker.WithAll(true)), domain, pool)
+	poller := reputation.NewFakePoller()
+	r.Reputation = poller
+	now := time.Now().Truncate(time.Second)
+	r.clock = func() time.Time { return now }
+
+	// without the secret the error is reported
+	reconcileObject(t, r, domain)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	require.NotNil(t, domain.Status.Reputation)
+	assert.Contains(t, domain.Status.Reputation.Error, "the secret reputation does not exist")
+	assert.Zero(t, poller.Polls())
+
+	require.NoError(t, r.Create(ctx, &corev1.Secret{
+		ObjectMeta: v1.ObjectMeta{Name: "reputation", Namespace: domain.Namespace},
+		Data: map[string][]byte{
+			corev1alpha1.ReputationPostmasterClientIDKey:     []byte("client"),
+			corev1alpha1.ReputationPostmasterClientSecretKey: []byte("secret"),
+			corev1alpha1.ReputationPostmasterRefreshTokenKey: []byte("refresh"),
+			corev1alpha1.ReputationSNDSKeyKey:                []byte("snds-key"),
+		},
+	}))
+	poller.SetTrafficStats("example.com", &reputation.TrafficStats{
+		Date:                  "2023-10-03",
+		DomainReputation:      "HIGH",
+		UserReportedSpamRatio: 0.0012,
+		IPReputations:         []reputation.IPReputation{{Reputation: "HIGH", IPCount: 1, SampleIPs: []string{"192.0.2.1"}}},
+	})
+	poller.SetSNDS("snds-key", []reputation.SNDSRecord{
+		{IP: "192.0.2.1", MessageRecipients: 150, FilterResult: "GREEN", ComplaintRate: "< 0.1%"},
+		{IP: "198.51.100.1", MessageRecipients: 40, FilterResult: "RED", ComplaintRate: "0.3%", TrapHits: 3},
+	})
+
+	res := reconcileObject(t, r, domain)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	status := domain.Status.Reputation
+	require.NotNil(t, status)
+	assert.Empty(t, status.Error)
+	assert.Equal(t, &corev1alpha1.PostmasterReputation{
+		Date:             "2023-10-03",
+		DomainReputation: "HIGH",
+		SpamRate:         "0.12%",
+		IPReputations:    []corev1alpha1.PostmasterIPReputation{{Reputation: "HIGH", IPCount: 1, SampleIPs: []string{"192.0.2.1"}}},
+	}, status.Postmaster)
+	// only the addresses of the sender pool are kept
+	require.NotNil(t, status.SNDS)
+	require.Len(t, status.SNDS.IPs, 1)
+	assert.Equal(t, "192.0.2.1", status.SNDS.IPs[0].IP)
+	assert.LessOrEqual(t, res.RequeueAfter, corev1alpha1.DefaultReputationPollInterval)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(domainPostmasterReputation.WithLabelValues("example.com", "HIGH")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(domainPostmasterReputation.WithLabelValues("example.com", "BAD")))
+	assert.InDelta(t, 0.0012, testutil.ToFloat64(domainPostmasterSpamRatio.WithLabelValues("example.com")), 1e-9)
+	assert.Equal(t, 1.0, testutil.ToFloat64(domainSNDSFilterResult.WithLabelValues("example.com", "192.0.2.1", "GREEN")))
+
+	// the services are not polled again before the interval
+	polls := poller.Polls()
+	reconcileObject(t, r, domain)
+	assert.Equal(t, polls, poller.Polls())
+
+	// a failed poll keeps the previous data
+	now = now.Add(corev1alpha1.DefaultReputationPollInterval)
+	poller.SetError(errors.New("postmaster tools: status 503"))
+	reconcileObject(t, r, domain)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	status = domain.Status.Reputation
+	assert.Greater(t, poller.Polls(), polls)
+	assert.Equal(t, "HIGH", status.Postmaster.DomainReputation)
+	assert.Equal(t, "postmaster tools: status 503", status.Postmaster.Error)
+	assert.Len(t, status.SNDS.IPs, 1)
+	assert.NotEmpty(t, status.SNDS.Error)
+
+	// without spec.reputation the data and the series are dropped
+	domain.Spec.Reputation = nil
+	require.NoError(t, r.Update(ctx, domain))
+	reconcileObject(t, r, domain)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.Nil(t, domain.Status.Reputation)
+	assert.Zero(t, domainPostmasterReputation.DeletePartialMatch(prometheus.Labels{"domain": "example.com"}))
+}
+
+// dmarcReport returns an aggregate report of example.com from google.com,
+// ending at end.
+func dmarcReport(id string, end time.Time) []byte {
+	return []byte(fmt.Sprintf(`<feedback>
+  <report_metadata><org_name>google.com</org_name><report_id>%s</report_id>
+    <date_range><begin>%d</begin><end>%d</end></date_range></report_metadata>
+  <policy_published><domain>example.com</domain><p>none</p></policy_published>
+  <record><row><source_ip>192.0.2.1</source_ip><count>95</count>
+    <policy_evaluated><disposition>none</disposition><dkim>pass</dkim><spf>pass</spf></policy_evaluated></row>
+    <identifiers><header_from>example.com</header_from></identifiers></record>
+  <record><row><source_ip>203.0.113.9</source_ip><count>5</count>
+    <policy_evaluated><disposition>none</disposition><dkim>fail</dkim><spf>fail</spf></policy_evaluated></row>
+    <identifiers><header_from>example.com</header_from></identifiers></record>
+</feedback>`, id, end.Add(-24*time.Hour).Unix(), end.Unix()))
+}
+
+func TestDMARCReports(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	domain.Spec.DMARCReports = &corev1alpha1.DMARCReportsSpec{
+		IMAP: &corev1alpha1.DMARCIMAPSpec{Address: "imap.example.com:993", SecretName: "dmarc-mailbox"},
+	}
+	r := createReconciler(t, checker.NewFakeDNSChecker(checker.WithAll(true)), domain)
+	r.DMARCReports = dmarc.NewStore(0)
+	fetcher := dmarc.NewFakeFetcher()
+	r.DMARCFetcher = fetcher
+
+	// without the secret the error is reported, and the mailbox polled
+	// again by the next reconcile
+	reconcileObject(t, r, domain)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	require.NotNil(t, domain.Status.DMARCReports)
+	assert.Equal(t, "the secret dmarc-mailbox does not exist", domain.Status.DMARCReports.Error)
+	assert.Zero(t, fetcher.Fetches())
+	assert.Zero(t, domain.Status.DMARCReports.Reports)
+	assert.Equal(t, "0.00%", domain.Status.DMARCReports.PassRate)
+
+	require.NoError(t, r.Create(ctx, &corev1.Secret{
+		ObjectMeta: v1.ObjectMeta{Name: "dmarc-mailbox", Namespace: domain.Namespace},
+		Data:       map[string][]byte{corev1alpha1.DMARCIMAPUsernameKey: []byte("dmarc"), corev1alpha1.DMARCIMAPPasswordKey: []byte("secret")},
+	}))
+	fetcher.Deliver("imap.example.com:993", dmarcReport("r1", time.Now().Add(-time.Hour)))
+	fetcher.Deliver("imap.example.com:993", []byte("Subject: hello\r\n\r\nnot a report\r\n"))
+	fetcher.Deliver("imap.example.com:993", bytes.ReplaceAll(dmarcReport("r2", time.Now()), []byte("<domain>example.com"), []byte("<domain>example.org")))
+
+	res := reconcileObject(t, r, domain)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	status := domain.Status.DMARCReports
+	assert.Empty(t, status.Error)
+	assert.Equal(t, 1, status.Reports)
+	assert.Equal(t, []string{"google.com"}, status.Reporters)
+	assert.Equal(t, int64(100), status.Messages)
+	assert.Equal(t, "95.00%", status.PassRate)
+	assert.Equal(t, "95.00%", status.DKIMAlignedRate)
+	assert.Equal(t, []corev1alpha1.DMARCSource{{IP: "203.0.113.9", HeaderFrom: "example.com", DKIM: "fail", SPF: "fail", Messages: 5}}, status.TopFailingSources)
+	assert.Equal(t, corev1alpha1.DefaultDMARCReportsWindow, status.Window.Duration)
+	assert.LessOrEqual(t, res.RequeueAfter, corev1alpha1.DefaultDMARCIMAPPollInterval)
+
+	assert.Equal(t, 95.0, testutil.ToFloat64(domainDMARCMessages.WithLabelValues("example.com", "pass")))
+	assert.Equal(t, 5.0, testutil.ToFloat64(domainDMARCMessages.WithLabelValues("example.com", "fail")))
+	assert.InDelta(t, 0.95, testutil.ToFloat64(domainDMARCAlignedRatio.WithLabelValues("example.com", "dmarc")), 1e-9)
+
+	// the mailbox is not polled again before the interval, the reports are
+	// not counted twice
+	fetches := fetcher.Fetches()
+	reconcileObject(t, r, domain)
+	assert.Equal(t, fetches, fetcher.Fetches())
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.Equal(t, 1, domain.Status.DMARCReports.Reports)
+
+	// without spec.dmarcReports the summary and the series are dropped
+	domain.Spec.DMARCReports = nil
+	require.NoError(t, r.Update(ctx, domain))
+	reconcileObject(t, r, domain)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.Nil(t, domain.Status.DMARCReports)
+	assert.Zero(t, domainDMARCMessages.DeletePartialMatch(prometheus.Labels{"domain": "example.com"}))
+}
+
+func TestDMARCReportAuthorizer(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	domain.Spec.DMARCReports = &corev1alpha1.DMARCReportsSpec{HTTP: &corev1alpha1.DMARCHTTPSpec{SecretName: "dmarc-token"}}
+	secret := &corev1.Secret{
+		ObjectMeta: v1.ObjectMeta{Name: "dmarc-token", Namespace: domain.Namespace},
+		Data:       map[string][]byte{corev1alpha1.DMARCHTTPTokenKey: []byte("token")},
+	}
+	r := createReconciler(t, checker.NewFakeDNSChecker(checker.WithAll(true)), domain, secret)
+	authorize := DMARCReportAuthorizer(r.Client)
+
+	name, err := authorize(ctx, domain.Namespace, domain.Name, "token")
+	require.NoError(t, err)
+	assert.Equal(t, "example.com", name)
+
+	_, err = authorize(ctx, domain.Namespace, domain.Name, "wrong")
+	assert.ErrorIs(t, err, dmarc.ErrUnauthorized)
+	_, err = authorize(ctx, domain.Namespace, "missing", "token")
+	assert.ErrorIs(t, err, dmarc.ErrUnauthorized)
+}
+
+type fakeNotifier struct {
+	events []notify.Event
+}
+
+func (n *fakeNotifier) Notify(_ context.Context, e notify.Event) error {
+	n.events = append(n.events, e)
+	return nil
+}
+
+func (n *fakeNotifier) types() []string {
+	types := []string{}
+	for _, e := range n.events {
+		types = append(types, e.Type)
+	}
+	n.events = nil
+	return types
+}
+
+func TestDomainNotifications(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	dnsChecker := checker.NewFakeDNSChecker(checker.WithAll(true))
+	notifier := &fakeNotifier{}
+	r := createReconciler(t, dnsChecker, domain)
+	r.Notifier = notifier
+	reconcileObject(t, r, domain)
+
+	require.Len(t, notifier.events, 1)
+	e := notifier.events[0]
+	assert.Equal(t, notify.EventVerified, e.Type)
+	assert.Equal(t, "example.com", e.Domain)
+	assert.Equal(t, "default", e.Namespace)
+	assert.Equal(t, "example", e.Name)
+	assert.Equal(t, []string{notify.EventVerified}, notifier.types())
+
+	// nothing changed
+	reconcileObject(t, r, domain)
+	assert.Empty(t, notifier.types())
+
+	// the ingress is deleted by hand
+	require.NoError(t, r.Delete(ctx, getStatsIngress(t, r, domain)))
+	reconcileObject(t, r, domain)
+	require.Len(t, notifier.events, 1)
+	assert.Contains(t, notifier.events[0].Message, "recreated")
+	assert.Equal(t, []string{notify.EventIngressDeleted}, notifier.types())
+	getStatsIngress(t, r, domain)
+
+	// the stats record is lost, taking the ingress down
+	dnsChecker.Set(checker.WithAll(false))
+	reconcileObject(t, r, domain)
+	assert.ElementsMatch(t, []string{notify.EventVerificationLost, notify.EventIngressDeleted}, notifier.types())
+}
+
+func TestDomainNotificationsAfterStatusWrite(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	dnsChecker := checker.NewFakeDNSChecker(checker.WithAll(false))
+	notifier := &fakeNotifier{}
+	r := createReconciler(t, dnsChecker, domain)
+	r.Notifier = notifier
+	now := time.Now()
+	r.clock = func() time.Time { return now }
+	reconcileObject(t, r, domain)
+	c := r.Client
+	stale := &corev1alpha1.Domain{}
+	require.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(domain), stale))
+	dnsChecker.Set(checker.WithAll(true))
+
+	// the transition is not notified until the status is written
+	failures := 1
+	r.Client = failingStatusClient{Client: c, failures: &failures}
+	_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(domain)})
+	require.Error(t, err)
+	assert.Empty(t, notifier.types())
+
+	reconcileObject(t, r, domain)
+	assert.Equal(t, []string{notify.EventVerified}, notifier.types())
+	require.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	verifiedAt := meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionReady).LastTransitionTime
+
+	// a reconcile of the Domain read before the transition was persisted
+	// does not notify it again
+	now = now.Add(time.Minute)
+	r.Client = &staleDomainClient{Client: c, stale: stale}
+	reconcileObject(t, r, domain)
+	assert.Empty(t, notifier.types())
+	require.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.Equal(t, verifiedAt, meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionReady).LastTransitionTime)
+}
+
+// staleDomainClient returns stale the first time a Domain is read.
+type staleDomainClient struct {
+	client.Client
+	stale *corev1alpha1.Domain
+}
+
+func (c *staleDomainClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if domain, ok := obj.(*corev1alpha1.Domain); ok && c.stale != nil {
+		c.stale.DeepCopyInto(domain)
+		c.stale = nil
+		return nil
+	}
+	return c.Client.Get(ctx, key, obj, opts...)
+}
+
+func TestFreshDomainRequeue(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	domain := createDomain(t)
+	domain.CreationTimestamp = v1.NewTime(now)
+	r := createReconciler(t, checker.NewFakeDNSChecker(checker.WithAll(true)), domain)
+	r.FreshPeriod = 5 * time.Minute
+	r.clock = func() time.Time { return now }
+
+	res := reconcileObject(t, r, domain)
+	assert.Equal(t, transitionRecheckInterval, res.RequeueAfter)
+
+	// a new domain is rechecked soon even once verified
+	now = now.Add(time.Minute)
+	res = reconcileObject(t, r, domain)
+	assert.Equal(t, freshRecheckInterval, res.RequeueAfter)
+	assert.True(t, r.isFresh(client.ObjectKeyFromObject(domain)))
+
+	now = now.Add(5 * time.Minute)
+	res = reconcileObject(t, r, domain)
+	assert.Equal(t, corev1alpha1.DefaultCheckInterval, res.RequeueAfter)
+	assert.False(t, r.isFresh(client.ObjectKeyFromObject(domain)))
+
+	// an edit of the spec makes it fresh again
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	domain.Generation++
+	require.NoError(t, r.Update(ctx, domain))
+	res = reconcileObject(t, r, domain)
+	assert.Equal(t, freshRecheckInterval, res.RequeueAfter)
+
+	now = now.Add(4 * time.Minute)
+	res = reconcileObject(t, r, domain)
+	assert.Equal(t, freshRecheckInterval, res.RequeueAfter)
+
+	now = now.Add(2 * time.Minute)
+	res = reconcileObject(t, r, domain)
+	assert.Equal(t, corev1alpha1.DefaultCheckInterval, res.RequeueAfter)
+}
+
+func TestRecheckScheduler(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	newDomain := func(name, baseDomain string) *corev1alpha1.Domain {
+		domain := createDomain(t)
+		domain.Name = name
+		domain.Spec.BaseDomain = baseDomain
+		return domain
+	}
+	due := newDomain("due", "mx.example.com")
+	sibling := newDomain("sibling", "mx.example.com")
+	later := newDomain("later", "mx.example.com")
+	other := newDomain("other", "mx.other.com")
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1alpha1.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(due, sibling, later, other).
+		WithIndex(&corev1alpha1.Domain{}, baseDomainIndex, indexBaseDomain).Build()
+
+	s := newRecheckScheduler(c, time.Minute, func() time.Time { return now })
+	s.schedule(due, 0)
+	s.schedule(sibling, 30*time.Second)
+	s.schedule(later, 2*time.Minute)
+	s.schedule(other, 30*time.Second)
+
+	assert.Equal(t, []types.NamespacedName{
+		client.ObjectKeyFromObject(due),
+		client.ObjectKeyFromObject(sibling),
+	}, s.take(ctx, now), "the sibling due within the window should have been batched")
+
+	assert.Equal(t, []types.NamespacedName{client.ObjectKeyFromObject(other)}, s.take(ctx, now.Add(30*time.Second)))
+	assert.Empty(t, s.take(ctx, now.Add(time.Minute)))
+
+	// a reconcile replaces the scheduled check
+	for i := 0; i < 3; i++ {
+		s.schedule(later, 5*time.Minute)
+	}
+	assert.Len(t, s.queue, 1, "a rescheduled check should replace its queue item")
+	assert.Empty(t, s.take(ctx, now.Add(2*time.Minute)))
+	s.forget(client.ObjectKeyFromObject(later))
+	assert.Empty(t, s.queue)
+	assert.Empty(t, s.take(ctx, now.Add(5*time.Minute)))
+	_, ok := s.next()
+	assert.False(t, ok, "nothing should be left scheduled")
+}
+
+func TestReconcileSchedulesRecheck(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	domain := createDomain(t)
+	r := createReconciler(t, checker.NewFakeDNSChecker(checker.WithAll(true)), domain)
+	r.clock = func() time.Time { return now }
+	r.scheduler = newRecheckScheduler(r.Client, time.Minute, r.now)
+
+	res := reconcileObject(t, r, domain)
+	assert.Zero(t, res.RequeueAfter, "the scheduler should requeue the domain")
+	at, ok := r.scheduler.next()
+	require.True(t, ok)
+	assert.Equal(t, now.Add(transitionRecheckInterval), at)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	require.NoError(t, r.Delete(ctx, domain))
+	reconcileObject(t, r, domain)
+	assert.NotContains(t, r.scheduler.due, client.ObjectKeyFromObject(domain))
+}
+
+func TestFreshRateLimiter(t *testing.T) {
+	fresh := types.NamespacedName{Namespace: "default", Name: "fresh"}
+	stable := types.NamespacedName{Namespace: "default", Name: "stable"}
+	l := newFreshRateLimiter(func(key types.NamespacedName) bool { return key == fresh })
+
+	var last time.Duration
+	for i := 0; i < 20; i++ {
+		assert.LessOrEqual(t, l.When(ctrl.Request{NamespacedName: fresh}), freshRetryMaxDelay)
+		last = l.When(ctrl.Request{NamespacedName: stable})
+	}
+	assert.Greater(t, last, freshRetryMaxDelay)
+}
+
+func TestDomainLogLevel(t *testing.T) {
+	// the checks of a reconcile log from their own goroutines
+	var m sync.Mutex
+	var lines []string
+	base := funcr.New(func(prefix, args string) {
+		m.Lock()
+		defer m.Unlock()
+		lines = append(lines, args)
+	}, funcr.Options{Verbosity: logging.MaxVerbosity})
+	logged := func() []string {
+		m.Lock()
+		defer m.Unlock()
+		logged := lines
+		lines = nil
+		return logged
+	}
+	ctx := log.IntoContext(context.Background(), logging.New(base, 0, logging.Sampling{}))
+
+	domain := createDomain(t)
+	r := createReconciler(t, checker.NewFakeDNSChecker(checker.WithAll(true)), domain)
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(domain)}
+
+	_, err := r.Reconcile(ctx, req)
+	require.NoError(t, err)
+	for _, line := range logged() {
+		assert.NotContains(t, line, "dns check done", "the debug logs should be off by default")
+	}
+
+	// the annotation raises the verbosity of the domain only
+	require.NoError(t, r.Get(ctx, req.NamespacedName, domain))
+	domain.Annotations = map[string]string{corev1alpha1.AnnotationLogLevel: "debug"}
+	require.NoError(t, r.Update(ctx, domain))
+	_, err = r.Reconcile(ctx, req)
+	require.NoError(t, err)
+
+	checks := 0
+	for _, line := range logged() {
+		if strings.Contains(line, `"msg"="dns check done"`) {
+			checks++
+		}
+	}
+	assert.Equal(t, 5, checks, "the dkim, spf, stats, mx and dmarc checks should be logged")
+
+	// an invalid level is reported and ignored
+	require.NoError(t, r.Get(ctx, req.NamespacedName, domain))
+	domain.Annotations[corev1alpha1.AnnotationLogLevel] = "trace"
+	require.NoError(t, r.Update(ctx, domain))
+	_, err = r.Reconcile(ctx, req)
+	require.NoError(t, err)
+	output := strings.Join(logged(), "\n")
+	assert.Contains(t, output, "ignoring the log level annotation")
+	assert.NotContains(t, output, "dns check done")
+}
+
+func TestDomainReportOnly(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	domain.Spec.ReportOnly = true
+	domain.Spec.DKIM.PublicKey = ""
+	r := createReconciler(t, checker.NewFakeDNSChecker(checker.WithAll(true)), domain)
+	r.Kannon = kannon.NewFakeClient()
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.Empty(t, domain.Finalizers)
+	assert.Nil(t, domain.Status.DKIM, "should not have generated a dkim key")
+	assert.True(t, meta.IsStatusConditionTrue(domain.Status.Conditions, corev1alpha1.ConditionReady))
+	assert.True(t, meta.IsStatusConditionTrue(domain.Status.Conditions, corev1alpha1.ConditionReportOnly))
+	cond := meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionIngressReady)
+	require.NotNil(t, cond)
+	assert.Equal(t, corev1alpha1.ReasonReportOnly, cond.Reason)
+	assert.Contains(t, cond.Message, "would be created")
+	cond = meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionKannonRegistered)
+	require.NotNil(t, cond)
+	assert.Equal(t, corev1alpha1.ReasonReportOnly, cond.Reason)
+
+	err := r.Get(ctx, types.NamespacedName{Name: statsIngressName(domain), Namespace: domain.Namespace}, &netwrkingv1.Ingress{})
+	assert.True(t, apierrors.IsNotFound(err), "should not have created the ingress")
+	secrets := &corev1.SecretList{}
+	require.NoError(t, r.List(ctx, secrets))
+	assert.Empty(t, secrets.Items)
+
+	// a hand-managed ingress is reported and left untouched
+	foreign := &netwrkingv1.Ingress{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      statsIngressName(domain),
+			Namespace: domain.Namespace,
+		},
+	}
+	require.NoError(t, r.Create(ctx, foreign))
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	cond = meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionIngressReady)
+	require.NotNil(t, cond)
+	assert.Contains(t, cond.Message, "would be left untouched")
+	ingress := getStatsIngress(t, r, domain)
+	assert.Empty(t, ingress.Spec.Rules)
+
+	// back to the normal mode the ingress conflict is reported
+	domain.Spec.ReportOnly = false
+	require.NoError(t, r.Update(ctx, domain))
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.Nil(t, meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionReportOnly))
+	cond = meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionIngressReady)
+	require.NotNil(t, cond)
+	assert.Equal(t, corev1alpha1.ReasonIngressConflict, cond.Reason)
+}
+
+func TestDryRunLeavesResourcesOnDeletion(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	r := createReconciler(t, checker.NewFakeDNSChecker(checker.WithAll(true)), domain)
+	reconcileObject(t, r, domain)
+	getStatsIngress(t, r, domain)
+
+	r.DryRun = true
+	reconcileObject(t, r, domain)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	cond := meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionReportOnly)
+	require.NotNil(t, cond)
+	assert.Equal(t, corev1alpha1.ReasonDryRun, cond.Reason)
+	cond = meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionIngressReady)
+	require.NotNil(t, cond)
+	assert.Contains(t, cond.Message, "would be kept up to date")
+
+	require.NoError(t, r.Delete(ctx, domain))
+	reconcileObject(t, r, domain)
+
+	err := r.Get(ctx, client.ObjectKeyFromObject(domain), domain)
+	assert.True(t, apierrors.IsNotFound(err), "the finalizer should have been removed")
+	getStatsIngress(t, r, domain)
+}
+
+func TestStatsRouteCollector(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	r := createReconciler(t, checker.NewFakeDNSChecker(checker.WithAll(true)), domain)
+	reconcileObject(t, r, domain)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.Equal(t, managedBy, getStatsIngress(t, r, domain).Labels[managedByLabel])
+
+	gc := &StatsRouteCollector{Client: r.Client, Reader: r.Client}
+	orphans, err := gc.Collect(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, orphans)
+
+	route := func(name string, owner *corev1alpha1.Domain, labels map[string]string) *netwrkingv1.Ingress {
+		ing := &netwrkingv1.Ingress{ObjectMeta: v1.ObjectMeta{Name: name, Namespace: "default", Labels: labels}}
+		if owner != nil {
+			require.NoError(t, ctrl.SetControllerReference(owner, ing, r.Scheme))
+		}
+		require.NoError(t, r.Create(ctx, ing))
+		return ing
+	}
+	managed := map[string]string{managedByLabel: managedBy, componentLabel: componentStats}
+	gone := createDomain(t)
+	gone.Name = "gone"
+	legacy := route("legacy-stats", domain, managed)
+	orphan := route("gone-stats", gone, managed)
+	unlabelled := route("unlabelled-stats", gone, nil)
+	uncontrolled := route("uncontrolled-stats", nil, managed)
+	mtaSTS := route(mtaSTSName(domain), domain, map[string]string{managedByLabel: managedBy, componentLabel: componentMTASTS})
+
+	orphans, err = gc.Collect(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, orphans)
+	for _, ing := range []*netwrkingv1.Ingress{legacy, orphan} {
+		err := r.Get(ctx, client.ObjectKeyFromObject(ing), &netwrkingv1.Ingress{})
+		assert.True(t, apierrors.IsNotFound(err), "%s should have been deleted", ing.Name)
+	}
+	for _, ing := range []*netwrkingv1.Ingress{unlabelled, uncontrolled, mtaSTS} {
+		assert.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(ing), &netwrkingv1.Ingress{}), "%s should have been kept", ing.Name)
+	}
+	getStatsIngress(t, r, domain)
+
+	// the stats are no longer exposed
+	disabled := false
+	domain.Spec.Ingress.Enabled = &disabled
+	require.NoError(t, r.Update(ctx, domain))
+
+	gc.DryRun = true
+	orphans, err = gc.Collect(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, orphans)
+	getStatsIngress(t, r, domain)
+
+	gc.DryRun = false
+	orphans, err = gc.Collect(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, orphans)
+	err = r.Get(ctx, types.NamespacedName{Name: statsIngressName(domain), Namespace: domain.Namespace}, &netwrkingv1.Ingress{})
+	assert.True(t, apierrors.IsNotFound(err))
+}
+
+func TestResourceMetadata(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	domain.Spec.DKIM.PublicKey = ""
+	domain.Spec.ResourceLabels = map[string]string{"team": "mail", "cost-center": "42"}
+	domain.Spec.ResourceAnnotations = map[string]string{"example.com/owner": "mail-team"}
+	domain.Spec.IngressLabels = map[string]string{"team": "stats"}
+	r := createReconciler(t, checker.NewFakeDNSChecker(checker.WithAll(true)), domain)
+	r.Kannon = kannon.NewFakeClient()
+	reconcileObject(t, r, domain)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+
+	ingress := getStatsIngress(t, r, domain)
+	assert.Equal(t, map[string]string{
+		"team":                         "stats",
+		"cost-center":                  "42",
+		"app.kubernetes.io/managed-by": "k8nnon",
+		"app.kubernetes.io/component":  "stats",
+		"kannon.email/domain":          "example",
+	}, ingress.Labels)
+	assert.Equal(t, "mail-team", ingress.Annotations["example.com/owner"])
+
+	require.NotNil(t, domain.Status.DKIM)
+	secrets := map[string]string{
+		domain.Status.DKIM.SecretName: "dkim-key",
+		kannonSecretName(domain):      "kannon-credentials",
+	}
+	for name, component := range secrets {
+		secret := &corev1.Secret{}
+		require.NoError(t, r.Get(ctx, types.NamespacedName{Name: name, Namespace: domain.Namespace}, secret))
+		assert.Equal(t, "mail", secret.Labels["team"], name)
+		assert.Equal(t, component, secret.Labels["app.kubernetes.io/component"], name)
+		assert.Equal(t, "example", secret.Labels["kannon.email/domain"], name)
+		assert.Equal(t, "mail-team", secret.Annotations["example.com/owner"], name)
+	}
+
+	// the labels dropped from the spec are removed
+	domain.Spec.ResourceLabels = map[string]string{"team": "mail"}
+	domain.Spec.ResourceAnnotations = nil
+	require.NoError(t, r.Update(ctx, domain))
+	reconcileObject(t, r, domain)
+
+	ingress = getStatsIngress(t, r, domain)
+	assert.NotContains(t, ingress.Labels, "cost-center")
+	assert.NotContains(t, ingress.Annotations, "example.com/owner")
+	secret := &corev1.Secret{}
+	require.NoError(t, r.Get(ctx, types.NamespacedName{Name: domain.Status.DKIM.SecretName, Namespace: domain.Namespace}, secret))
+	assert.NotContains(t, secret.Labels, "cost-center")
+	assert.NotContains(t, secret.Annotations, "example.com/owner")
+	assert.Equal(t, "mail", secret.Labels["team"])
+}
+
+func TestDomainShard(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	owner := shard.Of(domain.Namespace, domain.Name, 2)
+
+	other, err := shard.New(1-owner, 2)
+	require.NoError(t, err)
+	r := createReconciler(t, checker.NewFakeDNSChecker(checker.WithAll(true)), domain)
+	r.Shard = other
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.Empty(t, domain.Status.Conditions)
+
+	r.Shard, err = shard.New(owner, 2)
+	require.NoError(t, err)
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.True(t, meta.IsStatusConditionTrue(domain.Status.Conditions, corev1alpha1.ConditionReady))
+}
+
+func TestDomainCleanup(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	domain.Spec.DKIM.PublicKey = ""
+	domain.Spec.DNS = &corev1alpha1.DomainDNSSpec{
+		AutoProvision: true,
+		Provider:      &corev1alpha1.DNSProviderSpec{Name: "cloudflare", CredentialsSecretName: "cloudflare"},
+	}
+	credentials := &corev1.Secret{
+		ObjectMeta: v1.ObjectMeta{Name: "cloudflare", Namespace: "default"},
+		Data:       map[string][]byte{"api-token": []byte("token")},
+	}
+
+	zone := provider.NewFakeProvider()
+	require.NoError(t, zone.Set(ctx, "example.com", "TXT", []string{"v=spf1 include:_spf.google.com -all"}, 300))
+
+	r := createReconciler(t, checker.NewFakeDNSChecker(
+		checker.WithStatsDNSStats(checker.DNSCheckStats{
+			CntOK:    1,
+			Expected: checker.Record{Type: "CNAME", Name: "stats.example.com", Value: "mx.example.com"},
+		}),
+		checker.WithSPFStats(checker.DNSCheckStats{
+			CntKO:    1,
+			Expected: checker.Record{Type: "TXT", Name: "example.com", Value: "v=spf1 include:mx.example.com ~all"},
+		}),
+		checker.WithDMARCStats(checker.DNSCheckStats{
+			CntKO:    1,
+			Expected: checker.Record{Type: "TXT", Name: "_dmarc.example.com", Value: "v=DMARC1; p=none"},
+		}),
+	), domain, credentials)
+	providerErr := error(nil)
+	r.dnsProvider = func(name, zoneName string, creds map[string][]byte) (provider.Provider, error) {
+		return zone, providerErr
+	}
+	kannonClient := kannon.NewFakeClient()
+	r.Kannon = kannonClient
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.Contains(t, domain.Finalizers, cleanupFinalizer)
+	assert.Equal(t, []corev1alpha1.OriginalSPFRecord{
+		{Name: "example.com", Value: "v=spf1 include:_spf.google.com -all"},
+	}, domain.Status.OriginalSPF)
+	require.NotNil(t, domain.Status.DKIM)
+	dkimSecret := domain.Status.DKIM.SecretName
+	getStatsIngress(t, r, domain)
+	values, err := zone.Get(ctx, "stats.example.com", "CNAME")
+	require.NoError(t, err)
+	require.Equal(t, []string{"mx.example.com"}, values)
+
+	// a failing step keeps the domain and is reported
+	providerErr = errors.New("provider unavailable")
+	require.NoError(t, r.Delete(ctx, domain))
+	_, err = r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(domain)})
+	require.Error(t, err)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	cond := meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionTerminating)
+	require.NotNil(t, cond)
+	assert.Equal(t, v1.ConditionTrue, cond.Status)
+	assert.Equal(t, corev1alpha1.ReasonCleanupFailed, cond.Reason)
+	assert.Contains(t, cond.Message, "provider unavailable")
+
+	providerErr = nil
+	reconcileObject(t, r, domain)
+
+	values, err = zone.Get(ctx, "stats.example.com", "CNAME")
+	require.NoError(t, err)
+	assert.Empty(t, values)
+
+	values, err = zone.Get(ctx, "example.com", "TXT")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"v=spf1 include:_spf.google.com -all"}, values, "should only remove the include")
+
+	values, err = zone.Get(ctx, "_dmarc.example.com", "TXT")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"v=DMARC1; p=none"}, values, "should keep the DMARC record")
+
+	_, err = kannonClient.GetDomain(ctx, "example.com")
+	assert.True(t, errors.Is(err, kannon.ErrNotFound), "the registration should be deleted: %v", err)
+
+	for _, name := range []string{dkimSecret, "example-kannon"} {
+		err = r.Get(ctx, types.NamespacedName{Name: name, Namespace: "default"}, &corev1.Secret{})
+		assert.True(t, apierrors.IsNotFound(err), "secret %s should be deleted: %v", name, err)
+	}
+	err = r.Get(ctx, types.NamespacedName{Name: "example-stats", Namespace: "default"}, &netwrkingv1.Ingress{})
+	assert.True(t, apierrors.IsNotFound(err), "the stats ingress should be deleted: %v", err)
+	err = r.Get(ctx, client.ObjectKeyFromObject(domain), domain)
+	assert.True(t, apierrors.IsNotFound(err), "the domain should be released: %v", err)
+}
+
+func TestDomainCleanupCreatedSPF(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	domain.Spec.DNS = &corev1alpha1.DomainDNSSpec{
+		AutoProvision: true,
+		Provider:      &corev1alpha1.DNSProviderSpec{Name: "cloudflare", CredentialsSecretName: "cloudflare"},
+	}
+	credentials := &corev1.Secret{
+		ObjectMeta: v1.ObjectMeta{Name: "cloudflare", Namespace: "default"},
+		Data:       map[string][]byte{"api-token": []byte("token")},
+	}
+
+	zone := provider.NewFakeProvider()
+	r := createReconciler(t, checker.NewFakeDNSChecker(
+		checker.WithSPFStats(checker.DNSCheckStats{
+			CntKO:    1,
+			Expected: checker.Record{Type: "TXT", Name: "example.com", Value: "v=spf1 include:mx.example.com ~all"},
+		}),
+	), domain, credentials)
+	r.dnsProvider = func(name, zoneName string, creds map[string][]byte) (provider.Provider, error) {
+		return zone, nil
+	}
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.Contains(t, domain.Finalizers, cleanupFinalizer)
+	assert.Equal(t, []corev1alpha1.OriginalSPFRecord{{Name: "example.com"}}, domain.Status.OriginalSPF)
+	values, err := zone.Get(ctx, "example.com", "TXT")
+	require.NoError(t, err)
+	require.Equal(t, []string{"v=spf1 include:mx.example.com ~all"}, values)
+
+	// the record created for the domain is removed, not left as v=spf1 ~all
+	require.NoError(t, r.Delete(ctx, domain))
+	reconcileObject(t, r, domain)
+
+	values, err = zone.Get(ctx, "example.com", "TXT")
+	require.NoError(t, err)
+	assert.Empty(t, values)
+}
+
+func TestDomainCleanupFinalizer(t *testing.T) {
+	ctx := context.Background()
+
+	// nothing outside of the cluster is left behind without Kannon and a
+	// DNS provider
+	domain := createDomain(t)
+	r := createReconciler(t, checker.NewFakeDNSChecker(checker.WithAll(true)), domain)
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.NotContains(t, domain.Finalizers, cleanupFinalizer)
+}
+
+func TestRemainingRecordValues(t *testing.T) {
+	record := &corev1alpha1.DNSRecord{Type: "TXT", Name: "example.com", Value: "v=spf1 include:mx.example.com ~all"}
+
+	cases := []struct {
+		current   []string
+		original  *corev1alpha1.OriginalSPFRecord
+		remaining []string
+	}{
+		{[]string{"v=spf1 include:mx.example.com ~all", "google-site-verification=abc"}, &corev1alpha1.OriginalSPFRecord{Name: "example.com"}, []string{"google-site-verification=abc"}},
+		{[]string{"v=spf1 include:_spf.google.com include:mx.example.com -all"}, &corev1alpha1.OriginalSPFRecord{Name: "example.com", Value: "v=spf1 include:_spf.google.com -all"}, []string{"v=spf1 include:_spf.google.com -all"}},
+		// the Domains created before the original records were saved
+		{[]string{"v=spf1 include:mx.example.com ~all"}, nil, []string{}},
+		{[]string{"v=spf1 include:_spf.google.com include:mx.example.com -all"}, nil, []string{"v=spf1 include:_spf.google.com -all"}},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.remaining, remainingRecordValues(record, c.current, c.original), c.current)
+	}
+}
+
+func TestGeneratedDKIMKey(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	domain.Spec.DKIM.PublicKey = ""
+	domain.Spec.DKIM.KeyType = "ed25519"
+
+	r := createReconciler(t, checker.NewFakeDNSChecker(), domain)
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	require.NotNil(t, domain.Status.DKIM)
+	assert.Equal(t, "example-dkim-selector", domain.Status.DKIM.SecretName)
+	assert.Equal(t, "ed25519", domain.Status.DKIM.KeyType)
+	assert.NotEmpty(t, domain.Status.DKIM.PublicKey)
+
+	secret := &corev1.Secret{}
+	require.NoError(t, r.Get(ctx, types.NamespacedName{Name: "example-dkim-selector", Namespace: "default"}, secret))
+	assert.True(t, v1.IsControlledBy(secret, domain), "the secret should be owned by the domain")
+	assert.NotEmpty(t, secret.Data[dkim.PrivateKeyKey])
+	assert.Equal(t, domain.Status.DKIM.PublicKey, string(secret.Data[dkim.PublicKeyKey]))
+
+	// the key is generated once
+	publicKey := domain.Status.DKIM.PublicKey
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.Equal(t, publicKey, domain.Status.DKIM.PublicKey)
+	assert.Equal(t, publicKey, domain.DKIMPublicKey())
+}
+
+func TestDKIMKeyMismatch(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	domain.Spec.DKIM.PublicKey = ""
+	domain.Spec.DKIM.KeyType = "ed25519"
+
+	dnsChecker := checker.NewFakeDNSChecker(checker.WithAll(true))
+	r := createReconciler(t, dnsChecker, domain)
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.Nil(t, meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionDKIMKeyMismatch),
+		"should not compare the key before its record is found")
+
+	published := func(publicKey string) checker.FakeOption {
+		return checker.WithDKIMStats(checker.DNSCheckStats{CntOK: 1, Observed: []string{dkim.Record(dkim.KeyTypeEd25519, publicKey)}})
+	}
+
+	dnsChecker.Set(published(domain.Status.DKIM.PublicKey))
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	cond := meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionDKIMKeyMismatch)
+	require.NotNil(t, cond)
+	assert.Equal(t, v1.ConditionFalse, cond.Status)
+	assert.Equal(t, corev1alpha1.ReasonDKIMKeyMatches, cond.Reason)
+
+	stale, err := dkim.Generate(dkim.KeyTypeEd25519)
+	require.NoError(t, err)
+	dnsChecker.Set(published(stale.PublicKey))
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	cond = meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionDKIMKeyMismatch)
+	require.NotNil(t, cond)
+	assert.Equal(t, v1.ConditionTrue, cond.Status)
+	assert.Equal(t, corev1alpha1.ReasonDKIMKeyMismatch, cond.Reason)
+	assert.Contains(t, cond.Message, "example-dkim-selector")
+
+	// a private key replaced in the Secret no longer matches its public key
+	dnsChecker.Set(published(domain.Status.DKIM.PublicKey))
+	secret := &corev1.Secret{}
+	require.NoError(t, r.Get(ctx, types.NamespacedName{Name: "example-dkim-selector", Namespace: "default"}, secret))
+	secret.Data[dkim.PrivateKeyKey] = stale.PrivateKeyPEM
+	require.NoError(t, r.Update(ctx, secret))
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	cond = meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionDKIMKeyMismatch)
+	require.NotNil(t, cond)
+	assert.Equal(t, v1.ConditionTrue, cond.Status)
+	assert.Contains(t, cond.Message, "does not match its private.key")
+}
+
+func TestDKIMKeyPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	domain.Spec.DKIM.PublicKey = ""
+
+	dnsChecker := checker.NewFakeDNSChecker(checker.WithAll(true))
+	r := createReconciler(t, dnsChecker, domain)
+	r.DKIMMinRSAKeyBits = 4096
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	dnsChecker.Set(checker.WithDKIMStats(checker.DNSCheckStats{
+		CntOK:    1,
+		Observed: []string{dkim.Record(dkim.KeyTypeRSA, domain.Status.DKIM.PublicKey)},
+	}))
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	cond := meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionDKIMKeyMismatch)
+	require.NotNil(t, cond)
+	assert.Equal(t, v1.ConditionTrue, cond.Status)
+	assert.Equal(t, corev1alpha1.ReasonDKIMKeyPolicyViolation, cond.Reason)
+	assert.Contains(t, cond.Message, "2048-bit RSA key")
+}
+
+func TestDKIMKeyRotation(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	period := 30 * 24 * time.Hour
+
+	domain := createDomain(t)
+	domain.Spec.DKIM.PublicKey = ""
+	domain.Spec.DKIM.KeyType = "ed25519"
+	domain.Spec.DKIM.RotationPeriod = &v1.Duration{Duration: period}
+
+	dnsChecker := checker.NewFakeDNSChecker(checker.WithAll(true))
+	r := createReconciler(t, dnsChecker, domain)
+	r.clock = func() time.Time { return now }
+
+	getDomain := func() *corev1alpha1.Domain {
+		d := &corev1alpha1.Domain{}
+		require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), d))
+		return d
+	}
+	secretExists := func(name string) bool {
+		err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: "default"}, &corev1.Secret{})
+		if apierrors.IsNotFound(err) {
+			return false
+		}
+		require.NoError(t, err)
+		return true
+	}
+
+	reconcileObject(t, r, domain)
+	d := getDomain()
+	assert.Equal(t, "selector", d.Status.DKIM.Selector)
+	assert.Equal(t, corev1alpha1.ReasonDKIMNotRotating, meta.FindStatusCondition(d.Status.Conditions, corev1alpha1.ConditionDKIMRotating).Reason)
+
+	// the rotation is due but the new record is not published yet
+	now = now.Add(period)
+	dnsChecker.Set(checker.WithDKIM(false))
+	res := reconcileObject(t, r, domain)
+
+	d = getDomain()
+	require.NotNil(t, d.Status.DKIM.Pending)
+	pending := d.Status.DKIM.Pending.Selector
+	assert.Equal(t, fmt.Sprintf("selector-%d", now.Unix()), pending)
+	assert.Equal(t, "selector", d.Status.DKIM.Selector, "should keep the old key active")
+	assert.True(t, secretExists(dkimSecretName(d, pending)))
+	assert.True(t, meta.IsStatusConditionTrue(d.Status.Conditions, corev1alpha1.ConditionDKIMRotating))
+	assert.LessOrEqual(t, res.RequeueAfter, pendingDKIMRecheckInterval)
+
+	// the new record is verified
+	dnsChecker.Set(checker.WithDKIM(true))
+	reconcileObject(t, r, domain)
+
+	d = getDomain()
+	assert.Nil(t, d.Status.DKIM.Pending)
+	assert.Equal(t, pending, d.Status.DKIM.Selector)
+	assert.Equal(t, pending, d.DKIMSelector())
+	require.Len(t, d.Status.DKIM.RetiringKeys, 1)
+	assert.Equal(t, "selector", d.Status.DKIM.RetiringKeys[0].Selector)
+	assert.Equal(t, corev1alpha1.ReasonDKIMRotated, meta.FindStatusCondition(d.Status.Conditions, corev1alpha1.ConditionDKIMRotating).Reason)
+	assert.True(t, secretExists("example-dkim-selector"), "should keep the old key during the grace period")
+
+	// the grace period is over
+	now = now.Add(dkimRetireGracePeriod)
+	reconcileObject(t, r, domain)
+
+	d = getDomain()
+	assert.Empty(t, d.Status.DKIM.RetiringKeys)
+	assert.False(t, secretExists("example-dkim-selector"), "should delete the old key")
+	assert.True(t, secretExists(dkimSecretName(d, pending)))
+}
+
+func TestDKIMKeySpecChange(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	domain := createDomain(t)
+	domain.Spec.DKIM.PublicKey = ""
+	domain.Spec.DKIM.KeyType = "ed25519"
+
+	r := createReconciler(t, checker.NewFakeDNSChecker(checker.WithAll(true)), domain)
+	r.clock = func() time.Time { return now }
+
+	getDomain := func() *corev1alpha1.Domain {
+		d := &corev1alpha1.Domain{}
+		require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), d))
+		return d
+	}
+	updateSpec := func(update func(*corev1alpha1.Domain)) {
+		d := getDomain()
+		update(d)
+		d.Generation++
+		require.NoError(t, r.Update(ctx, d))
+	}
+	secretExists := func(name string) bool {
+		err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: "default"}, &corev1.Secret{})
+		if apierrors.IsNotFound(err) {
+			return false
+		}
+		require.NoError(t, err)
+		return true
+	}
+
+	reconcileObject(t, r, domain)
+	assert.Equal(t, "selector", getDomain().Status.DKIM.Selector)
+
+	// a new selector in the spec rotates the key to it
+	now = now.Add(time.Hour)
+	updateSpec(func(d *corev1alpha1.Domain) { d.Spec.DKIM.Selector = "kannon" })
+	reconcileObject(t, r, domain)
+
+	d := getDomain()
+	assert.Equal(t, "kannon", d.Status.DKIM.Selector)
+	assert.Equal(t, "ed25519", d.Status.DKIM.KeyType)
+	require.Len(t, d.Status.DKIM.RetiringKeys, 1)
+	assert.Equal(t, "selector", d.Status.DKIM.RetiringKeys[0].Selector)
+
+	// a new key type rotates the key again, within the grace period of the
+	// previous rotation
+	now = now.Add(time.Hour)
+	updateSpec(func(d *corev1alpha1.Domain) { d.Spec.DKIM.KeyType = "rsa" })
+	reconcileObject(t, r, domain)
+
+	d = getDomain()
+	assert.Equal(t, fmt.Sprintf("kannon-g%d", d.Generation), d.Status.DKIM.Selector)
+	assert.Equal(t, "rsa", d.Status.DKIM.KeyType)
+	require.Len(t, d.Status.DKIM.RetiringKeys, 2)
+	assert.Equal(t, "selector", d.Status.DKIM.RetiringKeys[0].Selector)
+	assert.Equal(t, "kannon", d.Status.DKIM.RetiringKeys[1].Selector)
+	assert.True(t, secretExists("example-dkim-selector"), "should keep the first key during its grace period")
+	assert.True(t, secretExists("example-dkim-kannon"), "should keep the second key during its grace period")
+
+	// each key is deleted at the end of its own grace period
+	now = now.Add(dkimRetireGracePeriod - time.Hour)
+	reconcileObject(t, r, domain)
+
+	d = getDomain()
+	require.Len(t, d.Status.DKIM.RetiringKeys, 1)
+	assert.Equal(t, "kannon", d.Status.DKIM.RetiringKeys[0].Selector)
+	assert.False(t, secretExists("example-dkim-selector"))
+	assert.True(t, secretExists("example-dkim-kannon"))
+}
+
+// failingStatusClient fails the given number of status writes.
+type failingStatusClient struct {
+	client.Client
+	failures *int
+}
+
+func (c failingStatusClient) Status() client.SubResourceWriter {
+	return failingStatusWriter{SubResourceWriter: c.Client.Status(), failures: c.failures}
+}
+
+type failingStatusWriter struct {
+	client.SubResourceWriter
+	failures *int
+}
+
+func (w failingStatusWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+	if *w.failures > 0 {
+		*w.failures--
+		return errors.New("status write failed")
+	}
+	return w.SubResourceWriter.Patch(ctx, obj, patch, opts...)
+}
+
+func (w failingStatusWriter) Update(ctx context.Context, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+	if *w.failures > 0 {
+		*w.failures--
+		return errors.New("status write failed")
+	}
+	return w.SubResourceWriter.Update(ctx, obj, opts...)
+}
+
+func TestDKIMKeyRotationRetry(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	period := 30 * 24 * time.Hour
+
+	domain := createDomain(t)
+	domain.Spec.DKIM.PublicKey = ""
+	domain.Spec.DKIM.KeyType = "ed25519"
+	domain.Spec.DKIM.RotationPeriod = &v1.Duration{Duration: period}
+
+	dnsChecker := checker.NewFakeDNSChecker(checker.WithAll(true))
+	r := createReconciler(t, dnsChecker, domain)
+	r.clock = func() time.Time { return now }
+	reconcileObject(t, r, domain)
+
+	// the status recording the pending key is lost
+	now = now.Add(period + time.Hour)
+	dnsChecker.Set(checker.WithDKIM(false))
+	c := r.Client
+	failures := 1
+	r.Client = failingStatusClient{Client: c, failures: &failures}
+	_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(domain)})
+	require.Error(t, err)
+	r.Client = c
+
+	now = now.Add(time.Minute)
+	reconcileObject(t, r, domain)
+
+	secrets := &corev1.SecretList{}
+	require.NoError(t, r.List(ctx, secrets, client.InNamespace("default")))
+	var pending []string
+	for _, secret := range secrets.Items {
+		if strings.HasPrefix(secret.Name, "example-dkim-selector-") {
+			pending = append(pending, secret.Name)
+		}
+	}
+	assert.Len(t, pending, 1, "the retried rotation should have reused the pending key")
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	require.NotNil(t, domain.Status.DKIM.Pending)
+	assert.Equal(t, pending, []string{domain.Status.DKIM.Pending.SecretName})
+}
+
+func TestClusterDomainConfigDefaults(t *testing.T) {
+	ctx := context.Background()
+
+	cfg := &corev1alpha1.ClusterDomainConfig{
+		ObjectMeta: v1.ObjectMeta{Name: "default"},
+		Spec: corev1alpha1.ClusterDomainConfigSpec{
+			Ingress: &corev1alpha1.IngressDefaults{
+				ClassName: "nginx",
+				Service:   &corev1alpha1.DomainIngressServiceSpec{Name: "kannon-stats-shared", Port: 8080},
+			},
+			CheckInterval: &v1.Duration{Duration: 2 * time.Hour},
+		},
+	}
+	domain := createDomain(t)
+	domain.Spec.Ingress.Service = corev1alpha1.DomainIngressServiceSpec{}
+
+	r := createReconciler(t, checker.NewFakeDNSChecker(checker.WithAll(true)), cfg, domain)
+	r.ClusterConfigName = "default"
+	reconcileObject(t, r, domain)
+	res := reconcileObject(t, r, domain)
+	assert.Equal(t, 2*time.Hour, res.RequeueAfter)
+
+	ingress := getStatsIngress(t, r, domain)
+	require.NotNil(t, ingress.Spec.IngressClassName)
+	assert.Equal(t, "nginx", *ingress.Spec.IngressClassName)
+	backend := ingress.Spec.Rules[0].HTTP.Paths[0].Backend.Service
+	assert.Equal(t, "kannon-stats-shared", backend.Name)
+	assert.Equal(t, int32(8080), backend.Port.Number)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.Empty(t, domain.Spec.Ingress.Service.Name, "the defaults should not be written to the spec")
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cfg), cfg))
+	cfg.Spec.Ingress.Service.Name = "kannon-stats-v2"
+	require.NoError(t, r.Update(ctx, cfg))
+	reconcileObject(t, r, domain)
+
+	ingress = getStatsIngress(t, r, domain)
+	assert.Equal(t, "kannon-stats-v2", ingress.Spec.Rules[0].HTTP.Paths[0].Backend.Service.Name)
+
+	// the Domain keeps the class and the service it sets
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	domain.Spec.Ingress.ClassName = "traefik"
+	domain.Spec.Ingress.Service = corev1alpha1.DomainIngressServiceSpec{Name: "kannon-stats", Port: 80}
+	require.NoError(t, r.Update(ctx, domain))
+	reconcileObject(t, r, domain)
+
+	ingress = getStatsIngress(t, r, domain)
+	assert.Equal(t, "traefik", *ingress.Spec.IngressClassName)
+	assert.Equal(t, "kannon-stats", ingress.Spec.Rules[0].HTTP.Paths[0].Backend.Service.Name)
+}
+
+func TestClusterDomainConfigNoStatsService(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	domain.Spec.Ingress.Service = corev1alpha1.DomainIngressServiceSpec{}
+
+	r := createReconciler(t, checker.NewFakeDNSChecker(checker.WithAll(true)), domain)
+	r.ClusterConfigName = "default"
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	cond := meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionIngressReady)
+	require.NotNil(t, cond)
+	assert.Equal(t, v1.ConditionFalse, cond.Status)
+	assert.Equal(t, corev1alpha1.ReasonNoStatsService, cond.Reason)
+}
+
+func TestClusterDomainConfigDKIMKeyBits(t *testing.T) {
+	ctx := context.Background()
+
+	cfg := &corev1alpha1.ClusterDomainConfig{
+		ObjectMeta: v1.ObjectMeta{Name: "default"},
+		Spec: corev1alpha1.ClusterDomainConfigSpec{
+			DKIM: &corev1alpha1.DKIMDefaults{RSAKeyBits: 1024, MinRSAKeyBits: 1024},
+		},
+	}
+	domain := createDomain(t)
+	domain.Spec.DKIM.PublicKey = ""
+
+	r := createReconciler(t, checker.NewFakeDNSChecker(checker.WithAll(true)), cfg, domain)
+	r.ClusterConfigName = "default"
+	r.DKIMMinRSAKeyBits = 4096
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	require.NotNil(t, domain.Status.DKIM)
+	bits, err := dkim.KeyBits(dkim.KeyTypeRSA, domain.Status.DKIM.PublicKey)
+	require.NoError(t, err)
+	assert.Equal(t, 1024, bits)
+	assert.Equal(t, 1024, r.minRSAKeyBits(), "the config should prevail over the flag")
+}
+
+func createReconciler(t *testing.T, dnsChecker checker.DNSChecker, objs ...client.Object) *DomainReconciler {
+	t.Helper()
+
+	c, scheme := newFakeClient(t, objs...)
+	return &DomainReconciler{Client: c, Scheme: scheme, DNSChecker: dnsChecker}
+}
+
+func getStatsIngress(t *testing.T, r *DomainReconciler, domain *corev1alpha1.Domain) *netwrkingv1.Ingress {
+	t.Helper()
+
+	ingress := &netwrkingv1.Ingress{}
+	key := types.NamespacedName{Name: statsIngressName(domain), Namespace: domain.Namespace}
+	require.NoError(t, r.Get(context.Background(), key, ingress))
+
+	return ingress
+}
+
+func createCertificate(t *testing.T, host string, notAfter time.Time) []byte {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: host},
+		DNSNames:     []string{host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func createDomain(t *testing.T) *corev1alpha1.Domain {
+	t.Helper()
+
+	return &corev1alpha1.Domain{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "example",
+			Namespace: "default",
+		},
+		Spec: corev1alpha1.DomainSpec{
+			DomainName: "example.com",
+			DKIM: corev1alpha1.DKIM{
+				Selector:  "selector",
+				PublicKey: "publicKey",
+			},
+			BaseDomain:  "mx.example.com",
+			StatsPrefix: "stats",
+			Ingress: corev1alpha1.DomainIngressSpec{
+				Service: corev1alpha1.DomainIngressServiceSpec{
+					Name: "kannon-stats",
+					Port: 80,
+				},
+			},
+		},
+	}
+}
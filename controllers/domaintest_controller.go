@@ -0,0 +1,318 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+	"github.com/kannon-email/k8nnon/internal/dkim"
+	"github.com/kannon-email/k8nnon/internal/dns/checker"
+	"github.com/kannon-email/k8nnon/internal/kannon"
+	"github.com/kannon-email/k8nnon/internal/shard"
+	"github.com/kannon-email/k8nnon/internal/smtpsink"
+	"github.com/kannon-email/k8nnon/internal/tracing"
+)
+
+const (
+	// domainTestPollInterval is how often the inbox of the SMTP sink is
+	// looked at while a test message is awaited.
+	domainTestPollInterval = 10 * time.Second
+
+	// defaultDomainTestRecipient is the local part of the sink address the
+	// test messages are sent to when spec.to is not set.
+	defaultDomainTestRecipient = "seed"
+
+	domainTestTokenSize = 8
+)
+
+// DomainTestReconciler reconciles a DomainTest object
+type DomainTestReconciler struct {
+	client.Client
+	clock
+	Scheme *runtime.Scheme
+
+	// Kannon sends the test messages.
+	Kannon kannon.Client
+
+	// DNSChecker checks the SPF authorization of the received messages.
+	DNSChecker checker.DNSChecker
+
+	// LookupTXT looks up the DKIM keys and the DMARC records the received
+	// messages are checked against.
+	LookupTXT dkim.TXTLookup
+
+	// Inbox holds the messages received by the SMTP sink.
+	Inbox *smtpsink.Inbox
+
+	// SinkDomain is the domain whose mail the SMTP sink accepts.
+	SinkDomain string
+
+	// Shard is the share of the DomainTests the replica reconciles.
+	Shard shard.Shard
+}
+
+//+kubebuilder:rbac:groups=core.k8s.kannon.email,resources=domaintests,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core.k8s.kannon.email,resources=domaintests/status,verbs=get;update;patch
+
+// Reconcile sends the test message of a DomainTest through Kannon, then
+// waits for the SMTP sink to receive it and records its authentication.
+// A finished test is not run again until its spec changes.
+func (r *DomainTestReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	if !r.Shard.Owns(req.Namespace, req.Name) {
+		return ctrl.Result{}, nil
+	}
+
+	l := log.FromContext(ctx)
+	l.Info("reconciling domain test", "domainTest", req.NamespacedName)
+
+	test := &corev1alpha1.DomainTest{}
+	if err := r.Get(ctx, req.NamespacedName, test); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if test.Status.ObservedGeneration != test.Generation || test.Status.Token == "" {
+		token, err := domainTestToken()
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		test.Status = corev1alpha1.DomainTestStatus{
+			ObservedGeneration: test.Generation,
+			Token:              token,
+			StartTime:          &v1.Time{Time: r.now()},
+		}
+	} else if domainTestFinished(test) {
+		return ctrl.Result{}, nil
+	}
+
+	cond, runErr := r.runTest(ctx, test, l)
+	meta.SetStatusCondition(&test.Status.Conditions, cond)
+
+	if err := r.Status().Update(ctx, test); err != nil {
+		return ctrl.Result{}, err
+	}
+	if runErr != nil {
+		return ctrl.Result{}, runErr
+	}
+	if cond.Status != v1.ConditionUnknown {
+		return ctrl.Result{}, nil
+	}
+
+	d := domainTestPollInterval
+	if left := r.deadline(test).Sub(r.now()); left < d {
+		d = left
+	}
+	if d <= 0 {
+		d = time.Nanosecond
+	}
+	return ctrl.Result{RequeueAfter: d}, nil
+}
+
+// runTest sends the test message when not sent yet, else looks for it in
+// the inbox, and returns the resulting Passed condition. The condition is
+// Unknown while the test runs. The returned error is retried.
+func (r *DomainTestReconciler) runTest(ctx context.Context, test *corev1alpha1.DomainTest, l logr.Logger) (v1.Condition, error) {
+	cond := v1.Condition{
+		Type:               corev1alpha1.ConditionTestPassed,
+		Status:             v1.ConditionUnknown,
+		ObservedGeneration: test.Generation,
+	}
+	timedOut := !r.now().Before(r.deadline(test))
+
+	if test.Status.SendingTime == nil && test.Status.SentTime == nil {
+		if timedOut {
+			return domainTestTimedOut(cond, test, "sent"), nil
+		}
+		return r.sendTest(ctx, test, cond, l)
+	}
+
+	token := test.Status.Token
+	msg, found := r.Inbox.Find(func(msg smtpsink.Message) bool {
+		return bytes.Contains(msg.Data, []byte(token))
+	})
+	if !found {
+		if timedOut {
+			return domainTestTimedOut(cond, test, "received"), nil
+		}
+		cond.Reason = corev1alpha1.ReasonTestSent
+		cond.Message = fmt.Sprintf("the message was sent to %s, awaiting it", test.Status.Recipient)
+		if test.Status.SentTime == nil {
+			cond.Message = fmt.Sprintf("the message may have been sent to %s, awaiting it", test.Status.Recipient)
+		}
+		return cond, nil
+	}
+
+	l.Info("received domain test message", "from", msg.MailFrom, "ip", msg.RemoteIP)
+	auth := authenticateMessage(ctx, r.LookupTXT, r.DNSChecker, msg)
+	test.Status.ReceivedTime = &v1.Time{Time: msg.ReceivedAt}
+	test.Status.DKIM = &auth.DKIM
+	test.Status.SPF = &auth.SPF
+	test.Status.DMARC = &auth.DMARC
+
+	if !auth.passed() {
+		cond.Status = v1.ConditionFalse
+		cond.Reason = corev1alpha1.ReasonTestFailed
+		cond.Message = strings.Join(auth.failures(), "; ")
+		return cond, nil
+	}
+	cond.Status = v1.ConditionTrue
+	cond.Reason = corev1alpha1.ReasonTestPassed
+	cond.Message = "the message was received with a valid DKIM signature, an authorized sending address and an aligned From domain"
+	return cond, nil
+}
+
+// sendTest sends the test message with the sending credentials of the
+// Domain.
+func (r *DomainTestReconciler) sendTest(ctx context.Context, test *corev1alpha1.DomainTest, cond v1.Condition, l logr.Logger) (v1.Condition, error) {
+	domain := &corev1alpha1.Domain{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: test.Namespace, Name: test.Spec.DomainRef.Name}, domain)
+	if apierrors.IsNotFound(err) {
+		cond.Reason = corev1alpha1.ReasonTestDomainNotFound
+		cond.Message = fmt.Sprintf("the domain %s does not exist", test.Spec.DomainRef.Name)
+		return cond, nil
+	}
+	if err != nil {
+		return cond, err
+	}
+
+	credentials, err := kannonCredentials(ctx, r.Client, domain)
+	if err != nil {
+		return cond, err
+	}
+	if credentials.Domain == "" || credentials.Key == "" {
+		cond.Reason = corev1alpha1.ReasonTestDomainNotRegistered
+		cond.Message = fmt.Sprintf("the domain %s is not registered with kannon yet", domain.Spec.DomainName)
+		return cond, nil
+	}
+
+	to := test.Spec.To
+	if to == "" {
+		if r.SinkDomain == "" {
+			cond.Status = v1.ConditionFalse
+			cond.Reason = corev1alpha1.ReasonTestSendFailed
+			cond.Message = "spec.to is not set and the SMTP sink has no domain"
+			return cond, nil
+		}
+		to = fmt.Sprintf("%s@%s", defaultDomainTestRecipient, r.SinkDomain)
+	}
+
+	// a message whose sending is not recorded is awaited rather than sent
+	// again, the test is marked as sending first
+	sending := cond
+	sending.Reason = corev1alpha1.ReasonTestSending
+	sending.Message = fmt.Sprintf("sending the message to %s", to)
+	meta.SetStatusCondition(&test.Status.Conditions, sending)
+	test.Status.Recipient = to
+	test.Status.SendingTime = &v1.Time{Time: r.now()}
+	if err := r.Status().Update(ctx, test); err != nil {
+		test.Status.SendingTime = nil
+		cond.Reason = corev1alpha1.ReasonTestSendFailed
+		cond.Message = err.Error()
+		return cond, err
+	}
+
+	token := test.Status.Token
+	id, err := r.Kannon.SendHTML(ctx, credentials, kannon.Message{
+		SenderEmail: fmt.Sprintf("%s@%s", test.Spec.SenderOrDefault(), credentials.Domain),
+		Subject:     fmt.Sprintf("k8nnon domain test %s", token),
+		HTML:        fmt.Sprintf("<p>This message tests the authentication of the mail of %s, token %s.</p>", credentials.Domain, token),
+		Recipients:  []string{to},
+	})
+	if err != nil {
+		// the message was not sent, it is sent again
+		test.Status.SendingTime = nil
+	}
+	if errors.Is(err, kannon.ErrUnsupported) {
+		cond.Status = v1.ConditionFalse
+		cond.Reason = corev1alpha1.ReasonTestSendFailed
+		cond.Message = "kannon can't send the test message: " + err.Error()
+		return cond, nil
+	}
+	if err != nil {
+		cond.Reason = corev1alpha1.ReasonTestSendFailed
+		cond.Message = err.Error()
+		return cond, err
+	}
+
+	l.Info("sent domain test message", "domain", credentials.Domain, "to", to, "messageID", id)
+	test.Status.MessageID = id
+	test.Status.SentTime = &v1.Time{Time: r.now()}
+
+	cond.Reason = corev1alpha1.ReasonTestSent
+	cond.Message = fmt.Sprintf("the message was sent to %s, awaiting it", to)
+	return cond, nil
+}
+
+func domainTestTimedOut(cond v1.Condition, test *corev1alpha1.DomainTest, step string) v1.Condition {
+	cond.Status = v1.ConditionFalse
+	cond.Reason = corev1alpha1.ReasonTestTimedOut
+	cond.Message = fmt.Sprintf("the message was not %s within %s", step, test.Spec.TimeoutOrDefault())
+	if prev := meta.FindStatusCondition(test.Status.Conditions, corev1alpha1.ConditionTestPassed); prev != nil && step == "sent" {
+		cond.Message += ": " + prev.Message
+	}
+	return cond
+}
+
+// domainTestFinished reports whether the test of the current generation
+// passed or failed.
+func domainTestFinished(test *corev1alpha1.DomainTest) bool {
+	cond := meta.FindStatusCondition(test.Status.Conditions, corev1alpha1.ConditionTestPassed)
+	return cond != nil && cond.ObservedGeneration == test.Generation && cond.Status != v1.ConditionUnknown
+}
+
+// deadline is when the test times out.
+func (r *DomainTestReconciler) deadline(test *corev1alpha1.DomainTest) time.Time {
+	start := test.CreationTimestamp.Time
+	if test.Status.StartTime != nil {
+		start = test.Status.StartTime.Time
+	}
+	return start.Add(test.Spec.TimeoutOrDefault())
+}
+
+func domainTestToken() (string, error) {
+	b := make([]byte, domainTestTokenSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *DomainTestReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&corev1alpha1.DomainTest{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Complete(tracing.Reconciler("DomainTest", r))
+}
@@ -0,0 +1,321 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+	"github.com/kannon-email/k8nnon/internal/dkim"
+	"github.com/kannon-email/k8nnon/internal/dns/checker"
+	"github.com/kannon-email/k8nnon/internal/kannon"
+	"github.com/kannon-email/k8nnon/internal/smtpsink"
+)
+
+func TestDomainTestPassed(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	kannonClient := createKannonClient(t, domain)
+	kp, err := dkim.Generate(dkim.KeyTypeEd25519)
+	require.NoError(t, err)
+	records := map[string]string{
+		"kannon._domainkey.example.com": dkim.Record(kp.Type, kp.PublicKey),
+		"_dmarc.example.com":            "v=DMARC1; p=reject",
+	}
+	now := time.Now().Truncate(time.Second)
+	test := createDomainTest(t)
+	r := createDomainTestReconciler(t, kannonClient, checker.NewFakeDNSChecker(checker.WithAll(true)), records,
+		func() time.Time { return now }, domain, createKannonSecret(t), test)
+
+	res := reconcileObject(t, r, test)
+	assert.Equal(t, domainTestPollInterval, res.RequeueAfter)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(test), test))
+	sent := kannonClient.Sent("example.com")
+	require.Len(t, sent, 1)
+	assert.Equal(t, "k8nnon-test@example.com", sent[0].SenderEmail)
+	assert.Equal(t, []string{"seed@sink.example.net"}, sent[0].Recipients)
+	assert.Contains(t, sent[0].Subject, test.Status.Token)
+	assert.Equal(t, "message-1@example.com", test.Status.MessageID)
+	cond := meta.FindStatusCondition(test.Status.Conditions, corev1alpha1.ConditionTestPassed)
+	require.NotNil(t, cond)
+	assert.Equal(t, v1.ConditionUnknown, cond.Status)
+	assert.Equal(t, corev1alpha1.ReasonTestSent, cond.Reason)
+
+	// awaited until received
+	now = now.Add(time.Minute)
+	reconcileObject(t, r, test)
+	assert.Len(t, kannonClient.Sent("example.com"), 1, "the message should be sent once")
+
+	data, err := dkim.Sign(receivedTestMessage(sent[0]), "example.com", "kannon", kp.PrivateKeyPEM, "Subject")
+	require.NoError(t, err)
+	r.Inbox.Deliver(smtpsink.Message{
+		RemoteIP:   "192.0.2.1",
+		Helo:       "mx.kannon.email",
+		MailFrom:   "bounces@mail.example.com",
+		Recipients: sent[0].Recipients,
+		Data:       data,
+		ReceivedAt: now,
+	})
+
+	res = reconcileObject(t, r, test)
+	assert.Zero(t, res.RequeueAfter, "a finished test should not be requeued")
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(test), test))
+	assert.True(t, meta.IsStatusConditionTrue(test.Status.Conditions, corev1alpha1.ConditionTestPassed), "%v", test.Status.Conditions)
+	assert.Equal(t, &corev1alpha1.MessageAuthResult{
+		Result:   corev1alpha1.AuthResultPass,
+		Domain:   "example.com",
+		Selector: "kannon",
+		Message:  "the message is signed by example.com with the key kannon",
+	}, test.Status.DKIM)
+	assert.Equal(t, &corev1alpha1.MessageAuthResult{
+		Result:  corev1alpha1.AuthResultPass,
+		Domain:  "mail.example.com",
+		IP:      "192.0.2.1",
+		Message: "192.0.2.1 is authorized by the SPF record of mail.example.com",
+	}, test.Status.SPF)
+	assert.Equal(t, &corev1alpha1.MessageAuthResult{
+		Result:  corev1alpha1.AuthResultPass,
+		Domain:  "example.com",
+		Message: "the From domain is aligned with the DKIM signing domain example.com",
+	}, test.Status.DMARC)
+	assert.Equal(t, now, test.Status.ReceivedTime.Time)
+}
+
+func TestDomainTestAuthenticationFailed(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	kannonClient := createKannonClient(t, domain)
+	test := createDomainTest(t)
+	r := createDomainTestReconciler(t, kannonClient, checker.NewFakeDNSChecker(checker.WithSPFCoverage(false)), nil,
+		time.Now, domain, createKannonSecret(t), test)
+
+	reconcileObject(t, r, test)
+	sent := kannonClient.Sent("example.com")
+	require.Len(t, sent, 1)
+
+	// unsigned, from an address the SPF record does not authorize and
+	// without a DMARC record
+	r.Inbox.Deliver(smtpsink.Message{
+		RemoteIP:   "192.0.2.1",
+		MailFrom:   "k8nnon-test@example.com",
+		Recipients: sent[0].Recipients,
+		Data:       receivedTestMessage(sent[0]),
+		ReceivedAt: time.Now(),
+	})
+	reconcileObject(t, r, test)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(test), test))
+	assert.Equal(t, corev1alpha1.AuthResultNone, test.Status.DKIM.Result)
+	assert.Equal(t, corev1alpha1.AuthResultFail, test.Status.SPF.Result)
+	assert.Equal(t, corev1alpha1.AuthResultNone, test.Status.DMARC.Result)
+	cond := meta.FindStatusCondition(test.Status.Conditions, corev1alpha1.ConditionTestPassed)
+	require.NotNil(t, cond)
+	assert.Equal(t, v1.ConditionFalse, cond.Status)
+	assert.Equal(t, corev1alpha1.ReasonTestFailed, cond.Reason)
+	assert.Equal(t, "DKIM none: the message is not signed; "+
+		"SPF fail: 192.0.2.1 is not authorized by the SPF record of example.com; "+
+		"DMARC none: no DMARC record is published for example.com", cond.Message)
+}
+
+func TestDomainTestTimedOut(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	kannonClient := createKannonClient(t, domain)
+	now := time.Now().Truncate(time.Second)
+	test := createDomainTest(t)
+	test.Spec.Timeout = &v1.Duration{Duration: time.Minute}
+	r := createDomainTestReconciler(t, kannonClient, checker.NewFakeDNSChecker(checker.WithAll(true)), nil,
+		func() time.Time { return now }, domain, createKannonSecret(t), test)
+
+	reconcileObject(t, r, test)
+
+	now = now.Add(50 * time.Second)
+	res := reconcileObject(t, r, test)
+	assert.Equal(t, 10*time.Second, res.RequeueAfter, "should be requeued at the deadline")
+
+	now = now.Add(10 * time.Second)
+	res = reconcileObject(t, r, test)
+	assert.Zero(t, res.RequeueAfter)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(test), test))
+	cond := meta.FindStatusCondition(test.Status.Conditions, corev1alpha1.ConditionTestPassed)
+	require.NotNil(t, cond)
+	assert.Equal(t, v1.ConditionFalse, cond.Status)
+	assert.Equal(t, corev1alpha1.ReasonTestTimedOut, cond.Reason)
+	assert.Equal(t, "the message was not received within 1m0s", cond.Message)
+
+	// a new spec runs the test again
+	test.Spec.Timeout = &v1.Duration{Duration: 5 * time.Minute}
+	test.Generation++
+	require.NoError(t, r.Update(ctx, test))
+	reconcileObject(t, r, test)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(test), test))
+	assert.Len(t, kannonClient.Sent("example.com"), 2)
+	assert.Nil(t, test.Status.DKIM)
+	assert.Equal(t, corev1alpha1.ReasonTestSent, meta.FindStatusCondition(test.Status.Conditions, corev1alpha1.ConditionTestPassed).Reason)
+}
+
+func TestDomainTestSentOnce(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	kannonClient := createKannonClient(t, domain)
+	test := createDomainTest(t)
+	r := createDomainTestReconciler(t, kannonClient, checker.NewFakeDNSChecker(checker.WithAll(true)), nil,
+		time.Now, domain, createKannonSecret(t), test)
+
+	// the status write after the message is sent fails
+	failures := 0
+	c := r.Client
+	r.Client = failingStatusClient{Client: c, failures: &failures}
+	r.Kannon = sendHookClient{Client: kannonClient, sent: func() { failures = 1 }}
+	_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(test)})
+	require.Error(t, err)
+	r.Client = c
+	require.Len(t, kannonClient.Sent("example.com"), 1)
+
+	reconcileObject(t, r, test)
+	sent := kannonClient.Sent("example.com")
+	assert.Len(t, sent, 1, "the message should not be sent again")
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(test), test))
+	assert.Contains(t, sent[0].Subject, test.Status.Token, "the message should be awaited")
+	assert.Empty(t, test.Status.MessageID)
+	cond := meta.FindStatusCondition(test.Status.Conditions, corev1alpha1.ConditionTestPassed)
+	require.NotNil(t, cond)
+	assert.Equal(t, corev1alpha1.ReasonTestSent, cond.Reason)
+	assert.Equal(t, "the message may have been sent to seed@sink.example.net, awaiting it", cond.Message)
+
+	r.Inbox.Deliver(smtpsink.Message{
+		RemoteIP:   "192.0.2.1",
+		MailFrom:   "k8nnon-test@example.com",
+		Recipients: sent[0].Recipients,
+		Data:       receivedTestMessage(sent[0]),
+		ReceivedAt: time.Now(),
+	})
+	reconcileObject(t, r, test)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(test), test))
+	assert.NotNil(t, test.Status.ReceivedTime)
+}
+
+// sendHookClient calls sent once a message is sent.
+type sendHookClient struct {
+	kannon.Client
+	sent func()
+}
+
+func (c sendHookClient) SendHTML(ctx context.Context, credentials kannon.Credentials, msg kannon.Message) (string, error) {
+	id, err := c.Client.SendHTML(ctx, credentials, msg)
+	c.sent()
+	return id, err
+}
+
+func TestDomainTestNotRegistered(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	test := createDomainTest(t)
+	r := createDomainTestReconciler(t, kannon.NewFakeClient(), checker.NewFakeDNSChecker(), nil, time.Now, domain, test)
+
+	res := reconcileObject(t, r, test)
+	assert.Equal(t, domainTestPollInterval, res.RequeueAfter)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(test), test))
+	cond := meta.FindStatusCondition(test.Status.Conditions, corev1alpha1.ConditionTestPassed)
+	require.NotNil(t, cond)
+	assert.Equal(t, v1.ConditionUnknown, cond.Status)
+	assert.Equal(t, corev1alpha1.ReasonTestDomainNotRegistered, cond.Reason)
+	assert.Equal(t, "the domain example.com is not registered with kannon yet", cond.Message)
+}
+
+// receivedTestMessage returns the message the sink receives for msg.
+func receivedTestMessage(msg kannon.Message) []byte {
+	return []byte("From: " + msg.SenderEmail + "\r\n" +
+		"To: " + strings.Join(msg.Recipients, ", ") + "\r\n" +
+		"Subject: " + msg.Subject + "\r\n" +
+		"Content-Type: text/html\r\n" +
+		"\r\n" +
+		msg.HTML + "\r\n")
+}
+
+func createKannonSecret(t *testing.T) *corev1.Secret {
+	t.Helper()
+
+	return &corev1.Secret{
+		ObjectMeta: v1.ObjectMeta{Name: "example-kannon", Namespace: "default"},
+		Data: map[string][]byte{
+			kannonDomainKey: []byte("example.com"),
+			kannonKeyKey:    []byte("key-example.com"),
+		},
+	}
+}
+
+func createDomainTestReconciler(t *testing.T, kannonClient kannon.Client, dnsChecker checker.DNSChecker, records map[string]string, clock func() time.Time, objs ...client.Object) *DomainTestReconciler {
+	t.Helper()
+
+	c, scheme := newFakeClient(t, objs...)
+	return &DomainTestReconciler{
+		Client:     c,
+		Scheme:     scheme,
+		Kannon:     kannonClient,
+		DNSChecker: dnsChecker,
+		LookupTXT: func(ctx context.Context, name string) ([]string, error) {
+			txt, ok := records[name]
+			if !ok {
+				return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+			}
+			return []string{txt}, nil
+		},
+		Inbox:      smtpsink.NewInbox(10),
+		SinkDomain: "sink.example.net",
+		clock:      clock,
+	}
+}
+
+func createDomainTest(t *testing.T) *corev1alpha1.DomainTest {
+	t.Helper()
+
+	return &corev1alpha1.DomainTest{
+		ObjectMeta: v1.ObjectMeta{
+			Name:       "deliverability",
+			Namespace:  "default",
+			Generation: 1,
+		},
+		Spec: corev1alpha1.DomainTestSpec{
+			DomainRef: corev1alpha1.DomainReference{Name: "example"},
+		},
+	}
+}
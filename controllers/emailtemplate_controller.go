@@ -0,0 +1,377 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+	"github.com/kannon-email/k8nnon/internal/kannon"
+	"github.com/kannon-email/k8nnon/internal/shard"
+	"github.com/kannon-email/k8nnon/internal/tracing"
+)
+
+// emailTemplateFinalizer delays the deletion of an EmailTemplate until the
+// remote template is deleted.
+const emailTemplateFinalizer = "core.k8s.kannon.email/emailtemplate-cleanup"
+
+// templateResyncPeriod is how often the synced templates are checked to
+// still exist in Kannon.
+const templateResyncPeriod = time.Hour
+
+// htmlFromIndex indexes the EmailTemplates by the ConfigMap they read their
+// body from.
+const htmlFromIndex = "spec.htmlFrom.name"
+
+func indexHTMLFrom(obj client.Object) []string {
+	template, ok := obj.(*corev1alpha1.EmailTemplate)
+	if !ok || template.Spec.HTMLFrom == nil {
+		return nil
+	}
+	return []string{template.Spec.HTMLFrom.Name}
+}
+
+// EmailTemplateReconciler reconciles a EmailTemplate object
+type EmailTemplateReconciler struct {
+	client.Client
+	clock
+	Scheme *runtime.Scheme
+
+	// Kannon stores the templates.
+	Kannon kannon.Client
+
+	// APIReader reads the ConfigMaps of the templates, which are only
+	// cached by their metadata. Nil reads them with Client.
+	APIReader client.Reader
+
+	// Shard is the share of the EmailTemplates the replica reconciles.
+	Shard shard.Shard
+}
+
+//+kubebuilder:rbac:groups=core.k8s.kannon.email,resources=emailtemplates,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=core.k8s.kannon.email,resources=emailtemplates/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=core.k8s.kannon.email,resources=emailtemplates/finalizers,verbs=update
+
+// Reconcile uploads the template to Kannon when its content changes or
+// the remote template is gone, and deletes the remote template with the
+// EmailTemplate. The synced templates are checked every
+// templateResyncPeriod.
+func (r *EmailTemplateReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	if !r.Shard.Owns(req.Namespace, req.Name) {
+		return ctrl.Result{}, nil
+	}
+
+	l := log.FromContext(ctx)
+	l.Info("reconciling email template", "emailTemplate", req.NamespacedName)
+
+	template := &corev1alpha1.EmailTemplate{}
+	if err := r.Get(ctx, req.NamespacedName, template); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if !template.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, r.finalizeEmailTemplate(ctx, template, l)
+	}
+
+	if !controllerutil.ContainsFinalizer(template, emailTemplateFinalizer) {
+		controllerutil.AddFinalizer(template, emailTemplateFinalizer)
+		if err := r.Update(ctx, template); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	cond, syncErr := r.syncTemplate(ctx, template, l)
+	template.Status.ObservedGeneration = template.Generation
+	meta.SetStatusCondition(&template.Status.Conditions, cond)
+
+	if err := r.Status().Update(ctx, template); err != nil {
+		return ctrl.Result{}, err
+	}
+	if syncErr != nil {
+		return ctrl.Result{}, syncErr
+	}
+
+	if template.Status.TemplateID != "" {
+		return ctrl.Result{RequeueAfter: templateResyncPeriod}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// syncTemplate uploads the template when missing or changed and returns the
+// resulting Ready condition. The returned error is retried.
+func (r *EmailTemplateReconciler) syncTemplate(ctx context.Context, template *corev1alpha1.EmailTemplate, l logr.Logger) (v1.Condition, error) {
+	cond := v1.Condition{
+		Type:               corev1alpha1.ConditionReady,
+		Status:             v1.ConditionFalse,
+		ObservedGeneration: template.Generation,
+	}
+
+	domain := &corev1alpha1.Domain{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: template.Namespace, Name: template.Spec.DomainRef.Name}, domain)
+	if apierrors.IsNotFound(err) {
+		cond.Reason = corev1alpha1.ReasonTemplateDomainNotFound
+		cond.Message = fmt.Sprintf("the domain %s does not exist", template.Spec.DomainRef.Name)
+		return cond, nil
+	}
+	if err != nil {
+		return cond, err
+	}
+
+	html, err := r.templateHTML(ctx, template)
+	var invalid invalidTemplateError
+	if errors.As(err, &invalid) {
+		cond.Reason = corev1alpha1.ReasonTemplateInvalid
+		cond.Message = err.Error()
+		return cond, nil
+	}
+	if err != nil {
+		return cond, err
+	}
+
+	remote := kannon.Template{ID: template.Status.TemplateID, Title: template.TitleOrDefault(), HTML: html}
+	hash := templateHash(remote)
+	name := domain.Spec.DomainName
+
+	if template.Status.Domain != "" && template.Status.Domain != name {
+		// the domain name changed, the template moves to the new one
+		if err := r.deleteRemoteTemplate(ctx, template, l); err != nil {
+			return syncFailedCondition(cond, err), err
+		}
+		template.Status.TemplateID = ""
+		template.Status.ContentHash = ""
+		remote.ID = ""
+	}
+
+	if remote.ID != "" && hash == template.Status.ContentHash {
+		exists, err := r.remoteTemplateExists(ctx, name, remote.ID)
+		if err != nil {
+			return syncFailedCondition(cond, err), err
+		}
+		if exists {
+			return templateSyncedCondition(cond, template), nil
+		}
+		// deleted outside of the operator, it is created again
+		l.Info("email template deleted from kannon", "domain", name, "templateID", remote.ID)
+		remote.ID = ""
+	}
+
+	var synced *kannon.Template
+	if remote.ID != "" {
+		l.Info("updating email template", "domain", name, "templateID", remote.ID)
+		synced, err = r.Kannon.UpdateTemplate(ctx, name, remote)
+		if errors.Is(err, kannon.ErrNotFound) {
+			// deleted outside of the operator, it is created again
+			remote.ID = ""
+		}
+	}
+	if remote.ID == "" {
+		l.Info("creating email template", "domain", name)
+		synced, err = r.Kannon.CreateTemplate(ctx, name, remote)
+	}
+	if err != nil {
+		return syncFailedCondition(cond, err), err
+	}
+
+	template.Status.TemplateID = synced.ID
+	template.Status.Domain = name
+	template.Status.ContentHash = hash
+	template.Status.LastSyncTime = &v1.Time{Time: r.now()}
+
+	return templateSyncedCondition(cond, template), nil
+}
+
+// remoteTemplateExists reports whether the template id still exists in
+// Kannon. A Kannon API without the call is trusted to still have it.
+func (r *EmailTemplateReconciler) remoteTemplateExists(ctx context.Context, domain, id string) (bool, error) {
+	_, err := r.Kannon.GetTemplate(ctx, domain, id)
+	switch {
+	case errors.Is(err, kannon.ErrNotFound):
+		return false, nil
+	case errors.Is(err, kannon.ErrUnsupported):
+		return true, nil
+	case err != nil:
+		return false, err
+	}
+
+	return true, nil
+}
+
+// invalidTemplateError is returned when the body of the template can't be
+// found. It is not retried: the spec or the ConfigMap must change.
+type invalidTemplateError struct {
+	msg string
+}
+
+func (e invalidTemplateError) Error() string {
+	return e.msg
+}
+
+// templateHTML returns the body of the template, inline or from its
+// ConfigMap.
+func (r *EmailTemplateReconciler) templateHTML(ctx context.Context, template *corev1alpha1.EmailTemplate) (string, error) {
+	ref := template.Spec.HTMLFrom
+	switch {
+	case template.Spec.HTML != "" && ref != nil:
+		return "", invalidTemplateError{"only one of html and htmlFrom can be set"}
+	case template.Spec.HTML != "":
+		return template.Spec.HTML, nil
+	case ref == nil:
+		return "", invalidTemplateError{"one of html and htmlFrom must be set"}
+	}
+
+	reader := r.APIReader
+	if reader == nil {
+		reader = r.Client
+	}
+	cm := &corev1.ConfigMap{}
+	err := reader.Get(ctx, types.NamespacedName{Namespace: template.Namespace, Name: ref.Name}, cm)
+	if apierrors.IsNotFound(err) {
+		return "", invalidTemplateError{fmt.Sprintf("the configmap %s does not exist", ref.Name)}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	html, ok := cm.Data[ref.Key]
+	if !ok {
+		return "", invalidTemplateError{fmt.Sprintf("the configmap %s has no key %s", ref.Name, ref.Key)}
+	}
+	return html, nil
+}
+
+func templateHash(template kannon.Template) string {
+	sum := sha256.Sum256([]byte(template.Title + "\x00" + template.HTML))
+	return hex.EncodeToString(sum[:])
+}
+
+// deleteRemoteTemplate deletes the template last synced. A template
+// already gone is not an error, and a Kannon API without the deletion call
+// leaves the template behind.
+func (r *EmailTemplateReconciler) deleteRemoteTemplate(ctx context.Context, template *corev1alpha1.EmailTemplate, l logr.Logger) error {
+	if template.Status.TemplateID == "" {
+		return nil
+	}
+
+	err := r.Kannon.DeleteTemplate(ctx, template.Status.Domain, template.Status.TemplateID)
+	switch {
+	case errors.Is(err, kannon.ErrNotFound):
+		return nil
+	case errors.Is(err, kannon.ErrUnsupported):
+		l.Info("kannon can't delete templates, the template is left behind",
+			"domain", template.Status.Domain, "templateID", template.Status.TemplateID)
+		return nil
+	}
+
+	return err
+}
+
+// finalizeEmailTemplate deletes the remote template of a deleted
+// EmailTemplate before releasing it.
+func (r *EmailTemplateReconciler) finalizeEmailTemplate(ctx context.Context, template *corev1alpha1.EmailTemplate, l logr.Logger) error {
+	if !controllerutil.ContainsFinalizer(template, emailTemplateFinalizer) {
+		return nil
+	}
+
+	if err := r.deleteRemoteTemplate(ctx, template, l); err != nil {
+		return err
+	}
+
+	controllerutil.RemoveFinalizer(template, emailTemplateFinalizer)
+	return r.Update(ctx, template)
+}
+
+func syncFailedCondition(cond v1.Condition, err error) v1.Condition {
+	cond.Reason = corev1alpha1.ReasonTemplateSyncFailed
+	cond.Message = err.Error()
+	return cond
+}
+
+func templateSyncedCondition(cond v1.Condition, template *corev1alpha1.EmailTemplate) v1.Condition {
+	cond.Status = v1.ConditionTrue
+	cond.Reason = corev1alpha1.ReasonTemplateSynced
+	cond.Message = fmt.Sprintf("the template is synced to %s as %s", template.Status.Domain, template.Status.TemplateID)
+	return cond
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *EmailTemplateReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &corev1alpha1.EmailTemplate{}, htmlFromIndex, indexHTMLFrom); err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&corev1alpha1.EmailTemplate{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		// only the metadata of the ConfigMaps is cached, their changes are
+		// mapped to the templates through the index
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}}, handler.EnqueueRequestsFromMapFunc(r.templatesForConfigMap),
+			builder.OnlyMetadata).
+		// a new domain name moves the templates
+		Watches(&source.Kind{Type: &corev1alpha1.Domain{}}, handler.EnqueueRequestsFromMapFunc(r.templatesForDomain),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Complete(tracing.Reconciler("EmailTemplate", r))
+}
+
+// templatesForConfigMap maps a ConfigMap to the EmailTemplates reading
+// their body from it.
+func (r *EmailTemplateReconciler) templatesForConfigMap(obj client.Object) []reconcile.Request {
+	return r.templatesMatching(obj.GetNamespace(), func(*corev1alpha1.EmailTemplate) bool { return true },
+		client.MatchingFields{htmlFromIndex: obj.GetName()})
+}
+
+// templatesForDomain maps a Domain to the EmailTemplates referencing it.
+func (r *EmailTemplateReconciler) templatesForDomain(obj client.Object) []reconcile.Request {
+	return r.templatesMatching(obj.GetNamespace(), func(template *corev1alpha1.EmailTemplate) bool {
+		return template.Spec.DomainRef.Name == obj.GetName()
+	})
+}
+
+func (r *EmailTemplateReconciler) templatesMatching(namespace string, match func(*corev1alpha1.EmailTemplate) bool, opts ...client.ListOption) []reconcile.Request {
+	templates := &corev1alpha1.EmailTemplateList{}
+	if err := r.List(context.Background(), templates, append(opts, client.InNamespace(namespace))...); err != nil {
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for i := range templates.Items {
+		if match(&templates.Items[i]) {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&templates.Items[i])})
+		}
+	}
+	return requests
+}
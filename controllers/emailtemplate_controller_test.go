@@ -0,0 +1,172 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+	"github.com/kannon-email/k8nnon/internal/kannon"
+)
+
+func TestEmailTemplateSync(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	template := createEmailTemplate(t)
+	template.Spec.HTMLFrom = &corev1.ConfigMapKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: "welcome"},
+		Key:                  "index.html",
+	}
+	cm := &corev1.ConfigMap{
+		ObjectMeta: v1.ObjectMeta{Name: "welcome", Namespace: "default"},
+		Data:       map[string]string{"index.html": "<p>Welcome</p>"},
+	}
+	kannonClient := createKannonClient(t, domain)
+	r := createEmailTemplateReconciler(t, kannonClient, domain, template, cm)
+	reconcileObject(t, r, template)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(template), template))
+	assert.Contains(t, template.Finalizers, emailTemplateFinalizer)
+	assert.Equal(t, "template-1", template.Status.TemplateID)
+	assert.Equal(t, "example.com", template.Status.Domain)
+	assert.NotEmpty(t, template.Status.ContentHash)
+	assert.True(t, meta.IsStatusConditionTrue(template.Status.Conditions, corev1alpha1.ConditionReady))
+	assert.Equal(t, map[string]kannon.Template{
+		"template-1": {ID: "template-1", Title: "welcome", HTML: "<p>Welcome</p>"},
+	}, kannonClient.Templates("example.com"))
+
+	// a changed body is uploaded to the same template
+	hash := template.Status.ContentHash
+	cm.Data["index.html"] = "<p>Hello</p>"
+	require.NoError(t, r.Update(ctx, cm))
+	reconcileObject(t, r, template)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(template), template))
+	assert.NotEqual(t, hash, template.Status.ContentHash)
+	assert.Equal(t, "<p>Hello</p>", kannonClient.Templates("example.com")["template-1"].HTML)
+
+	// deleting the email template deletes the remote template
+	require.NoError(t, r.Delete(ctx, template))
+	reconcileObject(t, r, template)
+
+	assert.Empty(t, kannonClient.Templates("example.com"))
+	err := r.Get(ctx, client.ObjectKeyFromObject(template), template)
+	assert.True(t, apierrors.IsNotFound(err), "the email template should be released: %v", err)
+}
+
+func TestEmailTemplateDeletedRemotely(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	template := createEmailTemplate(t)
+	template.Spec.HTML = "<p>Welcome</p>"
+	kannonClient := createKannonClient(t, domain)
+	r := createEmailTemplateReconciler(t, kannonClient, domain, template)
+	reconcileObject(t, r, template)
+
+	require.NoError(t, kannonClient.DeleteTemplate(ctx, "example.com", "template-1"))
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(template), template))
+	template.Spec.Title = "Welcome"
+	require.NoError(t, r.Update(ctx, template))
+	reconcileObject(t, r, template)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(template), template))
+	assert.Equal(t, "template-2", template.Status.TemplateID, "the template should be created again")
+	assert.Equal(t, "Welcome", kannonClient.Templates("example.com")["template-2"].Title)
+}
+
+func TestEmailTemplateResync(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	template := createEmailTemplate(t)
+	template.Spec.HTML = "<p>Welcome</p>"
+	kannonClient := createKannonClient(t, domain)
+	r := createEmailTemplateReconciler(t, kannonClient, domain, template)
+	res := reconcileObject(t, r, template)
+	assert.Equal(t, templateResyncPeriod, res.RequeueAfter, "the synced template should be checked periodically")
+
+	// the unchanged template is created again once deleted from Kannon
+	require.NoError(t, kannonClient.DeleteTemplate(ctx, "example.com", "template-1"))
+	reconcileObject(t, r, template)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(template), template))
+	assert.Equal(t, "template-2", template.Status.TemplateID)
+	assert.Equal(t, "<p>Welcome</p>", kannonClient.Templates("example.com")["template-2"].HTML)
+
+	// while it exists, it is not uploaded again
+	reconcileObject(t, r, template)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(template), template))
+	assert.Equal(t, "template-2", template.Status.TemplateID)
+	assert.Len(t, kannonClient.Templates("example.com"), 1)
+}
+
+func TestEmailTemplateInvalid(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	template := createEmailTemplate(t)
+	template.Spec.HTMLFrom = &corev1.ConfigMapKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: "welcome"},
+		Key:                  "index.html",
+	}
+	kannonClient := createKannonClient(t, domain)
+	r := createEmailTemplateReconciler(t, kannonClient, domain, template)
+	reconcileObject(t, r, template)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(template), template))
+	cond := meta.FindStatusCondition(template.Status.Conditions, corev1alpha1.ConditionReady)
+	require.NotNil(t, cond)
+	assert.Equal(t, corev1alpha1.ReasonTemplateInvalid, cond.Reason)
+	assert.Equal(t, "the configmap welcome does not exist", cond.Message)
+	assert.Empty(t, kannonClient.Templates("example.com"))
+
+	assert.Equal(t, []ctrl.Request{{NamespacedName: client.ObjectKeyFromObject(template)}},
+		r.templatesForConfigMap(&corev1.ConfigMap{ObjectMeta: v1.ObjectMeta{Name: "welcome", Namespace: "default"}}))
+}
+
+func createEmailTemplateReconciler(t *testing.T, kannonClient kannon.Client, objs ...client.Object) *EmailTemplateReconciler {
+	t.Helper()
+
+	c, scheme := newFakeClient(t, objs...)
+	return &EmailTemplateReconciler{Client: c, Scheme: scheme, Kannon: kannonClient}
+}
+
+func createEmailTemplate(t *testing.T) *corev1alpha1.EmailTemplate {
+	t.Helper()
+
+	return &corev1alpha1.EmailTemplate{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "welcome",
+			Namespace: "default",
+		},
+		Spec: corev1alpha1.EmailTemplateSpec{
+			DomainRef: corev1alpha1.DomainReference{Name: "example"},
+		},
+	}
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	gatewayv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+)
+
+// eventf records an Event on the Domain. It is a no-op without a Recorder.
+func (r *DomainReconciler) eventf(domain *corev1alpha1.Domain, eventType, reason, messageFmt string, args ...interface{}) {
+	if r.Recorder == nil {
+		return
+	}
+	r.Recorder.Eventf(domain, eventType, reason, messageFmt, args...)
+}
+
+// recordCheckTransition records an Event when the outcome of a DNS check
+// changes: when the record gets verified, when it is no longer verified and
+// when the check fails. It must be called before cond is set on the Domain.
+func (r *DomainReconciler) recordCheckTransition(domain *corev1alpha1.Domain, cond v1.Condition) {
+	prev := meta.FindStatusCondition(domain.Status.Conditions, cond.Type)
+	if prev != nil && prev.Status == cond.Status && prev.Reason == cond.Reason {
+		return
+	}
+
+	switch cond.Status {
+	case v1.ConditionTrue:
+		r.eventf(domain, corev1.EventTypeNormal, cond.Type, cond.Message)
+	case v1.ConditionUnknown:
+		r.eventf(domain, corev1.EventTypeWarning, cond.Reason, cond.Message)
+	default:
+		// a record missing since the Domain was created is not news
+		if prev != nil && prev.Status == v1.ConditionTrue {
+			r.eventf(domain, corev1.EventTypeWarning, cond.Reason, cond.Message)
+		}
+	}
+}
+
+// recordDKIMKeyMismatch records a Warning Event when the DKIM key starts
+// diverging from its record. It must be called before cond is set on the
+// Domain.
+func (r *DomainReconciler) recordDKIMKeyMismatch(domain *corev1alpha1.Domain, cond v1.Condition) {
+	prev := meta.FindStatusCondition(domain.Status.Conditions, cond.Type)
+	if cond.Status != v1.ConditionTrue || prev != nil && prev.Status == v1.ConditionTrue && prev.Reason == cond.Reason {
+		return
+	}
+	r.eventf(domain, corev1.EventTypeWarning, cond.Reason, cond.Message)
+}
+
+// recordStatsRouteEvent records the creation or the deletion of the stats
+// Ingress or HTTPRoute, action being "Created" or "Deleted".
+func (r *DomainReconciler) recordStatsRouteEvent(domain *corev1alpha1.Domain, obj client.Object, action string) {
+	kind := statsRouteKind(obj)
+	r.eventf(domain, corev1.EventTypeNormal, kind+action, "%s stats %s %s", strings.ToLower(action), kind, obj.GetName())
+}
+
+// statsRouteKind returns the kind of the stats route, Ingress or HTTPRoute.
+func statsRouteKind(obj client.Object) string {
+	if _, ok := obj.(*gatewayv1beta1.HTTPRoute); ok {
+		return "HTTPRoute"
+	}
+	return "Ingress"
+}
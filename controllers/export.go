@@ -0,0 +1,145 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+)
+
+// The keys of the exported runtime configuration, named to be loaded as
+// environment variables with envFrom.
+const (
+	exportDomainKey       = "KANNON_DOMAIN"
+	exportDKIMSelectorKey = "KANNON_DKIM_SELECTOR"
+	exportAPIKeyKey       = "KANNON_API_KEY"
+)
+
+// reconcileExport writes the runtime configuration of the Domain to the
+// Secret of spec.export, and deletes the Secrets it exported before under
+// another name or before the export was turned off.
+func (r *DomainReconciler) reconcileExport(ctx context.Context, domain *corev1alpha1.Domain) error {
+	name := ""
+	if domain.Spec.Export != nil {
+		name = domain.Spec.Export.SecretName
+	}
+
+	if err := r.deleteStaleExports(ctx, domain, name); err != nil {
+		return err
+	}
+	if name == "" {
+		return nil
+	}
+
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: domain.Namespace}, secret)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	if err == nil && v1.IsControlledBy(secret, domain) && secret.Labels[componentLabel] != componentRuntimeConfig {
+		// do not overwrite the DKIM keys or the Kannon credentials
+		return fmt.Errorf("the secret %s holds another resource of the domain", name)
+	}
+
+	data, err := r.exportData(ctx, domain)
+	if err != nil {
+		return err
+	}
+
+	secret = &corev1.Secret{ObjectMeta: v1.ObjectMeta{Name: name, Namespace: domain.Namespace}}
+	return r.createOrUpdateOwned(ctx, domain, secret, componentRuntimeConfig, func() {
+		secret.Type = corev1.SecretTypeOpaque
+		secret.Data = data
+	})
+}
+
+// exportData returns the runtime configuration of the Domain: the API key
+// is only known once the Domain is registered with Kannon.
+func (r *DomainReconciler) exportData(ctx context.Context, domain *corev1alpha1.Domain) (map[string][]byte, error) {
+	data := map[string][]byte{
+		exportDomainKey:       []byte(domain.Spec.DomainName),
+		exportDKIMSelectorKey: []byte(activeDKIMSelector(domain)),
+	}
+	if r.Kannon == nil && domain.Spec.KannonCredentialsRef == nil {
+		return data, nil
+	}
+
+	creds, err := kannonCredentials(ctx, r.Client, domain)
+	if err != nil {
+		return nil, err
+	}
+	if creds.Key != "" {
+		data[exportAPIKeyKey] = []byte(creds.Key)
+	}
+	return data, nil
+}
+
+// activeDKIMSelector returns the selector the mail of the Domain is signed
+// with: the one of the active generated key, or the configured one.
+func activeDKIMSelector(domain *corev1alpha1.Domain) string {
+	if domain.Status.DKIM != nil && domain.Status.DKIM.Selector != "" {
+		return domain.Status.DKIM.Selector
+	}
+	return domain.Spec.DKIM.SelectorOrDefault()
+}
+
+// deleteStaleExports deletes the exported Secrets of the Domain other than
+// keep.
+func (r *DomainReconciler) deleteStaleExports(ctx context.Context, domain *corev1alpha1.Domain, keep string) error {
+	secrets := &corev1.SecretList{}
+	if err := r.List(ctx, secrets, client.InNamespace(domain.Namespace), client.MatchingLabels(standardLabels(domain, componentRuntimeConfig))); err != nil {
+		return err
+	}
+
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		if secret.Name == keep || !v1.IsControlledBy(secret, domain) {
+			continue
+		}
+		if err := r.Delete(ctx, secret); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func exportCondition(domain *corev1alpha1.Domain, exportErr error) v1.Condition {
+	cond := v1.Condition{
+		Type:               corev1alpha1.ConditionConfigExported,
+		Status:             v1.ConditionFalse,
+		ObservedGeneration: domain.Generation,
+	}
+
+	if exportErr != nil {
+		cond.Reason = corev1alpha1.ReasonConfigExportFailed
+		cond.Message = exportErr.Error()
+		return cond
+	}
+
+	cond.Status = v1.ConditionTrue
+	cond.Reason = corev1alpha1.ReasonConfigExported
+	cond.Message = fmt.Sprintf("the runtime configuration is in the secret %s", domain.Spec.Export.SecretName)
+	return cond
+}
@@ -0,0 +1,346 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+	"github.com/kannon-email/k8nnon/internal/dkim"
+)
+
+// dnsEndpointGVK is the external-dns CRD the records are published with.
+// It is handled as unstructured to avoid depending on external-dns.
+var dnsEndpointGVK = schema.GroupVersionKind{
+	Group:   "externaldns.k8s.io",
+	Version: "v1alpha1",
+	Kind:    "DNSEndpoint",
+}
+
+// dnsEndpointTTL is the TTL of the published records, in seconds.
+const dnsEndpointTTL = 300
+
+// errExternalDNSDisabled is returned when a Domain requests the records to
+// be provisioned but the operator runs without the external-dns integration.
+var errExternalDNSDisabled = errors.New("the external-dns integration is not enabled in the operator")
+
+func newDNSEndpoint() *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(dnsEndpointGVK)
+	return obj
+}
+
+func dnsEndpointName(domain *corev1alpha1.Domain) string {
+	return fmt.Sprintf("%s-dns", domain.Name)
+}
+
+// reconcileDNSEndpoint publishes the expected stats CNAME, DKIM and SPF
+// records of the Domain with a DNSEndpoint owned by it, and deletes it once
+// the provisioning is turned off.
+func (r *DomainReconciler) reconcileDNSEndpoint(ctx context.Context, domain *corev1alpha1.Domain) error {
+	wanted := domain.Spec.AutoProvisionDNS() && domain.Spec.DNS.Provider == nil
+	if !r.ExternalDNS {
+		if wanted {
+			return errExternalDNSDisabled
+		}
+		return nil
+	}
+
+	endpoint := newDNSEndpoint()
+	err := r.Get(ctx, types.NamespacedName{Name: dnsEndpointName(domain), Namespace: domain.Namespace}, endpoint)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	found := err == nil
+
+	if !wanted {
+		if found && v1.IsControlledBy(endpoint, domain) && endpoint.GetDeletionTimestamp() == nil {
+			return r.Delete(ctx, endpoint)
+		}
+		return nil
+	}
+
+	endpoints := dnsEndpoints(domain)
+	var shared []string
+	if r.LookupTXT != nil {
+		endpoints, shared, err = r.withoutSharedTXT(ctx, endpoints, endpoint, found)
+		if err != nil {
+			return err
+		}
+	}
+	var sharedErr error
+	if len(shared) > 0 {
+		sharedErr = &sharedTXTError{names: shared}
+	}
+
+	if !found {
+		endpoint = newDNSEndpoint()
+		endpoint.SetName(dnsEndpointName(domain))
+		endpoint.SetNamespace(domain.Namespace)
+		applyResourceMetadata(endpoint, domain, componentDNSRecords)
+		if err := unstructured.SetNestedSlice(endpoint.Object, endpoints, "spec", "endpoints"); err != nil {
+			return err
+		}
+		if err := ctrl.SetControllerReference(domain, endpoint, r.Scheme); err != nil {
+			return err
+		}
+		if err := r.Create(ctx, endpoint, client.FieldOwner(fieldManager)); err != nil {
+			return err
+		}
+		return sharedErr
+	}
+
+	if !v1.IsControlledBy(endpoint, domain) {
+		return fmt.Errorf("dnsendpoint %s is not controlled by the domain", endpoint.GetName())
+	}
+
+	current, _, err := unstructured.NestedSlice(endpoint.Object, "spec", "endpoints")
+	if err != nil {
+		return err
+	}
+	metadataChanged := applyResourceMetadata(endpoint, domain, componentDNSRecords)
+	if !metadataChanged && reflect.DeepEqual(current, endpoints) {
+		return sharedErr
+	}
+
+	if err := unstructured.SetNestedSlice(endpoint.Object, endpoints, "spec", "endpoints"); err != nil {
+		return err
+	}
+
+	if err := r.Update(ctx, endpoint, client.FieldOwner(fieldManager)); err != nil {
+		return err
+	}
+	return sharedErr
+}
+
+// isPermanentProvisionError reports whether retrying the provisioning won't
+// help until someone changes the operator configuration or the records.
+func isPermanentProvisionError(err error) bool {
+	return errors.Is(err, errExternalDNSDisabled) || errors.As(err, new(*sharedTXTError))
+}
+
+// sharedTXTError reports the TXT records left out of the DNSEndpoint.
+type sharedTXTError struct {
+	names []string
+}
+
+func (e *sharedTXTError) Error() string {
+	return fmt.Sprintf("the TXT records of %s are not published, as external-dns would replace the other TXT records of the name: add the expected records by hand",
+		strings.Join(e.names, ", "))
+}
+
+// withoutSharedTXT leaves out the TXT endpoints whose name has records
+// neither expected nor published by the DNSEndpoint, e.g. the SPF record
+// of a domain carrying site verifications. external-dns owns the whole
+// record set of a name, it would replace them, and delete them with the
+// DNSEndpoint. The names left out are returned.
+func (r *DomainReconciler) withoutSharedTXT(ctx context.Context, endpoints []interface{}, published *unstructured.Unstructured, found bool) ([]interface{}, []string, error) {
+	records := endpoints
+	if found {
+		current, _, err := unstructured.NestedSlice(published.Object, "spec", "endpoints")
+		if err != nil {
+			return nil, nil, err
+		}
+		records = append(current, endpoints...)
+	}
+	ours := map[string]bool{}
+	for _, e := range records {
+		name, recordType, targets := endpointRecords(e)
+		for _, target := range targets {
+			ours[recordType+" "+name+" "+target] = true
+		}
+	}
+
+	kept := []interface{}{}
+	var shared []string
+	for _, e := range endpoints {
+		name, recordType, _ := endpointRecords(e)
+		if recordType != "TXT" {
+			kept = append(kept, e)
+			continue
+		}
+
+		values, err := r.LookupTXT(ctx, name)
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			values, err = nil, nil
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("looking up the TXT records of %s: %w", name, err)
+		}
+
+		others := false
+		for _, value := range values {
+			// the ownership records of the external-dns txt registry
+			if strings.HasPrefix(value, "heritage=external-dns,") {
+				continue
+			}
+			if !ours["TXT "+name+" "+value] {
+				others = true
+				break
+			}
+		}
+		if others {
+			shared = append(shared, name)
+			continue
+		}
+		kept = append(kept, e)
+	}
+
+	return kept, shared, nil
+}
+
+func endpointRecords(e interface{}) (string, string, []string) {
+	m, _ := e.(map[string]interface{})
+	name, _ := m["dnsName"].(string)
+	recordType, _ := m["recordType"].(string)
+	targets := []string{}
+	raw, _ := m["targets"].([]interface{})
+	for _, t := range raw {
+		if target, ok := t.(string); ok {
+			targets = append(targets, target)
+		}
+	}
+	return name, recordType, targets
+}
+
+// provisionedRecords returns the expected records of the Domain that the
+// operator publishes. The DKIM keys being rotated in or out are published
+// next to the active one.
+func provisionedRecords(domain *corev1alpha1.Domain) []*corev1alpha1.DNSRecord {
+	records := []*corev1alpha1.DNSRecord{
+		domain.Status.DNS.Stats.Expected,
+		domain.Status.DNS.DKIM.Expected,
+		domain.Status.DNS.SPF.Expected,
+	}
+	if stats := domain.Status.DNS.Stats.Expected; stats != nil {
+		// the other stats hosts point to the same target as the first
+		for _, host := range domain.Spec.StatsHosts()[1:] {
+			records = append(records, &corev1alpha1.DNSRecord{Type: stats.Type, Name: host, Value: stats.Value})
+		}
+	}
+	if status := domain.Status.DNS.MTASTS; status != nil {
+		records = append(records, status.Expected, status.Policy.Expected)
+	}
+	if status := domain.Status.DNS.TLSRPT; status != nil {
+		records = append(records, status.Expected)
+	}
+	if status := domain.Status.DNS.BIMI; status != nil {
+		records = append(records, status.Expected)
+	}
+	for _, status := range domain.Status.DNS.AdditionalDomains {
+		records = append(records, status.DKIM.Expected, status.SPF.Expected)
+	}
+	if ownershipPending(domain) {
+		records = append(records, domain.Status.Ownership.Expected)
+	}
+
+	if status := domain.Status.DKIM; status != nil && domain.Spec.DKIM.PublicKey == "" {
+		// the additional domains rotate their key along with the domain
+		for _, name := range domain.Spec.DomainNames() {
+			named := withDomainName(domain, name)
+			if pending := status.Pending; pending != nil {
+				records = append(records, dkimRecord(named, pending.Selector, pending.KeyType, pending.PublicKey))
+			}
+			for _, retiring := range status.RetiringKeys {
+				if retiring.PublicKey != "" {
+					records = append(records, dkimRecord(named, retiring.Selector, retiring.KeyType, retiring.PublicKey))
+				}
+			}
+		}
+	}
+
+	provisioned := []*corev1alpha1.DNSRecord{}
+	for _, record := range records {
+		if record != nil {
+			provisioned = append(provisioned, record)
+		}
+	}
+
+	return provisioned
+}
+
+// dnsEndpoints returns the external-dns endpoints of the Domain records.
+func dnsEndpoints(domain *corev1alpha1.Domain) []interface{} {
+	endpoints := []interface{}{}
+	for _, record := range provisionedRecords(domain) {
+		endpoints = append(endpoints, map[string]interface{}{
+			"dnsName":    record.Name,
+			"recordType": record.Type,
+			"recordTTL":  int64(dnsEndpointTTL),
+			"targets":    []interface{}{record.Value},
+		})
+	}
+
+	return endpoints
+}
+
+func dkimRecord(domain *corev1alpha1.Domain, selector, keyType, publicKey string) *corev1alpha1.DNSRecord {
+	return &corev1alpha1.DNSRecord{
+		Type:  "TXT",
+		Name:  fmt.Sprintf("%s._domainkey.%s", selector, domain.Spec.DomainName),
+		Value: dkim.Record(dkim.KeyType(keyType), publicKey),
+	}
+}
+
+// dnsProvisionedCondition computes the DNSProvisioned condition from the
+// outcome of the DNSEndpoint reconciliation.
+func dnsProvisionedCondition(domain *corev1alpha1.Domain, provisionErr error) v1.Condition {
+	cond := v1.Condition{
+		Type:               corev1alpha1.ConditionDNSProvisioned,
+		Status:             v1.ConditionFalse,
+		ObservedGeneration: domain.Generation,
+	}
+
+	switch {
+	case errors.Is(provisionErr, errExternalDNSDisabled):
+		cond.Reason = corev1alpha1.ReasonExternalDNSDisabled
+		cond.Message = provisionErr.Error()
+	case errors.As(provisionErr, new(*sharedTXTError)):
+		cond.Reason = corev1alpha1.ReasonDNSRecordsShared
+		cond.Message = provisionErr.Error()
+	case provisionErr != nil && domain.Spec.DNS.Provider != nil:
+		cond.Reason = corev1alpha1.ReasonDNSProviderFailed
+		cond.Message = provisionErr.Error()
+	case provisionErr != nil:
+		cond.Reason = corev1alpha1.ReasonDNSEndpointFailed
+		cond.Message = provisionErr.Error()
+	case domain.Spec.DNS.Provider != nil:
+		cond.Status = v1.ConditionTrue
+		cond.Reason = corev1alpha1.ReasonDNSRecordsPublished
+		cond.Message = fmt.Sprintf("the records are published with %s", domain.Spec.DNS.Provider.Name)
+	default:
+		cond.Status = v1.ConditionTrue
+		cond.Reason = corev1alpha1.ReasonDNSEndpointApplied
+		cond.Message = fmt.Sprintf("the records are published with the dnsendpoint %s", dnsEndpointName(domain))
+	}
+
+	return cond
+}
@@ -0,0 +1,214 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	netwrkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	gatewayv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+)
+
+// cleanupFinalizer delays the deletion of a Domain until the resources it
+// created are removed.
+const cleanupFinalizer = "core.k8s.kannon.email/domain-cleanup"
+
+// needsCleanup tells whether the Domain leaves something outside of the
+// cluster behind, the resources in it are garbage collected with it.
+func (r *DomainReconciler) needsCleanup(domain *corev1alpha1.Domain) bool {
+	if r.Kannon != nil {
+		return true
+	}
+	return domain.Spec.AutoProvisionDNS() && domain.Spec.DNS.Provider != nil
+}
+
+// cleanupStep removes one kind of resource created for a Domain.
+type cleanupStep struct {
+	name string
+	run  func(ctx context.Context, domain *corev1alpha1.Domain, l logr.Logger) error
+}
+
+// finalizeDomain tears down what the operator created for a deleted Domain,
+// outside of the cluster first, before releasing it. A failing step is
+// reported in the Terminating condition and retried.
+func (r *DomainReconciler) finalizeDomain(ctx context.Context, domain *corev1alpha1.Domain, l logr.Logger) error {
+	if !controllerutil.ContainsFinalizer(domain, cleanupFinalizer) {
+		return nil
+	}
+
+	if r.reportOnly(domain) {
+		l.Info("the domain is only checked, its resources are left behind", "domain", domain.Spec.DomainName)
+		forgetDomainMetrics(domain.Spec.DomainName)
+		controllerutil.RemoveFinalizer(domain, cleanupFinalizer)
+		return r.Update(ctx, domain)
+	}
+
+	if !meta.IsStatusConditionTrue(domain.Status.Conditions, corev1alpha1.ConditionTerminating) {
+		base := domain.DeepCopy()
+		meta.SetStatusCondition(&domain.Status.Conditions, v1.Condition{
+			Type:               corev1alpha1.ConditionTerminating,
+			Status:             v1.ConditionTrue,
+			Reason:             corev1alpha1.ReasonCleanupInProgress,
+			Message:            "removing the resources created for the domain",
+			ObservedGeneration: domain.Generation,
+		})
+		if err := r.patchStatus(ctx, domain, base); err != nil {
+			return err
+		}
+	}
+
+	steps := []cleanupStep{
+		{"dns records", r.cleanupDNSRecords},
+		{"kannon registration", r.cleanupKannonRegistration},
+		{"owned resources", r.deleteOwnedResources},
+	}
+
+	for _, step := range steps {
+		if err := step.run(ctx, domain, l); err != nil {
+			base := domain.DeepCopy()
+			meta.SetStatusCondition(&domain.Status.Conditions, v1.Condition{
+				Type:               corev1alpha1.ConditionTerminating,
+				Status:             v1.ConditionTrue,
+				Reason:             corev1alpha1.ReasonCleanupFailed,
+				Message:            fmt.Sprintf("failed to remove the %s: %v", step.name, err),
+				ObservedGeneration: domain.Generation,
+			})
+			if statusErr := r.patchStatus(ctx, domain, base); statusErr != nil {
+				l.Error(statusErr, "failed to report the cleanup failure")
+			}
+			return err
+		}
+		l.Info("removed domain resources", "resources", step.name)
+	}
+
+	forgetDomainMetrics(domain.Spec.DomainName)
+	controllerutil.RemoveFinalizer(domain, cleanupFinalizer)
+
+	return r.Update(ctx, domain)
+}
+
+func (r *DomainReconciler) cleanupDNSRecords(ctx context.Context, domain *corev1alpha1.Domain, l logr.Logger) error {
+	if !domain.Spec.AutoProvisionDNS() || domain.Spec.DNS.Provider == nil {
+		// the DNSEndpoint is deleted with the owned resources
+		return nil
+	}
+
+	err := r.cleanupProviderRecords(ctx, domain)
+	if apierrors.IsNotFound(err) {
+		// the credentials are gone, typically with the whole namespace
+		l.Info("dns provider credentials not found, the records are left behind", "domain", domain.Spec.DomainName)
+		return nil
+	}
+
+	return err
+}
+
+func (r *DomainReconciler) cleanupKannonRegistration(ctx context.Context, domain *corev1alpha1.Domain, l logr.Logger) error {
+	if r.Kannon == nil {
+		l.Info("kannon is not configured, the registration is left behind", "domain", domain.Spec.DomainName)
+		return nil
+	}
+	if domain.Spec.KannonCredentialsRef != nil {
+		l.Info("the domain is registered outside of the operator, the registration is left behind", "domain", domain.Spec.DomainName)
+		return nil
+	}
+
+	return r.deregisterKannonDomain(ctx, domain.Spec.DomainName, l)
+}
+
+// ownedObject names an object controlled by a Domain.
+type ownedObject struct {
+	name string
+	obj  client.Object
+}
+
+// deleteOwnedResources deletes the objects controlled by the Domain, rather
+// than leaving them to the garbage collector, so that they are gone once
+// the Domain is.
+func (r *DomainReconciler) deleteOwnedResources(ctx context.Context, domain *corev1alpha1.Domain, l logr.Logger) error {
+	owned := []ownedObject{
+		{statsIngressName(domain), &netwrkingv1.Ingress{}},
+		{statsIngressName(domain), &netwrkingv1.NetworkPolicy{}},
+		{kannonSecretName(domain), &corev1.Secret{}},
+	}
+	if r.GatewayAPI {
+		owned = append(owned, ownedObject{statsIngressName(domain), &gatewayv1beta1.HTTPRoute{}})
+	}
+	if r.ExternalDNS {
+		owned = append(owned, ownedObject{dnsEndpointName(domain), newDNSEndpoint()})
+	}
+	if r.PrometheusRules {
+		owned = append(owned, ownedObject{prometheusRuleName(domain), newPrometheusRule()})
+	}
+	if r.MTASTSService != "" {
+		for _, obj := range mtaSTSObjects() {
+			owned = append(owned, ownedObject{mtaSTSName(domain), obj})
+		}
+	}
+	if domain.Spec.Export != nil {
+		owned = append(owned, ownedObject{domain.Spec.Export.SecretName, &corev1.Secret{}})
+	}
+	if status := domain.Status.DKIM; status != nil {
+		owned = append(owned, ownedObject{status.SecretName, &corev1.Secret{}})
+		if status.Pending != nil {
+			owned = append(owned, ownedObject{status.Pending.SecretName, &corev1.Secret{}})
+		}
+		for _, key := range status.RetiringKeys {
+			owned = append(owned, ownedObject{key.SecretName, &corev1.Secret{}})
+		}
+	}
+
+	for _, o := range owned {
+		if o.name == "" {
+			continue
+		}
+		if err := r.deleteControlled(ctx, domain, o.name, o.obj); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteControlled deletes the object name of obj's kind when the Domain
+// controls it.
+func (r *DomainReconciler) deleteControlled(ctx context.Context, domain *corev1alpha1.Domain, name string, obj client.Object) error {
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: domain.Namespace}, obj)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if !v1.IsControlledBy(obj, domain) || obj.GetDeletionTimestamp() != nil {
+		return nil
+	}
+
+	return client.IgnoreNotFound(r.Delete(ctx, obj))
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/ratelimiter"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+)
+
+const (
+	// freshRecheckInterval is how often a fresh Domain is rechecked,
+	// whether its records are verified or not.
+	freshRecheckInterval = 15 * time.Second
+
+	// freshRetryMaxDelay caps the backoff of the failed reconciles of a
+	// fresh Domain, which would otherwise grow up to minutes.
+	freshRetryMaxDelay = 5 * time.Second
+)
+
+// trackFresh records whether the Domain is fresh: created, or seen with a
+// new spec, less than FreshPeriod ago. The edits are only known to the
+// replica that saw them; after a restart only the creation counts.
+func (r *DomainReconciler) trackFresh(domain *corev1alpha1.Domain) bool {
+	key := types.NamespacedName{Namespace: domain.Namespace, Name: domain.Name}
+	if r.FreshPeriod <= 0 {
+		r.fresh.Delete(key)
+		return false
+	}
+
+	now := r.now()
+	since := domain.CreationTimestamp.Time
+	if domain.Generation != domain.Status.ObservedGeneration {
+		since = now
+	} else if edited, ok := r.fresh.Load(key); ok && edited.(time.Time).After(since) {
+		since = edited.(time.Time)
+	}
+
+	if now.Sub(since) >= r.FreshPeriod {
+		r.fresh.Delete(key)
+		return false
+	}
+	r.fresh.Store(key, since)
+	return true
+}
+
+// isFresh reports whether the Domain was fresh when last reconciled.
+func (r *DomainReconciler) isFresh(key types.NamespacedName) bool {
+	since, ok := r.fresh.Load(key)
+	return ok && r.now().Sub(since.(time.Time)) < r.FreshPeriod
+}
+
+// freshRateLimiter retries the failed reconciles of the fresh Domains
+// sooner than the others.
+type freshRateLimiter struct {
+	ratelimiter.RateLimiter
+
+	fresh func(types.NamespacedName) bool
+}
+
+func newFreshRateLimiter(fresh func(types.NamespacedName) bool) freshRateLimiter {
+	return freshRateLimiter{
+		RateLimiter: workqueue.DefaultControllerRateLimiter(),
+		fresh:       fresh,
+	}
+}
+
+func (l freshRateLimiter) When(item interface{}) time.Duration {
+	d := l.RateLimiter.When(item)
+	if req, ok := item.(reconcile.Request); ok && d > freshRetryMaxDelay && l.fresh(req.NamespacedName) {
+		return freshRetryMaxDelay
+	}
+	return d
+}
@@ -0,0 +1,205 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	netwrkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	gatewayv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+	"github.com/kannon-email/k8nnon/internal/shard"
+)
+
+// StatsRouteCollector periodically deletes the stats Ingresses and
+// HTTPRoutes the operator created that their Domain no longer wants: the
+// Domain is gone, exposes its stats under another name, or does not expose
+// them with this kind of route anymore.
+type StatsRouteCollector struct {
+	client.Client
+
+	// Reader reads the Domains. It should bypass the cache, a Domain
+	// missing from it must not be mistaken for a deleted one.
+	Reader client.Reader
+
+	// Interval is the time between two collections.
+	Interval time.Duration
+
+	// GatewayAPI collects the HTTPRoutes too.
+	GatewayAPI bool
+
+	// DomainSelector restricts the collection to the routes of the
+	// Domains the operator reconciles. Nil selects them all.
+	DomainSelector labels.Selector
+
+	// DryRun only logs the routes that would be deleted.
+	DryRun bool
+
+	// Shard restricts the collection to the routes of the Domains of the
+	// shard.
+	Shard shard.Shard
+}
+
+// NeedLeaderElection makes only the leader collect the routes.
+func (c *StatsRouteCollector) NeedLeaderElection() bool {
+	return true
+}
+
+// Start collects the orphaned routes every Interval until ctx is done.
+func (c *StatsRouteCollector) Start(ctx context.Context) error {
+	l := log.FromContext(ctx).WithName("stats-route-gc")
+
+	ticker := time.NewTicker(c.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if _, err := c.Collect(log.IntoContext(ctx, l)); err != nil {
+				l.Error(err, "failed to collect the orphaned stats routes")
+			}
+		}
+	}
+}
+
+// Collect deletes the orphaned stats routes and returns how many it found.
+func (c *StatsRouteCollector) Collect(ctx context.Context) (int, error) {
+	routes := []client.ObjectList{&netwrkingv1.IngressList{}}
+	if c.GatewayAPI {
+		routes = append(routes, &gatewayv1beta1.HTTPRouteList{})
+	}
+
+	orphans := 0
+	for _, list := range routes {
+		if err := c.List(ctx, list, client.MatchingLabels{managedByLabel: managedBy, componentLabel: componentStats}); err != nil {
+			return orphans, err
+		}
+
+		objs, err := routeObjects(list)
+		if err != nil {
+			return orphans, err
+		}
+		for _, obj := range objs {
+			reason, err := c.orphaned(ctx, obj)
+			if err != nil {
+				return orphans, err
+			}
+			if reason == "" {
+				continue
+			}
+
+			orphans++
+			if err := c.collect(ctx, obj, reason); err != nil {
+				return orphans, err
+			}
+		}
+	}
+
+	return orphans, nil
+}
+
+// orphaned tells why the route is no longer wanted by its Domain, or
+// returns an empty string. Only the routes controlled by a Domain are
+// considered.
+func (c *StatsRouteCollector) orphaned(ctx context.Context, obj client.Object) (string, error) {
+	owner := v1.GetControllerOf(obj)
+	if owner == nil || owner.Kind != "Domain" || owner.APIVersion != corev1alpha1.GroupVersion.String() {
+		return "", nil
+	}
+	if !c.Shard.Owns(obj.GetNamespace(), owner.Name) || obj.GetDeletionTimestamp() != nil {
+		return "", nil
+	}
+
+	domain := &corev1alpha1.Domain{}
+	err := c.Reader.Get(ctx, types.NamespacedName{Namespace: obj.GetNamespace(), Name: owner.Name}, domain)
+	if apierrors.IsNotFound(err) {
+		return fmt.Sprintf("the Domain %s no longer exists", owner.Name), nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case domain.UID != owner.UID:
+		return fmt.Sprintf("the Domain %s was recreated", owner.Name), nil
+	case c.DomainSelector != nil && !c.DomainSelector.Matches(labels.Set(domain.Labels)):
+		return "", nil
+	case domain.Spec.Suspend, domain.Spec.ReportOnly, !domain.DeletionTimestamp.IsZero():
+		// the routes of these Domains are left as they are, or to the
+		// finalizer
+		return "", nil
+	case obj.GetName() != statsIngressName(domain):
+		return fmt.Sprintf("the stats route of the Domain %s is %s", domain.Name, statsIngressName(domain)), nil
+	}
+
+	routing := corev1alpha1.RoutingIngress
+	if _, ok := obj.(*gatewayv1beta1.HTTPRoute); ok {
+		routing = corev1alpha1.RoutingGatewayAPI
+	}
+	if !wantsStatsRoute(domain, routing) {
+		return fmt.Sprintf("the Domain %s does not expose its stats with a %s", domain.Name, statsRouteKind(obj)), nil
+	}
+
+	return "", nil
+}
+
+func (c *StatsRouteCollector) collect(ctx context.Context, obj client.Object, reason string) error {
+	l := log.FromContext(ctx).WithValues("kind", statsRouteKind(obj), "namespace", obj.GetNamespace(), "name", obj.GetName())
+	if c.DryRun {
+		l.Info("would delete the orphaned stats route", "reason", reason)
+		return nil
+	}
+
+	l.Info("deleting the orphaned stats route", "reason", reason)
+	if err := c.Delete(ctx, obj, client.Preconditions{UID: uidOf(obj)}); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	statsRoutesCollected.WithLabelValues(statsRouteKind(obj)).Inc()
+	return nil
+}
+
+func uidOf(obj client.Object) *types.UID {
+	uid := obj.GetUID()
+	return &uid
+}
+
+func routeObjects(list client.ObjectList) ([]client.Object, error) {
+	var objs []client.Object
+	switch list := list.(type) {
+	case *netwrkingv1.IngressList:
+		for i := range list.Items {
+			objs = append(objs, &list.Items[i])
+		}
+	case *gatewayv1beta1.HTTPRouteList:
+		for i := range list.Items {
+			objs = append(objs, &list.Items[i])
+		}
+	default:
+		return nil, fmt.Errorf("unexpected route list %T", list)
+	}
+	return objs, nil
+}
@@ -0,0 +1,195 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	gatewayv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+
+	"github.com/go-logr/logr"
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+)
+
+// reconcileHTTPRoute manages the stats HTTPRoute of a Domain with the
+// gatewayAPI routing, with the same lifecycle as the stats Ingress: it is
+// created once the stats CNAME is verified and deleted when it no longer is.
+func (r *DomainReconciler) reconcileHTTPRoute(ctx context.Context, domain *corev1alpha1.Domain, l logr.Logger) error {
+	wanted := wantsStatsRoute(domain, corev1alpha1.RoutingGatewayAPI)
+	if !r.GatewayAPI {
+		if wanted {
+			return errGatewayAPIDisabled
+		}
+		return nil
+	}
+
+	route := &gatewayv1beta1.HTTPRoute{}
+	err := r.Get(ctx, types.NamespacedName{Name: statsIngressName(domain), Namespace: domain.Namespace}, route)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	found := err == nil
+
+	if found && !wanted {
+		if v1.IsControlledBy(route, domain) {
+			return r.deleteStatsRoute(ctx, route, domain)
+		}
+		return nil
+	}
+	if !wanted {
+		return nil
+	}
+
+	if !found {
+		if !statsRouteAllowed(domain) {
+			return nil
+		}
+		r.notifyMissingStatsRoute(ctx, domain, "HTTPRoute", statsIngressName(domain))
+
+		route, err = r.buildDesiredHTTPRoute(domain)
+		if err != nil {
+			return err
+		}
+		return r.createStatsRoute(ctx, route, domain)
+	}
+
+	if !v1.IsControlledBy(route, domain) {
+		if err := r.adopt(ctx, route, domain, l); err != nil {
+			return err
+		}
+	}
+
+	if !statsRouteAllowed(domain) {
+		return r.deleteStatsRoute(ctx, route, domain)
+	}
+
+	desired, err := r.buildDesiredHTTPRoute(domain)
+	if err != nil {
+		return err
+	}
+
+	drifted := applyManagedMetadata(&route.DeepCopy().ObjectMeta, domain) ||
+		!reflect.DeepEqual(route.Spec, desired.Spec)
+	if !drifted {
+		return nil
+	}
+
+	l.Info("applying httproute", "httproute", route.Name)
+
+	return r.applyStatsRoute(ctx, route, desired, domain)
+}
+
+func (r *DomainReconciler) buildDesiredHTTPRoute(domain *corev1alpha1.Domain) (*gatewayv1beta1.HTTPRoute, error) {
+	defaulted := r.withClusterDefaults(domain)
+	if defaulted.Spec.Ingress.Service.Name == "" {
+		return nil, errNoStatsService
+	}
+
+	route := &gatewayv1beta1.HTTPRoute{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      statsIngressName(domain),
+			Namespace: domain.Namespace,
+		},
+		Spec: buildHTTPRouteSpec(defaulted),
+	}
+	applyManagedMetadata(&route.ObjectMeta, domain)
+
+	if err := ctrl.SetControllerReference(domain, route, r.Scheme); err != nil {
+		return route, err
+	}
+
+	return route, nil
+}
+
+// buildHTTPRouteSpec returns the desired HTTPRoute spec. The fields defaulted
+// by the API server are set explicitly, so that the stored route compares
+// equal to the desired one and is not applied again.
+func buildHTTPRouteSpec(domain *corev1alpha1.Domain) gatewayv1beta1.HTTPRouteSpec {
+	gatewayGroup := gatewayv1beta1.Group(gatewayv1beta1.GroupName)
+	gatewayKind := gatewayv1beta1.Kind("Gateway")
+	serviceGroup := gatewayv1beta1.Group("")
+	serviceKind := gatewayv1beta1.Kind("Service")
+	port := gatewayv1beta1.PortNumber(domain.Spec.Ingress.Service.Port)
+	weight := int32(1)
+
+	parent := gatewayv1beta1.ParentReference{
+		Group: &gatewayGroup,
+		Kind:  &gatewayKind,
+	}
+	if gw := domain.Spec.Gateway; gw != nil {
+		parent.Name = gatewayv1beta1.ObjectName(gw.Name)
+		if gw.Namespace != "" {
+			namespace := gatewayv1beta1.Namespace(gw.Namespace)
+			parent.Namespace = &namespace
+		}
+		if gw.SectionName != "" {
+			section := gatewayv1beta1.SectionName(gw.SectionName)
+			parent.SectionName = &section
+		}
+	}
+
+	spec := gatewayv1beta1.HTTPRouteSpec{
+		CommonRouteSpec: gatewayv1beta1.CommonRouteSpec{
+			ParentRefs: []gatewayv1beta1.ParentReference{parent},
+		},
+	}
+
+	for _, host := range servedStatsHosts(domain) {
+		spec.Hostnames = append(spec.Hostnames, gatewayv1beta1.Hostname(host))
+	}
+
+	rule := gatewayv1beta1.HTTPRouteRule{
+		BackendRefs: []gatewayv1beta1.HTTPBackendRef{
+			{
+				BackendRef: gatewayv1beta1.BackendRef{
+					BackendObjectReference: gatewayv1beta1.BackendObjectReference{
+						Group: &serviceGroup,
+						Kind:  &serviceKind,
+						Name:  gatewayv1beta1.ObjectName(domain.Spec.Ingress.Service.Name),
+						Port:  &port,
+					},
+					Weight: &weight,
+				},
+			},
+		},
+	}
+	for _, path := range domain.Spec.StatsRoutePaths() {
+		pathType := gatewayv1beta1.PathMatchPathPrefix
+		value := path
+		rule.Matches = append(rule.Matches, gatewayv1beta1.HTTPRouteMatch{
+			Path: &gatewayv1beta1.HTTPPathMatch{
+				Type:  &pathType,
+				Value: &value,
+			},
+		})
+	}
+	if host := domain.Spec.StatsRewriteHost(); host != "" {
+		hostname := gatewayv1beta1.PreciseHostname(host)
+		rule.Filters = []gatewayv1beta1.HTTPRouteFilter{{
+			Type:       gatewayv1beta1.HTTPRouteFilterURLRewrite,
+			URLRewrite: &gatewayv1beta1.HTTPURLRewriteFilter{Hostname: &hostname},
+		}}
+	}
+	spec.Rules = []gatewayv1beta1.HTTPRouteRule{rule}
+
+	return spec
+}
@@ -0,0 +1,278 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+	"github.com/kannon-email/k8nnon/internal/kannon"
+	"github.com/kannon-email/k8nnon/internal/shard"
+	"github.com/kannon-email/k8nnon/internal/tracing"
+)
+
+const warmupDay = 24 * time.Hour
+
+// ipWarmupFinalizer delays the deletion of an IPWarmup until the daily
+// limits of its addresses are removed from Kannon.
+const ipWarmupFinalizer = "core.k8s.kannon.email/ipwarmup-limit"
+
+// IPWarmupReconciler reconciles a IPWarmup object
+type IPWarmupReconciler struct {
+	client.Client
+	clock
+	Scheme *runtime.Scheme
+
+	// Kannon receives the daily limits of the addresses. Nil only reports
+	// the schedule.
+	Kannon kannon.Client
+
+	// DryRun computes the schedule without pushing its limits to Kannon,
+	// reporting in the LimitSynced condition what would be pushed.
+	DryRun bool
+
+	// Shard is the share of the IPWarmups the replica reconciles.
+	Shard shard.Shard
+}
+
+//+kubebuilder:rbac:groups=core.k8s.kannon.email,resources=ipwarmups,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=core.k8s.kannon.email,resources=ipwarmups/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=core.k8s.kannon.email,resources=ipwarmups/finalizers,verbs=update
+
+// Reconcile computes the current phase of the schedule and pushes its daily
+// limit to Kannon. It is requeued when the next day starts. The limits are
+// removed when the IPWarmup is deleted.
+func (r *IPWarmupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	if !r.Shard.Owns(req.Namespace, req.Name) {
+		return ctrl.Result{}, nil
+	}
+
+	l := log.FromContext(ctx)
+	l.Info("reconciling ip warmup", "ipWarmup", req.NamespacedName)
+
+	warmup := &corev1alpha1.IPWarmup{}
+	if err := r.Get(ctx, req.NamespacedName, warmup); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	pushLimits := r.Kannon != nil && !r.DryRun
+	if !warmup.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, r.finalizeIPWarmup(ctx, warmup, pushLimits)
+	}
+	if pushLimits && !controllerutil.ContainsFinalizer(warmup, ipWarmupFinalizer) {
+		controllerutil.AddFinalizer(warmup, ipWarmupFinalizer)
+		if err := r.Update(ctx, warmup); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	now := r.now()
+	pos := warmupPositionAt(warmup.Spec, now)
+	warmup.Status.State = pos.state
+	warmup.Status.Phase = pos.phase
+	warmup.Status.RemainingDays = pos.remainingDays
+	warmup.Status.DailyLimit = pos.dailyLimit
+	warmup.Status.ObservedGeneration = warmup.Generation
+
+	var syncErr error
+	if r.Kannon != nil && r.DryRun {
+		meta.SetStatusCondition(&warmup.Status.Conditions, dryRunLimitCondition(warmup))
+	} else if r.Kannon != nil {
+		if pos.state != corev1alpha1.IPWarmupPending {
+			syncErr = r.syncLimits(ctx, warmup.Spec.IPs, pos.dailyLimit)
+			if syncErr != nil {
+				l.Error(syncErr, "failed to set the daily limits", "ipWarmup", req.NamespacedName)
+			} else {
+				warmup.Status.LastSyncTime = &v1.Time{Time: now}
+			}
+		}
+		meta.SetStatusCondition(&warmup.Status.Conditions, limitSyncedCondition(warmup, syncErr))
+	} else {
+		meta.RemoveStatusCondition(&warmup.Status.Conditions, corev1alpha1.ConditionLimitSynced)
+	}
+
+	if err := r.Status().Update(ctx, warmup); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if syncErr != nil {
+		return ctrl.Result{}, syncErr
+	}
+	if pos.state == corev1alpha1.IPWarmupCompleted {
+		return ctrl.Result{}, nil
+	}
+
+	return ctrl.Result{RequeueAfter: pos.nextChange.Sub(now)}, nil
+}
+
+// finalizeIPWarmup removes the daily limits of a deleted IPWarmup before
+// releasing it, unless the limits are not pushed to Kannon.
+func (r *IPWarmupReconciler) finalizeIPWarmup(ctx context.Context, warmup *corev1alpha1.IPWarmup, pushLimits bool) error {
+	if !controllerutil.ContainsFinalizer(warmup, ipWarmupFinalizer) {
+		return nil
+	}
+
+	if pushLimits {
+		if err := r.syncLimits(ctx, validIPs(warmup.Spec.IPs), 0); err != nil {
+			return err
+		}
+		log.FromContext(ctx).Info("removed the daily limits", "ipWarmup", client.ObjectKeyFromObject(warmup))
+	}
+
+	controllerutil.RemoveFinalizer(warmup, ipWarmupFinalizer)
+	return r.Update(ctx, warmup)
+}
+
+// validIPs returns the addresses of ips, without the invalid ones.
+func validIPs(ips []string) []string {
+	var valid []string
+	for _, ip := range ips {
+		if net.ParseIP(ip) != nil {
+			valid = append(valid, ip)
+		}
+	}
+	return valid
+}
+
+// syncLimits sets limit on every address, a zero limit removes it.
+func (r *IPWarmupReconciler) syncLimits(ctx context.Context, ips []string, limit int64) error {
+	var errs []error
+	for _, ip := range ips {
+		if net.ParseIP(ip) == nil {
+			errs = append(errs, fmt.Errorf("%q is not an IP address", ip))
+			continue
+		}
+		if err := r.Kannon.SetDailyLimit(ctx, ip, limit); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", ip, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// warmupPosition is where a schedule is at a given time.
+type warmupPosition struct {
+	state         corev1alpha1.IPWarmupState
+	phase         int32
+	remainingDays int32
+	dailyLimit    int64
+
+	// nextChange is when the position changes, the start of the next day
+	nextChange time.Time
+}
+
+func warmupPositionAt(spec corev1alpha1.IPWarmupSpec, now time.Time) warmupPosition {
+	start := spec.StartTime.Time
+	if now.Before(start) {
+		return warmupPosition{
+			state:         corev1alpha1.IPWarmupPending,
+			remainingDays: spec.ScheduleDays(),
+			nextChange:    start,
+		}
+	}
+
+	day := int32(now.Sub(start) / warmupDay)
+	pos := warmupPosition{
+		state:      corev1alpha1.IPWarmupCompleted,
+		nextChange: start.Add(time.Duration(day+1) * warmupDay),
+	}
+
+	var end int32
+	for i, phase := range spec.Schedule {
+		end += phase.Days
+		if day < end {
+			pos.state = corev1alpha1.IPWarmupWarmingUp
+			pos.phase = int32(i + 1)
+			pos.dailyLimit = phase.DailyLimit
+			pos.remainingDays = spec.ScheduleDays() - day
+			return pos
+		}
+	}
+
+	return pos
+}
+
+// limitSyncedCondition computes the LimitSynced condition from the outcome
+// of the push of the limits.
+func limitSyncedCondition(warmup *corev1alpha1.IPWarmup, syncErr error) v1.Condition {
+	cond := v1.Condition{
+		Type:               corev1alpha1.ConditionLimitSynced,
+		Status:             v1.ConditionFalse,
+		ObservedGeneration: warmup.Generation,
+	}
+
+	switch {
+	case syncErr != nil:
+		cond.Reason = corev1alpha1.ReasonLimitSyncFailed
+		cond.Message = syncErr.Error()
+	case warmup.Status.State == corev1alpha1.IPWarmupPending:
+		cond.Reason = corev1alpha1.ReasonWarmupPending
+		cond.Message = fmt.Sprintf("the warm-up starts at %s", warmup.Spec.StartTime.UTC().Format(time.RFC3339))
+	case warmup.Status.State == corev1alpha1.IPWarmupCompleted:
+		cond.Status = v1.ConditionTrue
+		cond.Reason = corev1alpha1.ReasonLimitSynced
+		cond.Message = "the warm-up is completed, the daily limit is removed"
+	default:
+		cond.Status = v1.ConditionTrue
+		cond.Reason = corev1alpha1.ReasonLimitSynced
+		cond.Message = fmt.Sprintf("the daily limit of phase %d is %d messages per address", warmup.Status.Phase, warmup.Status.DailyLimit)
+	}
+
+	return cond
+}
+
+// dryRunLimitCondition reports the limits that would be pushed to Kannon
+// in dry-run mode.
+func dryRunLimitCondition(warmup *corev1alpha1.IPWarmup) v1.Condition {
+	cond := v1.Condition{
+		Type:               corev1alpha1.ConditionLimitSynced,
+		Status:             v1.ConditionFalse,
+		Reason:             corev1alpha1.ReasonReportOnly,
+		ObservedGeneration: warmup.Generation,
+	}
+
+	switch warmup.Status.State {
+	case corev1alpha1.IPWarmupPending:
+		cond.Message = fmt.Sprintf("the warm-up starts at %s", warmup.Spec.StartTime.UTC().Format(time.RFC3339))
+	case corev1alpha1.IPWarmupCompleted:
+		cond.Message = "the warm-up is completed, the daily limit would be removed"
+	default:
+		cond.Message = fmt.Sprintf("the daily limit of phase %d would be set to %d messages per address", warmup.Status.Phase, warmup.Status.DailyLimit)
+	}
+
+	return cond
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *IPWarmupReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&corev1alpha1.IPWarmup{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Complete(tracing.Reconciler("IPWarmup", r))
+}
@@ -0,0 +1,189 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+	"github.com/kannon-email/k8nnon/internal/kannon"
+)
+
+func TestIPWarmupSchedule(t *testing.T) {
+	ctx := context.Background()
+
+	start := time.Date(2023, 5, 1, 8, 0, 0, 0, time.UTC)
+	warmup := createIPWarmup(t, start, "192.0.2.1", "192.0.2.2")
+	kannonClient := kannon.NewFakeClient()
+	now := start.Add(9*warmupDay + time.Hour)
+	r := createIPWarmupReconciler(t, kannonClient, func() time.Time { return now }, warmup)
+
+	res := reconcileObject(t, r, warmup)
+	assert.Equal(t, 23*time.Hour, res.RequeueAfter, "should be requeued when the next day starts")
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(warmup), warmup))
+	assert.Equal(t, corev1alpha1.IPWarmupWarmingUp, warmup.Status.State)
+	assert.Equal(t, int32(2), warmup.Status.Phase)
+	assert.Equal(t, int32(5), warmup.Status.RemainingDays)
+	assert.Equal(t, int64(500), warmup.Status.DailyLimit)
+	assert.True(t, meta.IsStatusConditionTrue(warmup.Status.Conditions, corev1alpha1.ConditionLimitSynced))
+
+	limit, ok := kannonClient.DailyLimit("192.0.2.2")
+	assert.True(t, ok)
+	assert.Equal(t, int64(500), limit)
+
+	now = start.Add(14 * warmupDay)
+	res = reconcileObject(t, r, warmup)
+	assert.Zero(t, res.RequeueAfter, "a completed warm-up should not be requeued")
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(warmup), warmup))
+	assert.Equal(t, corev1alpha1.IPWarmupCompleted, warmup.Status.State)
+	assert.Zero(t, warmup.Status.Phase)
+	assert.Zero(t, warmup.Status.DailyLimit)
+
+	_, ok = kannonClient.DailyLimit("192.0.2.2")
+	assert.False(t, ok, "the limit should be removed")
+}
+
+func TestIPWarmupDeletion(t *testing.T) {
+	ctx := context.Background()
+
+	start := time.Date(2023, 5, 1, 8, 0, 0, 0, time.UTC)
+	warmup := createIPWarmup(t, start, "192.0.2.1", "192.0.2.2")
+	kannonClient := kannon.NewFakeClient()
+	now := start.Add(time.Hour)
+	r := createIPWarmupReconciler(t, kannonClient, func() time.Time { return now }, warmup)
+	reconcileObject(t, r, warmup)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(warmup), warmup))
+	assert.Contains(t, warmup.Finalizers, ipWarmupFinalizer)
+	_, ok := kannonClient.DailyLimit("192.0.2.1")
+	require.True(t, ok)
+
+	// deleting the warm-up removes the limits before releasing it
+	require.NoError(t, r.Delete(ctx, warmup))
+	reconcileObject(t, r, warmup)
+
+	for _, ip := range []string{"192.0.2.1", "192.0.2.2"} {
+		_, ok := kannonClient.DailyLimit(ip)
+		assert.False(t, ok, "the limit of %s should be removed", ip)
+	}
+	err := r.Get(ctx, client.ObjectKeyFromObject(warmup), warmup)
+	assert.True(t, apierrors.IsNotFound(err), "the ip warmup should be released: %v", err)
+}
+
+func TestIPWarmupPending(t *testing.T) {
+	ctx := context.Background()
+
+	start := time.Date(2023, 5, 1, 8, 0, 0, 0, time.UTC)
+	warmup := createIPWarmup(t, start, "192.0.2.1")
+	kannonClient := kannon.NewFakeClient()
+	r := createIPWarmupReconciler(t, kannonClient, func() time.Time { return start.Add(-2 * time.Hour) }, warmup)
+
+	res := reconcileObject(t, r, warmup)
+	assert.Equal(t, 2*time.Hour, res.RequeueAfter)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(warmup), warmup))
+	assert.Equal(t, corev1alpha1.IPWarmupPending, warmup.Status.State)
+	assert.Equal(t, int32(14), warmup.Status.RemainingDays)
+	assert.Nil(t, warmup.Status.LastSyncTime)
+
+	cond := meta.FindStatusCondition(warmup.Status.Conditions, corev1alpha1.ConditionLimitSynced)
+	require.NotNil(t, cond)
+	assert.Equal(t, corev1alpha1.ReasonWarmupPending, cond.Reason)
+
+	_, ok := kannonClient.DailyLimit("192.0.2.1")
+	assert.False(t, ok, "no limit should be set before the start")
+}
+
+func TestIPWarmupDryRun(t *testing.T) {
+	ctx := context.Background()
+
+	start := time.Date(2023, 5, 1, 8, 0, 0, 0, time.UTC)
+	warmup := createIPWarmup(t, start, "192.0.2.1", "192.0.2.2")
+	kannonClient := kannon.NewFakeClient()
+	r := createIPWarmupReconciler(t, kannonClient, func() time.Time { return start.Add(9*warmupDay + time.Hour) }, warmup)
+	r.DryRun = true
+
+	reconcileObject(t, r, warmup)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(warmup), warmup))
+	assert.Equal(t, int32(2), warmup.Status.Phase)
+	assert.Equal(t, int64(500), warmup.Status.DailyLimit)
+	assert.Nil(t, warmup.Status.LastSyncTime)
+
+	cond := meta.FindStatusCondition(warmup.Status.Conditions, corev1alpha1.ConditionLimitSynced)
+	require.NotNil(t, cond)
+	assert.Equal(t, v1.ConditionFalse, cond.Status)
+	assert.Equal(t, corev1alpha1.ReasonReportOnly, cond.Reason)
+	assert.Equal(t, "the daily limit of phase 2 would be set to 500 messages per address", cond.Message)
+
+	for _, ip := range warmup.Spec.IPs {
+		_, ok := kannonClient.DailyLimit(ip)
+		assert.False(t, ok, "no limit should be pushed in dry-run mode")
+	}
+}
+
+func TestIPWarmupWithoutKannon(t *testing.T) {
+	ctx := context.Background()
+
+	start := time.Date(2023, 5, 1, 8, 0, 0, 0, time.UTC)
+	warmup := createIPWarmup(t, start, "192.0.2.1")
+	r := createIPWarmupReconciler(t, nil, func() time.Time { return start }, warmup)
+	reconcileObject(t, r, warmup)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(warmup), warmup))
+	assert.Equal(t, int64(100), warmup.Status.DailyLimit)
+	assert.Nil(t, meta.FindStatusCondition(warmup.Status.Conditions, corev1alpha1.ConditionLimitSynced))
+}
+
+func createIPWarmupReconciler(t *testing.T, kannonClient kannon.Client, clock func() time.Time, objs ...client.Object) *IPWarmupReconciler {
+	t.Helper()
+
+	c, scheme := newFakeClient(t, objs...)
+	return &IPWarmupReconciler{Client: c, Scheme: scheme, Kannon: kannonClient, clock: clock}
+}
+
+// createIPWarmup creates a two weeks warm-up: 100 messages per day the
+// first week, 500 the second.
+func createIPWarmup(t *testing.T, start time.Time, ips ...string) *corev1alpha1.IPWarmup {
+	t.Helper()
+
+	return &corev1alpha1.IPWarmup{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "warmup",
+			Namespace: "default",
+		},
+		Spec: corev1alpha1.IPWarmupSpec{
+			IPs:       ips,
+			StartTime: v1.NewTime(start),
+			Schedule: []corev1alpha1.IPWarmupPhase{
+				{Days: 7, DailyLimit: 100},
+				{Days: 7, DailyLimit: 500},
+			},
+		},
+	}
+}
@@ -0,0 +1,236 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+	"github.com/kannon-email/k8nnon/internal/kannon"
+)
+
+// Keys of the Secret holding the Kannon sending credentials.
+const (
+	kannonDomainKey = "domain"
+	kannonKeyKey    = "key"
+)
+
+func kannonSecretName(domain *corev1alpha1.Domain) string {
+	return fmt.Sprintf("%s-kannon", domain.Name)
+}
+
+// reconcileKannonRegistration registers the domain with Kannon and stores
+// its sending credentials in a Secret owned by the Domain. The Secret
+// records the registration: once it exists, Kannon is not called again
+// until the domain name changes, except to push the sender settings that
+// changed. A Domain referencing its credentials is not registered.
+func (r *DomainReconciler) reconcileKannonRegistration(ctx context.Context, domain *corev1alpha1.Domain, l logr.Logger) error {
+	if ref := domain.Spec.KannonCredentialsRef; ref != nil {
+		return r.reconcileReferencedKannonCredentials(ctx, domain, ref, l)
+	}
+
+	name := kannonSecretName(domain)
+
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: domain.Namespace}, secret)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	if err == nil {
+		if !v1.IsControlledBy(secret, domain) {
+			return fmt.Errorf("kannon secret %s is not controlled by the domain", name)
+		}
+
+		registered := string(secret.Data[kannonDomainKey])
+		if registered == domain.Spec.DomainName {
+			if err := r.syncKannonSettings(ctx, domain, l); err != nil {
+				return err
+			}
+			return r.syncResourceMetadata(ctx, domain, secret, componentKannonCredentials)
+		}
+
+		// the domain name changed, the old registration is dropped
+		if err := r.deregisterKannonDomain(ctx, registered, l); err != nil {
+			return err
+		}
+		if err := r.Delete(ctx, secret); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	d, err := r.Kannon.GetDomain(ctx, domain.Spec.DomainName)
+	if errors.Is(err, kannon.ErrNotFound) {
+		l.Info("registering domain with kannon", "domain", domain.Spec.DomainName)
+		d, err = r.Kannon.CreateDomain(ctx, domain.Spec.DomainName)
+	}
+	if err != nil {
+		return err
+	}
+
+	secret = &corev1.Secret{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      name,
+			Namespace: domain.Namespace,
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			kannonDomainKey: []byte(d.Domain),
+			kannonKeyKey:    []byte(d.Key),
+		},
+	}
+	applyResourceMetadata(secret, domain, componentKannonCredentials)
+	if err := ctrl.SetControllerReference(domain, secret, r.Scheme); err != nil {
+		return err
+	}
+	if err := r.Create(ctx, secret); err != nil {
+		return err
+	}
+
+	// a new registration has none of the settings pushed to the previous one
+	domain.Status.KannonSettingsHash = ""
+	return r.syncKannonSettings(ctx, domain, l)
+}
+
+// reconcileReferencedKannonCredentials checks the credentials of a domain
+// registered outside of the operator, typically synced by an ExternalSecret
+// or a SealedSecret, before pushing its sender settings.
+func (r *DomainReconciler) reconcileReferencedKannonCredentials(ctx context.Context, domain *corev1alpha1.Domain, ref *corev1alpha1.CredentialsSecretReference, l logr.Logger) error {
+	data, err := readCredentials(ctx, r.Client, domain.Namespace, ref)
+	if apierrors.IsNotFound(err) {
+		return fmt.Errorf("the secret %s does not exist", ref.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	if creds := referencedKannonCredentials(domain, data); creds.Key == "" {
+		return fmt.Errorf("the secret %s holds no kannon credentials for %s", ref.Name, domain.Spec.DomainName)
+	}
+
+	return r.syncKannonSettings(ctx, domain, l)
+}
+
+// kannonSettings returns the sender settings of the Domain.
+func kannonSettings(domain *corev1alpha1.Domain) kannon.DomainSettings {
+	settings := kannon.DomainSettings{
+		DKIMSelector: activeDKIMSelector(domain),
+		SenderAlias:  domain.Spec.SenderAlias,
+	}
+	if ref := domain.Spec.SenderPoolRef; ref != nil {
+		settings.SenderPool = fmt.Sprintf("%s/%s", domain.Namespace, ref.Name)
+	}
+	return settings
+}
+
+func kannonSettingsHash(settings kannon.DomainSettings) (string, error) {
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// syncKannonSettings pushes the sender settings of the Domain to Kannon
+// when they differ from the ones last pushed, recorded by their hash in
+// the status. A Kannon API without the update call keeps the settings it
+// has, they are pushed again once it has the call.
+func (r *DomainReconciler) syncKannonSettings(ctx context.Context, domain *corev1alpha1.Domain, l logr.Logger) error {
+	settings := kannonSettings(domain)
+	hash, err := kannonSettingsHash(settings)
+	if err != nil {
+		return err
+	}
+	if hash == domain.Status.KannonSettingsHash {
+		return nil
+	}
+
+	l.Info("updating the kannon sender settings", "domain", domain.Spec.DomainName, "dkimSelector", settings.DKIMSelector)
+	err = r.Kannon.UpdateDomain(ctx, domain.Spec.DomainName, settings)
+	if errors.Is(err, kannon.ErrUnsupported) {
+		l.Info("kannon can't update the sender settings, they are left unchanged", "domain", domain.Spec.DomainName)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	domain.Status.KannonSettingsHash = hash
+	return nil
+}
+
+// deregisterKannonDomain removes the registration of a domain. A Kannon API
+// without the deletion call leaves the registration behind.
+func (r *DomainReconciler) deregisterKannonDomain(ctx context.Context, name string, l logr.Logger) error {
+	err := r.Kannon.DeleteDomain(ctx, name)
+	switch {
+	case errors.Is(err, kannon.ErrNotFound):
+		return nil
+	case errors.Is(err, kannon.ErrUnsupported):
+		l.Info("kannon can't delete domains, the registration is left behind", "domain", name)
+		return nil
+	}
+
+	return err
+}
+
+// kannonCondition computes the KannonRegistered condition from the outcome
+// of the registration.
+func kannonCondition(domain *corev1alpha1.Domain, registerErr error) v1.Condition {
+	cond := v1.Condition{
+		Type:               corev1alpha1.ConditionKannonRegistered,
+		Status:             v1.ConditionFalse,
+		ObservedGeneration: domain.Generation,
+	}
+
+	if registerErr != nil {
+		cond.Reason = corev1alpha1.ReasonKannonRegistrationFailed
+		cond.Message = registerErr.Error()
+		return cond
+	}
+
+	cond.Status = v1.ConditionTrue
+	cond.Reason = corev1alpha1.ReasonKannonRegistered
+	cond.Message = fmt.Sprintf("the sending credentials are stored in the secret %s", kannonCredentialsSecretName(domain))
+
+	return cond
+}
+
+// kannonOwnershipPendingCondition reports the registration as waiting for
+// the ownership of the domain to be verified.
+func kannonOwnershipPendingCondition(domain *corev1alpha1.Domain) v1.Condition {
+	return v1.Condition{
+		Type:               corev1alpha1.ConditionKannonRegistered,
+		Status:             v1.ConditionFalse,
+		Reason:             corev1alpha1.ReasonOwnershipNotVerified,
+		Message:            "the domain is registered once its ownership is verified",
+		ObservedGeneration: domain.Generation,
+	}
+}
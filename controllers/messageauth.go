@@ -0,0 +1,244 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/mail"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+	"github.com/kannon-email/k8nnon/internal/dkim"
+	"github.com/kannon-email/k8nnon/internal/dns/checker"
+	"github.com/kannon-email/k8nnon/internal/smtpsink"
+)
+
+// messageAuth is the authentication of a received message.
+type messageAuth struct {
+	DKIM  corev1alpha1.MessageAuthResult
+	SPF   corev1alpha1.MessageAuthResult
+	DMARC corev1alpha1.MessageAuthResult
+}
+
+// passed reports whether the three checks pass.
+func (a messageAuth) passed() bool {
+	return a.DKIM.Result == corev1alpha1.AuthResultPass &&
+		a.SPF.Result == corev1alpha1.AuthResultPass &&
+		a.DMARC.Result == corev1alpha1.AuthResultPass
+}
+
+// failures returns why the checks that did not pass failed.
+func (a messageAuth) failures() []string {
+	var failures []string
+	for _, check := range []struct {
+		name   string
+		result corev1alpha1.MessageAuthResult
+	}{{"DKIM", a.DKIM}, {"SPF", a.SPF}, {"DMARC", a.DMARC}} {
+		if check.result.Result != corev1alpha1.AuthResultPass {
+			failures = append(failures, fmt.Sprintf("%s %s: %s", check.name, check.result.Result, check.result.Message))
+		}
+	}
+	return failures
+}
+
+// authenticateMessage verifies the DKIM signatures of msg, the
+// authorization of its sending address by the SPF record of the MAIL FROM
+// domain, and the alignment of the From domain with either, as DMARC does.
+func authenticateMessage(ctx context.Context, lookup dkim.TXTLookup, dnsChecker checker.DNSChecker, msg smtpsink.Message) messageAuth {
+	auth := messageAuth{}
+
+	from := ""
+	if parsed, err := mail.ReadMessage(bytes.NewReader(msg.Data)); err == nil {
+		if addr, err := mail.ParseAddress(parsed.Header.Get("From")); err == nil {
+			from = addressDomain(addr.Address)
+		}
+	}
+
+	auth.DKIM = verifyMessageDKIM(ctx, lookup, msg, from)
+	auth.SPF = checkMessageSPF(ctx, dnsChecker, msg)
+	auth.DMARC = checkMessageDMARC(ctx, lookup, from, auth.DKIM, auth.SPF)
+	return auth
+}
+
+// verifyMessageDKIM returns the verification of the signature aligned with
+// the From domain, else of the first valid one, else of the first one.
+func verifyMessageDKIM(ctx context.Context, lookup dkim.TXTLookup, msg smtpsink.Message, from string) corev1alpha1.MessageAuthResult {
+	sigs, err := dkim.Verify(ctx, lookup, msg.Data)
+	if err != nil {
+		return corev1alpha1.MessageAuthResult{Result: corev1alpha1.AuthResultFail, Message: err.Error()}
+	}
+	if len(sigs) == 0 {
+		return corev1alpha1.MessageAuthResult{Result: corev1alpha1.AuthResultNone, Message: "the message is not signed"}
+	}
+
+	best := sigs[0]
+	for _, sig := range sigs {
+		switch {
+		case !sig.Valid():
+		case !best.Valid(), aligned(sig.Domain, from, false) && !aligned(best.Domain, from, false):
+			best = sig
+		}
+	}
+
+	res := corev1alpha1.MessageAuthResult{Domain: best.Domain, Selector: best.Selector}
+	if best.Valid() {
+		res.Result = corev1alpha1.AuthResultPass
+		res.Message = fmt.Sprintf("the message is signed by %s with the key %s", best.Domain, best.Selector)
+	} else {
+		res.Result = corev1alpha1.AuthResultFail
+		res.Message = best.Err.Error()
+	}
+	return res
+}
+
+// checkMessageSPF checks the sending address against the SPF record of the
+// MAIL FROM domain, or of the HELO name for a bounce.
+func checkMessageSPF(ctx context.Context, dnsChecker checker.DNSChecker, msg smtpsink.Message) corev1alpha1.MessageAuthResult {
+	domain := addressDomain(msg.MailFrom)
+	if domain == "" {
+		domain = strings.ToLower(msg.Helo)
+	}
+	res := corev1alpha1.MessageAuthResult{Domain: domain, IP: msg.RemoteIP}
+	if domain == "" {
+		res.Result = corev1alpha1.AuthResultNone
+		res.Message = "the message has no MAIL FROM domain"
+		return res
+	}
+
+	stats := dnsChecker.CheckSPFCoverage(ctx, domain, msg.RemoteIP)
+	switch {
+	case stats.Result():
+		res.Result = corev1alpha1.AuthResultPass
+		res.Message = fmt.Sprintf("%s is authorized by the SPF record of %s", msg.RemoteIP, domain)
+	case stats.Reason != "":
+		res.Result = corev1alpha1.AuthResultFail
+		res.Message = stats.Reason
+	case stats.Err != nil:
+		res.Result = corev1alpha1.AuthResultFail
+		res.Message = stats.Err.Error()
+	default:
+		res.Result = corev1alpha1.AuthResultFail
+		res.Message = fmt.Sprintf("%s is not authorized by the SPF record of %s", msg.RemoteIP, domain)
+	}
+	return res
+}
+
+// checkMessageDMARC checks the alignment of the From domain with the DKIM
+// signing domain and the SPF domain, strict or relaxed as the DMARC record
+// of the From domain, or of its organizational domain, asks.
+func checkMessageDMARC(ctx context.Context, lookup dkim.TXTLookup, from string, dkimRes, spfRes corev1alpha1.MessageAuthResult) corev1alpha1.MessageAuthResult {
+	res := corev1alpha1.MessageAuthResult{Domain: from}
+	if from == "" {
+		res.Result = corev1alpha1.AuthResultFail
+		res.Message = "the message has no valid From field"
+		return res
+	}
+
+	tags, found, err := lookupDMARC(ctx, lookup, from)
+	if err != nil {
+		res.Result = corev1alpha1.AuthResultFail
+		res.Message = err.Error()
+		return res
+	}
+	if !found {
+		res.Result = corev1alpha1.AuthResultNone
+		res.Message = fmt.Sprintf("no DMARC record is published for %s", from)
+		return res
+	}
+
+	switch {
+	case dkimRes.Result == corev1alpha1.AuthResultPass && aligned(dkimRes.Domain, from, tags["adkim"] == "s"):
+		res.Result = corev1alpha1.AuthResultPass
+		res.Message = fmt.Sprintf("the From domain is aligned with the DKIM signing domain %s", dkimRes.Domain)
+	case spfRes.Result == corev1alpha1.AuthResultPass && aligned(spfRes.Domain, from, tags["aspf"] == "s"):
+		res.Result = corev1alpha1.AuthResultPass
+		res.Message = fmt.Sprintf("the From domain is aligned with the SPF domain %s", spfRes.Domain)
+	default:
+		res.Result = corev1alpha1.AuthResultFail
+		res.Message = fmt.Sprintf("neither a valid DKIM signature nor an authorized SPF domain is aligned with %s", from)
+	}
+	return res
+}
+
+// lookupDMARC returns the tags of the DMARC record of domain, falling back
+// to the record of its organizational domain.
+func lookupDMARC(ctx context.Context, lookup dkim.TXTLookup, domain string) (map[string]string, bool, error) {
+	names := []string{domain}
+	if org := organizationalDomain(domain); org != domain {
+		names = append(names, org)
+	}
+
+	for _, name := range names {
+		txts, err := lookup(ctx, "_dmarc."+name)
+		if err != nil {
+			if dnsErr, ok := err.(*net.DNSError); ok && dnsErr.IsNotFound {
+				continue
+			}
+			return nil, false, fmt.Errorf("failed to look up the DMARC record of %s: %v", name, err)
+		}
+
+		for _, txt := range txts {
+			parts := strings.Split(txt, ";")
+			if strings.TrimSpace(parts[0]) != "v=DMARC1" {
+				continue
+			}
+			tags := map[string]string{}
+			for _, part := range parts[1:] {
+				tag, value, _ := strings.Cut(part, "=")
+				tags[strings.TrimSpace(tag)] = strings.ToLower(strings.TrimSpace(value))
+			}
+			return tags, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+// aligned reports whether domain is aligned with the From domain: the same
+// domain when strict, the same organizational domain when relaxed.
+func aligned(domain, from string, strict bool) bool {
+	if domain == "" || from == "" {
+		return false
+	}
+	if strict {
+		return strings.EqualFold(domain, from)
+	}
+	return strings.EqualFold(organizationalDomain(domain), organizationalDomain(from))
+}
+
+// organizationalDomain returns the registered domain of domain, one label
+// below its public suffix.
+func organizationalDomain(domain string) string {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	org, err := publicsuffix.EffectiveTLDPlusOne(domain)
+	if err != nil {
+		return domain
+	}
+	return org
+}
+
+func addressDomain(addr string) string {
+	at := strings.LastIndex(addr, "@")
+	if at < 0 {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSuffix(addr[at+1:], "."))
+}
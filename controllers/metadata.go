@@ -0,0 +1,260 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+)
+
+// The keys of the annotations and labels copied from the Domain spec are
+// recorded on the generated object, so that they can be removed once they
+// disappear from the spec without touching keys set by someone else.
+const (
+	managedAnnotationsKey = "core.k8s.kannon.email/managed-annotations"
+	managedLabelsKey      = "core.k8s.kannon.email/managed-labels"
+)
+
+// issuerAnnotations request a certificate from cert-manager. They are not
+// propagated when the Domain references a pre-provisioned TLS Secret.
+var issuerAnnotations = []string{
+	clusterIssuerAnnotation,
+	"cert-manager.io/issuer",
+}
+
+// The standard labels of the resources created for a Domain.
+const (
+	managedByLabel = "app.kubernetes.io/managed-by"
+	managedBy      = "k8nnon"
+	componentLabel = "app.kubernetes.io/component"
+	domainLabel    = "kannon.email/domain"
+)
+
+// The components of the resources created for a Domain.
+const (
+	componentStats             = "stats"
+	componentMTASTS            = "mta-sts"
+	componentDKIMKey           = "dkim-key"
+	componentKannonCredentials = "kannon-credentials"
+	componentDNSRecords        = "dns-records"
+	componentAlerts            = "alerts"
+	componentNetworkPolicy     = "network-policy"
+	componentRuntimeConfig     = "runtime-config"
+)
+
+// applyManagedMetadata applies the labels and annotations of the stats
+// route to obj and reports whether obj changed.
+func applyManagedMetadata(obj v1.Object, domain *corev1alpha1.Domain) bool {
+	return applyMetadata(obj, routeLabels(domain), routeAnnotations(domain))
+}
+
+// applyResourceMetadata applies the resource labels and annotations of the
+// Domain spec, and the standard labels of the component, to obj and
+// reports whether obj changed.
+func applyResourceMetadata(obj v1.Object, domain *corev1alpha1.Domain, component string) bool {
+	return applyMetadata(obj, resourceLabels(domain, component), domain.Spec.ResourceAnnotations)
+}
+
+func applyMetadata(obj v1.Object, labels, annotations map[string]string) bool {
+	currentAnnotations := obj.GetAnnotations()
+	if currentAnnotations == nil {
+		currentAnnotations = map[string]string{}
+	}
+	currentLabels := obj.GetLabels()
+	if currentLabels == nil {
+		currentLabels = map[string]string{}
+	}
+
+	prevAnnotations := splitKeys(currentAnnotations[managedAnnotationsKey])
+	prevLabels := splitKeys(currentAnnotations[managedLabelsKey])
+
+	changed := false
+	changed = syncManagedKeys(currentAnnotations, annotations, prevAnnotations) || changed
+	changed = syncManagedKeys(currentLabels, labels, prevLabels) || changed
+	changed = setOrDelete(currentAnnotations, managedAnnotationsKey, joinKeys(annotations)) || changed
+	changed = setOrDelete(currentAnnotations, managedLabelsKey, joinKeys(labels)) || changed
+
+	obj.SetAnnotations(currentAnnotations)
+	obj.SetLabels(currentLabels)
+	return changed
+}
+
+// syncResourceMetadata patches the labels and annotations of an existing
+// resource of the Domain that drifted from the spec.
+func (r *DomainReconciler) syncResourceMetadata(ctx context.Context, domain *corev1alpha1.Domain, obj client.Object, component string) error {
+	current := obj.DeepCopyObject().(client.Object)
+	if !applyResourceMetadata(obj, domain, component) {
+		return nil
+	}
+	return r.Patch(ctx, obj, client.MergeFrom(current))
+}
+
+// resourceLabels returns the labels of a resource created for the Domain:
+// the resource labels of its spec and the standard labels, which win.
+func resourceLabels(domain *corev1alpha1.Domain, component string) map[string]string {
+	return mergeMaps(domain.Spec.ResourceLabels, standardLabels(domain, component))
+}
+
+// routeLabels returns the labels of the stats route, where the ingress
+// labels of the spec take precedence over its resource labels.
+func routeLabels(domain *corev1alpha1.Domain) map[string]string {
+	labels := mergeMaps(domain.Spec.ResourceLabels, domain.Spec.IngressLabels)
+	return mergeMaps(labels, standardLabels(domain, componentStats))
+}
+
+func standardLabels(domain *corev1alpha1.Domain, component string) map[string]string {
+	return map[string]string{
+		managedByLabel: managedBy,
+		componentLabel: component,
+		domainLabel:    domain.Name,
+	}
+}
+
+// routeAnnotations returns the annotations of the stats route.
+func routeAnnotations(domain *corev1alpha1.Domain) map[string]string {
+	return mergeMaps(domain.Spec.ResourceAnnotations, ingressAnnotations(domain))
+}
+
+// mergeMaps returns the entries of both maps, those of b winning.
+func mergeMaps(a, b map[string]string) map[string]string {
+	merged := make(map[string]string, len(a)+len(b))
+	for key, value := range a {
+		merged[key] = value
+	}
+	for key, value := range b {
+		merged[key] = value
+	}
+	return merged
+}
+
+// clusterIssuerAnnotation requests a certificate from a cert-manager
+// ClusterIssuer.
+const clusterIssuerAnnotation = "cert-manager.io/cluster-issuer"
+
+// upstreamVhostAnnotation sets the Host header ingress-nginx forwards.
+const upstreamVhostAnnotation = "nginx.ingress.kubernetes.io/upstream-vhost"
+
+// ingressAnnotations returns the annotations of the Domain spec to set on
+// the stats Ingress.
+func ingressAnnotations(domain *corev1alpha1.Domain) map[string]string {
+	tls := domain.Spec.TLS
+	issuer := tls != nil && (tls.SecretName != "" || tls.ClusterIssuer != "")
+	rewriteHost := domain.Spec.StatsRewriteHost()
+	if !issuer && rewriteHost == "" {
+		return domain.Spec.Ingress.Annotations
+	}
+
+	annotations := make(map[string]string, len(domain.Spec.Ingress.Annotations)+2)
+	for key, value := range domain.Spec.Ingress.Annotations {
+		annotations[key] = value
+	}
+	if rewriteHost != "" {
+		annotations[upstreamVhostAnnotation] = rewriteHost
+	}
+
+	switch {
+	case !issuer:
+	case domain.Spec.HasExplicitTLSSecret():
+		for _, key := range issuerAnnotations {
+			delete(annotations, key)
+		}
+	default:
+		delete(annotations, "cert-manager.io/issuer")
+		annotations[clusterIssuerAnnotation] = tls.ClusterIssuer
+	}
+
+	return annotations
+}
+
+// pruneManagedMetadata removes from obj the annotations and labels recorded
+// as managed that the Domain spec no longer sets, and reports whether obj
+// changed.
+func pruneManagedMetadata(obj client.Object, domain *corev1alpha1.Domain) bool {
+	annotations := obj.GetAnnotations()
+	labels := obj.GetLabels()
+
+	changed := false
+	changed = pruneKeys(labels, routeLabels(domain), splitKeys(annotations[managedLabelsKey])) || changed
+	changed = pruneKeys(annotations, routeAnnotations(domain), splitKeys(annotations[managedAnnotationsKey])) || changed
+
+	return changed
+}
+
+func pruneKeys(current, desired map[string]string, previous []string) bool {
+	changed := false
+	for _, key := range previous {
+		if _, ok := desired[key]; ok {
+			continue
+		}
+		if _, ok := current[key]; ok {
+			delete(current, key)
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+// syncManagedKeys sets the desired entries on current and removes the
+// previously managed keys that are no longer desired.
+func syncManagedKeys(current, desired map[string]string, previous []string) bool {
+	changed := pruneKeys(current, desired, previous)
+
+	for key, value := range desired {
+		if v, ok := current[key]; !ok || v != value {
+			current[key] = value
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+func setOrDelete(m map[string]string, key, value string) bool {
+	v, ok := m[key]
+	if value == "" {
+		delete(m, key)
+		return ok
+	}
+
+	m[key] = value
+	return !ok || v != value
+}
+
+func joinKeys(m map[string]string) string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return strings.Join(keys, ",")
+}
+
+func splitKeys(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	return strings.Split(s, ",")
+}
@@ -0,0 +1,320 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+	"github.com/kannon-email/k8nnon/internal/dmarc"
+	"github.com/kannon-email/k8nnon/internal/dns/checker"
+	"github.com/kannon-email/k8nnon/internal/reputation"
+	"github.com/kannon-email/k8nnon/internal/resilience"
+	"github.com/kannon-email/k8nnon/internal/tracing"
+)
+
+var (
+	domainDNSVerified = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "k8nnon_domain_dns_verified",
+		Help: "Whether a DNS record of the domain is verified (1) or not (0).",
+	}, []string{"domain", "record"})
+
+	dnsCheckDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "k8nnon_dns_check_duration_seconds",
+		Help:    "Duration of the DNS checks, across all resolvers.",
+		Buckets: []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	}, []string{"record"})
+
+	dnsCheckErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "k8nnon_dns_check_errors_total",
+		Help: "DNS checks whose outcome is unknown because the resolvers failed, by error class.",
+	}, []string{"record", "class"})
+
+	statsRouteReconciles = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "k8nnon_ingress_reconcile_total",
+		Help: "Reconciliations of the stats Ingress or HTTPRoute, by result.",
+	}, []string{"result"})
+
+	statsRoutesCollected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "k8nnon_stats_routes_collected_total",
+		Help: "Orphaned stats Ingresses or HTTPRoutes deleted, by kind.",
+	}, []string{"kind"})
+
+	domainPostmasterReputation = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "k8nnon_domain_postmaster_reputation",
+		Help: "The domain reputation in Google Postmaster Tools: 1 for the current one, 0 for the others.",
+	}, []string{"domain", "reputation"})
+
+	domainPostmasterSpamRatio = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "k8nnon_domain_postmaster_spam_ratio",
+		Help: "The share of the messages delivered to the inbox reported as spam by the Gmail users.",
+	}, []string{"domain"})
+
+	domainPostmasterIPs = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "k8nnon_domain_postmaster_ip_reputation",
+		Help: "How many sending addresses of the domain have a reputation in Google Postmaster Tools.",
+	}, []string{"domain", "reputation"})
+
+	domainSNDSFilterResult = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "k8nnon_domain_snds_filter_result",
+		Help: "The filter result of a sending address in Microsoft SNDS: 1 for the current one, 0 for the others.",
+	}, []string{"domain", "ip", "result"})
+
+	domainSNDSComplaintRatio = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "k8nnon_domain_snds_complaint_ratio",
+		Help: "The share of the recipients of a sending address who reported its mail as junk in Microsoft SNDS.",
+	}, []string{"domain", "ip"})
+
+	domainSNDSTrapHits = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "k8nnon_domain_snds_trap_hits",
+		Help: "The messages of a sending address that reached spam traps, in the latest Microsoft SNDS data.",
+	}, []string{"domain", "ip"})
+
+	domainDMARCMessages = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "k8nnon_domain_dmarc_messages",
+		Help: "Messages of the domain evaluated by the DMARC aggregate reports of the window, by DMARC result.",
+	}, []string{"domain", "result"})
+
+	domainDMARCAlignedRatio = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "k8nnon_domain_dmarc_aligned_ratio",
+		Help: "Share of the messages of the domain passing an aligned mechanism (dkim, spf) or DMARC (dmarc), in the aggregate reports of the window.",
+	}, []string{"domain", "mechanism"})
+
+	statusWritesSkipped = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "k8nnon_domain_status_writes_skipped_total",
+		Help: "Reconciles of a Domain that did not write its status, as only the check times changed.",
+	})
+
+	recheckBatchSize = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "k8nnon_domain_recheck_batch_size",
+		Help:    "Domains enqueued together by the recheck scheduler.",
+		Buckets: []float64{1, 2, 5, 10, 25, 50, 100, 250, 500},
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(domainDNSVerified, dnsCheckDuration, dnsCheckErrors, statsRouteReconciles, statsRoutesCollected, statusWritesSkipped, recheckBatchSize)
+	metrics.Registry.MustRegister(domainPostmasterReputation, domainPostmasterSpamRatio, domainPostmasterIPs,
+		domainSNDSFilterResult, domainSNDSComplaintRatio, domainSNDSTrapHits)
+	metrics.Registry.MustRegister(domainDMARCMessages, domainDMARCAlignedRatio)
+	metrics.Registry.MustRegister(checker.Collectors()...)
+	metrics.Registry.MustRegister(resilience.Collectors()...)
+}
+
+// observeCheck runs a DNS check in a span and records its duration and,
+// when it fails, the class of its error.
+func observeCheck(ctx context.Context, record string, check func(context.Context) checker.DNSCheckStats) checker.DNSCheckStats {
+	ctx, span := tracing.Start(ctx, "CheckDNS", attribute.String("k8nnon.dns.record", record))
+
+	start := time.Now()
+	stats := check(ctx)
+	elapsed := time.Since(start)
+	dnsCheckDuration.WithLabelValues(record).Observe(elapsed.Seconds())
+
+	state := stats.State()
+	log.FromContext(ctx).V(1).Info("dns check done", "record", record, "state", state, "duration", elapsed,
+		"ok", stats.CntOK, "ko", stats.CntKO, "errors", stats.CntErr, "observed", stats.Observed, "reason", stats.Reason)
+	span.SetAttributes(attribute.String("k8nnon.dns.state", string(state)))
+	if state == corev1alpha1.CheckStateUnknown {
+		dnsCheckErrors.WithLabelValues(record, checker.ErrorClass(stats.Err)).Inc()
+		tracing.End(span, stats.Err)
+	} else {
+		span.End()
+	}
+
+	return stats
+}
+
+// recordDNSVerified exports the verification state of every record of the
+// domain.
+func recordDNSVerified(domain *corev1alpha1.Domain) {
+	dns := domain.Status.DNS
+	records := map[string]bool{
+		"dkim":  dns.DKIM.OK,
+		"spf":   dns.SPF.OK,
+		"stats": dns.Stats.OK,
+		"mx":    dns.MX.OK,
+		"dmarc": dns.DMARC.OK,
+	}
+	if mtaSTS := dns.MTASTS; mtaSTS != nil {
+		records["mta_sts"] = mtaSTS.OK
+		records["mta_sts_policy"] = mtaSTS.Policy.OK
+	} else {
+		domainDNSVerified.DeleteLabelValues(domain.Spec.DomainName, "mta_sts")
+		domainDNSVerified.DeleteLabelValues(domain.Spec.DomainName, "mta_sts_policy")
+	}
+	if tlsRPT := dns.TLSRPT; tlsRPT != nil {
+		records["tls_rpt"] = tlsRPT.OK
+	} else {
+		domainDNSVerified.DeleteLabelValues(domain.Spec.DomainName, "tls_rpt")
+	}
+	if bimi := dns.BIMI; bimi != nil {
+		records["bimi"] = bimi.OK
+		records["bimi_indicator"] = bimi.Indicator.OK
+	} else {
+		domainDNSVerified.DeleteLabelValues(domain.Spec.DomainName, "bimi")
+		domainDNSVerified.DeleteLabelValues(domain.Spec.DomainName, "bimi_indicator")
+	}
+	if bimi := dns.BIMI; bimi != nil && bimi.VMC != nil {
+		records["bimi_vmc"] = bimi.VMC.OK
+	} else {
+		domainDNSVerified.DeleteLabelValues(domain.Spec.DomainName, "bimi_vmc")
+	}
+	if len(dns.PTR) > 0 {
+		// verified when the reverse DNS of every sending address is
+		records["ptr"] = true
+		for _, ptr := range dns.PTR {
+			records["ptr"] = records["ptr"] && ptr.OK
+		}
+	} else {
+		domainDNSVerified.DeleteLabelValues(domain.Spec.DomainName, "ptr")
+	}
+
+	for record, ok := range records {
+		value := 0.0
+		if ok {
+			value = 1
+		}
+		domainDNSVerified.WithLabelValues(domain.Spec.DomainName, record).Set(value)
+	}
+}
+
+// forgetDomainMetrics drops the series of a deleted domain.
+func forgetDomainMetrics(name string) {
+	domainDNSVerified.DeletePartialMatch(prometheus.Labels{"domain": name})
+	forgetReputationMetrics(name)
+	forgetDMARCReportMetrics(name)
+}
+
+// trackMetricDomain remembers the domain name the series of the Domain are
+// exported for, so that they are dropped once it is gone, with or without
+// the cleanup finalizer, or renamed.
+func (r *DomainReconciler) trackMetricDomain(domain *corev1alpha1.Domain) {
+	key := types.NamespacedName{Namespace: domain.Namespace, Name: domain.Name}
+	if previous, ok := r.metricDomains.Swap(key, domain.Spec.DomainName); ok && previous.(string) != domain.Spec.DomainName {
+		forgetDomainMetrics(previous.(string))
+	}
+}
+
+// forgetMetricDomain drops the series of a deleted Domain.
+func (r *DomainReconciler) forgetMetricDomain(key types.NamespacedName) {
+	if name, ok := r.metricDomains.LoadAndDelete(key); ok {
+		forgetDomainMetrics(name.(string))
+	}
+}
+
+// postmasterReputations and sndsFilterResults are the values of the
+// one-hot series, so that a change is a flip rather than a new series.
+var (
+	postmasterReputations = []string{"HIGH", "MEDIUM", "LOW", "BAD"}
+	sndsFilterResults     = []string{"GREEN", "YELLOW", "RED"}
+)
+
+// recordReputation exports the reputation data of the domain. The series
+// are replaced on every poll, as the addresses come and go.
+func recordReputation(domain *corev1alpha1.Domain) {
+	name := domain.Spec.DomainName
+	forgetReputationMetrics(name)
+
+	status := domain.Status.Reputation
+	if status == nil {
+		return
+	}
+
+	if pm := status.Postmaster; pm != nil && pm.DomainReputation != "" {
+		for _, rep := range postmasterReputations {
+			value := 0.0
+			if rep == pm.DomainReputation {
+				value = 1
+			}
+			domainPostmasterReputation.WithLabelValues(name, rep).Set(value)
+		}
+		if ratio, ok := reputation.ParseComplaintRate(pm.SpamRate); ok {
+			domainPostmasterSpamRatio.WithLabelValues(name).Set(ratio)
+		}
+		for _, ip := range pm.IPReputations {
+			domainPostmasterIPs.WithLabelValues(name, ip.Reputation).Set(float64(ip.IPCount))
+		}
+	}
+
+	if snds := status.SNDS; snds != nil {
+		for _, ip := range snds.IPs {
+			for _, result := range sndsFilterResults {
+				value := 0.0
+				if result == ip.FilterResult {
+					value = 1
+				}
+				domainSNDSFilterResult.WithLabelValues(name, ip.IP, result).Set(value)
+			}
+			if ratio, ok := reputation.ParseComplaintRate(ip.ComplaintRate); ok {
+				domainSNDSComplaintRatio.WithLabelValues(name, ip.IP).Set(ratio)
+			}
+			domainSNDSTrapHits.WithLabelValues(name, ip.IP).Set(float64(ip.TrapHits))
+		}
+	}
+}
+
+func forgetReputationMetrics(name string) {
+	labels := prometheus.Labels{"domain": name}
+	domainPostmasterReputation.DeletePartialMatch(labels)
+	domainPostmasterSpamRatio.DeletePartialMatch(labels)
+	domainPostmasterIPs.DeletePartialMatch(labels)
+	domainSNDSFilterResult.DeletePartialMatch(labels)
+	domainSNDSComplaintRatio.DeletePartialMatch(labels)
+	domainSNDSTrapHits.DeletePartialMatch(labels)
+}
+
+// recordDMARCReports exports the summary of the DMARC reports of domain.
+// The ratios are only exported once a report evaluated a message.
+func recordDMARCReports(domain string, summary dmarc.Summary) {
+	forgetDMARCReportMetrics(domain)
+
+	domainDMARCMessages.WithLabelValues(domain, "pass").Set(float64(summary.Passed))
+	domainDMARCMessages.WithLabelValues(domain, "fail").Set(float64(summary.Messages - summary.Passed))
+	if summary.Messages == 0 {
+		return
+	}
+	domainDMARCAlignedRatio.WithLabelValues(domain, "dkim").Set(ratio(summary.DKIMAligned, summary.Messages))
+	domainDMARCAlignedRatio.WithLabelValues(domain, "spf").Set(ratio(summary.SPFAligned, summary.Messages))
+	domainDMARCAlignedRatio.WithLabelValues(domain, "dmarc").Set(ratio(summary.Passed, summary.Messages))
+}
+
+func forgetDMARCReportMetrics(domain string) {
+	labels := prometheus.Labels{"domain": domain}
+	domainDMARCMessages.DeletePartialMatch(labels)
+	domainDMARCAlignedRatio.DeletePartialMatch(labels)
+}
+
+// recordStatsRouteReconcile counts a reconciliation of the stats route.
+func recordStatsRouteReconcile(err error) {
+	result := "success"
+	switch {
+	case isPermanentRoutingError(err):
+		result = "conflict"
+	case err != nil:
+		result = "error"
+	}
+
+	statsRouteReconciles.WithLabelValues(result).Inc()
+}
@@ -0,0 +1,280 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	netwrkingv1 "k8s.io/api/networking/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+	"github.com/kannon-email/k8nnon/internal/mtasts"
+)
+
+// The ConfigMaps holding the MTA-STS policies are labeled so that the
+// policy server finds them, and annotated with the host they are served on.
+const (
+	mtaSTSPolicyLabel    = "core.k8s.kannon.email/mta-sts-policy"
+	mtaSTSHostAnnotation = "core.k8s.kannon.email/mta-sts-host"
+)
+
+// errMTASTSDisabled is returned when a Domain enables MTA-STS but the
+// operator runs without the policy server.
+var errMTASTSDisabled = errors.New("the MTA-STS policy server is not enabled in the operator")
+
+func mtaSTSName(domain *corev1alpha1.Domain) string {
+	return fmt.Sprintf("%s-mta-sts", domain.Name)
+}
+
+// reconcileMTASTS hosts the MTA-STS policy of the Domain: a ConfigMap holds
+// the policy, and an Ingress routes the policy host to an ExternalName
+// Service pointing at the policy server of the operator. They are deleted
+// once MTA-STS is turned off.
+func (r *DomainReconciler) reconcileMTASTS(ctx context.Context, domain *corev1alpha1.Domain) error {
+	name := mtaSTSName(domain)
+
+	wanted := domain.Spec.MTASTSEnabled()
+	if r.MTASTSService == "" {
+		if wanted {
+			return errMTASTSDisabled
+		}
+		return nil
+	}
+
+	if !wanted {
+		for _, obj := range mtaSTSObjects() {
+			if err := r.deleteControlled(ctx, domain, name, obj); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	host := mtasts.Host(domain.Spec.DomainName)
+	meta := v1.ObjectMeta{Name: name, Namespace: domain.Namespace}
+
+	cm := &corev1.ConfigMap{ObjectMeta: meta}
+	if err := r.createOrUpdateOwned(ctx, domain, cm, componentMTASTS, func() {
+		setKey(&cm.Labels, mtaSTSPolicyLabel, "true")
+		setKey(&cm.Annotations, mtaSTSHostAnnotation, host)
+		cm.Data = map[string]string{mtasts.PolicyKey: mtasts.DomainPolicy(domain)}
+	}); err != nil {
+		return err
+	}
+
+	svc := &corev1.Service{ObjectMeta: *meta.DeepCopy()}
+	if err := r.createOrUpdateOwned(ctx, domain, svc, componentMTASTS, func() {
+		svc.Spec.Type = corev1.ServiceTypeExternalName
+		svc.Spec.ExternalName = r.MTASTSService
+		svc.Spec.Ports = []corev1.ServicePort{{
+			Name:       "http",
+			Port:       r.MTASTSPort,
+			TargetPort: intstr.FromInt(int(r.MTASTSPort)),
+		}}
+	}); err != nil {
+		return err
+	}
+
+	ing := &netwrkingv1.Ingress{ObjectMeta: *meta.DeepCopy()}
+	return r.createOrUpdateOwned(ctx, domain, ing, componentMTASTS, func() {
+		for key, value := range mtaSTSIngressAnnotations(domain) {
+			setKey(&ing.Annotations, key, value)
+		}
+		for key, value := range domain.Spec.IngressLabels {
+			setKey(&ing.Labels, key, value)
+		}
+		ing.Spec = buildMTASTSIngressSpec(r.withClusterDefaults(domain), r.MTASTSPort)
+	})
+}
+
+// mtaSTSObjects returns empty objects of the kinds hosting the policy.
+func mtaSTSObjects() []client.Object {
+	return []client.Object{&netwrkingv1.Ingress{}, &corev1.Service{}, &corev1.ConfigMap{}}
+}
+
+// createOrUpdateOwned creates obj, or updates it when the Domain controls
+// it, after applying mutate and the resource metadata of the component.
+func (r *DomainReconciler) createOrUpdateOwned(ctx context.Context, domain *corev1alpha1.Domain, obj client.Object, component string, mutate func()) error {
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, obj, func() error {
+		if obj.GetResourceVersion() != "" && !v1.IsControlledBy(obj, domain) {
+			return fmt.Errorf("%T %s is not controlled by the domain", obj, obj.GetName())
+		}
+		mutate()
+		applyResourceMetadata(obj, domain, component)
+		return ctrl.SetControllerReference(domain, obj, r.Scheme)
+	})
+	return err
+}
+
+func setKey(m *map[string]string, key, value string) {
+	if *m == nil {
+		*m = map[string]string{}
+	}
+	(*m)[key] = value
+}
+
+// mtaSTSIngressAnnotations returns the annotations of the policy Ingress:
+// those of the stats Ingress, with the issuer of spec.tls even when the
+// stats host uses a pre-provisioned Secret, which can't cover the policy
+// host.
+func mtaSTSIngressAnnotations(domain *corev1alpha1.Domain) map[string]string {
+	annotations := map[string]string{}
+	for key, value := range domain.Spec.Ingress.Annotations {
+		annotations[key] = value
+	}
+	if tls := domain.Spec.TLS; tls != nil && tls.ClusterIssuer != "" {
+		delete(annotations, "cert-manager.io/issuer")
+		annotations[clusterIssuerAnnotation] = tls.ClusterIssuer
+	}
+
+	return annotations
+}
+
+func buildMTASTSIngressSpec(domain *corev1alpha1.Domain, port int32) netwrkingv1.IngressSpec {
+	pathExact := netwrkingv1.PathTypeExact
+	host := mtasts.Host(domain.Spec.DomainName)
+
+	spec := netwrkingv1.IngressSpec{
+		TLS: []netwrkingv1.IngressTLS{
+			{
+				Hosts:      []string{host},
+				SecretName: fmt.Sprintf("%s-tls", host),
+			},
+		},
+		Rules: []netwrkingv1.IngressRule{
+			{
+				Host: host,
+				IngressRuleValue: netwrkingv1.IngressRuleValue{
+					HTTP: &netwrkingv1.HTTPIngressRuleValue{
+						Paths: []netwrkingv1.HTTPIngressPath{
+							{
+								Path:     mtasts.PolicyPath,
+								PathType: &pathExact,
+								Backend: netwrkingv1.IngressBackend{
+									Service: &netwrkingv1.IngressServiceBackend{
+										Name: mtaSTSName(domain),
+										Port: netwrkingv1.ServiceBackendPort{Number: port},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	if className := domain.Spec.Ingress.ClassName; className != "" {
+		spec.IngressClassName = &className
+	}
+
+	return spec
+}
+
+// mtaSTSCondition computes the MTASTSConfigured condition from the outcome
+// of the policy hosting.
+func mtaSTSCondition(domain *corev1alpha1.Domain, mtaSTSErr error) v1.Condition {
+	cond := v1.Condition{
+		Type:               corev1alpha1.ConditionMTASTSConfigured,
+		Status:             v1.ConditionFalse,
+		ObservedGeneration: domain.Generation,
+	}
+
+	switch {
+	case errors.Is(mtaSTSErr, errMTASTSDisabled):
+		cond.Reason = corev1alpha1.ReasonMTASTSDisabled
+		cond.Message = mtaSTSErr.Error()
+	case mtaSTSErr != nil:
+		cond.Reason = corev1alpha1.ReasonMTASTSFailed
+		cond.Message = mtaSTSErr.Error()
+	default:
+		cond.Status = v1.ConditionTrue
+		cond.Reason = corev1alpha1.ReasonMTASTSPolicyHosted
+		cond.Message = fmt.Sprintf("the policy is served at %s", mtasts.PolicyURL(domain.Spec.DomainName))
+	}
+
+	return cond
+}
+
+// mtaSTSHostIndex indexes the policy ConfigMaps by the host they are
+// served on.
+const mtaSTSHostIndex = "metadata.annotations.mta-sts-host"
+
+func indexMTASTSHost(obj client.Object) []string {
+	if obj.GetLabels()[mtaSTSPolicyLabel] != "true" {
+		return nil
+	}
+	host := obj.GetAnnotations()[mtaSTSHostAnnotation]
+	if host == "" {
+		return nil
+	}
+	return []string{host}
+}
+
+// MTASTSPolicyStore returns the policies hosted in the ConfigMaps of the
+// Domains, to be served by the policy server. c must be the cached client
+// of the manager, with the ConfigMaps indexed by mtaSTSHostIndex: the
+// requests are unauthenticated. A ConfigMap is only served for host when
+// it is controlled by the Domain whose policy host it is, so that the
+// Domains of a namespace can't publish the policies of the others.
+func MTASTSPolicyStore(c client.Reader) mtasts.PolicyStore {
+	return func(ctx context.Context, host string) (string, bool, error) {
+		cms := &corev1.ConfigMapList{}
+		if err := c.List(ctx, cms, client.MatchingFields{mtaSTSHostIndex: host}); err != nil {
+			return "", false, err
+		}
+
+		for i := range cms.Items {
+			cm := &cms.Items[i]
+			ok, err := controlledByPolicyDomain(ctx, c, cm, host)
+			if err != nil {
+				return "", false, err
+			}
+			if !ok {
+				continue
+			}
+			policy, ok := cm.Data[mtasts.PolicyKey]
+			return policy, ok, nil
+		}
+
+		return "", false, nil
+	}
+}
+
+// controlledByPolicyDomain reports whether cm is controlled by a Domain
+// whose policy host is host.
+func controlledByPolicyDomain(ctx context.Context, c client.Reader, cm *corev1.ConfigMap, host string) (bool, error) {
+	ref := v1.GetControllerOf(cm)
+	if ref == nil || ref.APIVersion != corev1alpha1.GroupVersion.String() || ref.Kind != "Domain" {
+		return false, nil
+	}
+
+	domain := &corev1alpha1.Domain{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: cm.Namespace, Name: ref.Name}, domain); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+
+	return domain.UID == ref.UID && mtasts.Host(domain.Spec.DomainName) == host, nil
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	netwrkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+)
+
+// namespaceNameLabel is set by the API server on every namespace to its
+// name.
+const namespaceNameLabel = "kubernetes.io/metadata.name"
+
+// DefaultIngressControllerNamespace is the namespace the stats
+// NetworkPolicies allow the traffic from when neither the Domain nor the
+// operator set one.
+const DefaultIngressControllerNamespace = "ingress-nginx"
+
+// reconcileNetworkPolicy restricts the traffic to the pods of the stats
+// Service to the ingress controller, with a NetworkPolicy selecting the
+// same pods as the Service. It is deleted once the stats host is not
+// exposed or the policy is turned off.
+func (r *DomainReconciler) reconcileNetworkPolicy(ctx context.Context, domain *corev1alpha1.Domain) error {
+	name := statsIngressName(domain)
+
+	if !domain.Spec.StatsNetworkPolicyEnabled() || !domain.Spec.Ingress.IsEnabled() {
+		return r.deleteControlled(ctx, domain, name, &netwrkingv1.NetworkPolicy{})
+	}
+	defaulted := r.withClusterDefaults(domain)
+	if defaulted.Spec.Ingress.Service.Name == "" {
+		return errNoStatsService
+	}
+
+	svc := &corev1.Service{}
+	err := r.Get(ctx, types.NamespacedName{Name: defaulted.Spec.Ingress.Service.Name, Namespace: domain.Namespace}, svc)
+	if apierrors.IsNotFound(err) {
+		return fmt.Errorf("the stats service %s was not found", defaulted.Spec.Ingress.Service.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	spec, err := buildStatsNetworkPolicySpec(defaulted, svc, r.ingressControllerNamespace(domain))
+	if err != nil {
+		return err
+	}
+
+	policy := &netwrkingv1.NetworkPolicy{ObjectMeta: v1.ObjectMeta{Name: name, Namespace: domain.Namespace}}
+	return r.createOrUpdateOwned(ctx, domain, policy, componentNetworkPolicy, func() {
+		policy.Spec = spec
+	})
+}
+
+func (r *DomainReconciler) ingressControllerNamespace(domain *corev1alpha1.Domain) string {
+	if ns := domain.Spec.Stats.NetworkPolicy.IngressNamespace; ns != "" {
+		return ns
+	}
+	if r.IngressControllerNamespace != "" {
+		return r.IngressControllerNamespace
+	}
+	return DefaultIngressControllerNamespace
+}
+
+// buildStatsNetworkPolicySpec only lets the ingress controller namespace
+// reach the target port of the stats Service port, on the pods the Service
+// selects.
+func buildStatsNetworkPolicySpec(domain *corev1alpha1.Domain, svc *corev1.Service, namespace string) (netwrkingv1.NetworkPolicySpec, error) {
+	if len(svc.Spec.Selector) == 0 {
+		return netwrkingv1.NetworkPolicySpec{}, fmt.Errorf("the stats service %s does not select any pod", svc.Name)
+	}
+
+	var target *intstr.IntOrString
+	var protocol corev1.Protocol
+	for _, port := range svc.Spec.Ports {
+		if port.Port != domain.Spec.Ingress.Service.Port {
+			continue
+		}
+		targetPort := port.TargetPort
+		target = &targetPort
+		if target.Type == intstr.Int && target.IntVal == 0 {
+			// an unset target port is the port itself
+			*target = intstr.FromInt(int(port.Port))
+		}
+		protocol = port.Protocol
+		if protocol == "" {
+			protocol = corev1.ProtocolTCP
+		}
+	}
+	if target == nil {
+		return netwrkingv1.NetworkPolicySpec{}, fmt.Errorf("the stats service %s has no port %d", svc.Name, domain.Spec.Ingress.Service.Port)
+	}
+
+	peer := netwrkingv1.NetworkPolicyPeer{
+		NamespaceSelector: &v1.LabelSelector{MatchLabels: map[string]string{namespaceNameLabel: namespace}},
+	}
+	if selector := domain.Spec.Stats.NetworkPolicy.IngressPodSelector; selector != nil {
+		peer.PodSelector = selector.DeepCopy()
+	}
+
+	return netwrkingv1.NetworkPolicySpec{
+		PodSelector: v1.LabelSelector{MatchLabels: mergeMaps(svc.Spec.Selector, nil)},
+		PolicyTypes: []netwrkingv1.PolicyType{netwrkingv1.PolicyTypeIngress},
+		Ingress: []netwrkingv1.NetworkPolicyIngressRule{{
+			From:  []netwrkingv1.NetworkPolicyPeer{peer},
+			Ports: []netwrkingv1.NetworkPolicyPort{{Protocol: &protocol, Port: target}},
+		}},
+	}, nil
+}
+
+func networkPolicyCondition(domain *corev1alpha1.Domain, policyErr error) v1.Condition {
+	cond := v1.Condition{
+		Type:               corev1alpha1.ConditionNetworkPolicyConfigured,
+		Status:             v1.ConditionFalse,
+		ObservedGeneration: domain.Generation,
+	}
+
+	if policyErr != nil {
+		cond.Reason = corev1alpha1.ReasonNetworkPolicyFailed
+		cond.Message = policyErr.Error()
+		return cond
+	}
+
+	cond.Status = v1.ConditionTrue
+	cond.Reason = corev1alpha1.ReasonNetworkPolicyApplied
+	if domain.Spec.Ingress.IsEnabled() {
+		cond.Message = fmt.Sprintf("only the ingress controller can reach the stats service %s", domain.Spec.Ingress.Service.Name)
+	} else {
+		cond.Message = "the stats host is not exposed"
+	}
+	return cond
+}
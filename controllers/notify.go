@@ -0,0 +1,86 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+	"github.com/kannon-email/k8nnon/internal/notify"
+)
+
+// notify sends a notification about the Domain. It is a no-op without a
+// Notifier, and a notification that can't be sent is only logged.
+func (r *DomainReconciler) notify(ctx context.Context, domain *corev1alpha1.Domain, eventType, messageFmt string, args ...interface{}) {
+	if r.Notifier == nil {
+		return
+	}
+
+	err := r.Notifier.Notify(ctx, notify.Event{
+		Type:      eventType,
+		Namespace: domain.Namespace,
+		Name:      domain.Name,
+		Domain:    domain.Spec.DomainName,
+		Message:   fmt.Sprintf(messageFmt, args...),
+		Time:      r.now(),
+	})
+	if err != nil {
+		log.FromContext(ctx).Error(err, "failed to notify", "event", eventType, "domain", domain.Spec.DomainName)
+	}
+}
+
+// notifyReadyTransition notifies when the Domain gets fully verified and
+// when it loses the verification, once the status is written. ready is the
+// Ready condition the reconcile set, the transition is only notified when
+// its lastTransitionTime is the persisted one: a reconcile of a stale
+// Domain keeps the time of the reconcile that persisted, and notified, the
+// transition first.
+func (r *DomainReconciler) notifyReadyTransition(ctx context.Context, domain *corev1alpha1.Domain, wasReady bool, ready v1.Condition) {
+	isReady := ready.Status == v1.ConditionTrue
+	if isReady == wasReady {
+		return
+	}
+
+	persisted := meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionReady)
+	setAt := ready.LastTransitionTime.Rfc3339Copy()
+	if persisted == nil || !persisted.LastTransitionTime.Equal(&setAt) {
+		return
+	}
+
+	if isReady {
+		r.notify(ctx, domain, notify.EventVerified, "%s", ready.Message)
+	} else {
+		r.notify(ctx, domain, notify.EventVerificationLost, "%s", ready.Message)
+	}
+}
+
+// notifyMissingStatsRoute notifies that the stats route was deleted behind
+// the back of the operator. A missing route is only reported when it was
+// reconciled for the current spec, so that a new or a changed Domain does
+// not notify.
+func (r *DomainReconciler) notifyMissingStatsRoute(ctx context.Context, domain *corev1alpha1.Domain, kind, name string) {
+	prev := meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionIngressReady)
+	if prev == nil || prev.Status != v1.ConditionTrue || prev.ObservedGeneration != domain.Generation {
+		return
+	}
+	r.notify(ctx, domain, notify.EventIngressDeleted, "the stats %s %s was deleted, it is recreated", kind, name)
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+	"github.com/kannon-email/k8nnon/internal/dns/checker"
+)
+
+// ownershipTokenSize is the number of random bytes of a challenge token.
+const ownershipTokenSize = 16
+
+// verifyOwnership checks the challenge TXT record of the domain until it is
+// verified, generating a new token when the domain name changes. It only
+// fails when no token can be generated.
+func (r *DomainReconciler) verifyOwnership(ctx context.Context, domain *corev1alpha1.Domain) error {
+	name := domain.Spec.DomainName
+	status := domain.Status.Ownership
+	if status.Verified(name) {
+		return nil
+	}
+
+	if status == nil || status.Domain != name {
+		token, err := ownershipToken()
+		if err != nil {
+			return err
+		}
+		status = &corev1alpha1.OwnershipStatus{Domain: name, Token: token}
+		domain.Status.Ownership = status
+	}
+
+	if r.DNSCheckTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.DNSCheckTimeout)
+		defer cancel()
+	}
+	stats := observeCheck(ctx, "ownership", func(ctx context.Context) checker.DNSCheckStats {
+		return r.DNSChecker.CheckOwnership(ctx, name, status.Token)
+	})
+
+	now := &v1.Time{Time: r.now()}
+	status.DNSStatusStats = mapDNSCheckStats2DomainDNSResult(stats)
+	if status.Expected == nil {
+		status.Expected = &corev1alpha1.DNSRecord{Type: "TXT", Name: checker.OwnershipChallengeName(name), Value: status.Token}
+	}
+	status.CheckedAt = now
+	if status.State == corev1alpha1.CheckStateVerified {
+		status.LastVerified = now
+	}
+
+	return nil
+}
+
+func ownershipToken() (string, error) {
+	b := make([]byte, ownershipTokenSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "k8nnon-challenge=" + hex.EncodeToString(b), nil
+}
+
+// ownershipPending reports whether the ownership of the domain is required
+// and not yet verified. The stats route and the Kannon registration wait
+// for it.
+func ownershipPending(domain *corev1alpha1.Domain) bool {
+	return domain.Status.Ownership != nil && !domain.Status.Ownership.Verified(domain.Spec.DomainName)
+}
+
+// ownershipCondition computes the OwnershipVerified condition.
+func ownershipCondition(domain *corev1alpha1.Domain) v1.Condition {
+	cond := v1.Condition{
+		Type:               corev1alpha1.ConditionOwnershipVerified,
+		Status:             v1.ConditionFalse,
+		Reason:             corev1alpha1.ReasonOwnershipNotVerified,
+		ObservedGeneration: domain.Generation,
+	}
+
+	status := domain.Status.Ownership
+	switch {
+	case status.Verified(domain.Spec.DomainName):
+		cond.Status = v1.ConditionTrue
+		cond.Reason = corev1alpha1.ReasonOwnershipVerified
+		cond.Message = "the challenge record is verified and can be removed"
+	case status.State == corev1alpha1.CheckStateUnknown:
+		cond.Status = v1.ConditionUnknown
+		cond.Message = status.Message
+	default:
+		cond.Message = fmt.Sprintf("publish the TXT record %s with the value %q", checker.OwnershipChallengeName(status.Domain), status.Token)
+	}
+
+	return cond
+}
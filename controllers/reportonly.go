@@ -0,0 +1,113 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	netwrkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	gatewayv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+)
+
+// reportOnly reports whether the Domain is only checked, either because of
+// spec.reportOnly or because the operator runs in dry-run mode.
+func (r *DomainReconciler) reportOnly(domain *corev1alpha1.Domain) bool {
+	return r.DryRun || domain.Spec.ReportOnly
+}
+
+// reportOnlyCondition computes the ReportOnly condition of a Domain that is
+// only checked.
+func (r *DomainReconciler) reportOnlyCondition(domain *corev1alpha1.Domain) v1.Condition {
+	cond := v1.Condition{
+		Type:               corev1alpha1.ConditionReportOnly,
+		Status:             v1.ConditionTrue,
+		Reason:             corev1alpha1.ReasonReportOnly,
+		Message:            "spec.reportOnly is set, the DNS records are checked and no resource is created, changed or deleted",
+		ObservedGeneration: domain.Generation,
+	}
+	if r.DryRun {
+		cond.Reason = corev1alpha1.ReasonDryRun
+		cond.Message = "the operator runs in dry-run mode, the DNS records are checked and no resource is created, changed or deleted"
+	}
+	return cond
+}
+
+// notManagedCondition reports a resource the operator would manage for the
+// Domain if it was not only checked.
+func notManagedCondition(domain *corev1alpha1.Domain, condType, message string) v1.Condition {
+	return v1.Condition{
+		Type:               condType,
+		Status:             v1.ConditionFalse,
+		Reason:             corev1alpha1.ReasonReportOnly,
+		Message:            message,
+		ObservedGeneration: domain.Generation,
+	}
+}
+
+// reportStatsRoute computes the IngressReady condition of a Domain that is
+// only checked, telling what would happen to its stats route.
+func (r *DomainReconciler) reportStatsRoute(ctx context.Context, domain *corev1alpha1.Domain) (v1.Condition, error) {
+	routing := domain.Spec.RoutingOrDefault()
+	if !domain.Spec.Ingress.IsEnabled() {
+		return ingressCondition(domain, nil), nil
+	}
+	if routing == corev1alpha1.RoutingGatewayAPI && !r.GatewayAPI {
+		return ingressCondition(domain, errGatewayAPIDisabled), nil
+	}
+
+	var obj client.Object = &netwrkingv1.Ingress{}
+	if routing == corev1alpha1.RoutingGatewayAPI {
+		obj = &gatewayv1beta1.HTTPRoute{}
+	}
+	kind, name := statsRouteKind(obj), statsIngressName(domain)
+
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: domain.Namespace}, obj)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return v1.Condition{}, err
+	}
+	found := err == nil
+
+	var message string
+	switch {
+	case found && !v1.IsControlledBy(obj, domain) && !adoptable(obj):
+		message = fmt.Sprintf("the %s %s is not controlled by the domain and would be left untouched", kind, name)
+	case found && !statsRouteAllowed(domain):
+		message = fmt.Sprintf("the %s %s would be deleted until the stats CNAME record is verified", kind, name)
+	case found && v1.IsControlledBy(obj, domain):
+		message = fmt.Sprintf("the %s %s would be kept up to date", kind, name)
+	case found:
+		message = fmt.Sprintf("the %s %s would be adopted and kept up to date", kind, name)
+	case statsRouteAllowed(domain):
+		message = fmt.Sprintf("the %s %s would be created", kind, name)
+	default:
+		message = fmt.Sprintf("the %s %s would be created once the stats CNAME record is verified", kind, name)
+	}
+
+	return notManagedCondition(domain, corev1alpha1.ConditionIngressReady, message), nil
+}
+
+// adoptable reports whether adopt would take control of the stats route.
+func adoptable(obj client.Object) bool {
+	return v1.GetControllerOf(obj) == nil && obj.GetAnnotations()[corev1alpha1.AnnotationAdopt] == "true"
+}
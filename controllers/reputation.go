@@ -0,0 +1,202 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+	"github.com/kannon-email/k8nnon/internal/reputation"
+)
+
+// reconcileReputation polls the reputation services of spec.reputation once
+// the poll interval elapsed since the last poll, or on the next reconcile
+// after a failed one. A failed poll keeps the data of the previous one.
+func (r *DomainReconciler) reconcileReputation(ctx context.Context, domain *corev1alpha1.Domain) error {
+	if r.Reputation == nil || domain.Spec.Reputation == nil {
+		domain.Status.Reputation = nil
+		return nil
+	}
+	if reputationRequeueAfter(domain, r.now()) > 0 {
+		return nil
+	}
+
+	status := domain.Status.Reputation
+	if status == nil {
+		status = &corev1alpha1.ReputationStatus{}
+		domain.Status.Reputation = status
+	}
+	status.PolledAt = &v1.Time{Time: r.now()}
+	status.Error = ""
+
+	name := domain.Spec.Reputation.SecretName
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: domain.Namespace, Name: name}, secret)
+	if apierrors.IsNotFound(err) {
+		status.Error = fmt.Sprintf("the secret %s does not exist", name)
+		return nil
+	}
+	if err != nil {
+		status.Error = err.Error()
+		return err
+	}
+
+	credentials := reputation.PostmasterCredentials{
+		ClientID:     string(secret.Data[corev1alpha1.ReputationPostmasterClientIDKey]),
+		ClientSecret: string(secret.Data[corev1alpha1.ReputationPostmasterClientSecretKey]),
+		RefreshToken: string(secret.Data[corev1alpha1.ReputationPostmasterRefreshTokenKey]),
+	}
+	sndsKey := string(secret.Data[corev1alpha1.ReputationSNDSKeyKey])
+
+	postmaster := credentials.ClientID != "" && credentials.ClientSecret != "" && credentials.RefreshToken != ""
+	if !postmaster && sndsKey == "" {
+		status.Postmaster, status.SNDS = nil, nil
+		status.Error = fmt.Sprintf("the secret %s holds no credentials of Google Postmaster Tools nor of Microsoft SNDS", name)
+		return nil
+	}
+
+	if postmaster {
+		status.Postmaster = r.pollPostmaster(ctx, domain, credentials, status.Postmaster)
+	} else {
+		status.Postmaster = nil
+	}
+
+	if sndsKey != "" {
+		ips, err := r.reputationIPs(ctx, domain)
+		if err != nil {
+			return err
+		}
+		status.SNDS = r.pollSNDS(ctx, sndsKey, ips, status.SNDS)
+	} else {
+		status.SNDS = nil
+	}
+
+	return nil
+}
+
+func (r *DomainReconciler) pollPostmaster(ctx context.Context, domain *corev1alpha1.Domain, credentials reputation.PostmasterCredentials, previous *corev1alpha1.PostmasterReputation) *corev1alpha1.PostmasterReputation {
+	stats, err := r.Reputation.Postmaster(ctx, credentials, domain.Spec.DomainName)
+	if err != nil {
+		res := &corev1alpha1.PostmasterReputation{}
+		if previous != nil && !errors.Is(err, reputation.ErrNoData) {
+			res = previous.DeepCopy()
+		}
+		res.Error = err.Error()
+		return res
+	}
+
+	res := &corev1alpha1.PostmasterReputation{
+		Date:             stats.Date,
+		DomainReputation: stats.DomainReputation,
+		SpamRate:         formatRate(stats.UserReportedSpamRatio),
+	}
+	for _, ip := range stats.IPReputations {
+		res.IPReputations = append(res.IPReputations, corev1alpha1.PostmasterIPReputation{
+			Reputation: ip.Reputation,
+			IPCount:    ip.IPCount,
+			SampleIPs:  ip.SampleIPs,
+		})
+	}
+	return res
+}
+
+// pollSNDS polls the data of the key, keeping the one of ips when not nil.
+func (r *DomainReconciler) pollSNDS(ctx context.Context, key string, ips map[string]bool, previous *corev1alpha1.SNDSReputation) *corev1alpha1.SNDSReputation {
+	records, err := r.Reputation.SNDS(ctx, key)
+	if err != nil {
+		res := &corev1alpha1.SNDSReputation{}
+		if previous != nil && !errors.Is(err, reputation.ErrNoData) {
+			res = previous.DeepCopy()
+		}
+		res.Error = err.Error()
+		return res
+	}
+
+	res := &corev1alpha1.SNDSReputation{}
+	for _, record := range records {
+		if ips != nil && !ips[normalizeIP(record.IP)] {
+			continue
+		}
+
+		ip := corev1alpha1.SNDSIPReputation{
+			IP:                record.IP,
+			MessageRecipients: record.MessageRecipients,
+			FilterResult:      record.FilterResult,
+			ComplaintRate:     record.ComplaintRate,
+			TrapHits:          record.TrapHits,
+		}
+		if !record.ActivityEnd.IsZero() {
+			ip.ActivityEnd = &v1.Time{Time: record.ActivityEnd}
+		}
+		res.IPs = append(res.IPs, ip)
+	}
+	return res
+}
+
+// reputationIPs returns the addresses of the SenderPool of the domain, or
+// nil without one. A missing pool has no addresses.
+func (r *DomainReconciler) reputationIPs(ctx context.Context, domain *corev1alpha1.Domain) (map[string]bool, error) {
+	if domain.Spec.SenderPoolRef == nil {
+		return nil, nil
+	}
+
+	ips := map[string]bool{}
+	pool := &corev1alpha1.SenderPool{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: domain.Namespace, Name: domain.Spec.SenderPoolRef.Name}, pool)
+	if apierrors.IsNotFound(err) {
+		return ips, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ip := range pool.Spec.IPs {
+		ips[normalizeIP(ip)] = true
+	}
+	return ips, nil
+}
+
+func normalizeIP(ip string) string {
+	if parsed := net.ParseIP(ip); parsed != nil {
+		return parsed.String()
+	}
+	return ip
+}
+
+// reputationRequeueAfter returns how long until the reputation services of
+// the domain are polled again, zero when they are due.
+func reputationRequeueAfter(domain *corev1alpha1.Domain, now time.Time) time.Duration {
+	status := domain.Status.Reputation
+	if domain.Spec.Reputation == nil || status == nil || status.PolledAt == nil || status.Error != "" {
+		return 0
+	}
+
+	next := status.PolledAt.Add(domain.Spec.Reputation.PollIntervalOrDefault())
+	if !next.After(now) {
+		return 0
+	}
+	return next.Sub(now)
+}
@@ -0,0 +1,244 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"container/heap"
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+)
+
+// baseDomainIndex indexes the Domains by spec.baseDomain.
+const baseDomainIndex = "spec.baseDomain"
+
+func indexBaseDomain(obj client.Object) []string {
+	domain, ok := obj.(*corev1alpha1.Domain)
+	if !ok || domain.Spec.BaseDomain == "" {
+		return nil
+	}
+	return []string{domain.Spec.BaseDomain}
+}
+
+// schedulerIdleWait is how long the scheduler sleeps with nothing scheduled.
+const schedulerIdleWait = time.Hour
+
+// recheckScheduler enqueues the Domains when their next check is due,
+// instead of each reconcile requeueing its Domain. A due Domain brings
+// along the Domains of its base domain due within the batch window: they
+// share the sending infrastructure, so their checks made together find
+// the shared lookups in the cache, and they stay due together afterwards.
+type recheckScheduler struct {
+	c      client.Reader
+	window time.Duration
+	now    func() time.Time
+
+	m sync.Mutex
+	// due holds the scheduled check of each Domain, queued in queue.
+	due map[types.NamespacedName]*queuedRecheck
+	// queue orders the scheduled checks by due time.
+	queue recheckQueue
+	wake  chan struct{}
+}
+
+func newRecheckScheduler(c client.Reader, window time.Duration, now func() time.Time) *recheckScheduler {
+	return &recheckScheduler{
+		c:      c,
+		window: window,
+		now:    now,
+		due:    map[types.NamespacedName]*queuedRecheck{},
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// schedule replaces the next check of the Domain by one after interval.
+func (s *recheckScheduler) schedule(domain *corev1alpha1.Domain, interval time.Duration) {
+	key := client.ObjectKeyFromObject(domain)
+	at := s.now().Add(interval)
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	earliest := len(s.queue) == 0 || at.Before(s.queue[0].at)
+	if item, ok := s.due[key]; ok {
+		item.at = at
+		item.baseDomain = domain.Spec.BaseDomain
+		heap.Fix(&s.queue, item.index)
+	} else {
+		item := &queuedRecheck{at: at, key: key, baseDomain: domain.Spec.BaseDomain}
+		s.due[key] = item
+		heap.Push(&s.queue, item)
+	}
+
+	if earliest {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// forget drops the next check of a deleted or suspended Domain.
+func (s *recheckScheduler) forget(key types.NamespacedName) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	s.remove(key)
+}
+
+// remove drops the scheduled check of the Domain, s.m must be held.
+func (s *recheckScheduler) remove(key types.NamespacedName) {
+	if item, ok := s.due[key]; ok {
+		heap.Remove(&s.queue, item.index)
+		delete(s.due, key)
+	}
+}
+
+// next returns when the scheduler wakes up next.
+func (s *recheckScheduler) next() (time.Time, bool) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if len(s.queue) == 0 {
+		return time.Time{}, false
+	}
+	return s.queue[0].at, true
+}
+
+// take removes the Domains due at now from the scheduler, along with the
+// Domains of their base domains due within the window, and returns them
+// sorted.
+func (s *recheckScheduler) take(ctx context.Context, now time.Time) []types.NamespacedName {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	var batch []types.NamespacedName
+	baseDomains := map[string]bool{}
+	for len(s.queue) > 0 && !s.queue[0].at.After(now) {
+		item := heap.Pop(&s.queue).(*queuedRecheck)
+		delete(s.due, item.key)
+		batch = append(batch, item.key)
+		if item.baseDomain != "" {
+			baseDomains[item.baseDomain] = true
+		}
+	}
+
+	for baseDomain := range baseDomains {
+		domains := &corev1alpha1.DomainList{}
+		if err := s.c.List(ctx, domains, client.MatchingFields{baseDomainIndex: baseDomain}); err != nil {
+			log.FromContext(ctx).Error(err, "failed to list the domains of the base domain", "baseDomain", baseDomain)
+			continue
+		}
+		for i := range domains.Items {
+			key := client.ObjectKeyFromObject(&domains.Items[i])
+			if due, ok := s.due[key]; ok && !due.at.After(now.Add(s.window)) {
+				s.remove(key)
+				batch = append(batch, key)
+			}
+		}
+	}
+
+	sort.Slice(batch, func(i, j int) bool {
+		return batch[i].String() < batch[j].String()
+	})
+	if len(batch) > 0 {
+		recheckBatchSize.Observe(float64(len(batch)))
+	}
+	return batch
+}
+
+// run enqueues the due Domains through events until ctx is done.
+func (s *recheckScheduler) run(events chan<- event.GenericEvent) func(context.Context) error {
+	return func(ctx context.Context) error {
+		timer := time.NewTimer(schedulerIdleWait)
+		defer timer.Stop()
+
+		for {
+			wait := schedulerIdleWait
+			if at, ok := s.next(); ok {
+				wait = at.Sub(s.now())
+			}
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			timer.Reset(wait)
+
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-s.wake:
+				continue
+			case <-timer.C:
+			}
+
+			for _, key := range s.take(ctx, s.now()) {
+				domain := &corev1alpha1.Domain{ObjectMeta: v1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}}
+				select {
+				case events <- event.GenericEvent{Object: domain}:
+				case <-ctx.Done():
+					return nil
+				}
+			}
+		}
+	}
+}
+
+type queuedRecheck struct {
+	at         time.Time
+	key        types.NamespacedName
+	baseDomain string
+	// index is the position of the item in the queue.
+	index int
+}
+
+// recheckQueue is a heap of the scheduled checks, the earliest first.
+type recheckQueue []*queuedRecheck
+
+func (q recheckQueue) Len() int           { return len(q) }
+func (q recheckQueue) Less(i, j int) bool { return q[i].at.Before(q[j].at) }
+
+func (q recheckQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *recheckQueue) Push(x any) {
+	item := x.(*queuedRecheck)
+	item.index = len(*q)
+	*q = append(*q, item)
+}
+
+func (q *recheckQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+)
+
+// senderPoolCondition mirrors the readiness of the SenderPool referenced by
+// the Domain.
+func (r *DomainReconciler) senderPoolCondition(ctx context.Context, domain *corev1alpha1.Domain) (v1.Condition, error) {
+	cond := v1.Condition{
+		Type:               corev1alpha1.ConditionSenderPoolReady,
+		Status:             v1.ConditionFalse,
+		ObservedGeneration: domain.Generation,
+	}
+
+	name := domain.Spec.SenderPoolRef.Name
+	pool := &corev1alpha1.SenderPool{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: domain.Namespace, Name: name}, pool)
+	if apierrors.IsNotFound(err) {
+		cond.Reason = corev1alpha1.ReasonSenderPoolNotFound
+		cond.Message = fmt.Sprintf("the sender pool %s does not exist", name)
+		return cond, nil
+	}
+	if err != nil {
+		return cond, err
+	}
+
+	poolCond := meta.FindStatusCondition(pool.Status.Conditions, corev1alpha1.ConditionSenderPoolReady)
+	if poolCond == nil {
+		cond.Status = v1.ConditionUnknown
+		cond.Reason = corev1alpha1.ReasonSenderPoolNotVerified
+		cond.Message = fmt.Sprintf("the sender pool %s is not checked yet", name)
+		return cond, nil
+	}
+
+	cond.Status = poolCond.Status
+	cond.Reason = poolCond.Reason
+	cond.Message = fmt.Sprintf("sender pool %s: %s", name, poolCond.Message)
+	return cond, nil
+}
+
+// domainsForSenderPool maps a SenderPool to the Domains referencing it.
+func (r *DomainReconciler) domainsForSenderPool(obj client.Object) []reconcile.Request {
+	pool, ok := obj.(*corev1alpha1.SenderPool)
+	if !ok {
+		return nil
+	}
+
+	names, err := referencingDomains(context.Background(), r.Client, pool)
+	if err != nil {
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(names))
+	for _, name := range names {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: pool.Namespace, Name: name}})
+	}
+	return requests
+}
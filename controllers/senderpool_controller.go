@@ -0,0 +1,310 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+	"github.com/kannon-email/k8nnon/internal/dns/checker"
+	"github.com/kannon-email/k8nnon/internal/resilience"
+	"github.com/kannon-email/k8nnon/internal/shard"
+	"github.com/kannon-email/k8nnon/internal/tracing"
+)
+
+// SenderPoolReconciler reconciles a SenderPool object
+type SenderPoolReconciler struct {
+	client.Client
+	clock
+	Scheme     *runtime.Scheme
+	DNSChecker checker.DNSChecker
+
+	// DNSCheckTimeout bounds each DNS check, including all of its lookups.
+	// Zero leaves the checks bounded by the lookup timeout only.
+	DNSCheckTimeout time.Duration
+
+	// Shard is the share of the SenderPools the replica reconciles.
+	Shard shard.Shard
+}
+
+//+kubebuilder:rbac:groups=core.k8s.kannon.email,resources=senderpools,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core.k8s.kannon.email,resources=senderpools/status,verbs=get;update;patch
+
+// Reconcile checks the membership, the reverse DNS and the SPF
+// authorization of the addresses of a SenderPool.
+func (r *SenderPoolReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	if !r.Shard.Owns(req.Namespace, req.Name) {
+		return ctrl.Result{}, nil
+	}
+
+	l := log.FromContext(ctx)
+	l.Info("reconciling sender pool", "senderPool", req.NamespacedName)
+	ctx = resilience.WithBudgetKey(ctx, "senderpool/"+req.NamespacedName.String())
+
+	pool := &corev1alpha1.SenderPool{}
+	if err := r.Get(ctx, req.NamespacedName, pool); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	pools := &corev1alpha1.SenderPoolList{}
+	if err := r.List(ctx, pools, client.InNamespace(pool.Namespace)); err != nil {
+		return ctrl.Result{}, err
+	}
+	ips, invalid := poolMembers(pool, pools.Items)
+
+	pool.Status.IPs = r.checkAddresses(ctx, pool, ips)
+
+	domains, err := referencingDomains(ctx, r.Client, pool)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	pool.Status.Domains = len(domains)
+
+	pool.Status.ObservedGeneration = pool.Generation
+	pool.Status.LastCheckTime = &v1.Time{Time: r.now()}
+	meta.SetStatusCondition(&pool.Status.Conditions, senderPoolReadyCondition(pool, invalid))
+
+	if err := r.Status().Update(ctx, pool); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{RequeueAfter: pool.Spec.CheckIntervalOrDefault()}, nil
+}
+
+// poolMembers returns the valid addresses of the pool, and why the others
+// are not. An address listed by another pool of the namespace belongs to
+// the oldest one.
+func poolMembers(pool *corev1alpha1.SenderPool, pools []corev1alpha1.SenderPool) ([]string, []string) {
+	claimed := map[string]string{}
+	for _, other := range pools {
+		if other.UID == pool.UID || !olderPool(&other, pool) {
+			continue
+		}
+		for _, ip := range other.Spec.IPs {
+			if parsed := net.ParseIP(ip); parsed != nil {
+				claimed[parsed.String()] = other.Name
+			}
+		}
+	}
+
+	var ips, invalid []string
+	seen := map[string]bool{}
+	for _, ip := range pool.Spec.IPs {
+		parsed := net.ParseIP(ip)
+		switch {
+		case parsed == nil:
+			invalid = append(invalid, fmt.Sprintf("%q is not an IP address", ip))
+		case seen[parsed.String()]:
+			invalid = append(invalid, fmt.Sprintf("%s is listed twice", ip))
+		case claimed[parsed.String()] != "":
+			invalid = append(invalid, fmt.Sprintf("%s belongs to the sender pool %s", ip, claimed[parsed.String()]))
+		default:
+			ips = append(ips, ip)
+		}
+		if parsed != nil {
+			seen[parsed.String()] = true
+		}
+	}
+
+	return ips, invalid
+}
+
+func olderPool(a, b *corev1alpha1.SenderPool) bool {
+	if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return a.CreationTimestamp.Before(&b.CreationTimestamp)
+	}
+	return a.Name < b.Name
+}
+
+// checkAddresses checks the reverse DNS and the SPF authorization of the
+// addresses in parallel.
+func (r *SenderPoolReconciler) checkAddresses(ctx context.Context, pool *corev1alpha1.SenderPool, ips []string) []corev1alpha1.SenderPoolIPStatus {
+	ptrStats := make([]checker.DNSCheckStats, len(ips))
+	spfStats := make([]checker.DNSCheckStats, len(ips))
+
+	g := errgroup.Group{}
+	run := func(record string, stats *checker.DNSCheckStats, check func(context.Context) checker.DNSCheckStats) {
+		g.Go(func() error {
+			checkCtx := ctx
+			if r.DNSCheckTimeout > 0 {
+				var cancel context.CancelFunc
+				checkCtx, cancel = context.WithTimeout(ctx, r.DNSCheckTimeout)
+				defer cancel()
+			}
+
+			*stats = observeCheck(checkCtx, record, check)
+			return nil
+		})
+	}
+	for i, ip := range ips {
+		ip := ip
+		run("pool_ptr", &ptrStats[i], func(ctx context.Context) checker.DNSCheckStats {
+			return r.DNSChecker.CheckPTR(ctx, ip, pool.Spec.ReverseDomain)
+		})
+		run("pool_spf", &spfStats[i], func(ctx context.Context) checker.DNSCheckStats {
+			return r.DNSChecker.CheckSPFCoverage(ctx, pool.Spec.SPFDomain, ip)
+		})
+	}
+	_ = g.Wait()
+
+	status := make([]corev1alpha1.SenderPoolIPStatus, 0, len(ips))
+	for i, ip := range ips {
+		status = append(status, corev1alpha1.SenderPoolIPStatus{
+			IP:   ip,
+			PTR:  mapDNSCheckStats2DomainDNSResult(ptrStats[i]),
+			Host: ptrStats[i].Value,
+			SPF:  mapDNSCheckStats2DomainDNSResult(spfStats[i]),
+		})
+	}
+	return status
+}
+
+// senderPoolReadyCondition computes the SenderPoolReady condition from the
+// invalid members and the checks of the valid ones.
+func senderPoolReadyCondition(pool *corev1alpha1.SenderPool, invalid []string) v1.Condition {
+	cond := v1.Condition{
+		Type:               corev1alpha1.ConditionSenderPoolReady,
+		Status:             v1.ConditionFalse,
+		ObservedGeneration: pool.Generation,
+	}
+
+	if len(invalid) > 0 {
+		cond.Reason = corev1alpha1.ReasonInvalidMembers
+		cond.Message = strings.Join(invalid, "; ")
+		return cond
+	}
+
+	var failing []string
+	for _, ip := range pool.Status.IPs {
+		switch {
+		case !ip.PTR.OK:
+			failing = append(failing, fmt.Sprintf("the reverse DNS of %s is not verified", ip.IP))
+		case !ip.SPF.OK:
+			failing = append(failing, fmt.Sprintf("%s is not authorized by the SPF record of %s", ip.IP, pool.Spec.SPFDomain))
+		}
+	}
+	if len(failing) > 0 {
+		cond.Reason = corev1alpha1.ReasonSenderPoolNotVerified
+		cond.Message = strings.Join(failing, "; ")
+		return cond
+	}
+
+	cond.Status = v1.ConditionTrue
+	cond.Reason = corev1alpha1.ReasonSenderPoolVerified
+	cond.Message = "the reverse DNS and the SPF authorization of every address are verified"
+	return cond
+}
+
+// referencingDomains returns the names of the Domains of the namespace
+// referencing the pool, sorted.
+func referencingDomains(ctx context.Context, c client.Reader, pool *corev1alpha1.SenderPool) ([]string, error) {
+	domains := &corev1alpha1.DomainList{}
+	if err := c.List(ctx, domains, client.InNamespace(pool.Namespace)); err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, domain := range domains.Items {
+		if ref := domain.Spec.SenderPoolRef; ref != nil && ref.Name == pool.Name {
+			names = append(names, domain.Name)
+		}
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// poolsForDomain maps a Domain to the SenderPool it references. Updates
+// are mapped for both the old and the new object, so moving a Domain to
+// another pool recounts the one it left as well.
+func (r *SenderPoolReconciler) poolsForDomain(obj client.Object) []reconcile.Request {
+	domain, ok := obj.(*corev1alpha1.Domain)
+	if !ok || domain.Spec.SenderPoolRef == nil {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: domain.Namespace, Name: domain.Spec.SenderPoolRef.Name}}}
+}
+
+// poolsSharingAddresses maps a SenderPool to the other pools of the
+// namespace listing one of its addresses, whose membership depends on it.
+func (r *SenderPoolReconciler) poolsSharingAddresses(obj client.Object) []reconcile.Request {
+	pool, ok := obj.(*corev1alpha1.SenderPool)
+	if !ok {
+		return nil
+	}
+
+	ips := map[string]bool{}
+	for _, ip := range pool.Spec.IPs {
+		if parsed := net.ParseIP(ip); parsed != nil {
+			ips[parsed.String()] = true
+		}
+	}
+	if len(ips) == 0 {
+		return nil
+	}
+
+	pools := &corev1alpha1.SenderPoolList{}
+	if err := r.List(context.Background(), pools, client.InNamespace(pool.Namespace)); err != nil {
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, other := range pools.Items {
+		if other.UID == pool.UID {
+			continue
+		}
+		for _, ip := range other.Spec.IPs {
+			if parsed := net.ParseIP(ip); parsed != nil && ips[parsed.String()] {
+				requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&other)})
+				break
+			}
+		}
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *SenderPoolReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&corev1alpha1.SenderPool{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		// the pools sharing an address lose or win it when another one changes
+		Watches(&source.Kind{Type: &corev1alpha1.SenderPool{}}, handler.EnqueueRequestsFromMapFunc(r.poolsSharingAddresses),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		// the referencing Domains are counted in the status
+		Watches(&source.Kind{Type: &corev1alpha1.Domain{}}, handler.EnqueueRequestsFromMapFunc(r.poolsForDomain),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Complete(tracing.Reconciler("SenderPool", r))
+}
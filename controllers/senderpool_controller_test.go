@@ -0,0 +1,231 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+	"github.com/kannon-email/k8nnon/internal/dns/checker"
+)
+
+func TestSenderPoolVerified(t *testing.T) {
+	ctx := context.Background()
+
+	pool := createSenderPool(t, "pool", "192.0.2.1", "192.0.2.2")
+	domain := createDomain(t)
+	domain.Spec.SenderPoolRef = &corev1alpha1.SenderPoolReference{Name: "pool"}
+
+	dnsChecker := checker.NewFakeDNSChecker(checker.WithAll(true),
+		checker.WithPTRStats("192.0.2.1", checker.DNSCheckStats{CntOK: 1, Value: "out1.mx.example.com"}))
+	r := createSenderPoolReconciler(t, dnsChecker, pool, domain)
+	res := reconcileObject(t, r, pool)
+	assert.Equal(t, time.Hour, res.RequeueAfter)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(pool), pool))
+	require.Len(t, pool.Status.IPs, 2)
+	assert.Equal(t, "192.0.2.1", pool.Status.IPs[0].IP)
+	assert.True(t, pool.Status.IPs[0].PTR.OK)
+	assert.Equal(t, "out1.mx.example.com", pool.Status.IPs[0].Host)
+	assert.True(t, pool.Status.IPs[1].SPF.OK)
+	assert.Equal(t, 1, pool.Status.Domains)
+	assert.NotNil(t, pool.Status.LastCheckTime)
+	assert.True(t, meta.IsStatusConditionTrue(pool.Status.Conditions, corev1alpha1.ConditionSenderPoolReady))
+
+	assert.Contains(t, dnsChecker.Calls(), checker.FakeDNSCheckerCall{Method: "CheckPTR", Domain: "mx.example.com"})
+	assert.Contains(t, dnsChecker.Calls(), checker.FakeDNSCheckerCall{Method: "CheckSPFCoverage", Domain: "spf.example.com"})
+}
+
+func TestSenderPoolNotVerified(t *testing.T) {
+	ctx := context.Background()
+
+	pool := createSenderPool(t, "pool", "192.0.2.1", "192.0.2.2")
+
+	dnsChecker := checker.NewFakeDNSChecker(checker.WithAll(true),
+		checker.WithSPFCoverageStats("192.0.2.2", checker.DNSCheckStats{CntKO: 1, Reason: "spf.example.com does not authorize 192.0.2.2"}))
+	r := createSenderPoolReconciler(t, dnsChecker, pool)
+	reconcileObject(t, r, pool)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(pool), pool))
+	assert.Equal(t, "spf.example.com does not authorize 192.0.2.2", pool.Status.IPs[1].SPF.Message)
+
+	cond := meta.FindStatusCondition(pool.Status.Conditions, corev1alpha1.ConditionSenderPoolReady)
+	require.NotNil(t, cond)
+	assert.Equal(t, v1.ConditionFalse, cond.Status)
+	assert.Equal(t, corev1alpha1.ReasonSenderPoolNotVerified, cond.Reason)
+	assert.Equal(t, "192.0.2.2 is not authorized by the SPF record of spf.example.com", cond.Message)
+}
+
+func TestSenderPoolInvalidMembers(t *testing.T) {
+	ctx := context.Background()
+
+	older := createSenderPool(t, "older", "192.0.2.3")
+	older.CreationTimestamp = v1.NewTime(time.Now().Add(-time.Hour))
+	pool := createSenderPool(t, "pool", "192.0.2.1", "mx.example.com", "192.0.2.1", "192.0.2.3")
+	pool.CreationTimestamp = v1.NewTime(time.Now())
+
+	dnsChecker := checker.NewFakeDNSChecker(checker.WithAll(true))
+	r := createSenderPoolReconciler(t, dnsChecker, older, pool)
+	reconcileObject(t, r, pool)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(pool), pool))
+	require.Len(t, pool.Status.IPs, 1, "only the valid addresses should be checked")
+	assert.Equal(t, "192.0.2.1", pool.Status.IPs[0].IP)
+
+	cond := meta.FindStatusCondition(pool.Status.Conditions, corev1alpha1.ConditionSenderPoolReady)
+	require.NotNil(t, cond)
+	assert.Equal(t, corev1alpha1.ReasonInvalidMembers, cond.Reason)
+	assert.Equal(t, `"mx.example.com" is not an IP address; 192.0.2.1 is listed twice; 192.0.2.3 belongs to the sender pool older`, cond.Message)
+
+	reconcileObject(t, r, older)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(older), older))
+	assert.True(t, meta.IsStatusConditionTrue(older.Status.Conditions, corev1alpha1.ConditionSenderPoolReady), "the oldest pool keeps the address")
+}
+
+func TestSenderPoolConflictRequeue(t *testing.T) {
+	ctx := context.Background()
+
+	older := createSenderPool(t, "older", "192.0.2.3")
+	older.CreationTimestamp = v1.NewTime(time.Now().Add(-time.Hour))
+	pool := createSenderPool(t, "pool", "192.0.2.1", "192.0.2.3")
+	pool.CreationTimestamp = v1.NewTime(time.Now())
+	other := createSenderPool(t, "other", "192.0.2.9")
+
+	dnsChecker := checker.NewFakeDNSChecker(checker.WithAll(true))
+	r := createSenderPoolReconciler(t, dnsChecker, older, pool, other)
+	reconcileObject(t, r, pool)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(pool), pool))
+	assert.Equal(t, corev1alpha1.ReasonInvalidMembers, meta.FindStatusCondition(pool.Status.Conditions, corev1alpha1.ConditionSenderPoolReady).Reason)
+
+	assert.Equal(t, []reconcile.Request{{NamespacedName: client.ObjectKeyFromObject(pool)}}, r.poolsSharingAddresses(older),
+		"the pools sharing an address should be requeued")
+
+	require.NoError(t, r.Delete(ctx, older))
+	reconcileObject(t, r, pool)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(pool), pool))
+	assert.Len(t, pool.Status.IPs, 2)
+	assert.True(t, meta.IsStatusConditionTrue(pool.Status.Conditions, corev1alpha1.ConditionSenderPoolReady), "the address is released with the older pool")
+}
+
+func TestSenderPoolDomainMoved(t *testing.T) {
+	ctx := context.Background()
+
+	previous := createSenderPool(t, "previous", "192.0.2.1")
+	next := createSenderPool(t, "next", "192.0.2.2")
+	domain := createDomain(t)
+	domain.Spec.SenderPoolRef = &corev1alpha1.SenderPoolReference{Name: "previous"}
+
+	dnsChecker := checker.NewFakeDNSChecker(checker.WithAll(true))
+	r := createSenderPoolReconciler(t, dnsChecker, previous, next, domain)
+	reconcileObject(t, r, previous)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(previous), previous))
+	assert.Equal(t, 1, previous.Status.Domains)
+
+	moved := domain.DeepCopy()
+	moved.Spec.SenderPoolRef = &corev1alpha1.SenderPoolReference{Name: "next"}
+	require.NoError(t, r.Update(ctx, moved))
+
+	q := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	defer q.ShutDown()
+	handler.EnqueueRequestsFromMapFunc(r.poolsForDomain).Update(event.UpdateEvent{ObjectOld: domain, ObjectNew: moved}, q)
+	require.Equal(t, 2, q.Len(), "both the previous and the next pool should be requeued")
+
+	reconcileObject(t, r, previous)
+	reconcileObject(t, r, next)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(previous), previous))
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(next), next))
+	assert.Equal(t, 0, previous.Status.Domains)
+	assert.Equal(t, 1, next.Status.Domains)
+}
+
+func TestDomainSenderPoolCondition(t *testing.T) {
+	ctx := context.Background()
+
+	domain := createDomain(t)
+	domain.Spec.SenderPoolRef = &corev1alpha1.SenderPoolReference{Name: "pool"}
+
+	dnsChecker := checker.NewFakeDNSChecker(checker.WithAll(true))
+	r := createReconciler(t, dnsChecker, domain)
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	cond := meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionSenderPoolReady)
+	require.NotNil(t, cond)
+	assert.Equal(t, corev1alpha1.ReasonSenderPoolNotFound, cond.Reason)
+	assert.True(t, meta.IsStatusConditionTrue(domain.Status.Conditions, corev1alpha1.ConditionReady), "the sender pool should not affect readiness")
+
+	pool := createSenderPool(t, "pool", "192.0.2.1")
+	require.NoError(t, r.Create(ctx, pool))
+	poolReconciler := &SenderPoolReconciler{Client: r.Client, Scheme: r.Scheme, DNSChecker: dnsChecker}
+	reconcileObject(t, poolReconciler, pool)
+
+	assert.Equal(t, []ctrl.Request{{NamespacedName: client.ObjectKeyFromObject(domain)}}, r.domainsForSenderPool(pool))
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	cond = meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionSenderPoolReady)
+	require.NotNil(t, cond)
+	assert.Equal(t, v1.ConditionTrue, cond.Status)
+	assert.Equal(t, "sender pool pool: the reverse DNS and the SPF authorization of every address are verified", cond.Message)
+
+	domain.Spec.SenderPoolRef = nil
+	require.NoError(t, r.Update(ctx, domain))
+	reconcileObject(t, r, domain)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(domain), domain))
+	assert.Nil(t, meta.FindStatusCondition(domain.Status.Conditions, corev1alpha1.ConditionSenderPoolReady))
+}
+
+func createSenderPoolReconciler(t *testing.T, dnsChecker checker.DNSChecker, objs ...client.Object) *SenderPoolReconciler {
+	t.Helper()
+
+	c, scheme := newFakeClient(t, objs...)
+	return &SenderPoolReconciler{Client: c, Scheme: scheme, DNSChecker: dnsChecker}
+}
+
+func createSenderPool(t *testing.T, name string, ips ...string) *corev1alpha1.SenderPool {
+	t.Helper()
+
+	return &corev1alpha1.SenderPool{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			UID:       "uid-" + types.UID(name),
+		},
+		Spec: corev1alpha1.SenderPoolSpec{
+			IPs:           ips,
+			ReverseDomain: "mx.example.com",
+			SPFDomain:     "spf.example.com",
+		},
+	}
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+)
+
+// patchStatus writes the status the reconcile computed on domain from base,
+// the Domain as it was read. Only the changed fields are sent, and nothing
+// at all when the status did not change.
+//
+// The patch is conditioned on the resourceVersion of base, so that the
+// status is not computed from a stale Domain without anyone noticing. When
+// another writer updated the Domain in between, the changes of the
+// reconcile are replayed onto the latest version, whose other fields are
+// kept, and the patch is retried.
+func (r *DomainReconciler) patchStatus(ctx context.Context, domain, base *corev1alpha1.Domain) error {
+	changes, err := client.MergeFrom(base).Data(domain)
+	if err != nil {
+		return err
+	}
+	if string(changes) == "{}" {
+		return nil
+	}
+
+	err = r.Status().Patch(ctx, domain, client.MergeFromWithOptions(base, client.MergeFromWithOptimisticLock{}))
+	if !apierrors.IsConflict(err) {
+		return err
+	}
+
+	log.FromContext(ctx).V(1).Info("status conflict, replaying the changes onto the latest domain",
+		"domain", client.ObjectKeyFromObject(domain))
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest := &corev1alpha1.Domain{}
+		if err := r.Get(ctx, client.ObjectKeyFromObject(domain), latest); err != nil {
+			return err
+		}
+
+		replayed, err := replayChanges(latest, changes)
+		if err != nil {
+			return err
+		}
+		keepTransitionTimes(replayed, latest)
+		if err := r.Status().Patch(ctx, replayed, client.MergeFromWithOptions(latest, client.MergeFromWithOptimisticLock{})); err != nil {
+			return err
+		}
+
+		replayed.DeepCopyInto(domain)
+		return nil
+	})
+}
+
+// replayChanges applies the merge patch changes to a copy of domain.
+func replayChanges(domain *corev1alpha1.Domain, changes []byte) (*corev1alpha1.Domain, error) {
+	current, err := json.Marshal(domain)
+	if err != nil {
+		return nil, err
+	}
+	merged, err := jsonpatch.MergePatch(current, changes)
+	if err != nil {
+		return nil, err
+	}
+
+	replayed := &corev1alpha1.Domain{}
+	if err := json.Unmarshal(merged, replayed); err != nil {
+		return nil, err
+	}
+	return replayed, nil
+}
+
+// keepTransitionTimes keeps the lastTransitionTime of the conditions of
+// latest whose status the replayed changes did not change: the reconcile
+// computed the transitions from a stale Domain.
+func keepTransitionTimes(replayed, latest *corev1alpha1.Domain) {
+	for i, cond := range replayed.Status.Conditions {
+		if prev := meta.FindStatusCondition(latest.Status.Conditions, cond.Type); prev != nil && prev.Status == cond.Status {
+			replayed.Status.Conditions[i].LastTransitionTime = prev.LastTransitionTime
+		}
+	}
+}
+
+// lastCheckRefreshInterval bounds how old the persisted check time of a
+// Domain whose checks keep the same outcome gets.
+const lastCheckRefreshInterval = time.Hour
+
+// volatileStatusFields are the times the reconcile stamps on every check,
+// wherever they appear in the status.
+var volatileStatusFields = []string{"lastCheckTime", "checkedAt", "lastVerified", "updatedAt"}
+
+// statusChanged reports whether the status the reconcile computed on domain
+// differs from the one of base, the Domain as it was read, by more than the
+// times of the checks. The count of failed checks is also ignored once the
+// backoff reached its cap, as it does not change the rechecks anymore.
+//
+// The times are still written once the persisted check time is older than
+// lastCheckRefreshInterval.
+func (r *DomainReconciler) statusChanged(domain, base *corev1alpha1.Domain) bool {
+	last := base.Status.LastCheckTime
+	if last == nil || r.now().Sub(last.Time) >= lastCheckRefreshInterval {
+		return true
+	}
+
+	computed, persisted := domain.Status.DeepCopy(), base.Status.DeepCopy()
+	maxInterval := r.withClusterDefaults(domain).Spec.UnverifiedCheckIntervalOrDefault()
+	if failedRecheckBackoff(computed.FailedChecks, maxInterval) == maxInterval &&
+		failedRecheckBackoff(persisted.FailedChecks, maxInterval) == maxInterval {
+		computed.FailedChecks = persisted.FailedChecks
+	}
+
+	a, errA := withoutVolatileFields(computed)
+	b, errB := withoutVolatileFields(persisted)
+	if errA != nil || errB != nil {
+		return true
+	}
+	return !reflect.DeepEqual(a, b)
+}
+
+// withoutVolatileFields returns status as a JSON document, without the
+// volatileStatusFields.
+func withoutVolatileFields(status *corev1alpha1.DomainStatus) (interface{}, error) {
+	data, err := json.Marshal(status)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	dropFields(doc, volatileStatusFields)
+	return doc, nil
+}
+
+func dropFields(doc interface{}, fields []string) {
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		for _, f := range fields {
+			delete(v, f)
+		}
+		for _, child := range v {
+			dropFields(child, fields)
+		}
+	case []interface{}:
+		for _, child := range v {
+			dropFields(child, fields)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package delivery
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Types of the delivery events.
+const (
+	EventSent       = "sent"
+	EventDelivered  = "delivered"
+	EventBounced    = "bounced"
+	EventComplained = "complained"
+)
+
+// DefaultWindow is how far back the counters go.
+const DefaultWindow = 24 * time.Hour
+
+// bucketSize is the granularity of the rolling counters.
+const bucketSize = time.Hour
+
+// maxDomains bounds how many domains the aggregator holds counters for
+// between two drains.
+const maxDomains = 10000
+
+// Event is a delivery event of a message sent from a domain.
+type Event struct {
+	Type   string    `json:"type"`
+	Domain string    `json:"domain"`
+	Time   time.Time `json:"timestamp"`
+}
+
+// Stats are the counters of a domain over the window.
+type Stats struct {
+	Sent       int64
+	Delivered  int64
+	Bounced    int64
+	Complained int64
+}
+
+// BounceRate returns the share of the sent messages that bounced, 0 when
+// none was sent.
+func (s Stats) BounceRate() float64 {
+	if s.Sent == 0 {
+		return 0
+	}
+	return float64(s.Bounced) / float64(s.Sent)
+}
+
+// ComplaintRate returns the share of the sent messages reported as spam,
+// 0 when none was sent.
+func (s Stats) ComplaintRate() float64 {
+	if s.Sent == 0 {
+		return 0
+	}
+	return float64(s.Complained) / float64(s.Sent)
+}
+
+func (s *Stats) add(o Stats) {
+	s.Sent += o.Sent
+	s.Delivered += o.Delivered
+	s.Bounced += o.Bounced
+	s.Complained += o.Complained
+}
+
+// Bucket are the counters of the hour starting at Start.
+type Bucket struct {
+	Start time.Time
+	Stats
+}
+
+// Merge adds the counters of add to buckets, and returns them sorted by
+// start without the buckets out of the window.
+func Merge(buckets, add []Bucket, window time.Duration, now time.Time) []Bucket {
+	index := map[int64]int{}
+	merged := []Bucket{}
+	for _, b := range append(append([]Bucket{}, buckets...), add...) {
+		if now.Sub(b.Start) >= window+bucketSize {
+			continue
+		}
+		if i, ok := index[b.Start.Unix()]; ok {
+			merged[i].add(b.Stats)
+			continue
+		}
+		index[b.Start.Unix()] = len(merged)
+		merged = append(merged, b)
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Start.Before(merged[j].Start) })
+	return merged
+}
+
+// Sum returns the counters of the buckets within the window, to the hour.
+func Sum(buckets []Bucket, window time.Duration, now time.Time) Stats {
+	total := Stats{}
+	for _, b := range buckets {
+		if now.Sub(b.Start) < window+bucketSize {
+			total.add(b.Stats)
+		}
+	}
+	return total
+}
+
+// Aggregator counts the delivery events per domain and hour until they are
+// drained to where they are persisted. It is safe for concurrent use.
+type Aggregator struct {
+	window time.Duration
+	known  func(domain string) bool
+	now    func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string][]Bucket
+}
+
+// NewAggregator creates an Aggregator counting the events of the last
+// window, of the domains known reports. A zero window uses DefaultWindow,
+// a nil known accepts every domain.
+func NewAggregator(window time.Duration, known func(domain string) bool) *Aggregator {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	return &Aggregator{
+		window:  window,
+		known:   known,
+		now:     time.Now,
+		buckets: map[string][]Bucket{},
+	}
+}
+
+// Window returns how far back the counters go.
+func (a *Aggregator) Window() time.Duration {
+	return a.window
+}
+
+// Record counts an event and reports whether it was counted. Events of
+// unknown types or domains, events older than the window, and the events
+// of new domains once maxDomains are held, are ignored.
+func (a *Aggregator) Record(e Event) bool {
+	now := a.now()
+	if e.Time.IsZero() || e.Time.After(now) {
+		e.Time = now
+	}
+	if now.Sub(e.Time) >= a.window {
+		return false
+	}
+
+	domain := normalize(e.Domain)
+	if domain == "" || !knownType(e.Type) {
+		return false
+	}
+	if a.known != nil && !a.known(domain) {
+		return false
+	}
+
+	stats := Stats{}
+	switch e.Type {
+	case EventSent:
+		stats.Sent = 1
+	case EventDelivered:
+		stats.Delivered = 1
+	case EventBounced:
+		stats.Bounced = 1
+	case EventComplained:
+		stats.Complained = 1
+	}
+
+	return a.add(domain, []Bucket{{Start: e.Time.Truncate(bucketSize), Stats: stats}}, now)
+}
+
+// Restore gives back the buckets of a domain that could not be persisted,
+// to be drained again.
+func (a *Aggregator) Restore(domain string, buckets []Bucket) {
+	a.add(normalize(domain), buckets, a.now())
+}
+
+func (a *Aggregator) add(domain string, buckets []Bucket, now time.Time) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	existing, ok := a.buckets[domain]
+	if !ok && len(a.buckets) >= maxDomains {
+		return false
+	}
+	a.buckets[domain] = Merge(existing, buckets, a.window, now)
+	return true
+}
+
+// Drain returns the counters recorded since the last drain, by domain, and
+// resets them.
+func (a *Aggregator) Drain() map[string][]Bucket {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	drained := a.buckets
+	a.buckets = map[string][]Bucket{}
+	return drained
+}
+
+func knownType(t string) bool {
+	switch t {
+	case EventSent, EventDelivered, EventBounced, EventComplained:
+		return true
+	}
+	return false
+}
+
+func normalize(domain string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(domain), "."))
+}
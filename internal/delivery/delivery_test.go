@@ -0,0 +1,112 @@
+package delivery_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kannon-email/k8nnon/internal/delivery"
+)
+
+func TestAggregator(t *testing.T) {
+	agg := delivery.NewAggregator(24*time.Hour, func(domain string) bool { return domain != "example.org" })
+	now := time.Now()
+
+	for i := 0; i < 10; i++ {
+		assert.True(t, agg.Record(delivery.Event{Type: delivery.EventSent, Domain: "Example.com.", Time: now.Add(-2 * time.Hour)}))
+	}
+	agg.Record(delivery.Event{Type: delivery.EventBounced, Domain: "example.com", Time: now})
+	agg.Record(delivery.Event{Type: delivery.EventComplained, Domain: "example.com"})
+	assert.False(t, agg.Record(delivery.Event{Type: delivery.EventSent, Domain: "example.com", Time: now.Add(-25 * time.Hour)}), "events out of the window should be ignored")
+	assert.False(t, agg.Record(delivery.Event{Type: "opened", Domain: "example.com"}), "unknown events should be ignored")
+	assert.False(t, agg.Record(delivery.Event{Type: delivery.EventSent, Domain: "example.org"}), "unknown domains should be ignored")
+
+	drained := agg.Drain()
+	require.Len(t, drained, 1)
+	buckets := drained["example.com"]
+	assert.Len(t, buckets, 2, "the counters should be kept per hour")
+	stats := delivery.Sum(buckets, agg.Window(), now)
+	assert.Equal(t, delivery.Stats{Sent: 10, Bounced: 1, Complained: 1}, stats)
+	assert.InDelta(t, 0.1, stats.BounceRate(), 1e-9)
+
+	assert.Empty(t, agg.Drain(), "the drained counters should be reset")
+
+	agg.Restore("example.com", buckets)
+	assert.Equal(t, stats, delivery.Sum(agg.Drain()["example.com"], agg.Window(), now), "the restored counters should be drained again")
+}
+
+func TestAggregatorBound(t *testing.T) {
+	agg := delivery.NewAggregator(0, nil)
+
+	for i := 0; i < 10000; i++ {
+		require.True(t, agg.Record(delivery.Event{Type: delivery.EventSent, Domain: fmt.Sprintf("d%d.example.com", i)}))
+	}
+	assert.False(t, agg.Record(delivery.Event{Type: delivery.EventSent, Domain: "new.example.com"}), "new domains should be ignored once the aggregator is full")
+	assert.True(t, agg.Record(delivery.Event{Type: delivery.EventSent, Domain: "d1.example.com"}), "the held domains should still be counted")
+
+	assert.Len(t, agg.Drain(), 10000)
+	assert.True(t, agg.Record(delivery.Event{Type: delivery.EventSent, Domain: "new.example.com"}))
+}
+
+func TestMerge(t *testing.T) {
+	now := time.Date(2023, 5, 1, 12, 30, 0, 0, time.UTC)
+	hour := func(h int) time.Time { return now.Truncate(time.Hour).Add(time.Duration(-h) * time.Hour) }
+
+	persisted := []delivery.Bucket{
+		{Start: hour(30), Stats: delivery.Stats{Sent: 100}},
+		{Start: hour(2), Stats: delivery.Stats{Sent: 10}},
+		{Start: hour(0), Stats: delivery.Stats{Sent: 1}},
+	}
+	added := []delivery.Bucket{
+		{Start: hour(0), Stats: delivery.Stats{Sent: 2, Bounced: 1}},
+		{Start: hour(1), Stats: delivery.Stats{Delivered: 3}},
+	}
+
+	merged := delivery.Merge(persisted, added, 24*time.Hour, now)
+	assert.Equal(t, []delivery.Bucket{
+		{Start: hour(2), Stats: delivery.Stats{Sent: 10}},
+		{Start: hour(1), Stats: delivery.Stats{Delivered: 3}},
+		{Start: hour(0), Stats: delivery.Stats{Sent: 3, Bounced: 1}},
+	}, merged, "the buckets out of the window should be dropped")
+	assert.Equal(t, delivery.Stats{Sent: 11}, delivery.Sum(persisted, 24*time.Hour, now), "the buckets out of the window should not be summed")
+}
+
+func TestHandler(t *testing.T) {
+	agg := delivery.NewAggregator(0, nil)
+	h := delivery.Handler(agg, "secret")
+
+	body := `[{"type":"sent","domain":"example.com"},{"type":"bounced","domain":"example.com"}]`
+	cases := []struct {
+		name      string
+		path      string
+		body      string
+		signature string
+		status    int
+	}{
+		{"signed", delivery.Path, body, delivery.Sign([]byte(body), "secret"), http.StatusNoContent},
+		{"single event", delivery.Path, `{"type":"sent","domain":"example.com"}`, delivery.Sign([]byte(`{"type":"sent","domain":"example.com"}`), "secret"), http.StatusNoContent},
+		{"bad signature", delivery.Path, body, delivery.Sign([]byte(body), "other"), http.StatusUnauthorized},
+		{"unsigned", delivery.Path, body, "", http.StatusUnauthorized},
+		{"invalid body", delivery.Path, "nope", delivery.Sign([]byte("nope"), "secret"), http.StatusBadRequest},
+		{"other path", "/", body, delivery.Sign([]byte(body), "secret"), http.StatusNotFound},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodPost, "http://operator"+c.path, strings.NewReader(c.body))
+		if c.signature != "" {
+			req.Header.Set(delivery.SignatureHeader, c.signature)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		assert.Equal(t, c.status, rec.Code, c.name)
+	}
+
+	stats := delivery.Sum(agg.Drain()["example.com"], agg.Window(), time.Now())
+	assert.Equal(t, delivery.Stats{Sent: 2, Bounced: 1}, stats, "only the accepted webhooks should be counted")
+}
@@ -0,0 +1,149 @@
+package delivery
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Path is where the webhooks are received.
+const Path = "/webhooks/delivery"
+
+// SignatureHeader holds the HMAC-SHA256 of the body with the shared
+// secret, as sha256=<hex>.
+const SignatureHeader = "X-Kannon-Signature"
+
+// maxBodySize bounds the size of a webhook body.
+const maxBodySize = 1 << 20
+
+// Handler receives the delivery webhooks at Path and records their events
+// in the aggregator. The body is one event or an array of events. With a
+// secret, the body must be signed with it.
+func Handler(agg *Aggregator, secret string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != Path {
+			http.NotFound(w, req)
+			return
+		}
+		if req.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(req.Body, maxBodySize+1))
+		if err != nil {
+			http.Error(w, "failed to read the body", http.StatusBadRequest)
+			return
+		}
+		if len(body) > maxBodySize {
+			http.Error(w, "body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		if secret != "" && !validSignature(body, req.Header.Get(SignatureHeader), secret) {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+
+		events, err := parseEvents(body)
+		if err != nil {
+			http.Error(w, "invalid events: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, e := range events {
+			agg.Record(e)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func validSignature(body []byte, signature, secret string) bool {
+	return hmac.Equal([]byte(signature), []byte(Sign(body, secret)))
+}
+
+// Sign returns the signature of body with secret, for SignatureHeader.
+func Sign(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func parseEvents(body []byte) ([]Event, error) {
+	trimmed := strings.TrimSpace(string(body))
+	if strings.HasPrefix(trimmed, "[") {
+		events := []Event{}
+		err := json.Unmarshal(body, &events)
+		return events, err
+	}
+
+	e := Event{}
+	if err := json.Unmarshal(body, &e); err != nil {
+		return nil, err
+	}
+	return []Event{e}, nil
+}
+
+// Server receives the webhooks, over TLS when a certificate is set. It
+// runs on every replica, as the Service routes the webhooks to any of
+// them.
+type Server struct {
+	srv      *http.Server
+	certFile string
+	keyFile  string
+}
+
+// NewServer creates a Server listening on addr. Empty certFile and keyFile
+// serve plain HTTP.
+func NewServer(addr string, handler http.Handler, certFile, keyFile string) *Server {
+	return &Server{
+		srv: &http.Server{
+			Addr:              addr,
+			Handler:           handler,
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+}
+
+// Start receives the webhooks until ctx is done.
+func (s *Server) Start(ctx context.Context) error {
+	errCh := make(chan error, 1)
+	go func() {
+		if s.certFile != "" {
+			errCh <- s.srv.ListenAndServeTLS(s.certFile, s.keyFile)
+			return
+		}
+		errCh <- s.srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// NeedLeaderElection reports that the server runs on every replica.
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
@@ -0,0 +1,194 @@
+package dkim
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// KeyType is the algorithm of a DKIM key, as published in the k tag.
+type KeyType string
+
+const (
+	KeyTypeRSA     KeyType = "rsa"
+	KeyTypeEd25519 KeyType = "ed25519"
+)
+
+// RSAKeyBits is the size of the generated RSA keys.
+const RSAKeyBits = 2048
+
+// MinRSAKeyBits is the smallest RSA key RFC 8301 lets verifiers accept.
+const MinRSAKeyBits = 1024
+
+// Keys of the Secret data holding a key pair.
+const (
+	PrivateKeyKey = "private.key"
+	PublicKeyKey  = "public.key"
+	KeyTypeKey    = "key.type"
+)
+
+// KeyPair is a DKIM signing key with its public key encoded for DNS.
+type KeyPair struct {
+	Type KeyType
+
+	// PrivateKeyPEM is the PKCS #8 encoded private key.
+	PrivateKeyPEM []byte
+
+	// PublicKey is the value of the p tag of the DKIM record.
+	PublicKey string
+}
+
+// Generate creates a new key pair of type t.
+func Generate(t KeyType) (*KeyPair, error) {
+	return GenerateBits(t, RSAKeyBits)
+}
+
+// GenerateBits creates a new key pair of type t, with rsaBits bits when it
+// is an RSA key.
+func GenerateBits(t KeyType, rsaBits int) (*KeyPair, error) {
+	var private crypto.PrivateKey
+	var public []byte
+
+	switch t {
+	case KeyTypeRSA:
+		key, err := rsa.GenerateKey(rand.Reader, rsaBits)
+		if err != nil {
+			return nil, err
+		}
+
+		public, err = x509.MarshalPKIXPublicKey(&key.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		private = key
+	case KeyTypeEd25519:
+		// RFC 8463 publishes the raw public key rather than a SPKI structure
+		pub, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+
+		public = pub
+		private = key
+	default:
+		return nil, fmt.Errorf("unsupported DKIM key type %q", t)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KeyPair{
+		Type:          t,
+		PrivateKeyPEM: pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}),
+		PublicKey:     base64.StdEncoding.EncodeToString(public),
+	}, nil
+}
+
+// Record returns the content of the DKIM TXT record publishing publicKey.
+func Record(t KeyType, publicKey string) string {
+	return fmt.Sprintf("k=%s; p=%s", t, publicKey)
+}
+
+// PublicKeyOf returns the type of the PEM encoded private key and its
+// public key encoded for DNS, as Generate does.
+func PublicKeyOf(privateKeyPEM []byte) (KeyType, string, error) {
+	key, err := parsePrivateKey(privateKeyPEM)
+	if err != nil {
+		return "", "", err
+	}
+
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		public, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+		if err != nil {
+			return "", "", err
+		}
+		return KeyTypeRSA, base64.StdEncoding.EncodeToString(public), nil
+	case ed25519.PrivateKey:
+		return KeyTypeEd25519, base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey)), nil
+	default:
+		return "", "", fmt.Errorf("unsupported private key %T", key)
+	}
+}
+
+func parsePrivateKey(privateKeyPEM []byte) (crypto.PrivateKey, error) {
+	block, _ := pem.Decode(privateKeyPEM)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		// keys imported from other signers are often PKCS #1
+		rsaKey, rsaErr := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if rsaErr != nil {
+			return nil, err
+		}
+		key = rsaKey
+	}
+	return key, nil
+}
+
+// ParseRecord returns the type and the public key of a DKIM key record, the
+// type defaulting to rsa. The public key is empty for a revoked key, and ok
+// false when txt is not a DKIM key record.
+func ParseRecord(txt string) (t KeyType, publicKey string, ok bool) {
+	t = KeyTypeRSA
+	for _, tag := range strings.Split(txt, ";") {
+		name, value, found := strings.Cut(tag, "=")
+		if !found {
+			continue
+		}
+
+		// the value may be split by whitespace across TXT strings
+		value = strings.Join(strings.Fields(value), "")
+		switch strings.TrimSpace(name) {
+		case "v":
+			if value != "DKIM1" {
+				return "", "", false
+			}
+		case "k":
+			t = KeyType(value)
+		case "p":
+			publicKey, ok = value, true
+		}
+	}
+	return t, publicKey, ok
+}
+
+// KeyBits returns the size of a public key of type t encoded for DNS.
+func KeyBits(t KeyType, publicKey string) (int, error) {
+	der, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil {
+		return 0, err
+	}
+
+	switch t {
+	case KeyTypeRSA:
+		key, err := x509.ParsePKIXPublicKey(der)
+		if err != nil {
+			return 0, err
+		}
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return 0, fmt.Errorf("not an RSA public key: %T", key)
+		}
+		return rsaKey.N.BitLen(), nil
+	case KeyTypeEd25519:
+		if len(der) != ed25519.PublicKeySize {
+			return 0, fmt.Errorf("an ed25519 public key has %d bytes, not %d", ed25519.PublicKeySize, len(der))
+		}
+		return ed25519.PublicKeySize * 8, nil
+	default:
+		return 0, fmt.Errorf("unsupported DKIM key type %q", t)
+	}
+}
@@ -0,0 +1,127 @@
+package dkim_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kannon-email/k8nnon/internal/dkim"
+)
+
+func TestGenerateRSA(t *testing.T) {
+	kp, err := dkim.Generate(dkim.KeyTypeRSA)
+	require.NoError(t, err)
+
+	private := parsePrivateKey(t, kp)
+	key, ok := private.(*rsa.PrivateKey)
+	require.True(t, ok, "should be an RSA key")
+	assert.Equal(t, dkim.RSAKeyBits, key.N.BitLen())
+
+	der, err := base64.StdEncoding.DecodeString(kp.PublicKey)
+	require.NoError(t, err)
+	public, err := x509.ParsePKIXPublicKey(der)
+	require.NoError(t, err)
+	assert.True(t, key.PublicKey.Equal(public), "public key should match the private key")
+}
+
+func TestGenerateEd25519(t *testing.T) {
+	kp, err := dkim.Generate(dkim.KeyTypeEd25519)
+	require.NoError(t, err)
+
+	private := parsePrivateKey(t, kp)
+	key, ok := private.(ed25519.PrivateKey)
+	require.True(t, ok, "should be an Ed25519 key")
+
+	public, err := base64.StdEncoding.DecodeString(kp.PublicKey)
+	require.NoError(t, err)
+	assert.Equal(t, []byte(key.Public().(ed25519.PublicKey)), public)
+}
+
+func TestGenerateUnsupported(t *testing.T) {
+	_, err := dkim.Generate("dsa")
+	assert.Error(t, err)
+}
+
+func TestRecord(t *testing.T) {
+	assert.Equal(t, "k=ed25519; p=key", dkim.Record(dkim.KeyTypeEd25519, "key"))
+}
+
+func TestPublicKeyOf(t *testing.T) {
+	for _, keyType := range []dkim.KeyType{dkim.KeyTypeRSA, dkim.KeyTypeEd25519} {
+		kp, err := dkim.Generate(keyType)
+		require.NoError(t, err)
+
+		gotType, public, err := dkim.PublicKeyOf(kp.PrivateKeyPEM)
+		require.NoError(t, err)
+		assert.Equal(t, keyType, gotType)
+		assert.Equal(t, kp.PublicKey, public, "should derive the published key of %s", keyType)
+	}
+
+	kp, err := dkim.Generate(dkim.KeyTypeRSA)
+	require.NoError(t, err)
+	key := parsePrivateKey(t, kp).(*rsa.PrivateKey)
+	pkcs1 := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	_, public, err := dkim.PublicKeyOf(pkcs1)
+	require.NoError(t, err)
+	assert.Equal(t, kp.PublicKey, public, "should read PKCS #1 keys")
+
+	_, _, err = dkim.PublicKeyOf([]byte("not a key"))
+	assert.Error(t, err)
+}
+
+func TestParseRecord(t *testing.T) {
+	tests := []struct {
+		txt       string
+		keyType   dkim.KeyType
+		publicKey string
+		ok        bool
+	}{
+		{txt: "k=ed25519; p=key", keyType: dkim.KeyTypeEd25519, publicKey: "key", ok: true},
+		{txt: "v=DKIM1; p=ke y", keyType: dkim.KeyTypeRSA, publicKey: "key", ok: true},
+		{txt: "v=DKIM1; k=rsa; p=", keyType: dkim.KeyTypeRSA, ok: true},
+		{txt: "v=DKIM2; p=key"},
+		{txt: "v=spf1 -all"},
+	}
+
+	for _, tt := range tests {
+		keyType, publicKey, ok := dkim.ParseRecord(tt.txt)
+		assert.Equal(t, tt.ok, ok, tt.txt)
+		if ok {
+			assert.Equal(t, tt.keyType, keyType, tt.txt)
+			assert.Equal(t, tt.publicKey, publicKey, tt.txt)
+		}
+	}
+}
+
+func TestKeyBits(t *testing.T) {
+	for keyType, bits := range map[dkim.KeyType]int{dkim.KeyTypeRSA: dkim.RSAKeyBits, dkim.KeyTypeEd25519: 256} {
+		kp, err := dkim.Generate(keyType)
+		require.NoError(t, err)
+
+		got, err := dkim.KeyBits(keyType, kp.PublicKey)
+		require.NoError(t, err)
+		assert.Equal(t, bits, got)
+	}
+
+	_, err := dkim.KeyBits(dkim.KeyTypeEd25519, base64.StdEncoding.EncodeToString([]byte("short")))
+	assert.Error(t, err)
+}
+
+func parsePrivateKey(t *testing.T, kp *dkim.KeyPair) interface{} {
+	t.Helper()
+
+	block, _ := pem.Decode(kp.PrivateKeyPEM)
+	require.NotNil(t, block)
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	require.NoError(t, err)
+
+	return key
+}
@@ -0,0 +1,397 @@
+package dkim
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// SignatureHeader is the header field holding a DKIM signature.
+const SignatureHeader = "DKIM-Signature"
+
+// TXTLookup returns the TXT records of name.
+type TXTLookup func(ctx context.Context, name string) ([]string, error)
+
+// Signature is the verification of a DKIM signature of a message.
+type Signature struct {
+	// Domain is the signing domain, the d tag.
+	Domain string
+
+	// Selector is the selector of the key, the s tag.
+	Selector string
+
+	// Err is why the signature does not verify, nil when it does.
+	Err error
+}
+
+// Valid reports whether the signature verifies.
+func (s Signature) Valid() bool {
+	return s.Err == nil
+}
+
+// Verify verifies the DKIM signatures of message with the keys looked up
+// under the signing domains: the body hash, the signature of the header
+// fields and that the From field is signed, as the DomainTests report. The
+// rsa-sha256 and ed25519-sha256 algorithms are supported, the optional
+// i, l and x tags are not checked. A message without signatures returns
+// none, and an error is returned only when the message can not be parsed.
+func Verify(ctx context.Context, lookup TXTLookup, message []byte) ([]Signature, error) {
+	fields, body, err := splitMessage(message)
+	if err != nil {
+		return nil, err
+	}
+
+	var sigs []Signature
+	for _, field := range fields {
+		if strings.EqualFold(field.name, SignatureHeader) {
+			sigs = append(sigs, verifySignature(ctx, lookup, fields, body, field))
+		}
+	}
+	return sigs, nil
+}
+
+// Sign signs message for domain with the PEM encoded private key published
+// under selector, prepending the signature to the message. The header
+// fields and the body are canonicalized with the relaxed algorithm. The
+// signature covers the From field and the fields of headers the message
+// has.
+func Sign(message []byte, domain, selector string, privateKeyPEM []byte, headers ...string) ([]byte, error) {
+	key, err := parsePrivateKey(privateKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	message = normalizeLineEndings(message)
+	fields, body, err := splitMessage(message)
+	if err != nil {
+		return nil, err
+	}
+
+	signed := []string{"from"}
+	for _, name := range headers {
+		name = strings.ToLower(name)
+		if !contains(signed, name) && hasField(fields, name) {
+			signed = append(signed, name)
+		}
+	}
+
+	algorithm := "rsa-sha256"
+	if _, ok := key.(ed25519.PrivateKey); ok {
+		algorithm = "ed25519-sha256"
+	}
+	bodyHash := sha256.Sum256(canonicalBody(body, true))
+	value := fmt.Sprintf(" v=1; a=%s; c=relaxed/relaxed; d=%s; s=%s; h=%s; bh=%s; b=",
+		algorithm, domain, selector, strings.Join(signed, ":"), base64.StdEncoding.EncodeToString(bodyHash[:]))
+
+	digest := headerHash(fields, signed, SignatureHeader+":"+value, true)
+	var sig []byte
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		sig, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest)
+	case ed25519.PrivateKey:
+		sig = ed25519.Sign(key, digest)
+	default:
+		err = fmt.Errorf("unsupported private key %T", key)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	header := SignatureHeader + ":" + value + base64.StdEncoding.EncodeToString(sig) + "\r\n"
+	return append([]byte(header), message...), nil
+}
+
+type headerField struct {
+	// name is the field name as written.
+	name string
+	// raw is the field as written, continuation lines included, without
+	// the final CRLF.
+	raw string
+}
+
+// splitMessage returns the header fields and the body of message. Bare LF
+// line endings are read as CRLF.
+func splitMessage(message []byte) ([]headerField, []byte, error) {
+	message = normalizeLineEndings(message)
+
+	head, body := message, []byte{}
+	if bytes.HasPrefix(message, []byte("\r\n")) {
+		head, body = nil, message[2:]
+	} else if i := bytes.Index(message, []byte("\r\n\r\n")); i >= 0 {
+		head, body = message[:i], message[i+4:]
+	}
+
+	var fields []headerField
+	for _, line := range strings.Split(string(head), "\r\n") {
+		if line == "" {
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			if len(fields) == 0 {
+				return nil, nil, errors.New("the message starts with a continuation line")
+			}
+			fields[len(fields)-1].raw += "\r\n" + line
+			continue
+		}
+
+		name, _, found := strings.Cut(line, ":")
+		if !found || strings.TrimSpace(name) == "" {
+			return nil, nil, fmt.Errorf("malformed header field %q", line)
+		}
+		fields = append(fields, headerField{name: strings.TrimRight(name, " \t"), raw: line})
+	}
+	return fields, body, nil
+}
+
+func normalizeLineEndings(message []byte) []byte {
+	if !bytes.Contains(message, []byte("\n")) {
+		return message
+	}
+	message = bytes.ReplaceAll(message, []byte("\r\n"), []byte("\n"))
+	return bytes.ReplaceAll(message, []byte("\n"), []byte("\r\n"))
+}
+
+func verifySignature(ctx context.Context, lookup TXTLookup, fields []headerField, body []byte, field headerField) Signature {
+	_, value, _ := strings.Cut(field.raw, ":")
+	tags, err := parseTags(value)
+	if err != nil {
+		return Signature{Err: err}
+	}
+	sig := Signature{Domain: strings.ToLower(tags["d"]), Selector: tags["s"]}
+
+	for _, name := range []string{"v", "a", "b", "bh", "d", "h", "s"} {
+		if tags[name] == "" {
+			sig.Err = fmt.Errorf("the signature has no %s tag", name)
+			return sig
+		}
+	}
+	if tags["v"] != "1" {
+		sig.Err = fmt.Errorf("unsupported signature version %q", tags["v"])
+		return sig
+	}
+
+	var keyType KeyType
+	switch tags["a"] {
+	case "rsa-sha256":
+		keyType = KeyTypeRSA
+	case "ed25519-sha256":
+		keyType = KeyTypeEd25519
+	default:
+		sig.Err = fmt.Errorf("unsupported signature algorithm %q", tags["a"])
+		return sig
+	}
+
+	headerCanon, bodyCanon, _ := strings.Cut(tags["c"], "/")
+	relaxedHeader, relaxedBody := headerCanon == "relaxed", bodyCanon == "relaxed"
+	if (headerCanon != "" && headerCanon != "simple" && !relaxedHeader) || (bodyCanon != "" && bodyCanon != "simple" && !relaxedBody) {
+		sig.Err = fmt.Errorf("unsupported canonicalization %q", tags["c"])
+		return sig
+	}
+
+	signed := strings.Split(strings.ToLower(tags["h"]), ":")
+	if !contains(signed, "from") {
+		sig.Err = errors.New("the signature does not cover the From field")
+		return sig
+	}
+
+	bodyHash := sha256.Sum256(canonicalBody(body, relaxedBody))
+	if base64.StdEncoding.EncodeToString(bodyHash[:]) != tags["bh"] {
+		sig.Err = errors.New("the body hash does not match, the body was altered")
+		return sig
+	}
+
+	public, err := lookupKey(ctx, lookup, sig.Selector, sig.Domain, keyType)
+	if err != nil {
+		sig.Err = err
+		return sig
+	}
+	signature, err := base64.StdEncoding.DecodeString(tags["b"])
+	if err != nil {
+		sig.Err = fmt.Errorf("invalid signature encoding: %v", err)
+		return sig
+	}
+
+	digest := headerHash(fields, signed, withoutSignatureValue(field.raw), relaxedHeader)
+	switch public := public.(type) {
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(public, crypto.SHA256, digest, signature); err != nil {
+			sig.Err = errors.New("the signature does not verify with the published key")
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(public, digest, signature) {
+			sig.Err = errors.New("the signature does not verify with the published key")
+		}
+	}
+	return sig
+}
+
+// parseTags returns the tags of a signature. The whitespace of the values
+// is removed, it is only folding in the tags verified.
+func parseTags(value string) (map[string]string, error) {
+	tags := map[string]string{}
+	for _, tag := range strings.Split(value, ";") {
+		if strings.TrimSpace(tag) == "" {
+			continue
+		}
+		name, v, found := strings.Cut(tag, "=")
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			return nil, fmt.Errorf("malformed signature tag %q", strings.TrimSpace(tag))
+		}
+		tags[name] = strings.Join(strings.Fields(v), "")
+	}
+	return tags, nil
+}
+
+// withoutSignatureValue empties the b tag of a signature field, as it is
+// when the signature is computed.
+func withoutSignatureValue(raw string) string {
+	name, value, _ := strings.Cut(raw, ":")
+	tags := strings.Split(value, ";")
+	for i, tag := range tags {
+		tagName, _, found := strings.Cut(tag, "=")
+		if found && strings.TrimSpace(tagName) == "b" {
+			tags[i] = tag[:strings.Index(tag, "=")+1]
+		}
+	}
+	return name + ":" + strings.Join(tags, ";")
+}
+
+// lookupKey returns the public key of type t published under selector in
+// domain.
+func lookupKey(ctx context.Context, lookup TXTLookup, selector, domain string, t KeyType) (crypto.PublicKey, error) {
+	name := fmt.Sprintf("%s._domainkey.%s", selector, domain)
+	txts, err := lookup(ctx, name)
+	if err != nil {
+		if dnsErr, ok := err.(*net.DNSError); ok && dnsErr.IsNotFound {
+			return nil, fmt.Errorf("no DKIM key is published at %s", name)
+		}
+		return nil, fmt.Errorf("failed to look up the DKIM key at %s: %v", name, err)
+	}
+
+	for _, txt := range txts {
+		recordType, publicKey, ok := ParseRecord(txt)
+		if !ok {
+			continue
+		}
+		if publicKey == "" {
+			return nil, fmt.Errorf("the DKIM key at %s is revoked", name)
+		}
+		if recordType != t {
+			return nil, fmt.Errorf("the DKIM key at %s is a %s key, the signature needs %s", name, recordType, t)
+		}
+
+		der, err := base64.StdEncoding.DecodeString(publicKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DKIM key at %s: %v", name, err)
+		}
+		if t == KeyTypeEd25519 {
+			if len(der) != ed25519.PublicKeySize {
+				return nil, fmt.Errorf("invalid ed25519 DKIM key at %s", name)
+			}
+			return ed25519.PublicKey(der), nil
+		}
+
+		key, err := x509.ParsePKIXPublicKey(der)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DKIM key at %s: %v", name, err)
+		}
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("the DKIM key at %s is not an RSA key", name)
+		}
+		return rsaKey, nil
+	}
+	return nil, fmt.Errorf("no DKIM key is published at %s", name)
+}
+
+// headerHash hashes the signed header fields followed by the signature
+// field, selecting the instances of repeated fields from the bottom up.
+func headerHash(fields []headerField, signed []string, signature string, relaxed bool) []byte {
+	h := sha256.New()
+	used := map[string]int{}
+	for _, name := range signed {
+		name = strings.TrimSpace(name)
+		skip := used[name]
+		used[name]++
+		for i := len(fields) - 1; i >= 0; i-- {
+			if !strings.EqualFold(fields[i].name, name) {
+				continue
+			}
+			if skip > 0 {
+				skip--
+				continue
+			}
+			h.Write([]byte(canonicalHeader(fields[i].raw, relaxed) + "\r\n"))
+			break
+		}
+	}
+	h.Write([]byte(canonicalHeader(signature, relaxed)))
+	return h.Sum(nil)
+}
+
+func canonicalHeader(raw string, relaxed bool) string {
+	if !relaxed {
+		return raw
+	}
+	name, value, _ := strings.Cut(raw, ":")
+	value = strings.ReplaceAll(value, "\r\n", "")
+	return strings.ToLower(strings.TrimRight(name, " \t")) + ":" + strings.Join(strings.Fields(value), " ")
+}
+
+func canonicalBody(body []byte, relaxed bool) []byte {
+	lines := strings.Split(string(body), "\r\n")
+	if relaxed {
+		for i, line := range lines {
+			if strings.TrimRight(line, " \t") == "" {
+				lines[i] = ""
+				continue
+			}
+			// keep a single space where the line starts with whitespace
+			lead := ""
+			if line[0] == ' ' || line[0] == '\t' {
+				lead = " "
+			}
+			lines[i] = lead + strings.Join(strings.Fields(line), " ")
+		}
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	if len(lines) == 0 {
+		if relaxed {
+			return []byte{}
+		}
+		return []byte("\r\n")
+	}
+	return []byte(strings.Join(lines, "\r\n") + "\r\n")
+}
+
+func hasField(fields []headerField, name string) bool {
+	for _, field := range fields {
+		if strings.EqualFold(field.name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if strings.TrimSpace(v) == value {
+			return true
+		}
+	}
+	return false
+}
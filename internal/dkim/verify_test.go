@@ -0,0 +1,187 @@
+package dkim_test
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kannon-email/k8nnon/internal/dkim"
+)
+
+const testMessage = "From: Joe SixPack <joe@example.com>\r\n" +
+	"To: Suzie Q <suzie@example.net>\r\n" +
+	"Subject: Is dinner ready?\r\n" +
+	"\r\n" +
+	"Hi.\r\n" +
+	"\r\n" +
+	"We lost the game.  Are you hungry yet?\r\n"
+
+// lookupRecords serves the TXT records of records, and not found for the
+// other names.
+func lookupRecords(records map[string]string) dkim.TXTLookup {
+	return func(ctx context.Context, name string) ([]string, error) {
+		txt, ok := records[name]
+		if !ok {
+			return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+		}
+		return []string{txt}, nil
+	}
+}
+
+func signedMessage(t *testing.T, keyType dkim.KeyType) ([]byte, dkim.TXTLookup) {
+	kp, err := dkim.Generate(keyType)
+	require.NoError(t, err)
+
+	signed, err := dkim.Sign([]byte(testMessage), "example.com", "k8nnon", kp.PrivateKeyPEM, "To", "Subject", "Date")
+	require.NoError(t, err)
+
+	return signed, lookupRecords(map[string]string{
+		"k8nnon._domainkey.example.com": dkim.Record(kp.Type, kp.PublicKey),
+	})
+}
+
+func TestVerifyRFC8463Example(t *testing.T) {
+	message := strings.ReplaceAll(`DKIM-Signature: v=1; a=ed25519-sha256; c=relaxed/relaxed;
+ d=football.example.com; i=@football.example.com;
+ q=dns/txt; s=brisbane; t=1528637909; h=from : to :
+ subject : date : message-id : from : subject : date;
+ bh=2jUSOH9NhtVGCQWNr9BrIAPreKQjO6Sn7XIkfJVOzv8=;
+ b=/gCrinpcQOoIfuHNQIbq4pgh9kyIK3AQUdt9OdqQehSwhEIug4D11Bus
+ Fa3bT3FY5OsU7ZbnKELq+eXdp1Q1Dw==
+From: Joe SixPack <joe@football.example.com>
+To: Suzie Q <suzie@shopping.example.net>
+Subject: Is dinner ready?
+Date: Fri, 11 Jul 2003 21:00:37 -0700 (PDT)
+Message-ID: <20030712040037.46341.5F8J@football.example.com>
+
+Hi.
+
+We lost the game.  Are you hungry yet?
+
+Joe.
+`, "\n", "\r\n")
+	lookup := lookupRecords(map[string]string{
+		"brisbane._domainkey.football.example.com": "v=DKIM1; k=ed25519; p=11qYAYKxCrfVS/7TyWQHOg7hcvPapiMlrwIaaPcHURo=",
+	})
+
+	sigs, err := dkim.Verify(context.Background(), lookup, []byte(message))
+	require.NoError(t, err)
+	require.Len(t, sigs, 1)
+	assert.NoError(t, sigs[0].Err)
+	assert.Equal(t, "football.example.com", sigs[0].Domain)
+	assert.Equal(t, "brisbane", sigs[0].Selector)
+}
+
+func TestVerifySigned(t *testing.T) {
+	for _, keyType := range []dkim.KeyType{dkim.KeyTypeRSA, dkim.KeyTypeEd25519} {
+		t.Run(string(keyType), func(t *testing.T) {
+			signed, lookup := signedMessage(t, keyType)
+
+			sigs, err := dkim.Verify(context.Background(), lookup, signed)
+			require.NoError(t, err)
+			require.Len(t, sigs, 1)
+			assert.True(t, sigs[0].Valid(), "should verify: %v", sigs[0].Err)
+			assert.Equal(t, "example.com", sigs[0].Domain)
+			assert.Equal(t, "k8nnon", sigs[0].Selector)
+		})
+	}
+}
+
+func TestVerifyRelaxedChanges(t *testing.T) {
+	signed, lookup := signedMessage(t, dkim.KeyTypeEd25519)
+
+	// whitespace changes, bare LF line endings and new fields do not break
+	// a relaxed signature
+	changed := strings.NewReplacer(
+		"Subject: Is dinner ready?", "subject:  Is dinner\r\n\tready?  ",
+		"the game.  Are", "the game. Are",
+	).Replace(string(signed))
+	changed = "Received: from mx.example.net\r\n" + strings.ReplaceAll(changed, "\r\n", "\n") + "\n\n"
+
+	sigs, err := dkim.Verify(context.Background(), lookup, []byte(changed))
+	require.NoError(t, err)
+	require.Len(t, sigs, 1)
+	assert.NoError(t, sigs[0].Err)
+}
+
+func TestVerifyFailures(t *testing.T) {
+	signed, lookup := signedMessage(t, dkim.KeyTypeRSA)
+
+	tests := []struct {
+		name    string
+		message string
+		lookup  dkim.TXTLookup
+		err     string
+	}{
+		{
+			name:    "altered body",
+			message: strings.Replace(string(signed), "hungry", "thirsty", 1),
+			lookup:  lookup,
+			err:     "the body hash does not match, the body was altered",
+		},
+		{
+			name:    "altered header",
+			message: strings.Replace(string(signed), "Is dinner ready?", "Is lunch ready?", 1),
+			lookup:  lookup,
+			err:     "the signature does not verify with the published key",
+		},
+		{
+			name:    "added signed header",
+			message: strings.Replace(string(signed), "ready?\r\n", "ready?\r\nSubject: Hello\r\n", 1),
+			lookup:  lookup,
+			err:     "the signature does not verify with the published key",
+		},
+		{
+			name:    "no key",
+			message: string(signed),
+			lookup:  lookupRecords(nil),
+			err:     "no DKIM key is published at k8nnon._domainkey.example.com",
+		},
+		{
+			name:    "revoked key",
+			message: string(signed),
+			lookup:  lookupRecords(map[string]string{"k8nnon._domainkey.example.com": "v=DKIM1; k=rsa; p="}),
+			err:     "the DKIM key at k8nnon._domainkey.example.com is revoked",
+		},
+		{
+			name:    "other key type",
+			message: string(signed),
+			lookup:  lookupRecords(map[string]string{"k8nnon._domainkey.example.com": "v=DKIM1; k=ed25519; p=11qYAYKxCrfVS/7TyWQHOg7hcvPapiMlrwIaaPcHURo="}),
+			err:     "the DKIM key at k8nnon._domainkey.example.com is a ed25519 key, the signature needs rsa",
+		},
+		{
+			name:    "unsupported algorithm",
+			message: strings.Replace(string(signed), "a=rsa-sha256", "a=rsa-sha1", 1),
+			lookup:  lookup,
+			err:     `unsupported signature algorithm "rsa-sha1"`,
+		},
+		{
+			name:    "From not signed",
+			message: strings.Replace(string(signed), "h=from:", "h=", 1),
+			lookup:  lookup,
+			err:     "the signature does not cover the From field",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sigs, err := dkim.Verify(context.Background(), tt.lookup, []byte(tt.message))
+			require.NoError(t, err)
+			require.Len(t, sigs, 1)
+			assert.False(t, sigs[0].Valid())
+			assert.EqualError(t, sigs[0].Err, tt.err)
+		})
+	}
+}
+
+func TestVerifyUnsigned(t *testing.T) {
+	sigs, err := dkim.Verify(context.Background(), lookupRecords(nil), []byte(testMessage))
+	require.NoError(t, err)
+	assert.Empty(t, sigs)
+
+	_, err = dkim.Verify(context.Background(), lookupRecords(nil), []byte("not a header\r\n\r\nbody"))
+	assert.Error(t, err)
+}
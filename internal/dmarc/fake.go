@@ -0,0 +1,54 @@
+package dmarc
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// FakeFetcher is an in-memory Fetcher for tests.
+type FakeFetcher struct {
+	mu       sync.Mutex
+	messages map[string][][]byte
+	err      error
+	fetches  int
+}
+
+func NewFakeFetcher() *FakeFetcher {
+	return &FakeFetcher{messages: map[string][][]byte{}}
+}
+
+// Deliver adds a message to the mailbox of address.
+func (f *FakeFetcher) Deliver(address string, message []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.messages[address] = append(f.messages[address], message)
+}
+
+// SetError makes the fetches fail with err, nil restores them.
+func (f *FakeFetcher) SetError(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.err = err
+}
+
+// Fetches returns how many times the mailboxes were polled.
+func (f *FakeFetcher) Fetches() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.fetches
+}
+
+func (f *FakeFetcher) Fetch(ctx context.Context, mailbox Mailbox, since time.Time) ([][]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.fetches++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.messages[mailbox.Address], nil
+}
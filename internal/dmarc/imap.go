@@ -0,0 +1,239 @@
+package dmarc
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultIMAPTimeout bounds a whole poll of a mailbox.
+	defaultIMAPTimeout = time.Minute
+	// defaultMaxMessages bounds the messages fetched by a poll, the latest
+	// ones are kept.
+	defaultMaxMessages = 500
+	// maxLiteralSize bounds a message fetched from the mailbox.
+	maxLiteralSize = 32 << 20
+)
+
+// Mailbox is an IMAP mailbox the reports are delivered to.
+type Mailbox struct {
+	// Address is the host and the port of the server, over TLS.
+	Address  string
+	Name     string
+	Username string
+	Password string
+}
+
+// Fetcher fetches the messages of a mailbox.
+type Fetcher interface {
+	// Fetch returns the messages of mailbox received since the day of
+	// since.
+	Fetch(ctx context.Context, mailbox Mailbox, since time.Time) ([][]byte, error)
+}
+
+// IMAPFetcher fetches the messages over IMAP4rev1, read-only: the
+// mailbox is examined and the messages are not flagged as seen.
+type IMAPFetcher struct {
+	// TLSConfig is the configuration of the connections, the server name
+	// defaults to the host of the address.
+	TLSConfig *tls.Config
+
+	// Timeout bounds a whole poll. Zero defaults to a minute.
+	Timeout time.Duration
+
+	// MaxMessages bounds the messages fetched by a poll. Zero defaults to
+	// 500.
+	MaxMessages int
+}
+
+func (f *IMAPFetcher) Fetch(ctx context.Context, mailbox Mailbox, since time.Time) ([][]byte, error) {
+	timeout := f.Timeout
+	if timeout <= 0 {
+		timeout = defaultIMAPTimeout
+	}
+	maxMessages := f.MaxMessages
+	if maxMessages <= 0 {
+		maxMessages = defaultMaxMessages
+	}
+
+	cfg := &tls.Config{}
+	if f.TLSConfig != nil {
+		cfg = f.TLSConfig.Clone()
+	}
+	if cfg.ServerName == "" {
+		host, _, err := net.SplitHostPort(mailbox.Address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IMAP address %q: %w", mailbox.Address, err)
+		}
+		cfg.ServerName = host
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	dialer := &tls.Dialer{Config: cfg}
+	conn, err := dialer.DialContext(ctx, "tcp", mailbox.Address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	c := &imapConn{r: bufio.NewReader(conn), w: conn}
+	if err := c.greeting(); err != nil {
+		return nil, err
+	}
+
+	if _, err := c.command("LOGIN %s %s", quote(mailbox.Username), quote(mailbox.Password)); err != nil {
+		return nil, fmt.Errorf("login: %w", err)
+	}
+	name := mailbox.Name
+	if name == "" {
+		name = "INBOX"
+	}
+	if _, err := c.command("EXAMINE %s", quote(name)); err != nil {
+		return nil, fmt.Errorf("examine %s: %w", name, err)
+	}
+
+	responses, err := c.command("UID SEARCH SINCE %s", since.UTC().Format("2-Jan-2006"))
+	if err != nil {
+		return nil, fmt.Errorf("search: %w", err)
+	}
+	var uids []string
+	for _, res := range responses {
+		if fields := strings.Fields(res.line); len(fields) > 2 && fields[1] == "SEARCH" {
+			uids = append(uids, fields[2:]...)
+		}
+	}
+	if len(uids) > maxMessages {
+		uids = uids[len(uids)-maxMessages:]
+	}
+
+	var messages [][]byte
+	if len(uids) > 0 {
+		responses, err = c.command("UID FETCH %s (BODY.PEEK[])", strings.Join(uids, ","))
+		if err != nil {
+			return nil, fmt.Errorf("fetch: %w", err)
+		}
+		for _, res := range responses {
+			if strings.Contains(res.line, " FETCH ") && len(res.literals) > 0 {
+				messages = append(messages, res.literals[0])
+			}
+		}
+	}
+
+	_, _ = c.command("LOGOUT")
+	return messages, nil
+}
+
+// imapConn is a client connection, sending the commands one at a time.
+type imapConn struct {
+	r   *bufio.Reader
+	w   io.Writer
+	tag int
+}
+
+// imapResponse is an untagged response, its literals replaced by
+// placeholders in the line.
+type imapResponse struct {
+	line     string
+	literals [][]byte
+}
+
+func (c *imapConn) greeting() error {
+	res, err := c.readResponse()
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(res.line, "* OK") && !strings.HasPrefix(res.line, "* PREAUTH") {
+		return fmt.Errorf("unexpected IMAP greeting %q", res.line)
+	}
+	return nil
+}
+
+// command sends a command and returns its untagged responses, or the
+// reason the server did not complete it.
+func (c *imapConn) command(format string, args ...interface{}) ([]imapResponse, error) {
+	c.tag++
+	tag := "a" + strconv.Itoa(c.tag)
+	if _, err := fmt.Fprintf(c.w, tag+" "+format+"\r\n", args...); err != nil {
+		return nil, err
+	}
+
+	var responses []imapResponse
+	for {
+		res, err := c.readResponse()
+		if err != nil {
+			return nil, err
+		}
+		status, ok := strings.CutPrefix(res.line, tag+" ")
+		if !ok {
+			responses = append(responses, res)
+			continue
+		}
+		if !strings.HasPrefix(status, "OK") {
+			return nil, errors.New(status)
+		}
+		return responses, nil
+	}
+}
+
+func (c *imapConn) readResponse() (imapResponse, error) {
+	res := imapResponse{}
+	for {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			return res, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		size, ok := literalSize(line)
+		if !ok {
+			res.line += line
+			return res, nil
+		}
+		if size > maxLiteralSize {
+			return res, fmt.Errorf("a message of %d bytes is too large", size)
+		}
+
+		literal := make([]byte, size)
+		if _, err := io.ReadFull(c.r, literal); err != nil {
+			return res, err
+		}
+		res.literals = append(res.literals, literal)
+		res.line += line[:strings.LastIndex(line, "{")] + "{}"
+	}
+}
+
+// literalSize returns the size of the literal ending the line, as {123}.
+func literalSize(line string) (int, bool) {
+	if !strings.HasSuffix(line, "}") {
+		return 0, false
+	}
+	open := strings.LastIndex(line, "{")
+	if open < 0 {
+		return 0, false
+	}
+	size, err := strconv.Atoi(strings.TrimSuffix(line[open+1:len(line)-1], "+"))
+	if err != nil || size < 0 {
+		return 0, false
+	}
+	return size, true
+}
+
+// quote returns s as an IMAP quoted string. The line breaks, which quoted
+// strings cannot hold, are dropped.
+func quote(s string) string {
+	s = strings.NewReplacer("\r", "", "\n", "").Replace(s)
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,125 @@
+package dmarc_test
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kannon-email/k8nnon/internal/dmarc"
+)
+
+// startIMAP serves a mailbox holding messages over TLS, with the
+// certificate of httptest. It returns the address and the client TLS
+// configuration, and records the commands received.
+func startIMAP(t *testing.T, messages map[int][]byte) (string, *tls.Config, *[]string) {
+	t.Helper()
+
+	certs := httptest.NewTLSServer(http.NotFoundHandler())
+	t.Cleanup(certs.Close)
+	clientCfg := certs.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+	clientCfg.ServerName = "example.com"
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: certs.TLS.Certificates})
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	mu := sync.Mutex{}
+	commands := []string{}
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		fmt.Fprint(conn, "* OK IMAP4rev1 ready\r\n")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			tag, cmd, _ := strings.Cut(strings.TrimRight(line, "\r\n"), " ")
+			mu.Lock()
+			commands = append(commands, cmd)
+			mu.Unlock()
+
+			switch {
+			case strings.HasPrefix(cmd, "LOGIN "):
+				if cmd != `LOGIN "dmarc@example.com" "p\"ss"` {
+					fmt.Fprintf(conn, "%s NO [AUTHENTICATIONFAILED] Invalid credentials\r\n", tag)
+					continue
+				}
+			case strings.HasPrefix(cmd, "EXAMINE "):
+				fmt.Fprintf(conn, "* %d EXISTS\r\n", len(messages))
+			case strings.HasPrefix(cmd, "UID SEARCH "):
+				uids := []string{}
+				for uid := range messages {
+					uids = append(uids, fmt.Sprint(uid))
+				}
+				fmt.Fprintf(conn, "* SEARCH %s\r\n", strings.Join(uids, " "))
+			case strings.HasPrefix(cmd, "UID FETCH "):
+				for uid, msg := range messages {
+					fmt.Fprintf(conn, "* %d FETCH (UID %d BODY[] {%d}\r\n%s)\r\n", uid, uid, len(msg), msg)
+				}
+			case cmd == "LOGOUT":
+				fmt.Fprint(conn, "* BYE\r\n")
+				fmt.Fprintf(conn, "%s OK LOGOUT completed\r\n", tag)
+				return
+			}
+			fmt.Fprintf(conn, "%s OK completed\r\n", tag)
+		}
+	}()
+
+	return ln.Addr().String(), clientCfg, &commands
+}
+
+func TestIMAPFetch(t *testing.T) {
+	email := reportEmail(t, reportXML("r1", time.Now()))
+	addr, cfg, commands := startIMAP(t, map[int][]byte{7: email})
+
+	f := &dmarc.IMAPFetcher{TLSConfig: cfg, Timeout: 5 * time.Second}
+	since := time.Date(2023, 10, 2, 12, 0, 0, 0, time.UTC)
+	messages, err := f.Fetch(context.Background(), dmarc.Mailbox{Address: addr, Username: "dmarc@example.com", Password: `p"ss`}, since)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, email, messages[0])
+
+	reports, err := dmarc.Parse(messages[0])
+	require.NoError(t, err)
+	assert.Equal(t, "r1", reports[0].ID)
+
+	// the mailbox is read-only, the messages stay unseen
+	assert.Contains(t, *commands, `EXAMINE "INBOX"`)
+	assert.Contains(t, *commands, "UID SEARCH SINCE 2-Oct-2023")
+	assert.Contains(t, *commands, "UID FETCH 7 (BODY.PEEK[])")
+}
+
+func TestIMAPLoginFailure(t *testing.T) {
+	addr, cfg, _ := startIMAP(t, nil)
+
+	f := &dmarc.IMAPFetcher{TLSConfig: cfg, Timeout: 5 * time.Second}
+	_, err := f.Fetch(context.Background(), dmarc.Mailbox{Address: addr, Username: "dmarc@example.com", Password: "wrong"}, time.Now())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Invalid credentials")
+	assert.NotContains(t, err.Error(), "wrong")
+}
+
+func TestIMAPUntrustedServer(t *testing.T) {
+	addr, _, _ := startIMAP(t, nil)
+
+	// the certificate of httptest is not trusted by default
+	f := &dmarc.IMAPFetcher{Timeout: 5 * time.Second}
+	_, err := f.Fetch(context.Background(), dmarc.Mailbox{Address: addr, Username: "dmarc@example.com"}, time.Now())
+	assert.Error(t, err)
+}
@@ -0,0 +1,298 @@
+package dmarc
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// maxReportSize bounds the size of a decompressed report.
+const maxReportSize = 16 << 20
+
+// maxParts bounds how many MIME parts of a report email are walked.
+const maxParts = 64
+
+// ErrNoReport is returned when the data holds no aggregate report.
+var ErrNoReport = errors.New("no DMARC aggregate report found")
+
+// Report is a DMARC aggregate report, as defined by RFC 7489 appendix C.
+type Report struct {
+	// OrgName is the organization that sent the report, e.g. google.com.
+	OrgName string
+
+	// ID identifies the report at the organization.
+	ID string
+
+	// Begin and End bound the period of the report.
+	Begin time.Time
+	End   time.Time
+
+	// Domain is the domain of the published policy the report is about.
+	Domain string
+
+	// Policy is the p tag of the published policy.
+	Policy string
+
+	Records []Record
+}
+
+// Key identifies the report across the organizations.
+func (r *Report) Key() string {
+	return r.OrgName + "/" + r.ID
+}
+
+// Record is a row of a report: how many messages from a source were
+// evaluated to the same results.
+type Record struct {
+	SourceIP   string
+	Count      int64
+	HeaderFrom string
+
+	// Disposition is what the receiver did with the messages: none,
+	// quarantine or reject.
+	Disposition string
+
+	// DKIM and SPF are the results of the mechanisms evaluated for DMARC,
+	// that is aligned with HeaderFrom: pass or fail.
+	DKIM string
+	SPF  string
+}
+
+// Passed reports whether the messages passed DMARC, with either mechanism.
+func (r Record) Passed() bool {
+	return r.DKIM == "pass" || r.SPF == "pass"
+}
+
+type feedback struct {
+	XMLName  xml.Name `xml:"feedback"`
+	Metadata struct {
+		OrgName   string `xml:"org_name"`
+		ReportID  string `xml:"report_id"`
+		DateRange struct {
+			Begin int64 `xml:"begin"`
+			End   int64 `xml:"end"`
+		} `xml:"date_range"`
+	} `xml:"report_metadata"`
+	Policy struct {
+		Domain string `xml:"domain"`
+		P      string `xml:"p"`
+	} `xml:"policy_published"`
+	Records []struct {
+		Row struct {
+			SourceIP        string `xml:"source_ip"`
+			Count           int64  `xml:"count"`
+			PolicyEvaluated struct {
+				Disposition string `xml:"disposition"`
+				DKIM        string `xml:"dkim"`
+				SPF         string `xml:"spf"`
+			} `xml:"policy_evaluated"`
+		} `xml:"row"`
+		Identifiers struct {
+			HeaderFrom string `xml:"header_from"`
+		} `xml:"identifiers"`
+	} `xml:"record"`
+}
+
+// Parse returns the reports of data: the XML of a report, gzip or zip
+// compressed or not, or an email with reports attached.
+func Parse(data []byte) ([]*Report, error) {
+	reports, err := parse(data, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(reports) == 0 {
+		return nil, ErrNoReport
+	}
+	return reports, nil
+}
+
+func parse(data []byte, depth int) ([]*Report, error) {
+	if depth > 4 {
+		return nil, errors.New("the report is nested too deeply")
+	}
+
+	switch {
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip report: %w", err)
+		}
+		inflated, err := readLimited(zr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip report: %w", err)
+		}
+		return parse(inflated, depth+1)
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		return parseZip(data, depth)
+	case bytes.HasPrefix(bytes.TrimSpace(data), []byte("<")):
+		report, err := parseXML(data)
+		if err != nil {
+			return nil, err
+		}
+		return []*Report{report}, nil
+	default:
+		return parseMessage(data, depth)
+	}
+}
+
+func parseZip(data []byte, depth int) ([]*Report, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid zip report: %w", err)
+	}
+
+	var reports []*Report
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("invalid zip report: %w", err)
+		}
+		inflated, err := readLimited(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("invalid zip report: %w", err)
+		}
+
+		found, err := parse(inflated, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, found...)
+	}
+	return reports, nil
+}
+
+func parseXML(data []byte) (*Report, error) {
+	fb := feedback{}
+	if err := xml.Unmarshal(data, &fb); err != nil {
+		return nil, fmt.Errorf("invalid report XML: %w", err)
+	}
+
+	report := &Report{
+		OrgName: strings.TrimSpace(fb.Metadata.OrgName),
+		ID:      strings.TrimSpace(fb.Metadata.ReportID),
+		Begin:   time.Unix(fb.Metadata.DateRange.Begin, 0).UTC(),
+		End:     time.Unix(fb.Metadata.DateRange.End, 0).UTC(),
+		Domain:  normalize(fb.Policy.Domain),
+		Policy:  strings.ToLower(strings.TrimSpace(fb.Policy.P)),
+	}
+	if report.ID == "" || report.Domain == "" || fb.Metadata.DateRange.End == 0 {
+		return nil, errors.New("the report has no ID, policy domain or period")
+	}
+
+	for _, rec := range fb.Records {
+		eval := rec.Row.PolicyEvaluated
+		report.Records = append(report.Records, Record{
+			SourceIP:    strings.TrimSpace(rec.Row.SourceIP),
+			Count:       rec.Row.Count,
+			HeaderFrom:  normalize(rec.Identifiers.HeaderFrom),
+			Disposition: strings.ToLower(strings.TrimSpace(eval.Disposition)),
+			DKIM:        strings.ToLower(strings.TrimSpace(eval.DKIM)),
+			SPF:         strings.ToLower(strings.TrimSpace(eval.SPF)),
+		})
+	}
+	return report, nil
+}
+
+// parseMessage returns the reports attached to an email.
+func parseMessage(data []byte, depth int) ([]*Report, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrNoReport
+	}
+
+	parts := 0
+	return walkPart(msg.Header, msg.Body, depth, &parts)
+}
+
+// header is the header of an email or of one of its parts.
+type header interface {
+	Get(key string) string
+}
+
+func walkPart(h header, body io.Reader, depth int, parts *int) ([]*Report, error) {
+	*parts++
+	if *parts > maxParts {
+		return nil, errors.New("the report email has too many parts")
+	}
+
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		var reports []*Report
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			p, err := mr.NextPart()
+			if errors.Is(err, io.EOF) {
+				return reports, nil
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid report email: %w", err)
+			}
+			found, err := walkPart(p.Header, p, depth, parts)
+			if err != nil {
+				return nil, err
+			}
+			reports = append(reports, found...)
+		}
+	}
+
+	if !reportMediaType(mediaType) {
+		return nil, nil
+	}
+
+	switch strings.ToLower(strings.TrimSpace(h.Get("Content-Transfer-Encoding"))) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+	data, err := readLimited(body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid report attachment: %w", err)
+	}
+	return parse(data, depth+1)
+}
+
+// reportMediaType reports whether the MIME type is one the reports are
+// sent as.
+func reportMediaType(mediaType string) bool {
+	switch mediaType {
+	case "application/gzip", "application/x-gzip", "application/zip", "application/x-zip-compressed",
+		"application/xml", "text/xml", "application/octet-stream":
+		return true
+	}
+	return false
+}
+
+func readLimited(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxReportSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxReportSize {
+		return nil, errors.New("the report is too large")
+	}
+	return data, nil
+}
+
+func normalize(domain string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(domain), "."))
+}
@@ -0,0 +1,176 @@
+package dmarc_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kannon-email/k8nnon/internal/dmarc"
+)
+
+// reportXML returns a report of example.com ending at end, with a passing
+// source and a failing one.
+func reportXML(id string, end time.Time) []byte {
+	return []byte(fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8" ?>
+<feedback>
+  <report_metadata>
+    <org_name>google.com</org_name>
+    <email>noreply-dmarc-support@google.com</email>
+    <report_id>%s</report_id>
+    <date_range><begin>%d</begin><end>%d</end></date_range>
+  </report_metadata>
+  <policy_published>
+    <domain>example.com</domain>
+    <adkim>r</adkim><aspf>r</aspf><p>none</p><pct>100</pct>
+  </policy_published>
+  <record>
+    <row>
+      <source_ip>192.0.2.1</source_ip>
+      <count>90</count>
+      <policy_evaluated><disposition>none</disposition><dkim>pass</dkim><spf>fail</spf></policy_evaluated>
+    </row>
+    <identifiers><header_from>example.com</header_from></identifiers>
+  </record>
+  <record>
+    <row>
+      <source_ip>203.0.113.9</source_ip>
+      <count>10</count>
+      <policy_evaluated><disposition>none</disposition><dkim>fail</dkim><spf>fail</spf></policy_evaluated>
+    </row>
+    <identifiers><header_from>news.example.com</header_from></identifiers>
+  </record>
+</feedback>`, id, end.Add(-24*time.Hour).Unix(), end.Unix()))
+}
+
+func gzipped(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	buf := bytes.Buffer{}
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+// reportEmail returns an email with the gzipped report attached, as sent
+// by Google.
+func reportEmail(t *testing.T, xml []byte) []byte {
+	t.Helper()
+
+	attachment := base64.StdEncoding.EncodeToString(gzipped(t, xml))
+	lines := []string{}
+	for len(attachment) > 76 {
+		lines, attachment = append(lines, attachment[:76]), attachment[76:]
+	}
+	lines = append(lines, attachment)
+
+	return []byte("From: noreply-dmarc-support@google.com\r\n" +
+		"To: dmarc@example.com\r\n" +
+		"Subject: Report domain: example.com Submitter: google.com\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: multipart/mixed; boundary=\"b1\"\r\n" +
+		"\r\n" +
+		"--b1\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"\r\n" +
+		"This is an aggregate report from google.com.\r\n" +
+		"--b1\r\n" +
+		"Content-Type: application/gzip; name=\"google.com!example.com.xml.gz\"\r\n" +
+		"Content-Transfer-Encoding: base64\r\n" +
+		"\r\n" +
+		strings.Join(lines, "\r\n") + "\r\n" +
+		"--b1--\r\n")
+}
+
+func TestParse(t *testing.T) {
+	end := time.Unix(1696291200, 0).UTC()
+
+	zipped := bytes.Buffer{}
+	zw := zip.NewWriter(&zipped)
+	f, err := zw.Create("report.xml")
+	require.NoError(t, err)
+	_, err = f.Write(reportXML("r1", end))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	for name, data := range map[string][]byte{
+		"xml":   reportXML("r1", end),
+		"gzip":  gzipped(t, reportXML("r1", end)),
+		"zip":   zipped.Bytes(),
+		"email": reportEmail(t, reportXML("r1", end)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			reports, err := dmarc.Parse(data)
+			require.NoError(t, err)
+			require.Len(t, reports, 1)
+
+			report := reports[0]
+			assert.Equal(t, "google.com", report.OrgName)
+			assert.Equal(t, "r1", report.ID)
+			assert.Equal(t, "example.com", report.Domain)
+			assert.Equal(t, "none", report.Policy)
+			assert.Equal(t, end, report.End)
+			require.Len(t, report.Records, 2)
+			assert.Equal(t, dmarc.Record{SourceIP: "192.0.2.1", Count: 90, HeaderFrom: "example.com", Disposition: "none", DKIM: "pass", SPF: "fail"}, report.Records[0])
+			assert.True(t, report.Records[0].Passed())
+			assert.False(t, report.Records[1].Passed())
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	_, err := dmarc.Parse([]byte("<feedback><report_metadata></report_metadata></feedback>"))
+	assert.Error(t, err)
+
+	_, err = dmarc.Parse([]byte("Subject: hello\r\n\r\nno report here\r\n"))
+	assert.True(t, errors.Is(err, dmarc.ErrNoReport), "should have no report: %v", err)
+}
+
+func TestStoreSummary(t *testing.T) {
+	now := time.Now()
+	store := dmarc.NewStore(0)
+
+	add := func(id string, end time.Time) bool {
+		reports, err := dmarc.Parse(reportXML(id, end))
+		require.NoError(t, err)
+		return store.Add(reports[0])
+	}
+
+	assert.True(t, add("r1", now.Add(-time.Hour)))
+	assert.True(t, add("r2", now.Add(-48*time.Hour)))
+	// the reports are counted once
+	assert.False(t, add("r1", now.Add(-time.Hour)))
+	// out of the retention
+	assert.False(t, add("r3", now.Add(-60*24*time.Hour)))
+
+	summary := store.Summary("Example.com", 7*24*time.Hour)
+	assert.Equal(t, 2, summary.Reports)
+	assert.Equal(t, []string{"google.com"}, summary.Reporters)
+	assert.Equal(t, int64(200), summary.Messages)
+	assert.Equal(t, int64(180), summary.DKIMAligned)
+	assert.Equal(t, int64(0), summary.SPFAligned)
+	assert.Equal(t, int64(180), summary.Passed)
+	assert.Equal(t, []dmarc.Source{{IP: "203.0.113.9", HeaderFrom: "news.example.com", DKIM: "fail", SPF: "fail", Messages: 20}}, summary.TopFailingSources)
+	assert.Equal(t, now.Add(-time.Hour).Unix(), summary.LastReportEnd.Unix())
+
+	// the window only counts the recent reports
+	assert.Equal(t, 1, store.Summary("example.com", 24*time.Hour).Reports)
+	assert.Zero(t, store.Summary("other.example", 24*time.Hour).Reports)
+
+	select {
+	case domain := <-store.Changes():
+		assert.Equal(t, "example.com", domain)
+	default:
+		t.Fatal("the new reports should be notified")
+	}
+}
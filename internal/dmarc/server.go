@@ -0,0 +1,148 @@
+package dmarc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// PathPrefix is where the reports are received, followed by the namespace
+// and the name of the Domain.
+const PathPrefix = "/dmarc/reports/"
+
+// maxBodySize bounds the size of a received report.
+const maxBodySize = 10 << 20
+
+// ErrUnauthorized is returned by the Authorizers when the token does not
+// authorize the reports of the Domain.
+var ErrUnauthorized = errors.New("unauthorized")
+
+// Authorizer returns the domain name of the Domain namespace/name when
+// token authorizes its reports.
+type Authorizer func(ctx context.Context, namespace, name, token string) (string, error)
+
+// Handler receives the reports of the Domains at PathPrefix and adds them
+// to the store. The reports of other domains than the one of the Domain,
+// or of its subdomains, are rejected.
+func Handler(store *Store, authorize Authorizer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		namespace, name, ok := strings.Cut(strings.TrimPrefix(req.URL.Path, PathPrefix), "/")
+		if !strings.HasPrefix(req.URL.Path, PathPrefix) || !ok || namespace == "" || name == "" || strings.Contains(name, "/") {
+			http.NotFound(w, req)
+			return
+		}
+		if req.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			http.Error(w, "missing bearer token", http.StatusUnauthorized)
+			return
+		}
+		domain, err := authorize(req.Context(), namespace, name, token)
+		if errors.Is(err, ErrUnauthorized) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			http.Error(w, "failed to authorize the report", http.StatusInternalServerError)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(req.Body, maxBodySize+1))
+		if err != nil {
+			http.Error(w, "failed to read the body", http.StatusBadRequest)
+			return
+		}
+		if len(body) > maxBodySize {
+			http.Error(w, "body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		reports, err := Parse(body)
+		if err != nil {
+			http.Error(w, "invalid report: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, report := range reports {
+			if !InDomain(report.Domain, domain) {
+				http.Error(w, fmt.Sprintf("the report is about %s, not %s", report.Domain, domain), http.StatusUnprocessableEntity)
+				return
+			}
+		}
+		for _, report := range reports {
+			store.Add(report)
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+// InDomain reports whether name is domain or one of its subdomains.
+func InDomain(name, domain string) bool {
+	name, domain = normalize(name), normalize(domain)
+	return domain != "" && (name == domain || strings.HasSuffix(name, "."+domain))
+}
+
+// Server receives the reports, over TLS when a certificate is set. It runs
+// on the leader only, which reconciles the Domains and holds the reports.
+type Server struct {
+	srv      *http.Server
+	certFile string
+	keyFile  string
+}
+
+// NewServer creates a Server listening on addr. Empty certFile and keyFile
+// serve plain HTTP.
+func NewServer(addr string, handler http.Handler, certFile, keyFile string) *Server {
+	return &Server{
+		srv: &http.Server{
+			Addr:              addr,
+			Handler:           handler,
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+}
+
+// Start receives the reports until ctx is done.
+func (s *Server) Start(ctx context.Context) error {
+	errCh := make(chan error, 1)
+	go func() {
+		if s.certFile != "" {
+			errCh <- s.srv.ListenAndServeTLS(s.certFile, s.keyFile)
+			return
+		}
+		errCh <- s.srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// NeedLeaderElection reports that the server runs on the leader only.
+func (s *Server) NeedLeaderElection() bool {
+	return true
+}
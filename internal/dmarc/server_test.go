@@ -0,0 +1,58 @@
+package dmarc_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kannon-email/k8nnon/internal/dmarc"
+)
+
+func authorizeExample(_ context.Context, namespace, name, token string) (string, error) {
+	if namespace != "default" || name != "example" || token != "secret" {
+		return "", dmarc.ErrUnauthorized
+	}
+	return "example.com", nil
+}
+
+func TestHandler(t *testing.T) {
+	store := dmarc.NewStore(0)
+	h := dmarc.Handler(store, authorizeExample)
+
+	post := func(path, token string, body []byte) int {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	report := reportEmail(t, reportXML("r1", time.Now()))
+	assert.Equal(t, http.StatusUnauthorized, post("/dmarc/reports/default/example", "", report))
+	assert.Equal(t, http.StatusUnauthorized, post("/dmarc/reports/default/example", "wrong", report))
+	assert.Equal(t, http.StatusNotFound, post("/dmarc/reports/default", "secret", report))
+	assert.Equal(t, http.StatusBadRequest, post("/dmarc/reports/default/example", "secret", []byte("<feedback/>")))
+	assert.Zero(t, store.Summary("example.com", time.Hour).Reports)
+
+	assert.Equal(t, http.StatusAccepted, post("/dmarc/reports/default/example", "secret", report))
+	assert.Equal(t, 1, store.Summary("example.com", time.Hour).Reports)
+
+	// the reports of other domains are rejected
+	other := bytes.ReplaceAll(reportXML("r2", time.Now()), []byte("<domain>example.com"), []byte("<domain>example.org"))
+	assert.Equal(t, http.StatusUnprocessableEntity, post("/dmarc/reports/default/example", "secret", other))
+	assert.Zero(t, store.Summary("example.org", time.Hour).Reports)
+}
+
+func TestInDomain(t *testing.T) {
+	assert.True(t, dmarc.InDomain("example.com", "example.com"))
+	assert.True(t, dmarc.InDomain("news.Example.com.", "example.com"))
+	assert.False(t, dmarc.InDomain("badexample.com", "example.com"))
+	assert.False(t, dmarc.InDomain("example.com", ""))
+}
@@ -0,0 +1,182 @@
+package dmarc
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultRetention is how long the reports are kept, by the end of their
+// period.
+const DefaultRetention = 31 * 24 * time.Hour
+
+// maxTopSources bounds the failing sources of a summary.
+const maxTopSources = 5
+
+// Summary sums up the reports of a domain.
+type Summary struct {
+	Reports   int
+	Reporters []string
+
+	Messages    int64
+	DKIMAligned int64
+	SPFAligned  int64
+	Passed      int64
+
+	// TopFailingSources are the sources of most of the messages that failed
+	// DMARC, by decreasing count.
+	TopFailingSources []Source
+
+	// LastReportEnd is the end of the period of the latest report, zero
+	// without any.
+	LastReportEnd time.Time
+}
+
+// Source is a source of the messages of a domain, with the same results.
+type Source struct {
+	IP         string
+	HeaderFrom string
+	DKIM       string
+	SPF        string
+	Messages   int64
+}
+
+// Store keeps the reports of the last retention per domain, once each. It
+// is safe for concurrent use.
+type Store struct {
+	retention time.Duration
+	now       func() time.Time
+
+	mu      sync.Mutex
+	reports map[string]map[string]*Report
+
+	changes chan string
+}
+
+// NewStore creates a Store keeping the reports of the last retention. A
+// zero retention uses DefaultRetention.
+func NewStore(retention time.Duration) *Store {
+	if retention <= 0 {
+		retention = DefaultRetention
+	}
+	return &Store{
+		retention: retention,
+		now:       time.Now,
+		reports:   map[string]map[string]*Report{},
+		changes:   make(chan string, 1024),
+	}
+}
+
+// Changes receives the domains a new report was added for. Changes are
+// dropped while the channel is full.
+func (s *Store) Changes() <-chan string {
+	return s.changes
+}
+
+// Add keeps the report and reports whether it is new. The reports ending
+// out of the retention are ignored.
+func (s *Store) Add(report *Report) bool {
+	now := s.now()
+	if now.Sub(report.End) >= s.retention {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reports := s.prune(report.Domain, now)
+	if reports == nil {
+		reports = map[string]*Report{}
+		s.reports[report.Domain] = reports
+	}
+	if _, ok := reports[report.Key()]; ok {
+		return false
+	}
+	reports[report.Key()] = report
+
+	select {
+	case s.changes <- report.Domain:
+	default:
+	}
+	return true
+}
+
+// Summary sums up the reports of domain whose period ended in the last
+// window.
+func (s *Store) Summary(domain string, window time.Duration) Summary {
+	domain = normalize(domain)
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	summary := Summary{}
+	reporters := map[string]bool{}
+	failing := map[Source]int64{}
+	for _, report := range s.prune(domain, now) {
+		if now.Sub(report.End) >= window {
+			continue
+		}
+
+		summary.Reports++
+		reporters[report.OrgName] = true
+		if report.End.After(summary.LastReportEnd) {
+			summary.LastReportEnd = report.End
+		}
+
+		for _, rec := range report.Records {
+			summary.Messages += rec.Count
+			if rec.DKIM == "pass" {
+				summary.DKIMAligned += rec.Count
+			}
+			if rec.SPF == "pass" {
+				summary.SPFAligned += rec.Count
+			}
+			if rec.Passed() {
+				summary.Passed += rec.Count
+				continue
+			}
+			failing[Source{IP: rec.SourceIP, HeaderFrom: rec.HeaderFrom, DKIM: rec.DKIM, SPF: rec.SPF}] += rec.Count
+		}
+	}
+
+	for reporter := range reporters {
+		summary.Reporters = append(summary.Reporters, reporter)
+	}
+	sort.Strings(summary.Reporters)
+
+	for source, count := range failing {
+		source.Messages = count
+		summary.TopFailingSources = append(summary.TopFailingSources, source)
+	}
+	sort.Slice(summary.TopFailingSources, func(i, j int) bool {
+		a, b := summary.TopFailingSources[i], summary.TopFailingSources[j]
+		if a.Messages != b.Messages {
+			return a.Messages > b.Messages
+		}
+		if a.IP != b.IP {
+			return a.IP < b.IP
+		}
+		return a.HeaderFrom+a.DKIM+a.SPF < b.HeaderFrom+b.DKIM+b.SPF
+	})
+	if len(summary.TopFailingSources) > maxTopSources {
+		summary.TopFailingSources = summary.TopFailingSources[:maxTopSources]
+	}
+
+	return summary
+}
+
+// prune drops the reports of domain out of the retention.
+func (s *Store) prune(domain string, now time.Time) map[string]*Report {
+	reports := s.reports[domain]
+	for key, report := range reports {
+		if now.Sub(report.End) >= s.retention {
+			delete(reports, key)
+		}
+	}
+	if len(reports) == 0 {
+		delete(s.reports, domain)
+		return nil
+	}
+	return reports
+}
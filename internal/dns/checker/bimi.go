@@ -0,0 +1,303 @@
+package checker
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+	"github.com/kannon-email/k8nnon/internal/dns/resolver"
+)
+
+const (
+	// maxIndicatorSize bounds the size of a fetched BIMI logo, as
+	// recommended by the BIMI specification.
+	maxIndicatorSize = 32 * 1024
+	// maxVMCSize bounds the size of a fetched Verified Mark Certificate
+	// chain.
+	maxVMCSize = 64 * 1024
+)
+
+// oidBIMIExtKeyUsage is the extended key usage of the Verified Mark
+// Certificates.
+var oidBIMIExtKeyUsage = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 31}
+
+// BIMICheckStats is the outcome of the BIMI checks of a domain.
+type BIMICheckStats struct {
+	// Record is the check of the BIMI TXT record.
+	Record DNSCheckStats
+
+	// Indicator is the check of the SVG logo. Like the other fetches it is
+	// a single outcome.
+	Indicator DNSCheckStats
+
+	// VMC is the check of the Verified Mark Certificate, nil when the
+	// domain has none.
+	VMC *DNSCheckStats
+}
+
+// WithVMCRoots sets the roots the Verified Mark Certificates must chain to.
+// When nil, the default, only the certificate itself is checked.
+func WithVMCRoots(roots *x509.CertPool) Option {
+	return func(d *ResolverChecker) {
+		d.vmcRoots = roots
+	}
+}
+
+// CheckDomainBIMI verifies that the BIMI record of the domain points to the
+// logo and the certificate of spec.bimi, that the logo is an SVG Tiny PS
+// document and that the certificate is a valid VMC for the domain.
+func (d ResolverChecker) CheckDomainBIMI(ctx context.Context, domain *corev1alpha1.Domain) BIMICheckStats {
+	stats := BIMICheckStats{
+		Record: d.checkDNS(ctx, domain, checkDomainBIMI),
+	}
+	stats.Record.Expected = Record{Type: "TXT", Name: bimiName(domain), Value: bimiValue(domain)}
+
+	if domain.Spec.BIMI == nil {
+		return stats
+	}
+
+	if d.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.timeout)
+		defer cancel()
+	}
+
+	stats.Indicator = d.checkFetched(ctx, domain.Spec.BIMI.LogoURL, maxIndicatorSize, checkBIMIIndicator)
+	if url := domain.Spec.BIMI.VMCURL; url != "" {
+		vmc := d.checkFetched(ctx, url, maxVMCSize, func(body []byte) error {
+			return d.checkVMC(body, domain.Spec.DomainName, time.Now())
+		})
+		stats.VMC = &vmc
+	}
+
+	return stats
+}
+
+// checkFetched fetches url and validates the body with check. A failed
+// fetch counts as an error and a KO, a body failing the check as a KO.
+func (d ResolverChecker) checkFetched(ctx context.Context, url string, maxSize int64, check func([]byte) error) DNSCheckStats {
+	stats := DNSCheckStats{}
+
+	body, err := d.fetch(ctx, url, maxSize)
+	if err != nil {
+		stats.CntErr, stats.CntKO = 1, 1
+		stats.Err = err
+		return stats
+	}
+
+	if err := check(body); err != nil {
+		stats.CntKO = 1
+		stats.Reason = err.Error()
+		return stats
+	}
+
+	stats.CntOK = 1
+	return stats
+}
+
+func bimiName(domain *corev1alpha1.Domain) string {
+	selector := corev1alpha1.DefaultBIMISelector
+	if domain.Spec.BIMI != nil {
+		selector = domain.Spec.BIMI.SelectorOrDefault()
+	}
+	return fmt.Sprintf("%s._bimi.%s", selector, domain.Spec.DomainName)
+}
+
+func bimiValue(domain *corev1alpha1.Domain) string {
+	if domain.Spec.BIMI == nil {
+		return ""
+	}
+	value := fmt.Sprintf("v=BIMI1; l=%s", domain.Spec.BIMI.LogoURL)
+	if vmc := domain.Spec.BIMI.VMCURL; vmc != "" {
+		value += fmt.Sprintf("; a=%s", vmc)
+	}
+	return value
+}
+
+// checkDomainBIMI verifies that the BIMI record points to the logo and the
+// certificate of spec.bimi.
+func checkDomainBIMI(ctx context.Context, r resolver.Resolver, domain *corev1alpha1.Domain) (bool, checkDetail, error) {
+	res, err := r.LookupTXT(ctx, bimiName(domain))
+	if err != nil {
+		if dnsErr, ok := err.(*net.DNSError); ok {
+			if dnsErr.IsNotFound {
+				return false, checkDetail{}, nil
+			}
+		}
+
+		return false, checkDetail{}, err
+	}
+
+	detail := checkDetail{observed: res}
+	for _, txt := range res {
+		tags, ok := parseBIMIRecord(txt)
+		if !ok {
+			continue
+		}
+
+		if domain.Spec.BIMI == nil {
+			return true, detail, nil
+		}
+		if tags["l"] != domain.Spec.BIMI.LogoURL {
+			return false, detail, mismatchf("the BIMI record points to the logo %q", tags["l"])
+		}
+		if vmc := domain.Spec.BIMI.VMCURL; vmc != "" && tags["a"] != vmc {
+			return false, detail, mismatchf("the BIMI record points to the certificate %q", tags["a"])
+		}
+		return true, detail, nil
+	}
+
+	return false, detail, nil
+}
+
+// parseBIMIRecord returns the tags of a BIMI record.
+func parseBIMIRecord(txt string) (map[string]string, bool) {
+	parts := strings.Split(txt, ";")
+	if strings.TrimSpace(parts[0]) != "v=BIMI1" {
+		return nil, false
+	}
+
+	tags := map[string]string{}
+	for _, tag := range parts[1:] {
+		name, value, _ := strings.Cut(tag, "=")
+		if name = strings.TrimSpace(name); name != "" {
+			tags[name] = strings.TrimSpace(value)
+		}
+	}
+	return tags, true
+}
+
+// checkBIMIIndicator verifies that the logo is an SVG Tiny PS document: an
+// svg root with the tiny-ps base profile and a title, and no scripts.
+func checkBIMIIndicator(body []byte) error {
+	dec := xml.NewDecoder(bytes.NewReader(body))
+
+	root := true
+	title := false
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("the logo is not a valid SVG document: %v", err)
+		}
+
+		el, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case root:
+			if el.Name.Local != "svg" {
+				return fmt.Errorf("the logo root element is %q, not svg", el.Name.Local)
+			}
+			if profile := attr(el, "baseProfile"); profile != "tiny-ps" {
+				return fmt.Errorf("the logo base profile is %q, not tiny-ps", profile)
+			}
+			root = false
+		case el.Name.Local == "script":
+			return errors.New("the logo contains a script")
+		case el.Name.Local == "title":
+			title = true
+		}
+	}
+
+	if root {
+		return errors.New("the logo is empty")
+	}
+	if !title {
+		return errors.New("the logo has no title")
+	}
+	return nil
+}
+
+func attr(el xml.StartElement, name string) string {
+	for _, a := range el.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// checkVMC verifies that the first certificate of the PEM chain is valid at
+// now, is issued for the domain and has the BIMI extended key usage. With
+// VMC roots, the chain must lead to one of them.
+func (d ResolverChecker) checkVMC(body []byte, domain string, now time.Time) error {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, body = pem.Decode(body)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("the VMC is not a valid certificate: %v", err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return errors.New("no certificate found in the VMC")
+	}
+
+	leaf := certs[0]
+	if now.Before(leaf.NotBefore) || now.After(leaf.NotAfter) {
+		return fmt.Errorf("the VMC is only valid from %s to %s", leaf.NotBefore.Format(time.RFC3339), leaf.NotAfter.Format(time.RFC3339))
+	}
+
+	issued := false
+	for _, name := range leaf.DNSNames {
+		if strings.EqualFold(strings.TrimSuffix(name, "."), domain) {
+			issued = true
+		}
+	}
+	if !issued {
+		return fmt.Errorf("the VMC is not issued for %s", domain)
+	}
+
+	bimiUsage := false
+	for _, usage := range leaf.UnknownExtKeyUsage {
+		if usage.Equal(oidBIMIExtKeyUsage) {
+			bimiUsage = true
+		}
+	}
+	if !bimiUsage {
+		return errors.New("the VMC does not have the BIMI extended key usage")
+	}
+
+	if d.vmcRoots == nil {
+		return nil
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         d.vmcRoots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return fmt.Errorf("the VMC chain is not trusted: %v", err)
+	}
+	return nil
+}
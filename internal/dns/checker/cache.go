@@ -0,0 +1,258 @@
+package checker
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/kannon-email/k8nnon/internal/dns/resolver"
+)
+
+// DefaultCacheTTL is the maximum time a successful lookup result is reused
+// for. Answers with a lower TTL expire earlier.
+const DefaultCacheTTL = 30 * time.Second
+
+// DefaultSharedCacheTTL is the maximum time the result of a shared query is
+// reused for, see LookupCache.
+const DefaultSharedCacheTTL = 5 * time.Minute
+
+type cacheKey struct {
+	resolver   string
+	name       string
+	recordType string
+}
+
+type cacheEntry struct {
+	value     interface{}
+	expiresAt time.Time
+}
+
+// LookupCache stores successful lookup results for the TTL of their
+// answers, capped at a fixed TTL. The queries shared by many Domains, like
+// the targets of their SPF includes and the reverse DNS of their sending
+// addresses, are capped at a longer TTL: they are resolved once for a whole
+// batch of Domains instead of once for each. The results are keyed by
+// resolver and query, so one cache can back the checkers replacing each
+// other, see WithLookupCache. It is safe for concurrent use.
+type LookupCache struct {
+	ttl       time.Duration
+	sharedTTL time.Duration
+	now       func() time.Time
+
+	m         sync.Mutex
+	entries   map[cacheKey]cacheEntry
+	lastSweep time.Time
+}
+
+// NewLookupCache creates a cache reusing the results for up to ttl, and
+// the results of the shared queries for up to sharedTTL when longer. It
+// returns nil, caching nothing, when both are zero.
+func NewLookupCache(ttl, sharedTTL time.Duration) *LookupCache {
+	if ttl <= 0 && sharedTTL <= 0 {
+		return nil
+	}
+
+	return &LookupCache{
+		ttl:       ttl,
+		sharedTTL: sharedTTL,
+		now:       time.Now,
+		entries:   map[cacheKey]cacheEntry{},
+	}
+}
+
+func (c *LookupCache) get(key cacheKey) (interface{}, bool) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	if !c.now().Before(e.expiresAt) {
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	return e.value, true
+}
+
+// maxTTL is the longest any result is kept.
+func (c *LookupCache) maxTTL() time.Duration {
+	if c.sharedTTL > c.ttl {
+		return c.sharedTTL
+	}
+	return c.ttl
+}
+
+// set stores value for the TTL of its answers, when known and lower than
+// the cache TTL, the one of the shared queries when shared.
+func (c *LookupCache) set(key cacheKey, value interface{}, shared bool, answerTTL func() (time.Duration, bool)) {
+	ttl := c.ttl
+	if shared {
+		ttl = c.maxTTL()
+	}
+	if t, ok := answerTTL(); ok && t < ttl {
+		ttl = t
+	}
+	if ttl <= 0 {
+		return
+	}
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	now := c.now()
+	c.entries[key] = cacheEntry{value: value, expiresAt: now.Add(ttl)}
+
+	// drop the expired entries that were never read again
+	if now.Sub(c.lastSweep) < c.maxTTL() {
+		return
+	}
+	for k, e := range c.entries {
+		if !now.Before(e.expiresAt) {
+			delete(c.entries, k)
+		}
+	}
+	c.lastSweep = now
+}
+
+type bypassCacheKey struct{}
+
+// WithoutCache returns a context whose lookups skip the cached results. The
+// fresh results are still stored for later lookups.
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassCacheKey{}, true)
+}
+
+func bypassCache(ctx context.Context) bool {
+	bypass, _ := ctx.Value(bypassCacheKey{}).(bool)
+	return bypass
+}
+
+type sharedQueriesKey struct{}
+
+// withSharedQueries returns a context whose lookups are shared by many
+// Domains, and cached for the TTL of the shared queries.
+func withSharedQueries(ctx context.Context) context.Context {
+	return context.WithValue(ctx, sharedQueriesKey{}, true)
+}
+
+func sharedQueries(ctx context.Context) bool {
+	shared, _ := ctx.Value(sharedQueriesKey{}).(bool)
+	return shared
+}
+
+// cachingResolver reuses the successful results of the wrapped resolver,
+// stored under its name. Errors are never cached.
+type cachingResolver struct {
+	r     resolver.Resolver
+	name  string
+	cache *LookupCache
+}
+
+func withCache(r resolver.Resolver, name string, cache *LookupCache) resolver.Resolver {
+	if cache == nil {
+		return r
+	}
+
+	return cachingResolver{r: r, name: name, cache: cache}
+}
+
+func (c cachingResolver) key(name, recordType string) cacheKey {
+	return cacheKey{resolver: c.name, name: name, recordType: recordType}
+}
+
+// get returns the cached result for key, unless ctx bypasses the cache.
+func (c cachingResolver) get(ctx context.Context, key cacheKey) (interface{}, bool) {
+	v, ok := c.cache.get(key)
+	if !ok || bypassCache(ctx) {
+		cacheLookups.WithLabelValues(key.recordType, "miss").Inc()
+		return nil, false
+	}
+
+	cacheLookups.WithLabelValues(key.recordType, "hit").Inc()
+	return v, true
+}
+
+func (c cachingResolver) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	key := c.key(addr, "PTR")
+	if v, ok := c.get(ctx, key); ok {
+		return append([]string(nil), v.([]string)...), nil
+	}
+
+	lookupCtx, ttl := resolver.WithTTLRecorder(ctx)
+	res, err := c.r.LookupAddr(lookupCtx, addr)
+	if err != nil {
+		return res, err
+	}
+
+	c.cache.set(key, append([]string(nil), res...), sharedQueries(ctx), ttl)
+	return res, nil
+}
+
+func (c cachingResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	// the addresses of both families, not the A records alone
+	key := c.key(host, "HOST")
+	if v, ok := c.get(ctx, key); ok {
+		return append([]string(nil), v.([]string)...), nil
+	}
+
+	lookupCtx, ttl := resolver.WithTTLRecorder(ctx)
+	res, err := c.r.LookupHost(lookupCtx, host)
+	if err != nil {
+		return res, err
+	}
+
+	c.cache.set(key, append([]string(nil), res...), sharedQueries(ctx), ttl)
+	return res, nil
+}
+
+func (c cachingResolver) LookupCNAME(ctx context.Context, name string) (string, error) {
+	key := c.key(name, "CNAME")
+	if v, ok := c.get(ctx, key); ok {
+		return v.(string), nil
+	}
+
+	lookupCtx, ttl := resolver.WithTTLRecorder(ctx)
+	res, err := c.r.LookupCNAME(lookupCtx, name)
+	if err != nil {
+		return res, err
+	}
+
+	c.cache.set(key, res, sharedQueries(ctx), ttl)
+	return res, nil
+}
+
+func (c cachingResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	key := c.key(name, "TXT")
+	if v, ok := c.get(ctx, key); ok {
+		return append([]string(nil), v.([]string)...), nil
+	}
+
+	lookupCtx, ttl := resolver.WithTTLRecorder(ctx)
+	res, err := c.r.LookupTXT(lookupCtx, name)
+	if err != nil {
+		return res, err
+	}
+
+	c.cache.set(key, append([]string(nil), res...), sharedQueries(ctx), ttl)
+	return res, nil
+}
+
+func (c cachingResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	key := c.key(name, "MX")
+	if v, ok := c.get(ctx, key); ok {
+		return append([]*net.MX(nil), v.([]*net.MX)...), nil
+	}
+
+	lookupCtx, ttl := resolver.WithTTLRecorder(ctx)
+	res, err := c.r.LookupMX(lookupCtx, name)
+	if err != nil {
+		return res, err
+	}
+
+	c.cache.set(key, append([]*net.MX(nil), res...), sharedQueries(ctx), ttl)
+	return res, nil
+}
@@ -2,17 +2,157 @@ package checker
 
 import (
 	"context"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"net"
+	"net/http"
+	"sort"
 	"strings"
 	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
 
 	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+	"github.com/kannon-email/k8nnon/internal/dkim"
 	"github.com/kannon-email/k8nnon/internal/dns/resolver"
+	"github.com/kannon-email/k8nnon/internal/resilience"
 )
 
-type DNSChecker struct {
-	resolvers []resolver.Resolver
+// DefaultLookupTimeout is the maximum time a single DNS lookup may take
+// before it is aborted.
+const DefaultLookupTimeout = 5 * time.Second
+
+// DNSChecker verifies the DNS records required by a Domain.
+type DNSChecker interface {
+	CheckDomainDKIM(ctx context.Context, domain *corev1alpha1.Domain) DNSCheckStats
+	CheckDomainSPF(ctx context.Context, domain *corev1alpha1.Domain) DNSCheckStats
+	CheckDomainStatsDNS(ctx context.Context, domain *corev1alpha1.Domain) DNSCheckStats
+	CheckDomainMX(ctx context.Context, domain *corev1alpha1.Domain) DNSCheckStats
+	CheckDomainDMARC(ctx context.Context, domain *corev1alpha1.Domain) DNSCheckStats
+	CheckDomainMTASTS(ctx context.Context, domain *corev1alpha1.Domain) DNSCheckStats
+	CheckDomainMTASTSPolicy(ctx context.Context, domain *corev1alpha1.Domain) DNSCheckStats
+	CheckDomainTLSRPT(ctx context.Context, domain *corev1alpha1.Domain) DNSCheckStats
+	CheckDomainBIMI(ctx context.Context, domain *corev1alpha1.Domain) BIMICheckStats
+	CheckPTR(ctx context.Context, ip string, domains ...string) DNSCheckStats
+	CheckSPFCoverage(ctx context.Context, name, ip string) DNSCheckStats
+	CheckOwnership(ctx context.Context, domain, token string) DNSCheckStats
+}
+
+// ResolverChecker is a DNSChecker querying a set of resolvers and combining
+// their answers.
+type ResolverChecker struct {
+	resolvers  []resolver.Resolver
+	names      []string
+	quorum     int
+	timeout    time.Duration
+	cacheTTL   time.Duration
+	cache      *LookupCache
+	limiter    *rate.Limiter
+	mxHost     string
+	spfInclude string
+	httpClient *http.Client
+	vmcRoots   *x509.CertPool
+
+	breakerThreshold int
+	breakerCooldown  time.Duration
+	retryBudget      *resilience.Budget
+}
+
+// Option configures a ResolverChecker.
+type Option func(*ResolverChecker)
+
+// WithLookupTimeout sets the timeout applied to every single DNS lookup.
+func WithLookupTimeout(timeout time.Duration) Option {
+	return func(d *ResolverChecker) {
+		d.timeout = timeout
+	}
+}
+
+// WithMXHost sets the host the MX records of the domains must point to.
+// When empty, the Domain base domain is expected.
+func WithMXHost(host string) Option {
+	return func(d *ResolverChecker) {
+		d.mxHost = host
+	}
+}
+
+// WithSPFInclude sets the include mechanism the SPF records of the domains
+// must contain, e.g. spf.kannon.email. When empty, the Domain base domain is
+// expected.
+func WithSPFInclude(include string) Option {
+	return func(d *ResolverChecker) {
+		d.spfInclude = include
+	}
+}
+
+// WithQuorum sets how many resolvers must find a record for it to be
+// verified. Zero requires a majority of the resolvers.
+func WithQuorum(quorum int) Option {
+	return func(d *ResolverChecker) {
+		d.quorum = quorum
+	}
+}
+
+// WithCacheTTL sets for how long successful lookup results are reused.
+// A zero TTL disables the cache. It has no effect with WithLookupCache.
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(d *ResolverChecker) {
+		d.cacheTTL = ttl
+	}
+}
+
+// WithLookupCache stores the lookup results in cache, which outlives the
+// checker: the checkers built with the same cache, e.g. when the resolvers
+// are reconfigured, reuse each other's results. Nil disables the cache.
+func WithLookupCache(cache *LookupCache) Option {
+	return func(d *ResolverChecker) {
+		d.cache = cache
+		d.cacheTTL = 0
+	}
+}
+
+// WithRateLimit limits the DNS lookups, across all resolvers, to qps per
+// second with bursts of burst lookups. Cached results are not limited. A
+// zero qps, the default, disables the limit.
+func WithRateLimit(qps float64, burst int) Option {
+	return func(d *ResolverChecker) {
+		if qps <= 0 {
+			d.limiter = nil
+			return
+		}
+		d.limiter = rate.NewLimiter(rate.Limit(qps), burst)
+	}
+}
+
+// WithCircuitBreaker gives every resolver a circuit breaker opening after
+// threshold consecutive lookups timed out or failed by the resolver, for
+// the cooldown. A zero threshold, the default, disables the breakers.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(d *ResolverChecker) {
+		d.breakerThreshold = threshold
+		d.breakerCooldown = cooldown
+	}
+}
+
+// WithRetryBudget retries the lookups failing transiently, drawing the
+// retries from budget under the key of their context, see
+// resilience.WithBudgetKey. The budget is usually shared by the checkers
+// replacing each other. Without one, the lookups are not retried.
+func WithRetryBudget(budget *resilience.Budget) Option {
+	return func(d *ResolverChecker) {
+		d.retryBudget = budget
+	}
+}
+
+// WithHTTPClient sets the client fetching the MTA-STS policies and the BIMI
+// indicators and certificates. Redirects are never followed. The default
+// client only dials public addresses.
+func WithHTTPClient(c *http.Client) Option {
+	return func(d *ResolverChecker) {
+		d.httpClient = c
+	}
 }
 
 var ServerAddresses = []string{
@@ -32,32 +172,165 @@ type DNSCheckStats struct {
 	CntOK  int
 	CntKO  int
 	CntErr int
+
+	// Err joins the errors returned by the resolvers that failed.
+	Err error
+
+	// Reason explains why the record did not match, when known.
+	Reason string
+
+	// Value is a detail of the verified record agreed on by most resolvers,
+	// such as the DMARC policy.
+	Value string
+
+	// Observed are the records returned by any resolver, sorted and
+	// deduplicated.
+	Observed []string
+
+	// Expected is the record the check looks for.
+	Expected Record
+
+	// SPF is the evaluation of the SPF record, set by CheckDomainSPF when
+	// a resolver returned a record. A permerror seen by any resolver wins,
+	// then the largest lookup count.
+	SPF *SPFEvaluation
+
+	// Quorum is how many resolvers must find the record, zero for a
+	// majority.
+	Quorum int
+
+	// Resolvers are the outcomes of the single resolvers, sorted by name.
+	Resolvers []ResolverResult
+}
+
+// ResolverResult is the outcome of a check with a single resolver.
+type ResolverResult struct {
+	Resolver string
+	OK       bool
+	Err      error
+}
+
+// Record is a DNS record as it is entered in a DNS provider.
+type Record struct {
+	Type  string
+	Name  string
+	Value string
 }
 
 func (c DNSCheckStats) Result() bool {
+	if c.Quorum > 0 {
+		return c.CntOK >= c.Quorum
+	}
 	return c.CntOK > c.CntKO+c.CntErr
 }
 
-func NewDNSChecker(r ...resolver.Resolver) *DNSChecker {
-	return &DNSChecker{resolvers: r}
+// State tells a verified record from a missing one and from a check whose
+// outcome is unknown because most resolvers failed to answer.
+func (c DNSCheckStats) State() corev1alpha1.CheckState {
+	if c.Result() {
+		return corev1alpha1.CheckStateVerified
+	}
+
+	// failed lookups are counted both as errors and as KOs
+	if missing := c.CntKO - c.CntErr; c.CntErr > missing {
+		return corev1alpha1.CheckStateUnknown
+	}
+
+	return corev1alpha1.CheckStateMissing
+}
+
+func NewDNSChecker(r ...resolver.Resolver) *ResolverChecker {
+	return New(r)
 }
 
-type checkFunc func(ctx context.Context, r resolver.Resolver, domain *corev1alpha1.Domain) (bool, error)
+func New(r []resolver.Resolver, opts ...Option) *ResolverChecker {
+	d := &ResolverChecker{
+		timeout:    DefaultLookupTimeout,
+		cacheTTL:   DefaultCacheTTL,
+		httpClient: &http.Client{Transport: newFetchTransport()},
+	}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+	if d.cache == nil {
+		d.cache = NewLookupCache(d.cacheTTL, 0)
+	}
 
-func (d DNSChecker) CheckDomainDKim(ctx context.Context, domain *corev1alpha1.Domain) DNSCheckStats {
-	return d.checkDNS(ctx, domain, checkDomainDKim)
+	d.resolvers = make([]resolver.Resolver, 0, len(r))
+	d.names = make([]string, 0, len(r))
+	for i, res := range r {
+		name := resolver.Name(res)
+		if name == "" {
+			name = fmt.Sprintf("resolver-%d", i)
+		}
+		d.names = append(d.names, name)
+		breaker := resilience.NewBreaker("dns/"+name, d.breakerThreshold, d.breakerCooldown)
+		// the retries wait for the limiter, the lookups refused by the
+		// breaker do not
+		d.resolvers = append(d.resolvers, withCache(withResilience(withRateLimit(withTimeout(res, d.timeout), d.limiter), breaker, d.retryBudget), name, d.cache))
+	}
+
+	return d
 }
 
-func (d DNSChecker) CheckDomainSPF(ctx context.Context, domain *corev1alpha1.Domain) DNSCheckStats {
-	return d.checkDNS(ctx, domain, checkDomainSPF)
+var _ DNSChecker = &ResolverChecker{}
+
+// checkDetail describes what a resolver returned beyond the outcome.
+type checkDetail struct {
+	// value is a detail of the verified record, such as the DMARC policy.
+	value string
+	// observed are the records returned for the checked name.
+	observed []string
+	// spf is the evaluation of the SPF record, for the SPF check only.
+	spf *SPFEvaluation
 }
 
-func (d DNSChecker) CheckDomainStatsDNS(ctx context.Context, domain *corev1alpha1.Domain) DNSCheckStats {
-	return d.checkDNS(ctx, domain, checkDomainStatsDNS)
+type checkFunc func(ctx context.Context, r resolver.Resolver, domain *corev1alpha1.Domain) (bool, checkDetail, error)
+
+func (d ResolverChecker) CheckDomainDKIM(ctx context.Context, domain *corev1alpha1.Domain) DNSCheckStats {
+	stats := d.checkDNS(ctx, domain, checkDomainDKIM)
+	stats.Expected = Record{Type: "TXT", Name: dkimName(domain), Value: dkimValue(domain)}
+	return stats
 }
 
-func (d DNSChecker) checkDNS(ctx context.Context, domain *corev1alpha1.Domain, checkFunc checkFunc) DNSCheckStats {
-	result := DNSCheckStats{}
+func (d ResolverChecker) CheckDomainSPF(ctx context.Context, domain *corev1alpha1.Domain) DNSCheckStats {
+	stats := d.checkDNS(ctx, domain, d.checkDomainSPF)
+	stats.Expected = Record{Type: "TXT", Name: domain.Spec.DomainName, Value: fmt.Sprintf("v=spf1 include:%s ~all", d.expectedSPFInclude(domain))}
+	return stats
+}
+
+func (d ResolverChecker) CheckDomainStatsDNS(ctx context.Context, domain *corev1alpha1.Domain) DNSCheckStats {
+	stats := d.checkDNS(ctx, domain, checkDomainStatsDNS)
+	stats.Expected = Record{Type: "CNAME", Name: domain.Spec.StatsHostOrDefault(), Value: domain.Spec.BaseDomain}
+	return stats
+}
+
+func (d ResolverChecker) CheckDomainMX(ctx context.Context, domain *corev1alpha1.Domain) DNSCheckStats {
+	stats := d.checkDNS(ctx, domain, d.checkDomainMX)
+	stats.Expected = Record{Type: "MX", Name: domain.Spec.BounceHostOrDefault(), Value: d.expectedMXHost(domain)}
+	return stats
+}
+
+func (d ResolverChecker) CheckDomainDMARC(ctx context.Context, domain *corev1alpha1.Domain) DNSCheckStats {
+	stats := d.checkDNS(ctx, domain, checkDomainDMARC)
+	stats.Expected = Record{Type: "TXT", Name: dmarcName(domain), Value: dmarcValue(domain)}
+	return stats
+}
+
+// CheckDomainTLSRPT verifies that the TLS-RPT record of the domain lists
+// the report URIs of spec.tlsRPT.
+func (d ResolverChecker) CheckDomainTLSRPT(ctx context.Context, domain *corev1alpha1.Domain) DNSCheckStats {
+	stats := d.checkDNS(ctx, domain, checkDomainTLSRPT)
+	stats.Expected = Record{Type: "TXT", Name: tlsRPTName(domain), Value: tlsRPTValue(domain)}
+	return stats
+}
+
+func (d ResolverChecker) checkDNS(ctx context.Context, domain *corev1alpha1.Domain, checkFunc checkFunc) DNSCheckStats {
+	result := DNSCheckStats{Quorum: d.quorum}
+	errs := []error{}
+	values := map[string]int{}
+	observed := map[string]bool{}
 
 	wg := sync.WaitGroup{}
 	m := sync.Mutex{}
@@ -65,88 +338,350 @@ func (d DNSChecker) checkDNS(ctx context.Context, domain *corev1alpha1.Domain, c
 	innertCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	for _, res := range d.resolvers {
+	for i, res := range d.resolvers {
 		wg.Add(1)
 
-		go func(r resolver.Resolver) {
+		go func(name string, r resolver.Resolver) {
 			defer wg.Done()
 
-			status, err := checkFunc(innertCtx, r, domain)
+			status, detail, err := checkFunc(innertCtx, r, domain)
 			m.Lock()
+			defer m.Unlock()
+			for _, o := range detail.observed {
+				observed[o] = true
+			}
+			if spf := detail.spf; spf != nil && worseSPF(spf, result.SPF) {
+				result.SPF = spf
+			}
+			if isMismatch(err) {
+				result.Reason = err.Error()
+				err = nil
+			}
 			if err != nil {
 				result.CntErr += 1
+				errs = append(errs, err)
 			}
 			if status {
 				result.CntOK += 1
+				if detail.value != "" {
+					values[detail.value]++
+				}
 			} else {
 				result.CntKO += 1
 			}
-			m.Unlock()
-		}(res)
+			result.Resolvers = append(result.Resolvers, ResolverResult{Resolver: name, OK: status, Err: err})
+		}(d.names[i], res)
 	}
 
 	wg.Wait()
 
+	sort.Slice(result.Resolvers, func(i, j int) bool {
+		return result.Resolvers[i].Resolver < result.Resolvers[j].Resolver
+	})
+
+	result.Err = errors.Join(errs...)
+	result.Value = mostCommon(values)
+	result.Observed = sortedKeys(observed)
+
 	return result
 }
 
-func checkDomainDKim(ctx context.Context, r resolver.Resolver, domain *corev1alpha1.Domain) (bool, error) {
-	sub := fmt.Sprintf("%s._domainkey.%s", domain.Spec.DKim.Selector, domain.Spec.DomainName)
+// worseSPF reports whether the evaluation a of a resolver is more of a
+// concern than b.
+func worseSPF(a, b *SPFEvaluation) bool {
+	if b == nil || (a.PermError != "") != (b.PermError != "") {
+		return b == nil || a.PermError != ""
+	}
+	return a.Lookups > b.Lookups
+}
+
+func sortedKeys(m map[string]bool) []string {
+	if len(m) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
 
-	res, err := r.LookupTXT(ctx, sub)
+// mostCommon returns the value with the highest count, preferring the
+// smallest one on ties so that the result is stable.
+func mostCommon(counts map[string]int) string {
+	best := ""
+	for value, cnt := range counts {
+		if cnt > counts[best] || cnt == counts[best] && value < best {
+			best = value
+		}
+	}
+	return best
+}
+
+func dkimName(domain *corev1alpha1.Domain) string {
+	return fmt.Sprintf("%s._domainkey.%s", domain.DKIMSelector(), domain.Spec.DomainName)
+}
+
+func dkimValue(domain *corev1alpha1.Domain) string {
+	return dkim.Record(dkim.KeyType(domain.DKIMKeyType()), domain.DKIMPublicKey())
+}
+
+func checkDomainDKIM(ctx context.Context, r resolver.Resolver, domain *corev1alpha1.Domain) (bool, checkDetail, error) {
+	res, err := r.LookupTXT(ctx, dkimName(domain))
 	if err != nil {
 		if dnsErr, ok := err.(*net.DNSError); ok {
 			if dnsErr.IsNotFound {
-				return false, nil
+				return false, checkDetail{}, nil
 			}
 		}
 
-		return false, err
+		return false, checkDetail{}, err
 	}
 
+	detail := checkDetail{observed: res}
 	for _, txt := range res {
-		if txt == fmt.Sprintf("k=rsa; p=%s", domain.Spec.DKim.PublicKey) {
-			return true, nil
+		if txt == dkimValue(domain) {
+			return true, detail, nil
 		}
 	}
 
-	return false, nil
+	return false, detail, nil
+}
+
+func (d ResolverChecker) expectedSPFInclude(domain *corev1alpha1.Domain) string {
+	if d.spfInclude != "" {
+		return d.spfInclude
+	}
+	return domain.Spec.BaseDomain
 }
 
-func checkDomainSPF(ctx context.Context, r resolver.Resolver, domain *corev1alpha1.Domain) (bool, error) {
-	res, err := r.LookupTXT(ctx, domain.Spec.DomainName)
+// checkDomainSPF evaluates the SPF record of the domain, which must have the
+// expected include and no permerror.
+func (d ResolverChecker) checkDomainSPF(ctx context.Context, r resolver.Resolver, domain *corev1alpha1.Domain) (bool, checkDetail, error) {
+	e := &spfEvaluation{r: r, expected: d.expectedSPFInclude(domain)}
+	err := e.evaluate(ctx, domain.Spec.DomainName, true)
+
+	detail := checkDetail{observed: e.observed}
+	if err != nil && !isMismatch(err) {
+		return false, detail, err
+	}
+
+	detail.spf = &SPFEvaluation{Lookups: e.lookups}
+	if isPermError(err) {
+		detail.spf.PermError = err.Error()
+	}
+	if err != nil {
+		return false, detail, err
+	}
+
+	switch qualifier, _ := splitQualifier(e.include); {
+	case e.include == "":
+		return false, detail, mismatchf("%s does not include %s", domain.Spec.DomainName, e.expected)
+	case qualifier != '+':
+		return false, detail, mismatchf("%s has %s instead of include:%s", e.includedBy, e.include, e.expected)
+	}
+	return true, detail, nil
+}
+
+// checkDomainStatsDNS verifies that every stats host is a CNAME to the base
+// domain.
+func checkDomainStatsDNS(ctx context.Context, r resolver.Resolver, domain *corev1alpha1.Domain) (bool, checkDetail, error) {
+	detail := checkDetail{}
+	var mismatch error
+	for _, host := range domain.Spec.StatsHosts() {
+		res, err := r.LookupCNAME(ctx, host)
+		if err != nil {
+			if dnsErr, ok := err.(*net.DNSError); ok && dnsErr.IsNotFound {
+				if mismatch == nil {
+					mismatch = mismatchf("%s has no CNAME record", host)
+				}
+				continue
+			}
+
+			return false, detail, err
+		}
+
+		target := strings.TrimSuffix(res, ".")
+		detail.observed = append(detail.observed, target)
+		if !sameHost(target, domain.Spec.BaseDomain) && mismatch == nil {
+			mismatch = mismatchf("%s is a CNAME to %s instead of %s", host, target, domain.Spec.BaseDomain)
+		}
+	}
+
+	return mismatch == nil, detail, mismatch
+}
+
+func (d ResolverChecker) expectedMXHost(domain *corev1alpha1.Domain) string {
+	if d.mxHost != "" {
+		return d.mxHost
+	}
+	return domain.Spec.BaseDomain
+}
+
+func (d ResolverChecker) checkDomainMX(ctx context.Context, r resolver.Resolver, domain *corev1alpha1.Domain) (bool, checkDetail, error) {
+	expected := d.expectedMXHost(domain)
+
+	res, err := r.LookupMX(ctx, domain.Spec.BounceHostOrDefault())
 	if err != nil {
 		if dnsErr, ok := err.(*net.DNSError); ok {
 			if dnsErr.IsNotFound {
-				return false, nil
+				return false, checkDetail{}, nil
 			}
 		}
 
-		return false, err
+		return false, checkDetail{}, err
 	}
 
+	detail := checkDetail{}
+	found := false
+	for _, mx := range res {
+		host := strings.TrimSuffix(mx.Host, ".")
+		detail.observed = append(detail.observed, host)
+		if strings.EqualFold(host, strings.TrimSuffix(expected, ".")) {
+			found = true
+		}
+	}
+
+	return found, detail, nil
+}
+
+func dmarcName(domain *corev1alpha1.Domain) string {
+	return fmt.Sprintf("_dmarc.%s", domain.Spec.DomainName)
+}
+
+func dmarcValue(domain *corev1alpha1.Domain) string {
+	value := fmt.Sprintf("v=DMARC1; p=%s", domain.Spec.DMARC.PolicyOrDefault())
+	if dmarc := domain.Spec.DMARC; dmarc != nil && len(dmarc.ReportURIs) > 0 {
+		value += fmt.Sprintf("; rua=%s", strings.Join(dmarc.ReportURIs, ","))
+	}
+	return value
+}
+
+// checkDomainDMARC verifies that the domain publishes a DMARC record and
+// returns its policy.
+func checkDomainDMARC(ctx context.Context, r resolver.Resolver, domain *corev1alpha1.Domain) (bool, checkDetail, error) {
+	res, err := r.LookupTXT(ctx, dmarcName(domain))
+	if err != nil {
+		if dnsErr, ok := err.(*net.DNSError); ok {
+			if dnsErr.IsNotFound {
+				return false, checkDetail{}, nil
+			}
+		}
+
+		return false, checkDetail{}, err
+	}
+
+	detail := checkDetail{observed: res}
 	for _, txt := range res {
-		if strings.Contains(txt, fmt.Sprintf("include:%s", domain.Spec.BaseDomain)) {
-			return true, nil
+		if policy, ok := parseDMARCPolicy(txt); ok {
+			detail.value = policy
+			return true, detail, nil
 		}
 	}
 
-	return false, nil
+	return false, detail, nil
+}
+
+// parseDMARCPolicy returns the p tag of a DMARC record.
+func parseDMARCPolicy(txt string) (string, bool) {
+	tags := strings.Split(txt, ";")
+	if !strings.EqualFold(strings.TrimSpace(tags[0]), "v=DMARC1") {
+		return "", false
+	}
+
+	for _, tag := range tags[1:] {
+		name, value, _ := strings.Cut(tag, "=")
+		if strings.TrimSpace(name) != "p" {
+			continue
+		}
+
+		policy := strings.ToLower(strings.TrimSpace(value))
+		switch policy {
+		case "none", "quarantine", "reject":
+			return policy, true
+		}
+		return "", false
+	}
+
+	return "", false
+}
+
+func tlsRPTName(domain *corev1alpha1.Domain) string {
+	return fmt.Sprintf("_smtp._tls.%s", domain.Spec.DomainName)
+}
+
+func tlsRPTReportURIs(domain *corev1alpha1.Domain) []string {
+	if domain.Spec.TLSRPT == nil {
+		return nil
+	}
+	return domain.Spec.TLSRPT.ReportURIs
 }
 
-func checkDomainStatsDNS(ctx context.Context, r resolver.Resolver, domain *corev1alpha1.Domain) (bool, error) {
-	statsDomain := fmt.Sprintf("%s.%s", domain.Spec.StatsPrefix, domain.Spec.DomainName)
+func tlsRPTValue(domain *corev1alpha1.Domain) string {
+	return fmt.Sprintf("v=TLSRPTv1; rua=%s", strings.Join(tlsRPTReportURIs(domain), ","))
+}
 
-	res, err := r.LookupCNAME(ctx, statsDomain)
+// checkDomainTLSRPT verifies that the TLS-RPT record lists every expected
+// report URI and returns the URIs it lists.
+func checkDomainTLSRPT(ctx context.Context, r resolver.Resolver, domain *corev1alpha1.Domain) (bool, checkDetail, error) {
+	res, err := r.LookupTXT(ctx, tlsRPTName(domain))
 	if err != nil {
 		if dnsErr, ok := err.(*net.DNSError); ok {
 			if dnsErr.IsNotFound {
-				return false, nil
+				return false, checkDetail{}, nil
 			}
 		}
 
-		return false, err
+		return false, checkDetail{}, err
+	}
+
+	detail := checkDetail{observed: res}
+	for _, txt := range res {
+		uris, ok := parseTLSRPTReportURIs(txt)
+		if !ok {
+			continue
+		}
+
+		detail.value = strings.Join(uris, ",")
+		listed := map[string]bool{}
+		for _, uri := range uris {
+			listed[uri] = true
+		}
+		for _, uri := range tlsRPTReportURIs(domain) {
+			if !listed[uri] {
+				return false, detail, mismatchf("the TLS-RPT record does not report to %s", uri)
+			}
+		}
+		return true, detail, nil
+	}
+
+	return false, detail, nil
+}
+
+// parseTLSRPTReportURIs returns the rua tag of a TLS-RPT record.
+func parseTLSRPTReportURIs(txt string) ([]string, bool) {
+	tags := strings.Split(txt, ";")
+	if strings.TrimSpace(tags[0]) != "v=TLSRPTv1" {
+		return nil, false
+	}
+
+	for _, tag := range tags[1:] {
+		name, value, _ := strings.Cut(tag, "=")
+		if strings.TrimSpace(name) != "rua" {
+			continue
+		}
+
+		uris := []string{}
+		for _, uri := range strings.Split(value, ",") {
+			if uri = strings.TrimSpace(uri); uri != "" {
+				uris = append(uris, uri)
+			}
+		}
+		return uris, true
 	}
 
-	return res == domain.Spec.BaseDomain || res == domain.Spec.BaseDomain+".", nil
+	return nil, false
 }
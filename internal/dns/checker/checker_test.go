@@ -2,14 +2,33 @@ package checker_test
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	mockdns "github.com/foxcpp/go-mockdns"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
 	"github.com/kannon-email/k8nnon/internal/dns/checker"
 	"github.com/kannon-email/k8nnon/internal/dns/resolver"
+	"github.com/kannon-email/k8nnon/internal/mtasts"
+	"github.com/kannon-email/k8nnon/internal/resilience"
 )
 
 func TestDKimNotOk(t *testing.T) {
@@ -29,7 +48,7 @@ func TestDKimNotOk(t *testing.T) {
 
 	c := checker.NewDNSChecker(&r)
 
-	res := c.CheckDomainDKim(ctx, domain)
+	res := c.CheckDomainDKIM(ctx, domain)
 	assert.False(t, res.Result(), "should not have resolved DKIM")
 }
 
@@ -50,7 +69,7 @@ func TestDKimOk(t *testing.T) {
 
 	c := checker.NewDNSChecker(&r)
 
-	res := c.CheckDomainDKim(ctx, domain)
+	res := c.CheckDomainDKIM(ctx, domain)
 	assert.True(t, res.Result(), "should have resolved DKIM")
 }
 
@@ -91,10 +110,11 @@ func TestDKimMultipleOK(t *testing.T) {
 
 	c := checker.NewDNSChecker(r...)
 
-	res := c.CheckDomainDKim(ctx, domain)
+	res := c.CheckDomainDKIM(ctx, domain)
 	assert.True(t, res.Result(), "should have resolved DKIM")
 	assert.Equal(t, 2, res.CntOK)
 	assert.Equal(t, 1, res.CntKO)
+	assert.Equal(t, corev1alpha1.CheckStateVerified, res.State())
 }
 
 func TestDKimMultipleKO(t *testing.T) {
@@ -128,12 +148,39 @@ func TestDKimMultipleKO(t *testing.T) {
 
 	c := checker.NewDNSChecker(r...)
 
-	res := c.CheckDomainDKim(ctx, domain)
+	res := c.CheckDomainDKIM(ctx, domain)
 	assert.False(t, res.Result(), "should have resolved DKIM")
 	assert.Equal(t, 2, res.CntKO)
 	assert.Equal(t, 1, res.CntOK)
 }
 
+func TestDKimQuorum(t *testing.T) {
+	ctx := createContext(t)
+
+	found := &mockdns.Resolver{
+		Zones: map[string]mockdns.Zone{
+			"selector._domainkey.example.com.": {
+				TXT: []string{"k=rsa; p=publicKey"},
+			},
+		},
+	}
+	r := []resolver.Resolver{found, found, &mockdns.Resolver{Zones: map[string]mockdns.Zone{}}}
+
+	domain := createDomain(t)
+
+	res := checker.New(r, checker.WithQuorum(3)).CheckDomainDKIM(ctx, domain)
+	assert.False(t, res.Result(), "should require all the resolvers to agree")
+	assert.Equal(t, corev1alpha1.CheckStateMissing, res.State())
+	assert.Equal(t, []checker.ResolverResult{
+		{Resolver: "resolver-0", OK: true},
+		{Resolver: "resolver-1", OK: true},
+		{Resolver: "resolver-2", OK: false},
+	}, res.Resolvers)
+
+	res = checker.New(r, checker.WithQuorum(2)).CheckDomainDKIM(ctx, domain)
+	assert.True(t, res.Result(), "should be verified by two resolvers")
+}
+
 func TestDKIMWithoutHost(t *testing.T) {
 	ctx := createContext(t)
 
@@ -142,10 +189,33 @@ func TestDKIMWithoutHost(t *testing.T) {
 	domain := createDomain(t)
 	c := checker.NewDNSChecker(&r)
 
-	res := c.CheckDomainDKim(ctx, domain)
+	res := c.CheckDomainDKIM(ctx, domain)
 	assert.False(t, res.Result(), "should not have resolved SPF")
 }
 
+func TestDKIMGeneratedKey(t *testing.T) {
+	ctx := createContext(t)
+
+	r := mockdns.Resolver{
+		Zones: map[string]mockdns.Zone{
+			"selector._domainkey.example.com.": {
+				TXT: []string{
+					"k=ed25519; p=generatedKey",
+				},
+			},
+		},
+	}
+
+	domain := createDomain(t)
+	domain.Spec.DKIM.PublicKey = ""
+	domain.Status.DKIM = &corev1alpha1.DKIMStatus{DKIMKey: corev1alpha1.DKIMKey{KeyType: "ed25519", PublicKey: "generatedKey"}}
+
+	c := checker.NewDNSChecker(&r)
+
+	res := c.CheckDomainDKIM(ctx, domain)
+	assert.True(t, res.Result(), "should have resolved the generated DKIM key")
+}
+
 func TestSPFNotOk(t *testing.T) {
 	ctx := createContext(t)
 
@@ -188,6 +258,281 @@ func TestSPFOk(t *testing.T) {
 	assert.True(t, res.Result(), "should have resolved SPF")
 }
 
+func TestExpectedAndObservedRecords(t *testing.T) {
+	ctx := createContext(t)
+
+	r := mockdns.Resolver{
+		Zones: map[string]mockdns.Zone{
+			"example.com.": {
+				TXT: []string{
+					"v=spf1 include:mx.other.com ~all",
+				},
+			},
+			"selector._domainkey.example.com.": {
+				TXT: []string{
+					"k=rsa; p=wrongKey",
+				},
+			},
+			"stats.example.com": {
+				CNAME: "mx.other.com.",
+			},
+		},
+	}
+
+	domain := createDomain(t)
+	c := checker.NewDNSChecker(&r)
+
+	spf := c.CheckDomainSPF(ctx, domain)
+	assert.Equal(t, checker.Record{Type: "TXT", Name: "example.com", Value: "v=spf1 include:mx.example.com ~all"}, spf.Expected)
+	assert.Equal(t, []string{"v=spf1 include:mx.other.com ~all"}, spf.Observed)
+
+	dkim := c.CheckDomainDKIM(ctx, domain)
+	assert.Equal(t, checker.Record{Type: "TXT", Name: "selector._domainkey.example.com", Value: "k=rsa; p=publicKey"}, dkim.Expected)
+	assert.Equal(t, []string{"k=rsa; p=wrongKey"}, dkim.Observed)
+
+	stats := c.CheckDomainStatsDNS(ctx, domain)
+	assert.Equal(t, checker.Record{Type: "CNAME", Name: "stats.example.com", Value: "mx.example.com"}, stats.Expected)
+	assert.Equal(t, []string{"mx.other.com"}, stats.Observed)
+}
+
+func TestSPFNestedInclude(t *testing.T) {
+	ctx := createContext(t)
+
+	r := mockdns.Resolver{
+		Zones: map[string]mockdns.Zone{
+			"example.com.": {
+				TXT: []string{
+					"google-site-verification=abc",
+					"v=spf1 include:_spf.google.com redirect=_spf.example.com",
+				},
+			},
+			"_spf.google.com.": {
+				TXT: []string{
+					"v=spf1 ip4:35.190.247.0/24 ~all",
+				},
+			},
+			"_spf.example.com.": {
+				TXT: []string{
+					"v=spf1 include:_mail.example.com -all",
+				},
+			},
+			"_mail.example.com.": {
+				TXT: []string{
+					"v=spf1 include:mx.example.com ~all",
+				},
+			},
+		},
+	}
+
+	domain := createDomain(t)
+	c := checker.NewDNSChecker(&r)
+
+	res := c.CheckDomainSPF(ctx, domain)
+	assert.True(t, res.Result(), "should have resolved SPF through redirect and include")
+}
+
+func TestSPFInclude(t *testing.T) {
+	ctx := createContext(t)
+
+	r := mockdns.Resolver{
+		Zones: map[string]mockdns.Zone{
+			"example.com.": {
+				TXT: []string{
+					"v=spf1 include:spf.kannon.email ~all",
+				},
+			},
+		},
+	}
+
+	domain := createDomain(t)
+
+	c := checker.New([]resolver.Resolver{&r}, checker.WithSPFInclude("spf.kannon.email"))
+	assert.True(t, c.CheckDomainSPF(ctx, domain).Result(), "should have resolved the configured include")
+
+	c = checker.NewDNSChecker(&r)
+	assert.False(t, c.CheckDomainSPF(ctx, domain).Result(), "should expect the base domain by default")
+}
+
+func TestSPFMismatchReason(t *testing.T) {
+	tests := []struct {
+		name   string
+		txt    []string
+		reason string
+	}{
+		{
+			name:   "include as a substring",
+			txt:    []string{"v=spf1 include:mx.example.com.evil.com ~all"},
+			reason: "example.com does not include mx.example.com",
+		},
+		{
+			name:   "failing include",
+			txt:    []string{"v=spf1 -include:mx.example.com ~all"},
+			reason: "example.com has -include:mx.example.com instead of include:mx.example.com",
+		},
+		{
+			name:   "include after all",
+			txt:    []string{"v=spf1 ~all include:mx.example.com"},
+			reason: "example.com does not include mx.example.com",
+		},
+		{
+			name:   "not a SPF record",
+			txt:    []string{"include:mx.example.com"},
+			reason: "example.com has no SPF record",
+		},
+		{
+			name:   "multiple SPF records",
+			txt:    []string{"v=spf1 include:mx.example.com ~all", "v=spf1 -all"},
+			reason: "example.com has 2 SPF records",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := createContext(t)
+
+			r := mockdns.Resolver{
+				Zones: map[string]mockdns.Zone{
+					"example.com.": {TXT: tt.txt},
+					// an include without a SPF record is a permerror
+					"mx.example.com.evil.com.": {TXT: []string{"v=spf1 -all"}},
+				},
+			}
+
+			domain := createDomain(t)
+			c := checker.NewDNSChecker(&r)
+
+			res := c.CheckDomainSPF(ctx, domain)
+			assert.False(t, res.Result(), "should not have resolved SPF")
+			assert.Equal(t, corev1alpha1.CheckStateMissing, res.State())
+			assert.Equal(t, tt.reason, res.Reason)
+		})
+	}
+}
+
+func TestSPFLookupLimit(t *testing.T) {
+	ctx := createContext(t)
+
+	// every record includes the next one, the expected include is 12 lookups away
+	zones := map[string]mockdns.Zone{}
+	for i := 0; i < 12; i++ {
+		name := fmt.Sprintf("spf%d.example.com.", i)
+		if i == 0 {
+			name = "example.com."
+		}
+		zones[name] = mockdns.Zone{
+			TXT: []string{fmt.Sprintf("v=spf1 include:spf%d.example.com ~all", i+1)},
+		}
+	}
+	zones["spf12.example.com."] = mockdns.Zone{
+		TXT: []string{"v=spf1 include:mx.example.com ~all"},
+	}
+
+	r := mockdns.Resolver{Zones: zones}
+
+	domain := createDomain(t)
+	c := checker.NewDNSChecker(&r)
+
+	res := c.CheckDomainSPF(ctx, domain)
+	assert.False(t, res.Result(), "should stop after 10 lookups")
+	assert.Equal(t, "SPF record needs more than 10 DNS lookups", res.Reason)
+}
+
+func TestSPFEvaluation(t *testing.T) {
+	tests := []struct {
+		name      string
+		zones     map[string]mockdns.Zone
+		ok        bool
+		lookups   int
+		permError string
+	}{
+		{
+			name: "lookups of the whole record",
+			zones: map[string]mockdns.Zone{
+				"example.com.":      {TXT: []string{"v=spf1 include:mx.example.com a mx include:_spf.other.com redirect=_spf.example.com"}},
+				"_spf.other.com.":   {TXT: []string{"v=spf1 ip4:192.0.2.0/24 ip6:2001:db8::/32 exists:%{i}.other.com -all"}},
+				"_spf.example.com.": {TXT: []string{"v=spf1 ptr ~all"}},
+			},
+			ok:      true,
+			lookups: 7,
+		},
+		{
+			name: "too many lookups after the include",
+			zones: map[string]mockdns.Zone{
+				"example.com.": {TXT: []string{"v=spf1 include:mx.example.com a a a a a a a a a a ~all"}},
+			},
+			lookups:   11,
+			permError: "SPF record needs more than 10 DNS lookups",
+		},
+		{
+			name: "include without a SPF record",
+			zones: map[string]mockdns.Zone{
+				"example.com.":   {TXT: []string{"v=spf1 include:mx.example.com include:_spf.gone.com ~all"}},
+				"_spf.gone.com.": {TXT: []string{"google-site-verification=abc"}},
+			},
+			lookups:   2,
+			permError: "example.com includes _spf.gone.com, which has no SPF record",
+		},
+		{
+			name: "redirect without a SPF record",
+			zones: map[string]mockdns.Zone{
+				"example.com.": {TXT: []string{"v=spf1 include:mx.example.com redirect=_spf.example.com"}},
+			},
+			lookups:   2,
+			permError: "example.com redirects to _spf.example.com, which has no SPF record",
+		},
+		{
+			name: "multiple SPF records in an include",
+			zones: map[string]mockdns.Zone{
+				"example.com.":      {TXT: []string{"v=spf1 include:mx.example.com include:_spf.example.com ~all"}},
+				"_spf.example.com.": {TXT: []string{"v=spf1 -all", "v=spf1 ~all"}},
+			},
+			lookups:   2,
+			permError: "_spf.example.com has 2 SPF records",
+		},
+		{
+			name: "unknown mechanism",
+			zones: map[string]mockdns.Zone{
+				"example.com.": {TXT: []string{"v=spf1 include:mx.example.com ip:192.0.2.1 ~all"}},
+			},
+			permError: `example.com has the unknown mechanism "ip:192.0.2.1"`,
+		},
+		{
+			name: "invalid address after all",
+			zones: map[string]mockdns.Zone{
+				"example.com.": {TXT: []string{"v=spf1 include:mx.example.com ~all ip4:192.0.2.0/33"}},
+			},
+			permError: `example.com has the invalid mechanism "ip4:192.0.2.0/33"`,
+		},
+		{
+			name: "two redirects",
+			zones: map[string]mockdns.Zone{
+				"example.com.": {TXT: []string{"v=spf1 include:mx.example.com redirect=a.example.com redirect=b.example.com"}},
+			},
+			permError: "example.com has an invalid redirect modifier",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := createContext(t)
+
+			r := mockdns.Resolver{Zones: tt.zones}
+
+			domain := createDomain(t)
+			c := checker.NewDNSChecker(&r)
+
+			res := c.CheckDomainSPF(ctx, domain)
+			assert.Equal(t, tt.ok, res.Result())
+			require.NotNil(t, res.SPF)
+			assert.Equal(t, tt.lookups, res.SPF.Lookups)
+			assert.Equal(t, tt.permError, res.SPF.PermError)
+			if tt.permError != "" {
+				assert.Equal(t, tt.permError, res.Reason)
+			}
+		})
+	}
+}
+
 func TestStatsWithoutHost(t *testing.T) {
 	ctx := createContext(t)
 
@@ -238,6 +583,54 @@ func TestStatsOk(t *testing.T) {
 	assert.True(t, res.Result(), "should have resolved CNAME")
 }
 
+func TestStatsMultipleHosts(t *testing.T) {
+	ctx := createContext(t)
+
+	zones := map[string]mockdns.Zone{
+		"click.example.com": {CNAME: "mx.example.com"},
+	}
+	r := mockdns.Resolver{Zones: zones}
+
+	domain := createDomain(t)
+	domain.Spec.Stats = &corev1alpha1.DomainStatsSpec{Hosts: []string{"click.example.com", "open.example.com"}}
+
+	c := checker.NewDNSChecker(&r)
+
+	res := c.CheckDomainStatsDNS(ctx, domain)
+	assert.False(t, res.Result(), "should need the CNAME of every host")
+	assert.Equal(t, "open.example.com has no CNAME record", res.Reason)
+	assert.Equal(t, checker.Record{Type: "CNAME", Name: "click.example.com", Value: "mx.example.com"}, res.Expected)
+
+	zones["open.example.com"] = mockdns.Zone{CNAME: "mx.other.com"}
+	res = c.CheckDomainStatsDNS(ctx, domain)
+	assert.False(t, res.Result())
+	assert.Equal(t, "open.example.com is a CNAME to mx.other.com instead of mx.example.com", res.Reason)
+
+	zones["open.example.com"] = mockdns.Zone{CNAME: "mx.example.com"}
+	res = c.CheckDomainStatsDNS(checker.WithoutCache(ctx), domain)
+	assert.True(t, res.Result(), "should have resolved the CNAME of both hosts")
+}
+
+func TestStatsCustomHostOk(t *testing.T) {
+	ctx := createContext(t)
+
+	r := mockdns.Resolver{
+		Zones: map[string]mockdns.Zone{
+			"track.example.com": {
+				CNAME: "mx.example.com",
+			},
+		},
+	}
+
+	domain := createDomain(t)
+	domain.Spec.StatsHost = "track.example.com"
+
+	c := checker.NewDNSChecker(&r)
+
+	res := c.CheckDomainStatsDNS(ctx, domain)
+	assert.True(t, res.Result(), "should have resolved CNAME of the custom stats host")
+}
+
 func TestSPFNWithoutHost(t *testing.T) {
 	ctx := createContext(t)
 
@@ -250,41 +643,1109 @@ func TestSPFNWithoutHost(t *testing.T) {
 	assert.False(t, res.Result(), "should not have resolved SPF")
 }
 
-func TestLoopupCname(t *testing.T) {
+func TestDMARCOk(t *testing.T) {
 	ctx := createContext(t)
 
 	r := mockdns.Resolver{
 		Zones: map[string]mockdns.Zone{
-			"stats.example.com": {
-				CNAME: "mx.example.com",
+			"_dmarc.example.com.": {
+				TXT: []string{
+					"v=DMARC1; p=Quarantine; rua=mailto:dmarc@example.com",
+				},
 			},
 		},
 	}
 
-	res, err := r.LookupCNAME(ctx, "stats.example.com")
-	assert.Nil(t, err)
-	assert.Equal(t, "mx.example.com", res, "should have resolved CNAME")
+	domain := createDomain(t)
+	c := checker.NewDNSChecker(&r)
 
+	res := c.CheckDomainDMARC(ctx, domain)
+	assert.True(t, res.Result(), "should have resolved DMARC")
+	assert.Equal(t, "quarantine", res.Value)
 }
 
-func createDomain(t *testing.T) *corev1alpha1.Domain {
-	t.Helper()
+func TestDMARCNotOk(t *testing.T) {
+	tests := []struct {
+		name string
+		txt  []string
+	}{
+		{"missing policy", []string{"v=DMARC1; rua=mailto:dmarc@example.com"}},
+		{"invalid policy", []string{"v=DMARC1; p=monitor"}},
+		{"not a DMARC record", []string{"p=reject"}},
+	}
 
-	return &corev1alpha1.Domain{
-		Spec: corev1alpha1.DomainSpec{
-			DomainName: "example.com",
-			DKim: corev1alpha1.DKim{
-				Selector:  "selector",
-				PublicKey: "publicKey",
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := createContext(t)
+
+			r := mockdns.Resolver{
+				Zones: map[string]mockdns.Zone{
+					"_dmarc.example.com.": {TXT: tt.txt},
+				},
+			}
+
+			domain := createDomain(t)
+			c := checker.NewDNSChecker(&r)
+
+			res := c.CheckDomainDMARC(ctx, domain)
+			assert.False(t, res.Result(), "should not have resolved DMARC")
+			assert.Empty(t, res.Value)
+		})
+	}
+}
+
+func TestDMARCWithoutHost(t *testing.T) {
+	ctx := createContext(t)
+
+	r := mockdns.Resolver{}
+
+	domain := createDomain(t)
+	c := checker.NewDNSChecker(&r)
+
+	res := c.CheckDomainDMARC(ctx, domain)
+	assert.False(t, res.Result(), "should not have resolved DMARC")
+	assert.Equal(t, corev1alpha1.CheckStateMissing, res.State())
+}
+
+func TestDMARCExpected(t *testing.T) {
+	ctx := createContext(t)
+
+	domain := createDomain(t)
+	c := checker.NewDNSChecker(&mockdns.Resolver{})
+
+	res := c.CheckDomainDMARC(ctx, domain)
+	assert.Equal(t, checker.Record{Type: "TXT", Name: "_dmarc.example.com", Value: "v=DMARC1; p=none"}, res.Expected)
+
+	domain.Spec.DMARC = &corev1alpha1.DMARCSpec{
+		Policy:     "reject",
+		ReportURIs: []string{"mailto:dmarc@example.com", "https://reports.example.org/dmarc"},
+	}
+	res = c.CheckDomainDMARC(ctx, domain)
+	assert.Equal(t, "v=DMARC1; p=reject; rua=mailto:dmarc@example.com,https://reports.example.org/dmarc", res.Expected.Value)
+}
+
+func TestMXOk(t *testing.T) {
+	ctx := createContext(t)
+
+	r := mockdns.Resolver{
+		Zones: map[string]mockdns.Zone{
+			"example.com.": {
+				MX: []net.MX{
+					{Host: "mx.other.com.", Pref: 20},
+					{Host: "mx.example.com.", Pref: 10},
+				},
 			},
-			BaseDomain:  "mx.example.com",
-			StatsPrefix: "stats",
 		},
 	}
+
+	domain := createDomain(t)
+
+	c := checker.NewDNSChecker(&r)
+
+	res := c.CheckDomainMX(ctx, domain)
+	assert.True(t, res.Result(), "should have resolved MX")
+	assert.Equal(t, []string{"mx.example.com", "mx.other.com"}, res.Observed)
 }
 
-func createContext(t *testing.T) context.Context {
-	t.Helper()
+func TestMXNotOk(t *testing.T) {
+	ctx := createContext(t)
 
-	return context.Background()
+	r := mockdns.Resolver{
+		Zones: map[string]mockdns.Zone{
+			"example.com.": {
+				MX: []net.MX{
+					{Host: "mx.other.com.", Pref: 10},
+				},
+			},
+		},
+	}
+
+	domain := createDomain(t)
+
+	c := checker.NewDNSChecker(&r)
+
+	res := c.CheckDomainMX(ctx, domain)
+	assert.False(t, res.Result(), "should not have resolved MX")
+}
+
+func TestMXBounceHost(t *testing.T) {
+	ctx := createContext(t)
+
+	r := mockdns.Resolver{
+		Zones: map[string]mockdns.Zone{
+			"example.com.": {
+				MX: []net.MX{
+					{Host: "mx.other.com.", Pref: 10},
+				},
+			},
+			"bounces.example.com.": {
+				MX: []net.MX{
+					{Host: "mx.example.com.", Pref: 10},
+				},
+			},
+		},
+	}
+
+	domain := createDomain(t)
+	domain.Spec.BounceHost = "bounces.example.com"
+
+	c := checker.NewDNSChecker(&r)
+
+	res := c.CheckDomainMX(ctx, domain)
+	assert.True(t, res.Result(), "should have resolved MX of the bounce host")
+	assert.Equal(t, []string{"mx.example.com"}, res.Observed)
+}
+
+func TestMXConfiguredHost(t *testing.T) {
+	ctx := createContext(t)
+
+	r := mockdns.Resolver{
+		Zones: map[string]mockdns.Zone{
+			"example.com.": {
+				MX: []net.MX{
+					{Host: "inbound.kannon.email.", Pref: 10},
+				},
+			},
+		},
+	}
+
+	domain := createDomain(t)
+
+	c := checker.New([]resolver.Resolver{&r}, checker.WithMXHost("inbound.kannon.email"))
+
+	res := c.CheckDomainMX(ctx, domain)
+	assert.True(t, res.Result(), "should have resolved MX")
+}
+
+func TestMXWithoutHost(t *testing.T) {
+	ctx := createContext(t)
+
+	r := mockdns.Resolver{}
+
+	domain := createDomain(t)
+	c := checker.NewDNSChecker(&r)
+
+	res := c.CheckDomainMX(ctx, domain)
+	assert.False(t, res.Result(), "should not have resolved MX")
+	assert.Nil(t, res.Err, "missing records should not be an error")
+	assert.Equal(t, corev1alpha1.CheckStateMissing, res.State())
+}
+
+func TestLoopupCname(t *testing.T) {
+	ctx := createContext(t)
+
+	r := mockdns.Resolver{
+		Zones: map[string]mockdns.Zone{
+			"stats.example.com": {
+				CNAME: "mx.example.com",
+			},
+		},
+	}
+
+	res, err := r.LookupCNAME(ctx, "stats.example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, "mx.example.com", res, "should have resolved CNAME")
+
+}
+
+func createDomain(t *testing.T) *corev1alpha1.Domain {
+	t.Helper()
+
+	return &corev1alpha1.Domain{
+		Spec: corev1alpha1.DomainSpec{
+			DomainName: "example.com",
+			DKIM: corev1alpha1.DKIM{
+				Selector:  "selector",
+				PublicKey: "publicKey",
+			},
+			BaseDomain:  "mx.example.com",
+			StatsPrefix: "stats",
+		},
+	}
+}
+
+func createContext(t *testing.T) context.Context {
+	t.Helper()
+
+	return context.Background()
+}
+
+type blockingResolver struct{}
+
+func (blockingResolver) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (blockingResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (blockingResolver) LookupCNAME(ctx context.Context, name string) (string, error) {
+	<-ctx.Done()
+	return "", ctx.Err()
+}
+
+func (blockingResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (blockingResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestPing(t *testing.T) {
+	ctx := createContext(t)
+
+	ok := &mockdns.Resolver{}
+	c := checker.New([]resolver.Resolver{ok, ok, blockingResolver{}}, checker.WithLookupTimeout(50*time.Millisecond))
+	assert.Nil(t, c.Ping(ctx, checker.DefaultProbeDomain), "should be ready when most resolvers answer")
+
+	c = checker.New([]resolver.Resolver{ok, blockingResolver{}}, checker.WithLookupTimeout(50*time.Millisecond))
+	assert.ErrorIs(t, c.Ping(ctx, checker.DefaultProbeDomain), checker.ErrLookupTimeout)
+
+	c = checker.New([]resolver.Resolver{ok, blockingResolver{}, blockingResolver{}}, checker.WithLookupTimeout(50*time.Millisecond), checker.WithQuorum(1))
+	assert.Nil(t, c.Ping(ctx, checker.DefaultProbeDomain), "should be ready when the quorum answers")
+}
+
+type pingerFunc func(ctx context.Context, probeDomain string) error
+
+func (f pingerFunc) Ping(ctx context.Context, probeDomain string) error {
+	return f(ctx, probeDomain)
+}
+
+func TestCanary(t *testing.T) {
+	var failing atomic.Bool
+	var probed string
+	pinger := pingerFunc(func(_ context.Context, probeDomain string) error {
+		probed = probeDomain
+		if failing.Load() {
+			return errors.New("unreachable")
+		}
+		return nil
+	})
+
+	canary := checker.NewCanary(pinger, "canary.example.com", 50*time.Millisecond)
+	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+
+	assert.NoError(t, canary.ReadyCheck(req))
+	assert.NoError(t, canary.LiveCheck(req))
+	assert.Equal(t, "canary.example.com", probed)
+
+	// the readiness fails at once, the liveness after the threshold
+	failing.Store(true)
+	assert.Error(t, canary.ReadyCheck(req))
+	assert.NoError(t, canary.LiveCheck(req), "the liveness should tolerate short failures")
+	time.Sleep(60 * time.Millisecond)
+	err := canary.LiveCheck(req)
+	assert.ErrorContains(t, err, "the DNS resolution has been failing since")
+	assert.ErrorContains(t, err, "unreachable")
+
+	// a successful ping resets the failures
+	failing.Store(false)
+	assert.NoError(t, canary.ReadyCheck(req))
+	failing.Store(true)
+	assert.NoError(t, canary.LiveCheck(req))
+}
+
+func TestLookupTimeout(t *testing.T) {
+	ctx := createContext(t)
+
+	domain := createDomain(t)
+	c := checker.New([]resolver.Resolver{blockingResolver{}}, checker.WithLookupTimeout(50*time.Millisecond))
+
+	start := time.Now()
+	res := c.CheckDomainDKIM(ctx, domain)
+
+	assert.Less(t, time.Since(start), time.Second, "should have returned within the timeout")
+	assert.False(t, res.Result(), "should not have resolved DKIM")
+	assert.Equal(t, 1, res.CntErr)
+	assert.ErrorIs(t, res.Err, checker.ErrLookupTimeout)
+	assert.Equal(t, corev1alpha1.CheckStateUnknown, res.State())
+}
+
+func TestLookupTimeoutNonResponsiveServer(t *testing.T) {
+	ctx := createContext(t)
+
+	// a UDP socket that never answers behaves like a black hole resolver
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	r := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			d := net.Dialer{}
+			return d.DialContext(ctx, "udp", conn.LocalAddr().String())
+		},
+	}
+
+	domain := createDomain(t)
+	c := checker.New([]resolver.Resolver{r}, checker.WithLookupTimeout(100*time.Millisecond))
+
+	start := time.Now()
+	res := c.CheckDomainSPF(ctx, domain)
+
+	assert.Less(t, time.Since(start), time.Second, "should have returned within the timeout")
+	assert.False(t, res.Result(), "should not have resolved SPF")
+	assert.ErrorIs(t, res.Err, checker.ErrLookupTimeout)
+}
+
+func TestErrorClass(t *testing.T) {
+	cases := map[string]error{
+		checker.ErrorClassTimeout:       fmt.Errorf("lookup example.com: %w", checker.ErrLookupTimeout),
+		checker.ErrorClassNotFound:      &net.DNSError{Err: "no such host", IsNotFound: true},
+		checker.ErrorClassServerFailure: errors.Join(&net.DNSError{Err: "server misbehaving", IsTemporary: true}, errors.New("refused")),
+		checker.ErrorClassTemporary:     &net.DNSError{Err: "read: connection reset by peer", IsTemporary: true},
+		checker.ErrorClassUnavailable:   fmt.Errorf("lookup example.com: %w", resilience.ErrOpen),
+		checker.ErrorClassOther:         errors.New("boom"),
+	}
+
+	for class, err := range cases {
+		assert.Equal(t, class, checker.ErrorClass(err), err.Error())
+	}
+}
+
+func TestDKimDefaultSelector(t *testing.T) {
+	ctx := createContext(t)
+
+	r := mockdns.Resolver{
+		Zones: map[string]mockdns.Zone{
+			"kannon._domainkey.example.com.": {
+				TXT: []string{
+					"k=rsa; p=publicKey",
+				},
+			},
+		},
+	}
+
+	domain := createDomain(t)
+	domain.Spec.DKIM.Selector = ""
+
+	c := checker.NewDNSChecker(&r)
+
+	res := c.CheckDomainDKIM(ctx, domain)
+	assert.True(t, res.Result(), "should have resolved DKIM with the default selector")
+}
+
+type countingResolver struct {
+	resolver.Resolver
+	calls int32
+}
+
+func (c *countingResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	atomic.AddInt32(&c.calls, 1)
+	return c.Resolver.LookupTXT(ctx, name)
+}
+
+func TestLookupCacheReusesResults(t *testing.T) {
+	ctx := createContext(t)
+
+	r := &countingResolver{Resolver: &mockdns.Resolver{
+		Zones: map[string]mockdns.Zone{
+			"example.com.": {
+				TXT: []string{
+					"v=spf1 include:mx.example.com ~all",
+				},
+			},
+		},
+	}}
+
+	domain := createDomain(t)
+	c := checker.New([]resolver.Resolver{r}, checker.WithCacheTTL(time.Minute))
+
+	assert.True(t, c.CheckDomainSPF(ctx, domain).Result(), "should have resolved SPF")
+	assert.True(t, c.CheckDomainSPF(ctx, domain).Result(), "should have resolved SPF from cache")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&r.calls))
+}
+
+func TestLookupCacheExpires(t *testing.T) {
+	ctx := createContext(t)
+
+	r := &countingResolver{Resolver: &mockdns.Resolver{
+		Zones: map[string]mockdns.Zone{
+			"example.com.": {
+				TXT: []string{
+					"v=spf1 include:mx.example.com ~all",
+				},
+			},
+		},
+	}}
+
+	domain := createDomain(t)
+	c := checker.New([]resolver.Resolver{r}, checker.WithCacheTTL(20*time.Millisecond))
+
+	c.CheckDomainSPF(ctx, domain)
+	time.Sleep(40 * time.Millisecond)
+	c.CheckDomainSPF(ctx, domain)
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&r.calls))
+}
+
+func TestLookupCacheBypass(t *testing.T) {
+	ctx := createContext(t)
+
+	r := &countingResolver{Resolver: &mockdns.Resolver{
+		Zones: map[string]mockdns.Zone{
+			"example.com.": {
+				TXT: []string{
+					"v=spf1 include:mx.example.com ~all",
+				},
+			},
+		},
+	}}
+
+	domain := createDomain(t)
+	c := checker.New([]resolver.Resolver{r}, checker.WithCacheTTL(time.Minute))
+
+	c.CheckDomainSPF(ctx, domain)
+	c.CheckDomainSPF(checker.WithoutCache(ctx), domain)
+	c.CheckDomainSPF(ctx, domain)
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&r.calls))
+}
+
+func TestLookupCacheSkipsErrors(t *testing.T) {
+	ctx := createContext(t)
+
+	r := &countingResolver{Resolver: &mockdns.Resolver{}}
+
+	domain := createDomain(t)
+	c := checker.New([]resolver.Resolver{r}, checker.WithCacheTTL(time.Minute))
+
+	c.CheckDomainSPF(ctx, domain)
+	c.CheckDomainSPF(ctx, domain)
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&r.calls))
+}
+
+func TestLookupCacheRespectsAnswerTTL(t *testing.T) {
+	ctx := createContext(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"Status":0,"Answer":[{"name":"example.com.","type":16,"data":"\"v=spf1 include:mx.example.com ~all\"","TTL":0}]}`)
+	}))
+	t.Cleanup(srv.Close)
+
+	r := &countingResolver{Resolver: resolver.NewDoHResolver(srv.URL, srv.Client())}
+
+	domain := createDomain(t)
+	c := checker.New([]resolver.Resolver{r}, checker.WithCacheTTL(time.Minute))
+
+	assert.True(t, c.CheckDomainSPF(ctx, domain).Result(), "should have resolved SPF")
+	c.CheckDomainSPF(ctx, domain)
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&r.calls), "should not cache answers with a zero TTL")
+}
+
+func TestLookupCacheSharedByCheckers(t *testing.T) {
+	ctx := createContext(t)
+
+	r := &countingResolver{Resolver: &mockdns.Resolver{
+		Zones: map[string]mockdns.Zone{
+			"example.com.": {
+				TXT: []string{
+					"v=spf1 include:mx.example.com ~all",
+				},
+			},
+		},
+	}}
+
+	domain := createDomain(t)
+	cache := checker.NewLookupCache(time.Minute, 0)
+	c1 := checker.New([]resolver.Resolver{r}, checker.WithLookupCache(cache))
+	c2 := checker.New([]resolver.Resolver{r}, checker.WithLookupCache(cache))
+
+	assert.True(t, c1.CheckDomainSPF(ctx, domain).Result(), "should have resolved SPF")
+	assert.True(t, c2.CheckDomainSPF(ctx, domain).Result(), "should have resolved SPF from the shared cache")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&r.calls))
+}
+
+func TestLookupCacheSharedQueries(t *testing.T) {
+	ctx := createContext(t)
+
+	r := &countingResolver{Resolver: &mockdns.Resolver{
+		Zones: map[string]mockdns.Zone{
+			"example.com.": {
+				TXT: []string{
+					"v=spf1 include:_spf.provider.com include:mx.example.com ~all",
+				},
+			},
+			"_spf.provider.com.": {
+				TXT: []string{
+					"v=spf1 ip4:192.0.2.0/24 -all",
+				},
+			},
+		},
+	}}
+
+	domain := createDomain(t)
+	c := checker.New([]resolver.Resolver{r}, checker.WithLookupCache(checker.NewLookupCache(0, time.Minute)))
+
+	assert.True(t, c.CheckDomainSPF(ctx, domain).Result(), "should have resolved SPF")
+	assert.True(t, c.CheckDomainSPF(ctx, domain).Result(), "should have resolved SPF")
+	assert.Equal(t, int32(3), atomic.LoadInt32(&r.calls), "only the include target should have been cached")
+}
+
+func TestRateLimit(t *testing.T) {
+	ctx, cancel := context.WithTimeout(createContext(t), 100*time.Millisecond)
+	defer cancel()
+
+	zones := map[string]mockdns.Zone{
+		"example.com.": {
+			TXT: []string{
+				"v=spf1 include:mx.example.com ~all",
+			},
+		},
+	}
+	r1 := &countingResolver{Resolver: &mockdns.Resolver{Zones: zones}}
+	r2 := &countingResolver{Resolver: &mockdns.Resolver{Zones: zones}}
+
+	domain := createDomain(t)
+	c := checker.New([]resolver.Resolver{r1, r2}, checker.WithRateLimit(1, 1))
+
+	stats := c.CheckDomainSPF(ctx, domain)
+	assert.Equal(t, 1, stats.CntOK, "one lookup should fit in the burst")
+	assert.Equal(t, 1, stats.CntErr, "the other lookup should be rate limited")
+	assert.Equal(t, checker.ErrorClassTimeout, checker.ErrorClass(stats.Err))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&r1.calls)+atomic.LoadInt32(&r2.calls))
+}
+
+// failingResolver answers SERVFAIL to the first failures TXT lookups.
+type failingResolver struct {
+	countingResolver
+	failures int32
+}
+
+func (f *failingResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	if atomic.AddInt32(&f.calls, 1) <= f.failures {
+		return nil, &net.DNSError{Err: "server misbehaving", Name: name, IsTemporary: true}
+	}
+	return f.Resolver.LookupTXT(ctx, name)
+}
+
+func TestRetryBudget(t *testing.T) {
+	ctx := resilience.WithBudgetKey(createContext(t), "default/example")
+
+	zones := map[string]mockdns.Zone{
+		"example.com.": {
+			TXT: []string{
+				"v=spf1 include:mx.example.com ~all",
+			},
+		},
+	}
+	r := &failingResolver{countingResolver: countingResolver{Resolver: &mockdns.Resolver{Zones: zones}}, failures: 1}
+
+	domain := createDomain(t)
+	c := checker.New([]resolver.Resolver{r}, checker.WithCacheTTL(0), checker.WithRetryBudget(resilience.NewBudget(1, time.Hour)))
+
+	stats := c.CheckDomainSPF(ctx, domain)
+	assert.True(t, stats.Result(), "the SERVFAIL should have been retried")
+	assert.Equal(t, int32(2), atomic.LoadInt32(&r.calls))
+
+	// the budget of the domain is spent
+	r.failures = 3
+	stats = c.CheckDomainSPF(ctx, domain)
+	assert.False(t, stats.Result(), "should not have retried past the budget")
+	assert.Equal(t, checker.ErrorClassServerFailure, checker.ErrorClass(stats.Err))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&r.calls))
+}
+
+func TestRetryBudgetSkipsNotFound(t *testing.T) {
+	ctx := createContext(t)
+
+	r := &countingResolver{Resolver: &mockdns.Resolver{}}
+
+	domain := createDomain(t)
+	c := checker.New([]resolver.Resolver{r}, checker.WithRetryBudget(resilience.NewBudget(10, time.Hour)))
+
+	stats := c.CheckDomainSPF(ctx, domain)
+	assert.False(t, stats.Result())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&r.calls), "a not found answer should not have been retried")
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	ctx := createContext(t)
+
+	r := &failingResolver{countingResolver: countingResolver{Resolver: &mockdns.Resolver{}}, failures: 100}
+
+	domain := createDomain(t)
+	c := checker.New([]resolver.Resolver{r}, checker.WithCircuitBreaker(2, time.Hour))
+
+	for i := 0; i < 2; i++ {
+		stats := c.CheckDomainSPF(ctx, domain)
+		assert.Equal(t, checker.ErrorClassServerFailure, checker.ErrorClass(stats.Err))
+	}
+
+	stats := c.CheckDomainSPF(ctx, domain)
+	assert.Equal(t, 1, stats.CntErr)
+	assert.Equal(t, checker.ErrorClassUnavailable, checker.ErrorClass(stats.Err))
+	assert.ErrorIs(t, stats.Err, resilience.ErrOpen)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&r.calls), "the open breaker should have spared the resolver")
+}
+
+func createMTASTSDomain(t *testing.T) *corev1alpha1.Domain {
+	t.Helper()
+
+	domain := createDomain(t)
+	domain.Spec.MTASTS = &corev1alpha1.MTASTSSpec{Enabled: true, MX: []string{"mx.example.com"}}
+	return domain
+}
+
+func TestMTASTSRecord(t *testing.T) {
+	ctx := createContext(t)
+	domain := createMTASTSDomain(t)
+	id := mtasts.PolicyID(mtasts.DomainPolicy(domain))
+
+	tests := []struct {
+		name string
+		txt  string
+		ok   bool
+	}{
+		{"current id", "v=STSv1; id=" + id, true},
+		{"stale id", "v=STSv1; id=20230101", false},
+		{"not a policy record", "v=spf1 -all", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := mockdns.Resolver{
+				Zones: map[string]mockdns.Zone{
+					"_mta-sts.example.com.": {TXT: []string{tt.txt}},
+				},
+			}
+
+			res := checker.NewDNSChecker(&r).CheckDomainMTASTS(ctx, domain)
+			assert.Equal(t, tt.ok, res.Result())
+			assert.Equal(t, checker.Record{Type: "TXT", Name: "_mta-sts.example.com", Value: "v=STSv1; id=" + id}, res.Expected)
+		})
+	}
+}
+
+func TestMTASTSPolicy(t *testing.T) {
+	ctx := createContext(t)
+	domain := createMTASTSDomain(t)
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		state   corev1alpha1.CheckState
+	}{
+		{"served", func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "mta-sts.example.com", r.Host)
+			assert.Equal(t, mtasts.PolicyPath, r.URL.Path)
+			fmt.Fprint(w, strings.ReplaceAll(mtasts.DomainPolicy(domain), "\n", "\r\n"))
+		}, corev1alpha1.CheckStateVerified},
+		{"different policy", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, mtasts.Policy(mtasts.ModeEnforce, []string{"mx.example.com"}, time.Hour))
+		}, corev1alpha1.CheckStateMissing},
+		{"redirected", func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, "https://example.org/mta-sts.txt", http.StatusFound)
+		}, corev1alpha1.CheckStateUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := checker.New(nil, checker.WithHTTPClient(routedClient(t, tt.handler)))
+			res := c.CheckDomainMTASTSPolicy(ctx, domain)
+			assert.Equal(t, tt.state, res.State())
+			assert.Equal(t, checker.Record{Type: "CNAME", Name: "mta-sts.example.com", Value: "mx.example.com"}, res.Expected)
+		})
+	}
+}
+
+// routedClient returns an HTTP client sending every request to a TLS test
+// server running handler, whatever the host.
+func routedClient(t *testing.T, handler http.HandlerFunc) *http.Client {
+	srv := httptest.NewTLSServer(handler)
+	t.Cleanup(srv.Close)
+
+	client := srv.Client()
+	transport := client.Transport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, srv.Listener.Addr().String())
+	}
+	transport.TLSClientConfig.InsecureSkipVerify = true
+	client.Transport = transport
+
+	return client
+}
+
+func TestTLSRPT(t *testing.T) {
+	ctx := createContext(t)
+	domain := createDomain(t)
+	domain.Spec.TLSRPT = &corev1alpha1.TLSRPTSpec{ReportURIs: []string{"mailto:tls@example.com"}}
+
+	tests := []struct {
+		name   string
+		txt    []string
+		ok     bool
+		reason string
+	}{
+		{"listed", []string{"v=TLSRPTv1; rua=https://reports.example.org/tls, mailto:tls@example.com"}, true, ""},
+		{"other address", []string{"v=TLSRPTv1; rua=mailto:postmaster@example.com"}, false, "the TLS-RPT record does not report to mailto:tls@example.com"},
+		{"no record", []string{"v=spf1 -all"}, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := mockdns.Resolver{
+				Zones: map[string]mockdns.Zone{
+					"_smtp._tls.example.com.": {TXT: tt.txt},
+				},
+			}
+
+			res := checker.NewDNSChecker(&r).CheckDomainTLSRPT(ctx, domain)
+			assert.Equal(t, tt.ok, res.Result())
+			assert.Equal(t, tt.reason, res.Reason)
+			assert.Equal(t, checker.Record{Type: "TXT", Name: "_smtp._tls.example.com", Value: "v=TLSRPTv1; rua=mailto:tls@example.com"}, res.Expected)
+		})
+	}
+}
+
+func createBIMIDomain(t *testing.T) *corev1alpha1.Domain {
+	domain := createDomain(t)
+	domain.Spec.BIMI = &corev1alpha1.BIMISpec{
+		LogoURL: "https://example.com/bimi/logo.svg",
+		VMCURL:  "https://example.com/bimi/vmc.pem",
+	}
+	return domain
+}
+
+const bimiLogo = `<?xml version="1.0" encoding="UTF-8"?>
+<svg xmlns="http://www.w3.org/2000/svg" version="1.2" baseProfile="tiny-ps" viewBox="0 0 10 10">
+  <title>Example</title>
+  <rect width="10" height="10" fill="#000"/>
+</svg>`
+
+func TestBIMIRecord(t *testing.T) {
+	ctx := createContext(t)
+	domain := createBIMIDomain(t)
+
+	tests := []struct {
+		name   string
+		txt    []string
+		ok     bool
+		reason string
+	}{
+		{"published", []string{"v=BIMI1; l=https://example.com/bimi/logo.svg; a=https://example.com/bimi/vmc.pem"}, true, ""},
+		{"other logo", []string{"v=BIMI1; l=https://example.org/logo.svg; a=https://example.com/bimi/vmc.pem"}, false,
+			`the BIMI record points to the logo "https://example.org/logo.svg"`},
+		{"no certificate", []string{"v=BIMI1; l=https://example.com/bimi/logo.svg"}, false, `the BIMI record points to the certificate ""`},
+		{"no record", []string{"v=spf1 -all"}, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := mockdns.Resolver{
+				Zones: map[string]mockdns.Zone{
+					"default._bimi.example.com.": {TXT: tt.txt},
+				},
+			}
+
+			// the logo and the certificate cannot be fetched
+			c := checker.New([]resolver.Resolver{&r}, checker.WithHTTPClient(routedClient(t, http.NotFound)))
+			res := c.CheckDomainBIMI(ctx, domain)
+			assert.Equal(t, tt.ok, res.Record.Result())
+			assert.Equal(t, tt.reason, res.Record.Reason)
+			assert.Equal(t, checker.Record{
+				Type:  "TXT",
+				Name:  "default._bimi.example.com",
+				Value: "v=BIMI1; l=https://example.com/bimi/logo.svg; a=https://example.com/bimi/vmc.pem",
+			}, res.Record.Expected)
+
+			assert.Equal(t, corev1alpha1.CheckStateUnknown, res.Indicator.State())
+			if assert.NotNil(t, res.VMC) {
+				assert.Equal(t, corev1alpha1.CheckStateUnknown, res.VMC.State())
+			}
+		})
+	}
+}
+
+func TestBIMIIndicator(t *testing.T) {
+	ctx := createContext(t)
+	domain := createBIMIDomain(t)
+	domain.Spec.BIMI.VMCURL = ""
+
+	tests := []struct {
+		name   string
+		logo   string
+		state  corev1alpha1.CheckState
+		reason string
+	}{
+		{"tiny-ps", bimiLogo, corev1alpha1.CheckStateVerified, ""},
+		{"other profile", strings.Replace(bimiLogo, "tiny-ps", "tiny", 1), corev1alpha1.CheckStateMissing,
+			`the logo base profile is "tiny", not tiny-ps`},
+		{"script", strings.Replace(bimiLogo, "<title>", "<script>alert(1)</script><title>", 1), corev1alpha1.CheckStateMissing,
+			"the logo contains a script"},
+		{"no title", strings.Replace(bimiLogo, "<title>Example</title>", "", 1), corev1alpha1.CheckStateMissing,
+			"the logo has no title"},
+		{"too large", bimiLogo + strings.Repeat(" ", 32*1024), corev1alpha1.CheckStateUnknown, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := routedClient(t, func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "example.com", r.Host)
+				assert.Equal(t, "/bimi/logo.svg", r.URL.Path)
+				fmt.Fprint(w, tt.logo)
+			})
+
+			res := checker.New(nil, checker.WithHTTPClient(client)).CheckDomainBIMI(ctx, domain)
+			assert.Equal(t, tt.state, res.Indicator.State())
+			assert.Equal(t, tt.reason, res.Indicator.Reason)
+			assert.Nil(t, res.VMC, "the certificate is only checked with a vmcURL")
+		})
+	}
+}
+
+func TestBIMIFetchTargets(t *testing.T) {
+	ctx := createContext(t)
+
+	tests := []struct {
+		name string
+		url  string
+		err  string
+	}{
+		{"http", "http://example.com/bimi/logo.svg", "only https URLs are fetched"},
+		{"loopback", "https://127.0.0.1/bimi/logo.svg", "not a public address"},
+		{"link-local", "https://169.254.169.254/latest/meta-data", "not a public address"},
+		{"private", "https://10.0.0.1/bimi/logo.svg", "not a public address"},
+		{"private IPv6", "https://[fd00::1]/bimi/logo.svg", "not a public address"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			domain := createDomain(t)
+			domain.Spec.BIMI = &corev1alpha1.BIMISpec{LogoURL: tt.url}
+
+			res := checker.New(nil).CheckDomainBIMI(ctx, domain)
+			assert.Equal(t, corev1alpha1.CheckStateUnknown, res.Indicator.State())
+			require.Error(t, res.Indicator.Err)
+			assert.Contains(t, res.Indicator.Err.Error(), tt.err)
+		})
+	}
+}
+
+// createVMC returns a self-signed PEM certificate for the names, with the
+// BIMI extended key usage unless bimi is false.
+func createVMC(t *testing.T, names []string, bimi bool, notAfter time.Time) (string, *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Example"},
+		DNSNames:              names,
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	if bimi {
+		tmpl.UnknownExtKeyUsage = []asn1.ObjectIdentifier{{1, 3, 6, 1, 5, 5, 7, 3, 31}}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})), cert
+}
+
+func TestBIMIVMC(t *testing.T) {
+	ctx := createContext(t)
+	domain := createBIMIDomain(t)
+
+	valid, validCert := createVMC(t, []string{"example.com"}, true, time.Now().Add(time.Hour))
+	otherDomain, _ := createVMC(t, []string{"example.org"}, true, time.Now().Add(time.Hour))
+	noUsage, _ := createVMC(t, []string{"example.com"}, false, time.Now().Add(time.Hour))
+	expired, _ := createVMC(t, []string{"example.com"}, true, time.Now().Add(-time.Minute))
+	_, otherRoot := createVMC(t, []string{"example.com"}, true, time.Now().Add(time.Hour))
+
+	pool := func(cert *x509.Certificate) *x509.CertPool {
+		p := x509.NewCertPool()
+		p.AddCert(cert)
+		return p
+	}
+
+	tests := []struct {
+		name   string
+		pem    string
+		roots  *x509.CertPool
+		state  corev1alpha1.CheckState
+		reason string
+	}{
+		{"valid", valid, nil, corev1alpha1.CheckStateVerified, ""},
+		{"trusted", valid, pool(validCert), corev1alpha1.CheckStateVerified, ""},
+		{"untrusted", valid, pool(otherRoot), corev1alpha1.CheckStateMissing, ""},
+		{"other domain", otherDomain, nil, corev1alpha1.CheckStateMissing, "the VMC is not issued for example.com"},
+		{"no bimi usage", noUsage, nil, corev1alpha1.CheckStateMissing, "the VMC does not have the BIMI extended key usage"},
+		{"expired", expired, nil, corev1alpha1.CheckStateMissing, ""},
+		{"not a certificate", "not a certificate", nil, corev1alpha1.CheckStateMissing, "no certificate found in the VMC"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := routedClient(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/bimi/logo.svg" {
+					fmt.Fprint(w, bimiLogo)
+					return
+				}
+				assert.Equal(t, "/bimi/vmc.pem", r.URL.Path)
+				fmt.Fprint(w, tt.pem)
+			})
+
+			c := checker.New(nil, checker.WithHTTPClient(client), checker.WithVMCRoots(tt.roots))
+			res := c.CheckDomainBIMI(ctx, domain)
+			assert.True(t, res.Indicator.Result())
+			if assert.NotNil(t, res.VMC) {
+				assert.Equal(t, tt.state, res.VMC.State())
+				if tt.reason != "" {
+					assert.Equal(t, tt.reason, res.VMC.Reason)
+				}
+			}
+		})
+	}
+}
+
+func TestPTR(t *testing.T) {
+	ctx := createContext(t)
+	domain := createDomain(t)
+
+	tests := []struct {
+		name   string
+		zones  map[string]mockdns.Zone
+		ok     bool
+		host   string
+		reason string
+	}{
+		{"confirmed", map[string]mockdns.Zone{
+			"1.2.0.192.in-addr.arpa.": {PTR: []string{"out1.mx.example.com."}},
+			"out1.mx.example.com.":    {A: []string{"192.0.2.1"}},
+		}, true, "out1.mx.example.com", ""},
+		{"other domain", map[string]mockdns.Zone{
+			"1.2.0.192.in-addr.arpa.": {PTR: []string{"host.isp.example.net."}},
+			"host.isp.example.net.":   {A: []string{"192.0.2.1"}},
+		}, false, "", "the PTR record of 192.0.2.1 names host.isp.example.net, outside of example.com and mx.example.com"},
+		{"not resolving back", map[string]mockdns.Zone{
+			"1.2.0.192.in-addr.arpa.": {PTR: []string{"mail.example.com."}},
+			"mail.example.com.":       {A: []string{"192.0.2.2"}},
+		}, false, "", "mail.example.com does not resolve back to 192.0.2.1"},
+		{"no ptr", map[string]mockdns.Zone{}, false, "", "192.0.2.1 has no PTR record"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := mockdns.Resolver{Zones: tt.zones}
+
+			res := checker.NewDNSChecker(&r).CheckPTR(ctx, "192.0.2.1", domain.Spec.DomainName, domain.Spec.BaseDomain)
+			assert.Equal(t, tt.ok, res.Result())
+			assert.Equal(t, tt.host, res.Value)
+			assert.Equal(t, tt.reason, res.Reason)
+			assert.Zero(t, res.CntErr, "a missing record is not an error")
+		})
+	}
+}
+
+func TestSPFCoverage(t *testing.T) {
+	ctx := createContext(t)
+
+	tests := []struct {
+		name   string
+		ip     string
+		zones  map[string]mockdns.Zone
+		ok     bool
+		reason string
+	}{
+		{"ip4 network", "192.0.2.10", map[string]mockdns.Zone{
+			"spf.example.com.": {TXT: []string{"v=spf1 ip4:192.0.2.0/24 -all"}},
+		}, true, ""},
+		{"ip6 through include", "2001:db8::1", map[string]mockdns.Zone{
+			"spf.example.com.":   {TXT: []string{"v=spf1 include:_spf6.example.com -all"}},
+			"_spf6.example.com.": {TXT: []string{"v=spf1 ip6:2001:db8::/32 -all"}},
+		}, true, ""},
+		{"a mechanism", "192.0.2.10", map[string]mockdns.Zone{
+			"spf.example.com.": {TXT: []string{"v=spf1 a:out.example.com -all"}},
+			"out.example.com.": {A: []string{"192.0.2.10"}},
+		}, true, ""},
+		{"mx mechanism", "192.0.2.10", map[string]mockdns.Zone{
+			"spf.example.com.": {TXT: []string{"v=spf1 mx -all"}, MX: []net.MX{{Host: "out.example.com.", Pref: 10}}},
+			"out.example.com.": {A: []string{"192.0.2.10"}},
+		}, true, ""},
+		{"not listed", "192.0.2.10", map[string]mockdns.Zone{
+			"spf.example.com.": {TXT: []string{"v=spf1 ip4:198.51.100.0/24 -all"}},
+		}, false, "spf.example.com does not authorize 192.0.2.10"},
+		{"failed", "192.0.2.10", map[string]mockdns.Zone{
+			"spf.example.com.": {TXT: []string{"v=spf1 -ip4:192.0.2.10 ip4:192.0.2.0/24 -all"}},
+		}, false, "spf.example.com has -ip4:192.0.2.10 for 192.0.2.10"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := mockdns.Resolver{Zones: tt.zones}
+
+			res := checker.NewDNSChecker(&r).CheckSPFCoverage(ctx, "spf.example.com", tt.ip)
+			assert.Equal(t, tt.ok, res.Result())
+			assert.Equal(t, tt.reason, res.Reason)
+			assert.Equal(t, "spf.example.com", res.Expected.Name)
+		})
+	}
+}
+
+func TestOwnership(t *testing.T) {
+	ctx := createContext(t)
+
+	tests := []struct {
+		name   string
+		zones  map[string]mockdns.Zone
+		ok     bool
+		reason string
+	}{
+		{"token", map[string]mockdns.Zone{
+			"_k8nnon-challenge.example.com.": {TXT: []string{"other", "k8nnon-challenge=abc"}},
+		}, true, ""},
+		{"other token", map[string]mockdns.Zone{
+			"_k8nnon-challenge.example.com.": {TXT: []string{"k8nnon-challenge=def"}},
+		}, false, "the challenge record does not hold the token"},
+		{"no record", map[string]mockdns.Zone{}, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := mockdns.Resolver{Zones: tt.zones}
+
+			res := checker.NewDNSChecker(&r).CheckOwnership(ctx, "example.com", "k8nnon-challenge=abc")
+			assert.Equal(t, tt.ok, res.Result())
+			assert.Equal(t, tt.reason, res.Reason)
+			assert.Equal(t, checker.Record{Type: "TXT", Name: "_k8nnon-challenge.example.com", Value: "k8nnon-challenge=abc"}, res.Expected)
+		})
+	}
+}
+
+func TestFakeDNSChecker(t *testing.T) {
+	ctx := createContext(t)
+	domain := createDomain(t)
+
+	var c checker.DNSChecker = checker.NewFakeDNSChecker(checker.WithDKIM(true))
+	f := c.(*checker.FakeDNSChecker)
+
+	assert.True(t, c.CheckDomainDKIM(ctx, domain).Result())
+	assert.False(t, c.CheckDomainSPF(ctx, domain).Result(), "unset checks should fail")
+
+	f.Set(checker.WithAll(true))
+	assert.True(t, c.CheckDomainSPF(ctx, domain).Result())
+	assert.True(t, c.CheckDomainMX(ctx, domain).Result())
+
+	assert.Equal(t, []checker.FakeDNSCheckerCall{
+		{Method: "CheckDomainDKIM", Domain: "example.com"},
+		{Method: "CheckDomainSPF", Domain: "example.com"},
+		{Method: "CheckDomainSPF", Domain: "example.com"},
+		{Method: "CheckDomainMX", Domain: "example.com"},
+	}, f.Calls())
 }
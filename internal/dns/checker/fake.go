@@ -0,0 +1,331 @@
+package checker
+
+import (
+	"context"
+	"sync"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+)
+
+// FakeDNSChecker is a DNSChecker returning preset results without querying
+// any resolver, meant to be injected in controller tests. Every check fails
+// unless configured otherwise. It is safe for concurrent use.
+type FakeDNSChecker struct {
+	m       sync.Mutex
+	results map[string]DNSCheckStats
+	bimi    *BIMICheckStats
+	calls   []FakeDNSCheckerCall
+}
+
+// FakeDNSCheckerCall records an invocation of a FakeDNSChecker method.
+type FakeDNSCheckerCall struct {
+	Method string
+	Domain string
+}
+
+// FakeOption configures the results of a FakeDNSChecker.
+type FakeOption func(*FakeDNSChecker)
+
+const (
+	methodCheckDomainDKIM     = "CheckDomainDKIM"
+	methodCheckDomainSPF      = "CheckDomainSPF"
+	methodCheckDomainStatsDNS = "CheckDomainStatsDNS"
+	methodCheckDomainMX       = "CheckDomainMX"
+	methodCheckDomainDMARC    = "CheckDomainDMARC"
+
+	methodCheckDomainMTASTS       = "CheckDomainMTASTS"
+	methodCheckDomainMTASTSPolicy = "CheckDomainMTASTSPolicy"
+	methodCheckDomainTLSRPT       = "CheckDomainTLSRPT"
+	methodCheckDomainBIMI         = "CheckDomainBIMI"
+	methodCheckPTR                = "CheckPTR"
+	methodCheckSPFCoverage        = "CheckSPFCoverage"
+	methodCheckOwnership          = "CheckOwnership"
+)
+
+var _ DNSChecker = &FakeDNSChecker{}
+
+func NewFakeDNSChecker(opts ...FakeOption) *FakeDNSChecker {
+	f := &FakeDNSChecker{results: map[string]DNSCheckStats{}}
+	f.Set(opts...)
+
+	return f
+}
+
+// Set changes the results returned by the next checks.
+func (f *FakeDNSChecker) Set(opts ...FakeOption) {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	for _, opt := range opts {
+		opt(f)
+	}
+}
+
+// WithDKIM sets whether the DKIM check passes.
+func WithDKIM(ok bool) FakeOption {
+	return WithDKIMStats(statsFor(ok))
+}
+
+// WithSPF sets whether the SPF check passes.
+func WithSPF(ok bool) FakeOption {
+	return WithSPFStats(statsFor(ok))
+}
+
+// WithStats sets whether the stats CNAME check passes.
+func WithStats(ok bool) FakeOption {
+	return WithStatsDNSStats(statsFor(ok))
+}
+
+// WithMX sets whether the MX check passes.
+func WithMX(ok bool) FakeOption {
+	return WithMXStats(statsFor(ok))
+}
+
+// WithDMARC sets whether the DMARC check passes.
+func WithDMARC(ok bool) FakeOption {
+	return WithDMARCStats(statsFor(ok))
+}
+
+// WithMTASTS sets whether the MTA-STS record and policy checks pass.
+func WithMTASTS(ok bool) FakeOption {
+	return func(f *FakeDNSChecker) {
+		WithMTASTSStats(statsFor(ok))(f)
+		WithMTASTSPolicyStats(statsFor(ok))(f)
+	}
+}
+
+// WithTLSRPT sets whether the TLS-RPT check passes.
+func WithTLSRPT(ok bool) FakeOption {
+	return WithTLSRPTStats(statsFor(ok))
+}
+
+// WithBIMI sets whether the BIMI record, logo and certificate checks pass.
+func WithBIMI(ok bool) FakeOption {
+	vmc := statsFor(ok)
+	return WithBIMIStats(BIMICheckStats{Record: statsFor(ok), Indicator: statsFor(ok), VMC: &vmc})
+}
+
+// WithPTR sets whether the PTR checks of all the addresses pass.
+func WithPTR(ok bool) FakeOption {
+	return withResult(methodCheckPTR, statsFor(ok))
+}
+
+// WithSPFCoverage sets whether the SPF records authorize all the
+// addresses.
+func WithSPFCoverage(ok bool) FakeOption {
+	return withResult(methodCheckSPFCoverage, statsFor(ok))
+}
+
+// WithOwnership sets whether the ownership challenge check passes.
+func WithOwnership(ok bool) FakeOption {
+	return WithOwnershipStats(statsFor(ok))
+}
+
+// WithAll sets whether all the checks pass.
+func WithAll(ok bool) FakeOption {
+	return func(f *FakeDNSChecker) {
+		for _, opt := range []FakeOption{WithDKIM(ok), WithSPF(ok), WithStats(ok), WithMX(ok), WithDMARC(ok), WithMTASTS(ok), WithTLSRPT(ok), WithBIMI(ok), WithPTR(ok), WithSPFCoverage(ok), WithOwnership(ok)} {
+			opt(f)
+		}
+	}
+}
+
+// WithDKIMStats sets the exact result of the DKIM check.
+func WithDKIMStats(stats DNSCheckStats) FakeOption {
+	return withResult(methodCheckDomainDKIM, stats)
+}
+
+// WithSPFStats sets the exact result of the SPF check.
+func WithSPFStats(stats DNSCheckStats) FakeOption {
+	return withResult(methodCheckDomainSPF, stats)
+}
+
+// WithStatsDNSStats sets the exact result of the stats CNAME check.
+func WithStatsDNSStats(stats DNSCheckStats) FakeOption {
+	return withResult(methodCheckDomainStatsDNS, stats)
+}
+
+// WithMXStats sets the exact result of the MX check.
+func WithMXStats(stats DNSCheckStats) FakeOption {
+	return withResult(methodCheckDomainMX, stats)
+}
+
+// WithDMARCStats sets the exact result of the DMARC check.
+func WithDMARCStats(stats DNSCheckStats) FakeOption {
+	return withResult(methodCheckDomainDMARC, stats)
+}
+
+// WithMTASTSStats sets the exact result of the MTA-STS record check.
+func WithMTASTSStats(stats DNSCheckStats) FakeOption {
+	return withResult(methodCheckDomainMTASTS, stats)
+}
+
+// WithMTASTSPolicyStats sets the exact result of the MTA-STS policy check.
+func WithMTASTSPolicyStats(stats DNSCheckStats) FakeOption {
+	return withResult(methodCheckDomainMTASTSPolicy, stats)
+}
+
+// WithTLSRPTStats sets the exact result of the TLS-RPT check.
+func WithTLSRPTStats(stats DNSCheckStats) FakeOption {
+	return withResult(methodCheckDomainTLSRPT, stats)
+}
+
+// WithBIMIStats sets the exact result of the BIMI checks.
+func WithBIMIStats(stats BIMICheckStats) FakeOption {
+	return func(f *FakeDNSChecker) {
+		f.bimi = &stats
+	}
+}
+
+// WithPTRStats sets the exact result of the PTR check of ip, overriding
+// WithPTR.
+func WithPTRStats(ip string, stats DNSCheckStats) FakeOption {
+	return withResult(methodCheckPTR+"/"+ip, stats)
+}
+
+// WithSPFCoverageStats sets the exact result of the SPF check of ip,
+// overriding WithSPFCoverage.
+func WithSPFCoverageStats(ip string, stats DNSCheckStats) FakeOption {
+	return withResult(methodCheckSPFCoverage+"/"+ip, stats)
+}
+
+// WithDomainDKIMStats sets the exact result of the DKIM check of domain,
+// overriding WithDKIM and WithDKIMStats.
+func WithDomainDKIMStats(domain string, stats DNSCheckStats) FakeOption {
+	return withResult(methodCheckDomainDKIM+"/"+domain, stats)
+}
+
+// WithDomainSPFStats sets the exact result of the SPF check of domain,
+// overriding WithSPF and WithSPFStats.
+func WithDomainSPFStats(domain string, stats DNSCheckStats) FakeOption {
+	return withResult(methodCheckDomainSPF+"/"+domain, stats)
+}
+
+// WithOwnershipStats sets the exact result of the ownership challenge
+// check.
+func WithOwnershipStats(stats DNSCheckStats) FakeOption {
+	return withResult(methodCheckOwnership, stats)
+}
+
+func withResult(method string, stats DNSCheckStats) FakeOption {
+	return func(f *FakeDNSChecker) {
+		f.results[method] = stats
+	}
+}
+
+func statsFor(ok bool) DNSCheckStats {
+	if ok {
+		return DNSCheckStats{CntOK: 1}
+	}
+	return DNSCheckStats{CntKO: 1}
+}
+
+// Calls returns the checks invoked so far, in order.
+func (f *FakeDNSChecker) Calls() []FakeDNSCheckerCall {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	return append([]FakeDNSCheckerCall(nil), f.calls...)
+}
+
+func (f *FakeDNSChecker) CheckDomainDKIM(ctx context.Context, domain *corev1alpha1.Domain) DNSCheckStats {
+	return f.check(methodCheckDomainDKIM, domain)
+}
+
+func (f *FakeDNSChecker) CheckDomainSPF(ctx context.Context, domain *corev1alpha1.Domain) DNSCheckStats {
+	return f.check(methodCheckDomainSPF, domain)
+}
+
+func (f *FakeDNSChecker) CheckDomainStatsDNS(ctx context.Context, domain *corev1alpha1.Domain) DNSCheckStats {
+	return f.check(methodCheckDomainStatsDNS, domain)
+}
+
+func (f *FakeDNSChecker) CheckDomainMX(ctx context.Context, domain *corev1alpha1.Domain) DNSCheckStats {
+	return f.check(methodCheckDomainMX, domain)
+}
+
+func (f *FakeDNSChecker) CheckDomainDMARC(ctx context.Context, domain *corev1alpha1.Domain) DNSCheckStats {
+	return f.check(methodCheckDomainDMARC, domain)
+}
+
+func (f *FakeDNSChecker) CheckDomainMTASTS(ctx context.Context, domain *corev1alpha1.Domain) DNSCheckStats {
+	return f.check(methodCheckDomainMTASTS, domain)
+}
+
+func (f *FakeDNSChecker) CheckDomainMTASTSPolicy(ctx context.Context, domain *corev1alpha1.Domain) DNSCheckStats {
+	return f.check(methodCheckDomainMTASTSPolicy, domain)
+}
+
+func (f *FakeDNSChecker) CheckDomainTLSRPT(ctx context.Context, domain *corev1alpha1.Domain) DNSCheckStats {
+	return f.check(methodCheckDomainTLSRPT, domain)
+}
+
+// CheckPTR records the first of domains as the checked domain.
+func (f *FakeDNSChecker) CheckPTR(ctx context.Context, ip string, domains ...string) DNSCheckStats {
+	domain := ""
+	if len(domains) > 0 {
+		domain = domains[0]
+	}
+	return f.checkAddress(methodCheckPTR, domain, ip)
+}
+
+func (f *FakeDNSChecker) CheckSPFCoverage(ctx context.Context, name, ip string) DNSCheckStats {
+	return f.checkAddress(methodCheckSPFCoverage, name, ip)
+}
+
+func (f *FakeDNSChecker) CheckOwnership(ctx context.Context, domain, token string) DNSCheckStats {
+	return f.checkName(methodCheckOwnership, domain)
+}
+
+// CheckDomainBIMI drops the preset VMC result when the domain has no
+// certificate, like the real check.
+func (f *FakeDNSChecker) CheckDomainBIMI(ctx context.Context, domain *corev1alpha1.Domain) BIMICheckStats {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	f.calls = append(f.calls, FakeDNSCheckerCall{Method: methodCheckDomainBIMI, Domain: domain.Spec.DomainName})
+
+	stats := BIMICheckStats{Record: statsFor(false), Indicator: statsFor(false), VMC: &DNSCheckStats{CntKO: 1}}
+	if f.bimi != nil {
+		stats = *f.bimi
+	}
+	if domain.Spec.BIMI == nil || domain.Spec.BIMI.VMCURL == "" {
+		stats.VMC = nil
+	}
+	return stats
+}
+
+func (f *FakeDNSChecker) check(method string, domain *corev1alpha1.Domain) DNSCheckStats {
+	return f.checkName(method, domain.Spec.DomainName)
+}
+
+func (f *FakeDNSChecker) checkName(method, domain string) DNSCheckStats {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	f.calls = append(f.calls, FakeDNSCheckerCall{Method: method, Domain: domain})
+
+	if stats, ok := f.results[method+"/"+domain]; ok {
+		return stats
+	}
+	if stats, ok := f.results[method]; ok {
+		return stats
+	}
+	return statsFor(false)
+}
+
+// checkAddress returns the result set for ip, or else for every address.
+func (f *FakeDNSChecker) checkAddress(method, domain, ip string) DNSCheckStats {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	f.calls = append(f.calls, FakeDNSCheckerCall{Method: method, Domain: domain})
+
+	if stats, ok := f.results[method+"/"+ip]; ok {
+		return stats
+	}
+	if stats, ok := f.results[method]; ok {
+		return stats
+	}
+	return statsFor(false)
+}
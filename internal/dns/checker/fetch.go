@@ -0,0 +1,94 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// fetch gets rawURL, reading at most maxSize bytes of the body. Redirects
+// are not followed: RFC 8461 forbids them for the MTA-STS policies, and the
+// BIMI resources must be served from the published URL. Only https URLs
+// are fetched, the URLs come from the Domains.
+func (d ResolverChecker) fetch(ctx context.Context, rawURL string, maxSize int64) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "https" {
+		return nil, fmt.Errorf("fetching %s: only https URLs are fetched", rawURL)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	c := *d.httpClient
+	c.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	res, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: unexpected HTTP status %s", rawURL, res.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", rawURL, err)
+	}
+	if int64(len(body)) > maxSize {
+		return nil, fmt.Errorf("fetching %s: larger than %d bytes", rawURL, maxSize)
+	}
+
+	return body, nil
+}
+
+// newFetchTransport returns a transport with the settings of
+// http.DefaultTransport that only dials public addresses, so that the URLs
+// of the Domains can't reach the cluster or the node. The address is
+// checked once resolved, when it is dialed. No proxy is used, it would be
+// dialed instead of the host.
+func newFetchTransport() *http.Transport {
+	return &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+			Control:   dialPublicOnly,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+// errNonPublicAddress is returned when a fetched host resolves to a
+// loopback, link-local, private or unspecified address.
+var errNonPublicAddress = errors.New("not a public address")
+
+func dialPublicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsPrivate() || ip.IsUnspecified() {
+		return fmt.Errorf("dialing %s: %w", host, errNonPublicAddress)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/kannon-email/k8nnon/internal/dns/resolver"
+)
+
+// DefaultProbeDomain is the domain resolved to tell whether the resolvers
+// are reachable.
+const DefaultProbeDomain = "kannon.email"
+
+// Ping resolves probeDomain through every resolver, bypassing the cache, and
+// fails unless a majority of them, or the quorum when set, answers. Without
+// them every check ends up Unknown. A not found answer still proves the
+// resolver works.
+func (d ResolverChecker) Ping(ctx context.Context, probeDomain string) error {
+	ctx = WithoutCache(ctx)
+
+	errs := []error{}
+	wg := sync.WaitGroup{}
+	m := sync.Mutex{}
+
+	for _, res := range d.resolvers {
+		wg.Add(1)
+
+		go func(r resolver.Resolver) {
+			defer wg.Done()
+
+			_, err := r.LookupTXT(ctx, probeDomain)
+			if dnsErr, ok := err.(*net.DNSError); ok && dnsErr.IsNotFound {
+				err = nil
+			}
+			if err != nil {
+				m.Lock()
+				errs = append(errs, err)
+				m.Unlock()
+			}
+		}(res)
+	}
+
+	wg.Wait()
+
+	answered := len(d.resolvers) - len(errs)
+	if (d.quorum > 0 && answered < d.quorum) || (d.quorum == 0 && len(errs) >= answered) {
+		return fmt.Errorf("%d of %d DNS resolvers unreachable: %w", len(errs), len(d.resolvers), errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// livePingTimeout bounds the pings of the liveness checks, below the
+// timeout of the probe.
+const livePingTimeout = 3 * time.Second
+
+// Pinger is the part of the checker the Canary exercises.
+type Pinger interface {
+	Ping(ctx context.Context, probeDomain string) error
+}
+
+// Canary pings the resolvers for the health checks of the manager. The
+// readiness fails as soon as a ping does. The liveness only fails once the
+// pings have been failing for the failure threshold, so that a broken
+// outbound DNS restarts the pod but a single slow answer does not.
+type Canary struct {
+	pinger    Pinger
+	domain    string
+	threshold time.Duration
+	now       func() time.Time
+
+	m            sync.Mutex
+	failingSince time.Time
+}
+
+// NewCanary creates a canary resolving domain through pinger.
+func NewCanary(pinger Pinger, domain string, threshold time.Duration) *Canary {
+	return &Canary{pinger: pinger, domain: domain, threshold: threshold, now: time.Now}
+}
+
+// ReadyCheck is a readyz check failing with the ping.
+func (c *Canary) ReadyCheck(req *http.Request) error {
+	_, err := c.ping(req.Context())
+	return err
+}
+
+// LiveCheck is a healthz check failing once the pings have been failing for
+// the failure threshold.
+func (c *Canary) LiveCheck(req *http.Request) error {
+	// answer before the probe times out, a timeout would count as a failure
+	ctx, cancel := context.WithTimeout(req.Context(), livePingTimeout)
+	defer cancel()
+
+	since, err := c.ping(ctx)
+	if err == nil || c.now().Sub(since) < c.threshold {
+		return nil
+	}
+	return fmt.Errorf("the DNS resolution has been failing since %s: %w", since.Format(time.RFC3339), err)
+}
+
+// ping pings the resolvers, and returns since when the pings fail.
+func (c *Canary) ping(ctx context.Context) (time.Time, error) {
+	err := c.pinger.Ping(ctx, c.domain)
+
+	c.m.Lock()
+	defer c.m.Unlock()
+	switch {
+	case err == nil:
+		c.failingSince = time.Time{}
+	case c.failingSince.IsZero():
+		c.failingSince = c.now()
+	}
+	return c.failingSince, err
+}
@@ -0,0 +1,21 @@
+package checker
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	cacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "k8nnon_dns_cache_lookups_total",
+		Help: "DNS lookups of the checks, by record type and by whether the cache answered them.",
+	}, []string{"type", "result"})
+
+	rateLimitWait = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "k8nnon_dns_rate_limit_wait_seconds",
+		Help:    "Time the DNS lookups waited for the rate limiter.",
+		Buckets: []float64{0.001, 0.01, 0.05, 0.1, 0.5, 1, 2.5, 5},
+	})
+)
+
+// Collectors returns the metrics of the checker, for the caller to register.
+func Collectors() []prometheus.Collector {
+	return []prometheus.Collector{cacheLookups, rateLimitWait}
+}
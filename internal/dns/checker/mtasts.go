@@ -0,0 +1,80 @@
+package checker
+
+import (
+	"context"
+	"net"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+	"github.com/kannon-email/k8nnon/internal/dns/resolver"
+	"github.com/kannon-email/k8nnon/internal/mtasts"
+)
+
+// maxPolicySize bounds the size of a fetched MTA-STS policy.
+const maxPolicySize = 64 * 1024
+
+// CheckDomainMTASTS verifies that the _mta-sts TXT record announces the id
+// of the policy hosted for the domain.
+func (d ResolverChecker) CheckDomainMTASTS(ctx context.Context, domain *corev1alpha1.Domain) DNSCheckStats {
+	id := mtasts.PolicyID(mtasts.DomainPolicy(domain))
+
+	stats := d.checkDNS(ctx, domain, checkDomainMTASTS)
+	stats.Expected = Record{Type: "TXT", Name: mtasts.RecordName(domain.Spec.DomainName), Value: mtasts.Record(id)}
+	return stats
+}
+
+// CheckDomainMTASTSPolicy fetches the policy from the policy host and
+// verifies that it is the one hosted for the domain. It is a single fetch,
+// so the stats count one outcome.
+func (d ResolverChecker) CheckDomainMTASTSPolicy(ctx context.Context, domain *corev1alpha1.Domain) DNSCheckStats {
+	stats := DNSCheckStats{
+		Expected: Record{Type: "CNAME", Name: mtasts.Host(domain.Spec.DomainName), Value: domain.Spec.BaseDomain},
+	}
+
+	if d.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.timeout)
+		defer cancel()
+	}
+
+	body, err := d.fetch(ctx, mtasts.PolicyURL(domain.Spec.DomainName), maxPolicySize)
+	policy := string(body)
+	switch {
+	case err != nil:
+		// like a failed lookup, a failed fetch counts as an error and a KO
+		stats.CntErr, stats.CntKO = 1, 1
+		stats.Err = err
+	case !mtasts.SamePolicy(policy, mtasts.DomainPolicy(domain)):
+		stats.CntKO = 1
+		stats.Reason = "the served policy differs from the hosted one"
+		stats.Observed = []string{policy}
+	default:
+		stats.CntOK = 1
+	}
+
+	return stats
+}
+
+func checkDomainMTASTS(ctx context.Context, r resolver.Resolver, domain *corev1alpha1.Domain) (bool, checkDetail, error) {
+	res, err := r.LookupTXT(ctx, mtasts.RecordName(domain.Spec.DomainName))
+	if err != nil {
+		if dnsErr, ok := err.(*net.DNSError); ok {
+			if dnsErr.IsNotFound {
+				return false, checkDetail{}, nil
+			}
+		}
+
+		return false, checkDetail{}, err
+	}
+
+	expected := mtasts.PolicyID(mtasts.DomainPolicy(domain))
+
+	detail := checkDetail{observed: res}
+	for _, txt := range res {
+		if id, ok := mtasts.ParseRecord(txt); ok {
+			detail.value = id
+			return id == expected, detail, nil
+		}
+	}
+
+	return false, detail, nil
+}
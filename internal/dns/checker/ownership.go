@@ -0,0 +1,49 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+	"github.com/kannon-email/k8nnon/internal/dns/resolver"
+)
+
+// OwnershipChallengeName returns the name of the TXT record proving the
+// control of domain.
+func OwnershipChallengeName(domain string) string {
+	return fmt.Sprintf("_k8nnon-challenge.%s", domain)
+}
+
+// CheckOwnership verifies that the challenge TXT record of domain holds
+// token.
+func (d ResolverChecker) CheckOwnership(ctx context.Context, domain, token string) DNSCheckStats {
+	stats := d.checkDNS(ctx, nil, func(ctx context.Context, r resolver.Resolver, _ *corev1alpha1.Domain) (bool, checkDetail, error) {
+		return checkOwnership(ctx, r, domain, token)
+	})
+	stats.Expected = Record{Type: "TXT", Name: OwnershipChallengeName(domain), Value: token}
+
+	return stats
+}
+
+func checkOwnership(ctx context.Context, r resolver.Resolver, domain, token string) (bool, checkDetail, error) {
+	res, err := r.LookupTXT(ctx, OwnershipChallengeName(domain))
+	if err != nil {
+		if dnsErr, ok := err.(*net.DNSError); ok {
+			if dnsErr.IsNotFound {
+				return false, checkDetail{}, nil
+			}
+		}
+
+		return false, checkDetail{}, err
+	}
+
+	detail := checkDetail{observed: res}
+	for _, txt := range res {
+		if txt == token {
+			return true, detail, nil
+		}
+	}
+
+	return false, detail, mismatchf("the challenge record does not hold the token")
+}
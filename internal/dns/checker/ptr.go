@@ -0,0 +1,88 @@
+package checker
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+	"github.com/kannon-email/k8nnon/internal/dns/resolver"
+)
+
+// CheckPTR verifies the forward-confirmed reverse DNS of a sending address:
+// a PTR record of ip must name a host under one of domains, and the host
+// must resolve back to ip. The confirmed host is the Value of the stats.
+func (d ResolverChecker) CheckPTR(ctx context.Context, ip string, domains ...string) DNSCheckStats {
+	return d.checkDNS(ctx, nil, func(ctx context.Context, r resolver.Resolver, _ *corev1alpha1.Domain) (bool, checkDetail, error) {
+		// the Domains of a SenderPool all check its addresses
+		return checkPTR(withSharedQueries(ctx), r, ip, domains)
+	})
+}
+
+func checkPTR(ctx context.Context, r resolver.Resolver, ip string, domains []string) (bool, checkDetail, error) {
+	names, err := r.LookupAddr(ctx, ip)
+	if err != nil {
+		if dnsErr, ok := err.(*net.DNSError); ok {
+			if dnsErr.IsNotFound {
+				return false, checkDetail{}, mismatchf("%s has no PTR record", ip)
+			}
+		}
+
+		return false, checkDetail{}, err
+	}
+
+	detail := checkDetail{}
+	for _, name := range names {
+		detail.observed = append(detail.observed, strings.TrimSuffix(name, "."))
+	}
+
+	var mismatch error
+	for _, host := range detail.observed {
+		if !inAnyDomain(host, domains) {
+			mismatch = mismatchf("the PTR record of %s names %s, outside of %s", ip, host, strings.Join(domains, " and "))
+			continue
+		}
+
+		addrs, err := r.LookupHost(ctx, host)
+		if err != nil {
+			if dnsErr, ok := err.(*net.DNSError); ok && dnsErr.IsNotFound {
+				mismatch = mismatchf("%s does not resolve back to %s", host, ip)
+				continue
+			}
+			return false, detail, err
+		}
+
+		for _, addr := range addrs {
+			if sameIP(addr, ip) {
+				detail.value = host
+				return true, detail, nil
+			}
+		}
+		mismatch = mismatchf("%s does not resolve back to %s", host, ip)
+	}
+
+	if mismatch == nil {
+		mismatch = mismatchf("%s has no PTR record", ip)
+	}
+	return false, detail, mismatch
+}
+
+func inAnyDomain(host string, domains []string) bool {
+	for _, domain := range domains {
+		if inDomain(host, domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// inDomain reports whether host is domain or one of its subdomains.
+func inDomain(host, domain string) bool {
+	host, domain = strings.ToLower(host), strings.ToLower(strings.TrimSuffix(domain, "."))
+	return domain != "" && (host == domain || strings.HasSuffix(host, "."+domain))
+}
+
+func sameIP(a, b string) bool {
+	ipA, ipB := net.ParseIP(a), net.ParseIP(b)
+	return ipA != nil && ipA.Equal(ipB)
+}
@@ -0,0 +1,89 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/kannon-email/k8nnon/internal/dns/resolver"
+)
+
+// Default rate of the DNS lookups, across all resolvers.
+const (
+	DefaultRateLimit      = 50
+	DefaultRateLimitBurst = 100
+)
+
+// errRateLimited is returned when the lookup would wait for the limiter
+// past the deadline of its context. Retrying it would wait as long.
+var errRateLimited = errors.New("rate limited")
+
+// rateLimitedResolver waits for a limiter shared by all the resolvers before
+// every lookup.
+type rateLimitedResolver struct {
+	r       resolver.Resolver
+	limiter *rate.Limiter
+}
+
+func withRateLimit(r resolver.Resolver, limiter *rate.Limiter) resolver.Resolver {
+	if limiter == nil {
+		return r
+	}
+
+	return rateLimitedResolver{r: r, limiter: limiter}
+}
+
+func (l rateLimitedResolver) wait(ctx context.Context) error {
+	start := time.Now()
+	err := l.limiter.Wait(ctx)
+	rateLimitWait.Observe(time.Since(start).Seconds())
+
+	switch {
+	case err == nil:
+		return nil
+	case ctx.Err() != nil:
+		return ctx.Err()
+	default:
+		// the wait would outlast the deadline of ctx
+		return fmt.Errorf("%w: %w", ErrLookupTimeout, errRateLimited)
+	}
+}
+
+func (l rateLimitedResolver) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	if err := l.wait(ctx); err != nil {
+		return nil, err
+	}
+	return l.r.LookupAddr(ctx, addr)
+}
+
+func (l rateLimitedResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	if err := l.wait(ctx); err != nil {
+		return nil, err
+	}
+	return l.r.LookupHost(ctx, host)
+}
+
+func (l rateLimitedResolver) LookupCNAME(ctx context.Context, name string) (string, error) {
+	if err := l.wait(ctx); err != nil {
+		return "", err
+	}
+	return l.r.LookupCNAME(ctx, name)
+}
+
+func (l rateLimitedResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	if err := l.wait(ctx); err != nil {
+		return nil, err
+	}
+	return l.r.LookupTXT(ctx, name)
+}
+
+func (l rateLimitedResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	if err := l.wait(ctx); err != nil {
+		return nil, err
+	}
+	return l.r.LookupMX(ctx, name)
+}
@@ -0,0 +1,92 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/kannon-email/k8nnon/internal/dns/resolver"
+	"github.com/kannon-email/k8nnon/internal/resilience"
+)
+
+// resilientResolver fails the lookups fast while the circuit breaker of
+// the resolver is open, and retries the lookups failing transiently within
+// the retry budget of the context, typically the one of the Domain being
+// checked. A not found answer is an answer: it closes the breaker and is
+// never retried.
+type resilientResolver struct {
+	r       resolver.Resolver
+	breaker *resilience.Breaker
+	budget  *resilience.Budget
+}
+
+func withResilience(r resolver.Resolver, breaker *resilience.Breaker, budget *resilience.Budget) resolver.Resolver {
+	if breaker == nil && budget == nil {
+		return r
+	}
+
+	return resilientResolver{r: r, breaker: breaker, budget: budget}
+}
+
+// resilientLookup runs lookup through the breaker, as many times as the
+// budget allows.
+func resilientLookup[T any](ctx context.Context, l resilientResolver, name string, lookup func(context.Context) (T, error)) (T, error) {
+	for attempt := 0; ; attempt++ {
+		if err := l.breaker.Allow(); err != nil {
+			var zero T
+			return zero, fmt.Errorf("lookup %s: %w", name, err)
+		}
+
+		res, err := lookup(ctx)
+		class := ErrorClass(err)
+		switch {
+		case err == nil:
+			l.breaker.Done(false)
+			return res, nil
+		case ctx.Err() != nil || errors.Is(err, errRateLimited):
+			// said nothing about the resolver
+			l.breaker.Release()
+			return res, err
+		}
+		l.breaker.Done(transientClass(class))
+
+		key := resilience.BudgetKey(ctx)
+		if !transientClass(class) || attempt >= resilience.DefaultRetries || !resilience.Retry(l.budget, "dns", key) {
+			return res, err
+		}
+		if err := resilience.Backoff(ctx, attempt); err != nil {
+			return res, err
+		}
+	}
+}
+
+func (l resilientResolver) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	return resilientLookup(ctx, l, addr, func(ctx context.Context) ([]string, error) {
+		return l.r.LookupAddr(ctx, addr)
+	})
+}
+
+func (l resilientResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	return resilientLookup(ctx, l, host, func(ctx context.Context) ([]string, error) {
+		return l.r.LookupHost(ctx, host)
+	})
+}
+
+func (l resilientResolver) LookupCNAME(ctx context.Context, name string) (string, error) {
+	return resilientLookup(ctx, l, name, func(ctx context.Context) (string, error) {
+		return l.r.LookupCNAME(ctx, name)
+	})
+}
+
+func (l resilientResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	return resilientLookup(ctx, l, name, func(ctx context.Context) ([]string, error) {
+		return l.r.LookupTXT(ctx, name)
+	})
+}
+
+func (l resilientResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	return resilientLookup(ctx, l, name, func(ctx context.Context) ([]*net.MX, error) {
+		return l.r.LookupMX(ctx, name)
+	})
+}
@@ -0,0 +1,445 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+	"github.com/kannon-email/k8nnon/internal/dns/resolver"
+)
+
+// maxSPFLookups caps the DNS lookups done while evaluating a SPF record, as
+// required by RFC 7208.
+const maxSPFLookups = 10
+
+var errSPFTooManyLookups = permErrorf("SPF record needs more than %d DNS lookups", maxSPFLookups)
+
+// SPFEvaluation is the outcome of the evaluation of the SPF record of a
+// domain as receivers do it.
+type SPFEvaluation struct {
+	// Lookups is how many DNS lookups the record, and the records it
+	// includes or redirects to, take. It stops past the limit.
+	Lookups int
+
+	// PermError explains why receivers fail the record with a permerror,
+	// empty when they do not.
+	PermError string
+}
+
+// spfEvaluation walks a SPF record and the records it includes looking for
+// the expected include mechanism.
+type spfEvaluation struct {
+	r        resolver.Resolver
+	expected string
+	lookups  int
+
+	// include is the expected include term with its qualifier, and
+	// includedBy the domain whose record has it. Both are empty until the
+	// walk finds it.
+	include    string
+	includedBy string
+
+	// observed are the TXT records of the evaluated domain.
+	observed []string
+}
+
+// evaluate walks the SPF record of domain and the records it includes or
+// redirects to, in evaluation order, counting the DNS lookups and noting
+// the first expected include. The walk does not stop there: the other
+// senders of the domain need the whole record to fit the lookup limit.
+// The returned error is a permerror, or the failure of a resolver.
+func (e *spfEvaluation) evaluate(ctx context.Context, domain string, top bool) error {
+	txts, record, err := lookupSPFRecord(ctx, e.r, domain)
+	if top {
+		e.observed = txts
+	}
+	if err != nil {
+		return err
+	}
+	if err := checkSPFSyntax(domain, record); err != nil {
+		return err
+	}
+
+	redirect := ""
+	for _, term := range strings.Fields(record)[1:] {
+		if name, value, ok := spfModifier(term); ok {
+			if name == "redirect" {
+				redirect = value
+			}
+			continue
+		}
+
+		_, mechanism := splitQualifier(term)
+		name, value, _ := strings.Cut(mechanism, ":")
+
+		switch strings.ToLower(name) {
+		case "include":
+			if err := e.countLookup(); err != nil {
+				return err
+			}
+
+			if !sameHost(value, e.expected) {
+				if err := e.evaluateTarget(ctx, domain, "includes", value); err != nil {
+					return err
+				}
+				continue
+			}
+
+			// the expected include is the record of Kannon, which is not
+			// walked: it is checked with the sender pools
+			if e.include == "" {
+				e.include, e.includedBy = term, domain
+			}
+		case "a", "mx", "ptr", "exists":
+			if err := e.countLookup(); err != nil {
+				return err
+			}
+		case "all":
+			// the terms after all, and the redirect, are never evaluated
+			return nil
+		}
+	}
+
+	if redirect == "" {
+		return nil
+	}
+	if err := e.countLookup(); err != nil {
+		return err
+	}
+	return e.evaluateTarget(ctx, domain, "redirects to", redirect)
+}
+
+// evaluateTarget evaluates the record an include or a redirect of domain
+// points to. Unlike for the top record, a target without a SPF record is a
+// permerror.
+func (e *spfEvaluation) evaluateTarget(ctx context.Context, domain, verb, target string) error {
+	if strings.Contains(target, "%") {
+		// macros expand per message, the target is only known then
+		return nil
+	}
+
+	// the targets are mostly the records of the mail providers, included
+	// by many Domains
+	err := e.evaluate(withSharedQueries(ctx), target, false)
+	if isMismatch(err) && !isPermError(err) {
+		return permErrorf("%s %s %s, which has no SPF record", domain, verb, target)
+	}
+	return err
+}
+
+// checkSPFSyntax returns a permerror for the first term of record receivers
+// reject. Like them it checks the whole record, the terms after all too.
+func checkSPFSyntax(domain, record string) error {
+	modifiers := map[string]bool{}
+	for _, term := range strings.Fields(record)[1:] {
+		if name, value, ok := spfModifier(term); ok {
+			if name != "redirect" && name != "exp" {
+				// unknown modifiers are ignored
+				continue
+			}
+			if value == "" || modifiers[name] {
+				return permErrorf("%s has an invalid %s modifier", domain, name)
+			}
+			modifiers[name] = true
+			continue
+		}
+
+		_, mechanism := splitQualifier(term)
+		name, value, hasValue := strings.Cut(mechanism, ":")
+		if i := strings.IndexByte(name, '/'); i >= 0 {
+			name = name[:i]
+		}
+
+		valid := true
+		switch strings.ToLower(name) {
+		case "all":
+			valid = mechanism == name
+		case "include", "exists":
+			valid = value != ""
+		case "a", "mx", "ptr":
+			valid = !hasValue || value != ""
+		case "ip4":
+			valid = validSPFNetwork(value, false)
+		case "ip6":
+			valid = validSPFNetwork(value, true)
+		default:
+			return permErrorf("%s has the unknown mechanism %q", domain, term)
+		}
+		if !valid {
+			return permErrorf("%s has the invalid mechanism %q", domain, term)
+		}
+	}
+	return nil
+}
+
+// spfModifier splits a name=value modifier, the name lowercased.
+func spfModifier(term string) (string, string, bool) {
+	name, value, ok := strings.Cut(term, "=")
+	if !ok || name == "" || strings.ContainsAny(name, ":/") {
+		return "", "", false
+	}
+	return strings.ToLower(name), value, true
+}
+
+// validSPFNetwork reports whether the value of an ip4 or ip6 mechanism is
+// an address of the family, with an optional prefix length.
+func validSPFNetwork(value string, ip6 bool) bool {
+	addr, prefix, hasPrefix := strings.Cut(value, "/")
+	ip := net.ParseIP(addr)
+	if ip == nil || (ip.To4() == nil) != ip6 || strings.Contains(addr, ":") != ip6 {
+		return false
+	}
+	if !hasPrefix {
+		return true
+	}
+
+	bits, err := strconv.Atoi(prefix)
+	max := 32
+	if ip6 {
+		max = 128
+	}
+	return err == nil && prefix[0] != '+' && bits >= 0 && bits <= max
+}
+
+// CheckSPFCoverage verifies that the SPF record of name, or one of the
+// records it includes or redirects to, authorizes ip with an ip4, ip6, a
+// or mx mechanism.
+func (d ResolverChecker) CheckSPFCoverage(ctx context.Context, name, ip string) DNSCheckStats {
+	stats := d.checkDNS(ctx, nil, func(ctx context.Context, r resolver.Resolver, _ *corev1alpha1.Domain) (bool, checkDetail, error) {
+		addr := net.ParseIP(ip)
+		if addr == nil {
+			return false, checkDetail{}, mismatchf("%q is not an IP address", ip)
+		}
+
+		e := spfEvaluation{r: r}
+		ok, err := e.coversIP(ctx, name, addr)
+		return ok, checkDetail{observed: e.observed}, err
+	})
+	stats.Expected = Record{Type: "TXT", Name: name, Value: fmt.Sprintf("v=spf1 %s ~all", spfIPMechanism(ip))}
+	return stats
+}
+
+// coversIP reports whether the SPF record of domain, or one of the records
+// it includes or redirects to, authorizes ip.
+func (e *spfEvaluation) coversIP(ctx context.Context, domain string, ip net.IP) (bool, error) {
+	txts, record, err := lookupSPFRecord(ctx, e.r, domain)
+	if e.lookups == 0 {
+		// nested lookups are always counted first, this is the top record
+		e.observed = txts
+	}
+	if err != nil {
+		return false, err
+	}
+
+	redirect := ""
+	for _, term := range strings.Fields(record)[1:] {
+		if strings.HasPrefix(strings.ToLower(term), "redirect=") {
+			redirect = term[len("redirect="):]
+			continue
+		}
+
+		qualifier, mechanism := splitQualifier(term)
+		name, value, _ := strings.Cut(mechanism, ":")
+
+		var matched bool
+		switch strings.ToLower(name) {
+		case "ip4", "ip6":
+			matched = networkContains(value, ip)
+		case "include":
+			if err := e.countLookup(); err != nil {
+				return false, err
+			}
+
+			// a nested record not authorizing ip is not a failure
+			found, err := e.coversIP(ctx, value, ip)
+			if err == errSPFTooManyLookups || err != nil && !isMismatch(err) {
+				return false, err
+			}
+			matched = found
+		case "a", "mx":
+			if err := e.countLookup(); err != nil {
+				return false, err
+			}
+
+			matched, err = e.hostsContain(ctx, strings.ToLower(name) == "mx", value, domain, ip)
+			if err != nil {
+				return false, err
+			}
+		case "ptr", "exists":
+			if err := e.countLookup(); err != nil {
+				return false, err
+			}
+		case "all":
+			if qualifier != '+' {
+				// the terms after all are never evaluated
+				return false, mismatchf("%s does not authorize %s", domain, ip)
+			}
+			matched = true
+		}
+
+		if !matched {
+			continue
+		}
+		if qualifier == '+' {
+			return true, nil
+		}
+		return false, mismatchf("%s has %s for %s", domain, term, ip)
+	}
+
+	if redirect != "" {
+		if err := e.countLookup(); err != nil {
+			return false, err
+		}
+		return e.coversIP(ctx, redirect, ip)
+	}
+
+	return false, mismatchf("%s does not authorize %s", domain, ip)
+}
+
+// hostsContain reports whether the addresses of the target of an a or mx
+// mechanism, or of its MX hosts, contain ip. The target defaults to domain
+// and may have a CIDR length.
+func (e *spfEvaluation) hostsContain(ctx context.Context, mx bool, target, domain string, ip net.IP) (bool, error) {
+	host, cidr, _ := strings.Cut(target, "/")
+	if host == "" {
+		host = domain
+	}
+
+	hosts := []string{host}
+	if mx {
+		mxs, err := e.r.LookupMX(ctx, host)
+		if err != nil {
+			if dnsErr, ok := err.(*net.DNSError); ok && dnsErr.IsNotFound {
+				return false, nil
+			}
+			return false, err
+		}
+
+		hosts = hosts[:0]
+		for _, mx := range mxs {
+			hosts = append(hosts, mx.Host)
+		}
+	}
+
+	for _, h := range hosts {
+		addrs, err := e.r.LookupHost(ctx, h)
+		if err != nil {
+			if dnsErr, ok := err.(*net.DNSError); ok && dnsErr.IsNotFound {
+				continue
+			}
+			return false, err
+		}
+
+		for _, addr := range addrs {
+			network := addr
+			if cidr != "" {
+				network += "/" + cidr
+			}
+			if networkContains(network, ip) {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// networkContains reports whether the address or CIDR network contains ip.
+func networkContains(network string, ip net.IP) bool {
+	if !strings.Contains(network, "/") {
+		return net.ParseIP(network).Equal(ip)
+	}
+
+	_, ipNet, err := net.ParseCIDR(network)
+	return err == nil && ipNet.Contains(ip)
+}
+
+// spfIPMechanism returns the ip4 or ip6 mechanism authorizing ip.
+func spfIPMechanism(ip string) string {
+	if addr := net.ParseIP(ip); addr != nil && addr.To4() == nil {
+		return "ip6:" + ip
+	}
+	return "ip4:" + ip
+}
+
+func (e *spfEvaluation) countLookup() error {
+	e.lookups++
+	if e.lookups > maxSPFLookups {
+		return errSPFTooManyLookups
+	}
+	return nil
+}
+
+// lookupSPFRecord returns the TXT records of domain and the single SPF
+// record among them.
+func lookupSPFRecord(ctx context.Context, r resolver.Resolver, domain string) ([]string, string, error) {
+	res, err := r.LookupTXT(ctx, domain)
+	if err != nil {
+		if dnsErr, ok := err.(*net.DNSError); ok && dnsErr.IsNotFound {
+			return nil, "", mismatchf("%s has no SPF record", domain)
+		}
+		return nil, "", err
+	}
+
+	records := []string{}
+	for _, txt := range res {
+		fields := strings.Fields(txt)
+		if len(fields) > 0 && strings.EqualFold(fields[0], "v=spf1") {
+			records = append(records, txt)
+		}
+	}
+
+	switch len(records) {
+	case 0:
+		return res, "", mismatchf("%s has no SPF record", domain)
+	case 1:
+		return res, records[0], nil
+	default:
+		return res, "", permErrorf("%s has %d SPF records", domain, len(records))
+	}
+}
+
+// splitQualifier splits the qualifier of a SPF mechanism, defaulting to '+'.
+func splitQualifier(term string) (byte, string) {
+	if term != "" && strings.ContainsRune("+-~?", rune(term[0])) {
+		return term[0], term[1:]
+	}
+	return '+', term
+}
+
+func sameHost(a, b string) bool {
+	return strings.EqualFold(strings.TrimSuffix(a, "."), strings.TrimSuffix(b, "."))
+}
+
+// mismatchError explains why a record was found but does not match. Unlike
+// other errors it does not make the outcome of a check unknown.
+type mismatchError struct {
+	reason string
+	// perm marks the records receivers fail with a SPF permerror.
+	perm bool
+}
+
+func mismatchf(format string, args ...interface{}) error {
+	return &mismatchError{reason: fmt.Sprintf(format, args...)}
+}
+
+func permErrorf(format string, args ...interface{}) error {
+	return &mismatchError{reason: fmt.Sprintf(format, args...), perm: true}
+}
+
+func (e *mismatchError) Error() string {
+	return e.reason
+}
+
+func isMismatch(err error) bool {
+	_, ok := err.(*mismatchError)
+	return ok
+}
+
+func isPermError(err error) bool {
+	mismatch, ok := err.(*mismatchError)
+	return ok && mismatch.perm
+}
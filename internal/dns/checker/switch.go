@@ -0,0 +1,95 @@
+package checker
+
+import (
+	"context"
+	"sync"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+)
+
+// Switch is a DNSChecker delegating to another one, which can be replaced
+// while the checks run, e.g. when the resolvers are reconfigured. The
+// checks already started complete with the checker they started with.
+type Switch struct {
+	m       sync.RWMutex
+	checker DNSChecker
+}
+
+var _ DNSChecker = &Switch{}
+
+// NewSwitch creates a Switch delegating to checker.
+func NewSwitch(checker DNSChecker) *Switch {
+	return &Switch{checker: checker}
+}
+
+// Set replaces the checker the next checks delegate to.
+func (s *Switch) Set(checker DNSChecker) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	s.checker = checker
+}
+
+// Current returns the checker the checks delegate to.
+func (s *Switch) Current() DNSChecker {
+	s.m.RLock()
+	defer s.m.RUnlock()
+
+	return s.checker
+}
+
+// Ping pings the resolvers of the current checker, when it can.
+func (s *Switch) Ping(ctx context.Context, probeDomain string) error {
+	if pinger, ok := s.Current().(Pinger); ok {
+		return pinger.Ping(ctx, probeDomain)
+	}
+	return nil
+}
+
+func (s *Switch) CheckDomainDKIM(ctx context.Context, domain *corev1alpha1.Domain) DNSCheckStats {
+	return s.Current().CheckDomainDKIM(ctx, domain)
+}
+
+func (s *Switch) CheckDomainSPF(ctx context.Context, domain *corev1alpha1.Domain) DNSCheckStats {
+	return s.Current().CheckDomainSPF(ctx, domain)
+}
+
+func (s *Switch) CheckDomainStatsDNS(ctx context.Context, domain *corev1alpha1.Domain) DNSCheckStats {
+	return s.Current().CheckDomainStatsDNS(ctx, domain)
+}
+
+func (s *Switch) CheckDomainMX(ctx context.Context, domain *corev1alpha1.Domain) DNSCheckStats {
+	return s.Current().CheckDomainMX(ctx, domain)
+}
+
+func (s *Switch) CheckDomainDMARC(ctx context.Context, domain *corev1alpha1.Domain) DNSCheckStats {
+	return s.Current().CheckDomainDMARC(ctx, domain)
+}
+
+func (s *Switch) CheckDomainMTASTS(ctx context.Context, domain *corev1alpha1.Domain) DNSCheckStats {
+	return s.Current().CheckDomainMTASTS(ctx, domain)
+}
+
+func (s *Switch) CheckDomainMTASTSPolicy(ctx context.Context, domain *corev1alpha1.Domain) DNSCheckStats {
+	return s.Current().CheckDomainMTASTSPolicy(ctx, domain)
+}
+
+func (s *Switch) CheckDomainTLSRPT(ctx context.Context, domain *corev1alpha1.Domain) DNSCheckStats {
+	return s.Current().CheckDomainTLSRPT(ctx, domain)
+}
+
+func (s *Switch) CheckDomainBIMI(ctx context.Context, domain *corev1alpha1.Domain) BIMICheckStats {
+	return s.Current().CheckDomainBIMI(ctx, domain)
+}
+
+func (s *Switch) CheckPTR(ctx context.Context, ip string, domains ...string) DNSCheckStats {
+	return s.Current().CheckPTR(ctx, ip, domains...)
+}
+
+func (s *Switch) CheckSPFCoverage(ctx context.Context, name, ip string) DNSCheckStats {
+	return s.Current().CheckSPFCoverage(ctx, name, ip)
+}
+
+func (s *Switch) CheckOwnership(ctx context.Context, domain, token string) DNSCheckStats {
+	return s.Current().CheckOwnership(ctx, domain, token)
+}
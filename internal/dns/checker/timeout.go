@@ -0,0 +1,135 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/kannon-email/k8nnon/internal/dns/resolver"
+	"github.com/kannon-email/k8nnon/internal/resilience"
+)
+
+// ErrLookupTimeout is returned when a DNS lookup does not complete within
+// the configured timeout.
+var ErrLookupTimeout = errors.New("dns lookup timed out")
+
+// Classes of the errors of failed lookups, see ErrorClass.
+const (
+	ErrorClassTimeout       = "timeout"
+	ErrorClassNotFound      = "not_found"
+	ErrorClassServerFailure = "server_failure"
+	ErrorClassTemporary     = "temporary"
+	ErrorClassUnavailable   = "unavailable"
+	ErrorClassOther         = "other"
+)
+
+// errServerMisbehaving is the error of the Go resolver for the SERVFAIL
+// answers, and for the other answers it can't make sense of.
+const errServerMisbehaving = "server misbehaving"
+
+// ErrorClass classifies the error of a failed lookup, or the first of the
+// errors joined in DNSCheckStats.Err. A lookup refused by the circuit
+// breaker of its resolver is unavailable.
+func ErrorClass(err error) string {
+	var dnsErr *net.DNSError
+	switch {
+	case errors.Is(err, resilience.ErrOpen):
+		return ErrorClassUnavailable
+	case errors.Is(err, ErrLookupTimeout), errors.Is(err, context.DeadlineExceeded):
+		return ErrorClassTimeout
+	case !errors.As(err, &dnsErr):
+		return ErrorClassOther
+	case dnsErr.IsTimeout:
+		return ErrorClassTimeout
+	case dnsErr.IsNotFound:
+		return ErrorClassNotFound
+	case dnsErr.Err == errServerMisbehaving:
+		return ErrorClassServerFailure
+	case dnsErr.IsTemporary:
+		return ErrorClassTemporary
+	default:
+		return ErrorClassOther
+	}
+}
+
+// transientClass reports the classes of the errors a resolver may not
+// return when asked again.
+func transientClass(class string) bool {
+	switch class {
+	case ErrorClassTimeout, ErrorClassServerFailure, ErrorClassTemporary:
+		return true
+	}
+	return false
+}
+
+// timeoutResolver bounds every lookup of the wrapped resolver with a timeout
+// derived from the caller context.
+type timeoutResolver struct {
+	r       resolver.Resolver
+	timeout time.Duration
+}
+
+func withTimeout(r resolver.Resolver, timeout time.Duration) resolver.Resolver {
+	if timeout <= 0 {
+		return r
+	}
+
+	return timeoutResolver{r: r, timeout: timeout}
+}
+
+func (t timeoutResolver) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	res, err := t.r.LookupAddr(ctx, addr)
+	return res, t.wrapErr(ctx, addr, err)
+}
+
+func (t timeoutResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	res, err := t.r.LookupHost(ctx, host)
+	return res, t.wrapErr(ctx, host, err)
+}
+
+func (t timeoutResolver) LookupCNAME(ctx context.Context, name string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	res, err := t.r.LookupCNAME(ctx, name)
+	return res, t.wrapErr(ctx, name, err)
+}
+
+func (t timeoutResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	res, err := t.r.LookupTXT(ctx, name)
+	return res, t.wrapErr(ctx, name, err)
+}
+
+func (t timeoutResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	res, err := t.r.LookupMX(ctx, name)
+	return res, t.wrapErr(ctx, name, err)
+}
+
+func (t timeoutResolver) wrapErr(ctx context.Context, name string, err error) error {
+	if err == nil {
+		return err
+	}
+
+	// the resolver may hit the deadline on its socket before ctx expires
+	var dnsErr *net.DNSError
+	timedOut := errors.Is(ctx.Err(), context.DeadlineExceeded) || (errors.As(err, &dnsErr) && dnsErr.IsTimeout)
+	if !timedOut {
+		return err
+	}
+
+	return fmt.Errorf("lookup %s: %w after %s: %v", name, ErrLookupTimeout, t.timeout, err)
+}
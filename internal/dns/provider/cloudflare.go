@@ -0,0 +1,207 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCloudflareEndpoint is the base URL of the Cloudflare v4 API.
+const DefaultCloudflareEndpoint = "https://api.cloudflare.com/client/v4"
+
+// CloudflareProvider manages the records of a zone with the Cloudflare v4
+// API, authenticated with an API token allowed to edit the zone DNS.
+type CloudflareProvider struct {
+	endpoint string
+	token    string
+	zone     string
+	client   *http.Client
+
+	mu     sync.Mutex
+	zoneID string
+}
+
+type cloudflareResponse struct {
+	Success bool              `json:"success"`
+	Errors  []cloudflareError `json:"errors"`
+	Result  json.RawMessage   `json:"result"`
+}
+
+type cloudflareError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type cloudflareRecord struct {
+	ID      string `json:"id,omitempty"`
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Content string `json:"content"`
+	TTL     int    `json:"ttl,omitempty"`
+}
+
+// NewCloudflare creates a provider for the zone named zone. A nil client
+// uses a client with a 10 seconds timeout.
+func NewCloudflare(endpoint, token, zone string, client *http.Client) *CloudflareProvider {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &CloudflareProvider{endpoint: strings.TrimSuffix(endpoint, "/"), token: token, zone: trimDot(zone), client: client}
+}
+
+func (p *CloudflareProvider) Get(ctx context.Context, name, recordType string) ([]string, error) {
+	records, err := p.records(ctx, name, recordType)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]string, 0, len(records))
+	for _, record := range records {
+		values = append(values, cloudflareValue(record))
+	}
+
+	return values, nil
+}
+
+// Set creates the missing values and deletes the ones no longer desired, as
+// Cloudflare stores every value as a separate record.
+func (p *CloudflareProvider) Set(ctx context.Context, name, recordType string, values []string, ttl int) error {
+	records, err := p.records(ctx, name, recordType)
+	if err != nil {
+		return err
+	}
+
+	zoneID, err := p.lookupZoneID(ctx)
+	if err != nil {
+		return err
+	}
+
+	desired := map[string]bool{}
+	for _, value := range values {
+		desired[value] = true
+	}
+
+	for _, record := range records {
+		value := cloudflareValue(record)
+		if desired[value] {
+			delete(desired, value)
+			continue
+		}
+		if err := p.do(ctx, http.MethodDelete, fmt.Sprintf("/zones/%s/dns_records/%s", zoneID, record.ID), nil, nil); err != nil {
+			return err
+		}
+	}
+
+	for _, value := range values {
+		if !desired[value] {
+			continue
+		}
+		record := cloudflareRecord{Type: recordType, Name: trimDot(name), Content: value, TTL: ttl}
+		if err := p.do(ctx, http.MethodPost, fmt.Sprintf("/zones/%s/dns_records", zoneID), record, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *CloudflareProvider) records(ctx context.Context, name, recordType string) ([]cloudflareRecord, error) {
+	zoneID, err := p.lookupZoneID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	q := url.Values{}
+	q.Set("type", recordType)
+	q.Set("name", trimDot(name))
+
+	records := []cloudflareRecord{}
+	if err := p.do(ctx, http.MethodGet, fmt.Sprintf("/zones/%s/dns_records?%s", zoneID, q.Encode()), nil, &records); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+func (p *CloudflareProvider) lookupZoneID(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.zoneID != "" {
+		return p.zoneID, nil
+	}
+
+	zones := []struct {
+		ID string `json:"id"`
+	}{}
+	if err := p.do(ctx, http.MethodGet, "/zones?"+url.Values{"name": {p.zone}}.Encode(), nil, &zones); err != nil {
+		return "", err
+	}
+	if len(zones) == 0 {
+		return "", fmt.Errorf("cloudflare zone %s not found", p.zone)
+	}
+
+	p.zoneID = zones[0].ID
+
+	return p.zoneID, nil
+}
+
+func (p *CloudflareProvider) do(ctx context.Context, method, path string, body, result interface{}) error {
+	var reqBody bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&reqBody).Encode(body); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, p.endpoint+path, &reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	res := cloudflareResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return fmt.Errorf("cloudflare %s %s: status %d: %w", method, path, resp.StatusCode, err)
+	}
+	if !res.Success || resp.StatusCode >= 300 {
+		msgs := []string{}
+		for _, e := range res.Errors {
+			msgs = append(msgs, fmt.Sprintf("%d %s", e.Code, e.Message))
+		}
+		return fmt.Errorf("cloudflare %s %s: status %d: %s", method, path, resp.StatusCode, strings.Join(msgs, ", "))
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	return json.Unmarshal(res.Result, result)
+}
+
+// cloudflareValue returns the record value, unquoting TXT contents that the
+// API may return quoted.
+func cloudflareValue(record cloudflareRecord) string {
+	if record.Type == "TXT" && strings.HasPrefix(record.Content, `"`) {
+		return unquoteTXT(record.Content)
+	}
+	if record.Type == "CNAME" {
+		return trimDot(record.Content)
+	}
+
+	return record.Content
+}
@@ -0,0 +1,45 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// FakeProvider is an in-memory Provider for tests.
+type FakeProvider struct {
+	mu      sync.Mutex
+	records map[string][]string
+
+	// Sets counts the calls to Set.
+	Sets int
+}
+
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{records: map[string][]string{}}
+}
+
+func (p *FakeProvider) Get(ctx context.Context, name, recordType string) ([]string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]string{}, p.records[fakeKey(name, recordType)]...), nil
+}
+
+func (p *FakeProvider) Set(ctx context.Context, name, recordType string, values []string, ttl int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.Sets++
+	if len(values) == 0 {
+		delete(p.records, fakeKey(name, recordType))
+		return nil
+	}
+	p.records[fakeKey(name, recordType)] = append([]string{}, values...)
+
+	return nil
+}
+
+func fakeKey(name, recordType string) string {
+	return recordType + " " + strings.ToLower(trimDot(name))
+}
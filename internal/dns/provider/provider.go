@@ -0,0 +1,74 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Provider manages the records of a DNS zone through the API of a DNS
+// provider. Records are handled as record sets: all the values of a name and
+// type. TXT values are unquoted and CNAME values have no trailing dot.
+type Provider interface {
+	// Get returns the values of the record set, empty when it doesn't exist.
+	Get(ctx context.Context, name, recordType string) ([]string, error)
+
+	// Set replaces the values of the record set. No values delete it.
+	Set(ctx context.Context, name, recordType string, values []string, ttl int) error
+}
+
+// Supported providers.
+const (
+	Cloudflare = "cloudflare"
+	Route53    = "route53"
+)
+
+// Keys of the credentials Secret.
+const (
+	CloudflareAPITokenKey     = "api-token"
+	Route53AccessKeyIDKey     = "access-key-id"
+	Route53SecretAccessKeyKey = "secret-access-key"
+	Route53SessionTokenKey    = "session-token"
+)
+
+// New creates the provider name for zone, with the credentials read from a
+// Secret. The zone is the zone name for Cloudflare and the hosted zone ID
+// for Route53. A nil client uses a client with a 10 seconds timeout.
+func New(name, zone string, credentials map[string][]byte, client *http.Client) (Provider, error) {
+	switch name {
+	case Cloudflare:
+		token, err := credential(credentials, CloudflareAPITokenKey)
+		if err != nil {
+			return nil, err
+		}
+		return NewCloudflare(DefaultCloudflareEndpoint, token, zone, client), nil
+	case Route53:
+		accessKeyID, err := credential(credentials, Route53AccessKeyIDKey)
+		if err != nil {
+			return nil, err
+		}
+		secretAccessKey, err := credential(credentials, Route53SecretAccessKeyKey)
+		if err != nil {
+			return nil, err
+		}
+		p := NewRoute53(DefaultRoute53Endpoint, zone, accessKeyID, secretAccessKey, client)
+		p.sessionToken = strings.TrimSpace(string(credentials[Route53SessionTokenKey]))
+		return p, nil
+	default:
+		return nil, fmt.Errorf("unknown dns provider %q", name)
+	}
+}
+
+func credential(credentials map[string][]byte, key string) (string, error) {
+	value := strings.TrimSpace(string(credentials[key]))
+	if value == "" {
+		return "", fmt.Errorf("missing %s in the dns provider credentials", key)
+	}
+
+	return value, nil
+}
+
+func trimDot(name string) string {
+	return strings.TrimSuffix(name, ".")
+}
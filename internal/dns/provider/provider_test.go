@@ -0,0 +1,227 @@
+package provider_test
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kannon-email/k8nnon/internal/dns/provider"
+)
+
+type cloudflareRecord struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Content string `json:"content"`
+	TTL     int    `json:"ttl"`
+}
+
+// cloudflareServer fakes the zones and dns_records endpoints of the
+// Cloudflare API for the example.com zone.
+func cloudflareServer(t *testing.T, records []cloudflareRecord) (*httptest.Server, *[]cloudflareRecord) {
+	t.Helper()
+
+	reply := func(w http.ResponseWriter, result interface{}) {
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "result": result}))
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+
+		switch {
+		case r.URL.Path == "/zones":
+			assert.Equal(t, "example.com", r.URL.Query().Get("name"))
+			reply(w, []map[string]string{{"id": "zone"}})
+		case r.URL.Path == "/zones/zone/dns_records" && r.Method == http.MethodGet:
+			res := []cloudflareRecord{}
+			for _, rec := range records {
+				if rec.Name == r.URL.Query().Get("name") && rec.Type == r.URL.Query().Get("type") {
+					res = append(res, rec)
+				}
+			}
+			reply(w, res)
+		case r.URL.Path == "/zones/zone/dns_records" && r.Method == http.MethodPost:
+			rec := cloudflareRecord{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&rec))
+			rec.ID = rec.Content
+			records = append(records, rec)
+			reply(w, rec)
+		case strings.HasPrefix(r.URL.Path, "/zones/zone/dns_records/") && r.Method == http.MethodDelete:
+			id := strings.TrimPrefix(r.URL.Path, "/zones/zone/dns_records/")
+			kept := []cloudflareRecord{}
+			for _, rec := range records {
+				if rec.ID != id {
+					kept = append(kept, rec)
+				}
+			}
+			records = kept
+			reply(w, map[string]string{"id": id})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			reply(w, nil)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, &records
+}
+
+func TestCloudflareGet(t *testing.T) {
+	srv, _ := cloudflareServer(t, []cloudflareRecord{
+		{ID: "1", Type: "TXT", Name: "example.com", Content: `"v=spf1 -all"`},
+		{ID: "2", Type: "TXT", Name: "example.com", Content: "google-site-verification=abc"},
+		{ID: "3", Type: "CNAME", Name: "stats.example.com", Content: "mx.example.com"},
+	})
+	p := provider.NewCloudflare(srv.URL, "token", "example.com", nil)
+
+	values, err := p.Get(context.Background(), "example.com", "TXT")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"v=spf1 -all", "google-site-verification=abc"}, values)
+
+	values, err = p.Get(context.Background(), "missing.example.com", "TXT")
+	require.NoError(t, err)
+	assert.Empty(t, values)
+}
+
+func TestCloudflareSet(t *testing.T) {
+	srv, records := cloudflareServer(t, []cloudflareRecord{
+		{ID: "1", Type: "TXT", Name: "example.com", Content: "v=spf1 -all"},
+		{ID: "2", Type: "TXT", Name: "example.com", Content: "google-site-verification=abc"},
+	})
+	p := provider.NewCloudflare(srv.URL, "token", "example.com", nil)
+
+	err := p.Set(context.Background(), "example.com", "TXT", []string{"v=spf1 include:mx.example.com -all", "google-site-verification=abc"}, 300)
+	require.NoError(t, err)
+
+	assert.Equal(t, []cloudflareRecord{
+		{ID: "2", Type: "TXT", Name: "example.com", Content: "google-site-verification=abc"},
+		{ID: "v=spf1 include:mx.example.com -all", Type: "TXT", Name: "example.com", Content: "v=spf1 include:mx.example.com -all", TTL: 300},
+	}, *records)
+}
+
+func TestCloudflareError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = io.WriteString(w, `{"success":false,"errors":[{"code":10000,"message":"Authentication error"}]}`)
+	}))
+	t.Cleanup(srv.Close)
+
+	_, err := provider.NewCloudflare(srv.URL, "token", "example.com", nil).Get(context.Background(), "example.com", "TXT")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Authentication error")
+}
+
+func TestRoute53Get(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2013-04-01/hostedzone/Z123/rrset", r.URL.Path)
+		assert.Equal(t, "example.com.", r.URL.Query().Get("name"))
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/"))
+		assert.Contains(t, r.Header.Get("Authorization"), "/us-east-1/route53/aws4_request, SignedHeaders=host;x-amz-date, Signature=")
+		assert.NotEmpty(t, r.Header.Get("X-Amz-Date"))
+
+		_, _ = io.WriteString(w, `<?xml version="1.0"?>
+<ListResourceRecordSetsResponse xmlns="https://route53.amazonaws.com/doc/2013-04-01/">
+  <ResourceRecordSets>
+    <ResourceRecordSet>
+      <Name>example.com.</Name>
+      <Type>TXT</Type>
+      <TTL>300</TTL>
+      <ResourceRecords>
+        <ResourceRecord><Value>"v=spf1 include:mx.example.com " "~all"</Value></ResourceRecord>
+      </ResourceRecords>
+    </ResourceRecordSet>
+  </ResourceRecordSets>
+</ListResourceRecordSetsResponse>`)
+	}))
+	t.Cleanup(srv.Close)
+
+	p := provider.NewRoute53(srv.URL, "/hostedzone/Z123", "AKID", "secret", nil)
+
+	values, err := p.Get(context.Background(), "example.com", "TXT")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"v=spf1 include:mx.example.com ~all"}, values)
+
+	// the listing continues with the next record set when none matches
+	values, err = p.Get(context.Background(), "example.com", "CNAME")
+	require.NoError(t, err)
+	assert.Empty(t, values)
+}
+
+func TestRoute53Set(t *testing.T) {
+	type change struct {
+		Action string   `xml:"ChangeBatch>Changes>Change>Action"`
+		Name   string   `xml:"ChangeBatch>Changes>Change>ResourceRecordSet>Name"`
+		Type   string   `xml:"ChangeBatch>Changes>Change>ResourceRecordSet>Type"`
+		TTL    int      `xml:"ChangeBatch>Changes>Change>ResourceRecordSet>TTL"`
+		Values []string `xml:"ChangeBatch>Changes>Change>ResourceRecordSet>ResourceRecords>ResourceRecord>Value"`
+	}
+
+	var got change
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/2013-04-01/hostedzone/Z123/rrset", r.URL.Path)
+		require.NoError(t, xml.NewDecoder(r.Body).Decode(&got))
+		_, _ = io.WriteString(w, `<ChangeResourceRecordSetsResponse><ChangeInfo><Status>PENDING</Status></ChangeInfo></ChangeResourceRecordSetsResponse>`)
+	}))
+	t.Cleanup(srv.Close)
+
+	key := strings.Repeat("a", 300)
+	p := provider.NewRoute53(srv.URL, "Z123", "AKID", "secret", nil)
+	require.NoError(t, p.Set(context.Background(), "sel._domainkey.example.com", "TXT", []string{"k=rsa; p=" + key}, 300))
+
+	assert.Equal(t, change{
+		Action: "UPSERT",
+		Name:   "sel._domainkey.example.com.",
+		Type:   "TXT",
+		TTL:    300,
+		Values: []string{`"k=rsa; p=` + key[:246] + `" "` + key[246:] + `"`},
+	}, got)
+}
+
+func TestRoute53SetDeletesEmptyRecordSet(t *testing.T) {
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			_, _ = io.WriteString(w, `<ListResourceRecordSetsResponse><ResourceRecordSets><ResourceRecordSet>
+<Name>stats.example.com.</Name><Type>CNAME</Type><TTL>60</TTL>
+<ResourceRecords><ResourceRecord><Value>mx.example.com</Value></ResourceRecord></ResourceRecords>
+</ResourceRecordSet></ResourceRecordSets></ListResourceRecordSetsResponse>`)
+			return
+		}
+
+		data, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		body = string(data)
+	}))
+	t.Cleanup(srv.Close)
+
+	p := provider.NewRoute53(srv.URL, "Z123", "AKID", "secret", nil)
+	require.NoError(t, p.Set(context.Background(), "stats.example.com", "CNAME", nil, 300))
+
+	assert.Contains(t, body, "<Action>DELETE</Action>")
+	assert.Contains(t, body, "<TTL>60</TTL>", "should delete the record set as stored")
+	assert.Contains(t, body, "<Value>mx.example.com</Value>")
+}
+
+func TestNew(t *testing.T) {
+	_, err := provider.New(provider.Cloudflare, "example.com", map[string][]byte{}, nil)
+	assert.Error(t, err, "should require the api token")
+
+	p, err := provider.New(provider.Cloudflare, "example.com", map[string][]byte{provider.CloudflareAPITokenKey: []byte("token\n")}, nil)
+	require.NoError(t, err)
+	assert.IsType(t, &provider.CloudflareProvider{}, p)
+
+	_, err = provider.New(provider.Route53, "Z123", map[string][]byte{provider.Route53AccessKeyIDKey: []byte("AKID")}, nil)
+	assert.Error(t, err, "should require the secret access key")
+
+	_, err = provider.New("bind", "example.com", nil, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,278 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultRoute53Endpoint is the global endpoint of the Route53 API.
+const DefaultRoute53Endpoint = "https://route53.amazonaws.com"
+
+// route53Region is the region Route53 requests are signed for, as the
+// service is global.
+const route53Region = "us-east-1"
+
+const route53Namespace = "https://route53.amazonaws.com/doc/2013-04-01/"
+
+// Route53Provider manages the records of a hosted zone with the Route53
+// API, authenticated with an access key signing the requests.
+type Route53Provider struct {
+	endpoint        string
+	zoneID          string
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+	client          *http.Client
+
+	// now returns the signing time, it defaults to time.Now.
+	now func() time.Time
+}
+
+type route53RecordSet struct {
+	Name    string          `xml:"Name"`
+	Type    string          `xml:"Type"`
+	TTL     int             `xml:"TTL,omitempty"`
+	Records []route53Record `xml:"ResourceRecords>ResourceRecord"`
+}
+
+type route53Record struct {
+	Value string `xml:"Value"`
+}
+
+type route53ListResponse struct {
+	RecordSets []route53RecordSet `xml:"ResourceRecordSets>ResourceRecordSet"`
+}
+
+type route53ChangeRequest struct {
+	XMLName xml.Name        `xml:"ChangeResourceRecordSetsRequest"`
+	Xmlns   string          `xml:"xmlns,attr"`
+	Changes []route53Change `xml:"ChangeBatch>Changes>Change"`
+}
+
+type route53Change struct {
+	Action    string           `xml:"Action"`
+	RecordSet route53RecordSet `xml:"ResourceRecordSet"`
+}
+
+type route53ErrorResponse struct {
+	Code    string `xml:"Error>Code"`
+	Message string `xml:"Error>Message"`
+}
+
+// NewRoute53 creates a provider for the hosted zone zoneID. A nil client
+// uses a client with a 10 seconds timeout.
+func NewRoute53(endpoint, zoneID, accessKeyID, secretAccessKey string, client *http.Client) *Route53Provider {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &Route53Provider{
+		endpoint:        strings.TrimSuffix(endpoint, "/"),
+		zoneID:          strings.TrimPrefix(zoneID, "/hostedzone/"),
+		accessKeyID:     accessKeyID,
+		secretAccessKey: secretAccessKey,
+		client:          client,
+		now:             time.Now,
+	}
+}
+
+func (p *Route53Provider) Get(ctx context.Context, name, recordType string) ([]string, error) {
+	set, err := p.recordSet(ctx, name, recordType)
+	if err != nil || set == nil {
+		return []string{}, err
+	}
+
+	values := []string{}
+	for _, record := range set.Records {
+		values = append(values, route53Value(recordType, record.Value))
+	}
+
+	return values, nil
+}
+
+// Set upserts the record set, or deletes it when values is empty.
+func (p *Route53Provider) Set(ctx context.Context, name, recordType string, values []string, ttl int) error {
+	if len(values) == 0 {
+		return p.delete(ctx, name, recordType)
+	}
+
+	set := route53RecordSet{Name: fqdn(name), Type: recordType, TTL: ttl}
+	for _, value := range values {
+		if recordType == "TXT" {
+			value = quoteTXT(value)
+		}
+		set.Records = append(set.Records, route53Record{Value: value})
+	}
+
+	req := route53ChangeRequest{
+		Xmlns:   route53Namespace,
+		Changes: []route53Change{{Action: "UPSERT", RecordSet: set}},
+	}
+
+	return p.do(ctx, http.MethodPost, "/rrset", req, nil)
+}
+
+// delete removes a record set, which Route53 only accepts with its current
+// values and TTL.
+func (p *Route53Provider) delete(ctx context.Context, name, recordType string) error {
+	set, err := p.recordSet(ctx, name, recordType)
+	if err != nil || set == nil {
+		return err
+	}
+
+	req := route53ChangeRequest{
+		Xmlns:   route53Namespace,
+		Changes: []route53Change{{Action: "DELETE", RecordSet: *set}},
+	}
+
+	return p.do(ctx, http.MethodPost, "/rrset", req, nil)
+}
+
+// recordSet returns the record set of name and type as stored by Route53,
+// nil when it doesn't exist.
+func (p *Route53Provider) recordSet(ctx context.Context, name, recordType string) (*route53RecordSet, error) {
+	q := url.Values{}
+	q.Set("name", fqdn(name))
+	q.Set("type", recordType)
+	q.Set("maxitems", "1")
+
+	res := route53ListResponse{}
+	if err := p.do(ctx, http.MethodGet, "/rrset?"+q.Encode(), nil, &res); err != nil {
+		return nil, err
+	}
+
+	// the listing starts at the requested name, it returns the next record
+	// set when the requested one doesn't exist
+	for i := range res.RecordSets {
+		set := &res.RecordSets[i]
+		if strings.EqualFold(set.Name, fqdn(name)) && set.Type == recordType {
+			return set, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (p *Route53Provider) do(ctx context.Context, method, path string, body, result interface{}) error {
+	payload := []byte{}
+	if body != nil {
+		var err error
+		payload, err = xml.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = append([]byte(xml.Header), payload...)
+	}
+
+	u := fmt.Sprintf("%s/2013-04-01/hostedzone/%s%s", p.endpoint, p.zoneID, path)
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "text/xml")
+	}
+	p.sign(req, payload)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 300 {
+		e := route53ErrorResponse{}
+		if xml.Unmarshal(data, &e) == nil && e.Code != "" {
+			return fmt.Errorf("route53 %s %s: status %d: %s: %s", method, path, resp.StatusCode, e.Code, e.Message)
+		}
+		return fmt.Errorf("route53 %s %s: status %d", method, path, resp.StatusCode)
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	return xml.Unmarshal(data, result)
+}
+
+// sign adds an AWS Signature Version 4 to req.
+func (p *Route53Provider) sign(req *http.Request, payload []byte) {
+	t := p.now().UTC()
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if p.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", p.sessionToken)
+	}
+
+	headers := []string{"host", "x-amz-date"}
+	canonicalHeaders := fmt.Sprintf("host:%s\nx-amz-date:%s\n", req.URL.Host, amzDate)
+	if p.sessionToken != "" {
+		headers = append(headers, "x-amz-security-token")
+		canonicalHeaders += fmt.Sprintf("x-amz-security-token:%s\n", p.sessionToken)
+	}
+	signedHeaders := strings.Join(headers, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		strings.ReplaceAll(req.URL.Query().Encode(), "+", "%20"),
+		canonicalHeaders,
+		signedHeaders,
+		hexSHA256(payload),
+	}, "\n")
+
+	scope := fmt.Sprintf("%s/%s/route53/aws4_request", date, route53Region)
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hexSHA256([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+p.secretAccessKey), date)
+	key = hmacSHA256(key, route53Region)
+	key = hmacSHA256(key, "route53")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		p.accessKeyID, scope, signedHeaders, signature))
+}
+
+func hexSHA256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func route53Value(recordType, value string) string {
+	switch recordType {
+	case "TXT":
+		return unquoteTXT(value)
+	case "CNAME":
+		return trimDot(value)
+	default:
+		return value
+	}
+}
+
+func fqdn(name string) string {
+	return trimDot(name) + "."
+}
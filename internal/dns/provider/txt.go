@@ -0,0 +1,50 @@
+package provider
+
+import "strings"
+
+// maxTXTString is the maximum length of a single string of a TXT record.
+const maxTXTString = 255
+
+// quoteTXT formats a TXT value in the zone file syntax, split in strings of
+// at most 255 characters as long DKIM keys require.
+func quoteTXT(value string) string {
+	parts := []string{}
+	for len(value) > maxTXTString {
+		parts = append(parts, value[:maxTXTString])
+		value = value[maxTXTString:]
+	}
+	parts = append(parts, value)
+
+	for i, part := range parts {
+		part = strings.ReplaceAll(part, `\`, `\\`)
+		parts[i] = `"` + strings.ReplaceAll(part, `"`, `\"`) + `"`
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// unquoteTXT joins the quoted strings of a TXT value in the zone file
+// syntax. A value without quotes is returned as is.
+func unquoteTXT(value string) string {
+	if !strings.HasPrefix(value, `"`) {
+		return value
+	}
+
+	var b strings.Builder
+	inQuotes, escaped := false, false
+	for _, c := range value {
+		switch {
+		case escaped:
+			b.WriteRune(c)
+			escaped = false
+		case c == '\\' && inQuotes:
+			escaped = true
+		case c == '"':
+			inQuotes = !inQuotes
+		case inQuotes:
+			b.WriteRune(c)
+		}
+	}
+
+	return b.String()
+}
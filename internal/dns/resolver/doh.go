@@ -0,0 +1,340 @@
+package resolver
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultDoHEndpoints are public DNS-over-HTTPS endpoints speaking the JSON API.
+var DefaultDoHEndpoints = []string{
+	"https://dns.google/resolve",
+	"https://cloudflare-dns.com/dns-query",
+}
+
+// DNS record types and response codes used by the JSON API.
+const (
+	typeA     = 1
+	typeCNAME = 5
+	typePTR   = 12
+	typeMX    = 15
+	typeTXT   = 16
+	typeAAAA  = 28
+
+	rcodeSuccess  = 0
+	rcodeServFail = 2
+	rcodeNXDomain = 3
+)
+
+// DoHResolver resolves names with the JSON API of a DNS-over-HTTPS endpoint,
+// such as https://dns.google/resolve, for environments where outbound port
+// 53 is blocked.
+type DoHResolver struct {
+	endpoint string
+	client   *http.Client
+}
+
+type dohResponse struct {
+	Status int         `json:"Status"`
+	Answer []dohAnswer `json:"Answer"`
+}
+
+type dohAnswer struct {
+	Name string `json:"name"`
+	Type int    `json:"type"`
+	Data string `json:"data"`
+	TTL  uint32 `json:"TTL"`
+}
+
+// NewDoHResolvers creates resolvers querying the JSON API of DoH endpoints
+// given as url[#server-name]. The certificate is verified against the
+// server name, or the host of the URL. A nil tlsConfig uses the system
+// roots.
+func NewDoHResolvers(tlsConfig *tls.Config, endpoints ...string) []Resolver {
+	var client *http.Client
+	if tlsConfig != nil {
+		client = &http.Client{Timeout: 10 * time.Second, Transport: newDoHTransport(tlsConfig)}
+	}
+
+	resolvers := make([]Resolver, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		endpoint, serverName, _ := strings.Cut(endpoint, "#")
+		if serverName == "" {
+			resolvers = append(resolvers, NewDoHResolver(endpoint, client))
+			continue
+		}
+
+		config := &tls.Config{MinVersion: tls.VersionTLS12}
+		if tlsConfig != nil {
+			config = tlsConfig.Clone()
+		}
+		config.ServerName = serverName
+		resolvers = append(resolvers, NewDoHResolver(endpoint, &http.Client{Timeout: 10 * time.Second, Transport: newDoHTransport(config)}))
+	}
+
+	return resolvers
+}
+
+// newDoHTransport returns a transport with the settings of
+// http.DefaultTransport and tlsConfig. It is built from scratch rather than
+// cloned, since http.DefaultTransport may have been wrapped.
+func newDoHTransport(tlsConfig *tls.Config) *http.Transport {
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+		TLSClientConfig:       tlsConfig,
+	}
+}
+
+// NewDoHResolver creates a resolver querying endpoint. A nil client uses a
+// client with a 10 seconds timeout.
+func NewDoHResolver(endpoint string, client *http.Client) *DoHResolver {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &DoHResolver{endpoint: endpoint, client: client}
+}
+
+func (d *DoHResolver) String() string {
+	return d.endpoint
+}
+
+func (d *DoHResolver) LookupCNAME(ctx context.Context, name string) (string, error) {
+	answers, err := d.query(ctx, name, typeCNAME)
+	if err != nil {
+		return "", err
+	}
+
+	// like net.Resolver, return the end of the CNAME chain or the name itself
+	cname := fqdn(name)
+	for _, a := range answers {
+		if a.Type == typeCNAME {
+			cname = a.Data
+		}
+	}
+
+	return cname, nil
+}
+
+// LookupAddr returns the names of the PTR records of addr.
+func (d *DoHResolver) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	name, err := reverseName(addr)
+	if err != nil {
+		return nil, d.dnsError(addr, err.Error())
+	}
+
+	answers, err := d.query(ctx, name, typePTR)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, a := range answers {
+		if a.Type == typePTR {
+			names = append(names, a.Data)
+		}
+	}
+
+	return names, nil
+}
+
+// LookupHost returns the IPv4 and IPv6 addresses of host. Like
+// net.Resolver, a host without addresses is not found.
+func (d *DoHResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	addrs := []string{}
+	for _, recordType := range []int{typeA, typeAAAA} {
+		answers, err := d.query(ctx, host, recordType)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, a := range answers {
+			if a.Type == recordType {
+				addrs = append(addrs, a.Data)
+			}
+		}
+	}
+
+	if len(addrs) == 0 {
+		return nil, &net.DNSError{Err: "no such host", Name: host, Server: d.endpoint, IsNotFound: true}
+	}
+	return addrs, nil
+}
+
+func (d *DoHResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	answers, err := d.query(ctx, name, typeTXT)
+	if err != nil {
+		return nil, err
+	}
+
+	txts := []string{}
+	for _, a := range answers {
+		if a.Type == typeTXT {
+			txts = append(txts, joinTXTStrings(a.Data))
+		}
+	}
+
+	return txts, nil
+}
+
+func (d *DoHResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	answers, err := d.query(ctx, name, typeMX)
+	if err != nil {
+		return nil, err
+	}
+
+	mxs := []*net.MX{}
+	for _, a := range answers {
+		if a.Type != typeMX {
+			continue
+		}
+
+		fields := strings.Fields(a.Data)
+		if len(fields) != 2 {
+			return nil, d.dnsError(name, fmt.Sprintf("malformed MX record %q", a.Data))
+		}
+
+		pref, err := strconv.ParseUint(fields[0], 10, 16)
+		if err != nil {
+			return nil, d.dnsError(name, fmt.Sprintf("malformed MX record %q", a.Data))
+		}
+
+		mxs = append(mxs, &net.MX{Host: fields[1], Pref: uint16(pref)})
+	}
+
+	return mxs, nil
+}
+
+func (d *DoHResolver) query(ctx context.Context, name string, recordType int) ([]dohAnswer, error) {
+	u, err := url.Parse(d.endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	q := u.Query()
+	q.Set("name", name)
+	q.Set("type", strconv.Itoa(recordType))
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/dns-json")
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return nil, &net.DNSError{Err: err.Error(), Name: name, Server: d.endpoint, IsTimeout: isTimeout(err)}
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, d.dnsError(name, fmt.Sprintf("unexpected HTTP status %s", res.Status))
+	}
+
+	body := dohResponse{}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, d.dnsError(name, fmt.Sprintf("invalid response: %v", err))
+	}
+
+	switch body.Status {
+	case rcodeSuccess:
+		for _, a := range body.Answer {
+			reportTTL(ctx, time.Duration(a.TTL)*time.Second)
+		}
+		return body.Answer, nil
+	case rcodeNXDomain:
+		return nil, &net.DNSError{Err: "no such host", Name: name, Server: d.endpoint, IsNotFound: true}
+	case rcodeServFail:
+		// like the Go resolver does
+		return nil, &net.DNSError{Err: "server misbehaving", Name: name, Server: d.endpoint, IsTemporary: true}
+	default:
+		return nil, d.dnsError(name, fmt.Sprintf("server returned rcode %d", body.Status))
+	}
+}
+
+func (d *DoHResolver) dnsError(name, msg string) error {
+	return &net.DNSError{Err: msg, Name: name, Server: d.endpoint}
+}
+
+func isTimeout(err error) bool {
+	netErr, ok := err.(net.Error)
+	return ok && netErr.Timeout()
+}
+
+// joinTXTStrings concatenates the quoted character strings of a TXT record
+// as presented by the JSON API, e.g. `"v=spf1 " "-all"`.
+func joinTXTStrings(data string) string {
+	if !strings.HasPrefix(data, `"`) {
+		return data
+	}
+
+	var b strings.Builder
+	inQuotes, escaped := false, false
+	for _, c := range data {
+		switch {
+		case escaped:
+			b.WriteRune(c)
+			escaped = false
+		case c == '\\' && inQuotes:
+			escaped = true
+		case c == '"':
+			inQuotes = !inQuotes
+		case inQuotes:
+			b.WriteRune(c)
+		}
+	}
+
+	return b.String()
+}
+
+// reverseName returns the in-addr.arpa or ip6.arpa name of the PTR records
+// of addr.
+func reverseName(addr string) (string, error) {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return "", fmt.Errorf("unrecognized address %q", addr)
+	}
+
+	var b strings.Builder
+	if ip4 := ip.To4(); ip4 != nil {
+		for i := len(ip4) - 1; i >= 0; i-- {
+			fmt.Fprintf(&b, "%d.", ip4[i])
+		}
+		b.WriteString("in-addr.arpa.")
+		return b.String(), nil
+	}
+
+	const hex = "0123456789abcdef"
+	for i := len(ip) - 1; i >= 0; i-- {
+		b.WriteByte(hex[ip[i]&0xf])
+		b.WriteByte('.')
+		b.WriteByte(hex[ip[i]>>4])
+		b.WriteByte('.')
+	}
+	b.WriteString("ip6.arpa.")
+	return b.String(), nil
+}
+
+func fqdn(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
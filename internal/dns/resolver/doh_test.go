@@ -0,0 +1,207 @@
+package resolver_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kannon-email/k8nnon/internal/dns/resolver"
+	"github.com/kannon-email/k8nnon/internal/resilience"
+)
+
+type answer struct {
+	Name string `json:"name"`
+	Type int    `json:"type"`
+	Data string `json:"data"`
+	TTL  int    `json:"TTL"`
+}
+
+func TestDoHLookupTXT(t *testing.T) {
+	r := createDoHResolver(t, map[string]interface{}{
+		"Status": 0,
+		"Answer": []answer{
+			{Name: "example.com.", Type: 16, Data: `"v=spf1 include:mx.example.com " "~all"`},
+		},
+	})
+
+	res, err := r.LookupTXT(context.Background(), "example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"v=spf1 include:mx.example.com ~all"}, res)
+}
+
+func TestDoHLookupCNAME(t *testing.T) {
+	r := createDoHResolver(t, map[string]interface{}{
+		"Status": 0,
+		"Answer": []answer{
+			{Name: "stats.example.com.", Type: 5, Data: "mx.example.com."},
+		},
+	})
+
+	res, err := r.LookupCNAME(context.Background(), "stats.example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, "mx.example.com.", res)
+}
+
+func TestDoHLookupMX(t *testing.T) {
+	r := createDoHResolver(t, map[string]interface{}{
+		"Status": 0,
+		"Answer": []answer{
+			{Name: "example.com.", Type: 15, Data: "10 mx.example.com."},
+		},
+	})
+
+	res, err := r.LookupMX(context.Background(), "example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, []*net.MX{{Host: "mx.example.com.", Pref: 10}}, res)
+}
+
+func TestDoHLookupAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		name string
+	}{
+		{"192.0.2.1", "1.2.0.192.in-addr.arpa."},
+		{"2001:db8::1", "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, tt.name, r.URL.Query().Get("name"))
+				assert.Equal(t, "12", r.URL.Query().Get("type"))
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{
+					"Status": 0,
+					"Answer": []answer{{Name: tt.name, Type: 12, Data: "mail.example.com."}},
+				})
+			}))
+			t.Cleanup(srv.Close)
+
+			res, err := resolver.NewDoHResolver(srv.URL, srv.Client()).LookupAddr(context.Background(), tt.addr)
+			assert.Nil(t, err)
+			assert.Equal(t, []string{"mail.example.com."}, res)
+		})
+	}
+}
+
+func TestDoHLookupHost(t *testing.T) {
+	r := createDoHResolver(t, map[string]interface{}{
+		"Status": 0,
+		"Answer": []answer{
+			{Name: "mail.example.com.", Type: 1, Data: "192.0.2.1"},
+			{Name: "mail.example.com.", Type: 28, Data: "2001:db8::1"},
+		},
+	})
+
+	res, err := r.LookupHost(context.Background(), "mail.example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"192.0.2.1", "2001:db8::1"}, res)
+}
+
+func TestDoHReportsTTL(t *testing.T) {
+	r := createDoHResolver(t, map[string]interface{}{
+		"Status": 0,
+		"Answer": []answer{
+			{Name: "example.com.", Type: 16, Data: `"v=spf1 -all"`, TTL: 300},
+			{Name: "example.com.", Type: 16, Data: `"google-site-verification=abc"`, TTL: 120},
+		},
+	})
+
+	ctx, ttl := resolver.WithTTLRecorder(context.Background())
+	_, err := r.LookupTXT(ctx, "example.com")
+	assert.Nil(t, err)
+
+	got, ok := ttl()
+	assert.True(t, ok, "should report the TTL")
+	assert.Equal(t, 120*time.Second, got, "should report the lowest TTL")
+}
+
+func TestDoHNXDomain(t *testing.T) {
+	r := createDoHResolver(t, map[string]interface{}{
+		"Status": 3,
+	})
+
+	_, err := r.LookupTXT(context.Background(), "missing.example.com")
+	dnsErr, ok := err.(*net.DNSError)
+	assert.True(t, ok, "should return a DNSError")
+	assert.True(t, dnsErr.IsNotFound, "should be a not found error")
+}
+
+func TestDoHResolversWithWrappedDefaultTransport(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"Status": 0,
+			"Answer": []answer{{Name: "example.com.", Type: 16, Data: `"v=spf1 ~all"`}},
+		})
+	}))
+	defer srv.Close()
+
+	// main wraps http.DefaultTransport with a circuit breaker per host
+	defaultTransport := http.DefaultTransport
+	http.DefaultTransport = resilience.NewTransport(defaultTransport, nil, 5, time.Minute)
+	defer func() { http.DefaultTransport = defaultTransport }()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+	resolvers := resolver.NewDoHResolvers(&tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}, srv.URL)
+	assert.Len(t, resolvers, 1)
+
+	txt, err := resolvers[0].LookupTXT(context.Background(), "example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"v=spf1 ~all"}, txt)
+}
+
+func TestDoHResolversServerName(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"Status": 0,
+			"Answer": []answer{{Name: "example.com.", Type: 16, Data: `"v=spf1 ~all"`}},
+		})
+	}))
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+	config := &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}
+
+	// the test certificate is valid for example.com, each endpoint is
+	// verified against its own name
+	resolvers := resolver.NewDoHResolvers(config, srv.URL+"#example.com", srv.URL+"#dns.example.org")
+	require.Len(t, resolvers, 2)
+	assert.Equal(t, srv.URL, resolver.Name(resolvers[0]))
+
+	txt, err := resolvers[0].LookupTXT(context.Background(), "example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"v=spf1 ~all"}, txt)
+
+	_, err = resolvers[1].LookupTXT(context.Background(), "example.com")
+	assert.Error(t, err, "should reject a certificate for another name")
+}
+
+func createDoHResolver(t *testing.T, response map[string]interface{}) *resolver.DoHResolver {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/dns-json", r.Header.Get("Accept"))
+		assert.NotEmpty(t, r.URL.Query().Get("name"))
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(srv.Close)
+
+	return resolver.NewDoHResolver(srv.URL, srv.Client())
+}
+
+func TestResolverName(t *testing.T) {
+	assert.Equal(t, "1.1.1.1:53", resolver.Name(resolver.NewResolvers("1.1.1.1")[0]))
+	assert.Equal(t, "[2606:4700:4700::1111]:53", resolver.Name(resolver.NewResolvers("2606:4700:4700::1111")[0]))
+	assert.Equal(t, "127.0.0.1:5353", resolver.Name(resolver.NewResolvers("127.0.0.1:5353")[0]))
+	assert.Equal(t, "https://dns.google/resolve", resolver.Name(resolver.NewDoHResolver("https://dns.google/resolve", nil)))
+}
@@ -0,0 +1,99 @@
+package resolver
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultDoTEndpoints are public DNS-over-TLS endpoints, with the name in
+// their certificates.
+var DefaultDoTEndpoints = []string{
+	"1.1.1.1:853#cloudflare-dns.com",
+	"8.8.8.8:853#dns.google",
+}
+
+// dotResolver is a net.Resolver speaking DNS-over-TLS with a single server.
+type dotResolver struct {
+	*net.Resolver
+	endpoint string
+}
+
+// NewDoTResolvers creates resolvers querying DNS-over-TLS endpoints given as
+// host[:port][#server-name], for environments where outbound port 53 is
+// blocked. The port defaults to 853. The certificate is verified against
+// the server name, or the host. A nil tlsConfig uses the system roots.
+func NewDoTResolvers(tlsConfig *tls.Config, endpoints ...string) []Resolver {
+	resolvers := make([]Resolver, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		resolvers = append(resolvers, NewDoTResolver(endpoint, tlsConfig))
+	}
+
+	return resolvers
+}
+
+// NewDoTResolver creates a resolver querying a DNS-over-TLS endpoint, see
+// NewDoTResolvers.
+func NewDoTResolver(endpoint string, tlsConfig *tls.Config) Resolver {
+	addr, serverName, _ := strings.Cut(endpoint, "#")
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "853")
+	}
+
+	config := &tls.Config{}
+	if tlsConfig != nil {
+		config = tlsConfig.Clone()
+	}
+	config.ServerName = serverName
+	if serverName == "" {
+		config.ServerName, _, _ = net.SplitHostPort(addr)
+	}
+
+	return dotResolver{
+		Resolver: &net.Resolver{
+			PreferGo: true,
+			// a TLS connection is not a PacketConn, so the resolver
+			// speaks DNS over TCP on it
+			Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+				d := tls.Dialer{
+					NetDialer: &net.Dialer{Timeout: 10 * time.Second},
+					Config:    config,
+				}
+				return d.DialContext(ctx, "tcp", addr)
+			},
+		},
+		endpoint: endpoint,
+	}
+}
+
+func (r dotResolver) String() string {
+	return "tls://" + r.endpoint
+}
+
+// TLSConfig returns the TLS configuration of the DoH and DoT resolvers.
+// caBundle is a PEM file with the roots the servers are verified against,
+// the system roots are used when empty. The name verified in the server
+// certificates is set per endpoint.
+func TLSConfig(caBundle string) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caBundle == "" {
+		return config, nil
+	}
+
+	pem, err := os.ReadFile(caBundle)
+	if err != nil {
+		return nil, fmt.Errorf("read the dns ca bundle: %w", err)
+	}
+
+	config.RootCAs = x509.NewCertPool()
+	if !config.RootCAs.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificate found in the dns ca bundle %s", caBundle)
+	}
+
+	return config, nil
+}
@@ -0,0 +1,85 @@
+package resolver_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	mockdns "github.com/foxcpp/go-mockdns"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kannon-email/k8nnon/internal/dns/resolver"
+)
+
+func TestDoTLookupTXT(t *testing.T) {
+	addr, caBundle := startDoTServer(t, map[string]mockdns.Zone{
+		"example.com.": {TXT: []string{"v=spf1 include:mx.example.com ~all"}},
+	})
+
+	config, err := resolver.TLSConfig(caBundle)
+	require.NoError(t, err)
+
+	// the test certificate is valid for example.com
+	r := resolver.NewDoTResolver(addr+"#example.com", config)
+	res, err := r.LookupTXT(context.Background(), "example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"v=spf1 include:mx.example.com ~all"}, res)
+	assert.Equal(t, "tls://"+addr+"#example.com", resolver.Name(r))
+}
+
+func TestDoTVerifiesServerName(t *testing.T) {
+	addr, caBundle := startDoTServer(t, map[string]mockdns.Zone{
+		"example.com.": {TXT: []string{"v=spf1 ~all"}},
+	})
+
+	config, err := resolver.TLSConfig(caBundle)
+	require.NoError(t, err)
+
+	_, err = resolver.NewDoTResolver(addr+"#dns.example.org", config).LookupTXT(context.Background(), "example.com")
+	assert.Error(t, err, "should reject a certificate for another name")
+}
+
+func TestTLSConfigInvalidBundle(t *testing.T) {
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(bundle, []byte("not a certificate"), 0o600))
+
+	_, err := resolver.TLSConfig(bundle)
+	assert.Error(t, err)
+}
+
+// startDoTServer serves zones over DNS-over-TLS with the httptest
+// certificate and returns the address and a CA bundle trusting it.
+func startDoTServer(t *testing.T, zones map[string]mockdns.Zone) (string, string) {
+	t.Helper()
+
+	certSrv := httptest.NewUnstartedServer(http.NotFoundHandler())
+	certSrv.StartTLS()
+	cert := certSrv.TLS.Certificates[0]
+	certSrv.Close()
+
+	handler, err := mockdns.NewServer(zones, false)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = handler.Close() })
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	require.NoError(t, err)
+	srv := &dns.Server{Listener: l, Net: "tcp-tls", Handler: handler}
+	go func() { _ = srv.ActivateAndServe() }()
+	t.Cleanup(func() { _ = srv.Shutdown() })
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(bundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leaf.Raw}), 0o600))
+
+	return l.Addr().(*net.TCPAddr).String(), bundle
+}
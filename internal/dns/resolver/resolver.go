@@ -2,22 +2,33 @@ package resolver
 
 import (
 	"context"
+	"fmt"
 	"net"
 	"time"
 )
 
 type Resolver interface {
-	// LookupAddr(addr string) (names []string, err error)
+	LookupAddr(ctx context.Context, addr string) (names []string, err error)
 	LookupCNAME(ctx context.Context, name string) (cname string, err error)
-	// LookupHost(host string) (addrs []string, err error)
+	LookupHost(ctx context.Context, host string) (addrs []string, err error)
 	// LookupIP(host string) (ips []net.IP, err error)
-	// LookupMX(name string) (mxs []*net.MX, err error)
+	LookupMX(ctx context.Context, name string) (mxs []*net.MX, err error)
 	// LookupNS(name string) (nss []*net.NS, err error)
 	// LookupPort(network, service string) (port int, err error)
 	// LookupSRV(service, proto, name string) (cname string, addrs []*net.SRV, err error)
 	LookupTXT(ctx context.Context, name string) (txts []string, err error)
 }
 
+// Name returns the address or endpoint of r, empty when it has none.
+func Name(r Resolver) string {
+	if named, ok := r.(fmt.Stringer); ok {
+		return named.String()
+	}
+	return ""
+}
+
+// NewResolvers creates resolvers querying the given servers over UDP. An
+// address without a port uses port 53.
 func NewResolvers(address ...string) []Resolver {
 	resolvers := make([]Resolver, 0, len(address))
 	for _, addr := range address {
@@ -27,14 +38,31 @@ func NewResolvers(address ...string) []Resolver {
 	return resolvers
 }
 
+// udpResolver is a net.Resolver bound to a single server.
+type udpResolver struct {
+	*net.Resolver
+	addr string
+}
+
 func newResolver(addr string) Resolver {
-	return &net.Resolver{
-		PreferGo: true,
-		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
-			d := net.Dialer{
-				Timeout: time.Millisecond * time.Duration(10000),
-			}
-			return d.DialContext(ctx, "udp", addr+":53")
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "53")
+	}
+
+	return udpResolver{
+		Resolver: &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+				d := net.Dialer{
+					Timeout: time.Millisecond * time.Duration(10000),
+				}
+				return d.DialContext(ctx, "udp", addr)
+			},
 		},
+		addr: addr,
 	}
 }
+
+func (r udpResolver) String() string {
+	return r.addr
+}
@@ -0,0 +1,45 @@
+package resolver
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type ttlKey struct{}
+
+// ttlRecorder keeps the lowest TTL reported by the lookups of a context.
+type ttlRecorder struct {
+	m   sync.Mutex
+	ttl time.Duration
+	set bool
+}
+
+// WithTTLRecorder returns a context whose lookups report the TTL of their
+// answers, and a function returning the lowest reported TTL. The TTL is
+// unknown when the resolver does not expose it, e.g. for net.Resolver.
+func WithTTLRecorder(ctx context.Context) (context.Context, func() (time.Duration, bool)) {
+	rec := &ttlRecorder{}
+	return context.WithValue(ctx, ttlKey{}, rec), rec.get
+}
+
+func (r *ttlRecorder) get() (time.Duration, bool) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	return r.ttl, r.set
+}
+
+// reportTTL records the TTL of an answer on the recorder of ctx, if any.
+func reportTTL(ctx context.Context, ttl time.Duration) {
+	rec, ok := ctx.Value(ttlKey{}).(*ttlRecorder)
+	if !ok {
+		return
+	}
+
+	rec.m.Lock()
+	defer rec.m.Unlock()
+	if !rec.set || ttl < rec.ttl {
+		rec.ttl = ttl
+		rec.set = true
+	}
+}
@@ -0,0 +1,324 @@
+package kannon
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// adminService is the Connect service of the Kannon admin API.
+const adminService = "pkg.kannon.admin.apiv1.ApiService"
+
+// mailerService is the Connect service of the Kannon mailer API, where the
+// domains send their messages.
+const mailerService = "pkg.kannon.mailer.apiv1.Mailer"
+
+// ErrNotFound is returned when the domain is not registered with Kannon.
+var ErrNotFound = errors.New("domain not registered with kannon")
+
+// ErrUnsupported is returned when the Kannon API does not implement a call.
+var ErrUnsupported = errors.New("not supported by the kannon api")
+
+// Domain is a domain registered with Kannon.
+type Domain struct {
+	Domain string `json:"domain"`
+
+	// Key is the sending credential of the domain.
+	Key string `json:"key"`
+}
+
+// DomainSettings are the sender settings of a domain registered with
+// Kannon.
+type DomainSettings struct {
+	// DKIMSelector is the selector the mail of the domain is signed with.
+	DKIMSelector string `json:"dkimSelector"`
+
+	// SenderAlias is the display name of the sender, when the send request
+	// sets none.
+	SenderAlias string `json:"senderAlias,omitempty"`
+
+	// SenderPool is the SenderPool the mail is sent from, as
+	// namespace/name.
+	SenderPool string `json:"senderPool,omitempty"`
+}
+
+// APIKey is an API key of a domain registered with Kannon.
+type APIKey struct {
+	ID  string `json:"id"`
+	Key string `json:"key"`
+}
+
+// Template is an email template of a domain registered with Kannon.
+type Template struct {
+	ID    string `json:"templateId"`
+	Title string `json:"title"`
+	HTML  string `json:"html"`
+}
+
+// Credentials authenticate the calls of the mailer API for a domain.
+type Credentials struct {
+	Domain string
+	Key    string
+}
+
+// Message is an HTML message sent through Kannon.
+type Message struct {
+	SenderEmail string
+	SenderAlias string
+	Subject     string
+	HTML        string
+	Recipients  []string
+}
+
+// Client registers domains with Kannon.
+type Client interface {
+	GetDomain(ctx context.Context, domain string) (*Domain, error)
+	CreateDomain(ctx context.Context, domain string) (*Domain, error)
+	DeleteDomain(ctx context.Context, domain string) error
+
+	// UpdateDomain replaces the sender settings of a registered domain.
+	UpdateDomain(ctx context.Context, domain string, settings DomainSettings) error
+
+	// SetDailyLimit caps the messages sent from the address ip per day. A
+	// zero limit removes the cap.
+	SetDailyLimit(ctx context.Context, ip string, limit int64) error
+
+	CreateAPIKey(ctx context.Context, domain, name string) (*APIKey, error)
+	RevokeAPIKey(ctx context.Context, domain, id string) error
+
+	// GetTemplate returns the template id of domain, ErrNotFound when it
+	// doesn't exist.
+	GetTemplate(ctx context.Context, domain, id string) (*Template, error)
+	CreateTemplate(ctx context.Context, domain string, template Template) (*Template, error)
+	UpdateTemplate(ctx context.Context, domain string, template Template) (*Template, error)
+	DeleteTemplate(ctx context.Context, domain, id string) error
+
+	// SendHTML sends msg from the domain of credentials and returns the
+	// Kannon identifier of the message.
+	SendHTML(ctx context.Context, credentials Credentials, msg Message) (string, error)
+}
+
+// ConnectClient calls the Kannon admin API with the JSON encoding of the
+// Connect protocol, which the admin API serves next to gRPC.
+type ConnectClient struct {
+	endpoint string
+	token    string
+	client   *http.Client
+}
+
+type connectError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// NewConnectClient creates a client of the admin API at endpoint,
+// authenticated with token. A nil client uses a client with a 10 seconds
+// timeout.
+func NewConnectClient(endpoint, token string, client *http.Client) *ConnectClient {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &ConnectClient{endpoint: strings.TrimSuffix(endpoint, "/"), token: token, client: client}
+}
+
+func (c *ConnectClient) GetDomain(ctx context.Context, domain string) (*Domain, error) {
+	res := struct {
+		Domain *Domain `json:"domain"`
+	}{}
+	if err := c.call(ctx, "GetDomain", map[string]string{"domain": domain}, &res); err != nil {
+		return nil, err
+	}
+	if res.Domain == nil {
+		return nil, ErrNotFound
+	}
+
+	return res.Domain, nil
+}
+
+func (c *ConnectClient) CreateDomain(ctx context.Context, domain string) (*Domain, error) {
+	res := &Domain{}
+	if err := c.call(ctx, "CreateDomain", map[string]string{"domain": domain}, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (c *ConnectClient) DeleteDomain(ctx context.Context, domain string) error {
+	return c.call(ctx, "DeleteDomain", map[string]string{"domain": domain}, nil)
+}
+
+func (c *ConnectClient) UpdateDomain(ctx context.Context, domain string, settings DomainSettings) error {
+	req := struct {
+		Domain string `json:"domain"`
+		DomainSettings
+	}{Domain: domain, DomainSettings: settings}
+
+	return c.call(ctx, "UpdateDomain", req, nil)
+}
+
+func (c *ConnectClient) SetDailyLimit(ctx context.Context, ip string, limit int64) error {
+	req := struct {
+		IP         string `json:"ip"`
+		DailyLimit int64  `json:"dailyLimit"`
+	}{IP: ip, DailyLimit: limit}
+
+	return c.call(ctx, "SetIPDailyLimit", req, nil)
+}
+
+func (c *ConnectClient) CreateAPIKey(ctx context.Context, domain, name string) (*APIKey, error) {
+	res := struct {
+		APIKey *APIKey `json:"apiKey"`
+	}{}
+	if err := c.call(ctx, "CreateApiKey", map[string]string{"domain": domain, "name": name}, &res); err != nil {
+		return nil, err
+	}
+	if res.APIKey == nil {
+		return nil, fmt.Errorf("kannon CreateApiKey: no key returned")
+	}
+
+	return res.APIKey, nil
+}
+
+func (c *ConnectClient) RevokeAPIKey(ctx context.Context, domain, id string) error {
+	return c.call(ctx, "RevokeApiKey", map[string]string{"domain": domain, "id": id}, nil)
+}
+
+type templateRequest struct {
+	Domain string `json:"domain"`
+	Template
+}
+
+type templateResponse struct {
+	Template *Template `json:"template"`
+}
+
+func (c *ConnectClient) GetTemplate(ctx context.Context, domain, id string) (*Template, error) {
+	res := templateResponse{}
+	if err := c.call(ctx, "GetTemplate", map[string]string{"domain": domain, "templateId": id}, &res); err != nil {
+		return nil, err
+	}
+	if res.Template == nil {
+		return nil, ErrNotFound
+	}
+
+	return res.Template, nil
+}
+
+func (c *ConnectClient) CreateTemplate(ctx context.Context, domain string, template Template) (*Template, error) {
+	res := templateResponse{}
+	if err := c.call(ctx, "CreateTemplate", templateRequest{Domain: domain, Template: template}, &res); err != nil {
+		return nil, err
+	}
+	if res.Template == nil {
+		return nil, fmt.Errorf("kannon CreateTemplate: no template returned")
+	}
+
+	return res.Template, nil
+}
+
+func (c *ConnectClient) UpdateTemplate(ctx context.Context, domain string, template Template) (*Template, error) {
+	res := templateResponse{}
+	if err := c.call(ctx, "UpdateTemplate", templateRequest{Domain: domain, Template: template}, &res); err != nil {
+		return nil, err
+	}
+	if res.Template == nil {
+		return nil, ErrNotFound
+	}
+
+	return res.Template, nil
+}
+
+func (c *ConnectClient) DeleteTemplate(ctx context.Context, domain, id string) error {
+	return c.call(ctx, "DeleteTemplate", map[string]string{"domain": domain, "templateId": id}, nil)
+}
+
+type sendRecipient struct {
+	Email string `json:"email"`
+}
+
+func (c *ConnectClient) SendHTML(ctx context.Context, credentials Credentials, msg Message) (string, error) {
+	req := struct {
+		Sender struct {
+			Email string `json:"email"`
+			Alias string `json:"alias,omitempty"`
+		} `json:"sender"`
+		Subject    string          `json:"subject"`
+		HTML       string          `json:"html"`
+		Recipients []sendRecipient `json:"recipients"`
+	}{Subject: msg.Subject, HTML: msg.HTML}
+	req.Sender.Email = msg.SenderEmail
+	req.Sender.Alias = msg.SenderAlias
+	for _, to := range msg.Recipients {
+		req.Recipients = append(req.Recipients, sendRecipient{Email: to})
+	}
+
+	res := struct {
+		MessageID string `json:"messageId"`
+	}{}
+	// the mailer API authenticates the domains with their sending key
+	auth := "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials.Domain+":"+credentials.Key))
+	if err := c.do(ctx, mailerService, "SendHTML", auth, req, &res); err != nil {
+		return "", err
+	}
+
+	return res.MessageID, nil
+}
+
+func (c *ConnectClient) call(ctx context.Context, method string, req, res interface{}) error {
+	auth := ""
+	if c.token != "" {
+		auth = "Bearer " + c.token
+	}
+	return c.do(ctx, adminService, method, auth, req, res)
+}
+
+func (c *ConnectClient) do(ctx context.Context, service, method, auth string, req, res interface{}) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	u := fmt.Sprintf("%s/%s/%s", c.endpoint, service, method)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Connect-Protocol-Version", "1")
+	if auth != "" {
+		httpReq.Header.Set("Authorization", auth)
+	}
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		e := connectError{}
+		_ = json.NewDecoder(resp.Body).Decode(&e)
+
+		switch e.Code {
+		case "not_found":
+			return ErrNotFound
+		case "unimplemented":
+			return fmt.Errorf("%s: %w", method, ErrUnsupported)
+		}
+		return fmt.Errorf("kannon %s: status %d: %s %s", method, resp.StatusCode, e.Code, e.Message)
+	}
+
+	if res == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(res)
+}
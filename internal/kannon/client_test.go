@@ -0,0 +1,182 @@
+package kannon_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kannon-email/k8nnon/internal/kannon"
+)
+
+func createClient(t *testing.T, handler http.HandlerFunc) *kannon.ConnectClient {
+	t.Helper()
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	return kannon.NewConnectClient(srv.URL, "token", nil)
+}
+
+func TestCreateDomain(t *testing.T) {
+	c := createClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/pkg.kannon.admin.apiv1.ApiService/CreateDomain", r.URL.Path)
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		req := map[string]string{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "example.com", req["domain"])
+
+		_, _ = io.WriteString(w, `{"domain":"example.com","key":"secret","dkimPubKey":"MIIB"}`)
+	})
+
+	d, err := c.CreateDomain(context.Background(), "example.com")
+	require.NoError(t, err)
+	assert.Equal(t, &kannon.Domain{Domain: "example.com", Key: "secret"}, d)
+}
+
+func TestUpdateDomain(t *testing.T) {
+	c := createClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/pkg.kannon.admin.apiv1.ApiService/UpdateDomain", r.URL.Path)
+
+		req := map[string]interface{}{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, map[string]interface{}{"domain": "example.com", "dkimSelector": "kannon", "senderPool": "default/pool"}, req)
+
+		_, _ = io.WriteString(w, `{}`)
+	})
+
+	settings := kannon.DomainSettings{DKIMSelector: "kannon", SenderPool: "default/pool"}
+	require.NoError(t, c.UpdateDomain(context.Background(), "example.com", settings))
+}
+
+func TestSetDailyLimit(t *testing.T) {
+	c := createClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/pkg.kannon.admin.apiv1.ApiService/SetIPDailyLimit", r.URL.Path)
+
+		req := map[string]interface{}{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, map[string]interface{}{"ip": "192.0.2.1", "dailyLimit": float64(500)}, req)
+
+		_, _ = io.WriteString(w, `{}`)
+	})
+
+	require.NoError(t, c.SetDailyLimit(context.Background(), "192.0.2.1", 500))
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	c := createClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/pkg.kannon.admin.apiv1.ApiService/CreateApiKey", r.URL.Path)
+
+		req := map[string]string{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, map[string]string{"domain": "example.com", "name": "default/sender"}, req)
+
+		_, _ = io.WriteString(w, `{"apiKey":{"id":"k1","key":"secret"}}`)
+	})
+
+	key, err := c.CreateAPIKey(context.Background(), "example.com", "default/sender")
+	require.NoError(t, err)
+	assert.Equal(t, &kannon.APIKey{ID: "k1", Key: "secret"}, key)
+}
+
+func TestUpdateTemplate(t *testing.T) {
+	c := createClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/pkg.kannon.admin.apiv1.ApiService/UpdateTemplate", r.URL.Path)
+
+		req := map[string]string{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, map[string]string{"domain": "example.com", "templateId": "t1", "title": "Welcome", "html": "<p>hi</p>"}, req)
+
+		_, _ = io.WriteString(w, `{"template":{"templateId":"t1","title":"Welcome","html":"<p>hi</p>"}}`)
+	})
+
+	template, err := c.UpdateTemplate(context.Background(), "example.com", kannon.Template{ID: "t1", Title: "Welcome", HTML: "<p>hi</p>"})
+	require.NoError(t, err)
+	assert.Equal(t, "t1", template.ID)
+}
+
+func TestGetTemplateNotFound(t *testing.T) {
+	c := createClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/pkg.kannon.admin.apiv1.ApiService/GetTemplate", r.URL.Path)
+
+		req := map[string]string{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, map[string]string{"domain": "example.com", "templateId": "t1"}, req)
+
+		_, _ = io.WriteString(w, `{}`)
+	})
+
+	_, err := c.GetTemplate(context.Background(), "example.com", "t1")
+	assert.ErrorIs(t, err, kannon.ErrNotFound)
+}
+
+func TestSendHTML(t *testing.T) {
+	c := createClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/pkg.kannon.mailer.apiv1.Mailer/SendHTML", r.URL.Path)
+		user, pass, ok := r.BasicAuth()
+		assert.True(t, ok, "should authenticate with the domain credentials")
+		assert.Equal(t, "example.com", user)
+		assert.Equal(t, "secret", pass)
+
+		req := map[string]interface{}{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, map[string]interface{}{
+			"sender":     map[string]interface{}{"email": "test@example.com", "alias": "Test"},
+			"subject":    "Hello",
+			"html":       "<p>Hello</p>",
+			"recipients": []interface{}{map[string]interface{}{"email": "seed@sink.example.net"}},
+		}, req)
+
+		_, _ = io.WriteString(w, `{"messageId":"message-1@example.com"}`)
+	})
+
+	id, err := c.SendHTML(context.Background(), kannon.Credentials{Domain: "example.com", Key: "secret"}, kannon.Message{
+		SenderEmail: "test@example.com",
+		SenderAlias: "Test",
+		Subject:     "Hello",
+		HTML:        "<p>Hello</p>",
+		Recipients:  []string{"seed@sink.example.net"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "message-1@example.com", id)
+}
+
+func TestGetDomainNotFound(t *testing.T) {
+	c := createClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = io.WriteString(w, `{"code":"not_found","message":"domain not found"}`)
+	})
+
+	_, err := c.GetDomain(context.Background(), "example.com")
+	assert.True(t, errors.Is(err, kannon.ErrNotFound), "should be not found: %v", err)
+}
+
+func TestDeleteDomainUnsupported(t *testing.T) {
+	c := createClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotImplemented)
+		_, _ = io.WriteString(w, `{"code":"unimplemented"}`)
+	})
+
+	err := c.DeleteDomain(context.Background(), "example.com")
+	assert.True(t, errors.Is(err, kannon.ErrUnsupported), "should be unsupported: %v", err)
+}
+
+func TestCallError(t *testing.T) {
+	c := createClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = io.WriteString(w, `{"code":"unauthenticated","message":"invalid token"}`)
+	})
+
+	_, err := c.CreateDomain(context.Background(), "example.com")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid token")
+}
@@ -0,0 +1,253 @@
+package kannon
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// FakeClient is an in-memory Client for tests.
+type FakeClient struct {
+	mu      sync.Mutex
+	domains map[string]*Domain
+	limits  map[string]int64
+	keys    map[string]string
+	nextKey int
+
+	// settings are the sender settings of the domains, and updates how
+	// many times they were pushed
+	settings map[string]DomainSettings
+	updates  map[string]int
+
+	templates    map[string]map[string]Template
+	nextTemplate int
+
+	sent map[string][]Message
+}
+
+func NewFakeClient() *FakeClient {
+	return &FakeClient{
+		domains:   map[string]*Domain{},
+		limits:    map[string]int64{},
+		keys:      map[string]string{},
+		settings:  map[string]DomainSettings{},
+		updates:   map[string]int{},
+		templates: map[string]map[string]Template{},
+		sent:      map[string][]Message{},
+	}
+}
+
+func (c *FakeClient) GetDomain(ctx context.Context, domain string) (*Domain, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	d, ok := c.domains[domain]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return d, nil
+}
+
+func (c *FakeClient) CreateDomain(ctx context.Context, domain string) (*Domain, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.domains[domain]; ok {
+		return nil, fmt.Errorf("domain %s already exists", domain)
+	}
+
+	d := &Domain{Domain: domain, Key: "key-" + domain}
+	c.domains[domain] = d
+
+	return d, nil
+}
+
+func (c *FakeClient) DeleteDomain(ctx context.Context, domain string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.domains[domain]; !ok {
+		return ErrNotFound
+	}
+	delete(c.domains, domain)
+	delete(c.settings, domain)
+
+	return nil
+}
+
+func (c *FakeClient) UpdateDomain(ctx context.Context, domain string, settings DomainSettings) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.domains[domain]; !ok {
+		return ErrNotFound
+	}
+	c.settings[domain] = settings
+	c.updates[domain]++
+
+	return nil
+}
+
+// Settings returns the sender settings of domain, and how many times they
+// were updated.
+func (c *FakeClient) Settings(domain string) (DomainSettings, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.settings[domain], c.updates[domain]
+}
+
+func (c *FakeClient) SetDailyLimit(ctx context.Context, ip string, limit int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if limit == 0 {
+		delete(c.limits, ip)
+		return nil
+	}
+	c.limits[ip] = limit
+
+	return nil
+}
+
+// DailyLimit returns the cap set on the address ip, and whether there is one.
+func (c *FakeClient) DailyLimit(ip string) (int64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	limit, ok := c.limits[ip]
+	return limit, ok
+}
+
+func (c *FakeClient) CreateAPIKey(ctx context.Context, domain, name string) (*APIKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.domains[domain]; !ok {
+		return nil, ErrNotFound
+	}
+
+	c.nextKey++
+	key := &APIKey{ID: fmt.Sprintf("key-%d", c.nextKey), Key: fmt.Sprintf("secret-%s-%d", name, c.nextKey)}
+	c.keys[key.ID] = domain
+
+	return key, nil
+}
+
+func (c *FakeClient) RevokeAPIKey(ctx context.Context, domain, id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.keys[id] != domain {
+		return ErrNotFound
+	}
+	delete(c.keys, id)
+
+	return nil
+}
+
+// APIKeys returns the identifiers of the keys of domain not revoked, sorted.
+func (c *FakeClient) APIKeys(domain string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ids := []string{}
+	for id, d := range c.keys {
+		if d == domain {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	return ids
+}
+
+func (c *FakeClient) GetTemplate(ctx context.Context, domain, id string) (*Template, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	template, ok := c.templates[domain][id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return &template, nil
+}
+
+func (c *FakeClient) CreateTemplate(ctx context.Context, domain string, template Template) (*Template, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.domains[domain]; !ok {
+		return nil, ErrNotFound
+	}
+
+	c.nextTemplate++
+	template.ID = fmt.Sprintf("template-%d", c.nextTemplate)
+	if c.templates[domain] == nil {
+		c.templates[domain] = map[string]Template{}
+	}
+	c.templates[domain][template.ID] = template
+
+	return &template, nil
+}
+
+func (c *FakeClient) UpdateTemplate(ctx context.Context, domain string, template Template) (*Template, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.templates[domain][template.ID]; !ok {
+		return nil, ErrNotFound
+	}
+	c.templates[domain][template.ID] = template
+
+	return &template, nil
+}
+
+func (c *FakeClient) DeleteTemplate(ctx context.Context, domain, id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.templates[domain][id]; !ok {
+		return ErrNotFound
+	}
+	delete(c.templates[domain], id)
+
+	return nil
+}
+
+// Templates returns the templates of domain by identifier.
+func (c *FakeClient) Templates(domain string) map[string]Template {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	templates := map[string]Template{}
+	for id, template := range c.templates[domain] {
+		templates[id] = template
+	}
+
+	return templates
+}
+
+func (c *FakeClient) SendHTML(ctx context.Context, credentials Credentials, msg Message) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	d, ok := c.domains[credentials.Domain]
+	if !ok || d.Key != credentials.Key {
+		return "", fmt.Errorf("kannon SendHTML: invalid credentials of %s", credentials.Domain)
+	}
+	c.sent[credentials.Domain] = append(c.sent[credentials.Domain], msg)
+
+	return fmt.Sprintf("message-%d@%s", len(c.sent[credentials.Domain]), credentials.Domain), nil
+}
+
+// Sent returns the messages sent from domain, oldest first.
+func (c *FakeClient) Sent(domain string) []Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]Message(nil), c.sent[domain]...)
+}
@@ -0,0 +1,182 @@
+// Package logging filters the logs of the operator by verbosity, which a
+// single reconcile can raise or lower, and samples the repeated ones.
+package logging
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-logr/logr"
+	"go.uber.org/zap/zapcore"
+)
+
+// MaxVerbosity is the highest V level that can be enabled. The underlying
+// logger must log up to it, the filtering is done by the loggers of New.
+const MaxVerbosity = 10
+
+// Sampling drops the repeated logs: in every Tick, the first Initial logs
+// with the same message and level are kept, then one every Thereafter.
+// Errors are never dropped. A zero Initial disables the sampling.
+type Sampling struct {
+	Initial    int
+	Thereafter int
+	Tick       time.Duration
+}
+
+// New returns a logger writing to base the logs up to the verbosity, a V
+// level or -1 for the errors only.
+func New(base logr.Logger, verbosity int, sampling Sampling) logr.Logger {
+	baseSink := base.GetSink()
+	if withCallDepth, ok := baseSink.(logr.CallDepthLogSink); ok {
+		// the sink adds a frame between the logger and the base sink
+		baseSink = withCallDepth.WithCallDepth(1)
+	}
+
+	s := &sink{base: baseSink, verbosity: verbosity}
+	if sampling.Initial > 0 {
+		s.sampler = newSampler(sampling, time.Now)
+	}
+	return base.WithSink(s)
+}
+
+// WithVerbosity returns a logger with its own verbosity and without
+// sampling, to follow a single object closely. Loggers not built by New are
+// returned as they are.
+func WithVerbosity(l logr.Logger, verbosity int) logr.Logger {
+	s, ok := l.GetSink().(*sink)
+	if !ok {
+		return l
+	}
+	c := *s
+	c.verbosity = verbosity
+	c.sampler = nil
+	return l.WithSink(&c)
+}
+
+// ParseLevel parses a log level: error, info, debug or a V level.
+func ParseLevel(value string) (int, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "error":
+		return -1, nil
+	case "info":
+		return 0, nil
+	case "debug":
+		return 1, nil
+	}
+
+	v, err := strconv.Atoi(value)
+	if err != nil || v < 0 || v > MaxVerbosity {
+		return 0, fmt.Errorf("invalid log level %q: not error, info, debug or a V level between 0 and %d", value, MaxVerbosity)
+	}
+	return v, nil
+}
+
+// Verbosity returns the highest V level enabled by a zap level.
+func Verbosity(level zapcore.LevelEnabler) int {
+	if !level.Enabled(zapcore.InfoLevel) {
+		return -1
+	}
+	v := 0
+	for v < MaxVerbosity && level.Enabled(zapcore.Level(-v-1)) {
+		v++
+	}
+	return v
+}
+
+type sink struct {
+	base      logr.LogSink
+	verbosity int
+	sampler   *sampler
+}
+
+// Init does nothing, the base sink is initialized by its own logger.
+func (s *sink) Init(logr.RuntimeInfo) {}
+
+func (s *sink) Enabled(level int) bool {
+	return level <= s.verbosity && s.base.Enabled(level)
+}
+
+func (s *sink) Info(level int, msg string, keysAndValues ...interface{}) {
+	if s.sampler != nil && !s.sampler.allow(level, msg) {
+		return
+	}
+	s.base.Info(level, msg, keysAndValues...)
+}
+
+func (s *sink) Error(err error, msg string, keysAndValues ...interface{}) {
+	s.base.Error(err, msg, keysAndValues...)
+}
+
+func (s *sink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	c := *s
+	c.base = s.base.WithValues(keysAndValues...)
+	return &c
+}
+
+func (s *sink) WithName(name string) logr.LogSink {
+	c := *s
+	c.base = s.base.WithName(name)
+	return &c
+}
+
+func (s *sink) WithCallDepth(depth int) logr.LogSink {
+	withCallDepth, ok := s.base.(logr.CallDepthLogSink)
+	if !ok {
+		return s
+	}
+	c := *s
+	c.base = withCallDepth.WithCallDepth(depth)
+	return &c
+}
+
+// sampleBuckets bounds the memory of the sampler: the messages are counted
+// in buckets by hash, as zap does.
+const sampleBuckets = 4096
+
+type sampler struct {
+	tick       int64
+	initial    uint64
+	thereafter uint64
+	now        func() time.Time
+	counters   [sampleBuckets]counter
+}
+
+type counter struct {
+	resetAt atomic.Int64
+	count   atomic.Uint64
+}
+
+func newSampler(sampling Sampling, now func() time.Time) *sampler {
+	tick := sampling.Tick
+	if tick <= 0 {
+		tick = time.Second
+	}
+	return &sampler{
+		tick:       int64(tick),
+		initial:    uint64(sampling.Initial),
+		thereafter: uint64(sampling.Thereafter),
+		now:        now,
+	}
+}
+
+func (s *sampler) allow(level int, msg string) bool {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(msg))
+	c := &s.counters[(h.Sum32()+uint32(level))%sampleBuckets]
+
+	now := s.now().UnixNano()
+	n := c.count.Add(1)
+	if resetAt := c.resetAt.Load(); now > resetAt && c.resetAt.CompareAndSwap(resetAt, now+s.tick) {
+		c.count.Store(1)
+		n = 1
+	}
+
+	if n <= s.initial {
+		return true
+	}
+	return s.thereafter > 0 && (n-s.initial)%s.thereafter == 0
+}
@@ -0,0 +1,100 @@
+package logging
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func recordLogs(verbosity int, sampling Sampling) (logr.Logger, *[]string) {
+	var lines []string
+	base := funcr.New(func(prefix, args string) {
+		lines = append(lines, args)
+	}, funcr.Options{Verbosity: MaxVerbosity})
+	return New(base, verbosity, sampling), &lines
+}
+
+func TestSinkVerbosity(t *testing.T) {
+	l, lines := recordLogs(0, Sampling{})
+
+	l.Info("info")
+	l.V(1).Info("debug")
+	l.Error(errors.New("boom"), "error")
+	require.Len(t, *lines, 2)
+	assert.Contains(t, (*lines)[0], `"msg"="info"`)
+	assert.Contains(t, (*lines)[1], `"msg"="error"`)
+
+	// a single logger can be more verbose, and keeps its values
+	debug := WithVerbosity(l.WithValues("domain", "example"), 2)
+	debug.V(2).Info("verbose")
+	require.Len(t, *lines, 3)
+	assert.Contains(t, (*lines)[2], `"domain"="example"`)
+	l.V(1).Info("debug")
+	assert.Len(t, *lines, 3, "the other loggers keep the verbosity")
+
+	// or only log the errors
+	quiet := WithVerbosity(l, -1)
+	quiet.Info("info")
+	quiet.Error(errors.New("boom"), "error")
+	assert.Len(t, *lines, 4)
+}
+
+func TestSinkSampling(t *testing.T) {
+	l, lines := recordLogs(0, Sampling{Initial: 2, Thereafter: 3})
+	now := time.Unix(0, 0)
+	l.GetSink().(*sink).sampler.now = func() time.Time { return now }
+
+	for i := 0; i < 8; i++ {
+		l.Info("repeated")
+	}
+	// the first 2, then the 5th and the 8th
+	assert.Len(t, *lines, 4)
+
+	l.Info("other")
+	assert.Len(t, *lines, 5, "another message is counted apart")
+	for i := 0; i < 3; i++ {
+		l.Error(errors.New("boom"), "repeated")
+	}
+	assert.Len(t, *lines, 8, "the errors are not sampled")
+
+	// the counts restart every tick
+	now = now.Add(2 * time.Second)
+	l.Info("repeated")
+	l.Info("repeated")
+	assert.Len(t, *lines, 10)
+
+	// a logger following an object is not sampled
+	debug := WithVerbosity(l, 0)
+	for i := 0; i < 5; i++ {
+		debug.Info("repeated")
+	}
+	assert.Len(t, *lines, 15)
+}
+
+func TestParseLevel(t *testing.T) {
+	cases := map[string]int{"error": -1, "info": 0, "Debug": 1, "0": 0, "4": 4}
+	for value, want := range cases {
+		got, err := ParseLevel(value)
+		require.NoError(t, err, value)
+		assert.Equal(t, want, got, value)
+	}
+
+	for _, value := range []string{"", "trace", "-1", "11"} {
+		_, err := ParseLevel(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestVerbosity(t *testing.T) {
+	assert.Equal(t, 0, Verbosity(zapcore.InfoLevel))
+	assert.Equal(t, 1, Verbosity(zapcore.DebugLevel))
+	assert.Equal(t, 3, Verbosity(zapcore.Level(-3)))
+	assert.Equal(t, -1, Verbosity(zapcore.ErrorLevel))
+	assert.Equal(t, MaxVerbosity, Verbosity(zapcore.Level(-20)))
+}
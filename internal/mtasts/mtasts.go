@@ -0,0 +1,110 @@
+package mtasts
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+)
+
+// Modes of a policy.
+const (
+	ModeEnforce = "enforce"
+	ModeTesting = "testing"
+	ModeNone    = "none"
+)
+
+// PolicyPath is where a policy is served on the policy host.
+const PolicyPath = "/.well-known/mta-sts.txt"
+
+// PolicyKey is the key of the ConfigMap data holding a policy.
+const PolicyKey = "mta-sts.txt"
+
+// Host returns the host serving the policy of domain.
+func Host(domain string) string {
+	return "mta-sts." + domain
+}
+
+// PolicyURL returns the URL the policy of domain is fetched from.
+func PolicyURL(domain string) string {
+	return "https://" + Host(domain) + PolicyPath
+}
+
+// RecordName returns the name of the TXT record announcing the policy of
+// domain.
+func RecordName(domain string) string {
+	return "_mta-sts." + domain
+}
+
+// Policy renders a policy as defined by RFC 8461.
+func Policy(mode string, mx []string, maxAge time.Duration) string {
+	b := strings.Builder{}
+	b.WriteString("version: STSv1\n")
+	fmt.Fprintf(&b, "mode: %s\n", mode)
+	for _, host := range mx {
+		fmt.Fprintf(&b, "mx: %s\n", host)
+	}
+	fmt.Fprintf(&b, "max_age: %d\n", int64(maxAge/time.Second))
+
+	return b.String()
+}
+
+// DomainPolicy renders the policy hosted for domain.
+func DomainPolicy(domain *corev1alpha1.Domain) string {
+	spec := corev1alpha1.MTASTSSpec{}
+	if domain.Spec.MTASTS != nil {
+		spec = *domain.Spec.MTASTS
+	}
+	return Policy(spec.ModeOrDefault(), spec.MX, spec.MaxAgeOrDefault())
+}
+
+// PolicyID returns the id announcing policy in the TXT record. It changes
+// with the policy, so that senders fetch the new one.
+func PolicyID(policy string) string {
+	sum := sha256.Sum256([]byte(policy))
+	return hex.EncodeToString(sum[:10])
+}
+
+// Record returns the value of the TXT record announcing the policy with id.
+func Record(id string) string {
+	return fmt.Sprintf("v=STSv1; id=%s", id)
+}
+
+// ParseRecord returns the id of a TXT record announcing a policy.
+func ParseRecord(txt string) (string, bool) {
+	tags := strings.Split(txt, ";")
+	if strings.TrimSpace(tags[0]) != "v=STSv1" {
+		return "", false
+	}
+
+	for _, tag := range tags[1:] {
+		name, value, _ := strings.Cut(tag, "=")
+		if strings.TrimSpace(name) == "id" {
+			return strings.TrimSpace(value), true
+		}
+	}
+
+	return "", false
+}
+
+// SamePolicy reports whether two policies have the same fields, ignoring
+// the line endings and the whitespace around the fields.
+func SamePolicy(a, b string) bool {
+	return fields(a) == fields(b)
+}
+
+func fields(policy string) string {
+	lines := []string{}
+	for _, line := range strings.Split(strings.ReplaceAll(policy, "\r\n", "\n"), "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		lines = append(lines, strings.TrimSpace(key)+":"+strings.TrimSpace(value))
+	}
+
+	return strings.Join(lines, "\n")
+}
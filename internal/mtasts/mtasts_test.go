@@ -0,0 +1,74 @@
+package mtasts_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kannon-email/k8nnon/internal/mtasts"
+)
+
+func TestPolicy(t *testing.T) {
+	policy := mtasts.Policy(mtasts.ModeEnforce, []string{"mx1.example.com", "*.mail.example.com"}, 7*24*time.Hour)
+
+	assert.Equal(t, "version: STSv1\nmode: enforce\nmx: mx1.example.com\nmx: *.mail.example.com\nmax_age: 604800\n", policy)
+	assert.True(t, mtasts.SamePolicy(policy, "version:STSv1\r\nmode: enforce\r\nmx: mx1.example.com\r\nmx: *.mail.example.com\r\nmax_age: 604800"))
+	assert.False(t, mtasts.SamePolicy(policy, mtasts.Policy(mtasts.ModeTesting, []string{"mx1.example.com", "*.mail.example.com"}, 7*24*time.Hour)))
+}
+
+func TestPolicyID(t *testing.T) {
+	a := mtasts.PolicyID(mtasts.Policy(mtasts.ModeTesting, []string{"mx.example.com"}, time.Hour))
+	b := mtasts.PolicyID(mtasts.Policy(mtasts.ModeEnforce, []string{"mx.example.com"}, time.Hour))
+
+	assert.Len(t, a, 20)
+	assert.NotEqual(t, a, b, "should change with the policy")
+}
+
+func TestParseRecord(t *testing.T) {
+	id, ok := mtasts.ParseRecord(mtasts.Record("abc123"))
+	assert.True(t, ok)
+	assert.Equal(t, "abc123", id)
+
+	_, ok = mtasts.ParseRecord("v=spf1 -all")
+	assert.False(t, ok)
+}
+
+func TestHandler(t *testing.T) {
+	h := mtasts.Handler(func(ctx context.Context, host string) (string, bool, error) {
+		switch host {
+		case "mta-sts.example.com":
+			return "version: STSv1\n", true, nil
+		case "mta-sts.broken.com":
+			return "", false, errors.New("boom")
+		}
+		return "", false, nil
+	})
+
+	cases := []struct {
+		host, path string
+		status     int
+	}{
+		{"mta-sts.example.com:443", mtasts.PolicyPath, http.StatusOK},
+		{"mta-sts.example.com", "/", http.StatusNotFound},
+		{"mta-sts.example.org", mtasts.PolicyPath, http.StatusNotFound},
+		{"mta-sts.broken.com", mtasts.PolicyPath, http.StatusInternalServerError},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, "http://"+c.host+c.path, nil)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		assert.Equal(t, c.status, rec.Code, "%s%s", c.host, c.path)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "http://mta-sts.example.com"+mtasts.PolicyPath, nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, "version: STSv1\n", rec.Body.String())
+	assert.Equal(t, "text/plain", rec.Header().Get("Content-Type"))
+}
@@ -0,0 +1,95 @@
+package mtasts
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// PolicyStore returns the policy served on host, and false when there is
+// none.
+type PolicyStore func(ctx context.Context, host string) (string, bool, error)
+
+// Handler serves the policy of the request host at PolicyPath.
+func Handler(store PolicyStore) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != PolicyPath {
+			http.NotFound(w, req)
+			return
+		}
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		policy, ok, err := store(req.Context(), requestHost(req))
+		if err != nil {
+			http.Error(w, "failed to load the policy", http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte(policy))
+	})
+}
+
+func requestHost(req *http.Request) string {
+	host := req.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+// Server serves the policies over plain HTTP, behind the Ingresses of the
+// policy hosts terminating TLS. It runs on every replica of the operator.
+type Server struct {
+	srv *http.Server
+}
+
+// NewServer creates a Server listening on addr.
+func NewServer(addr string, store PolicyStore) *Server {
+	return &Server{srv: &http.Server{
+		Addr:              addr,
+		Handler:           Handler(store),
+		ReadHeaderTimeout: 10 * time.Second,
+	}}
+}
+
+// Start serves the policies until ctx is done.
+func (s *Server) Start(ctx context.Context) error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// NeedLeaderElection reports that the server runs on every replica.
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
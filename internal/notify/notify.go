@@ -0,0 +1,301 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+)
+
+// Types of the notified events.
+const (
+	// EventVerified is sent when every DNS record of a Domain gets
+	// verified.
+	EventVerified = "Verified"
+	// EventVerificationLost is sent when a verified Domain no longer is.
+	EventVerificationLost = "VerificationLost"
+	// EventIngressDeleted is sent when the stats Ingress or HTTPRoute of a
+	// Domain is deleted.
+	EventIngressDeleted = "IngressDeleted"
+)
+
+// DefaultSlackTemplate is the text of the Slack messages when the sink has
+// no template.
+const DefaultSlackTemplate = "*{{ .Domain }}* ({{ .Namespace }}/{{ .Name }}): {{ .Message }}"
+
+// sendTimeout bounds each delivery of a notification.
+const sendTimeout = 10 * time.Second
+
+// queueSize is how many notifications can wait to be delivered.
+const queueSize = 256
+
+// ErrQueueFull is returned when a notification is dropped because too many
+// are waiting to be delivered.
+var ErrQueueFull = errors.New("the notification queue is full")
+
+// Event is a change of the state of a Domain.
+type Event struct {
+	Type      string    `json:"type"`
+	Namespace string    `json:"namespace"`
+	Name      string    `json:"name"`
+	Domain    string    `json:"domain"`
+	Message   string    `json:"message"`
+	Time      time.Time `json:"timestamp"`
+}
+
+// Notifier sends the notifications of the events.
+type Notifier interface {
+	Notify(ctx context.Context, e Event) error
+}
+
+// Config lists where the notifications are sent.
+type Config struct {
+	Slack    []SinkConfig `json:"slack,omitempty"`
+	Webhooks []SinkConfig `json:"webhooks,omitempty"`
+}
+
+// SinkConfig is an endpoint receiving the notifications.
+type SinkConfig struct {
+	// URL is where the notifications are posted, the incoming webhook URL
+	// for Slack.
+	URL string `json:"url"`
+
+	// Template is a text/template executed with the Event. For Slack it
+	// renders the text of the message, for the other webhooks the request
+	// body. The webhooks receive the Event as JSON when empty.
+	Template string `json:"template,omitempty"`
+
+	// Headers are added to the requests.
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// Events are the types of the events notified, all of them when empty.
+	Events []string `json:"events,omitempty"`
+}
+
+// ParseConfig parses a YAML or JSON configuration.
+func ParseConfig(data []byte) (Config, error) {
+	cfg := Config{}
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("invalid notification config: %w", err)
+	}
+	return cfg, nil
+}
+
+type sink struct {
+	url      string
+	host     string
+	slack    bool
+	template *template.Template
+	headers  map[string]string
+	events   map[string]bool
+}
+
+func newSink(cfg SinkConfig, slack bool) (*sink, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("a notification sink has no url")
+	}
+
+	u, err := url.Parse(cfg.URL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid notification url %q", redact(cfg.URL))
+	}
+
+	// the webhook URLs of Slack are secrets, only their host is logged
+	s := &sink{url: cfg.URL, host: u.Host, slack: slack, headers: cfg.Headers}
+	text := cfg.Template
+	if text == "" && slack {
+		text = DefaultSlackTemplate
+	}
+	if text != "" {
+		tmpl, err := template.New(s.host).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template of the %s sink: %w", s.host, err)
+		}
+		s.template = tmpl
+	}
+	if len(cfg.Events) > 0 {
+		s.events = map[string]bool{}
+		for _, e := range cfg.Events {
+			s.events[e] = true
+		}
+	}
+
+	return s, nil
+}
+
+// redact drops what follows the host of a malformed url.
+func redact(raw string) string {
+	if scheme, rest, ok := strings.Cut(raw, "://"); ok {
+		host, _, _ := strings.Cut(rest, "/")
+		return scheme + "://" + host
+	}
+	return "..."
+}
+
+func (s *sink) wants(e Event) bool {
+	return s.events == nil || s.events[e.Type]
+}
+
+// body renders the request body of the notification.
+func (s *sink) body(e Event) ([]byte, error) {
+	if s.template == nil {
+		return json.Marshal(e)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := s.template.Execute(buf, e); err != nil {
+		return nil, err
+	}
+	if s.slack {
+		return json.Marshal(map[string]string{"text": buf.String()})
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *sink) send(ctx context.Context, client *http.Client, e Event) error {
+	body, err := s.body(e)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if s.slack || s.template == nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range s.headers {
+		req.Header.Set(name, value)
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		// the errors of the client include the url
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return urlErr.Err
+		}
+		return err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64*1024))
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+	return nil
+}
+
+// Dispatcher sends the notifications to the sinks in the background, so
+// that a slow endpoint does not hold the reconciles.
+type Dispatcher struct {
+	m      sync.RWMutex
+	sinks  []*sink
+	client *http.Client
+	queue  chan Event
+}
+
+// NewDispatcher creates a Dispatcher sending to the sinks of cfg. A nil
+// client uses http.DefaultClient.
+func NewDispatcher(cfg Config, client *http.Client) (*Dispatcher, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	d := &Dispatcher{client: client, queue: make(chan Event, queueSize)}
+	if err := d.Configure(cfg); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// Configure replaces the sinks with the ones of cfg. The sinks are kept
+// when cfg is invalid.
+func (d *Dispatcher) Configure(cfg Config) error {
+	sinks := []*sink{}
+	for _, c := range cfg.Slack {
+		s, err := newSink(c, true)
+		if err != nil {
+			return err
+		}
+		sinks = append(sinks, s)
+	}
+	for _, c := range cfg.Webhooks {
+		s, err := newSink(c, false)
+		if err != nil {
+			return err
+		}
+		sinks = append(sinks, s)
+	}
+
+	d.m.Lock()
+	defer d.m.Unlock()
+	d.sinks = sinks
+
+	return nil
+}
+
+func (d *Dispatcher) currentSinks() []*sink {
+	d.m.RLock()
+	defer d.m.RUnlock()
+
+	return d.sinks
+}
+
+// Notify queues the notification of e. It does not wait for the delivery.
+func (d *Dispatcher) Notify(_ context.Context, e Event) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	select {
+	case d.queue <- e:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Start sends the queued notifications until ctx is done. A failed delivery
+// is logged and not retried.
+func (d *Dispatcher) Start(ctx context.Context) error {
+	l := log.FromContext(ctx).WithName("notify")
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case e := <-d.queue:
+			for _, s := range d.currentSinks() {
+				if !s.wants(e) {
+					continue
+				}
+
+				sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+				if err := s.send(sendCtx, d.client, e); err != nil {
+					l.Error(err, "failed to send notification", "host", s.host, "event", e.Type, "domain", e.Domain)
+				}
+				cancel()
+			}
+		}
+	}
+}
+
+// NeedLeaderElection reports that the dispatcher runs on every replica, it
+// only sends what the reconciles of the leader queue.
+func (d *Dispatcher) NeedLeaderElection() bool {
+	return false
+}
@@ -0,0 +1,134 @@
+package notify_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kannon-email/k8nnon/internal/notify"
+)
+
+type request struct {
+	path   string
+	header http.Header
+	body   string
+}
+
+func TestDispatcher(t *testing.T) {
+	received := make(chan request, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		received <- request{path: req.URL.Path, header: req.Header, body: string(body)}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	cfg, err := notify.ParseConfig([]byte(`
+slack:
+- url: ` + srv.URL + `/slack
+  events: [VerificationLost]
+webhooks:
+- url: ` + srv.URL + `/json
+  headers:
+    Authorization: Bearer token
+- url: ` + srv.URL + `/text
+  template: '{{ .Type }} {{ .Domain }}'
+`))
+	require.NoError(t, err)
+	d, err := notify.NewDispatcher(cfg, srv.Client())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = d.Start(ctx) }()
+
+	e := notify.Event{
+		Type:      notify.EventVerified,
+		Namespace: "default",
+		Name:      "example",
+		Domain:    "example.com",
+		Message:   "all DNS records are verified",
+		Time:      time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	require.NoError(t, d.Notify(ctx, e))
+
+	next := func() request {
+		select {
+		case r := <-received:
+			return r
+		case <-time.After(5 * time.Second):
+			t.Fatal("no notification received")
+			return request{}
+		}
+	}
+
+	r := next()
+	assert.Equal(t, "/json", r.path)
+	assert.Equal(t, "Bearer token", r.header.Get("Authorization"))
+	got := notify.Event{}
+	require.NoError(t, json.Unmarshal([]byte(r.body), &got))
+	assert.Equal(t, e, got)
+
+	r = next()
+	assert.Equal(t, "/text", r.path)
+	assert.Equal(t, "Verified example.com", r.body)
+
+	// the slack sink only wants the lost verifications
+	e.Type = notify.EventVerificationLost
+	e.Message = "the DKIM record is not verified"
+	require.NoError(t, d.Notify(ctx, e))
+
+	r = next()
+	assert.Equal(t, "/slack", r.path)
+	assert.JSONEq(t, `{"text": "*example.com* (default/example): the DKIM record is not verified"}`, r.body)
+}
+
+func TestDispatcherConfigure(t *testing.T) {
+	received := make(chan string, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		received <- req.URL.Path
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	d, err := notify.NewDispatcher(notify.Config{Webhooks: []notify.SinkConfig{{URL: srv.URL + "/old"}}}, srv.Client())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = d.Start(ctx) }()
+
+	require.NoError(t, d.Configure(notify.Config{Webhooks: []notify.SinkConfig{{URL: srv.URL + "/new"}}}))
+	assert.Error(t, d.Configure(notify.Config{Webhooks: []notify.SinkConfig{{URL: "not a url"}}}), "should reject an invalid sink")
+
+	require.NoError(t, d.Notify(ctx, notify.Event{Type: notify.EventVerified, Domain: "example.com"}))
+	select {
+	case path := <-received:
+		assert.Equal(t, "/new", path, "should keep the sinks of the last valid config")
+	case <-time.After(5 * time.Second):
+		t.Fatal("no notification received")
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	_, err := notify.ParseConfig([]byte("slack:\n- url: https://hooks.slack.com/x\n  channel: ops\n"))
+	assert.Error(t, err, "unknown fields should be rejected")
+
+	cfg, err := notify.ParseConfig([]byte(`{"webhooks": [{"url": "https://example.com/hook", "template": "{{ .Nope"}]}`))
+	require.NoError(t, err)
+	_, err = notify.NewDispatcher(cfg, nil)
+	assert.Error(t, err, "invalid templates should be rejected")
+
+	cfg, err = notify.ParseConfig([]byte(`{"slack": [{"url": "hooks.slack.com/services/secret"}]}`))
+	require.NoError(t, err)
+	_, err = notify.NewDispatcher(cfg, nil)
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "secret")
+}
@@ -0,0 +1,70 @@
+package reputation
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	// DefaultPostmasterEndpoint is the Google Postmaster Tools API.
+	DefaultPostmasterEndpoint = "https://gmailpostmastertools.googleapis.com"
+
+	// DefaultTokenURL is where the Google OAuth refresh tokens are
+	// exchanged for access tokens.
+	DefaultTokenURL = "https://oauth2.googleapis.com/token"
+
+	// DefaultSNDSEndpoint is the automated data access of Microsoft SNDS.
+	DefaultSNDSEndpoint = "https://sendersupport.olc.protection.outlook.com/snds/data.aspx"
+)
+
+// ErrNoData is returned when a service has no data yet, as it only
+// publishes some once enough mail is received.
+var ErrNoData = errors.New("no data published yet")
+
+// Poller fetches the reputation data of the services.
+type Poller interface {
+	// Postmaster returns the latest traffic statistics of domain in Google
+	// Postmaster Tools.
+	Postmaster(ctx context.Context, credentials PostmasterCredentials, domain string) (*TrafficStats, error)
+
+	// SNDS returns the latest data of the addresses of key in Microsoft
+	// SNDS.
+	SNDS(ctx context.Context, key string) ([]SNDSRecord, error)
+}
+
+// Client polls the public APIs of the services.
+type Client struct {
+	PostmasterEndpoint string
+	TokenURL           string
+	SNDSEndpoint       string
+
+	client *http.Client
+}
+
+// NewClient creates a Client of the public endpoints. A nil client uses a
+// client with a 30 seconds timeout.
+func NewClient(client *http.Client) *Client {
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	return &Client{
+		PostmasterEndpoint: DefaultPostmasterEndpoint,
+		TokenURL:           DefaultTokenURL,
+		SNDSEndpoint:       DefaultSNDSEndpoint,
+		client:             client,
+	}
+}
+
+// withoutURL drops the URL from the errors of the HTTP client, as it may
+// hold a credential.
+func withoutURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
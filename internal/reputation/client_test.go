@@ -0,0 +1,147 @@
+package reputation_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kannon-email/k8nnon/internal/reputation"
+)
+
+var credentials = reputation.PostmasterCredentials{ClientID: "client", ClientSecret: "secret", RefreshToken: "refresh"}
+
+func createClient(t *testing.T, handler http.HandlerFunc) *reputation.Client {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "refresh_token", r.PostForm.Get("grant_type"))
+		assert.Equal(t, "refresh", r.PostForm.Get("refresh_token"))
+		assert.Equal(t, "client", r.PostForm.Get("client_id"))
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"access_token":"access","token_type":"Bearer","expires_in":3600}`)
+	})
+	mux.HandleFunc("/", handler)
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	c := reputation.NewClient(nil)
+	c.PostmasterEndpoint = srv.URL
+	c.TokenURL = srv.URL + "/token"
+	c.SNDSEndpoint = srv.URL + "/snds"
+	return c
+}
+
+func TestPostmaster(t *testing.T) {
+	c := createClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/domains/example.com/trafficStats", r.URL.Path)
+		assert.Equal(t, "Bearer access", r.Header.Get("Authorization"))
+		assert.NotEmpty(t, r.URL.Query().Get("startDate.year"))
+
+		if r.URL.Query().Get("pageToken") == "" {
+			_, _ = io.WriteString(w, `{"trafficStats":[
+				{"name":"domains/example.com/trafficStats/20231001","domainReputation":"LOW","userReportedSpamRatio":0.01}
+			],"nextPageToken":"next"}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"trafficStats":[
+			{"name":"domains/example.com/trafficStats/20231003","domainReputation":"HIGH","userReportedSpamRatio":0.0012,
+			 "ipReputations":[{"reputation":"HIGH","ipCount":"2","sampleIps":["192.0.2.1","192.0.2.2"]},{"reputation":"BAD","ipCount":1}]},
+			{"name":"domains/example.com/trafficStats/20231002","domainReputation":"MEDIUM"}
+		]}`)
+	})
+
+	stats, err := c.Postmaster(context.Background(), credentials, "example.com")
+	require.NoError(t, err)
+	assert.Equal(t, &reputation.TrafficStats{
+		Date:                  "2023-10-03",
+		DomainReputation:      "HIGH",
+		UserReportedSpamRatio: 0.0012,
+		IPReputations: []reputation.IPReputation{
+			{Reputation: "HIGH", IPCount: 2, SampleIPs: []string{"192.0.2.1", "192.0.2.2"}},
+			{Reputation: "BAD", IPCount: 1},
+		},
+	}, stats)
+}
+
+func TestPostmasterNoData(t *testing.T) {
+	c := createClient(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{}`)
+	})
+
+	_, err := c.Postmaster(context.Background(), credentials, "example.com")
+	assert.True(t, errors.Is(err, reputation.ErrNoData), "should have no data: %v", err)
+}
+
+func TestPostmasterError(t *testing.T) {
+	c := createClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = io.WriteString(w, `{"error":{"code":403,"message":"The caller does not have permission","status":"PERMISSION_DENIED"}}`)
+	})
+
+	_, err := c.Postmaster(context.Background(), credentials, "example.com")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "does not have permission")
+}
+
+func TestSNDS(t *testing.T) {
+	c := createClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/snds", r.URL.Path)
+		assert.Equal(t, "snds-key", r.URL.Query().Get("key"))
+
+		_, _ = io.WriteString(w, "192.0.2.1,10/2/2023 8:00 AM,10/2/2023 9:00 PM,120,110,150,GREEN,< 0.1%,,,0,mx1.kannon.email,,\n"+
+			"192.0.2.2,10/2/2023 8:00 AM,10/2/2023 9:00 PM,40,40,40,RED,0.3%,10/2/2023 9:00 AM,10/2/2023 9:30 AM,3,mx2.kannon.email,,\n")
+	})
+
+	records, err := c.SNDS(context.Background(), "snds-key")
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, reputation.SNDSRecord{
+		IP:                "192.0.2.1",
+		ActivityStart:     time.Date(2023, 10, 2, 8, 0, 0, 0, time.UTC),
+		ActivityEnd:       time.Date(2023, 10, 2, 21, 0, 0, 0, time.UTC),
+		RCPTCommands:      120,
+		DataCommands:      110,
+		MessageRecipients: 150,
+		FilterResult:      "GREEN",
+		ComplaintRate:     "< 0.1%",
+	}, records[0])
+	assert.Equal(t, "RED", records[1].FilterResult)
+	assert.Equal(t, int64(3), records[1].TrapHits)
+}
+
+func TestSNDSErrorHidesKey(t *testing.T) {
+	c := reputation.NewClient(nil)
+	c.SNDSEndpoint = "http://127.0.0.1:1/snds"
+
+	_, err := c.SNDS(context.Background(), "snds-key")
+	require.Error(t, err)
+	assert.False(t, strings.Contains(err.Error(), "snds-key"), "should not leak the key: %v", err)
+}
+
+func TestParseSNDSInvalid(t *testing.T) {
+	_, err := reputation.ParseSNDS(strings.NewReader("192.0.2.1,GREEN\n"))
+	assert.Error(t, err)
+}
+
+func TestParseComplaintRate(t *testing.T) {
+	for rate, want := range map[string]float64{"< 0.1%": 0.001, "0.3%": 0.003, "2%": 0.02} {
+		got, ok := reputation.ParseComplaintRate(rate)
+		assert.True(t, ok, rate)
+		assert.InDelta(t, want, got, 1e-9, rate)
+	}
+
+	_, ok := reputation.ParseComplaintRate("n/a")
+	assert.False(t, ok)
+}
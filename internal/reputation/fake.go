@@ -0,0 +1,83 @@
+package reputation
+
+import (
+	"context"
+	"sync"
+)
+
+// FakePoller is an in-memory Poller for tests.
+type FakePoller struct {
+	mu sync.Mutex
+
+	// stats are the traffic statistics by domain, and snds the data by key
+	stats map[string]*TrafficStats
+	snds  map[string][]SNDSRecord
+	err   error
+	polls int
+}
+
+func NewFakePoller() *FakePoller {
+	return &FakePoller{stats: map[string]*TrafficStats{}, snds: map[string][]SNDSRecord{}}
+}
+
+// SetTrafficStats sets the statistics returned for domain.
+func (p *FakePoller) SetTrafficStats(domain string, stats *TrafficStats) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.stats[domain] = stats
+}
+
+// SetSNDS sets the data returned for key.
+func (p *FakePoller) SetSNDS(key string, records []SNDSRecord) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.snds[key] = records
+}
+
+// SetError makes the polls fail with err, nil restores them.
+func (p *FakePoller) SetError(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.err = err
+}
+
+// Polls returns how many times the services were polled.
+func (p *FakePoller) Polls() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.polls
+}
+
+func (p *FakePoller) Postmaster(ctx context.Context, credentials PostmasterCredentials, domain string) (*TrafficStats, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.polls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	stats, ok := p.stats[domain]
+	if !ok {
+		return nil, ErrNoData
+	}
+	return stats, nil
+}
+
+func (p *FakePoller) SNDS(ctx context.Context, key string) ([]SNDSRecord, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.polls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	records, ok := p.snds[key]
+	if !ok {
+		return nil, ErrNoData
+	}
+	return records, nil
+}
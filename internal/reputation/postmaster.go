@@ -0,0 +1,181 @@
+package reputation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// postmasterLookback is how many days back the traffic statistics are
+// looked for. Google publishes them a day or two late, and skips the days
+// with too little mail.
+const postmasterLookback = 14
+
+// PostmasterCredentials are the OAuth client and the refresh token of a
+// Google account the domain is verified with.
+type PostmasterCredentials struct {
+	ClientID     string
+	ClientSecret string
+	RefreshToken string
+}
+
+// TrafficStats are the statistics of a day of the mail of a domain
+// received by Gmail.
+type TrafficStats struct {
+	// Date is the day of the statistics, as 2006-01-02.
+	Date string
+
+	// DomainReputation is HIGH, MEDIUM, LOW or BAD.
+	DomainReputation string
+
+	// UserReportedSpamRatio is the share of the messages delivered to the
+	// inbox that the users reported as spam.
+	UserReportedSpamRatio float64
+
+	IPReputations []IPReputation
+}
+
+// IPReputation is how many sending addresses have a reputation.
+type IPReputation struct {
+	Reputation string
+	IPCount    int64
+	SampleIPs  []string
+}
+
+// int64Value decodes the int64 fields, which the JSON encoding of the API
+// sends as strings.
+type int64Value int64
+
+func (v *int64Value) UnmarshalJSON(data []byte) error {
+	n, err := strconv.ParseInt(strings.Trim(string(data), `"`), 10, 64)
+	if err != nil {
+		return err
+	}
+	*v = int64Value(n)
+	return nil
+}
+
+type trafficStatsResponse struct {
+	TrafficStats []struct {
+		// Name is domains/<domain>/trafficStats/<yyyymmdd>
+		Name                  string  `json:"name"`
+		DomainReputation      string  `json:"domainReputation"`
+		UserReportedSpamRatio float64 `json:"userReportedSpamRatio"`
+		IPReputations         []struct {
+			Reputation string     `json:"reputation"`
+			IPCount    int64Value `json:"ipCount"`
+			SampleIPs  []string   `json:"sampleIps"`
+		} `json:"ipReputations"`
+	} `json:"trafficStats"`
+	NextPageToken string `json:"nextPageToken"`
+}
+
+type googleError struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+	} `json:"error"`
+}
+
+func (c *Client) Postmaster(ctx context.Context, credentials PostmasterCredentials, domain string) (*TrafficStats, error) {
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, c.client)
+	cfg := oauth2.Config{
+		ClientID:     credentials.ClientID,
+		ClientSecret: credentials.ClientSecret,
+		Endpoint:     oauth2.Endpoint{TokenURL: c.TokenURL, AuthStyle: oauth2.AuthStyleInParams},
+	}
+	client := cfg.Client(ctx, &oauth2.Token{RefreshToken: credentials.RefreshToken})
+
+	end := time.Now().UTC()
+	start := end.AddDate(0, 0, -postmasterLookback)
+	query := url.Values{
+		"startDate.year":  {strconv.Itoa(start.Year())},
+		"startDate.month": {strconv.Itoa(int(start.Month()))},
+		"startDate.day":   {strconv.Itoa(start.Day())},
+		"endDate.year":    {strconv.Itoa(end.Year())},
+		"endDate.month":   {strconv.Itoa(int(end.Month()))},
+		"endDate.day":     {strconv.Itoa(end.Day())},
+	}
+
+	var latest *TrafficStats
+	latestName := ""
+	for {
+		res := trafficStatsResponse{}
+		u := fmt.Sprintf("%s/v1/domains/%s/trafficStats?%s", strings.TrimSuffix(c.PostmasterEndpoint, "/"), url.PathEscape(domain), query.Encode())
+		if err := getJSON(ctx, client, u, &res); err != nil {
+			return nil, err
+		}
+
+		for _, stats := range res.TrafficStats {
+			// the names end with the day, they sort by date
+			if stats.Name <= latestName {
+				continue
+			}
+			latestName = stats.Name
+			latest = &TrafficStats{
+				Date:                  statsDate(stats.Name),
+				DomainReputation:      stats.DomainReputation,
+				UserReportedSpamRatio: stats.UserReportedSpamRatio,
+			}
+			for _, ip := range stats.IPReputations {
+				latest.IPReputations = append(latest.IPReputations, IPReputation{
+					Reputation: ip.Reputation,
+					IPCount:    int64(ip.IPCount),
+					SampleIPs:  ip.SampleIPs,
+				})
+			}
+		}
+
+		if res.NextPageToken == "" {
+			break
+		}
+		query.Set("pageToken", res.NextPageToken)
+	}
+
+	if latest == nil {
+		return nil, ErrNoData
+	}
+	return latest, nil
+}
+
+// statsDate returns the day of a traffic stats name as 2006-01-02.
+func statsDate(name string) string {
+	day := name[strings.LastIndex(name, "/")+1:]
+	t, err := time.Parse("20060102", day)
+	if err != nil {
+		return day
+	}
+	return t.Format("2006-01-02")
+}
+
+func getJSON(ctx context.Context, client *http.Client, u string, res interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("postmaster tools: %w", withoutURL(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		e := googleError{}
+		_ = json.NewDecoder(resp.Body).Decode(&e)
+		if e.Error.Message != "" {
+			return fmt.Errorf("postmaster tools: status %d: %s", resp.StatusCode, e.Error.Message)
+		}
+		return fmt.Errorf("postmaster tools: status %d", resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(res)
+}
@@ -0,0 +1,122 @@
+package reputation
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSNDSSize bounds the size of the SNDS data.
+const maxSNDSSize = 4 << 20
+
+// sndsTimeLayout is the layout of the times of the SNDS data.
+const sndsTimeLayout = "1/2/2006 3:04 PM"
+
+// SNDSRecord is the data of a day of a sending address in Microsoft SNDS.
+type SNDSRecord struct {
+	IP string
+
+	ActivityStart time.Time
+	ActivityEnd   time.Time
+
+	RCPTCommands      int64
+	DataCommands      int64
+	MessageRecipients int64
+
+	// FilterResult is GREEN, YELLOW or RED.
+	FilterResult string
+
+	// ComplaintRate is the rate as published, e.g. < 0.1% or 0.3%.
+	ComplaintRate string
+
+	TrapHits int64
+}
+
+func (c *Client) SNDS(ctx context.Context, key string) ([]SNDSRecord, error) {
+	u := c.SNDSEndpoint + "?" + url.Values{"key": {key}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, errors.New("snds: invalid endpoint")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("snds: %w", withoutURL(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("snds: status %d", resp.StatusCode)
+	}
+
+	records, err := ParseSNDS(io.LimitReader(resp.Body, maxSNDSSize))
+	if err != nil {
+		return nil, fmt.Errorf("snds: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, ErrNoData
+	}
+	return records, nil
+}
+
+// ParseSNDS parses the CSV data of SNDS: the address, the activity start
+// and end, the RCPT and DATA commands, the message recipients, the filter
+// result, the complaint rate, the trap message start and end, the trap
+// hits, then sample fields that are ignored.
+func ParseSNDS(r io.Reader) ([]SNDSRecord, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var records []SNDSRecord
+	for {
+		fields, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(fields) < 11 {
+			return nil, fmt.Errorf("line %d has %d fields, not at least 11", len(records)+1, len(fields))
+		}
+
+		record := SNDSRecord{
+			IP:            strings.TrimSpace(fields[0]),
+			FilterResult:  strings.ToUpper(strings.TrimSpace(fields[6])),
+			ComplaintRate: strings.TrimSpace(fields[7]),
+		}
+		record.ActivityStart, _ = time.Parse(sndsTimeLayout, strings.TrimSpace(fields[1]))
+		record.ActivityEnd, _ = time.Parse(sndsTimeLayout, strings.TrimSpace(fields[2]))
+		for _, n := range []struct {
+			field int
+			value *int64
+		}{{3, &record.RCPTCommands}, {4, &record.DataCommands}, {5, &record.MessageRecipients}, {10, &record.TrapHits}} {
+			if *n.value, err = strconv.ParseInt(strings.TrimSpace(fields[n.field]), 10, 64); err != nil {
+				return nil, fmt.Errorf("line %d: invalid count %q", len(records)+1, fields[n.field])
+			}
+		}
+		records = append(records, record)
+	}
+}
+
+// ParseComplaintRate returns the complaint rate of SNDS as a ratio. Rates
+// published as below a bound, e.g. < 0.1%, return the bound.
+func ParseComplaintRate(rate string) (float64, bool) {
+	rate = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(rate), "<"))
+	if !strings.HasSuffix(rate, "%") {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(rate, "%")), 64)
+	if err != nil {
+		return 0, false
+	}
+	return v / 100, true
+}
@@ -0,0 +1,125 @@
+package resilience
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Defaults of the circuit breakers.
+const (
+	DefaultBreakerThreshold = 5
+	DefaultBreakerCooldown  = 30 * time.Second
+)
+
+// ErrOpen is returned for the calls rejected by an open Breaker.
+var ErrOpen = errors.New("circuit breaker open")
+
+// Breaker is a circuit breaker for a dependency, such as a DNS resolver or
+// an API host. It opens after threshold consecutive failures and rejects
+// the calls for the cooldown, then lets a single trial call through: its
+// success closes the breaker, its failure opens it again. A nil Breaker
+// never opens.
+type Breaker struct {
+	name      string
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	m        sync.Mutex
+	failures int
+	openedAt time.Time
+	trial    bool
+}
+
+// NewBreaker creates the breaker of the dependency name. A non-positive
+// threshold disables it and returns nil.
+func NewBreaker(name string, threshold int, cooldown time.Duration) *Breaker {
+	if threshold <= 0 {
+		return nil
+	}
+
+	breakerOpen.WithLabelValues(name).Set(0)
+	return &Breaker{name: name, threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+// Allow returns ErrOpen when the call must not be made. Every allowed call
+// must be followed by Done or Release.
+func (b *Breaker) Allow() error {
+	if b == nil {
+		return nil
+	}
+
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	switch {
+	case b.openedAt.IsZero():
+		return nil
+	case b.trial || b.now().Sub(b.openedAt) < b.cooldown:
+		return ErrOpen
+	default:
+		b.trial = true
+		return nil
+	}
+}
+
+// Done records the outcome of an allowed call.
+func (b *Breaker) Done(failed bool) {
+	if b == nil {
+		return
+	}
+
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	wasTrial := b.trial
+	b.trial = false
+
+	if !failed {
+		b.failures = 0
+		b.setOpen(false)
+		return
+	}
+
+	b.failures++
+	if wasTrial || b.failures >= b.threshold {
+		b.setOpen(true)
+	}
+}
+
+// Release ends an allowed call whose outcome says nothing about the
+// dependency, e.g. one cancelled by its caller.
+func (b *Breaker) Release() {
+	if b == nil {
+		return
+	}
+
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	b.trial = false
+}
+
+// Open reports whether the breaker rejects the calls.
+func (b *Breaker) Open() bool {
+	if b == nil {
+		return false
+	}
+
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	return !b.openedAt.IsZero()
+}
+
+func (b *Breaker) setOpen(open bool) {
+	switch {
+	case open:
+		b.openedAt = b.now()
+		breakerOpen.WithLabelValues(b.name).Set(1)
+	case !b.openedAt.IsZero():
+		b.openedAt = time.Time{}
+		breakerOpen.WithLabelValues(b.name).Set(0)
+	}
+}
@@ -0,0 +1,66 @@
+package resilience
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreaker(t *testing.T) {
+	now := time.Unix(1672531200, 0)
+	b := NewBreaker("test", 2, time.Minute)
+	b.now = func() time.Time { return now }
+
+	assert.NoError(t, b.Allow())
+	b.Done(true)
+	assert.False(t, b.Open(), "one failure is below the threshold")
+
+	assert.NoError(t, b.Allow())
+	b.Done(true)
+	assert.True(t, b.Open())
+	assert.ErrorIs(t, b.Allow(), ErrOpen)
+
+	// a single trial once the cooldown is over
+	now = now.Add(time.Minute)
+	assert.NoError(t, b.Allow())
+	assert.ErrorIs(t, b.Allow(), ErrOpen, "a trial is already running")
+	b.Done(true)
+	assert.ErrorIs(t, b.Allow(), ErrOpen, "the failed trial should have opened the breaker again")
+
+	now = now.Add(time.Minute)
+	assert.NoError(t, b.Allow())
+	b.Release()
+	assert.NoError(t, b.Allow(), "the released trial should have been given back")
+	b.Done(false)
+	assert.False(t, b.Open())
+	assert.NoError(t, b.Allow())
+}
+
+func TestBreakerDisabled(t *testing.T) {
+	var b *Breaker
+	assert.Nil(t, NewBreaker("test", 0, time.Minute))
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, b.Allow())
+		b.Done(true)
+	}
+	assert.False(t, b.Open())
+}
+
+func TestBudget(t *testing.T) {
+	now := time.Unix(1672531200, 0)
+	b := NewBudget(2, time.Minute)
+	b.now = func() time.Time { return now }
+
+	assert.True(t, b.Take("a"))
+	assert.True(t, b.Take("a"))
+	assert.False(t, b.Take("a"), "the budget of a should be spent")
+	assert.True(t, b.Take("b"), "the keys should have their own budget")
+
+	now = now.Add(30 * time.Second)
+	assert.True(t, b.Take("a"), "the budget should refill over time")
+	assert.False(t, b.Take("a"))
+
+	assert.Nil(t, NewBudget(0, time.Minute))
+	assert.False(t, (*Budget)(nil).Take("a"))
+}
@@ -0,0 +1,134 @@
+package resilience
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// DefaultRetryBudget is how many failed calls of a key are retried per
+// minute by default.
+const DefaultRetryBudget = 10
+
+type budgetKey struct{}
+
+// WithBudgetKey returns a context whose calls draw their retries from the
+// budget of key, e.g. the namespaced name of the Domain being reconciled.
+func WithBudgetKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, budgetKey{}, key)
+}
+
+// BudgetKey returns the budget key of ctx, empty when none was set.
+func BudgetKey(ctx context.Context) string {
+	key, _ := ctx.Value(budgetKey{}).(string)
+	return key
+}
+
+// Budget bounds the retries of the failed calls of each key, so that a
+// persistently failing dependency is not hammered by the retries of every
+// caller. A nil Budget allows no retry.
+type Budget struct {
+	limit rate.Limit
+	burst int
+	per   time.Duration
+	now   func() time.Time
+
+	m         sync.Mutex
+	keys      map[string]*budgetEntry
+	lastPrune time.Time
+}
+
+type budgetEntry struct {
+	limiter  *rate.Limiter
+	lastUsed time.Time
+}
+
+// NewBudget creates a budget allowing retries retries per key every per,
+// in bursts of up to retries. A non-positive retries allows none and
+// returns nil.
+func NewBudget(retries int, per time.Duration) *Budget {
+	if retries <= 0 || per <= 0 {
+		return nil
+	}
+
+	return &Budget{
+		limit: rate.Limit(float64(retries) / per.Seconds()),
+		burst: retries,
+		per:   per,
+		now:   time.Now,
+		keys:  map[string]*budgetEntry{},
+	}
+}
+
+// Take reports whether a call of key may be retried, and draws the retry
+// from the budget when it may.
+func (b *Budget) Take(key string) bool {
+	if b == nil {
+		return false
+	}
+
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	now := b.now()
+	b.prune(now)
+
+	entry, ok := b.keys[key]
+	if !ok {
+		entry = &budgetEntry{limiter: rate.NewLimiter(b.limit, b.burst)}
+		b.keys[key] = entry
+	}
+	entry.lastUsed = now
+
+	return entry.limiter.AllowN(now, 1)
+}
+
+// prune drops the keys unused for a whole period: their budget is full
+// again, like the one of a new key.
+func (b *Budget) prune(now time.Time) {
+	if now.Sub(b.lastPrune) < b.per {
+		return
+	}
+	b.lastPrune = now
+
+	for key, entry := range b.keys {
+		if now.Sub(entry.lastUsed) >= b.per {
+			delete(b.keys, key)
+		}
+	}
+}
+
+// Retry draws a retry of key from budget and records whether it was
+// allowed, under kind, e.g. dns or http.
+func Retry(budget *Budget, kind, key string) bool {
+	if budget == nil {
+		return false
+	}
+
+	if !budget.Take(key) {
+		retries.WithLabelValues(kind, "exhausted").Inc()
+		return false
+	}
+	retries.WithLabelValues(kind, "retried").Inc()
+	return true
+}
+
+// retryBackoff is the wait before the first retry, doubled for each one
+// after.
+const retryBackoff = 100 * time.Millisecond
+
+// Backoff waits before the retry following attempt, the first attempt
+// being 0, and returns the error of ctx when it ends first.
+func Backoff(ctx context.Context, attempt int) error {
+	t := time.NewTimer(retryBackoff << attempt)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,21 @@
+package resilience
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	breakerOpen = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "k8nnon_circuit_breaker_open",
+		Help: "Whether the circuit breaker of a DNS resolver or an API host rejects the calls.",
+	}, []string{"dependency"})
+
+	retries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "k8nnon_retries_total",
+		Help: "Failed calls to the DNS resolvers and the APIs, by whether they were retried or the retry budget was exhausted.",
+	}, []string{"kind", "result"})
+)
+
+// Collectors returns the metrics of the breakers and the budgets, for the
+// caller to register.
+func Collectors() []prometheus.Collector {
+	return []prometheus.Collector{breakerOpen, retries}
+}
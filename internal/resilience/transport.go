@@ -0,0 +1,105 @@
+package resilience
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultRetries is how many times a call failing transiently is retried,
+// budget permitting.
+const DefaultRetries = 2
+
+// Transport is an http.RoundTripper giving each API host a Breaker, and
+// retrying the idempotent requests failing transiently within the budget
+// of their context, or of their host without one.
+type Transport struct {
+	base      http.RoundTripper
+	budget    *Budget
+	threshold int
+	cooldown  time.Duration
+
+	m        sync.Mutex
+	breakers map[string]*Breaker
+}
+
+// NewTransport wraps base, http.DefaultTransport when nil.
+func NewTransport(base http.RoundTripper, budget *Budget, threshold int, cooldown time.Duration) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &Transport{
+		base:      base,
+		budget:    budget,
+		threshold: threshold,
+		cooldown:  cooldown,
+		breakers:  map[string]*Breaker{},
+	}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	breaker := t.breaker(host)
+
+	key := BudgetKey(req.Context())
+	if key == "" {
+		key = host
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := breaker.Allow(); err != nil {
+			return nil, fmt.Errorf("%s %s: %w", req.Method, host, err)
+		}
+
+		res, err := t.base.RoundTrip(req)
+		failed := err != nil || transientStatus(res.StatusCode)
+		if err != nil && req.Context().Err() != nil {
+			breaker.Release()
+			return nil, err
+		}
+		breaker.Done(failed)
+
+		if !failed || attempt >= DefaultRetries || !replayable(req) || !Retry(t.budget, "http", key) {
+			return res, err
+		}
+		if res != nil {
+			_, _ = io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+		}
+		if err := Backoff(req.Context(), attempt); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (t *Transport) breaker(host string) *Breaker {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	b, ok := t.breakers[host]
+	if !ok {
+		b = NewBreaker(host, t.threshold, t.cooldown)
+		t.breakers[host] = b
+	}
+	return b
+}
+
+// transientStatus reports the statuses of the servers failing or shedding
+// load, 4xx being the fault of the request.
+func transientStatus(code int) bool {
+	return code >= http.StatusInternalServerError || code == http.StatusTooManyRequests
+}
+
+// replayable reports whether the request may be sent again: only the
+// idempotent ones without a body that can't be read twice.
+func replayable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+	default:
+		return false
+	}
+	return req.Body == nil || req.Body == http.NoBody
+}
@@ -0,0 +1,84 @@
+package resilience_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kannon-email/k8nnon/internal/resilience"
+)
+
+func failingServer(t *testing.T, failures int32) (*httptest.Server, *int32) {
+	t.Helper()
+
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestTransportRetries(t *testing.T) {
+	srv, calls := failingServer(t, 1)
+	client := &http.Client{Transport: resilience.NewTransport(nil, resilience.NewBudget(10, time.Minute), 0, 0)}
+
+	res, err := client.Get(srv.URL)
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+}
+
+func TestTransportRetriesWithinBudget(t *testing.T) {
+	srv, calls := failingServer(t, 100)
+	client := &http.Client{Transport: resilience.NewTransport(nil, resilience.NewBudget(1, time.Hour), 0, 0)}
+
+	ctx := resilience.WithBudgetKey(context.Background(), "default/example")
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+		require.NoError(t, err)
+		res, err := client.Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+	}
+	assert.Equal(t, int32(3), atomic.LoadInt32(calls), "only one retry should fit in the budget")
+}
+
+func TestTransportSkipsNonIdempotent(t *testing.T) {
+	srv, calls := failingServer(t, 1)
+	client := &http.Client{Transport: resilience.NewTransport(nil, resilience.NewBudget(10, time.Minute), 0, 0)}
+
+	res, err := client.Post(srv.URL, "text/plain", strings.NewReader("body"))
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+	assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+}
+
+func TestTransportBreaker(t *testing.T) {
+	srv, calls := failingServer(t, 100)
+	client := &http.Client{Transport: resilience.NewTransport(nil, nil, 2, time.Hour)}
+
+	for i := 0; i < 2; i++ {
+		res, err := client.Get(srv.URL)
+		require.NoError(t, err)
+		res.Body.Close()
+	}
+
+	_, err := client.Get(srv.URL)
+	assert.ErrorIs(t, err, resilience.ErrOpen)
+	assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+}
@@ -0,0 +1,50 @@
+package scope
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+)
+
+// NewCache restricts the cache of the manager to the namespaces, all of
+// them when empty, and the Domains to the ones matching the selector.
+func NewCache(namespaces []string, domainSelector labels.Selector) cache.NewCacheFunc {
+	return func(config *rest.Config, opts cache.Options) (cache.Cache, error) {
+		opts = cacheOptions(namespaces, domainSelector, opts)
+		if len(namespaces) > 1 {
+			return cache.MultiNamespacedCacheBuilder(namespaces)(config, opts)
+		}
+		return cache.New(config, opts)
+	}
+}
+
+// cacheOptions restricts opts to the Domains matching the selector, and to
+// the namespace when there is a single one. Several namespaces need a
+// cache for each.
+func cacheOptions(namespaces []string, domainSelector labels.Selector, opts cache.Options) cache.Options {
+	if !domainSelector.Empty() {
+		opts.SelectorsByObject = cache.SelectorsByObject{
+			&corev1alpha1.Domain{}: {Label: domainSelector},
+		}
+	}
+	if len(namespaces) == 1 {
+		opts.Namespace = namespaces[0]
+	}
+	return opts
+}
+
+// SplitList splits a comma-separated flag, trimming the items and dropping
+// the empty ones.
+func SplitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
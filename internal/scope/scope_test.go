@@ -0,0 +1,65 @@
+package scope
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+
+	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+)
+
+func TestCacheOptionsNamespaces(t *testing.T) {
+	opts := cacheOptions([]string{"tenant-a"}, labels.Everything(), cache.Options{})
+	assert.Equal(t, "tenant-a", opts.Namespace)
+
+	opts = cacheOptions([]string{"tenant-a", "tenant-b"}, labels.Everything(), cache.Options{})
+	assert.Empty(t, opts.Namespace, "several namespaces need a cache for each")
+
+	opts = cacheOptions(nil, labels.Everything(), cache.Options{})
+	assert.Empty(t, opts.Namespace)
+}
+
+func TestCacheOptionsDomainSelector(t *testing.T) {
+	opts := cacheOptions(nil, labels.Everything(), cache.Options{})
+	assert.Nil(t, opts.SelectorsByObject, "an empty selector should not restrict the Domains")
+
+	selector, err := labels.Parse("tenant=a")
+	require.NoError(t, err)
+	opts = cacheOptions(nil, selector, cache.Options{})
+	require.Len(t, opts.SelectorsByObject, 1)
+	for obj, s := range opts.SelectorsByObject {
+		assert.IsType(t, &corev1alpha1.Domain{}, obj)
+		assert.Equal(t, selector, s.Label)
+	}
+}
+
+func TestNewCacheMultipleNamespaces(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1alpha1.AddToScheme(scheme))
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{corev1alpha1.GroupVersion})
+	mapper.Add(corev1alpha1.GroupVersion.WithKind("Domain"), meta.RESTScopeNamespace)
+	opts := cache.Options{Scheme: scheme, Mapper: mapper}
+
+	c, err := NewCache([]string{"tenant-a", "tenant-b"}, labels.Everything())(&rest.Config{Host: "localhost"}, opts)
+	require.NoError(t, err)
+
+	// the cache of other namespaces fails without reaching the API server
+	err = c.Get(context.Background(), types.NamespacedName{Namespace: "tenant-c", Name: "example"}, &corev1alpha1.Domain{})
+	assert.ErrorContains(t, err, "unknown namespace")
+}
+
+func TestSplitList(t *testing.T) {
+	assert.Equal(t, []string{"a", "b"}, SplitList("a, ,b"))
+	assert.Equal(t, []string{"a"}, SplitList(" a "))
+	assert.Nil(t, SplitList(""))
+	assert.Nil(t, SplitList(" , "))
+}
@@ -0,0 +1,57 @@
+package shard
+
+import (
+	"fmt"
+	"hash/fnv"
+)
+
+// Shard is the share of the objects a replica of the operator reconciles.
+// The zero value reconciles all of them.
+type Shard struct {
+	// Index is the shard of the replica, from 0 to Total-1.
+	Index int
+	// Total is the number of shards. 0 and 1 disable the sharding.
+	Total int
+}
+
+// New returns the shard index out of total, and an error when the index is
+// out of range.
+func New(index, total int) (Shard, error) {
+	if total < 0 || (total > 1 && (index < 0 || index >= total)) {
+		return Shard{}, fmt.Errorf("invalid shard %d of %d", index, total)
+	}
+	return Shard{Index: index, Total: total}, nil
+}
+
+// Enabled reports whether the objects are split across several shards.
+func (s Shard) Enabled() bool {
+	return s.Total > 1
+}
+
+// Owns reports whether the object namespace/name belongs to the shard.
+func (s Shard) Owns(namespace, name string) bool {
+	if !s.Enabled() {
+		return true
+	}
+	return Of(namespace, name, s.Total) == s.Index
+}
+
+// Of returns the shard of the object namespace/name out of total. It is a
+// jump consistent hash: going from n to n+1 shards only moves 1/(n+1) of
+// the objects, all to the new shard.
+func Of(namespace, name string, total int) int {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(namespace + "/" + name))
+	return jump(h.Sum64(), total)
+}
+
+// jump is the jump consistent hash of Lamping and Veach.
+func jump(key uint64, buckets int) int {
+	b, j := int64(-1), int64(0)
+	for j < int64(buckets) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int(b)
+}
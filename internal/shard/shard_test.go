@@ -0,0 +1,55 @@
+package shard_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kannon-email/k8nnon/internal/shard"
+)
+
+func TestShard(t *testing.T) {
+	assert.True(t, shard.Shard{}.Owns("default", "example"), "the zero shard owns everything")
+
+	_, err := shard.New(3, 3)
+	assert.Error(t, err)
+	_, err = shard.New(-1, 2)
+	assert.Error(t, err)
+
+	shards := make([]shard.Shard, 4)
+	for i := range shards {
+		shards[i], err = shard.New(i, len(shards))
+		require.NoError(t, err)
+	}
+
+	counts := make([]int, len(shards))
+	for i := 0; i < 4000; i++ {
+		name := fmt.Sprintf("domain-%d", i)
+		owners := 0
+		for j, s := range shards {
+			if s.Owns("default", name) {
+				owners++
+				counts[j]++
+			}
+		}
+		require.Equal(t, 1, owners, "%s should have a single owner", name)
+	}
+	for _, c := range counts {
+		assert.InDelta(t, 1000, c, 150, "the shards should be balanced: %v", counts)
+	}
+}
+
+func TestOfIsConsistent(t *testing.T) {
+	moved := 0
+	for i := 0; i < 4000; i++ {
+		name := fmt.Sprintf("domain-%d", i)
+		before, after := shard.Of("default", name, 4), shard.Of("default", name, 5)
+		if before != after {
+			assert.Equal(t, 4, after, "objects only move to the new shard")
+			moved++
+		}
+	}
+	assert.InDelta(t, 800, moved, 150)
+}
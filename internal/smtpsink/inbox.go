@@ -0,0 +1,56 @@
+package smtpsink
+
+import (
+	"sync"
+	"time"
+)
+
+// Inbox keeps the latest messages accepted by the sink for an hour. Its
+// Deliver method is the Handler of the Server.
+type Inbox struct {
+	mu       sync.Mutex
+	messages []Message
+	max      int
+	ttl      time.Duration
+}
+
+// NewInbox creates an Inbox keeping up to max messages.
+func NewInbox(max int) *Inbox {
+	return &Inbox{max: max, ttl: time.Hour}
+}
+
+// Deliver keeps msg, dropping the oldest message when the inbox is full.
+func (i *Inbox) Deliver(msg Message) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.expire(msg.ReceivedAt)
+	if len(i.messages) >= i.max {
+		i.messages = i.messages[1:]
+	}
+	i.messages = append(i.messages, msg)
+}
+
+// Find returns the latest message matching match.
+func (i *Inbox) Find(match func(Message) bool) (Message, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.expire(time.Now())
+	for n := len(i.messages) - 1; n >= 0; n-- {
+		if match(i.messages[n]) {
+			return i.messages[n], true
+		}
+	}
+	return Message{}, false
+}
+
+func (i *Inbox) expire(now time.Time) {
+	kept := i.messages[:0]
+	for _, msg := range i.messages {
+		if now.Sub(msg.ReceivedAt) <= i.ttl {
+			kept = append(kept, msg)
+		}
+	}
+	i.messages = kept
+}
@@ -0,0 +1,280 @@
+package smtpsink
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// MaxMessageSize bounds the size of a received message.
+	MaxMessageSize = 10 << 20
+	// maxRecipients bounds the recipients of a message.
+	maxRecipients = 100
+	// sessionTimeout bounds a whole SMTP session.
+	sessionTimeout = 5 * time.Minute
+)
+
+// Message is a message accepted by the sink.
+type Message struct {
+	// RemoteIP is the address the message was received from.
+	RemoteIP string
+
+	// Helo is the name the client greeted with.
+	Helo string
+
+	// MailFrom is the envelope sender, empty for a bounce.
+	MailFrom string
+
+	// Recipients are the envelope recipients.
+	Recipients []string
+
+	// Data is the message, header fields and body, with CRLF line endings.
+	Data []byte
+
+	ReceivedAt time.Time
+}
+
+// Handler receives the messages accepted by the sink.
+type Handler func(msg Message)
+
+// Server is an SMTP server accepting the mail of a domain and handing it
+// to a Handler. It relays nothing and bounces nothing. It runs on the
+// leader only, where the received messages are checked.
+type Server struct {
+	addr    string
+	domain  string
+	handler Handler
+
+	// wg tracks the open sessions
+	wg sync.WaitGroup
+}
+
+// NewServer creates a Server listening on addr and accepting the
+// recipients of domain.
+func NewServer(addr, domain string, handler Handler) *Server {
+	return &Server{addr: addr, domain: strings.ToLower(strings.TrimSuffix(domain, ".")), handler: handler}
+}
+
+// Start accepts the messages until ctx is done.
+func (s *Server) Start(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, ln)
+}
+
+// Serve accepts the sessions of ln until ctx is done, then closes ln and
+// waits up to 5 seconds for the open sessions.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	errCh := make(chan error, 1)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				errCh <- err
+				return
+			}
+
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				s.serveConn(conn)
+			}()
+		}
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	_ = ln.Close()
+	<-errCh
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+	}
+	return nil
+}
+
+// NeedLeaderElection reports that the server runs on the leader only.
+func (s *Server) NeedLeaderElection() bool {
+	return true
+}
+
+// session is the state of an SMTP session.
+type session struct {
+	helo       string
+	mailFrom   string
+	recipients []string
+	// inTransaction is true between MAIL and the end of DATA
+	inTransaction bool
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(sessionTimeout))
+
+	remoteIP, _, err := net.SplitHostPort(conn.RemoteAddr().String())
+	if err != nil {
+		remoteIP = conn.RemoteAddr().String()
+	}
+
+	tp := textproto.NewConn(conn)
+	reply := func(format string, args ...interface{}) bool {
+		return tp.PrintfLine(format, args...) == nil
+	}
+
+	if !reply("220 %s ESMTP ready", s.hostname()) {
+		return
+	}
+
+	sess := &session{}
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		arg = strings.TrimSpace(arg)
+
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			sess = &session{helo: arg}
+			ok := reply("250-%s", s.hostname()) &&
+				reply("250-8BITMIME") &&
+				reply("250 SIZE %d", MaxMessageSize)
+			if !ok {
+				return
+			}
+		case "HELO":
+			sess = &session{helo: arg}
+			if !reply("250 %s", s.hostname()) {
+				return
+			}
+		case "MAIL":
+			from, ok := pathArg(arg, "FROM:")
+			switch {
+			case sess.helo == "":
+				reply("503 5.5.1 send EHLO or HELO first")
+			case sess.inTransaction:
+				reply("503 5.5.1 nested MAIL command")
+			case !ok:
+				reply("501 5.5.4 syntax: MAIL FROM:<address>")
+			default:
+				sess.mailFrom, sess.recipients, sess.inTransaction = from, nil, true
+				reply("250 2.1.0 ok")
+			}
+		case "RCPT":
+			to, ok := pathArg(arg, "TO:")
+			switch {
+			case !sess.inTransaction:
+				reply("503 5.5.1 send MAIL first")
+			case !ok || to == "":
+				reply("501 5.5.4 syntax: RCPT TO:<address>")
+			case !s.accepts(to):
+				reply("550 5.7.1 relaying denied")
+			case len(sess.recipients) >= maxRecipients:
+				reply("452 4.5.3 too many recipients")
+			default:
+				sess.recipients = append(sess.recipients, to)
+				reply("250 2.1.5 ok")
+			}
+		case "DATA":
+			if len(sess.recipients) == 0 {
+				reply("503 5.5.1 send RCPT first")
+				continue
+			}
+			if !reply("354 end data with <CR><LF>.<CR><LF>") {
+				return
+			}
+
+			dot := tp.DotReader()
+			data, err := io.ReadAll(io.LimitReader(dot, MaxMessageSize+1))
+			if err != nil {
+				return
+			}
+			if len(data) > MaxMessageSize {
+				if _, err := io.Copy(io.Discard, dot); err != nil {
+					return
+				}
+				reply("552 5.3.4 message too big")
+			} else {
+				s.handler(Message{
+					RemoteIP:   remoteIP,
+					Helo:       sess.helo,
+					MailFrom:   sess.mailFrom,
+					Recipients: sess.recipients,
+					Data:       crlf(data),
+					ReceivedAt: time.Now(),
+				})
+				reply("250 2.0.0 ok")
+			}
+			sess = &session{helo: sess.helo}
+		case "RSET":
+			sess = &session{helo: sess.helo}
+			reply("250 2.0.0 ok")
+		case "NOOP":
+			reply("250 2.0.0 ok")
+		case "VRFY":
+			reply("252 2.5.0 cannot verify")
+		case "QUIT":
+			reply("221 2.0.0 bye")
+			return
+		default:
+			reply("502 5.5.2 command not implemented")
+		}
+	}
+}
+
+func (s *Server) hostname() string {
+	if s.domain != "" {
+		return s.domain
+	}
+	return "localhost"
+}
+
+// accepts reports whether the sink accepts the mail of the address: any
+// address without a domain, or only the ones of it.
+func (s *Server) accepts(addr string) bool {
+	if s.domain == "" {
+		return true
+	}
+	_, domain, _ := strings.Cut(addr, "@")
+	return strings.EqualFold(strings.TrimSuffix(domain, "."), s.domain)
+}
+
+// pathArg returns the address of a MAIL FROM or RCPT TO argument, without
+// its parameters.
+func pathArg(arg, prefix string) (string, bool) {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return "", false
+	}
+	path := strings.TrimSpace(arg[len(prefix):])
+	if !strings.HasPrefix(path, "<") {
+		return "", false
+	}
+	end := strings.Index(path, ">")
+	if end < 0 {
+		return "", false
+	}
+	return path[1:end], true
+}
+
+// crlf restores the CRLF line endings the dot reader turns into LF.
+func crlf(data []byte) []byte {
+	return []byte(strings.ReplaceAll(string(data), "\n", "\r\n"))
+}
@@ -0,0 +1,109 @@
+package smtpsink_test
+
+import (
+	"context"
+	"net"
+	"net/smtp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kannon-email/k8nnon/internal/smtpsink"
+)
+
+// startSink serves a sink of sink.example.com on a random port, keeping the
+// accepted messages in the returned inbox.
+func startSink(t *testing.T) (string, *smtpsink.Inbox) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	inbox := smtpsink.NewInbox(10)
+	srv := smtpsink.NewServer("", "sink.example.com", inbox.Deliver)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		assert.NoError(t, srv.Serve(ctx, ln))
+	}()
+	t.Cleanup(func() {
+		cancel()
+		wg.Wait()
+	})
+
+	return ln.Addr().String(), inbox
+}
+
+func TestSinkReceives(t *testing.T) {
+	addr, inbox := startSink(t)
+
+	c, err := smtp.Dial(addr)
+	require.NoError(t, err)
+	defer c.Close()
+
+	require.NoError(t, c.Hello("mx.kannon.email"))
+	require.NoError(t, c.Mail("bounce@example.com"))
+	require.NoError(t, c.Rcpt("seed@sink.example.com"))
+	assert.Error(t, c.Rcpt("victim@example.net"), "should not relay")
+
+	w, err := c.Data()
+	require.NoError(t, err)
+	_, err = w.Write([]byte("From: test@example.com\nSubject: token-1\n\n.dotted line\nbody\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.NoError(t, c.Quit())
+
+	msg, ok := inbox.Find(func(m smtpsink.Message) bool {
+		return strings.Contains(string(m.Data), "token-1")
+	})
+	require.True(t, ok, "should have received the message")
+	assert.Equal(t, "127.0.0.1", msg.RemoteIP)
+	assert.Equal(t, "mx.kannon.email", msg.Helo)
+	assert.Equal(t, "bounce@example.com", msg.MailFrom)
+	assert.Equal(t, []string{"seed@sink.example.com"}, msg.Recipients)
+	assert.Equal(t, "From: test@example.com\r\nSubject: token-1\r\n\r\n.dotted line\r\nbody\r\n", string(msg.Data))
+
+	_, ok = inbox.Find(func(m smtpsink.Message) bool {
+		return strings.Contains(string(m.Data), "token-2")
+	})
+	assert.False(t, ok)
+}
+
+func TestSinkCommandOrder(t *testing.T) {
+	addr, _ := startSink(t)
+
+	c, err := smtp.Dial(addr)
+	require.NoError(t, err)
+	defer c.Close()
+
+	require.NoError(t, c.Hello("mx.kannon.email"))
+	assert.Error(t, c.Rcpt("seed@sink.example.com"), "should need MAIL first")
+	require.NoError(t, c.Mail("bounce@example.com"))
+	_, err = c.Data()
+	assert.Error(t, err, "should need RCPT first")
+	require.NoError(t, c.Reset())
+	require.NoError(t, c.Noop())
+}
+
+func TestInboxLimit(t *testing.T) {
+	inbox := smtpsink.NewInbox(2)
+	for _, subject := range []string{"a", "b", "c"} {
+		inbox.Deliver(smtpsink.Message{Data: []byte(subject), ReceivedAt: time.Now()})
+	}
+
+	find := func(data string) bool {
+		_, ok := inbox.Find(func(m smtpsink.Message) bool { return string(m.Data) == data })
+		return ok
+	}
+	assert.False(t, find("a"), "the oldest message should be dropped")
+	assert.True(t, find("b"))
+	assert.True(t, find("c"))
+
+	inbox.Deliver(smtpsink.Message{Data: []byte("old"), ReceivedAt: time.Now().Add(-2 * time.Hour)})
+	assert.False(t, find("old"), "messages older than an hour should expire")
+}
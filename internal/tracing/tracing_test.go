@@ -0,0 +1,112 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+	return recorder
+}
+
+func attributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestEnabled(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	assert.False(t, Enabled(), "no endpoint is configured")
+
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://collector:4318")
+	assert.True(t, Enabled())
+
+	t.Setenv("OTEL_SDK_DISABLED", "true")
+	assert.False(t, Enabled(), "the SDK is disabled")
+
+	t.Setenv("OTEL_SDK_DISABLED", "")
+	t.Setenv("OTEL_TRACES_EXPORTER", "none")
+	assert.False(t, Enabled(), "the traces exporter is disabled")
+}
+
+func TestReconciler(t *testing.T) {
+	recorder := recordSpans(t)
+
+	failure := errors.New("boom")
+	r := Reconciler("Domain", reconcile.Func(func(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+		_ = Run(ctx, "CheckDNS", func(context.Context) error { return nil })
+		return ctrl.Result{RequeueAfter: time.Minute}, failure
+	}))
+
+	_, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "example"}})
+	assert.ErrorIs(t, err, failure)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	child, parent := spans[0], spans[1]
+	assert.Equal(t, "CheckDNS", child.Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), child.Parent().SpanID(), "the check should be a child of the reconcile")
+
+	assert.Equal(t, "Domain.Reconcile", parent.Name())
+	assert.Equal(t, codes.Error, parent.Status().Code)
+	assert.Equal(t, "boom", parent.Status().Description)
+	attrs := attributes(parent)
+	assert.Equal(t, "default", attrs["k8s.namespace.name"].AsString())
+	assert.Equal(t, "example", attrs["k8nnon.domain.name"].AsString())
+	assert.Equal(t, "1m0s", attrs["k8nnon.requeue_after"].AsString())
+}
+
+func TestTransport(t *testing.T) {
+	recorder := recordSpans(t)
+
+	var traceparent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: Transport(nil)}
+	res, err := client.Get(srv.URL + "/v1/domains?token=secret")
+	require.NoError(t, err)
+	res.Body.Close()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "HTTP GET", span.Name())
+	assert.Contains(t, traceparent, span.SpanContext().TraceID().String(), "the trace context should be propagated")
+	assert.Equal(t, codes.Error, span.Status().Code)
+
+	attrs := attributes(span)
+	assert.Equal(t, srv.URL+"/v1/domains", attrs["http.url"].AsString(), "the query should be redacted")
+	assert.Equal(t, int64(http.StatusServiceUnavailable), attrs["http.status_code"].AsInt64())
+}
@@ -17,24 +17,49 @@ limitations under the License.
 package main
 
 import (
+	"context"
+	"crypto/x509"
 	"flag"
+	"fmt"
+	"net"
+	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	"go.uber.org/zap/zapcore"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	gatewayv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
 
 	corev1alpha1 "github.com/kannon-email/k8nnon/api/v1alpha1"
+	corev1beta1 "github.com/kannon-email/k8nnon/api/v1beta1"
 	"github.com/kannon-email/k8nnon/controllers"
+	"github.com/kannon-email/k8nnon/internal/delivery"
+	"github.com/kannon-email/k8nnon/internal/dkim"
+	"github.com/kannon-email/k8nnon/internal/dmarc"
 	"github.com/kannon-email/k8nnon/internal/dns/checker"
 	"github.com/kannon-email/k8nnon/internal/dns/resolver"
+	"github.com/kannon-email/k8nnon/internal/kannon"
+	"github.com/kannon-email/k8nnon/internal/logging"
+	"github.com/kannon-email/k8nnon/internal/mtasts"
+	"github.com/kannon-email/k8nnon/internal/notify"
+	"github.com/kannon-email/k8nnon/internal/reputation"
+	"github.com/kannon-email/k8nnon/internal/resilience"
+	"github.com/kannon-email/k8nnon/internal/scope"
+	"github.com/kannon-email/k8nnon/internal/shard"
+	"github.com/kannon-email/k8nnon/internal/smtpsink"
+	"github.com/kannon-email/k8nnon/internal/tracing"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -45,8 +70,10 @@ var (
 
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(gatewayv1beta1.AddToScheme(scheme))
 
 	utilruntime.Must(corev1alpha1.AddToScheme(scheme))
+	utilruntime.Must(corev1beta1.AddToScheme(scheme))
 	//+kubebuilder:scaffold:scheme
 }
 
@@ -54,26 +81,252 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var dnsLookupTimeout time.Duration
+	var dnsCheckTimeout time.Duration
+	var dnsCacheTTL time.Duration
+	var dnsSharedCacheTTL time.Duration
+	var dnsRateLimit float64
+	var dnsRateLimitBurst int
+	var retryBudget int
+	var breakerThreshold int
+	var breakerCooldown time.Duration
+	var mxHost string
+	var spfInclude string
+	var dnsProbeDomain string
+	var defaultIngressClass string
+	var dnsMode string
+	var dohEndpoints string
+	var dnsResolvers string
+	var dotEndpoints string
+	var dnsCABundle string
+	var dnsQuorum int
+	var maxConcurrentReconciles int
+	var freshDomainPeriod time.Duration
+	var recheckBatchWindow time.Duration
+	var dryRun bool
+	var statsRouteGCInterval time.Duration
+	var ingressControllerNamespace string
+	var logSamplingInitial int
+	var dnsLivenessThreshold time.Duration
+	var dkimMinRSAKeyBits int
+	var logSamplingThereafter int
+	var watchNamespaces string
+	var shardIndex int
+	var shardTotal int
+	var domainLabelSelector string
+	var enableGatewayAPI bool
+	var enableExternalDNS bool
+	var enablePrometheusRules bool
+	var kannonAPIEndpoint string
+	var mtaSTSBindAddress string
+	var mtaSTSService string
+	var bimiVMCRoots string
+	var requireOwnership bool
+	var deliveryBindAddress string
+	var deliveryTLSCert string
+	var deliveryTLSKey string
+	var dmarcReportBindAddress string
+	var dmarcReportTLSCert string
+	var dmarcReportTLSKey string
+	var domainTestSMTPBindAddress string
+	var domainTestSinkDomain string
+	var notifyConfig string
+	var notifySlackURL string
+	var notifyWebhookURL string
+	var clusterDomainConfig string
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.DurationVar(&dnsLookupTimeout, "dns-lookup-timeout", checker.DefaultLookupTimeout,
+		"The maximum duration of a single DNS lookup performed by the domain checks.")
+	flag.DurationVar(&dnsCheckTimeout, "dns-check-timeout", 30*time.Second,
+		"The maximum duration of a single DNS check of a domain, including all of its lookups. Set to 0 to disable.")
+	flag.DurationVar(&dnsCacheTTL, "dns-cache-ttl", checker.DefaultCacheTTL,
+		"The maximum time successful DNS lookup results are reused for, lower when the answers have a lower TTL. "+
+			"Set to 0 to disable the cache.")
+	flag.DurationVar(&dnsSharedCacheTTL, "dns-shared-cache-ttl", checker.DefaultSharedCacheTTL,
+		"The maximum time the results of the DNS lookups shared by many Domains, such as the targets of their SPF includes "+
+			"and the reverse DNS of their sending addresses, are reused for. Lower values than --dns-cache-ttl have no effect.")
+	flag.Float64Var(&dnsRateLimit, "dns-rate-limit", checker.DefaultRateLimit,
+		"The maximum DNS lookups per second, across all resolvers. Set to 0 to disable the limit.")
+	flag.IntVar(&dnsRateLimitBurst, "dns-rate-limit-burst", checker.DefaultRateLimitBurst,
+		"The maximum burst of DNS lookups allowed by --dns-rate-limit.")
+	flag.IntVar(&retryBudget, "retry-budget", resilience.DefaultRetryBudget,
+		"The maximum retries per minute of the DNS lookups and the API calls failing transiently, per Domain, "+
+			"or per API host outside of a Domain. Set to 0 to disable the retries.")
+	flag.IntVar(&breakerThreshold, "circuit-breaker-threshold", resilience.DefaultBreakerThreshold,
+		"The consecutive failures of a DNS resolver or an API host after which its calls are refused for the cooldown. "+
+			"Set to 0 to disable the circuit breakers.")
+	flag.DurationVar(&breakerCooldown, "circuit-breaker-cooldown", resilience.DefaultBreakerCooldown,
+		"How long the calls to a failing DNS resolver or API host are refused before one is tried again.")
+	flag.StringVar(&mxHost, "mx-host", "",
+		"The host the MX records of the domains must point to. Defaults to the base domain of each Domain.")
+	flag.StringVar(&spfInclude, "spf-include", "",
+		"The include mechanism the SPF records must contain. Defaults to the base domain of each Domain.")
+	flag.StringVar(&dnsProbeDomain, "dns-probe-domain", checker.DefaultProbeDomain,
+		"The domain resolved by the readiness and liveness checks to verify the DNS resolvers are reachable.")
+	flag.DurationVar(&dnsLivenessThreshold, "dns-liveness-threshold", 5*time.Minute,
+		"How long the DNS resolution of the probe domain can fail before the liveness check fails and the pod is restarted. "+
+			"0 disables the DNS liveness check.")
+	flag.IntVar(&dkimMinRSAKeyBits, "dkim-min-rsa-key-bits", dkim.MinRSAKeyBits,
+		"The smallest generated RSA DKIM key accepted, smaller keys set the DKIMKeyMismatch condition of their Domain.")
+	flag.StringVar(&defaultIngressClass, "default-ingress-class", "",
+		"The ingress class set by the defaulting webhook on Domains that do not specify one.")
+	flag.StringVar(&dnsMode, "dns-mode", "udp",
+		"How the DNS checks query the resolvers: udp, doh (DNS-over-HTTPS) or dot (DNS-over-TLS).")
+	flag.StringVar(&dnsResolvers, "dns-resolvers", strings.Join(checker.ServerAddresses, ","),
+		"Comma-separated addresses of the resolvers queried when --dns-mode=udp, with an optional port.")
+	flag.StringVar(&dotEndpoints, "dot-endpoints", strings.Join(resolver.DefaultDoTEndpoints, ","),
+		"Comma-separated DNS-over-TLS endpoints used when --dns-mode=dot, as host[:port][#server-name].")
+	flag.StringVar(&dnsCABundle, "dns-ca-bundle", "",
+		"A PEM file with the CA certificates the DoH and DoT resolvers are verified against. Defaults to the system roots.")
+	flag.IntVar(&dnsQuorum, "dns-quorum", 0,
+		"How many resolvers must find a record for it to be verified. 0 requires a majority.")
+	flag.StringVar(&dohEndpoints, "doh-endpoints", strings.Join(resolver.DefaultDoHEndpoints, ","),
+		"Comma-separated DNS-over-HTTPS JSON API endpoints used when --dns-mode=doh, as url[#server-name].")
+	flag.IntVar(&maxConcurrentReconciles, "max-concurrent-reconciles", 1,
+		"The maximum number of Domains that can be reconciled concurrently.")
+	flag.DurationVar(&freshDomainPeriod, "fresh-domain-period", 5*time.Minute,
+		"How long new and edited Domains are rechecked every few seconds before following their check interval. 0 disables it.")
+	flag.DurationVar(&recheckBatchWindow, "recheck-batch-window", time.Minute,
+		"How much earlier than due a Domain is rechecked along with a due Domain of the same base domain, "+
+			"to share the cached lookups. 0 requeues every Domain after its own interval instead.")
+	flag.BoolVar(&dryRun, "dry-run", false,
+		"Only check the DNS records of the Domains and report in their status what would be done, "+
+			"without creating, changing or deleting any resource.")
+	flag.StringVar(&ingressControllerNamespace, "ingress-controller-namespace", controllers.DefaultIngressControllerNamespace,
+		"The namespace of the ingress controller, the only one the stats NetworkPolicies of the Domains allow the traffic from.")
+	flag.DurationVar(&statsRouteGCInterval, "stats-route-gc-interval", time.Hour,
+		"How often the stats Ingresses and HTTPRoutes no longer wanted by their Domain are deleted. 0 disables it.")
+	flag.IntVar(&shardIndex, "shard-index", 0,
+		"The shard of the Domains the replica reconciles, from 0 to --shard-total minus one.")
+	flag.IntVar(&shardTotal, "shard-total", 1,
+		"The number of shards the Domains are split into by a consistent hash of their namespace and name. "+
+			"Each shard elects its own leader.")
+	flag.StringVar(&watchNamespaces, "watch-namespaces", "",
+		"Comma-separated namespaces the operator watches. All the namespaces are watched when empty.")
+	flag.StringVar(&domainLabelSelector, "domain-label-selector", "",
+		"A label selector restricting the Domains the operator reconciles, e.g. tenant=acme.")
+	flag.BoolVar(&enableGatewayAPI, "enable-gateway-api", false,
+		"Manage HTTPRoutes for Domains with the gatewayAPI routing. Requires the Gateway API CRDs.")
+	flag.BoolVar(&enableExternalDNS, "enable-external-dns", false,
+		"Publish the DNS records of Domains with spec.dns.autoProvision as external-dns DNSEndpoints.")
+	flag.BoolVar(&enablePrometheusRules, "enable-prometheus-rules", false,
+		"Create PrometheusRules for Domains with spec.monitoring.alerts. Requires the Prometheus Operator CRDs.")
+	flag.StringVar(&kannonAPIEndpoint, "kannon-api-endpoint", "",
+		"The URL of the Kannon admin API the Domains are registered with, authenticated with the "+
+			"KANNON_API_TOKEN environment variable. Registration is disabled when empty.")
+	flag.StringVar(&mtaSTSBindAddress, "mta-sts-bind-address", "",
+		"The address the MTA-STS policy server binds to. The MTA-STS hosting is disabled when empty.")
+	flag.StringVar(&mtaSTSService, "mta-sts-service", "",
+		"The host of the Service exposing the MTA-STS policy server, e.g. k8nnon-mta-sts.k8nnon-system.svc.cluster.local.")
+	flag.BoolVar(&requireOwnership, "require-domain-ownership", false,
+		"Require a _k8nnon-challenge TXT record proving the control of a Domain before exposing its stats host and registering it with Kannon.")
+	flag.StringVar(&deliveryBindAddress, "delivery-webhook-bind-address", "",
+		"The address the receiver of the Kannon delivery webhooks binds to, verifying their signature with the "+
+			"DELIVERY_WEBHOOK_SECRET environment variable. The delivery statistics are disabled when empty.")
+	flag.StringVar(&deliveryTLSCert, "delivery-webhook-tls-cert", "",
+		"The certificate the delivery webhook receiver serves. It serves plain HTTP when empty.")
+	flag.StringVar(&deliveryTLSKey, "delivery-webhook-tls-key", "",
+		"The private key of the delivery webhook receiver certificate.")
+	flag.StringVar(&dmarcReportBindAddress, "dmarc-report-bind-address", "",
+		"The address the receiver of the DMARC aggregate reports of the Domains with spec.dmarcReports.http binds to. "+
+			"The reports are only polled from the mailboxes when empty.")
+	flag.StringVar(&dmarcReportTLSCert, "dmarc-report-tls-cert", "",
+		"The certificate the DMARC report receiver serves. It serves plain HTTP when empty.")
+	flag.StringVar(&dmarcReportTLSKey, "dmarc-report-tls-key", "",
+		"The private key of the DMARC report receiver certificate.")
+	flag.StringVar(&domainTestSMTPBindAddress, "domain-test-smtp-bind-address", "",
+		"The address the SMTP sink receiving the messages of the DomainTests binds to. The DomainTests are disabled when empty.")
+	flag.StringVar(&domainTestSinkDomain, "domain-test-sink-domain", "",
+		"The domain whose mail the SMTP sink accepts, its MX records must point to the sink. "+
+			"The DomainTests without spec.to send their message to an address of it.")
+	flag.StringVar(&notifyConfig, "notify-config", "",
+		"A YAML file, usually mounted from a ConfigMap, listing the Slack and webhook endpoints notified of the "+
+			"verification changes of the Domains and of the deletions of their stats routes.")
+	flag.StringVar(&notifySlackURL, "notify-slack-webhook-url", "",
+		"A Slack incoming webhook URL notified of the verification changes of the Domains.")
+	flag.StringVar(&notifyWebhookURL, "notify-webhook-url", "",
+		"An HTTP endpoint receiving the notifications of the Domains as JSON.")
+	flag.StringVar(&clusterDomainConfig, "cluster-domain-config", "",
+		"The name of the ClusterDomainConfig with the defaults of the Domains, the resolvers and the notification sinks. Empty disables it.")
+	flag.StringVar(&bimiVMCRoots, "bimi-vmc-roots", "",
+		"A PEM file with the CA certificates the BIMI Verified Mark Certificates must chain to. The chain is not verified when empty.")
+	flag.IntVar(&logSamplingInitial, "log-sampling-initial", 0,
+		"How many logs with the same message are kept every second before sampling them. 0 disables the sampling. "+
+			"The errors and the Domains with the "+corev1alpha1.AnnotationLogLevel+" annotation are not sampled.")
+	flag.IntVar(&logSamplingThereafter, "log-sampling-thereafter", 100,
+		"One log every how many with the same message is kept every second once sampled.")
 	opts := zap.Options{
 		Development: true,
 	}
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
 
-	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+	// zap logs every level, the verbosity is applied by the logging sink so
+	// that the annotation of a Domain can raise it for its reconciles
+	verbosity := logging.Verbosity(zapcore.DebugLevel)
+	if opts.Level != nil {
+		verbosity = logging.Verbosity(opts.Level)
+	}
+	opts.Level = zapcore.Level(-logging.MaxVerbosity)
+	ctrl.SetLogger(logging.New(zap.New(zap.UseFlagOptions(&opts)), verbosity, logging.Sampling{
+		Initial:    logSamplingInitial,
+		Thereafter: logSamplingThereafter,
+		Tick:       time.Second,
+	}))
+
+	// apiTransport calls Kannon, the DNS providers, the reputation services
+	// and the notified endpoints. http.DefaultTransport is left alone, the
+	// DoH resolvers and the fetches of the checks don't go through it.
+	var apiTransport http.RoundTripper = http.DefaultTransport
+	shutdownTracing := func(context.Context) error { return nil }
+	if tracing.Enabled() {
+		var err error
+		shutdownTracing, err = tracing.Setup(context.Background())
+		if err != nil {
+			setupLog.Error(err, "unable to set up tracing")
+			os.Exit(1)
+		}
+		// the API calls get a span each
+		apiTransport = tracing.Transport(apiTransport)
+		setupLog.Info("exporting traces with OTLP")
+	}
+
+	// the API calls get a circuit breaker per host, each attempt its own
+	// span
+	apiTransport = resilience.NewTransport(apiTransport,
+		resilience.NewBudget(retryBudget, time.Minute), breakerThreshold, breakerCooldown)
+
+	replicaShard, err := shard.New(shardIndex, shardTotal)
+	if err != nil {
+		setupLog.Error(err, "invalid shard", "shard-index", shardIndex, "shard-total", shardTotal)
+		os.Exit(1)
+	}
+	leaderElectionID := "f8ed27dd.k8s.kannon.email"
+	if replicaShard.Enabled() {
+		// the replicas of a shard elect a leader among them. The lease is
+		// keyed on the index only, so that the old and the new replicas of
+		// the shard keep sharing it while --shard-total is rolled out.
+		leaderElectionID = fmt.Sprintf("shard-%d.%s", shardIndex, leaderElectionID)
+	}
+
+	domainSelector, err := labels.Parse(domainLabelSelector)
+	if err != nil {
+		setupLog.Error(err, "invalid domain label selector", "domain-label-selector", domainLabelSelector)
+		os.Exit(1)
+	}
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
+		NewCache:               scope.NewCache(scope.SplitList(watchNamespaces), domainSelector),
 		MetricsBindAddress:     metricsAddr,
 		Port:                   9443,
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "f8ed27dd.k8s.kannon.email",
+		LeaderElectionID:       leaderElectionID,
 		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
 		// when the Manager ends. This requires the binary to immediately end when the
 		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
@@ -91,30 +344,350 @@ func main() {
 		os.Exit(1)
 	}
 
-	resolvers := resolver.NewResolvers(checker.ServerAddresses...)
+	tlsConfig, err := resolver.TLSConfig(dnsCABundle)
+	if err != nil {
+		setupLog.Error(err, "invalid dns tls configuration")
+		os.Exit(1)
+	}
 
-	if err = (&controllers.DomainReconciler{
-		Client:     mgr.GetClient(),
-		Scheme:     mgr.GetScheme(),
-		DNSChecker: *checker.NewDNSChecker(resolvers...),
-	}).SetupWithManager(mgr); err != nil {
+	// newResolvers creates the resolvers of the dns mode querying addrs,
+	// the ones of the flags or of the ClusterDomainConfig
+	var newResolvers func(addrs []string) []resolver.Resolver
+	var resolverAddrs string
+	switch dnsMode {
+	case "udp":
+		newResolvers = func(addrs []string) []resolver.Resolver { return resolver.NewResolvers(addrs...) }
+		resolverAddrs = dnsResolvers
+	case "doh":
+		newResolvers = func(addrs []string) []resolver.Resolver { return resolver.NewDoHResolvers(tlsConfig, addrs...) }
+		resolverAddrs = dohEndpoints
+	case "dot":
+		newResolvers = func(addrs []string) []resolver.Resolver { return resolver.NewDoTResolvers(tlsConfig, addrs...) }
+		resolverAddrs = dotEndpoints
+	default:
+		setupLog.Error(nil, "invalid dns mode", "dns-mode", dnsMode)
+		os.Exit(1)
+	}
+	resolvers := newResolvers(strings.Split(resolverAddrs, ","))
+
+	if dnsQuorum < 0 || dnsQuorum > len(resolvers) {
+		setupLog.Error(nil, "the dns quorum must be between 0 and the number of resolvers", "dns-quorum", dnsQuorum)
+		os.Exit(1)
+	}
+
+	var vmcRoots *x509.CertPool
+	if bimiVMCRoots != "" {
+		pem, err := os.ReadFile(bimiVMCRoots)
+		if err != nil {
+			setupLog.Error(err, "unable to read the bimi vmc roots")
+			os.Exit(1)
+		}
+		vmcRoots = x509.NewCertPool()
+		if !vmcRoots.AppendCertsFromPEM(pem) {
+			setupLog.Error(nil, "no certificate found in the bimi vmc roots", "bimi-vmc-roots", bimiVMCRoots)
+			os.Exit(1)
+		}
+	}
+
+	checkerOptions := []checker.Option{
+		checker.WithQuorum(dnsQuorum),
+		checker.WithLookupTimeout(dnsLookupTimeout),
+		// the checkers rebuilt for new resolvers keep the cached results
+		checker.WithLookupCache(checker.NewLookupCache(dnsCacheTTL, dnsSharedCacheTTL)),
+		checker.WithRateLimit(dnsRateLimit, dnsRateLimitBurst),
+		checker.WithMXHost(mxHost),
+		checker.WithSPFInclude(spfInclude),
+		checker.WithVMCRoots(vmcRoots),
+		checker.WithCircuitBreaker(breakerThreshold, breakerCooldown),
+		checker.WithRetryBudget(resilience.NewBudget(retryBudget, time.Minute)),
+	}
+	flagsChecker := checker.New(resolvers, checkerOptions...)
+
+	var dnsChecker interface {
+		checker.DNSChecker
+		checker.Pinger
+	} = flagsChecker
+	var dnsSwitch *checker.Switch
+	if clusterDomainConfig != "" {
+		// the resolvers of the ClusterDomainConfig replace the ones of
+		// the flags while the checks run
+		dnsSwitch = checker.NewSwitch(flagsChecker)
+		dnsChecker = dnsSwitch
+	}
+
+	reconciler := &controllers.DomainReconciler{
+		Client:          mgr.GetClient(),
+		Scheme:          mgr.GetScheme(),
+		DNSChecker:      dnsChecker,
+		DNSCheckTimeout: dnsCheckTimeout,
+		Recorder:        mgr.GetEventRecorderFor("domain-controller"),
+
+		MaxConcurrentReconciles: maxConcurrentReconciles,
+		FreshPeriod:             freshDomainPeriod,
+		RecheckBatchWindow:      recheckBatchWindow,
+		GatewayAPI:              enableGatewayAPI,
+		ExternalDNS:             enableExternalDNS,
+		LookupTXT:               resolvers[0].LookupTXT,
+		PrometheusRules:         enablePrometheusRules,
+		RequireOwnership:        requireOwnership,
+		DryRun:                  dryRun,
+		Shard:                   replicaShard,
+
+		HTTPClient:                 &http.Client{Timeout: 10 * time.Second, Transport: apiTransport},
+		IngressControllerNamespace: ingressControllerNamespace,
+		DKIMMinRSAKeyBits:          dkimMinRSAKeyBits,
+		ClusterConfigName:          clusterDomainConfig,
+
+		// only the Domains with spec.reputation and spec.dmarcReports are
+		// polled
+		Reputation:   reputation.NewClient(&http.Client{Timeout: 30 * time.Second, Transport: apiTransport}),
+		DMARCReports: dmarc.NewStore(dmarc.DefaultRetention),
+		DMARCFetcher: &dmarc.IMAPFetcher{},
+	}
+	if mtaSTSBindAddress != "" {
+		_, port, err := net.SplitHostPort(mtaSTSBindAddress)
+		if err != nil || mtaSTSService == "" {
+			setupLog.Error(err, "the mta-sts policy server needs a bind address with a port and a service",
+				"mta-sts-bind-address", mtaSTSBindAddress, "mta-sts-service", mtaSTSService)
+			os.Exit(1)
+		}
+		portNum, err := strconv.ParseInt(port, 10, 32)
+		if err != nil {
+			setupLog.Error(err, "invalid mta-sts port", "mta-sts-bind-address", mtaSTSBindAddress)
+			os.Exit(1)
+		}
+		reconciler.MTASTSService = mtaSTSService
+		reconciler.MTASTSPort = int32(portNum)
+
+		if err := mgr.Add(mtasts.NewServer(mtaSTSBindAddress, controllers.MTASTSPolicyStore(mgr.GetClient()))); err != nil {
+			setupLog.Error(err, "unable to set up the mta-sts policy server")
+			os.Exit(1)
+		}
+	}
+	if kannonAPIEndpoint != "" {
+		reconciler.Kannon = kannon.NewConnectClient(kannonAPIEndpoint, os.Getenv("KANNON_API_TOKEN"),
+			&http.Client{Timeout: 10 * time.Second, Transport: apiTransport})
+	}
+	notifyFlags := notify.Config{}
+	var dispatcher *notify.Dispatcher
+	if notifyConfig != "" || notifySlackURL != "" || notifyWebhookURL != "" || clusterDomainConfig != "" {
+		cfg := notify.Config{}
+		if notifyConfig != "" {
+			data, err := os.ReadFile(notifyConfig)
+			if err != nil {
+				setupLog.Error(err, "unable to read the notification config")
+				os.Exit(1)
+			}
+			if cfg, err = notify.ParseConfig(data); err != nil {
+				setupLog.Error(err, "unable to parse the notification config", "notify-config", notifyConfig)
+				os.Exit(1)
+			}
+		}
+		if notifySlackURL != "" {
+			cfg.Slack = append(cfg.Slack, notify.SinkConfig{URL: notifySlackURL})
+		}
+		if notifyWebhookURL != "" {
+			cfg.Webhooks = append(cfg.Webhooks, notify.SinkConfig{URL: notifyWebhookURL})
+		}
+
+		notifyFlags = cfg
+
+		dispatcher, err = notify.NewDispatcher(cfg, &http.Client{Transport: apiTransport})
+		if err != nil {
+			setupLog.Error(err, "unable to set up the notifications")
+			os.Exit(1)
+		}
+		if err := mgr.Add(dispatcher); err != nil {
+			setupLog.Error(err, "unable to set up the notifications")
+			os.Exit(1)
+		}
+		reconciler.Notifier = dispatcher
+	}
+	if deliveryBindAddress != "" {
+		secret := os.Getenv("DELIVERY_WEBHOOK_SECRET")
+		if secret == "" {
+			setupLog.Error(nil, "the delivery webhook receiver needs the DELIVERY_WEBHOOK_SECRET environment variable")
+			os.Exit(1)
+		}
+		// every replica receives a share of the webhooks, and adds its
+		// counters to the status of the Domains
+		reconciler.Delivery = delivery.NewAggregator(delivery.DefaultWindow, controllers.KnownDeliveryDomain(mgr.GetClient()))
+		if err := mgr.Add(&controllers.DeliveryFlusher{Client: mgr.GetClient(), Aggregator: reconciler.Delivery}); err != nil {
+			setupLog.Error(err, "unable to set up the delivery webhook receiver")
+			os.Exit(1)
+		}
+
+		handler := delivery.Handler(reconciler.Delivery, secret)
+		if err := mgr.Add(delivery.NewServer(deliveryBindAddress, handler, deliveryTLSCert, deliveryTLSKey)); err != nil {
+			setupLog.Error(err, "unable to set up the delivery webhook receiver")
+			os.Exit(1)
+		}
+	}
+	if dmarcReportBindAddress != "" {
+		if replicaShard.Enabled() {
+			// the reports of a domain would be kept by whichever shard the
+			// Service routes them to
+			setupLog.Error(nil, "the dmarc report receiver does not support sharding")
+			os.Exit(1)
+		}
+		handler := dmarc.Handler(reconciler.DMARCReports, controllers.DMARCReportAuthorizer(mgr.GetAPIReader()))
+		if err := mgr.Add(dmarc.NewServer(dmarcReportBindAddress, handler, dmarcReportTLSCert, dmarcReportTLSKey)); err != nil {
+			setupLog.Error(err, "unable to set up the dmarc report receiver")
+			os.Exit(1)
+		}
+	}
+	if err = reconciler.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Domain")
 		os.Exit(1)
 	}
+	if clusterDomainConfig != "" {
+		if err = (&controllers.ClusterDomainConfigReconciler{
+			Client:            mgr.GetClient(),
+			Scheme:            mgr.GetScheme(),
+			Name:              clusterDomainConfig,
+			DNSChecker:        dnsSwitch,
+			DefaultDNSChecker: flagsChecker,
+			NewDNSChecker: func(addrs []string) (checker.DNSChecker, error) {
+				rs := newResolvers(addrs)
+				if dnsQuorum > len(rs) {
+					return nil, fmt.Errorf("the dns quorum %d exceeds the %d resolvers", dnsQuorum, len(rs))
+				}
+				return checker.New(rs, checkerOptions...), nil
+			},
+			Notifier:      dispatcher,
+			Notifications: notifyFlags,
+			Shard:         replicaShard,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "ClusterDomainConfig")
+			os.Exit(1)
+		}
+	}
+	if statsRouteGCInterval > 0 {
+		if err := mgr.Add(&controllers.StatsRouteCollector{
+			Client:         mgr.GetClient(),
+			Reader:         mgr.GetAPIReader(),
+			Interval:       statsRouteGCInterval,
+			GatewayAPI:     enableGatewayAPI,
+			DomainSelector: domainSelector,
+			DryRun:         dryRun,
+			Shard:          replicaShard,
+		}); err != nil {
+			setupLog.Error(err, "unable to set up the stats route garbage collection")
+			os.Exit(1)
+		}
+	}
+	if err = (&controllers.SenderPoolReconciler{
+		Client:          mgr.GetClient(),
+		Scheme:          mgr.GetScheme(),
+		DNSChecker:      dnsChecker,
+		DNSCheckTimeout: dnsCheckTimeout,
+		Shard:           replicaShard,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "SenderPool")
+		os.Exit(1)
+	}
+	if err = (&controllers.IPWarmupReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+		Kannon: reconciler.Kannon,
+		DryRun: dryRun,
+		Shard:  replicaShard,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "IPWarmup")
+		os.Exit(1)
+	}
+	if reconciler.Kannon != nil && dryRun {
+		// the API keys, the templates and the tests have nothing to check
+		setupLog.Info("the ApiKey, EmailTemplate and DomainTest controllers are disabled in dry-run mode")
+	} else if reconciler.Kannon != nil {
+		if err = (&controllers.ApiKeyReconciler{
+			Client: mgr.GetClient(),
+			Scheme: mgr.GetScheme(),
+			Kannon: reconciler.Kannon,
+			Shard:  replicaShard,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "ApiKey")
+			os.Exit(1)
+		}
+		if err = (&controllers.EmailTemplateReconciler{
+			Client:    mgr.GetClient(),
+			Scheme:    mgr.GetScheme(),
+			Kannon:    reconciler.Kannon,
+			APIReader: mgr.GetAPIReader(),
+			Shard:     replicaShard,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "EmailTemplate")
+			os.Exit(1)
+		}
+		if domainTestSMTPBindAddress != "" {
+			if replicaShard.Enabled() {
+				// the sink of a shard would receive the messages of the
+				// tests of the others
+				setupLog.Error(nil, "the domain test smtp sink does not support sharding")
+				os.Exit(1)
+			}
+			inbox := smtpsink.NewInbox(256)
+			if err := mgr.Add(smtpsink.NewServer(domainTestSMTPBindAddress, domainTestSinkDomain, inbox.Deliver)); err != nil {
+				setupLog.Error(err, "unable to set up the domain test smtp sink")
+				os.Exit(1)
+			}
+			if err = (&controllers.DomainTestReconciler{
+				Client:     mgr.GetClient(),
+				Scheme:     mgr.GetScheme(),
+				Kannon:     reconciler.Kannon,
+				DNSChecker: dnsChecker,
+				LookupTXT:  resolvers[0].LookupTXT,
+				Inbox:      inbox,
+				SinkDomain: domainTestSinkDomain,
+				Shard:      replicaShard,
+			}).SetupWithManager(mgr); err != nil {
+				setupLog.Error(err, "unable to create controller", "controller", "DomainTest")
+				os.Exit(1)
+			}
+		}
+	}
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		defaulter := &corev1alpha1.DomainDefaulter{DefaultIngressClass: defaultIngressClass}
+		if err = (&corev1alpha1.Domain{}).SetupWebhookWithManager(mgr, defaulter); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Domain")
+			os.Exit(1)
+		}
+		if err = (&corev1beta1.Domain{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Domain", "version", "v1beta1")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
 	}
+	dnsCanary := checker.NewCanary(dnsChecker, dnsProbeDomain, dnsLivenessThreshold)
+	if dnsLivenessThreshold > 0 {
+		if err := mgr.AddHealthzCheck("dns", dnsCanary.LiveCheck); err != nil {
+			setupLog.Error(err, "unable to set up dns health check")
+			os.Exit(1)
+		}
+	}
 	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up ready check")
 		os.Exit(1)
 	}
+	if err := mgr.AddReadyzCheck("dns", dnsCanary.ReadyCheck); err != nil {
+		setupLog.Error(err, "unable to set up dns ready check")
+		os.Exit(1)
+	}
 
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
+
+	flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(flushCtx); err != nil {
+		setupLog.Error(err, "unable to flush the traces")
+	}
 }